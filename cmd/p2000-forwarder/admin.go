@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+const (
+	pauseArgs    = "[-reason TEXT] [-for DURATION] [-url URL]"
+	muteArgs     = "[-rule] [-reason TEXT] [-url URL] [TARGET [for] DURATION]"
+	unmuteArgs   = "[-rule] [-url URL] TARGET"
+	adminTimeout = 10 * time.Second
+)
+
+// pauseState is the response of the pause endpoints
+type pauseState struct {
+	Paused bool       `json:"paused"`
+	Reason string     `json:"reason"`
+	Author string     `json:"author"`
+	Since  time.Time  `json:"since"`
+	Until  *time.Time `json:"until"`
+}
+
+// pauseCommand pauses notification sending of the running forwarder
+func pauseCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pause", pauseArgs, stderr)
+	reason := fs.String("reason", "", "Reason shown in the status, e.g. drill or ntfy maintenance")
+	duration := fs.Duration("for", 0, "Resume automatically after this duration, e.g. 2h")
+	url := fs.String("url", "", "Pause endpoint, the management API on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+	if *duration < 0 {
+		fmt.Fprintln(stderr, "-for must not be negative")
+		return 2
+	}
+
+	body := map[string]string{"reason": *reason}
+	if *duration > 0 {
+		body["duration"] = duration.String()
+	}
+	var state pauseState
+	if code := adminRequest(http.MethodPost, *url, "/api/admin/pause", body, &state, stderr); code != 0 {
+		return code
+	}
+	printPause(stdout, state)
+	return 0
+}
+
+// resumeCommand resumes notification sending of the running forwarder
+func resumeCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("resume", "[-url URL]", stderr)
+	url := fs.String("url", "", "Resume endpoint, the management API on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+	var state pauseState
+	if code := adminRequest(http.MethodPost, *url, "/api/admin/resume", nil, &state, stderr); code != 0 {
+		return code
+	}
+	printPause(stdout, state)
+	return 0
+}
+
+// muteCommand mutes a capcode, or a rule with -rule, for a duration, e.g.
+// "mute 0101001 for 4h". Without arguments it lists the current mutes.
+func muteCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("mute", muteArgs, stderr)
+	rule := fs.Bool("rule", false, "TARGET is the name of a routing rule instead of a capcode")
+	reason := fs.String("reason", "", "Reason shown in the list of mutes, e.g. exercise of a station")
+	url := fs.String("url", "", "Mutes endpoint, the management API on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		var mutes []store.Mute
+		if code := adminRequest(http.MethodGet, *url, "/api/mutes", nil, &mutes, stderr); code != 0 {
+			return code
+		}
+		if len(mutes) == 0 {
+			fmt.Fprintln(stdout, "nothing muted")
+			return 0
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		for _, m := range mutes {
+			fmt.Fprintf(tw, "%s %s\tuntil %s\t%s\n", m.Kind, m.Target, m.Until.Local().Format(time.DateTime), m.Reason)
+		}
+		tw.Flush()
+		return 0
+	}
+
+	if len(rest) == 3 && strings.EqualFold(rest[1], "for") {
+		rest = []string{rest[0], rest[2]}
+	}
+	if len(rest) != 2 {
+		fs.Usage()
+		return 2
+	}
+	duration, err := time.ParseDuration(rest[1])
+	if err != nil || duration <= 0 {
+		fmt.Fprintf(stderr, "invalid duration %q, e.g. 4h or 30m\n", rest[1])
+		return 2
+	}
+
+	body := map[string]string{"capcode": rest[0], "duration": duration.String(), "reason": *reason}
+	if *rule {
+		body = map[string]string{"rule": rest[0], "duration": duration.String(), "reason": *reason}
+	}
+	var m store.Mute
+	if code := adminRequest(http.MethodPost, *url, "/api/mutes", body, &m, stderr); code != 0 {
+		return code
+	}
+	fmt.Fprintf(stdout, "%s %s muted until %s\n", m.Kind, m.Target, m.Until.Local().Format(time.DateTime))
+	return 0
+}
+
+// unmuteCommand removes the mute of a capcode, or a rule with -rule
+func unmuteCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("unmute", unmuteArgs, stderr)
+	rule := fs.Bool("rule", false, "TARGET is the name of a routing rule instead of a capcode")
+	url := fs.String("url", "", "Mutes endpoint, the management API on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	kind := store.MuteCapcode
+	if *rule {
+		kind = store.MuteRule
+	}
+	endpoint, path := "", "/api/mutes/"+kind+"/"+neturl.PathEscape(fs.Arg(0))
+	if *url != "" {
+		endpoint = strings.TrimSuffix(*url, "/") + "/" + kind + "/" + neturl.PathEscape(fs.Arg(0))
+	}
+	if code := adminRequest(http.MethodDelete, endpoint, path, nil, nil, stderr); code != 0 {
+		return code
+	}
+	fmt.Fprintf(stdout, "%s %s unmuted\n", kind, fs.Arg(0))
+	return 0
+}
+
+// adminRequest sends body to an admin endpoint of the running forwarder,
+// the management API on localhost unless url is set, and decodes the
+// response into result. It returns the exit code of a failed request, 0 on
+// success.
+func adminRequest(method, url, path string, body, result any, stderr io.Writer) int {
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if cfg.API.Token == "" {
+		fmt.Fprintln(stderr, "the management API is required, set api.token")
+		return 1
+	}
+	client := &http.Client{Timeout: adminTimeout}
+	if url == "" {
+		url = localURL(cfg, path)
+		client = localClient(adminTimeout)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.API.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to reach the forwarder: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		fmt.Fprintf(stderr, "%s returned status %d %s\n", url, resp.StatusCode, apiErr.Error)
+		return 1
+	}
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			fmt.Fprintf(stderr, "invalid response from %s: %v\n", url, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// printPause prints whether sending is paused
+func printPause(stdout io.Writer, state pauseState) {
+	if !state.Paused {
+		fmt.Fprintln(stdout, "sending resumed")
+		return
+	}
+	fmt.Fprintf(stdout, "sending paused since %s", state.Since.Local().Format(time.DateTime))
+	if state.Until != nil {
+		fmt.Fprintf(stdout, " until %s", state.Until.Local().Format(time.DateTime))
+	}
+	if state.Reason != "" {
+		fmt.Fprintf(stdout, ": %s", state.Reason)
+	}
+	fmt.Fprintln(stdout)
+}
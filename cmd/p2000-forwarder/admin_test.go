@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseCommand(t *testing.T) {
+	writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+api:
+  token: "secret"
+`)
+
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/admin/pause":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			w.Write([]byte(`{"paused": true, "reason": "drill", "since": "2024-05-01T08:00:00Z", "until": "2024-05-01T10:00:00Z"}`))
+		case "/api/admin/resume":
+			w.Write([]byte(`{"paused": false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"pause", "-reason", "drill", "-for", "2h", "-url", server.URL + "/api/admin/pause"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, map[string]string{"reason": "drill", "duration": "2h0m0s"}, body)
+	assert.Contains(t, stdout.String(), "sending paused since")
+	assert.Contains(t, stdout.String(), ": drill")
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"resume", "-url", server.URL + "/api/admin/resume"}, &stdout, &stderr))
+	assert.Equal(t, "sending resumed\n", stdout.String())
+
+	assert.Equal(t, 1, execute([]string{"resume", "-url", server.URL + "/missing"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"pause", "-for", "-1h"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"resume", "now"}, &stdout, &stderr))
+}
+
+func TestMuteCommand(t *testing.T) {
+	writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+api:
+  token: "secret"
+`)
+
+	var body map[string]string
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/mutes":
+			w.Write([]byte(`[{"kind": "capcode", "target": "101001", "reason": "exercise", "until": "2024-05-01T12:00:00Z"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/mutes":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"kind": "capcode", "target": "101001", "until": "2024-05-01T12:00:00Z"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/mutes/capcode/101001":
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not muted"}`))
+		}
+	}))
+	defer server.Close()
+	url := server.URL + "/api/mutes"
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"mute", "-reason", "exercise", "-url", url, "0101001", "for", "4h"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, map[string]string{"capcode": "0101001", "duration": "4h0m0s", "reason": "exercise"}, body)
+	assert.Contains(t, stdout.String(), "capcode 101001 muted until")
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"mute", "-url", url}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "capcode 101001")
+	assert.Contains(t, stdout.String(), "exercise")
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"unmute", "-url", url, "101001"}, &stdout, &stderr))
+	assert.Equal(t, "/api/mutes/capcode/101001", deleted)
+	assert.Equal(t, "capcode 101001 unmuted\n", stdout.String())
+
+	assert.Equal(t, 1, execute([]string{"unmute", "-rule", "-url", url, "grip"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "not muted")
+	assert.Equal(t, 2, execute([]string{"mute", "0101001", "soon"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"mute", "0101001"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"unmute"}, &stdout, &stderr))
+}
+
+func TestPauseCommand_WithoutAPI(t *testing.T) {
+	writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+`)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, execute([]string{"pause"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "set api.token")
+}
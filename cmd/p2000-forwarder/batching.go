@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// flushBatch delivers the combined notification of an incident burst once
+// its window ended. The pages merged into it are not notified themselves.
+func (app *Application) flushBatch(msg model.Message, d notifier.Delivery, merged []string) {
+	if len(merged) > 0 {
+		app.metrics.RecordBatchedPages(len(merged))
+		app.logger.Debug().
+			Str("id", msg.ID).
+			Int("pages", len(merged)+1).
+			Strs("capcodes", msg.Capcodes).
+			Msg("incident pages combined")
+		app.trace(msg.ID, traceEvent(store.TraceBatched, fmt.Sprintf("combined with %d later pages", len(merged))))
+		for _, id := range merged {
+			app.trace(id, traceEvent(store.TraceBatched, "combined into "+msg.ID))
+		}
+	}
+	app.deliver(msg, d)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/incident"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMessage_Batching(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		store:      history,
+		direct:     true,
+	}
+	app.batcher = incident.NewBatcher(50*time.Millisecond, app.flushBatch)
+	app.status = status.NewManager(app.metrics)
+
+	text := "P 1 BDH-01 Brand woning Kerkstraat 12 Harmelen"
+	for _, code := range []string{"0101001", "0101002", "0101003"} {
+		app.handleMessage(model.Message{Type: "FLEX", Capcodes: []string{code}, Message: text})
+	}
+	id, forwarded := app.process(model.Message{Type: "FLEX", Capcodes: []string{"0101004"}, Message: text}, false)
+	assert.True(t, forwarded)
+
+	sender.mu.Lock()
+	require.Len(t, sender.msgs, 1, "test messages from the API are not batched")
+	assert.Equal(t, id, sender.msgs[0].ID)
+	sender.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		sender.mu.Lock()
+		defer sender.mu.Unlock()
+		return len(sender.msgs) == 2
+	}, time.Second, 5*time.Millisecond)
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	assert.Equal(t, []string{"0101001", "0101002", "0101003"}, sender.msgs[1].Capcodes)
+	assert.Equal(t, 2.0, testutil.ToFloat64(app.metrics.BatchedPages))
+
+	records := history.Messages(0)
+	require.Len(t, records, 4)
+	assert.Contains(t, steps(t, history, sender.msgs[1].ID), "batched combined with 2 later pages")
+	assert.Contains(t, steps(t, history, records[1].ID), "batched combined into "+sender.msgs[1].ID)
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/chaos"
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/service"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// serviceName is the default name of the Windows service
+const serviceName = "p2000-forwarder"
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+const serviceArgs = "install [-name NAME] [-config FILE] | uninstall [-name NAME]"
+
+const testNotifyArgs = "[-destination NAME] [-message TEXT] [-pipeline [-filter] [-capcodes LIST] [-url URL]]"
+
+const (
+	testNotifyTimeout = 30 * time.Second
+	healthTimeout     = 3 * time.Second
+)
+
+// command is a subcommand of p2000-forwarder
+type command struct {
+	name    string
+	aliases []string
+	args    string // Synopsis of the flags and arguments
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+// commandList returns the subcommands. run is used when the first argument
+// is not a command, so the forwarder still starts without one.
+func commandList() []command {
+	return []command{
+		{name: "run", args: "[-config FILE]", summary: "Forward P2000 messages to ntfy (default)", run: runCommand},
+		{name: "replay", aliases: []string{"import"}, args: "[-speed N] [-send] FILE...", summary: "Replay archived feed files through the filters", run: replayCommand},
+		{name: "validate-config", args: "[FILE]", summary: "Report all problems in the configuration", run: validateConfigCommand},
+		{name: "test-notify", args: testNotifyArgs, summary: "Send a test notification to the destinations or through the pipeline", run: testNotifyCommand},
+		{name: "lookup", args: "[-csv PATH] CAPCODE...", summary: "Show the capcode database entries of capcodes", run: lookupCommand},
+		{name: "pause", args: pauseArgs, summary: "Pause notification sending of a running forwarder", run: pauseCommand},
+		{name: "resume", args: "[-url URL]", summary: "Resume notification sending of a running forwarder", run: resumeCommand},
+		{name: "mute", args: muteArgs, summary: "Mute a capcode or rule of a running forwarder for a while, or list the mutes", run: muteCommand},
+		{name: "unmute", args: unmuteArgs, summary: "Remove the mute of a capcode or rule", run: unmuteCommand},
+		{name: "health", args: "[-url URL]", summary: "Check the liveness endpoint of a running forwarder", run: healthCommand},
+		{name: "service", args: serviceArgs, summary: "Install or uninstall the Windows service", run: serviceCommand},
+		{name: "version", summary: "Print the version", run: versionCommand},
+		{name: "help", summary: "Show this help", run: helpCommand},
+	}
+}
+
+// execute runs the subcommand named by the first argument and returns the
+// exit code of the process
+func execute(args []string, stdout, stderr io.Writer) int {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commandList() {
+		if cmd.name == name || slices.Contains(cmd.aliases, name) {
+			return cmd.run(args, stdout, stderr)
+		}
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+	printUsage(stderr)
+	return 2
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: p2000-forwarder [COMMAND] [ARGS]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commandList() {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nThe configuration is read from CONFIG_PATH, config.yaml by default.\n")
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: p2000-forwarder %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the arguments of a subcommand, returning the exit code
+// and false when the command should not run
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, false
+		}
+		return 2, false
+	}
+	return 0, true
+}
+
+// quietLogger logs only warnings and errors, for commands printing their
+// own output
+func quietLogger(stderr io.Writer) zerolog.Logger {
+	return zerolog.New(zerolog.ConsoleWriter{Out: stderr, NoColor: true}).
+		Level(zerolog.WarnLevel).
+		With().Timestamp().Logger()
+}
+
+// runCommand starts the forwarder
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("run", "[-config FILE]", stderr)
+	configFile := fs.String("config", "", "Configuration file, overriding CONFIG_PATH")
+	chaosCfg := chaos.RegisterFlags(fs)
+	chaos.HideFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	if *configFile != "" {
+		os.Setenv("CONFIG_PATH", *configFile)
+	}
+
+	return service.Run(serviceName, func(stop <-chan struct{}) int {
+		return serve(chaosCfg, nil, stop)
+	})
+}
+
+// replayCommand imports archived feed files instead of the live feed
+func replayCommand(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseImportFlags(args)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	return serve(&chaos.Config{}, opts, nil)
+}
+
+// validateConfigCommand checks the configuration file given as argument,
+// or the one the forwarder would use
+func validateConfigCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate-config", "[FILE]", stderr)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	path := configPath()
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	return validateConfig(path, stdout)
+}
+
+// testNotifyCommand sends a test message to every destination, or to the
+// one given with -destination, and reports the outcome of each. With
+// -pipeline the message is injected into the running forwarder instead.
+func testNotifyCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("test-notify", testNotifyArgs, stderr)
+	only := fs.String("destination", "", "Only notify this destination (ntfy or a name from destinations)")
+	text := fs.String("message", model.DefaultTestText, "Text of the test message")
+	viaPipeline := fs.Bool("pipeline", false, "Pass the message through the pipeline of the running forwarder, using the management API")
+	filtered := fs.Bool("filter", false, "Apply the filters and routing rules to the message, implies -pipeline")
+	capcodes := fs.String("capcodes", "", "Comma-separated capcodes of the message, for -pipeline")
+	url := fs.String("url", "", "Test notification endpoint, the management API on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if *viaPipeline || *filtered {
+		if *only != "" {
+			fmt.Fprintln(stderr, "-destination cannot be combined with -pipeline")
+			return 2
+		}
+		return injectTestMessage(cfg, *url, *text, splitTags(*capcodes), *filtered, stdout, stderr)
+	}
+
+	translator, err := i18n.New(cfg.Language)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := useProxy(cfg); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	_, destinations, err := newSender(cfg, nil, translator, nil, nil, nil, nil, quietLogger(stderr))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create notifiers: %v\n", err)
+		return 1
+	}
+
+	names := make([]string, 0, len(destinations))
+	for name := range destinations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if *only != "" {
+		if _, ok := destinations[*only]; !ok {
+			fmt.Fprintf(stderr, "unknown destination %q, configured: %s\n", *only, strings.Join(names, ", "))
+			return 2
+		}
+		names = []string{*only}
+	}
+
+	msg := model.NewTestMessage(*text, nil, time.Now())
+	msg.Test = true
+	msg.Enrich(nil)
+
+	code := 0
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), testNotifyTimeout)
+		err := destinations[name].Send(ctx, msg)
+		cancel()
+		if errors.Is(err, notifier.ErrSkipped) {
+			fmt.Fprintf(stdout, "%s: skipped: %v\n", name, err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: failed: %v\n", name, err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: sent\n", name)
+	}
+	return code
+}
+
+// injectTestMessage asks the running forwarder to pass a test message
+// through its pipeline
+func injectTestMessage(cfg *config.Config, url, text string, capcodes []string, filter bool, stdout, stderr io.Writer) int {
+	if cfg.API.Token == "" {
+		fmt.Fprintln(stderr, "-pipeline requires the management API, set api.token")
+		return 1
+	}
+	client := &http.Client{Timeout: testNotifyTimeout}
+	if url == "" {
+		url = localURL(cfg, "/api/test-notify")
+		client = localClient(testNotifyTimeout)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"message":  text,
+		"capcodes": capcodes,
+		"filter":   filter,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.API.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to reach the forwarder: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID        string `json:"id"`
+		Forwarded bool   `json:"forwarded"`
+		Error     string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(stderr, "%s returned status %d %s\n", url, resp.StatusCode, result.Error)
+		return 1
+	}
+
+	if !result.Forwarded {
+		fmt.Fprintf(stdout, "message %s: filtered out\n", result.ID)
+		return 1
+	}
+	fmt.Fprintf(stdout, "message %s: queued\n", result.ID)
+	return 0
+}
+
+// lookupCommand prints the capcode database entries of the capcodes given
+// as arguments and how the configuration filters and routes their pages
+func lookupCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("lookup", "[-csv PATH] CAPCODE...", stderr)
+	csvPath := fs.String("csv", "", "Capcode database file or URL, capcode_csv_path from the configuration by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// The routing is only shown when the configuration loads; a database
+	// given with -csv can be inspected without one
+	cfg, err := config.Load(configPath())
+	if err != nil && *csvPath == "" {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	dbCfg := &config.Config{CapcodeCSVPath: *csvPath}
+	if *csvPath == "" {
+		dbCfg.CapcodeCSVPath = cfg.CapcodeCSVPath
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := quietLogger(stderr)
+	lookup := capcode.NewLookupFromRecords(nil)
+	if dbCfg.CapcodeCSVPath != "" {
+		if err := loadCapcodeLookup(ctx, dbCfg, lookup, logger); err != nil {
+			fmt.Fprintf(stderr, "failed to load capcode database %s: %v\n", dbCfg.CapcodeCSVPath, err)
+			return 1
+		}
+	}
+
+	code := 0
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CAPCODE\tAGENCY\tREGION\tSTATION\tFUNCTION")
+	for _, arg := range fs.Args() {
+		info := lookup.Get(arg)
+		if info == nil {
+			fmt.Fprintf(tw, "%s\tnot found\t\t\t\n", arg)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.Capcode, info.Agency, info.Region, info.Station, info.Function)
+	}
+	tw.Flush()
+
+	if cfg == nil {
+		return code
+	}
+	explainer, err := newRouteExplainer(cfg, lookup, logger)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, arg := range fs.Args() {
+		fmt.Fprintln(stdout)
+		explainer.explain(stdout, arg)
+	}
+	return code
+}
+
+// healthCommand checks the liveness endpoint of a running forwarder, for
+// container health checks
+func healthCommand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("health", "[-url URL]", stderr)
+	url := fs.String("url", "", "Endpoint to check, the configured liveness endpoint on localhost by default")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	client := &http.Client{Timeout: healthTimeout}
+	var cfg *config.Config
+	if *url == "" {
+		var err error
+		if cfg, err = config.Load(configPath()); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		*url = localURL(cfg, cfg.Server.LivePath)
+		client = localClient(healthTimeout)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, *url, nil)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if cfg != nil {
+		setLocalAuth(req, cfg)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "unhealthy: %v\n", err)
+		return 1
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(stderr, "unhealthy: %s returned status %d\n", *url, resp.StatusCode)
+		return 1
+	}
+	fmt.Fprintln(stdout, "healthy")
+	return 0
+}
+
+// localURL returns the URL of path on the HTTP server of the forwarder
+// running on this host
+func localURL(cfg *config.Config, path string) string {
+	scheme := "http"
+	if cfg.Server.TLS.CertFile != "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, cfg.Server.Port, path)
+}
+
+// localClient returns a client for localURL. The server certificate is
+// issued for the public host name, so it is not verified on localhost.
+func localClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// setLocalAuth adds the server credentials to a request for localURL
+func setLocalAuth(req *http.Request, cfg *config.Config) {
+	auth := cfg.Server.Auth
+	switch {
+	case auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// serviceCommand installs the forwarder as a Windows service running with
+// the given configuration file, or removes it again
+func serviceCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "install" && args[0] != "uninstall") {
+		fmt.Fprintf(stderr, "Usage: p2000-forwarder service %s\n", serviceArgs)
+		return 2
+	}
+	action := args[0]
+
+	fs := newFlagSet("service "+action, serviceArgs, stderr)
+	name := fs.String("name", serviceName, "Name of the service")
+	configFile := fs.String("config", "", "Configuration file of the service, CONFIG_PATH or config.yaml by default")
+	if code, ok := parseFlags(fs, args[1:]); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	if action == "uninstall" {
+		if err := service.Uninstall(*name); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "service %s uninstalled\n", *name)
+		return 0
+	}
+
+	// Services start in the system directory, so the configuration is
+	// passed as an absolute path
+	path := *configFile
+	if path == "" {
+		path = configPath()
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := service.Install(*name, "Forwards P2000 messages to ntfy", exe, []string{"run", "-config", path}); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "service %s installed with configuration %s\n", *name, path)
+	return 0
+}
+
+// versionCommand prints the version and build platform
+func versionCommand(args []string, stdout, stderr io.Writer) int {
+	fmt.Fprintf(stdout, "p2000-forwarder %s (%s %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return 0
+}
+
+// helpCommand prints the usage
+func helpCommand(args []string, stdout, stderr io.Writer) int {
+	printUsage(stdout)
+	return 0
+}
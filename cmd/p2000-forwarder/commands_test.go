@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes a configuration file and points CONFIG_PATH at it
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	t.Setenv("CONFIG_PATH", path)
+	return path
+}
+
+func TestExecute_Commands(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"version"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "p2000-forwarder dev")
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"help"}, &stdout, &stderr))
+	for _, name := range []string{"run", "replay", "validate-config", "test-notify", "lookup", "version"} {
+		assert.Contains(t, stdout.String(), "  "+name)
+	}
+
+	assert.Equal(t, 2, execute([]string{"frobnicate"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+
+	stderr.Reset()
+	assert.Equal(t, 2, execute([]string{"import"}, &stdout, &stderr), "import is an alias of replay")
+	assert.Contains(t, stderr.String(), "usage: p2000-forwarder replay")
+
+	assert.Equal(t, 2, execute([]string{"run", "extra"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"-unknown-flag"}, &stdout, &stderr), "flags without a command belong to run")
+}
+
+func TestServiceCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, execute([]string{"service"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: p2000-forwarder service install")
+	assert.Equal(t, 2, execute([]string{"service", "start"}, &stdout, &stderr))
+	assert.Equal(t, 2, execute([]string{"service", "uninstall", "extra"}, &stdout, &stderr))
+
+	if runtime.GOOS != "windows" {
+		stderr.Reset()
+		assert.Equal(t, 1, execute([]string{"service", "install"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "services can only be installed on windows")
+	}
+}
+
+func TestValidateConfigCommand(t *testing.T) {
+	path := writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+`)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"validate-config"}, &stdout, &stderr))
+	assert.Equal(t, path+": configuration is valid\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 1, execute([]string{"validate-config", filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr))
+}
+
+func TestTestNotifyCommand(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	writeConfig(t, `
+ntfy:
+  server: "`+server.URL+`"
+  topic: "p2000"
+destinations:
+  backup:
+    server: "`+server.URL+`"
+    topic: "backup"
+`)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"test-notify"}, &stdout, &stderr))
+	assert.Equal(t, "backup: sent\nntfy: sent\n", stdout.String())
+	assert.ElementsMatch(t, []string{"/p2000", "/backup"}, paths)
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"test-notify", "-destination", "backup"}, &stdout, &stderr))
+	assert.Equal(t, "backup: sent\n", stdout.String())
+
+	assert.Equal(t, 2, execute([]string{"test-notify", "-destination", "pager"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown destination "pager", configured: backup, ntfy`)
+}
+
+func TestTestNotifyCommand_Pipeline(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/test-notify", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		fmt.Fprintf(w, `{"id": "7", "forwarded": %t}`, req["filter"] != true)
+	}))
+	defer server.Close()
+
+	writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+api:
+  token: "secret"
+`)
+	url := server.URL + "/api/test-notify"
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"test-notify", "-pipeline", "-url", url}, &stdout, &stderr))
+	assert.Equal(t, "message 7: queued\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 1, execute([]string{"test-notify", "-filter", "-capcodes", "0101001, 1420059", "-url", url}, &stdout, &stderr))
+	assert.Equal(t, "message 7: filtered out\n", stdout.String())
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, false, requests[0]["filter"])
+	assert.Equal(t, true, requests[1]["filter"])
+	assert.Equal(t, []any{"0101001", "1420059"}, requests[1]["capcodes"])
+
+	assert.Equal(t, 2, execute([]string{"test-notify", "-pipeline", "-destination", "ntfy"}, &stdout, &stderr))
+}
+
+func TestLookupCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"lookup", "-csv", "../../testdata/capcodes-test.csv", "0101001", "0999999"}, &stdout, &stderr)
+	assert.Equal(t, 1, code, "a capcode was not found")
+	assert.Contains(t, stdout.String(), "0101001  Brandweer  Utrecht  Centrum  Kazernealarm")
+	assert.Contains(t, stdout.String(), "0999999  not found")
+
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"lookup", "-csv", "../../testdata/capcodes-test.csv", "0234567"}, &stdout, &stderr))
+
+	assert.NotContains(t, stdout.String(), "result:", "routing needs a configuration")
+
+	writeConfig(t, `
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+capcode_csv_path: "../../testdata/capcodes-test.csv"
+capcodes: ["0101001"]
+`)
+	stdout.Reset()
+	assert.Equal(t, 0, execute([]string{"lookup", "0101001"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "0101001  Brandweer")
+	assert.Contains(t, stdout.String(), "  filter:    forwarded\n")
+
+	assert.Equal(t, 2, execute([]string{"lookup"}, &stdout, &stderr))
+	assert.Equal(t, 1, execute([]string{"lookup", "-csv", "missing.csv", "0101001"}, &stdout, &stderr))
+}
+
+func TestHealthCommand(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/live", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"health", "-url", server.URL + "/live"}, &stdout, &stderr))
+	assert.Equal(t, "healthy\n", stdout.String())
+
+	status = http.StatusServiceUnavailable
+	assert.Equal(t, 1, execute([]string{"health", "-url", server.URL + "/live"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "returned status 503")
+}
+
+func TestHealthCommand_ServerAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	writeConfig(t, fmt.Sprintf(`
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+server:
+  port: %d
+  auth:
+    username: "admin"
+    password: "secret"
+`, port))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, execute([]string{"health"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "healthy\n", stdout.String())
+}
@@ -0,0 +1,7 @@
+package main
+
+// database/sql drivers opened by name in the internal packages
+import (
+	_ "github.com/jackc/pgx/v5/stdlib" // postgres.Driver, for the postgres sink and ha mode
+	_ "modernc.org/sqlite"             // capcode.SQLiteDriver, for .db, .sqlite and .sqlite3 capcode databases
+)
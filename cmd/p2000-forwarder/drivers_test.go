@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/ha"
+	"github.com/kaije/p2000-nfty/internal/postgres"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPostgres returns the DSN of a scratch database from
+// P2000_TEST_POSTGRES_DSN, skipping the test when it is not set
+func testPostgres(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("P2000_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("P2000_TEST_POSTGRES_DSN not set")
+	}
+	return dsn
+}
+
+func TestPostgresDriver_Registered(t *testing.T) {
+	// A port nobody listens on: the registered driver gets as far as dialing
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = postgres.Open(ctx, postgres.Options{DSN: "postgres://p2000@" + addr + "/p2000?sslmode=disable", Logger: zerolog.Nop()})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to connect to postgres")
+	assert.NotContains(t, err.Error(), "unknown driver")
+}
+
+func TestPostgresDriver_EndToEnd(t *testing.T) {
+	dsn := testPostgres(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sink, err := postgres.Open(ctx, postgres.Options{DSN: dsn, Instance: "test", Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go sink.Run(runCtx)
+
+	msg := model.Message{Type: "FLEX", Timestamp: time.Now().Unix(), Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand Utrecht"}
+	msg.ID = model.MessageID(msg)
+	sink.RecordMessage(msg, time.Now(), true)
+	sink.RecordNotification(msg, time.Now(), time.Millisecond, nil)
+
+	key := "test-" + msg.ID + "-" + time.Now().Format(time.RFC3339Nano)
+	claimed, err := sink.Claim(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = sink.Claim(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, claimed, "claimed once")
+}
+
+func TestHA_Postgres(t *testing.T) {
+	dsn := testPostgres(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var instances []*ha.Deduplicator
+	for _, name := range []string{"primary", "standby"} {
+		sink, err := postgres.Open(ctx, postgres.Options{DSN: dsn, Instance: name, Logger: zerolog.Nop()})
+		require.NoError(t, err)
+		runCtx, stop := context.WithCancel(context.Background())
+		t.Cleanup(stop)
+		go sink.Run(runCtx)
+		instances = append(instances, ha.New(sink, nil, zerolog.Nop()))
+	}
+
+	msg := model.Message{Type: "FLEX", Timestamp: time.Now().UnixNano(), Capcodes: []string{"0101001"}, Message: "P 1 Reanimatie Utrecht"}
+	assert.True(t, instances[0].Claim(msg))
+	assert.False(t, instances[1].Claim(msg), "notified by the other instance")
+}
+
+func TestSQLiteDriver_CapcodeDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capcodes.sqlite")
+	db, err := sql.Open(capcode.SQLiteDriver, path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE capcodes (capcode TEXT, agency TEXT, region TEXT, station TEXT, function TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO capcodes VALUES ('0101001', 'Brandweer', 'Utrecht', 'Kazerne Utrecht', NULL)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	lookup, err := capcode.NewLookup(path)
+	require.NoError(t, err)
+	info, ok := lookup.Find("101001")
+	require.True(t, ok)
+	assert.Equal(t, "Brandweer", info.Agency)
+	assert.Equal(t, "Kazerne Utrecht", info.Station)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// duplicateTracker remembers the IDs of recently received messages, so a
+// page received twice, e.g. replayed by the feed after a reconnect, is only
+// handled once
+type duplicateTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+// newDuplicateTracker creates a tracker remembering IDs for window
+func newDuplicateTracker(window time.Duration) *duplicateTracker {
+	return &duplicateTracker{window: window, seen: make(map[string]time.Time)}
+}
+
+// Seen records id at now, reporting whether it was received within the
+// window before
+func (d *duplicateTracker) Seen(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Forget expired IDs once per window
+	if now.Sub(d.pruned) >= d.window {
+		for k, at := range d.seen {
+			if now.Sub(at) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.pruned = now
+	}
+
+	if at, ok := d.seen[id]; ok && now.Sub(at) < d.window {
+		return true
+	}
+	d.seen[id] = now
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateTracker(t *testing.T) {
+	d := newDuplicateTracker(10 * time.Minute)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, d.Seen("a", now))
+	assert.True(t, d.Seen("a", now.Add(time.Minute)))
+	assert.False(t, d.Seen("b", now.Add(time.Minute)))
+	assert.False(t, d.Seen("a", now.Add(11*time.Minute)), "forgotten after the window")
+	assert.Len(t, d.seen, 1, "expired IDs are pruned")
+}
+
+func TestHandleMessage_Duplicates(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		store:      history,
+		duplicates: newDuplicateTracker(time.Minute),
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	page := model.Message{Type: "FLEX", Timestamp: 1760522400, Capcodes: []string{"0101001", "0101002"}, Message: "P 1 Brand woning Utrecht"}
+	app.handleMessage(page)
+	page.Capcodes = []string{"0101002", "0101001"}
+	app.handleMessage(page)
+	app.handleMessage(model.Message{Type: "FLEX", Message: "P 2 Buitenbrand"})
+	app.handleMessage(model.Message{Type: "FLEX", Message: "P 2 Buitenbrand"})
+
+	require.Len(t, sender.msgs, 3, "copies without a timestamp may be new pages")
+	id := model.MessageID(page)
+	assert.Equal(t, id, sender.msgs[0].ID)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.DuplicateMessages))
+
+	r, ok := history.Message(id)
+	require.True(t, ok, "history is queried by the message ID")
+	assert.Equal(t, "P 1 Brand woning Utrecht", r.Message.Message)
+	assert.Len(t, history.Messages(0), 3)
+}
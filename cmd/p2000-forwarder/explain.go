@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/api"
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/pipeline"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// explain traces the decisions process makes for msg, without forwarding
+// it or recording anything. Incident threads are not correlated.
+func (app *Application) explain(msg model.Message) api.Explanation {
+	msg.Enrich(app.capcodes)
+	sent := time.Now()
+	if msg.Timestamp > 0 {
+		sent = time.Unix(msg.Timestamp, 0)
+	}
+
+	var exp api.Explanation
+	if app.testAlarms != nil {
+		msg.Test = app.testAlarms.IsTest(msg.Message, sent)
+		exp.TestAlarm = &api.TestAlarmTrace{Detected: msg.Test}
+	}
+	if app.reanimation != nil {
+		msg.Reanimation = app.reanimation.IsReanimation(msg)
+	}
+
+	var d notifier.Delivery
+	var res rules.Result
+	if app.rules != nil {
+		res, exp.Rules = app.rules.Explain(msg)
+		res.Apply(&d)
+	}
+	var scriptDropped, scriptRouted bool
+	if app.script != nil && !res.Drop {
+		exp.Script = app.explainScript(&msg, &d)
+		scriptDropped, scriptRouted = exp.Script.Drop, len(exp.Script.Destinations) > 0
+	}
+	d.Tags = append(d.Tags, app.severityTags(msg.Severity)...)
+	dropped := res.Drop || scriptDropped
+	gripRouted := !dropped && app.routeGRIP(msg, &d)
+	testDropped := false
+	if msg.Test && !dropped {
+		exp.TestAlarm.Action = app.cfg.TestAlarms.Action
+		testDropped = app.testAlarmAction(&d)
+	}
+	maintenanceDropped := false
+	if app.maintenance != nil && !dropped && !testDropped {
+		if w, ok := app.maintenance.Match(msg.Capcodes, sent); ok {
+			exp.Maintenance = &api.MaintenanceTrace{Window: w.Name, Action: w.Action}
+			maintenanceDropped = maintenanceAction(&d, w)
+		}
+	}
+
+	exp.Filter = app.explainFilter(msg)
+	exp.TypeAllowed = app.typeFilter.Allow(msg.Type, msg.Message)
+	rejectedBy, rejected := app.rejectingPlugin(msg)
+
+	switch {
+	case res.Drop:
+		exp.Reason = "dropped by rule " + res.Matched[len(res.Matched)-1]
+	case scriptDropped:
+		exp.Reason = "dropped by script"
+	case testDropped:
+		exp.Reason = "dropped as test alarm"
+	case maintenanceDropped:
+		exp.Reason = "suppressed by maintenance window " + exp.Maintenance.Window
+	case !exp.TypeAllowed:
+		exp.Reason = "suppressed by message type"
+	case rejected:
+		exp.Reason = "rejected by plugin " + rejectedBy
+	case gripRouted:
+		exp.Forward = true
+		exp.Reason = fmt.Sprintf("routed as GRIP %d", msg.GRIP)
+		exp.Destinations = d.Routes
+	case len(res.Destinations) > 0:
+		exp.Forward = true
+		exp.Reason = "routed by rules"
+		exp.Destinations = res.Destinations
+	case scriptRouted:
+		exp.Forward = true
+		exp.Reason = "routed by script"
+		exp.Destinations = d.Routes
+	case exp.Filter.Forward:
+		exp.Forward = true
+		exp.Reason = "accepted by the filters"
+		exp.Destinations = app.filterDestinations(exp.Filter)
+	default:
+		exp.Reason = "rejected by the filters"
+	}
+	if exp.Forward && app.paused() {
+		exp.Forward = false
+		exp.Reason += ", but sending is paused"
+	} else if m, ok := app.muted(msg, res.Matched); exp.Forward && ok {
+		exp.Forward = false
+		exp.Reason += fmt.Sprintf(", but %s %s is muted", m.Kind, m.Target)
+	}
+	exp.Message = msg
+	exp.Delivery = d
+	return exp
+}
+
+// explainFilter reports the outcome of the filters for msg, like accepts
+func (app *Application) explainFilter(msg model.Message) filter.Step {
+	if router, ok := app.filter.(*pipeline.Router); ok {
+		return router.ExplainMessage(msg)
+	}
+	return filter.Explain(app.filter, msg.Capcodes)
+}
+
+// filterDestinations returns the destinations of a message accepted by the
+// filters and not routed by rules
+func (app *Application) filterDestinations(step filter.Step) []string {
+	var names []string
+	add := func(name string) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	switch {
+	case len(app.cfg.ActivePipelines()) > 0:
+		for _, s := range step.Steps {
+			if !s.Forward {
+				continue
+			}
+			for _, pc := range app.cfg.ActivePipelines() {
+				if pc.Name == s.Name {
+					for _, name := range pc.Destinations {
+						add(name)
+					}
+				}
+			}
+		}
+	case len(app.cfg.Recipients) > 0:
+		for _, rc := range app.cfg.Recipients {
+			for _, name := range rc.Channels {
+				add(name)
+			}
+		}
+	default:
+		add(config.DefaultDestination)
+	}
+	return names
+}
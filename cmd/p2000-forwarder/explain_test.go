@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/api"
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{
+		{Name: "proef", When: `text matches "(?i)proefalarm"`, Drop: true},
+		{Name: "a1", When: `priority == "A1"`, Destinations: []string{"pager"}},
+	})
+	require.NoError(t, err)
+
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{TestAlarms: config.TestAlarmConfig{Action: config.TestAlarmDowngrade, Priority: 1}},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter([]string{"POCSAG"}, false, logger),
+		testAlarms: filter.NewTestAlarmDetector([]string{"testoproep"}, false, logger),
+		rules:      engine,
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	exp := app.explain(model.Message{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "B2 Ambulance"})
+	assert.True(t, exp.Forward)
+	assert.Equal(t, "accepted by the filters", exp.Reason)
+	assert.Equal(t, []string{"ntfy"}, exp.Destinations)
+	assert.Equal(t, filter.Step{Filter: "capcode", Forward: true}, exp.Filter)
+	require.Len(t, exp.Rules, 2)
+	assert.False(t, exp.Rules[0].Match)
+	assert.Equal(t, "B2", exp.Message.Priority)
+
+	exp = app.explain(model.Message{Type: "FLEX", Capcodes: []string{"9999999"}, Message: "A1 Brand woning"})
+	assert.True(t, exp.Forward)
+	assert.Equal(t, "routed by rules", exp.Reason)
+	assert.Equal(t, []string{"pager"}, exp.Destinations)
+	assert.False(t, exp.Filter.Forward)
+
+	exp = app.explain(model.Message{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "A1 Proefalarm"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "dropped by rule proef", exp.Reason)
+	assert.True(t, exp.Rules[1].Skipped)
+
+	exp = app.explain(model.Message{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "B2 Testoproep"})
+	assert.True(t, exp.Forward)
+	assert.Equal(t, &api.TestAlarmTrace{Detected: true, Action: config.TestAlarmDowngrade}, exp.TestAlarm)
+	assert.Equal(t, 1, exp.Delivery.PriorityOverride)
+
+	exp = app.explain(model.Message{Type: "POCSAG", Capcodes: []string{"0101001"}, Message: "B2 Ambulance"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "suppressed by message type", exp.Reason)
+
+	exp = app.explain(model.Message{Type: "FLEX", Capcodes: []string{"9999999"}, Message: "B2 Ambulance"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "rejected by the filters", exp.Reason)
+
+	// Tuesday 5 March 2024 at 20:00 in Dutch time
+	app.maintenance = newMaintenanceCalendar([]config.MaintenanceWindowConfig{
+		{Name: "oefenavond", Capcodes: []string{"0101001"}, Cron: "0 19 * * tue", Duration: "3h"},
+	}, logger)
+	exp = app.explain(model.Message{Type: "FLEX", Timestamp: 1709665200, Capcodes: []string{"0101001"}, Message: "B2 Ambulance"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "suppressed by maintenance window oefenavond", exp.Reason)
+	assert.Equal(t, &api.MaintenanceTrace{Window: "oefenavond", Action: config.MaintenanceSuppress}, exp.Maintenance)
+
+	assert.Empty(t, sender.msgs, "nothing is forwarded")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/archive"
+	"github.com/rs/zerolog"
+)
+
+const (
+	invalidFrameTimeout  = 30 * time.Second
+	invalidFrameMaxBytes = 2000 // Raw bytes included in a notification
+)
+
+// textSender sends plain notifications, such as the ntfy notifier
+type textSender interface {
+	SendText(ctx context.Context, title, message string) error
+}
+
+// invalidFrames archives and forwards the feed frames that are not valid
+// messages. At most one notification is in flight; frames arriving
+// meanwhile are only archived, so a misbehaving feed cannot flood the
+// debug topic.
+type invalidFrames struct {
+	archive *archive.Writer // Optional
+	sender  textSender      // Optional
+	busy    atomic.Bool
+	logger  zerolog.Logger
+}
+
+// handle is the invalid frame hook of the websocket client
+func (f *invalidFrames) handle(data []byte, err error) {
+	f.logger.Warn().Err(err).Int("bytes", len(data)).Msg("received invalid frame")
+
+	if f.archive != nil {
+		f.archive.Archive(data)
+	}
+	if f.sender == nil {
+		return
+	}
+	if !f.busy.CompareAndSwap(false, true) {
+		f.logger.Debug().Msg("invalid frame notification in flight, not forwarding")
+		return
+	}
+
+	raw := string(data)
+	if len(raw) > invalidFrameMaxBytes {
+		raw = raw[:invalidFrameMaxBytes] + "…"
+	}
+	message := fmt.Sprintf("%s\n\n%s", err, raw)
+	go func() {
+		defer f.busy.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), invalidFrameTimeout)
+		defer cancel()
+		if err := f.sender.SendText(ctx, "Invalid P2000 frame", message); err != nil {
+			f.logger.Error().Err(err).Msg("failed to forward invalid frame")
+		}
+	}()
+}
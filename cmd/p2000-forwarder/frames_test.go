@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTextSender records texts, blocking each send until release is
+// closed
+type blockingTextSender struct {
+	texts   chan string
+	release chan struct{}
+}
+
+func (s *blockingTextSender) SendText(ctx context.Context, title, message string) error {
+	s.texts <- title + ": " + message
+	<-s.release
+	return nil
+}
+
+func TestInvalidFrames(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := archive.New(archive.Options{Dir: dir, Logger: getTestLogger()})
+	require.NoError(t, err)
+	sender := &blockingTextSender{texts: make(chan string, 10), release: make(chan struct{})}
+	frames := &invalidFrames{archive: writer, sender: sender, logger: getTestLogger()}
+
+	frames.handle([]byte("not json"), errors.New("invalid character"))
+	select {
+	case text := <-sender.texts:
+		assert.Equal(t, "Invalid P2000 frame: invalid character\n\nnot json", text)
+	case <-time.After(time.Second):
+		t.Fatal("invalid frame not forwarded")
+	}
+
+	// Only archived while a notification is in flight
+	frames.handle([]byte(strings.Repeat("x", 3000)), errors.New("invalid character"))
+	close(sender.release)
+	assert.Eventually(t, func() bool { return !frames.busy.Load() }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, sender.texts)
+
+	require.NoError(t, writer.Close())
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+}
+
+func TestInvalidFrames_Truncated(t *testing.T) {
+	sender := &blockingTextSender{texts: make(chan string, 1), release: make(chan struct{})}
+	close(sender.release)
+	frames := &invalidFrames{sender: sender, logger: getTestLogger()}
+
+	frames.handle([]byte(strings.Repeat("x", 3000)), errors.New("invalid character"))
+	text := <-sender.texts
+	assert.Contains(t, text, strings.Repeat("x", invalidFrameMaxBytes)+"…")
+	assert.NotContains(t, text, strings.Repeat("x", invalidFrameMaxBytes+1))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// gripRefreshInterval is how often the active GRIP level is recomputed, so
+// the gauge drops back once announcements fall out of the window
+const gripRefreshInterval = time.Minute
+
+// gripTracker remembers the GRIP levels announced recently
+type gripTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   [6]time.Time // Last announcement per level
+}
+
+// newGRIPTracker creates a tracker keeping levels active for window after
+// their last announcement
+func newGRIPTracker(window time.Duration) *gripTracker {
+	return &gripTracker{window: window}
+}
+
+// Record notes an announcement of level at t
+func (g *gripTracker) Record(level int, t time.Time) {
+	if level < 1 || level >= len(g.seen) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if t.After(g.seen[level]) {
+		g.seen[level] = t
+	}
+}
+
+// Active returns the highest level announced within the window before now,
+// or 0
+func (g *gripTracker) Active(now time.Time) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for level := len(g.seen) - 1; level > 0; level-- {
+		if !g.seen[level].IsZero() && now.Sub(g.seen[level]) < g.window {
+			return level
+		}
+	}
+	return 0
+}
+
+// recordGRIP tracks the GRIP level of a message for the gauge
+func (app *Application) recordGRIP(msg model.Message, sent time.Time) {
+	if app.grip == nil || msg.GRIP == 0 {
+		return
+	}
+	app.grip.Record(msg.GRIP, sent)
+	app.metrics.SetGRIPLevel(app.grip.Active(time.Now()))
+}
+
+// refreshGRIPLevel keeps the GRIP gauge up to date until ctx is cancelled
+func (app *Application) refreshGRIPLevel(ctx context.Context) {
+	ticker := time.NewTicker(gripRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.metrics.SetGRIPLevel(app.grip.Active(time.Now()))
+		}
+	}
+}
+
+// routeGRIP sends a GRIP announcement of at least grip.min_level to the
+// GRIP destinations at the GRIP priority, reporting whether it did
+func (app *Application) routeGRIP(msg model.Message, d *notifier.Delivery) bool {
+	cfg := app.cfg.GRIP
+	if len(cfg.Destinations) == 0 || msg.GRIP == 0 || msg.GRIP < cfg.MinLevel {
+		return false
+	}
+	for _, dest := range cfg.Destinations {
+		if !slices.Contains(d.Routes, dest) {
+			d.Routes = append(d.Routes, dest)
+		}
+	}
+	if cfg.Priority > 0 {
+		d.PriorityOverride = cfg.Priority
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRIPTracker(t *testing.T) {
+	g := newGRIPTracker(time.Hour)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 0, g.Active(now))
+
+	g.Record(1, now.Add(-10*time.Minute))
+	g.Record(3, now.Add(-2*time.Hour))
+	g.Record(7, now)
+	assert.Equal(t, 1, g.Active(now), "GRIP 3 is no longer active")
+
+	g.Record(2, now)
+	assert.Equal(t, 2, g.Active(now))
+	assert.Equal(t, 0, g.Active(now.Add(time.Hour)))
+}
+
+func TestHandleMessage_GRIP(t *testing.T) {
+	logger := getTestLogger()
+	fallback := &recordingSender{name: "ntfy"}
+	grip := &recordingSender{name: "grip"}
+	call := &recordingSender{name: "call"}
+	app := &Application{
+		cfg: &config.Config{GRIP: config.GRIPConfig{
+			Destinations: []string{"grip", "call"},
+			MinLevel:     2,
+			Priority:     5,
+		}},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   rules.NewRouter(map[string]notifier.Sender{"grip": grip, "call": call}, fallback, logger),
+		grip:       newGRIPTracker(time.Hour),
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Capcodes: []string{"9999999"}, Message: "P 1 GRIP 2 Brand chemie Moerdijk"})
+	app.handleMessage(model.Message{Capcodes: []string{"0101001"}, Message: "P 1 GRIP 1 Brand industrie"})
+	app.handleMessage(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand"})
+
+	assert.Equal(t, []string{"P 1 GRIP 1 Brand industrie", "P 2 Buitenbrand"}, fallback.texts)
+	require.Len(t, grip.msgs, 1, "routed past the capcode filter")
+	assert.Equal(t, "P 1 GRIP 2 Brand chemie Moerdijk", grip.msgs[0].Message)
+	assert.Equal(t, 5, grip.deliveries[0].PriorityOverride)
+	assert.Len(t, call.msgs, 1)
+	assert.Equal(t, 2.0, testutil.ToFloat64(app.metrics.GRIPLevel))
+
+	exp := app.explain(model.Message{Capcodes: []string{"9999999"}, Message: "P 1 GRIP 3 Brand"})
+	assert.True(t, exp.Forward)
+	assert.Equal(t, "routed as GRIP 3", exp.Reason)
+	assert.Equal(t, []string{"grip", "call"}, exp.Destinations)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// importOptions holds the flags of the import subcommand, which replays
+// archive files through the pipeline instead of the live feed
+type importOptions struct {
+	files []string
+	speed float64
+	send  bool
+}
+
+// parseImportFlags parses the arguments following the import subcommand
+func parseImportFlags(args []string) (*importOptions, error) {
+	opts := &importOptions{}
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Float64Var(&opts.speed, "speed", 1, "Replay speed relative to the original timing, 0 replays as fast as possible")
+	fs.BoolVar(&opts.send, "send", false, "Send notifications instead of only logging what would be forwarded")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	opts.files = fs.Args()
+	if len(opts.files) == 0 {
+		return nil, fmt.Errorf("usage: p2000-forwarder replay [-speed N] [-send] FILE...")
+	}
+	if opts.speed < 0 {
+		return nil, fmt.Errorf("import speed must not be negative")
+	}
+	return opts, nil
+}
+
+// dryRunSender logs the messages that would have been forwarded during an
+// import without the -send flag
+type dryRunSender struct {
+	logger    zerolog.Logger
+	forwarded atomic.Int64
+}
+
+// Name identifies the dry-run destination
+func (d *dryRunSender) Name() string {
+	return "dry-run"
+}
+
+// Send logs and counts the message
+func (d *dryRunSender) Send(ctx context.Context, msg model.Message) error {
+	d.forwarded.Add(1)
+	d.logger.Info().
+		Str("agency", msg.Agency).
+		Strs("capcodes", msg.Capcodes).
+		Str("message", msg.Message).
+		Msg("would forward message")
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImportFlags(t *testing.T) {
+	opts, err := parseImportFlags([]string{"-speed", "60", "a.jsonl.gz", "b.jsonl.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, opts.speed)
+	assert.False(t, opts.send)
+	assert.Equal(t, []string{"a.jsonl.gz", "b.jsonl.gz"}, opts.files)
+
+	opts, err = parseImportFlags([]string{"-send", "a.jsonl"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, opts.speed, "original timing by default")
+	assert.True(t, opts.send)
+
+	_, err = parseImportFlags(nil)
+	assert.ErrorContains(t, err, "usage")
+
+	_, err = parseImportFlags([]string{"-speed", "-1", "a.jsonl"})
+	assert.Error(t, err)
+}
+
+func TestDryRunSender(t *testing.T) {
+	d := &dryRunSender{logger: getTestLogger()}
+	require.NoError(t, d.Send(context.Background(), model.Message{Message: "A1 Test"}))
+	require.NoError(t, d.Send(context.Background(), model.Message{Message: "A2 Test"}))
+	assert.Equal(t, int64(2), d.forwarded.Load())
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -10,11 +11,19 @@ import (
 	"testing"
 	"time"
 
-	"github.com/kaije/p2000-nfty/internal/capcode"
+	"github.com/kaije/p2000-nfty/internal/ack"
 	"github.com/kaije/p2000-nfty/internal/config"
 	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/health"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/internal/metrics"
 	"github.com/kaije/p2000-nfty/internal/notifier"
-	"github.com/kaije/p2000-nfty/internal/websocket"
+	"github.com/kaije/p2000-nfty/internal/receipt"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -69,7 +78,7 @@ func TestFilterAndNotifier_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create filter
-	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger)
 
 	// Create test server to receive notifications
 	var receivedNotifications int
@@ -88,7 +97,7 @@ func TestFilterAndNotifier_Integration(t *testing.T) {
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, lookup, logger)
 
 	// Test messages
-	messages := []websocket.P2000Message{
+	messages := []model.Message{
 		{
 			Type:     "FLEX",
 			Capcodes: []string{"0101001"},
@@ -123,10 +132,10 @@ func TestMetrics_Integration(t *testing.T) {
 	// Note: Skip metrics.NewMetrics() to avoid duplicate registration in tests
 
 	// Create filter
-	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger)
 
 	// Test messages
-	messages := []websocket.P2000Message{
+	messages := []model.Message{
 		{Capcodes: []string{"0101001"}}, // Match
 		{Capcodes: []string{"0101002"}}, // No match
 		{Capcodes: []string{"0101001"}}, // Match
@@ -157,11 +166,11 @@ func TestEndToEnd_ForwardAll(t *testing.T) {
 	defer server.Close()
 
 	// Create components with forward_all enabled
-	capcodeFilter := filter.NewCapcodeFilter(true, []string{}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(true, []string{}, false, logger)
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, nil, logger)
 
 	// Test messages
-	messages := []websocket.P2000Message{
+	messages := []model.Message{
 		{Type: "FLEX", Message: "Message 1"},
 		{Type: "FLEX", Message: "Message 2"},
 		{Type: "FLEX", Message: "Message 3"},
@@ -179,6 +188,57 @@ func TestEndToEnd_ForwardAll(t *testing.T) {
 	assert.Equal(t, 3, receivedCount)
 }
 
+func TestEndToEnd_ReceiptCancelsEscalation(t *testing.T) {
+	logger := getTestLogger()
+
+	// The topic's event stream shows the published notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": "ntfy-1", "event": "message", "time": time.Now().Unix()})
+	}))
+	defer server.Close()
+
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+	escalated := make(chan string, 1)
+	app := &Application{
+		cfg:     &config.Config{},
+		logger:  logger,
+		metrics: metrics.NewMetrics(),
+		store:   history,
+	}
+	app.status = status.NewManager(app.metrics)
+	app.acks = ack.NewTracker(history, []ack.Step{{
+		After:       time.Minute,
+		Destination: "pager",
+		Send: func(_ context.Context, msg model.Message) error {
+			escalated <- msg.ID
+			return nil
+		},
+	}}, app.metrics, logger)
+
+	receipts := receipt.NewTracker(server.URL, "test", "", "", "", time.Hour, logger)
+	receipts.OnDelivered(app.acknowledgeReceipt)
+	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, nil, logger)
+	ntfy.OnPublished(receipts.Published)
+	app.notifier = ntfy
+
+	msg := model.Message{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "A1 Brand woning"}
+	msg.ID = history.AddMessage(msg, true).ID
+	app.send(context.Background(), msg, notifier.Delivery{})
+	require.Equal(t, 1, app.acks.Pending(), "waiting for an acknowledgement")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go receipts.Run(ctx)
+	require.Eventually(t, func() bool { return app.acks.Pending() == 0 }, time.Second, 5*time.Millisecond)
+
+	record, ok := history.Message(msg.ID)
+	require.True(t, ok)
+	require.Len(t, record.Acknowledgements, 1)
+	assert.Equal(t, ack.ReceiptAuthor, record.Acknowledgements[0].Author)
+	assert.Empty(t, escalated)
+}
+
 func TestEndToEnd_WithRetry(t *testing.T) {
 	logger := getTestLogger()
 
@@ -195,7 +255,7 @@ func TestEndToEnd_WithRetry(t *testing.T) {
 
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -217,7 +277,7 @@ func TestEndToEnd_WithAuthentication(t *testing.T) {
 
 	ntfy := notifier.NewNotifier(server.URL, "test", "my-token", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -251,10 +311,10 @@ func TestEndToEnd_MultipleCapcodes(t *testing.T) {
 	}))
 	defer server.Close()
 
-	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, false, logger)
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, lookup, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:     "FLEX",
 		Capcodes: []string{"0101001", "0101002", "0101003"},
 		Message:  "Multi-unit response",
@@ -299,6 +359,152 @@ func TestHealthEndpoint_Integration(t *testing.T) {
 	assert.Contains(t, body, "disconnected")
 }
 
+func TestReadinessHandler_CapcodeLookup(t *testing.T) {
+	app := &Application{
+		cfg:      &config.Config{},
+		logger:   getTestLogger(),
+		metrics:  metrics.NewMetrics(),
+		backends: health.NewBackends(),
+	}
+	app.status = status.NewManager(app.metrics)
+	app.status.SetConnected(true)
+	app.translator, _ = i18n.New("en")
+
+	ready := func() (int, string) {
+		rec := httptest.NewRecorder()
+		app.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	app.status.SetCapcodesAvailable(false)
+	status, body := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "capcode lookup unavailable")
+
+	app.status.SetCapcodesAvailable(true)
+	status, _ = ready()
+	assert.Equal(t, http.StatusOK, status)
+
+	app.status.SetConnected(false)
+	status, body = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "websocket disconnected")
+}
+
+func TestSelfHeal(t *testing.T) {
+	app := &Application{
+		cfg:     &config.Config{Server: config.ServerConfig{HealthWindow: 0}},
+		logger:  getTestLogger(),
+		metrics: metrics.NewMetrics(),
+	}
+	app.status = status.NewManager(app.metrics)
+	time.Sleep(time.Millisecond)
+
+	restarts := make(chan struct{}, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	app.selfHeal(ctx, "websocket", func() { restarts <- struct{}{} }, 5*time.Millisecond, time.Hour)
+
+	// The stale source is restarted once within the cooldown
+	assert.Len(t, restarts, 1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.SelfHeals.WithLabelValues("websocket")))
+}
+
+func TestSelfHeal_Alive(t *testing.T) {
+	app := &Application{
+		cfg:     &config.Config{Server: config.ServerConfig{HealthWindow: 300}},
+		logger:  getTestLogger(),
+		metrics: metrics.NewMetrics(),
+	}
+	app.status = status.NewManager(app.metrics)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	app.selfHeal(ctx, "decoder", func() { t.Error("source restarted while messages arrive") }, 5*time.Millisecond, 0)
+}
+
+func TestHealthHandlers_JSON(t *testing.T) {
+	app := &Application{
+		cfg:      &config.Config{Server: config.ServerConfig{HealthWindow: 0}},
+		logger:   getTestLogger(),
+		metrics:  metrics.NewMetrics(),
+		backends: health.NewBackends(),
+	}
+	app.status = status.NewManager(app.metrics)
+	app.status.SetConnected(true)
+	app.status.SetCapcodesAvailable(true)
+	app.translator, _ = i18n.New("en")
+	time.Sleep(time.Millisecond)
+	app.backends.Record(notifier.DeliveryResult{Destination: "https://ntfy.sh/p2000", Success: true, Attempts: 1})
+
+	get := func(handler http.HandlerFunc) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec.Code, report
+	}
+
+	status, report := get(app.livenessHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "no messages received in 0s", report.Checks[0].Message)
+	assert.Greater(t, report.LastMessageAge, 0.0)
+
+	status, report = get(app.readinessHandler)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	require.Len(t, report.Backends, 1)
+	assert.True(t, report.Backends[0].Healthy)
+	require.NotNil(t, report.LastNotification)
+	assert.True(t, report.LastNotification.Success)
+
+	status, report = get(app.healthCheckHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Len(t, report.Checks, 3)
+
+	app.cfg.Server.HealthWindow = 60
+	app.status.MessageReceived()
+	status, _ = get(app.healthCheckHandler)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestRetryCapcodeLookup(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "capcodes.csv")
+	app := &Application{
+		cfg:     &config.Config{CapcodeCSVPath: csvPath},
+		logger:  getTestLogger(),
+		metrics: metrics.NewMetrics(),
+	}
+	app.status = status.NewManager(app.metrics)
+
+	lookup := capcode.NewLookupFromRecords(nil)
+	require.Error(t, loadCapcodeLookup(context.Background(), app.cfg, lookup, app.logger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		app.retryCapcodeLookup(ctx, lookup, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.NoError(t, os.WriteFile(csvPath, []byte("0101001;Brandweer;Utrecht;Station;Alarm\n"), 0644))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("capcode lookup was not loaded")
+	}
+
+	assert.True(t, app.status.CapcodesAvailable())
+	require.NotNil(t, lookup.Get("0101001"))
+	assert.Equal(t, "Brandweer", lookup.Get("0101001").Agency)
+}
+
 func TestMessageFlow_Complete(t *testing.T) {
 	logger := getTestLogger()
 
@@ -313,7 +519,7 @@ func TestMessageFlow_Complete(t *testing.T) {
 	lookup, err := capcode.NewLookup(csvPath)
 	require.NoError(t, err)
 
-	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger)
 
 	var notifications []string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -326,7 +532,7 @@ func TestMessageFlow_Complete(t *testing.T) {
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, lookup, logger)
 
 	// Simulate message flow
-	messages := []websocket.P2000Message{
+	messages := []model.Message{
 		{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "Brand"},
 		{Type: "FLEX", Capcodes: []string{"9999999"}, Message: "Other"},
 		{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "Brand 2"},
@@ -388,7 +594,7 @@ func TestConcurrentMessageProcessing(t *testing.T) {
 	defer server.Close()
 
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, nil, logger)
-	capcodeFilter := filter.NewCapcodeFilter(true, []string{}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(true, []string{}, false, logger)
 
 	// Process multiple messages concurrently
 	numMessages := 10
@@ -397,7 +603,7 @@ func TestConcurrentMessageProcessing(t *testing.T) {
 
 	for i := 0; i < numMessages; i++ {
 		go func(id int) {
-			msg := websocket.P2000Message{
+			msg := model.Message{
 				Type:    "FLEX",
 				Message: "Concurrent test",
 			}
@@ -424,10 +630,10 @@ func BenchmarkCompleteMessageFlow(b *testing.B) {
 	}))
 	defer server.Close()
 
-	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, logger)
+	capcodeFilter := filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger)
 	ntfy := notifier.NewNotifier(server.URL, "test", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:     "FLEX",
 		Capcodes: []string{"0101001"},
 		Message:  "Test",
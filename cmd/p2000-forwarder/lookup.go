@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// routeExplainer shows how the configuration filters and routes pages to a
+// capcode, to debug why a unit's pages are or are not forwarded
+type routeExplainer struct {
+	cfg       *config.Config
+	lookup    *capcode.Lookup
+	filter    filter.Filter
+	pipelines map[string]filter.Filter
+	rules     *rules.Engine
+}
+
+// newRouteExplainer builds the filters and rules of cfg like the forwarder
+func newRouteExplainer(cfg *config.Config, lookup *capcode.Lookup, logger zerolog.Logger) (*routeExplainer, error) {
+	e := &routeExplainer{cfg: cfg, lookup: lookup}
+
+	var err error
+	pipelines := cfg.ActivePipelines()
+	if len(pipelines) == 0 {
+		if e.filter, err = newFilter(cfg.DefaultPipeline(), cfg.DisciplineRanges, cfg.LenientCapcodes(), lookup, logger); err != nil {
+			return nil, err
+		}
+	}
+	e.pipelines = make(map[string]filter.Filter, len(pipelines))
+	for _, pc := range pipelines {
+		if e.pipelines[pc.Name], err = newFilter(pc, cfg.DisciplineRanges, cfg.LenientCapcodes(), lookup, logger); err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", pc.Name, err)
+		}
+	}
+	if len(cfg.Rules) > 0 {
+		if e.rules, err = newRules(cfg.Rules); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// explain writes the filter, pipeline, rule and override outcome for a page
+// to code. Rules are evaluated on a page without text, so conditions on the
+// text or priority only match when negated, and pipelines with a pattern are
+// marked as depending on the text.
+func (e *routeExplainer) explain(w io.Writer, code string) {
+	msg := model.Message{Capcodes: []string{code}}
+	msg.Enrich(e.lookup)
+
+	fmt.Fprintln(w, code)
+
+	accepted := false
+	if e.filter != nil {
+		accepted = e.filter.ShouldForward(msg.Capcodes)
+		line(w, "filter", outcome(accepted, "forwarded", "not forwarded"))
+	} else {
+		var names []string
+		for _, pc := range e.cfg.ActivePipelines() {
+			if !e.pipelines[pc.Name].ShouldForward(msg.Capcodes) {
+				continue
+			}
+			if pc.Pattern != "" {
+				names = append(names, fmt.Sprintf("%s (text matching %s)", pc.Name, pc.Pattern))
+			} else {
+				names = append(names, pc.Name)
+			}
+		}
+		accepted = len(names) > 0
+		line(w, "pipelines", listOrNone(names))
+	}
+
+	var res rules.Result
+	if e.rules != nil {
+		res = e.rules.Evaluate(msg)
+		matched := slices.Clone(res.Matched)
+		if res.Drop {
+			matched[len(matched)-1] += " (drop)"
+		}
+		line(w, "rules", listOrNone(matched))
+	}
+
+	if o, ok := e.override(code); ok {
+		var details []string
+		if o.Name != "" {
+			details = append(details, o.Name)
+		}
+		if o.Tags != "" {
+			details = append(details, "tags "+o.Tags)
+		}
+		if o.Priority > 0 {
+			details = append(details, fmt.Sprintf("priority %d", o.Priority))
+		}
+		if o.PriorityBump > 0 {
+			details = append(details, fmt.Sprintf("priority +%d", o.PriorityBump))
+		}
+		line(w, "override", strings.Join(details, ", "))
+	}
+
+	switch {
+	case res.Drop:
+		line(w, "result", "dropped by rule "+res.Matched[len(res.Matched)-1])
+	case len(res.Destinations) > 0:
+		line(w, "result", "routed to "+strings.Join(res.Destinations, ", "))
+	default:
+		line(w, "result", outcome(accepted, "forwarded", "not forwarded"))
+	}
+}
+
+// override returns the capcode override of code, ignoring leading zeros.
+// As in the notifier, the override of the capcode takes precedence over
+// those of prefixes and ranges.
+func (e *routeExplainer) override(code string) (config.CapcodeOverrideConfig, bool) {
+	normalized := capcode.Normalize(code)
+	for key, o := range e.cfg.CapcodeOverrides {
+		if !filter.IsCapcodePattern(key) && capcode.Normalize(key) == normalized {
+			return o, true
+		}
+	}
+	patterns := slices.Sorted(maps.Keys(e.cfg.CapcodeOverrides))
+	for _, key := range patterns {
+		if filter.IsCapcodePattern(key) && filter.NewMatcher([]string{key}, true).Match(code) {
+			return e.cfg.CapcodeOverrides[key], true
+		}
+	}
+	for key, name := range e.cfg.CapcodeTranslations {
+		if capcode.Normalize(key) == normalized {
+			return config.CapcodeOverrideConfig{Name: name}, true
+		}
+	}
+	return config.CapcodeOverrideConfig{}, false
+}
+
+// line writes an indented label and value of an explanation
+func line(w io.Writer, label, value string) {
+	fmt.Fprintf(w, "  %-11s%s\n", label+":", value)
+}
+
+func outcome(ok bool, yes, no string) string {
+	if ok {
+		return yes
+	}
+	return no
+}
+
+func listOrNone(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteExplainer(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht"},
+		{Capcode: "1420059", Agency: "Ambulance", Region: "Utrecht"},
+	})
+	cfg := &config.Config{
+		Capcodes:         []string{"0101001"},
+		Regions:          []string{"Utrecht"},
+		ExcludeCapcodes:  []string{"1420059"},
+		CapcodeOverrides: map[string]config.CapcodeOverrideConfig{"101001": {Name: "Kazerne", PriorityBump: 1}},
+		Rules: []config.RuleConfig{
+			{Name: "ambulance", When: `"ambulance" in disciplines`, Destinations: []string{"pager"}},
+			{Name: "proef", When: `capcodes contains "0202002"`, Drop: true},
+		},
+	}
+
+	e, err := newRouteExplainer(cfg, lookup, getTestLogger())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	e.explain(&out, "0101001")
+	assert.Equal(t, "0101001\n"+
+		"  filter:    forwarded\n"+
+		"  rules:     none\n"+
+		"  override:  Kazerne, priority +1\n"+
+		"  result:    forwarded\n", out.String())
+
+	out.Reset()
+	e.explain(&out, "1420059")
+	assert.Contains(t, out.String(), "filter:    not forwarded")
+	assert.Contains(t, out.String(), "rules:     ambulance")
+	assert.Contains(t, out.String(), "result:    routed to pager")
+
+	out.Reset()
+	e.explain(&out, "0202002")
+	assert.Contains(t, out.String(), "rules:     proef (drop)")
+	assert.Contains(t, out.String(), "result:    dropped by rule proef")
+
+	cfg = &config.Config{Pipelines: []config.PipelineConfig{
+		{Name: "brandweer", Capcodes: []string{"0101001"}},
+		{Name: "all", ForwardAll: true},
+	}}
+	e, err = newRouteExplainer(cfg, lookup, getTestLogger())
+	require.NoError(t, err)
+
+	out.Reset()
+	e.explain(&out, "0101001")
+	assert.Contains(t, out.String(), "pipelines: brandweer, all")
+	assert.NotContains(t, out.String(), "rules:")
+}
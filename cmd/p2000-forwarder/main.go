@@ -2,52 +2,158 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/kaije/p2000-nfty/internal/capcode"
+	"github.com/kaije/p2000-nfty/internal/ack"
+	"github.com/kaije/p2000-nfty/internal/api"
+	"github.com/kaije/p2000-nfty/internal/archive"
+	"github.com/kaije/p2000-nfty/internal/chaos"
 	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/dispatch"
+	"github.com/kaije/p2000-nfty/internal/elastic"
+	"github.com/kaije/p2000-nfty/internal/enrich"
 	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/geocode"
+	"github.com/kaije/p2000-nfty/internal/ha"
+	"github.com/kaije/p2000-nfty/internal/health"
+	"github.com/kaije/p2000-nfty/internal/httpauth"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/internal/incident"
+	"github.com/kaije/p2000-nfty/internal/influx"
+	"github.com/kaije/p2000-nfty/internal/maintenance"
 	"github.com/kaije/p2000-nfty/internal/metrics"
 	"github.com/kaije/p2000-nfty/internal/notifier"
-	"github.com/kaije/p2000-nfty/internal/websocket"
+	"github.com/kaije/p2000-nfty/internal/pipeline"
+	"github.com/kaije/p2000-nfty/internal/plugins"
+	"github.com/kaije/p2000-nfty/internal/postgres"
+	"github.com/kaije/p2000-nfty/internal/proxy"
+	"github.com/kaije/p2000-nfty/internal/receipt"
+	"github.com/kaije/p2000-nfty/internal/report"
+	"github.com/kaije/p2000-nfty/internal/rpc"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/script"
+	"github.com/kaije/p2000-nfty/internal/service"
+	"github.com/kaije/p2000-nfty/internal/source"
+	"github.com/kaije/p2000-nfty/internal/stats"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/internal/stream"
+	"github.com/kaije/p2000-nfty/internal/subscription"
+	"github.com/kaije/p2000-nfty/internal/tlsconfig"
+	"github.com/kaije/p2000-nfty/internal/translate"
+	"github.com/kaije/p2000-nfty/internal/weather"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/websocket"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 const (
-	healthCheckWindow = 5 * time.Minute
+	storeSaveInterval = 30 * time.Second
+	geocodeTimeout    = 5 * time.Second
+	translateTimeout  = 5 * time.Second
+	enrichTimeout     = 5 * time.Second
+	selfHealInterval  = 30 * time.Second
 )
 
 type Application struct {
-	cfg        *config.Config
-	logger     zerolog.Logger
-	metrics    *metrics.Metrics
-	wsClient   *websocket.Client
-	filter     *filter.CapcodeFilter
-	notifier   *notifier.Notifier
-	httpServer *http.Server
-	lastMsg    time.Time
-	wsConnected bool
+	cfg         *config.Config
+	logger      zerolog.Logger
+	metrics     *metrics.Metrics
+	wsClient    *websocket.Client
+	filter      filter.Filter
+	typeFilter  *filter.TypeFilter
+	plugins     []plugins.Filter // Filter plugins a message must pass as well
+	script      *script.Script   // Message script run after the rules, nil when disabled
+	testAlarms  *filter.TestAlarmDetector
+	reanimation *filter.ReanimationDetector
+	maintenance *maintenance.Calendar
+	grip        *gripTracker
+	duplicates  *duplicateTracker   // IDs of recent messages, nil when disabled
+	shard       *filter.ShardFilter // Messages of other shards are left to their instances
+	threads     *incident.Correlator
+	batcher     *incident.Batcher // Combines the frames of an incident burst, nil when disabled
+	notifier    notifier.Sender
+	buffer      *dispatch.Buffer // Received messages waiting for handleMessage, nil when disabled
+	dispatcher  *dispatch.Dispatcher
+	httpServer  *http.Server
+	apiServer   *api.Server
+	rpc         *rpc.Server  // gRPC API, nil when disabled
+	grpcServer  *grpc.Server // Serves rpc
+	store       *store.Store
+	stats       *report.Collector
+	aggregates  *stats.Aggregator
+	translator  *i18n.Translator
+	capcodes    *capcode.Lookup
+	backends    *health.Backends
+	status      *status.Manager
+	acks        *ack.Tracker
+	geocoder    *geocode.Geocoder
+	bag         *geocode.BAG          // Local addresses, tried before the geocoder
+	mt          *translate.Translator // Machine translation of the message text, nil when disabled
+	enrichers   *enrich.Chain         // Context added after geocoding
+	archive     *archive.Writer
+	rules       *rules.Engine
+	stream      *stream.Publisher
+	postgres    *postgres.Sink
+	influx      *influx.Writer
+	elastic     *elastic.Indexer
+	dedup       *ha.Deduplicator // Claims messages shared with redundant instances
+	direct      bool             // Send without queueing, so replayed messages are not dropped
+
+	subscriptions   *subscription.Store
+	subscribers     *subscription.Sender
+	subscriberQueue *dispatch.Dispatcher // Subscriptions are notified apart from the main queue
 }
 
 func main() {
-	// Setup structured logging
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// newLogger creates the console logger used by all commands
+func newLogger() zerolog.Logger {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	logger := log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339})
+	return log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339})
+}
 
-	// Load configuration
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config.yaml"
+// configPath returns the configuration file from CONFIG_PATH, config.yaml
+// by default
+func configPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return "config.yaml"
+}
+
+// serve runs the forwarder until it receives a shutdown signal or stop is
+// closed by the Windows service manager. With replay set, archive files are
+// imported instead of the live feed and serve returns once they are
+// replayed. It returns the process exit code, 1 when the websocket client
+// gave up reconnecting.
+func serve(chaosCfg *chaos.Config, replay *importOptions, stop <-chan struct{}) int {
+	// Setup structured logging
+	logger := newLogger()
+
+	if err := chaosCfg.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("refusing to start with chaos flags")
 	}
+	chaosCfg.LogEnabled(logger)
 
-	cfg, err := config.Load(configPath)
+	// Load configuration
+	cfg, err := config.Load(configPath())
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to load configuration")
 	}
@@ -57,71 +163,616 @@ func main() {
 		Str("ntfy_topic", cfg.Ntfy.Topic).
 		Bool("forward_all", cfg.ForwardAll).
 		Int("capcodes", len(cfg.Capcodes)).
+		Strs("regions", cfg.Regions).
 		Msg("configuration loaded")
 
-	// Initialize capcode lookup
-	var capcodeLookup *capcode.Lookup
-	if cfg.CapcodeCSVPath != "" {
-		lookup, err := capcode.NewLookup(cfg.CapcodeCSVPath)
-		if err != nil {
-			logger.Warn().
-				Err(err).
-				Str("csv_path", cfg.CapcodeCSVPath).
-				Msg("failed to load capcode CSV, continuing without lookup")
-		} else {
-			capcodeLookup = lookup
-			logger.Info().
-				Str("csv_path", cfg.CapcodeCSVPath).
-				Msg("capcode lookup loaded successfully")
+	if err := useProxy(cfg); err != nil {
+		logger.Fatal().Err(err).Msg("invalid proxy")
+	}
+
+	// Imports do not touch the message history on disk and, unless sending,
+	// nothing is published
+	if replay != nil {
+		cfg.Store.Path = ""
+		if !replay.send {
+			cfg.Report.Interval = 0
+			cfg.Stats.Summary = ""
+			cfg.Ntfy.Receipts.Enabled = false
+			cfg.Subscriptions.Enabled = false
 		}
 	}
 
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Initialize application
 	app := &Application{
 		cfg:     cfg,
 		logger:  logger,
 		metrics: metrics.NewMetrics(),
-		lastMsg: time.Now(),
+	}
+	app.status = status.NewManager(app.metrics)
+
+	// Initialize capcode lookup. On failure messages are forwarded without
+	// capcode details while the load is retried, unless strict mode is on.
+	var capcodeLookup *capcode.Lookup
+	capcodesLoaded := true
+	if cfg.CapcodeCSVPath != "" {
+		capcodeLookup = capcode.NewLookupFromRecords(nil)
+		if err := loadCapcodeLookup(ctx, cfg, capcodeLookup, logger); err != nil {
+			if cfg.CapcodeStrict {
+				logger.Fatal().Err(err).Str("csv_path", cfg.CapcodeCSVPath).Msg("failed to load capcode database")
+			}
+			logger.Warn().
+				Err(err).
+				Str("csv_path", cfg.CapcodeCSVPath).
+				Msg("failed to load capcode database, continuing without lookup")
+			capcodesLoaded = false
+			if cfg.CapcodeRetry > 0 {
+				go app.retryCapcodeLookup(ctx, capcodeLookup, time.Duration(cfg.CapcodeRetry)*time.Second)
+			}
+		}
+	}
+	app.capcodes = capcodeLookup
+	app.status.SetCapcodesAvailable(capcodesLoaded)
+
+	// Initialize translations
+	app.translator, err = i18n.New(cfg.Language)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load translations")
+	}
+
+	// Initialize message store
+	app.store, err = store.Open(cfg.Store.Path, cfg.Store.MaxMessages, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Str("path", cfg.Store.Path).Msg("failed to open message store")
+	}
+	go app.store.Run(ctx, storeSaveInterval)
+	if p, ok := app.store.Paused(); ok {
+		logger.Warn().
+			Time("since", p.Since).
+			Str("reason", p.Reason).
+			Msg("notification sending is paused, resume with POST /api/admin/resume")
 	}
 
 	// Initialize filter
-	app.filter = filter.NewCapcodeFilter(cfg.ForwardAll, cfg.Capcodes, logger)
+	app.filter, err = newFilter(cfg.DefaultPipeline(), cfg.DisciplineRanges, cfg.LenientCapcodes(), capcodeLookup, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid discipline filter")
+	}
+
+	var suppressedTypes []string
+	for name, mt := range cfg.MessageTypes {
+		if mt.Suppress {
+			suppressedTypes = append(suppressedTypes, name)
+		}
+	}
+	app.typeFilter = filter.NewTypeFilter(suppressedTypes, cfg.SkipNumeric, logger)
+	for _, pc := range cfg.Plugins {
+		if pc.Type != config.PluginFilter {
+			continue
+		}
+		f, err := plugins.LoadFilter(pc.Name, pc.Path, pc.Config)
+		if err != nil {
+			logger.Fatal().Err(err).Str("plugin", pc.Name).Msg("failed to load filter plugin")
+		}
+		app.plugins = append(app.plugins, f)
+		logger.Info().Str("plugin", pc.Name).Str("path", pc.Path).Msg("filter plugin loaded")
+	}
+	if cfg.TestAlarms.Action != "" && cfg.TestAlarms.Action != config.TestAlarmOff {
+		app.testAlarms = filter.NewTestAlarmDetector(cfg.TestAlarms.Keywords, cfg.TestAlarms.Schedule, logger)
+	}
+	if cfg.Reanimation.Enabled {
+		app.reanimation = filter.NewReanimationDetector(cfg.Reanimation.Keywords, logger)
+	}
+	if len(cfg.MaintenanceWindows) > 0 {
+		app.maintenance = newMaintenanceCalendar(cfg.MaintenanceWindows, logger)
+	}
+	app.grip = newGRIPTracker(time.Duration(cfg.GRIP.Window) * time.Second)
+	if cfg.DuplicateWindow > 0 {
+		app.duplicates = newDuplicateTracker(time.Duration(cfg.DuplicateWindow) * time.Second)
+	}
+	go app.refreshGRIPLevel(ctx)
+	// Imported archives are processed whole, regardless of the shard
+	if cfg.Shard.Count > 1 && replay == nil {
+		app.shard, err = filter.NewShardFilter(cfg.Shard.Index, cfg.Shard.Count, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid shard")
+		}
+	}
+	if cfg.Threads.Enabled {
+		app.threads = incident.NewCorrelator(time.Duration(cfg.Threads.Window) * time.Second)
+	}
+	// Replayed messages are sent right away
+	if cfg.Batching.Enabled && replay == nil {
+		app.batcher = incident.NewBatcher(time.Duration(cfg.Batching.Window)*time.Second, app.flushBatch)
+	}
+
+	// Initialize delivery receipts
+	var receipts *receipt.Tracker
+	var onPublished notifier.PublishHook
+	if cfg.Ntfy.Receipts.Enabled {
+		receipts = receipt.NewTracker(
+			cfg.Ntfy.Server,
+			cfg.Ntfy.Topic,
+			cfg.Ntfy.Token,
+			cfg.Ntfy.Username,
+			cfg.Ntfy.Password,
+			time.Duration(cfg.Ntfy.Receipts.PollInterval)*time.Second,
+			logger,
+		)
+		onPublished = receipts.Published
+		go receipts.Run(ctx)
+	}
+
+	// Track delivery outcomes for health checks
+	app.backends = health.NewBackends()
+	onDelivery := []notifier.DeliveryHook{app.backends.Record, app.recordDelivery}
+
+	// Reports, summaries and invalid frames use the proxy, TLS settings and
+	// connection pool of their destination, but are not counted as
+	// deliveries
+	newInternal, err := newNtfyFactory(cfg, capcodeLookup, app.translator, nil, nil, chaosCfg.Transport, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create notifier")
+	}
+
+	// Reports and summaries are sent to the default ntfy topic
+	var reportNotifier *notifier.Notifier
+	if cfg.Report.Interval > 0 || cfg.Stats.Summary != "" {
+		reportNotifier, err = newInternal(config.DefaultDestination, cfg.Ntfy, cfg.Templates)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create report notifier")
+		}
+	}
+
+	// Initialize periodic report
+	if cfg.Report.Interval > 0 {
+		interval := time.Duration(cfg.Report.Interval) * time.Second
+		app.stats = report.NewCollector(interval)
+		onDelivery = append(onDelivery, app.stats.RecordDelivery)
+		go report.NewReporter(app.stats, reportNotifier, interval, app.translator, logger).Run(ctx)
+	}
+
+	// Initialize statistics, starting from the message history
+	if cfg.Stats.Retention > 0 {
+		app.aggregates = stats.NewAggregator(time.Duration(cfg.Stats.Retention) * 24 * time.Hour)
+		for _, r := range app.store.Messages(0) {
+			app.aggregates.Record(r.Message, r.ReceivedAt, r.Forwarded)
+		}
+	}
+	if cfg.Stats.Summary != "" && app.aggregates != nil {
+		at, _ := stats.ParseTime(cfg.Stats.Time)
+		// Days follow Dutch time, like the feed
+		location, err := time.LoadLocation("Europe/Amsterdam")
+		if err != nil {
+			logger.Warn().Err(err).Msg("timezone data unavailable, using local time for the statistics summary")
+			location = time.Local
+		}
+		go stats.NewSummarizer(app.aggregates, reportNotifier, cfg.Stats.Summary, at, location, app.translator, logger).Run(ctx)
+	}
+
+	// Initialize geocoding of incident addresses
+	if cfg.Geocoding.Provider != "" {
+		app.geocoder, err = geocode.New(geocode.Options{
+			Provider:  cfg.Geocoding.Provider,
+			URL:       cfg.Geocoding.URL,
+			UserAgent: cfg.Geocoding.UserAgent,
+			RateLimit: cfg.Geocoding.RateLimit,
+			CacheSize: cfg.Geocoding.CacheSize,
+			CacheTTL:  time.Duration(cfg.Geocoding.CacheTTL) * time.Second,
+			Metrics:   app.metrics,
+			Logger:    logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create geocoder")
+		}
+	}
+
+	// Load the local BAG extract verifying postcodes and house numbers
+	if cfg.Geocoding.BAG != "" {
+		app.bag, err = geocode.LoadBAG(cfg.Geocoding.BAG)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to load BAG extract")
+		}
+		logger.Info().Int("addresses", app.bag.Len()).Msg("loaded BAG extract")
+	}
+
+	// Add context to the messages after geocoding, such as the weather of
+	// storm damage and nature fire calls
+	app.enrichers = enrich.NewChain(enrichTimeout, app.metrics, logger)
+	if cfg.Weather.Provider != "" {
+		provider, err := weather.New(weather.Options{
+			Provider: cfg.Weather.Provider,
+			URL:      cfg.Weather.URL,
+			APIKey:   cfg.Weather.APIKey,
+			Keywords: cfg.Weather.Keywords,
+			CacheTTL: time.Duration(cfg.Weather.CacheTTL) * time.Second,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create weather provider")
+		}
+		app.enrichers.Add(provider)
+	}
+
+	// Initialize the machine translation of the message text
+	if cfg.Translation.Provider != "" {
+		app.mt, err = translate.New(translate.Options{
+			Provider:  cfg.Translation.Provider,
+			URL:       cfg.Translation.URL,
+			APIKey:    cfg.Translation.APIKey,
+			Source:    cfg.Translation.Source,
+			Target:    cfg.Translation.Target,
+			CacheSize: cfg.Translation.CacheSize,
+			CacheTTL:  time.Duration(cfg.Translation.CacheTTL) * time.Second,
+			Metrics:   app.metrics,
+			Logger:    logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create translator")
+		}
+	}
+
+	// Publish the enriched feed for downstream analytics
+	if cfg.Stream.URL != "" {
+		app.stream, err = stream.New(stream.Options{
+			URL:      cfg.Stream.URL,
+			Subject:  cfg.Stream.Subject,
+			Token:    cfg.Stream.Token,
+			Username: cfg.Stream.Username,
+			Password: cfg.Stream.Password,
+			Buffer:   cfg.Stream.Buffer,
+			Metrics:  app.metrics,
+			Logger:   logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create stream publisher")
+		}
+		go app.stream.Run(ctx)
+	}
+
+	// Share the message history with other instances
+	if cfg.Postgres.DSN != "" {
+		openCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		app.postgres, err = postgres.Open(openCtx, postgres.Options{
+			DSN:      cfg.Postgres.DSN,
+			Instance: cfg.Postgres.Instance,
+			Buffer:   cfg.Postgres.Buffer,
+			Metrics:  app.metrics,
+			Logger:   logger,
+		})
+		cancel()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to open postgres database")
+		}
+		go app.postgres.Run(ctx)
+		// Imported archives are notified by the importing instance only
+		if cfg.HA.Enabled && replay == nil {
+			app.dedup = ha.New(app.postgres, app.metrics, logger)
+			logger.Info().Msg("high-availability mode, notifying the messages claimed by this instance")
+		}
+	}
+
+	// Write a time series of the messages for long-term analysis
+	if cfg.Influx.URL != "" {
+		app.influx, err = influx.New(influx.Options{
+			URL:           cfg.Influx.URL,
+			Org:           cfg.Influx.Org,
+			Bucket:        cfg.Influx.Bucket,
+			Token:         cfg.Influx.Token,
+			Measurement:   cfg.Influx.Measurement,
+			FlushInterval: time.Duration(cfg.Influx.FlushInterval) * time.Second,
+			Buffer:        cfg.Influx.Buffer,
+			Metrics:       app.metrics,
+			Logger:        logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create influx writer")
+		}
+		go app.influx.Run(ctx)
+	}
+
+	// Index the messages for full-text search
+	if cfg.Elasticsearch.URL != "" {
+		app.elastic, err = elastic.New(elastic.Options{
+			URL:           cfg.Elasticsearch.URL,
+			Index:         cfg.Elasticsearch.Index,
+			Username:      cfg.Elasticsearch.Username,
+			Password:      cfg.Elasticsearch.Password,
+			APIKey:        cfg.Elasticsearch.APIKey,
+			FlushInterval: time.Duration(cfg.Elasticsearch.FlushInterval) * time.Second,
+			Buffer:        cfg.Elasticsearch.Buffer,
+			Metrics:       app.metrics,
+			Logger:        logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create elasticsearch indexer")
+		}
+		go app.elastic.Run(ctx)
+	}
 
 	// Initialize notifier
-	app.notifier = notifier.NewNotifier(
-		cfg.Ntfy.Server,
-		cfg.Ntfy.Topic,
-		cfg.Ntfy.Token,
-		cfg.Ntfy.Username,
-		cfg.Ntfy.Password,
-		cfg.CapcodeTranslations,
-		capcodeLookup,
-		logger,
-	)
-
-	// Initialize WebSocket client
-	app.wsClient = websocket.NewClient(logger, app.handleMessage)
+	onBreakerChange := func(destination string, state notifier.BreakerState) {
+		app.status.SetBreakerState(destination, state)
+		if state != notifier.BreakerClosed {
+			logger.Warn().
+				Str("destination", destination).
+				Stringer("state", state).
+				Msg("circuit breaker state changed")
+		}
+	}
+	var destinations map[string]notifier.Sender
+	app.notifier, destinations, err = newSender(cfg, capcodeLookup, app.translator, onPublished, onDelivery, onBreakerChange, chaosCfg.Transport, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create notifier")
+	}
+	// Pipelines filter each message themselves
+	if router, ok := app.notifier.(*pipeline.Router); ok {
+		app.filter = router
+	}
+
+	// Routing rules and GRIP announcements may send messages to other
+	// destinations
+	if len(cfg.Rules) > 0 {
+		app.rules, err = newRules(cfg.Rules)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid routing rules")
+		}
+	}
+	if cfg.Script.Path != "" {
+		app.script, err = script.Load(cfg.Script.Path, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid message script")
+		}
+		logger.Info().Str("path", cfg.Script.Path).Msg("message script loaded")
+	}
+	if len(cfg.Rules) > 0 || len(cfg.GRIP.Destinations) > 0 {
+		app.notifier = rules.NewRouter(destinations, app.notifier, logger)
+	}
+	var dryRun *dryRunSender
+	if replay != nil && !replay.send {
+		dryRun = &dryRunSender{logger: logger}
+		app.notifier = dryRun
+		for name := range destinations {
+			destinations[name] = dryRun
+		}
+	}
+
+	// Initialize acknowledgement tracking and escalation
+	steps := make([]ack.Step, 0, len(cfg.Escalation.Steps))
+	for _, step := range cfg.Escalation.Steps {
+		steps = append(steps, ack.Step{
+			After:       time.Duration(step.After) * time.Second,
+			Destination: step.Destination,
+			Send:        destinations[step.Destination].Send,
+		})
+	}
+	app.acks = ack.NewTracker(app.store, steps, app.metrics, logger)
+	go app.acks.Run(ctx)
+	if receipts != nil {
+		receipts.OnDelivered(app.acknowledgeReceipt)
+	}
+	app.dispatcher = dispatch.New(app.send, cfg.Queue.Workers, cfg.Queue.Size, app.metrics, logger)
+
+	// Initialize self-service subscriptions, each notified on its own topic
+	// of the default ntfy server
+	if cfg.Subscriptions.Enabled {
+		app.subscriptions, err = subscription.Open(cfg.Subscriptions.Path, cfg.Subscriptions.Max)
+		if err != nil {
+			logger.Fatal().Err(err).Str("path", cfg.Subscriptions.Path).Msg("failed to open subscriptions")
+		}
+		app.subscriptions.OnChange(app.metrics.SetSubscriptions)
+		app.metrics.SetSubscriptions(app.subscriptions.Len())
+
+		newNtfy, err := newNtfyFactory(cfg, capcodeLookup, app.translator, onDelivery, onBreakerChange, chaosCfg.Transport, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create subscription notifier")
+		}
+		app.subscribers = subscription.NewSender(app.subscriptions, func(topic string) (notifier.Sender, error) {
+			c := cfg.Ntfy
+			c.Topic, c.Topics = topic, nil
+			return newNtfy("", c, cfg.Templates)
+		}, app.metrics, logger)
+		app.subscriberQueue = dispatch.New(app.notifySubscribers, cfg.Queue.Workers, cfg.Queue.Size, nil, logger)
+		logger.Info().
+			Int("subscriptions", app.subscriptions.Len()).
+			Bool("persistent", app.subscriptions.Persistent()).
+			Msg("self-service subscriptions enabled")
+	}
+
+	// Initialize management API
+	// Editing is disabled when the database failed to load, so a write does
+	// not replace the file with a partial list
+	var capcodeStore *capcode.Store
+	if capcodeLookup != nil && capcodesLoaded {
+		capcodeStore = capcode.NewStore(capcodeLookup, cfg.CapcodeCSVPath)
+	}
+	app.apiServer = api.NewServer(cfg.API.Token, api.Services{
+		Capcodes: capcodeStore,
+		Receipts: receipts,
+		Store:    app.store,
+		Status:   app.status,
+		Acks:     app.acks,
+		Inject:   app.process,
+		Explain:  app.explain,
+		Stats:    app.aggregates,
+		Backends: app.backends,
+
+		Subscriptions:     app.subscriptions,
+		RegistrationToken: cfg.Subscriptions.RegistrationToken,
+	}, logger)
+	if cfg.GRPC.Port > 0 {
+		app.rpc, err = rpc.New(rpc.Options{
+			Token:    cfg.API.Token,
+			Store:    app.store,
+			Capcodes: app.capcodes,
+			Buffer:   cfg.GRPC.Buffer,
+			Metrics:  app.metrics,
+			Logger:   logger,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create grpc server")
+		}
+	}
+
+	// Initialize the message source: the live WebSocket feed, decoder
+	// output on stdin or from a supervised decoder command, or archive
+	// files when importing
+	var src source.Source
+	var restart func() // Restarts a stale live source
+	var replaySource *source.File
+	var statusChan <-chan bool
+
+	// Live sources hand messages to the buffer so a stalled pipeline does
+	// not block reading; an import is paced by the pipeline instead
+	handler := app.handleMessage
+	if replay == nil && cfg.Buffer.Size > 0 {
+		app.buffer, err = dispatch.NewBuffer(app.handleMessage, cfg.Buffer.Size, cfg.Buffer.DropPolicy, app.metrics, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid message buffer")
+		}
+		handler = app.buffer.Push
+	}
+	if replay != nil {
+		replaySource = source.NewFile(replay.files, replay.speed, app.handleMessage, logger)
+		src = replaySource
+		app.direct = true
+	} else if strings.EqualFold(cfg.Source, config.SourceStdin) {
+		src = source.NewReader(os.Stdin, handler, logger)
+		// There is no connection to monitor, the source is up while it runs
+		app.status.SetConnected(true)
+	} else if strings.EqualFold(cfg.Source, config.SourceDecoder) {
+		decoder := source.NewCommand(cfg.Decoder.Command, handler, logger)
+		src = decoder
+		statusChan = decoder.StatusChan()
+		restart = decoder.Restart
+	} else {
+		app.wsClient = websocket.NewClient(logger, handler)
+		app.wsClient.SetMetrics(app.metrics)
+		app.wsClient.SetJitter(cfg.WebSocket.Jitter)
+		app.wsClient.SetMaxReconnectAttempts(cfg.WebSocket.MaxReconnectAttempts)
+		app.wsClient.SetHeaders(cfg.WebSocket.DialHeaders())
+		app.wsClient.SetIdleTimeout(time.Duration(cfg.WebSocket.IdleTimeout) * time.Second)
+		app.wsClient.Dialer().EnableCompression = cfg.WebSocket.Compression
+		if chaosCfg.DNSDelay > 0 {
+			dialer := &net.Dialer{Timeout: 30 * time.Second}
+			app.wsClient.Dialer().NetDialContext = chaosCfg.DialContext(dialer.DialContext)
+		}
+		if ws := cfg.WebSocketProxy(); ws != "" {
+			proxyFunc, err := proxy.New(ws, cfg.Proxy.NoProxy)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("invalid websocket proxy")
+			}
+			app.wsClient.Dialer().Proxy = proxyFunc
+		}
+		tlsCfg, err := tlsconfig.Client(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid websocket tls configuration")
+		}
+		app.wsClient.Dialer().TLSClientConfig = tlsCfg
+		src = app.wsClient
+		statusChan = app.wsClient.StatusChan()
+		restart = app.wsClient.Reconnect
+
+		// Archive the raw feed, independent of filtering
+		if cfg.Archive.Dir != "" {
+			app.archive, err = archive.New(archive.Options{
+				Dir:            cfg.Archive.Dir,
+				RotateInterval: time.Duration(cfg.Archive.RotateInterval) * time.Second,
+				MaxSize:        int64(cfg.Archive.MaxSize) << 20,
+				MaxFiles:       cfg.Archive.MaxFiles,
+				Logger:         logger,
+			})
+			if err != nil {
+				logger.Fatal().Err(err).Str("dir", cfg.Archive.Dir).Msg("failed to open feed archive")
+			}
+			app.wsClient.OnFrame(app.archive.Archive)
+		}
+
+		// Keep the frames that are not valid messages for debugging
+		if invalid := cfg.WebSocket.InvalidFrames; invalid.Dir != "" || invalid.Destination != "" {
+			frames := &invalidFrames{logger: logger}
+			if invalid.Dir != "" {
+				frames.archive, err = archive.New(archive.Options{
+					Dir:            invalid.Dir,
+					RotateInterval: time.Duration(cfg.Archive.RotateInterval) * time.Second,
+					MaxSize:        int64(cfg.Archive.MaxSize) << 20,
+					MaxFiles:       cfg.Archive.MaxFiles,
+					Logger:         logger,
+				})
+				if err != nil {
+					logger.Fatal().Err(err).Str("dir", invalid.Dir).Msg("failed to open invalid frame archive")
+				}
+				defer frames.archive.Close()
+			}
+			if invalid.Destination != "" {
+				dest, _ := cfg.Destination(invalid.Destination)
+				frames.sender, err = newInternal(invalid.Destination, dest, cfg.Templates)
+				if err != nil {
+					logger.Fatal().Err(err).Msg("failed to create invalid frame notifier")
+				}
+			}
+			app.wsClient.OnInvalidFrame(frames.handle)
+		}
+	}
 
 	// Setup HTTP server for metrics and health checks
-	app.setupHTTPServer()
+	if err := app.setupHTTPServer(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid server auth configuration")
+	}
+	if cfg.Server.TLS.CertFile != "" {
+		if app.httpServer.TLSConfig, err = tlsconfig.Server(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile); err != nil {
+			logger.Fatal().Err(err).Msg("invalid server tls configuration")
+		}
+	}
 
-	// Create context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Serve the gRPC API on its own port, with the certificate of the HTTP
+	// server
+	if app.rpc != nil {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to listen for grpc")
+		}
+		app.grpcServer = app.rpc.GRPCServer(app.httpServer.TLSConfig)
+		go func() {
+			logger.Info().
+				Int("port", cfg.GRPC.Port).
+				Bool("tls", app.httpServer.TLSConfig != nil).
+				Msg("starting gRPC server")
+			if err := app.grpcServer.Serve(lis); err != nil {
+				logger.Error().Err(err).Msg("gRPC server error")
+			}
+		}()
+	}
 
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Start WebSocket client in goroutine
+	// Start the message source in goroutine; done is closed when it
+	// returns, which for an import means all files were replayed
+	done := make(chan struct{})
+	var srcErr error
 	go func() {
-		if err := app.wsClient.Connect(ctx); err != nil && err != context.Canceled {
-			logger.Error().Err(err).Msg("websocket client error")
+		defer close(done)
+		if srcErr = src.Connect(ctx); srcErr != nil && srcErr != context.Canceled {
+			logger.Error().Err(srcErr).Msg("message source error")
 		}
 	}()
 
-	// Monitor WebSocket connection status
-	go app.monitorConnectionStatus(ctx)
+	if statusChan != nil {
+		// Monitor the WebSocket connection or decoder process
+		go app.monitorConnectionStatus(ctx, statusChan)
+	}
+	if cfg.SelfHeal.Enabled && restart != nil {
+		cooldown := time.Duration(cfg.SelfHeal.Cooldown) * time.Second
+		go app.selfHeal(ctx, sourceName(cfg), restart, selfHealInterval, cooldown)
+	}
+	if app.wsClient != nil {
+		// Inject websocket resets when chaos testing
+		go chaosCfg.RunWebsocketResets(ctx, app.wsClient.Reconnect, logger)
+	}
 
 	// Start HTTP server
 	go func() {
@@ -129,19 +780,87 @@ func main() {
 			Int("port", cfg.Server.Port).
 			Str("metrics", cfg.Server.MetricsPath).
 			Str("health", cfg.Server.HealthPath).
+			Str("live", cfg.Server.LivePath).
+			Str("ready", cfg.Server.ReadyPath).
+			Bool("tls", app.httpServer.TLSConfig != nil).
 			Msg("starting HTTP server")
 
-		if err := app.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if app.httpServer.TLSConfig != nil {
+			// The certificate is served by TLSConfig.GetCertificate
+			err = app.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = app.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error().Err(err).Msg("HTTP server error")
 		}
 	}()
 
+	// Report readiness to systemd and keep its watchdog fed while messages
+	// flow
+	if err := service.Ready(fmt.Sprintf("forwarding to %s", cfg.Ntfy.Topic)); err != nil {
+		logger.Warn().Err(err).Msg("failed to report readiness to systemd")
+	}
+	go service.Watchdog(ctx, app.alive, logger)
+
 	// Wait for shutdown signal
-	<-sigChan
-	logger.Info().Msg("shutdown signal received")
+	select {
+	case <-sigChan:
+		logger.Info().Msg("shutdown signal received")
+	case <-stop:
+		logger.Info().Msg("service stop requested")
+	case <-done:
+		logger.Info().Msg("message source finished")
+	}
+	if err := service.Stopping(); err != nil {
+		logger.Warn().Err(err).Msg("failed to report shutdown to systemd")
+	}
 
-	// Graceful shutdown
+	// Graceful shutdown: stop receiving messages first so queued
+	// notifications can be drained
 	cancel()
+	if app.wsClient != nil {
+		app.wsClient.Close()
+	}
+	if app.archive != nil {
+		if err := app.archive.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close feed archive")
+		}
+	}
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(cfg.Queue.DrainTimeout)*time.Second)
+	defer drainCancel()
+	if app.buffer != nil {
+		logger.Info().Int("buffered", app.buffer.Len()).Msg("draining message buffer")
+		if err := app.buffer.Drain(drainCtx); err != nil {
+			logger.Error().Err(err).Msg("message buffer not drained")
+		}
+	}
+	if app.batcher != nil {
+		logger.Info().Int("incidents", app.batcher.Len()).Msg("flushing batched pages")
+		app.batcher.Close()
+	}
+	logger.Info().Int("queued", app.dispatcher.Len()).Msg("draining notification queue")
+	if err := app.dispatcher.Drain(drainCtx); err != nil {
+		logger.Error().Err(err).Msg("notification queue not drained")
+	}
+	if app.subscriberQueue != nil {
+		if err := app.subscriberQueue.Drain(drainCtx); err != nil {
+			logger.Error().Err(err).Msg("subscription queue not drained")
+		}
+	}
+
+	if replaySource != nil {
+		<-done
+		event := logger.Info().
+			Int("messages", replaySource.Stats().Messages).
+			Int("skipped", replaySource.Stats().Skipped)
+		if dryRun != nil {
+			event = event.Int64("forwarded", dryRun.forwarded.Load())
+		}
+		event.Msg("import finished")
+	}
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -149,13 +868,339 @@ func main() {
 	if err := app.httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error().Err(err).Msg("HTTP server shutdown error")
 	}
+	if app.grpcServer != nil {
+		// Subscribe streams only end when cancelled
+		app.grpcServer.Stop()
+	}
 
-	app.wsClient.Close()
+	if err := app.store.Save(); err != nil {
+		logger.Error().Err(err).Msg("failed to save message store")
+	}
 	logger.Info().Msg("application stopped")
+
+	select {
+	case <-done:
+		if errors.Is(srcErr, websocket.ErrMaxReconnects) {
+			return 1
+		}
+	default:
+	}
+	return 0
+}
+
+// loadCapcodeLookup loads the capcode database from disk or over HTTP(S)
+// into lookup. Remote databases are refreshed in the background until ctx is
+// cancelled.
+func loadCapcodeLookup(ctx context.Context, cfg *config.Config, lookup *capcode.Lookup, logger zerolog.Logger) error {
+	if capcode.IsRemote(cfg.CapcodeCSVPath) {
+		interval := time.Duration(cfg.CapcodeRefresh) * time.Second
+		remote, err := capcode.NewRemoteLookup(ctx, cfg.CapcodeCSVPath, interval, logger)
+		if err != nil {
+			return err
+		}
+
+		// Share the lookup with the filters and notifier so refreshes and
+		// a late first load are picked up everywhere
+		lookup.Replace(remote.All())
+		remote.Lookup = lookup
+		go remote.Run(ctx)
+
+		logger.Info().
+			Str("csv_url", cfg.CapcodeCSVPath).
+			Dur("refresh_interval", interval).
+			Msg("remote capcode lookup loaded successfully")
+		return nil
+	}
+
+	loaded, err := capcode.NewLookup(cfg.CapcodeCSVPath)
+	if err != nil {
+		return err
+	}
+	lookup.Replace(loaded.All())
+
+	logger.Info().
+		Str("csv_path", cfg.CapcodeCSVPath).
+		Msg("capcode lookup loaded successfully")
+	return nil
 }
 
-// setupHTTPServer configures the HTTP server with metrics and health endpoints
-func (app *Application) setupHTTPServer() {
+// retryCapcodeLookup keeps attempting to load the capcode database until it
+// succeeds or ctx is cancelled
+func (app *Application) retryCapcodeLookup(ctx context.Context, lookup *capcode.Lookup, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := loadCapcodeLookup(ctx, app.cfg, lookup, app.logger); err != nil {
+				app.logger.Warn().
+					Err(err).
+					Str("csv_path", app.cfg.CapcodeCSVPath).
+					Dur("retry_interval", interval).
+					Msg("capcode lookup still unavailable")
+				continue
+			}
+			app.status.SetCapcodesAvailable(true)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// useProxy routes all outbound HTTP requests using the default transport,
+// e.g. capcode downloads, geocoding and notifications, through the configured
+// proxy. Vault is reached while loading the configuration, before the proxy
+// is applied, and only honors the proxy environment variables.
+func useProxy(cfg *config.Config) error {
+	if cfg.Proxy.URL == "" {
+		return nil
+	}
+	proxyFunc, err := proxy.New(cfg.Proxy.URL, cfg.Proxy.NoProxy)
+	if err != nil {
+		return err
+	}
+	http.DefaultTransport.(*http.Transport).Proxy = proxyFunc
+	return nil
+}
+
+// newTransport returns the HTTP transport for the notification destinations,
+// pooling connections as configured. It picks up the proxy of useProxy.
+func newTransport(c config.HTTPClientConfig) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = c.MaxIdleConns
+	t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	t.IdleConnTimeout = time.Duration(c.IdleTimeout) * time.Second
+	if !c.HTTP2 {
+		// A non-nil empty map disables HTTP/2
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// newSender creates the notification sender. Without recipients every message
+// goes to the ntfy destination; with recipients each one is notified once on
+// their preferred destination, and with pipelines each matching pipeline
+// notifies its own destinations. All configured destinations are returned by
+// name as well. wrap, when set, wraps the HTTP transport of every destination.
+func newSender(cfg *config.Config, capcodeLookup *capcode.Lookup, translator *i18n.Translator, onPublished notifier.PublishHook, onDelivery []notifier.DeliveryHook, onBreakerChange notifier.BreakerHook, wrap func(http.RoundTripper) http.RoundTripper, logger zerolog.Logger) (notifier.Sender, map[string]notifier.Sender, error) {
+	newNtfy, err := newNtfyFactory(cfg, capcodeLookup, translator, onDelivery, onBreakerChange, wrap, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	primary, err := newNtfy(config.DefaultDestination, cfg.Ntfy, cfg.Templates)
+	if err != nil {
+		return nil, nil, err
+	}
+	if onPublished != nil {
+		primary.OnPublished(onPublished)
+	}
+
+	destinations := map[string]notifier.Sender{config.DefaultDestination: primary}
+	for name, dest := range cfg.Destinations {
+		n, err := newNtfy(name, dest, cfg.Templates)
+		if err != nil {
+			return nil, nil, err
+		}
+		destinations[name] = n
+	}
+	for _, name := range cfg.TopicDestinations() {
+		dest, _ := cfg.Destination(name)
+		n, err := newNtfy(name, dest, cfg.Templates)
+		if err != nil {
+			return nil, nil, err
+		}
+		destinations[name] = n
+	}
+	for _, pc := range cfg.Plugins {
+		if pc.Type != config.PluginNotifier {
+			continue
+		}
+		s, err := plugins.LoadNotifier(pc.Path, pc.Config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("plugin %q: %w", pc.Name, err)
+		}
+		destinations[pc.Name] = s
+	}
+	if len(cfg.ActivePipelines()) > 0 {
+		router, err := newRouter(cfg, capcodeLookup, newNtfy, onPublished, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return router, destinations, nil
+	}
+	if len(cfg.Recipients) == 0 {
+		return primary, destinations, nil
+	}
+
+	recipients := make([]notifier.Recipient, 0, len(cfg.Recipients))
+	for _, rc := range cfg.Recipients {
+		recipient := notifier.Recipient{Name: rc.Name}
+		for _, channel := range rc.Channels {
+			recipient.Channels = append(recipient.Channels, destinations[channel])
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	logger.Info().
+		Int("recipients", len(recipients)).
+		Int("destinations", len(destinations)).
+		Msg("per-recipient delivery enabled")
+
+	return notifier.NewRecipientDispatcher(recipients, logger), destinations, nil
+}
+
+// ntfyFactory creates an ntfy notifier for the destination named name, using
+// defaults for the templates it does not set. An unnamed notifier is named by
+// its server and topic.
+type ntfyFactory func(name string, c config.NtfyConfig, defaults config.TemplateConfig) (*notifier.Notifier, error)
+
+// newNtfyFactory returns the factory for ntfy notifiers sharing the message
+// types, special units, capcode overrides and delivery hooks of cfg
+func newNtfyFactory(cfg *config.Config, capcodeLookup *capcode.Lookup, translator *i18n.Translator, onDelivery []notifier.DeliveryHook, onBreakerChange notifier.BreakerHook, wrap func(http.RoundTripper) http.RoundTripper, logger zerolog.Logger) (ntfyFactory, error) {
+	messageTypes := make(map[string]notifier.MessageType, len(cfg.MessageTypes))
+	for name, mt := range cfg.MessageTypes {
+		messageTypes[name] = notifier.MessageType{Tags: mt.Tags, Priority: mt.Priority}
+	}
+
+	specialUnits := notifier.DefaultSpecialUnits
+	if cfg.SpecialUnits != nil {
+		specialUnits = make([]notifier.SpecialUnit, 0, len(cfg.SpecialUnits))
+		for _, su := range cfg.SpecialUnits {
+			specialUnits = append(specialUnits, notifier.SpecialUnit{
+				Name:     su.Name,
+				Capcodes: su.Capcodes,
+				Keywords: su.Keywords,
+				Tags:     su.Tags,
+				Priority: su.Priority,
+			})
+		}
+	}
+
+	// Deprecated capcode translations are display names without tags
+	overrides := make(map[string]notifier.CapcodeOverride, len(cfg.CapcodeTranslations)+len(cfg.CapcodeOverrides))
+	for code, name := range cfg.CapcodeTranslations {
+		if code != "" && name != "" {
+			overrides[code] = notifier.CapcodeOverride{Name: name}
+		}
+	}
+	for code, o := range cfg.CapcodeOverrides {
+		overrides[code] = notifier.CapcodeOverride{Name: o.Name, Tags: o.Tags, Priority: o.Priority, PriorityBump: o.PriorityBump}
+	}
+
+	mapImage, err := notifier.ParseMapImage(cfg.MapImage.URL, cfg.MapImage.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Destinations on the same server share its pooled connections
+	shared := newTransport(cfg.HTTPClient)
+
+	return func(name string, c config.NtfyConfig, defaults config.TemplateConfig) (*notifier.Notifier, error) {
+		title, body := c.Templates.Title, c.Templates.Body
+		if title == "" {
+			title = defaults.Title
+		}
+		if body == "" {
+			body = defaults.Body
+		}
+		templates, err := notifier.ParseTemplates(title, body)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", c.Server, c.Topic, err)
+		}
+
+		actionConfigs := c.Actions
+		if actionConfigs == nil {
+			actionConfigs = cfg.Actions
+		}
+		actions, err := notifier.ParseActions(newActions(actionConfigs))
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", c.Server, c.Topic, err)
+		}
+
+		// A destination proxy or TLS settings need their own transport
+		var transport http.RoundTripper = shared
+		tlsCfg := cfg.DestinationTLS(c)
+		if c.Proxy != "" || tlsCfg != (config.TLSConfig{}) {
+			t := shared.Clone()
+			if c.Proxy != "" {
+				if t.Proxy, err = proxy.New(c.Proxy, ""); err != nil {
+					return nil, fmt.Errorf("%s/%s: %w", c.Server, c.Topic, err)
+				}
+			}
+			if t.TLSClientConfig, err = tlsconfig.Client(tlsCfg.CAFile, tlsCfg.CertFile, tlsCfg.KeyFile); err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", c.Server, c.Topic, err)
+			}
+			transport = t
+		}
+		if wrap != nil {
+			transport = wrap(transport)
+		}
+
+		opts := notifier.Options{
+			Name:             name,
+			Server:           c.Server,
+			Topic:            c.Topic,
+			Token:            c.Token,
+			Username:         c.Username,
+			Password:         c.Password,
+			Backend:          c.Backend,
+			GotifyPriorities: c.GotifyPriorities,
+			Rooms:            c.Rooms,
+			SMS:              notifier.SMSConfig(c.SMS),
+			Call:             notifier.CallConfig(c.Call),
+			APRS:             notifier.APRSConfig(c.APRS),
+			CapcodeOverrides: overrides,
+			CapcodeLookup:    capcodeLookup,
+			MessageTypes:     messageTypes,
+			SpecialUnits:     specialUnits,
+			OwnUnit:          notifier.OwnUnit(cfg.OwnUnit),
+			Details:          notifier.CapcodeDetails(cfg.CapcodeDetails),
+			Templates:        templates,
+			MapImage:         mapImage,
+			Actions:          actions,
+			Headers:          c.Headers,
+			Markdown:         c.Markdown,
+			JSON:             c.JSON,
+			MaxBodyLength:    c.MaxBodyLength,
+			Truncate:         c.Truncate,
+			Translator:       translator,
+			Transport:        transport,
+			OnDelivery:       onDelivery,
+			Breaker: notifier.BreakerConfig{
+				Threshold: cfg.CircuitBreaker.Threshold,
+				Cooldown:  time.Duration(cfg.CircuitBreaker.Cooldown) * time.Second,
+			},
+			OnBreakerChange: onBreakerChange,
+			Logger:          logger,
+		}
+		return notifier.New(opts)
+	}, nil
+}
+
+// newActions converts configured action buttons for the notifier
+func newActions(configs []config.ActionConfig) []notifier.Action {
+	actions := make([]notifier.Action, 0, len(configs))
+	for _, c := range configs {
+		actions = append(actions, notifier.Action{
+			Action:  c.Action,
+			Label:   c.Label,
+			URL:     c.URL,
+			Method:  c.Method,
+			Headers: c.Headers,
+			Body:    c.Body,
+			Clear:   c.Clear,
+		})
+	}
+	return actions
+}
+
+// setupHTTPServer configures the HTTP server with metrics and health
+// endpoints, protected by the server authentication
+func (app *Application) setupHTTPServer() error {
 	mux := http.NewServeMux()
 
 	// Metrics endpoint
@@ -164,45 +1209,122 @@ func (app *Application) setupHTTPServer() {
 	// Health check endpoint
 	mux.HandleFunc(app.cfg.Server.HealthPath, app.healthCheckHandler)
 
+	// Kubernetes liveness and readiness endpoints
+	mux.HandleFunc(app.cfg.Server.LivePath, app.livenessHandler)
+	mux.HandleFunc(app.cfg.Server.ReadyPath, app.readinessHandler)
+
+	// Management API endpoints
+	app.apiServer.Register(mux)
+
+	// When credentials are required the API token is accepted as well, so
+	// API clients only need one credential
+	authCfg := app.cfg.Server.Auth
+	var tokens []string
+	if authCfg.Username != "" || authCfg.Token != "" {
+		tokens = []string{authCfg.Token, app.cfg.API.Token}
+	}
+	auth, err := httpauth.New(httpauth.Options{
+		Username:   authCfg.Username,
+		Password:   authCfg.Password,
+		Tokens:     tokens,
+		AllowedIPs: authCfg.AllowedIPs,
+		Public:     authCfg.Public,
+	})
+	if err != nil {
+		return err
+	}
+
 	app.httpServer = &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.cfg.Server.Port),
-		Handler:      mux,
+		Handler:      auth.Wrap(mux),
 		ReadTimeout:  time.Duration(app.cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(app.cfg.Server.WriteTimeout) * time.Second,
 	}
+	return nil
 }
 
-// healthCheckHandler handles health check requests
-func (app *Application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if WebSocket is connected
-	if !app.wsConnected {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		fmt.Fprintf(w, "unhealthy: websocket disconnected\n")
-		return
+// healthReport collects the state reported by the health endpoints
+func (app *Application) healthReport() health.Report {
+	snap := app.status.Snapshot()
+	return health.Report{
+		Uptime:                 snap.Uptime,
+		WebsocketConnected:     snap.WebsocketConnected,
+		Reconnects:             snap.Reconnects,
+		LastMessageAge:         snap.LastMessageAge,
+		CapcodeLookupAvailable: snap.CapcodeLookupAvailable,
+		LastNotification:       app.backends.Last(),
+		Backends:               app.backends.List(),
+	}
+}
+
+// alive reports whether a message was received within the health window
+func (app *Application) alive() bool {
+	return app.status.LastMessageAge() <= time.Duration(app.cfg.Server.HealthWindow)*time.Second
+}
+
+// checkLiveness fails when no message was received within the health window,
+// which indicates a stalled connection that a restart may fix
+func (app *Application) checkLiveness(report *health.Report) {
+	window := time.Duration(app.cfg.Server.HealthWindow) * time.Second
+	ok := app.alive()
+
+	var message string
+	if !ok {
+		message = app.translator.T("health.no_messages", window)
 	}
+	report.Add("messages", ok, message)
+}
 
-	// Check if we've received a message recently
-	if time.Since(app.lastMsg) > healthCheckWindow {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		fmt.Fprintf(w, "unhealthy: no messages received in %v\n", healthCheckWindow)
-		return
+// checkReadiness fails when the websocket is disconnected or the capcode
+// database is unavailable
+func (app *Application) checkReadiness(report *health.Report) {
+	var message string
+	if !report.WebsocketConnected {
+		message = app.translator.T("health.websocket_disconnected")
+	}
+	report.Add("websocket", report.WebsocketConnected, message)
+
+	message = ""
+	if !report.CapcodeLookupAvailable {
+		message = app.translator.T("health.capcodes_unavailable")
 	}
+	report.Add("capcode_lookup", report.CapcodeLookupAvailable, message)
+}
+
+// healthCheckHandler reports the detailed state with all checks
+func (app *Application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	report := app.healthReport()
+	app.checkReadiness(&report)
+	app.checkLiveness(&report)
+	report.Write(w)
+}
+
+// livenessHandler reports whether messages are still flowing
+func (app *Application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	report := app.healthReport()
+	app.checkLiveness(&report)
+	report.Write(w)
+}
 
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "healthy\n")
+// readinessHandler reports whether the application is fully operational,
+// including the capcode database
+func (app *Application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := app.healthReport()
+	app.checkReadiness(&report)
+	report.Write(w)
 }
 
-// monitorConnectionStatus monitors WebSocket connection status changes
-func (app *Application) monitorConnectionStatus(ctx context.Context) {
+// monitorConnectionStatus monitors the status changes of the message
+// source: the WebSocket connection or the decoder process
+func (app *Application) monitorConnectionStatus(ctx context.Context, statusChan <-chan bool) {
 	for {
 		select {
-		case connected := <-app.wsClient.StatusChan():
-			app.wsConnected = connected
-			app.metrics.SetWebsocketConnected(connected)
+		case connected := <-statusChan:
+			app.status.SetConnected(connected)
 			if connected {
-				app.logger.Info().Msg("websocket connection established")
+				app.logger.Info().Msg("message source connected")
 			} else {
-				app.logger.Warn().Msg("websocket connection lost")
+				app.logger.Warn().Msg("message source disconnected")
 			}
 		case <-ctx.Done():
 			return
@@ -210,40 +1332,559 @@ func (app *Application) monitorConnectionStatus(ctx context.Context) {
 	}
 }
 
+// selfHeal restarts the message source when the liveness check fails, as a
+// stalled connection or decoder rarely recovers by itself. After a restart
+// the source gets cooldown to deliver messages again before the next one.
+func (app *Application) selfHeal(ctx context.Context, name string, restart func(), interval, cooldown time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var restarted time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if app.alive() || time.Since(restarted) < cooldown {
+				continue
+			}
+			app.logger.Warn().
+				Str("source", name).
+				Dur("last_message", app.status.LastMessageAge()).
+				Msg("no messages within the health window, restarting message source")
+			app.metrics.RecordSelfHeal(name)
+			restart()
+			restarted = time.Now()
+		}
+	}
+}
+
+// sourceName returns the name of the live message source, used in logs and
+// metrics
+func sourceName(cfg *config.Config) string {
+	if strings.EqualFold(cfg.Source, config.SourceDecoder) {
+		return config.SourceDecoder
+	}
+	return config.SourceWebsocket
+}
+
+// recordDelivery counts a delivery in the metrics of its destination
+func (app *Application) recordDelivery(result notifier.DeliveryResult) {
+	app.metrics.RecordBackendDelivery(result.Destination, result.Success, result.Duration.Seconds())
+}
+
 // handleMessage processes incoming P2000 messages
-func (app *Application) handleMessage(msg websocket.P2000Message) {
+func (app *Application) handleMessage(msg model.Message) {
 	app.metrics.RecordMessageReceived()
-	app.lastMsg = time.Now()
+	app.status.MessageReceived()
+	if lag, ok := app.pageDelay(msg); ok {
+		app.metrics.SetFeedLag(lag.Seconds())
+	}
+	if app.shard != nil && !app.shard.Owns(msg) {
+		app.metrics.RecordShardSkipped()
+		return
+	}
+	app.process(msg, true)
+}
+
+// process enriches, filters and queues a message, returning its history ID
+// and whether it is forwarded. Unfiltered messages, such as test messages
+// injected through the API, are forwarded regardless of the filters and
+// routing rules dropping them.
+func (app *Application) process(msg model.Message, filtered bool) (string, bool) {
+	msg.Enrich(app.capcodes)
+	sent := time.Now()
+	if msg.Timestamp > 0 {
+		sent = time.Unix(msg.Timestamp, 0)
+	}
+	if msg.ID == "" {
+		msg.ID = model.MessageID(msg)
+	}
+	var trace decisionTrace
+	if filtered {
+		trace.add(store.TraceReceived, "")
+	} else {
+		trace.add(store.TraceReceived, "injected through the API, filters bypassed")
+	}
+
+	// Copies of a page share its ID. Without a timestamp, or for test
+	// messages from the API, a repeated text may be a new message.
+	if filtered && msg.Timestamp > 0 && app.duplicates != nil && app.duplicates.Seen(msg.ID, time.Now()) {
+		app.metrics.RecordDuplicateMessage()
+		app.logger.Debug().Str("id", msg.ID).Strs("capcodes", msg.Capcodes).Msg("duplicate message dropped")
+		app.trace(msg.ID, traceEvent(store.TraceDuplicate, "copy received again and dropped"))
+		return msg.ID, false
+	}
+
+	if filtered {
+		app.metrics.RecordMessageSeverity(string(msg.Severity))
+		app.recordGRIP(msg, sent)
+	}
+
+	// Detect test pages first, so rules can match on them
+	if app.testAlarms != nil {
+		msg.Test = app.testAlarms.IsTest(msg.Message, sent)
+	}
+	if app.reanimation != nil {
+		msg.Reanimation = app.reanimation.IsReanimation(msg)
+	}
+
+	// Routing rules may drop a message or route it regardless of the filter
+	var delivery notifier.Delivery
+	var dropped, routed bool
+	var matched []string
+	if app.rules != nil {
+		res := app.rules.Evaluate(msg)
+		res.Apply(&delivery)
+		for _, name := range res.Matched {
+			app.metrics.RecordRuleMatch(name)
+			trace.add(store.TraceRule, name)
+		}
+		dropped, routed, matched = res.Drop, len(res.Destinations) > 0, res.Matched
+		if dropped {
+			trace.add(store.TraceDropped, "dropped by rule "+res.Matched[len(res.Matched)-1])
+		} else if routed {
+			trace.add(store.TraceRouted, "routed by rules to "+strings.Join(res.Destinations, ", "))
+		}
+	}
+	if app.script != nil && !dropped {
+		var scriptRouted bool
+		dropped, scriptRouted = app.runScript(&msg, &delivery, &trace)
+		routed = routed || scriptRouted
+	}
+	delivery.Tags = append(delivery.Tags, app.severityTags(msg.Severity)...)
+
+	// GRIP announcements take their own high-priority path
+	if !dropped && app.routeGRIP(msg, &delivery) {
+		routed = true
+		trace.add(store.TraceRouted, fmt.Sprintf("routed as GRIP %d to %s", msg.GRIP, strings.Join(app.cfg.GRIP.Destinations, ", ")))
+	}
+	if msg.Test && !dropped {
+		dropped = app.applyTestAlarm(msg, &delivery, &trace)
+	}
+	if app.maintenance != nil && !dropped {
+		dropped = app.applyMaintenance(msg, &delivery, sent, &trace)
+	}
 
 	// Check if message should be forwarded
-	if !app.filter.ShouldForward(msg.Capcodes) {
-		return
+	allowed := !dropped && app.typeFilter.Allow(msg.Type, msg.Message)
+	rejectedBy, rejected := "", false
+	if allowed && filtered {
+		rejectedBy, rejected = app.rejectingPlugin(msg)
+		allowed = !rejected
+	}
+	forward := allowed && (routed || app.accepts(msg))
+	switch {
+	case !filtered:
+		allowed, forward = true, true
+	case dropped, routed && allowed:
+		// Traced already
+	case rejected:
+		trace.add(store.TraceDropped, "rejected by plugin "+rejectedBy)
+	case !allowed:
+		trace.add(store.TraceDropped, "suppressed by message type")
+	case forward:
+		trace.add(store.TraceAccepted, "accepted by the filters")
+	default:
+		trace.add(store.TraceDropped, "rejected by the filters")
+	}
+
+	// Sending paused or the capcode or rule muted through the API: messages
+	// are kept and counted but not notified. Test messages from the API are
+	// still sent.
+	silenced := false
+	if filtered && app.paused() {
+		silenced = true
+		if forward {
+			app.metrics.RecordNotificationPaused()
+			trace.add(store.TraceSilenced, "sending is paused")
+		}
+	} else if m, ok := app.muted(msg, matched); filtered && ok {
+		silenced = true
+		if forward {
+			app.metrics.RecordNotificationMuted(m.Kind)
+			app.logger.Debug().
+				Str("kind", m.Kind).
+				Str("target", m.Target).
+				Strs("capcodes", msg.Capcodes).
+				Msg("message muted")
+			trace.add(store.TraceSilenced, fmt.Sprintf("%s %s is muted", m.Kind, m.Target))
+		}
+	}
+	forward = forward && !silenced
+
+	// Follow-up pages update the notification of their incident
+	if forward && app.threads != nil {
+		delivery.Thread, delivery.Update = app.threads.Correlate(msg, sent)
+		if delivery.Update {
+			app.metrics.RecordIncidentUpdate()
+		}
+	}
+
+	if app.store != nil {
+		msg.ID = app.store.AddMessage(msg, forward).ID
+		if delivery.Thread != "" {
+			if _, err := app.store.SetThread(msg.ID, delivery.Thread); err != nil {
+				app.logger.Debug().Err(err).Str("id", msg.ID).Msg("message no longer in history")
+			}
+		}
+		app.trace(msg.ID, trace...)
+	}
+	if app.stats != nil {
+		app.stats.RecordMessage(forward)
+	}
+	if app.aggregates != nil {
+		app.aggregates.Record(msg, sent, forward)
+	}
+	if app.stream != nil && (forward || !app.cfg.Stream.ForwardedOnly) {
+		app.stream.Publish(msg, time.Now(), forward)
+	}
+	if app.rpc != nil {
+		app.rpc.Publish(msg, delivery.Thread, time.Now(), forward)
+	}
+	if app.postgres != nil {
+		app.postgres.RecordMessage(msg, time.Now(), forward)
+	}
+	if app.influx != nil {
+		app.influx.Record(msg, sent, forward)
+	}
+	if app.elastic != nil {
+		app.elastic.Index(msg, sent, time.Now(), forward)
+	}
+
+	// Redundant instances receive the same feed, the first to claim a
+	// message notifies it. API test messages are local.
+	if allowed && filtered && app.dedup != nil && !app.dedup.Claim(msg) {
+		if forward {
+			app.trace(msg.ID, traceEvent(store.TraceSkipped, "claimed by another instance"))
+		}
+		return msg.ID, false
+	}
+
+	// Subscriptions choose their own capcodes, independent of the filters
+	if allowed && !silenced && app.subscriptions != nil {
+		app.queueSubscriptions(msg, delivery)
+	}
+	if !forward {
+		return msg.ID, false
 	}
 
 	app.metrics.RecordMessageFiltered()
 
+	// Pages of an incident burst wait for the others, API test messages
+	// are sent right away
+	if app.batcher != nil && filtered {
+		app.batcher.Add(msg, delivery)
+		return msg.ID, true
+	}
+	return msg.ID, app.deliver(msg, delivery)
+}
+
+// deliver sends msg as d describes, or queues it when not sending directly,
+// reporting whether it was sent or queued
+func (app *Application) deliver(msg model.Message, d notifier.Delivery) bool {
+	if app.direct {
+		app.send(context.Background(), msg, d)
+		return true
+	}
+	if err := app.dispatcher.Enqueue(msg, d); err != nil {
+		app.logger.Error().
+			Err(err).
+			Str("id", msg.ID).
+			Str("agency", msg.Agency).
+			Strs("capcodes", msg.Capcodes).
+			Msg("failed to queue notification")
+		app.metrics.RecordNotificationFailed()
+		app.trace(msg.ID, traceEvent(store.TraceFailed, "failed to queue: "+err.Error()))
+		return false
+	}
+	app.trace(msg.ID, traceEvent(store.TraceQueued, ""))
+	return true
+}
+
+// paused reports whether notification sending is paused
+func (app *Application) paused() bool {
+	if app.store == nil {
+		return false
+	}
+	_, ok := app.store.Paused()
+	return ok
+}
+
+// muted returns the mute of a capcode of msg or of a rule it matched
+func (app *Application) muted(msg model.Message, rules []string) (store.Mute, bool) {
+	if app.store == nil {
+		return store.Mute{}, false
+	}
+	return app.store.Muted(msg.Capcodes, rules)
+}
+
+// queueSubscriptions queues msg for the subscriptions it matches
+func (app *Application) queueSubscriptions(msg model.Message, d notifier.Delivery) {
+	if len(app.subscriptions.Match(msg)) == 0 {
+		return
+	}
+	if app.direct {
+		app.notifySubscribers(context.Background(), msg, d)
+		return
+	}
+	if err := app.subscriberQueue.Enqueue(msg, d); err != nil {
+		app.logger.Error().
+			Err(err).
+			Strs("capcodes", msg.Capcodes).
+			Msg("failed to queue subscription notifications")
+	}
+}
+
+// notifySubscribers delivers a queued message to the matching subscriptions
+func (app *Application) notifySubscribers(ctx context.Context, msg model.Message, d notifier.Delivery) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Failures are logged and counted per subscription
+	_ = app.subscribers.Send(notifier.WithDelivery(ctx, d), msg)
+}
+
+// accepts reports whether the filters forward msg. Pipelines also match their
+// patterns against the message text.
+func (app *Application) accepts(msg model.Message) bool {
+	if router, ok := app.filter.(*pipeline.Router); ok {
+		return router.Accepts(msg)
+	}
+	return app.filter.ShouldForward(msg.Capcodes)
+}
+
+// applyTestAlarm labels or downgrades a test page as configured, reporting
+// whether it is dropped instead
+func (app *Application) applyTestAlarm(msg model.Message, d *notifier.Delivery, trace *decisionTrace) bool {
+	action := app.cfg.TestAlarms.Action
+	app.metrics.RecordTestAlarm(action)
+	app.logger.Info().
+		Strs("capcodes", msg.Capcodes).
+		Str("action", action).
+		Msg("test alarm detected")
+	trace.add(store.TraceTestAlarm, action)
+	if !app.testAlarmAction(d) {
+		return false
+	}
+	trace.add(store.TraceDropped, "dropped as test alarm")
+	return true
+}
+
+// testAlarmAction changes the delivery of a test page for the configured
+// action, reporting whether it is dropped
+func (app *Application) testAlarmAction(d *notifier.Delivery) bool {
+	action := app.cfg.TestAlarms.Action
+	if action == config.TestAlarmDrop {
+		return true
+	}
+	d.Tags = append(d.Tags, splitTags(app.cfg.TestAlarms.Tags)...)
+	if action == config.TestAlarmDowngrade {
+		d.PriorityOverride = app.cfg.TestAlarms.Priority
+	}
+	return false
+}
+
+// severityTags returns the configured ntfy tags of a severity
+func (app *Application) severityTags(severity classify.Severity) []string {
+	return splitTags(app.cfg.Severity.Tags[string(severity)])
+}
+
+// newMaintenanceCalendar creates the calendar of the configured maintenance
+// windows, logging when each starts next
+func newMaintenanceCalendar(configs []config.MaintenanceWindowConfig, logger zerolog.Logger) *maintenance.Calendar {
+	var windows []maintenance.Window
+	for _, wc := range configs {
+		w, err := wc.Window()
+		if err != nil {
+			// Rejected by the validation already
+			logger.Error().Err(err).Str("window", wc.Name).Msg("invalid maintenance window")
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	calendar := maintenance.NewCalendar(windows, logger)
+	for _, w := range windows {
+		event := logger.Info().Str("window", w.Name).Str("action", w.Action)
+		if next, ok := calendar.Next(w, time.Now()); ok {
+			event = event.Time("next", next)
+		}
+		event.Msg("maintenance window configured")
+	}
+	return calendar
+}
+
+// applyMaintenance handles a message sent during a maintenance window,
+// reporting whether it is suppressed
+func (app *Application) applyMaintenance(msg model.Message, d *notifier.Delivery, sent time.Time, trace *decisionTrace) bool {
+	w, ok := app.maintenance.Match(msg.Capcodes, sent)
+	if !ok {
+		return false
+	}
+	app.metrics.RecordMaintenanceMessage(w.Name, w.Action)
+	app.logger.Debug().
+		Str("window", w.Name).
+		Str("action", w.Action).
+		Strs("capcodes", msg.Capcodes).
+		Msg("message sent during maintenance window")
+	trace.add(store.TraceMaintenance, fmt.Sprintf("%s (%s)", w.Name, w.Action))
+	if !maintenanceAction(d, w) {
+		return false
+	}
+	trace.add(store.TraceDropped, "suppressed by maintenance window "+w.Name)
+	return true
+}
+
+// maintenanceAction changes the delivery of a message sent during window w
+// for its action, reporting whether it is suppressed
+func maintenanceAction(d *notifier.Delivery, w maintenance.Window) bool {
+	if w.Action == config.MaintenanceSuppress {
+		return true
+	}
+	d.Tags = append(d.Tags, splitTags(w.Tags)...)
+	return false
+}
+
+// acknowledgeReceipt acknowledges the message of a notification the ntfy
+// server delivered, cancelling its escalation
+func (app *Application) acknowledgeReceipt(r receipt.Receipt) {
+	if r.MessageID == "" {
+		return
+	}
+	if err := app.acks.Delivered(r.MessageID); err != nil {
+		app.logger.Debug().
+			Err(err).
+			Str("id", r.MessageID).
+			Str("ntfy_id", r.ID).
+			Msg("failed to acknowledge delivered notification")
+	}
+}
+
+// send delivers a queued message to the notifier as d describes; ctx is
+// cancelled when the shutdown drain times out
+func (app *Application) send(ctx context.Context, msg model.Message, d notifier.Delivery) {
 	// Send notification with timing
 	start := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	if err := app.notifier.Send(ctx, msg); err != nil {
+	if app.geocoder != nil || app.bag != nil {
+		app.locate(ctx, &msg)
+	}
+	if msg.Reanimation {
+		app.alertReanimation(msg, &d)
+	}
+	if app.enrichers != nil {
+		app.enrichers.Enrich(ctx, &msg)
+	}
+	if app.mt != nil {
+		app.translate(ctx, &msg)
+	}
+
+	err := app.notifier.Send(notifier.WithDelivery(ctx, d), msg)
+	if errors.Is(err, notifier.ErrSkipped) {
+		// Not delivered on purpose, so neither sent nor failed
+		app.logger.Debug().
+			Str("id", msg.ID).
+			Msg("notification skipped by every destination")
+		app.trace(msg.ID, traceEvent(store.TraceSkipped, "skipped by every destination"))
+		return
+	}
+	if err != nil {
 		app.logger.Error().
 			Err(err).
+			Str("id", msg.ID).
 			Str("agency", msg.Agency).
 			Strs("capcodes", msg.Capcodes).
 			Msg("failed to send notification")
 		app.metrics.RecordNotificationFailed()
+		if app.postgres != nil {
+			app.postgres.RecordNotification(msg, start, time.Since(start), err)
+		}
+		if app.acks != nil && app.cfg.Escalation.OnFailure {
+			app.acks.Failed(msg)
+		}
+		app.trace(msg.ID, traceEvent(store.TraceFailed, err.Error()))
 		return
 	}
 
 	duration := time.Since(start)
 	app.metrics.NotificationDuration.Observe(duration.Seconds())
 	app.metrics.RecordNotificationSent()
+	if app.postgres != nil {
+		app.postgres.RecordNotification(msg, start, duration, nil)
+	}
+	if latency, ok := app.pageDelay(msg); ok {
+		app.metrics.RecordNotificationLatency(latency.Seconds())
+	}
+	if app.acks != nil {
+		app.acks.Track(msg)
+	}
+	app.trace(msg.ID, traceEvent(store.TraceSent, strings.Join(d.Routes, ", ")))
 
 	app.logger.Info().
+		Str("id", msg.ID).
 		Str("agency", msg.Agency).
 		Strs("capcodes", msg.Capcodes).
 		Dur("duration", duration).
 		Msg("notification forwarded")
 }
+
+// pageDelay returns the time since msg was paged according to its
+// timestamp. Replayed messages and messages without a timestamp have no
+// meaningful delay; clock skew is reported as no delay.
+func (app *Application) pageDelay(msg model.Message) (time.Duration, bool) {
+	if msg.Timestamp <= 0 || app.direct {
+		return 0, false
+	}
+	return max(time.Since(time.Unix(msg.Timestamp, 0)), 0), true
+}
+
+// translate adds the machine translation of the message text. On failure
+// the notification is sent without translation.
+func (app *Application) translate(ctx context.Context, msg *model.Message) {
+	ctx, cancel := context.WithTimeout(ctx, translateTimeout)
+	defer cancel()
+
+	translation, err := app.mt.Translate(ctx, msg.Message)
+	if err != nil {
+		app.logger.Warn().Err(err).Str("id", msg.ID).Msg("failed to translate message")
+		return
+	}
+	msg.Translation = translation
+}
+
+// locate resolves the incident address of a message against the BAG
+// extract, or else geocodes it, and stores the result in the message
+// history. On failure the notification is sent without coordinates.
+func (app *Application) locate(ctx context.Context, msg *model.Message) {
+	location, coordinates := msg.Location, msg.Coordinates
+	if app.bag != nil && app.bag.Enrich(msg) {
+		app.metrics.RecordGeocodeLookup("bag")
+	} else if app.geocoder != nil {
+		ctx, cancel := context.WithTimeout(ctx, geocodeTimeout)
+		defer cancel()
+
+		if err := app.geocoder.Locate(ctx, msg); err != nil {
+			event := app.logger.Warn()
+			if errors.Is(err, geocode.ErrNotFound) {
+				event = app.logger.Debug()
+			}
+			event.Err(err).
+				Str("id", msg.ID).
+				Str("address", geocode.Query(*msg)).
+				Msg("failed to geocode message")
+			return
+		}
+	}
+	if msg.Location == location && msg.Coordinates == coordinates {
+		return
+	}
+
+	if app.store != nil && msg.ID != "" {
+		if _, err := app.store.SetLocation(msg.ID, msg.Location, msg.Coordinates); err != nil {
+			app.logger.Debug().Err(err).Str("id", msg.ID).Msg("message no longer in history")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/pipeline"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+)
+
+// newFilter combines the capcode, region, station, discipline and exclude
+// filters of a pipeline. With lenient, capcodes match regardless of leading
+// zeros.
+func newFilter(p config.PipelineConfig, disciplineRanges map[string][]string, lenient bool, capcodeLookup *capcode.Lookup, logger zerolog.Logger) (filter.Filter, error) {
+	var f filter.Filter = filter.NewCapcodeFilter(p.ForwardAll, p.Capcodes, lenient, logger)
+	if !p.ForwardAll && (len(p.Regions) > 0 || len(p.Stations) > 0) {
+		f = filter.Any(
+			f,
+			filter.NewRegionFilter(capcodeLookup, p.Regions, p.Stations, logger),
+		)
+	}
+	if len(p.Disciplines) > 0 {
+		disciplineFilter, err := filter.NewDisciplineFilter(capcodeLookup, p.Disciplines, disciplineRanges, logger)
+		if err != nil {
+			return nil, err
+		}
+		f = filter.All(f, disciplineFilter)
+	}
+	if len(p.ExcludeCapcodes) > 0 {
+		f = filter.All(f, filter.NewExcludeFilter(p.ExcludeCapcodes, lenient, logger))
+	}
+	return f, nil
+}
+
+// newRouter creates the configured pipelines, or one per ntfy topic. Each
+// pipeline gets its own notifiers, so its templates apply to its destinations
+// only.
+func newRouter(cfg *config.Config, capcodeLookup *capcode.Lookup, newNtfy ntfyFactory, onPublished notifier.PublishHook, logger zerolog.Logger) (*pipeline.Router, error) {
+	configs := cfg.ActivePipelines()
+	pipelines := make([]pipeline.Pipeline, 0, len(configs))
+	for _, pc := range configs {
+		f, err := newFilter(pc, cfg.DisciplineRanges, cfg.LenientCapcodes(), capcodeLookup, logger)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", pc.Name, err)
+		}
+
+		templates := pc.Templates
+		if templates.Title == "" {
+			templates.Title = cfg.Templates.Title
+		}
+		if templates.Body == "" {
+			templates.Body = cfg.Templates.Body
+		}
+
+		p := pipeline.Pipeline{Name: pc.Name, Filter: f}
+		if pc.Pattern != "" {
+			if p.Pattern, err = regexp.Compile(pc.Pattern); err != nil {
+				return nil, fmt.Errorf("pipeline %s: %w", pc.Name, err)
+			}
+		}
+		for _, name := range pc.Destinations {
+			dest, _ := cfg.Destination(name)
+			n, err := newNtfy(name, dest, templates)
+			if err != nil {
+				return nil, fmt.Errorf("pipeline %s: %w", pc.Name, err)
+			}
+			if name == config.DefaultDestination && onPublished != nil {
+				n.OnPublished(onPublished)
+			}
+			p.Senders = append(p.Senders, n)
+		}
+		pipelines = append(pipelines, p)
+	}
+
+	logger.Info().
+		Int("pipelines", len(pipelines)).
+		Msg("forwarding pipelines enabled")
+
+	return pipeline.NewRouter(pipelines, logger), nil
+}
+
+// newRules compiles the routing rules. Unnamed rules are named by position.
+func newRules(configs []config.RuleConfig) (*rules.Engine, error) {
+	list := make([]rules.Rule, 0, len(configs))
+	for i, rc := range configs {
+		name := rc.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		when, err := rules.Compile(rc.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+
+		list = append(list, rules.Rule{
+			Name:         name,
+			When:         when,
+			Drop:         rc.Drop,
+			Destinations: rc.Destinations,
+			Priority:     rc.Priority,
+			Tags:         splitTags(rc.Tags),
+			Email:        rc.Email,
+			Delay:        rc.Delay,
+			Stop:         rc.Stop,
+		})
+	}
+	return rules.NewEngine(list), nil
+}
+
+// splitTags splits comma separated ntfy tags
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/ha"
+	"github.com/kaije/p2000-nfty/internal/incident"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/pipeline"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/stats"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/internal/subscription"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFilter(t *testing.T) {
+	f, err := newFilter(config.PipelineConfig{
+		ForwardAll:      true,
+		ExcludeCapcodes: []string{"0101999"},
+	}, nil, true, nil, getTestLogger())
+	require.NoError(t, err)
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.False(t, f.ShouldForward([]string{"0101001", "0101999"}))
+	assert.False(t, f.ShouldForward([]string{"0101001", "000101999"}), "leading zeros are ignored")
+
+	ranges := map[string][]string{"brandweer": {"not-a-range"}}
+	_, err = newFilter(config.PipelineConfig{ForwardAll: true, Disciplines: []string{"brandweer"}}, ranges, false, nil, getTestLogger())
+	assert.Error(t, err)
+}
+
+func TestNewSender_Pipelines(t *testing.T) {
+	var mu sync.Mutex
+	titles := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles[r.URL.Path] = append(titles[r.URL.Path], r.Header.Get("Title"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		ForwardAll:   true,
+		Ntfy:         config.NtfyConfig{Server: server.URL, Topic: "brandweer"},
+		Destinations: map[string]config.NtfyConfig{"public": {Server: server.URL, Topic: "public"}},
+		Templates:    config.TemplateConfig{Title: "P2000 {{.Urgency}}"},
+		Pipelines: []config.PipelineConfig{
+			{Name: "brandweer", Capcodes: []string{"0101001"}, Destinations: []string{"ntfy"}},
+			{
+				Name:         "public",
+				ForwardAll:   true,
+				Templates:    config.TemplateConfig{Title: "Melding"},
+				Destinations: []string{"public"},
+			},
+		},
+	}
+
+	sender, destinations, err := newSender(cfg, nil, nil, nil, nil, nil, nil, getTestLogger())
+	require.NoError(t, err)
+	assert.Len(t, destinations, 2)
+	router, ok := sender.(*pipeline.Router)
+	require.True(t, ok)
+
+	require.NoError(t, router.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}, Priority: "A1", Message: "A1 Brand"}))
+	require.NoError(t, router.Send(context.Background(), model.Message{Capcodes: []string{"1420059"}, Message: "A2 Ambulance"}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"P2000 A1"}, titles["/brandweer"])
+	assert.Equal(t, []string{"Melding", "Melding"}, titles["/public"])
+}
+
+func TestNewSender_Topics(t *testing.T) {
+	var mu sync.Mutex
+	topics := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		topics[r.URL.Path] = append(topics[r.URL.Path], r.Header.Get("Title"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Ntfy: config.NtfyConfig{Server: server.URL, Topics: []config.TopicConfig{
+			{Topic: "p2000-all", ForwardAll: true},
+			{Topic: "ts-4231", Capcodes: []string{"0101001"}, Pattern: `(?i)\bbrand\b`, Templates: config.TemplateConfig{Title: "TS {{.Urgency}}"}},
+		}},
+	}
+	cfg.Ntfy.Topic = "p2000-all"
+
+	sender, destinations, err := newSender(cfg, nil, nil, nil, nil, nil, nil, getTestLogger())
+	require.NoError(t, err)
+	assert.Contains(t, destinations, "ntfy")
+	assert.Contains(t, destinations, "ntfy/ts-4231")
+	router, ok := sender.(*pipeline.Router)
+	require.True(t, ok)
+
+	brand := model.Message{Capcodes: []string{"0101001"}, Priority: "P 1", Message: "P 1 Brand woning"}
+	other := model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Nacontrole"}
+	assert.True(t, router.Accepts(other))
+	require.NoError(t, router.Send(context.Background(), brand))
+	require.NoError(t, router.Send(context.Background(), other))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, topics["/p2000-all"], 2)
+	assert.Equal(t, []string{"TS P 1"}, topics["/ts-4231"])
+}
+
+func TestNewSender_RecipientsWithoutTopics(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("X-Gotify-Key"))
+	}))
+	defer server.Close()
+
+	// Gotify destinations on one server are told apart by their names
+	cfg := &config.Config{
+		ForwardAll: true,
+		Ntfy:       config.NtfyConfig{Server: server.URL, Topic: "p2000"},
+		Destinations: map[string]config.NtfyConfig{
+			"kazerne": {Server: server.URL, Backend: notifier.BackendGotify, Token: "kazerne-token"},
+			"ovd":     {Server: server.URL, Backend: notifier.BackendGotify, Token: "ovd-token"},
+		},
+		Recipients: []config.RecipientConfig{
+			{Name: "Kazerne", Channels: []string{"kazerne"}},
+			{Name: "OvD", Channels: []string{"ovd"}},
+		},
+	}
+
+	var names []string
+	onDelivery := []notifier.DeliveryHook{func(r notifier.DeliveryResult) { names = append(names, r.Destination) }}
+	sender, destinations, err := newSender(cfg, nil, nil, nil, onDelivery, nil, nil, getTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "ovd", destinations["ovd"].Name())
+	assert.Equal(t, "ntfy", destinations["ntfy"].Name())
+
+	require.NoError(t, sender.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}, Message: "A1 Brand"}))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"kazerne-token", "ovd-token"}, keys)
+	assert.Equal(t, []string{"kazerne", "ovd"}, names)
+}
+
+func TestNewSender_DestinationProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// A forward proxy receives the absolute URL of the request
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxyServer.Close()
+
+	cfg := &config.Config{
+		ForwardAll:   true,
+		Ntfy:         config.NtfyConfig{Server: "http://ntfy.invalid", Topic: "p2000", Proxy: proxyServer.URL},
+		Destinations: map[string]config.NtfyConfig{"backup": {Server: "http://backup.invalid", Topic: "p2000", Proxy: proxyServer.URL}},
+	}
+
+	wrapped := 0
+	wrap := func(rt http.RoundTripper) http.RoundTripper {
+		wrapped++
+		assert.NotNil(t, rt)
+		return rt
+	}
+	_, destinations, err := newSender(cfg, nil, nil, nil, nil, nil, wrap, getTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, 2, wrapped)
+
+	for _, name := range []string{"ntfy", "backup"} {
+		require.NoError(t, destinations[name].Send(context.Background(), model.Message{Capcodes: []string{"0101001"}, Message: "A1 Brand"}))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"http://ntfy.invalid/p2000", "http://backup.invalid/p2000"}, proxied)
+}
+
+func TestNewSender_SharedTransport(t *testing.T) {
+	cfg := &config.Config{
+		ForwardAll:   true,
+		HTTPClient:   config.HTTPClientConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 20, IdleTimeout: 30},
+		Ntfy:         config.NtfyConfig{Server: "https://ntfy.sh", Topic: "p2000"},
+		Destinations: map[string]config.NtfyConfig{"backup": {Server: "https://ntfy.sh", Topic: "backup"}, "proxied": {Server: "https://ntfy.sh", Topic: "proxied", Proxy: "http://proxy.invalid:3128"}},
+	}
+
+	var transports []*http.Transport
+	wrap := func(rt http.RoundTripper) http.RoundTripper {
+		transports = append(transports, rt.(*http.Transport))
+		return rt
+	}
+	_, _, err := newSender(cfg, nil, nil, nil, nil, nil, wrap, getTestLogger())
+	require.NoError(t, err)
+	require.Len(t, transports, 3)
+
+	shared := 0
+	for _, tr := range transports {
+		assert.Equal(t, 50, tr.MaxIdleConns)
+		assert.Equal(t, 20, tr.MaxIdleConnsPerHost)
+		assert.Equal(t, 30*time.Second, tr.IdleConnTimeout)
+		assert.False(t, tr.ForceAttemptHTTP2)
+		assert.NotNil(t, tr.TLSNextProto, "HTTP/2 disabled")
+		assert.Empty(t, tr.TLSNextProto)
+		if tr == transports[0] {
+			shared++
+		}
+	}
+	assert.Equal(t, 2, shared, "the proxied destination has its own transport")
+}
+
+// recordingSender records the messages sent and their deliveries
+type recordingSender struct {
+	name       string
+	mu         sync.Mutex
+	texts      []string
+	msgs       []model.Message
+	deliveries []notifier.Delivery
+}
+
+func (r *recordingSender) Name() string {
+	return r.name
+}
+
+func (r *recordingSender) Send(ctx context.Context, msg model.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.texts = append(r.texts, msg.Message)
+	r.msgs = append(r.msgs, msg)
+	r.deliveries = append(r.deliveries, notifier.DeliveryFrom(ctx))
+	return nil
+}
+
+func TestNewRules(t *testing.T) {
+	engine, err := newRules([]config.RuleConfig{
+		{When: `grip > 0`, Tags: "warning, grip"},
+	})
+	require.NoError(t, err)
+	res := engine.Evaluate(model.Message{GRIP: 1})
+	assert.Equal(t, []string{"1"}, res.Matched)
+	assert.Equal(t, []string{"warning", "grip"}, res.Tags)
+
+	_, err = newRules([]config.RuleConfig{{Name: "broken", When: `grip >`}})
+	assert.ErrorContains(t, err, "rule broken")
+}
+
+func TestHandleMessage_Rules(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{
+		{Name: "test-alarm", When: `text matches "(?i)proefalarm"`, Drop: true},
+		{Name: "a1", When: `priority == "A1"`, Destinations: []string{"pager"}},
+	})
+	require.NoError(t, err)
+
+	fallback := &recordingSender{name: "ntfy"}
+	pager := &recordingSender{name: "pager"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   rules.NewRouter(map[string]notifier.Sender{"pager": pager}, fallback, logger),
+		rules:      engine,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Capcodes: []string{"0101001"}, Message: "B1 Proefalarm"})
+	app.handleMessage(model.Message{Capcodes: []string{"0101001"}, Message: "B2 Ambulance"})
+	app.handleMessage(model.Message{Capcodes: []string{"9999999"}, Message: "A1 Brand woning"})
+	app.handleMessage(model.Message{Capcodes: []string{"9999999"}, Message: "B2 Dienstverlening"})
+
+	assert.Equal(t, []string{"B2 Ambulance"}, fallback.texts)
+	assert.Equal(t, []string{"A1 Brand woning"}, pager.texts, "routed past the capcode filter")
+}
+
+// claimStore is an ha.Store holding the claims of another instance
+type claimStore map[string]bool
+
+func (s claimStore) Claim(ctx context.Context, key string) (bool, error) {
+	if s[key] {
+		return false, nil
+	}
+	s[key] = true
+	return true, nil
+}
+
+func TestHandleMessage_HA(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	claimed := model.Message{Type: "FLEX", Timestamp: 1760522400, Message: "A1 Dorpsstraat Utrecht"}
+	store := claimStore{ha.Key(claimed): true}
+	app.dedup = ha.New(store, app.metrics, logger)
+
+	app.handleMessage(claimed)
+	app.handleMessage(model.Message{Type: "FLEX", Timestamp: 1760522401, Message: "B2 Ambulance"})
+	assert.Equal(t, []string{"B2 Ambulance"}, sender.texts)
+
+	// Test messages through the API are not shared
+	_, forwarded := app.process(claimed, false)
+	assert.True(t, forwarded)
+}
+
+func TestHandleMessage_Shard(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+	own := filter.Shard([]string{"001180000"}, 2)
+	var err error
+	app.shard, err = filter.NewShardFilter(own, 2, logger)
+	require.NoError(t, err)
+
+	var other string
+	for _, code := range []string{"0101001", "0101002", "0101003", "0101004", "0101005"} {
+		if filter.Shard([]string{code}, 2) != own {
+			other = code
+			break
+		}
+	}
+	require.NotEmpty(t, other)
+	app.handleMessage(model.Message{Capcodes: []string{"001180000"}, Message: "A1 Dorpsstraat Utrecht"})
+	app.handleMessage(model.Message{Capcodes: []string{other}, Message: "B2 Ambulance"})
+	assert.Equal(t, []string{"A1 Dorpsstraat Utrecht"}, sender.texts)
+}
+
+func TestHandleMessage_TestAlarms(t *testing.T) {
+	logger := getTestLogger()
+	newApp := func(action string) (*Application, *recordingSender) {
+		sender := &recordingSender{name: "ntfy"}
+		app := &Application{
+			cfg:        &config.Config{TestAlarms: config.TestAlarmConfig{Action: action, Priority: 1, Tags: "test_tube"}},
+			logger:     logger,
+			metrics:    metrics.NewMetrics(),
+			filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+			typeFilter: filter.NewTypeFilter(nil, false, logger),
+			testAlarms: filter.NewTestAlarmDetector(nil, true, logger),
+			notifier:   sender,
+			direct:     true,
+		}
+		app.status = status.NewManager(app.metrics)
+		return app, sender
+	}
+
+	app, sender := newApp(config.TestAlarmDrop)
+	app.handleMessage(model.Message{Message: "B2 Proefalarm Kazerne"})
+	app.handleMessage(model.Message{Message: "A1 Brand woning"})
+	assert.Equal(t, []string{"A1 Brand woning"}, sender.texts)
+
+	app, sender = newApp(config.TestAlarmDowngrade)
+	app.handleMessage(model.Message{Message: "B2 Proefalarm Kazerne"})
+	require.Len(t, sender.msgs, 1)
+	assert.True(t, sender.msgs[0].Test)
+	assert.Equal(t, 1, sender.deliveries[0].PriorityOverride)
+	assert.Equal(t, []string{"test_tube"}, sender.deliveries[0].Tags)
+
+	// Monday 4 March 2024 at noon, the siren test
+	app, sender = newApp(config.TestAlarmLabel)
+	app.handleMessage(model.Message{Timestamp: 1709550000, Message: "Test sirenes"})
+	require.Len(t, sender.msgs, 1)
+	assert.Equal(t, 0, sender.deliveries[0].PriorityOverride)
+	assert.Equal(t, []string{"test_tube"}, sender.deliveries[0].Tags)
+}
+
+func TestHandleMessage_Maintenance(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		direct:     true,
+		maintenance: newMaintenanceCalendar([]config.MaintenanceWindowConfig{
+			{Name: "oefenavond", Capcodes: []string{"0101001"}, Cron: "0 19 * * tue", Duration: "3h", Action: config.MaintenanceLabel},
+			{Name: "onderhoud", Capcodes: []string{"0202001"}, Cron: "0 19 * * tue", Duration: "2h"},
+		}, logger),
+	}
+	app.status = status.NewManager(app.metrics)
+
+	// Tuesday 5 March 2024 at 20:00 in Dutch time
+	at := int64(1709665200)
+	app.handleMessage(model.Message{Timestamp: at, Capcodes: []string{"101001"}, Message: "P 2 Oefening"})
+	app.handleMessage(model.Message{Timestamp: at, Capcodes: []string{"0202001"}, Message: "P 2 Onderhoud"})
+	app.handleMessage(model.Message{Timestamp: at + 3600, Capcodes: []string{"0202001"}, Message: "A1 Brand woning"})
+	assert.Equal(t, []string{"P 2 Oefening", "A1 Brand woning"}, sender.texts)
+	assert.Equal(t, []string{"oefening"}, sender.deliveries[0].Tags)
+	assert.Empty(t, sender.deliveries[1].Tags)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.MaintenanceMessages.WithLabelValues("onderhoud", "suppress")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.MaintenanceMessages.WithLabelValues("oefenavond", "label")))
+}
+
+func TestHandleMessage_Severity(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{
+		{Name: "critical", When: `severity == "critical"`, Destinations: []string{"ntfy"}, Priority: 5},
+	})
+	require.NoError(t, err)
+
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{Severity: config.SeverityConfig{Tags: map[string]string{"critical": "sos", "high": "red_circle"}}},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		rules:      engine,
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Message: "P 1 Zeer grote brand loods Eindhoven"})
+	app.handleMessage(model.Message{Message: "A1 Reanimatie Utrecht"})
+	app.handleMessage(model.Message{Message: "Proefalarm"})
+	require.Len(t, sender.msgs, 3)
+	assert.Equal(t, classify.SeverityCritical, sender.msgs[0].Severity)
+	assert.Equal(t, []string{"sos"}, sender.deliveries[0].Tags)
+	assert.Equal(t, 5, sender.deliveries[0].PriorityOverride)
+	assert.Equal(t, []string{"red_circle"}, sender.deliveries[1].Tags)
+	assert.Empty(t, sender.deliveries[2].Tags)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.MessageSeverities.WithLabelValues("critical")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.MessageSeverities.WithLabelValues("unknown")))
+}
+
+func TestHandleMessage_Threads(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		threads:    incident.NewCorrelator(15 * time.Minute),
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Timestamp: 1709550000, Capcodes: []string{"0101001"}, Message: "P 1 BR woning Kerkstraat 12 Harmelen"})
+	app.handleMessage(model.Message{Timestamp: 1709550060, Capcodes: []string{"9999999"}, Message: "P 1 BR woning Kerkstraat 12 Harmelen"})
+	app.handleMessage(model.Message{Timestamp: 1709550300, Capcodes: []string{"0101001"}, Message: "P 1 GRIP 1 BR woning Kerkstraat 12 Harmelen"})
+	app.handleMessage(model.Message{Timestamp: 1709550300, Capcodes: []string{"0101001"}, Message: "A1 Ambulance Damstraat 3 Utrecht"})
+
+	require.Len(t, sender.msgs, 3)
+	assert.NotEmpty(t, sender.deliveries[0].Thread)
+	assert.False(t, sender.deliveries[0].Update)
+	assert.Equal(t, sender.deliveries[0].Thread, sender.deliveries[1].Thread)
+	assert.True(t, sender.deliveries[1].Update)
+	assert.NotEqual(t, sender.deliveries[0].Thread, sender.deliveries[2].Thread)
+	assert.False(t, sender.deliveries[2].Update)
+}
+
+func TestProcess_TestMessage(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		aggregates: stats.NewAggregator(24 * time.Hour),
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	at := time.Now()
+	_, forwarded := app.process(model.NewTestMessage("", nil, at), true)
+	assert.False(t, forwarded, "filtered out by the capcode filter")
+
+	_, forwarded = app.process(model.NewTestMessage("", nil, at), false)
+	assert.True(t, forwarded)
+
+	_, forwarded = app.process(model.NewTestMessage("", []string{"0101001"}, at), true)
+	assert.True(t, forwarded)
+
+	require.Len(t, sender.msgs, 2)
+	assert.Equal(t, "P 2", sender.msgs[0].Priority, "enriched like a received message")
+	assert.Equal(t, stats.Count{Received: 3, Forwarded: 2}, app.aggregates.Aggregate(at.Add(-time.Hour), at.Add(time.Hour)).Total)
+}
+
+func TestHandleMessage_Paused(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+	var err error
+	app.store, err = store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	app.store.Pause(store.Pause{Reason: "drill", Since: time.Now()})
+	app.handleMessage(model.Message{Message: "A1 Brand woning"})
+	_, forwarded := app.process(model.NewTestMessage("", nil, time.Now()), false)
+	assert.True(t, forwarded, "test messages are sent while paused")
+
+	app.store.Resume()
+	app.handleMessage(model.Message{Message: "B2 Ambulance"})
+
+	// The paused message is kept in the history
+	records := app.store.Messages(0)
+	require.Len(t, records, 3)
+	assert.Equal(t, "A1 Brand woning", records[2].Message.Message)
+	assert.False(t, records[2].Forwarded)
+	require.Len(t, sender.texts, 2)
+	assert.Equal(t, "B2 Ambulance", sender.texts[1])
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.NotificationsPaused))
+}
+
+func TestHandleMessage_Muted(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{{Name: "grip", When: `grip >= 1`}})
+	require.NoError(t, err)
+
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		notifier:   sender,
+		rules:      engine,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+	app.store, err = store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	until := time.Now().Add(4 * time.Hour)
+	app.store.Mute(store.Mute{Kind: store.MuteCapcode, Target: "0101001", Since: time.Now(), Until: until})
+	app.store.Mute(store.Mute{Kind: store.MuteRule, Target: "grip", Since: time.Now(), Until: until})
+
+	app.handleMessage(model.Message{Capcodes: []string{"101001", "1420059"}, Message: "P 2 Oefening"})
+	app.handleMessage(model.Message{Capcodes: []string{"1420059"}, Message: "P 1 GRIP 1 Brand industrie"})
+	app.handleMessage(model.Message{Capcodes: []string{"1420059"}, Message: "A1 Ambulance"})
+
+	assert.Equal(t, []string{"A1 Ambulance"}, sender.texts)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.NotificationsMuted.WithLabelValues(store.MuteCapcode)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.NotificationsMuted.WithLabelValues(store.MuteRule)))
+
+	exp := app.explain(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Oefening"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "accepted by the filters, but capcode 101001 is muted", exp.Reason)
+}
+
+func TestHandleMessage_Subscriptions(t *testing.T) {
+	logger := getTestLogger()
+	subscriptions, err := subscription.Open("", 0)
+	require.NoError(t, err)
+	_, _, err = subscriptions.Create(subscription.Subscription{Topic: "jan", Capcodes: []string{"0202002"}})
+	require.NoError(t, err)
+
+	sender := &recordingSender{name: "ntfy"}
+	topics := make(map[string]*recordingSender)
+	app := &Application{
+		cfg:           &config.Config{},
+		logger:        logger,
+		metrics:       metrics.NewMetrics(),
+		filter:        filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter:    filter.NewTypeFilter([]string{"POCSAG"}, false, logger),
+		notifier:      sender,
+		subscriptions: subscriptions,
+		direct:        true,
+	}
+	app.subscribers = subscription.NewSender(subscriptions, func(topic string) (notifier.Sender, error) {
+		topics[topic] = &recordingSender{name: topic}
+		return topics[topic], nil
+	}, app.metrics, logger)
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Type: "FLEX", Capcodes: []string{"0101001"}, Message: "A1 Brand woning"})
+	app.handleMessage(model.Message{Type: "FLEX", Capcodes: []string{"0202002"}, Message: "A2 Ambulance"})
+	app.handleMessage(model.Message{Type: "POCSAG", Capcodes: []string{"0202002"}, Message: "Suppressed type"})
+
+	assert.Equal(t, []string{"A1 Brand woning"}, sender.texts)
+	require.Contains(t, topics, "jan")
+	assert.Equal(t, []string{"A2 Ambulance"}, topics["jan"].texts, "subscriptions bypass the filters but not suppressed types")
+}
+
+func TestPageDelay(t *testing.T) {
+	app := &Application{}
+
+	delay, ok := app.pageDelay(model.Message{Timestamp: time.Now().Add(-10 * time.Second).Unix()})
+	require.True(t, ok)
+	assert.InDelta(t, 10, delay.Seconds(), 1.5)
+
+	delay, ok = app.pageDelay(model.Message{Timestamp: time.Now().Add(time.Minute).Unix()})
+	require.True(t, ok)
+	assert.Zero(t, delay, "clock skew")
+
+	_, ok = app.pageDelay(model.Message{})
+	assert.False(t, ok)
+
+	app.direct = true
+	_, ok = app.pageDelay(model.Message{Timestamp: time.Now().Unix()})
+	assert.False(t, ok, "replayed")
+}
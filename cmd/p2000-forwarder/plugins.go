@@ -0,0 +1,14 @@
+package main
+
+import "github.com/kaije/p2000-nfty/pkg/model"
+
+// rejectingPlugin returns the name of the first filter plugin not allowing
+// msg, if any
+func (app *Application) rejectingPlugin(msg model.Message) (string, bool) {
+	for _, p := range app.plugins {
+		if !p.Allow(msg) {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/plugins"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordFilter is a filter plugin rejecting messages containing a keyword
+type keywordFilter string
+
+func (f keywordFilter) Allow(msg model.Message) bool {
+	return !strings.Contains(msg.Message, string(f))
+}
+
+func TestProcess_FilterPlugins(t *testing.T) {
+	logger := getTestLogger()
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	fallback := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		plugins: []plugins.Filter{
+			{Name: "oefening", Filter: keywordFilter("Oefening")},
+			{Name: "proef", Filter: keywordFilter("Proef")},
+		},
+		notifier: fallback,
+		store:    history,
+		direct:   true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand"})
+	id, _ := app.process(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Proef brandmelding"}, true)
+	assert.Equal(t, []string{"P 2 Buitenbrand"}, fallback.texts)
+	assert.Equal(t, []string{"received ", "dropped rejected by plugin proef"}, steps(t, history, id))
+
+	exp := app.explain(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Oefening"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "rejected by plugin oefening", exp.Reason)
+
+	// Test messages from the API bypass the filters
+	_, forwarded := app.process(model.Message{Capcodes: []string{"0101001"}, Message: "Proef"}, false)
+	assert.True(t, forwarded)
+	assert.Equal(t, []string{"P 2 Buitenbrand", "Proef"}, fallback.texts)
+}
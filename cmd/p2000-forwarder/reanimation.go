@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// Results of forwarded resuscitation calls for the reanimation metric
+const (
+	reanimationAlerted    = "alerted"
+	reanimationOutOfRange = "out_of_range"
+)
+
+// alertReanimation raises the delivery of a resuscitation call to the
+// reanimation priority and tags, once it is located. Calls with known
+// coordinates farther than the radius from every configured location are
+// sent as usual; calls that could not be located are alerted, as they may
+// well be nearby.
+func (app *Application) alertReanimation(msg model.Message, d *notifier.Delivery) {
+	cfg := app.cfg.Reanimation
+	if msg.Coordinates != nil && len(cfg.Locations) > 0 {
+		name, ok := nearestLocation(*msg.Coordinates, cfg.Locations, float64(cfg.Radius))
+		if !ok {
+			app.metrics.RecordReanimationAlert(reanimationOutOfRange)
+			app.logger.Debug().
+				Str("id", msg.ID).
+				Float64("lat", msg.Coordinates.Lat).
+				Float64("lon", msg.Coordinates.Lon).
+				Msg("reanimation outside the radius of the locations")
+			return
+		}
+		app.logger.Debug().Str("id", msg.ID).Str("location", name).Msg("reanimation near location")
+	}
+
+	app.metrics.RecordReanimationAlert(reanimationAlerted)
+	if cfg.Priority > 0 {
+		d.PriorityOverride = cfg.Priority
+	}
+	d.Tags = append(d.Tags, splitTags(cfg.Tags)...)
+}
+
+// nearestLocation returns the name of the location closest to c within
+// radius meters
+func nearestLocation(c model.Coordinates, locations []config.LocationConfig, radius float64) (string, bool) {
+	name, best := "", radius
+	found := false
+	for _, l := range locations {
+		if d := c.Distance(model.Coordinates{Lat: l.Lat, Lon: l.Lon}); d <= best {
+			name, best, found = l.Name, d, true
+		}
+	}
+	return name, found
+}
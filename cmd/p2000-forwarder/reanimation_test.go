@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMessage_Reanimation(t *testing.T) {
+	logger := getTestLogger()
+	sender := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg: &config.Config{Reanimation: config.ReanimationConfig{
+			Enabled:  true,
+			Priority: 5,
+			Tags:     "heartpulse",
+			Radius:   1000,
+		}},
+		logger:      logger,
+		metrics:     metrics.NewMetrics(),
+		filter:      filter.NewCapcodeFilter(true, nil, false, logger),
+		typeFilter:  filter.NewTypeFilter(nil, false, logger),
+		reanimation: filter.NewReanimationDetector(nil, logger),
+		notifier:    sender,
+		direct:      true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	app.handleMessage(model.Message{Capcodes: []string{"1600123"}, Message: "A1 Reanimatie Dorpsstraat Utrecht"})
+	app.handleMessage(model.Message{Capcodes: []string{"1600123"}, Message: "A2 Val van trap Dorpsstraat Utrecht"})
+
+	require.Len(t, sender.msgs, 2)
+	assert.True(t, sender.msgs[0].Reanimation)
+	assert.Equal(t, 5, sender.deliveries[0].PriorityOverride)
+	assert.Equal(t, []string{"heartpulse"}, sender.deliveries[0].Tags)
+	assert.False(t, sender.msgs[1].Reanimation)
+	assert.Zero(t, sender.deliveries[1].PriorityOverride)
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.ReanimationAlerts.WithLabelValues("alerted")))
+}
+
+func TestAlertReanimation_Radius(t *testing.T) {
+	app := &Application{
+		cfg: &config.Config{Reanimation: config.ReanimationConfig{
+			Priority: 5,
+			Tags:     "heartpulse",
+			Radius:   1000,
+			Locations: []config.LocationConfig{
+				{Name: "thuis", Lat: 52.0907, Lon: 5.1214},
+				{Name: "werk", Lat: 52.3731, Lon: 4.8926},
+			},
+		}},
+		logger:  getTestLogger(),
+		metrics: metrics.NewMetrics(),
+	}
+
+	var near notifier.Delivery
+	app.alertReanimation(model.Message{Reanimation: true, Coordinates: &model.Coordinates{Lat: 52.3760, Lon: 4.8950}}, &near)
+	assert.Equal(t, 5, near.PriorityOverride, "within 1 km of werk")
+
+	var far notifier.Delivery
+	app.alertReanimation(model.Message{Reanimation: true, Coordinates: &model.Coordinates{Lat: 51.9225, Lon: 4.4792}}, &far)
+	assert.Zero(t, far.PriorityOverride)
+	assert.Empty(t, far.Tags)
+
+	var unknown notifier.Delivery
+	app.alertReanimation(model.Message{Reanimation: true}, &unknown)
+	assert.Equal(t, 5, unknown.PriorityOverride, "alerted when the location is unknown")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(app.metrics.ReanimationAlerts.WithLabelValues("alerted")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.ReanimationAlerts.WithLabelValues("out_of_range")))
+}
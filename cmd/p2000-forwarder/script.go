@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/api"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/script"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// runScript applies the decision of the message script to msg and its
+// delivery, reporting whether the script dropped or routed it. A failing
+// script leaves both unchanged, so a bug in the script never loses a page.
+func (app *Application) runScript(msg *model.Message, delivery *notifier.Delivery, trace *decisionTrace) (dropped, routed bool) {
+	d, err := app.script.Run(*msg)
+	if err != nil {
+		app.metrics.RecordScriptError()
+		app.logger.Warn().Err(err).Str("id", msg.ID).Msg("message script failed, message left unchanged")
+		trace.add(store.TraceScript, "failed: "+err.Error())
+		return false, false
+	}
+	d.Apply(msg, delivery)
+	if changes := scriptChanges(d); changes != "" {
+		trace.add(store.TraceScript, changes)
+	}
+	if d.Drop {
+		trace.add(store.TraceDropped, "dropped by script")
+		return true, false
+	}
+	if len(d.Destinations) > 0 {
+		trace.add(store.TraceRouted, "routed by script to "+strings.Join(d.Destinations, ", "))
+		return false, true
+	}
+	return false, false
+}
+
+// scriptChanges describes the changes of d to the priority, tags and text
+func scriptChanges(d script.Decision) string {
+	var changes []string
+	if d.Priority > 0 {
+		changes = append(changes, fmt.Sprintf("priority %d", d.Priority))
+	}
+	if len(d.Tags) > 0 {
+		changes = append(changes, "tags "+strings.Join(d.Tags, ", "))
+	}
+	if d.Text != "" {
+		changes = append(changes, "text rewritten")
+	}
+	return strings.Join(changes, "; ")
+}
+
+// explainScript runs the message script on msg for /api/explain
+func (app *Application) explainScript(msg *model.Message, delivery *notifier.Delivery) *api.ScriptTrace {
+	d, err := app.script.Run(*msg)
+	if err != nil {
+		return &api.ScriptTrace{Error: err.Error()}
+	}
+	d.Apply(msg, delivery)
+	return &api.ScriptTrace{
+		Drop:         d.Drop,
+		Destinations: d.Destinations,
+		Priority:     d.Priority,
+		Tags:         d.Tags,
+		Text:         d.Text,
+	}
+}
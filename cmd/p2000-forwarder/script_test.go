@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/script"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Script(t *testing.T) {
+	logger := getTestLogger()
+	path := filepath.Join(t.TempDir(), "route.star")
+	require.NoError(t, os.WriteFile(path, []byte(`
+def process(msg):
+    if "oefening" in msg["text"].lower():
+        return {"drop": True}
+    if msg["text"].startswith("BR "):
+        return {"destinations": ["pager"], "priority": 5, "text": "Brand " + msg["text"][3:]}
+    if "fout" in msg["text"]:
+        return {"priority": "hoog"}
+`), 0o644))
+	s, err := script.Load(path, logger)
+	require.NoError(t, err)
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	fallback := &recordingSender{name: "ntfy"}
+	pager := &recordingSender{name: "pager"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		script:     s,
+		notifier:   rules.NewRouter(map[string]notifier.Sender{"pager": pager}, fallback, logger),
+		store:      history,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	id, _ := app.process(model.Message{Capcodes: []string{"9999999"}, Message: "BR Rotterdam"}, true)
+	require.Len(t, pager.msgs, 1, "routed past the capcode filter")
+	assert.Equal(t, "Brand Rotterdam", pager.msgs[0].Message)
+	assert.Equal(t, 5, pager.deliveries[0].PriorityOverride)
+	assert.Equal(t, []string{"received ", "script priority 5; text rewritten", "routed routed by script to pager", "sent pager"}, steps(t, history, id))
+
+	id, _ = app.process(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Oefening"}, true)
+	assert.Equal(t, []string{"received ", "dropped dropped by script"}, steps(t, history, id))
+
+	id, _ = app.process(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 fout"}, true)
+	assert.Equal(t, []string{"P 2 fout"}, fallback.texts, "left unchanged by the failing script")
+	assert.Equal(t, 1.0, testutil.ToFloat64(app.metrics.ScriptErrors))
+	assert.Contains(t, steps(t, history, id)[1], "script failed: ")
+
+	exp := app.explain(model.Message{Capcodes: []string{"9999999"}, Message: "BR Utrecht"})
+	assert.True(t, exp.Forward)
+	assert.Equal(t, "routed by script", exp.Reason)
+	assert.Equal(t, []string{"pager"}, exp.Destinations)
+	require.NotNil(t, exp.Script)
+	assert.Equal(t, "Brand Utrecht", exp.Message.Message)
+
+	exp = app.explain(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Oefening"})
+	assert.False(t, exp.Forward)
+	assert.Equal(t, "dropped by script", exp.Reason)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+// decisionTrace collects the decisions process makes about a message until
+// it is stored
+type decisionTrace []store.TraceEvent
+
+// add notes a decision
+func (t *decisionTrace) add(step, detail string) {
+	*t = append(*t, traceEvent(step, detail))
+}
+
+// traceEvent returns a decision made now
+func traceEvent(step, detail string) store.TraceEvent {
+	return store.TraceEvent{Time: time.Now(), Step: step, Detail: detail}
+}
+
+// trace appends decisions to the history record of a message, so
+// GET /api/messages/{id}/trace shows why it was or was not notified
+func (app *Application) trace(id string, events ...store.TraceEvent) {
+	if app.store == nil || id == "" || len(events) == 0 {
+		return
+	}
+	if err := app.store.Trace(id, events...); err != nil {
+		app.logger.Debug().Err(err).Str("id", id).Msg("message no longer in history")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// steps returns the steps and details of the trace of a stored message
+func steps(t *testing.T, history *store.Store, id string) []string {
+	t.Helper()
+	r, ok := history.Message(id)
+	require.True(t, ok)
+	var steps []string
+	for _, e := range r.Trace {
+		steps = append(steps, e.Step+" "+e.Detail)
+	}
+	return steps
+}
+
+func TestProcess_Trace(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{
+		{Name: "proefalarm", When: `text matches "(?i)proefalarm"`, Drop: true},
+		{Name: "a1", When: `priority == "A1"`, Destinations: []string{"pager"}},
+	})
+	require.NoError(t, err)
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	fallback := &recordingSender{name: "ntfy"}
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		rules:      engine,
+		notifier:   rules.NewRouter(map[string]notifier.Sender{"pager": &recordingSender{name: "pager"}}, fallback, logger),
+		store:      history,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	id, _ := app.process(model.Message{Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand"}, true)
+	assert.Equal(t, []string{"received ", "accepted accepted by the filters", "sent "}, steps(t, history, id))
+
+	id, _ = app.process(model.Message{Capcodes: []string{"9999999"}, Message: "A1 Reanimatie Utrecht"}, true)
+	assert.Equal(t, []string{"received ", "rule a1", "routed routed by rules to pager", "sent pager"}, steps(t, history, id))
+
+	id, _ = app.process(model.Message{Capcodes: []string{"0101001"}, Message: "Proefalarm"}, true)
+	assert.Equal(t, []string{"received ", "rule proefalarm", "dropped dropped by rule proefalarm"}, steps(t, history, id))
+
+	id, _ = app.process(model.Message{Capcodes: []string{"9999999"}, Message: "P 2 Buitenbrand"}, true)
+	assert.Equal(t, []string{"received ", "dropped rejected by the filters"}, steps(t, history, id))
+
+	history.Mute(store.Mute{Kind: store.MuteCapcode, Target: "101001", Until: time.Now().Add(time.Hour)})
+	id, _ = app.process(model.Message{Capcodes: []string{"0101001"}, Message: "P 1 Brand woning"}, true)
+	assert.Equal(t, []string{"received ", "accepted accepted by the filters", "silenced capcode 101001 is muted"}, steps(t, history, id))
+}
+
+func TestProcess_TraceSkipped(t *testing.T) {
+	logger := getTestLogger()
+	engine, err := newRules([]config.RuleConfig{{Name: "call", When: `priority == "A2"`, Destinations: []string{"call"}}})
+	require.NoError(t, err)
+	history, err := store.Open("", 10, logger)
+	require.NoError(t, err)
+
+	call := notify.SenderFunc("call", func(context.Context, model.Message) error { return notifier.ErrSkipped })
+	app := &Application{
+		cfg:        &config.Config{},
+		logger:     logger,
+		metrics:    metrics.NewMetrics(),
+		filter:     filter.NewCapcodeFilter(false, nil, false, logger),
+		typeFilter: filter.NewTypeFilter(nil, false, logger),
+		rules:      engine,
+		notifier:   rules.NewRouter(map[string]notifier.Sender{"call": call}, &recordingSender{name: "ntfy"}, logger),
+		store:      history,
+		direct:     true,
+	}
+	app.status = status.NewManager(app.metrics)
+
+	id, _ := app.process(model.Message{Capcodes: []string{"1420059"}, Message: "A2 Ambulance Utrecht"}, true)
+	assert.Equal(t, []string{"received ", "rule call", "routed routed by rules to call", "skipped skipped by every destination"}, steps(t, history, id))
+	assert.Equal(t, 0.0, testutil.ToFloat64(app.metrics.NotificationsSent))
+	assert.Equal(t, 0.0, testutil.ToFloat64(app.metrics.NotificationsFailed))
+}
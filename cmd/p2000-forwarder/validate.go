@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kaije/p2000-nfty/internal/config"
+)
+
+// validateConfig implements the validate-config subcommand: it loads the
+// configuration at path like on startup and prints every problem found. It
+// returns the exit code of the process.
+func validateConfig(path string, w io.Writer) int {
+	_, err := config.Load(path)
+	if err == nil {
+		fmt.Fprintf(w, "%s: configuration is valid\n", path)
+		return 0
+	}
+
+	var problems config.Problems
+	if !errors.As(err, &problems) {
+		fmt.Fprintf(w, "%s: %v\n", path, err)
+		return 1
+	}
+
+	fmt.Fprintf(w, "%s: %d problem(s) found\n", path, len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  - %v\n", problem)
+	}
+	return 1
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+`), 0644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, validateConfig(path, &out))
+	assert.Equal(t, path+": configuration is valid\n", out.String())
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+forward_all: false
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "p2000"
+  colour: "red"
+queue:
+  workers: -1
+`), 0644))
+
+	out.Reset()
+	assert.Equal(t, 1, validateConfig(path, &out))
+	assert.Equal(t, path+`: 3 problem(s) found
+  - line 6: field colour not found in type config.NtfyConfig
+  - at least one capcode must be configured when forward_all is false
+  - queue workers must not be negative
+`, out.String())
+
+	out.Reset()
+	assert.Equal(t, 1, validateConfig(filepath.Join(dir, "missing.yaml"), &out))
+	assert.Contains(t, out.String(), "failed to read config file")
+}
@@ -0,0 +1,240 @@
+// Package ack records acknowledgements of forwarded pages and runs an
+// escalation chain for pages that fail to deliver or are not acknowledged
+// in time.
+package ack
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/rs/zerolog"
+)
+
+const (
+	checkInterval   = 10 * time.Second
+	escalateTimeout = 30 * time.Second
+)
+
+// ReceiptAuthor is the author of acknowledgements recorded from ntfy
+// delivery receipts
+const ReceiptAuthor = "ntfy receipt"
+
+// EscalateFunc notifies an escalation destination of a message
+type EscalateFunc func(ctx context.Context, msg model.Message) error
+
+// Step is a stage of the escalation chain. It fires After the message was
+// forwarded unless the message was acknowledged before.
+type Step struct {
+	After       time.Duration
+	Destination string // Name used in logs
+	Send        EscalateFunc
+}
+
+// pending is a forwarded message waiting for an acknowledgement
+type pending struct {
+	msg   model.Message
+	since time.Time // Start of the chain the step delays count from
+	next  int       // Index of the next step to fire
+}
+
+// escalation is a step that is due for a message
+type escalation struct {
+	msg  model.Message
+	step Step
+}
+
+// Tracker stores acknowledgements in the message store and walks forwarded
+// messages through the escalation chain until they are acknowledged. It is
+// safe for concurrent use.
+type Tracker struct {
+	store   *store.Store
+	steps   []Step
+	metrics *metrics.Metrics
+	logger  zerolog.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pending
+	wake    chan struct{} // Triggers a check outside the regular interval
+}
+
+// NewTracker creates an acknowledgement tracker with an escalation chain,
+// ordered by delay. Escalation is disabled without steps. The metrics are
+// updated when m is not nil.
+func NewTracker(s *store.Store, steps []Step, m *metrics.Metrics, logger zerolog.Logger) *Tracker {
+	return &Tracker{
+		store:   s,
+		steps:   steps,
+		metrics: m,
+		logger:  logger,
+		now:     time.Now,
+		pending: make(map[string]*pending),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// escalates reports whether unacknowledged messages are escalated
+func (t *Tracker) escalates() bool {
+	return len(t.steps) > 0
+}
+
+// Track starts the escalation chain of a forwarded message. It is a no-op
+// when escalation is disabled, the message has no ID or it was acknowledged
+// already, e.g. by a delivery receipt arriving before the send returned.
+func (t *Tracker) Track(msg model.Message) {
+	if !t.escalates() || msg.ID == "" {
+		return
+	}
+	if record, ok := t.store.Message(msg.ID); ok && len(record.Acknowledgements) > 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[msg.ID] = &pending{msg: msg, since: t.now()}
+}
+
+// Failed starts the escalation chain of a message whose notification could
+// not be delivered. There is nothing to acknowledge, so the first step fires
+// right away and the later steps keep their delay relative to it.
+func (t *Tracker) Failed(msg model.Message) {
+	if !t.escalates() || msg.ID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	t.pending[msg.ID] = &pending{msg: msg, since: t.now().Add(-t.steps[0].After)}
+	t.mu.Unlock()
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Acknowledge records an acknowledgement of the message with the given ID
+// and cancels its escalation. It returns store.ErrNotFound for unknown IDs.
+func (t *Tracker) Acknowledge(id, author string) (store.Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	before, ok := t.store.Message(id)
+	if !ok {
+		return store.Record{}, store.ErrNotFound
+	}
+	now := t.now()
+	record, err := t.store.Acknowledge(id, store.Acknowledgement{Author: author, CreatedAt: now})
+	if err != nil {
+		return store.Record{}, err
+	}
+
+	delete(t.pending, id)
+	if len(before.Acknowledgements) == 0 {
+		if t.metrics != nil {
+			t.metrics.RecordAcknowledgement(now.Sub(record.ReceivedAt).Seconds())
+		}
+		t.logger.Info().
+			Str("id", id).
+			Str("author", author).
+			Msg("message acknowledged")
+	}
+	return record, nil
+}
+
+// Delivered acknowledges the message with the given ID on a delivery
+// receipt of its notification, cancelling its escalation. Messages
+// acknowledged before are left alone. It returns store.ErrNotFound for
+// unknown IDs.
+func (t *Tracker) Delivered(id string) error {
+	record, ok := t.store.Message(id)
+	if !ok {
+		return store.ErrNotFound
+	}
+	if len(record.Acknowledgements) > 0 {
+		return nil
+	}
+	_, err := t.Acknowledge(id, ReceiptAuthor)
+	return err
+}
+
+// Pending returns the number of messages waiting for an acknowledgement
+func (t *Tracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// Run escalates overdue messages until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context) {
+	if !t.escalates() {
+		return
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.check(ctx)
+		case <-t.wake:
+			t.check(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check fires the escalation steps that are due. Each step fires at most
+// once per message; a failed step does not stop the chain.
+func (t *Tracker) check(ctx context.Context) {
+	t.mu.Lock()
+	now := t.now()
+	var due []escalation
+	for id, p := range t.pending {
+		for p.next < len(t.steps) && now.Sub(p.since) >= t.steps[p.next].After {
+			due = append(due, escalation{msg: p.msg, step: t.steps[p.next]})
+			p.next++
+		}
+		if p.next >= len(t.steps) {
+			delete(t.pending, id)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, e := range due {
+		t.logger.Warn().
+			Str("id", e.msg.ID).
+			Strs("capcodes", e.msg.Capcodes).
+			Str("destination", e.step.Destination).
+			Msg("escalating message")
+
+		sendCtx, cancel := context.WithTimeout(ctx, escalateTimeout)
+		err := e.step.Send(sendCtx, e.msg)
+		cancel()
+		if errors.Is(err, notify.ErrSkipped) {
+			t.logger.Debug().
+				Str("id", e.msg.ID).
+				Str("destination", e.step.Destination).
+				Msg("escalation skipped by destination")
+			continue
+		}
+		if err != nil {
+			t.logger.Error().
+				Err(err).
+				Str("id", e.msg.ID).
+				Str("destination", e.step.Destination).
+				Msg("failed to escalate message")
+			continue
+		}
+		if t.metrics != nil {
+			t.metrics.RecordEscalation()
+		}
+	}
+}
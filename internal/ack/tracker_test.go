@@ -0,0 +1,263 @@
+package ack
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// fakeClock returns a controllable time source
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Now()
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+// recorder collects escalations per destination
+type recorder struct {
+	mu        sync.Mutex
+	escalated []string // "destination:id"
+}
+
+func (r *recorder) step(destination string, after time.Duration, err error) Step {
+	return Step{
+		After:       after,
+		Destination: destination,
+		Send: func(ctx context.Context, msg model.Message) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.escalated = append(r.escalated, destination+":"+msg.ID)
+			return err
+		},
+	}
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.escalated...)
+}
+
+func newTestTracker(t *testing.T, steps []Step, m *metrics.Metrics) (*Tracker, *store.Store, func(time.Duration)) {
+	s, err := store.Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	clock, advance := fakeClock()
+	tracker := NewTracker(s, steps, m, getTestLogger())
+	tracker.now = clock
+	return tracker, s, advance
+}
+
+func addMessage(s *store.Store, text string) model.Message {
+	msg := model.Message{Message: text}
+	msg.ID = s.AddMessage(msg, true).ID
+	return msg
+}
+
+func TestTracker_Acknowledge(t *testing.T) {
+	m := metrics.NewMetrics()
+	tracker, s, advance := newTestTracker(t, nil, m)
+
+	msg := addMessage(s, "A1 Brand woning")
+	advance(time.Minute)
+
+	record, err := tracker.Acknowledge(msg.ID, "jan")
+	require.NoError(t, err)
+	require.Len(t, record.Acknowledgements, 1)
+	assert.Equal(t, "jan", record.Acknowledgements[0].Author)
+
+	// Only the first acknowledgement of a message is counted
+	_, err = tracker.Acknowledge(msg.ID, "piet")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Acknowledgements))
+
+	_, err = tracker.Acknowledge("missing", "jan")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestTracker_EscalationChain(t *testing.T) {
+	m := metrics.NewMetrics()
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("pager", 5*time.Minute, nil),
+		r.step("phone", 10*time.Minute, nil),
+	}, m)
+
+	acked := addMessage(s, "A1 Brand woning")
+	unacked := addMessage(s, "A2 Ongeval")
+	tracker.Track(acked)
+	tracker.Track(unacked)
+	assert.Equal(t, 2, tracker.Pending())
+
+	_, err := tracker.Acknowledge(acked.ID, "jan")
+	require.NoError(t, err)
+
+	advance(4 * time.Minute)
+	tracker.check(context.Background())
+	assert.Empty(t, r.get(), "not overdue yet")
+
+	advance(time.Minute)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"pager:" + unacked.ID}, r.get())
+
+	// Each step fires once
+	tracker.check(context.Background())
+	assert.Len(t, r.get(), 1)
+
+	advance(5 * time.Minute)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"pager:" + unacked.ID, "phone:" + unacked.ID}, r.get())
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.Escalations))
+	assert.Equal(t, 0, tracker.Pending())
+}
+
+func TestTracker_AcknowledgeStopsChain(t *testing.T) {
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("pager", time.Minute, nil),
+		r.step("phone", 10*time.Minute, nil),
+	}, nil)
+
+	msg := addMessage(s, "A1 Brand woning")
+	tracker.Track(msg)
+
+	advance(time.Minute)
+	tracker.check(context.Background())
+	_, err := tracker.Acknowledge(msg.ID, "jan")
+	require.NoError(t, err)
+
+	advance(time.Hour)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"pager:" + msg.ID}, r.get())
+}
+
+func TestTracker_DeliveredStopsChain(t *testing.T) {
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("pager", time.Minute, nil),
+	}, nil)
+
+	msg := addMessage(s, "A1 Brand woning")
+	tracker.Track(msg)
+	require.NoError(t, tracker.Delivered(msg.ID))
+	assert.Equal(t, 0, tracker.Pending())
+
+	advance(time.Hour)
+	tracker.check(context.Background())
+	assert.Empty(t, r.get(), "delivered messages are not escalated")
+
+	record, ok := s.Message(msg.ID)
+	require.True(t, ok)
+	require.Len(t, record.Acknowledgements, 1)
+	assert.Equal(t, ReceiptAuthor, record.Acknowledgements[0].Author)
+
+	// A receipt before the send returned keeps the message out of the chain
+	early := addMessage(s, "A2 Ongeval")
+	require.NoError(t, tracker.Delivered(early.ID))
+	tracker.Track(early)
+	assert.Equal(t, 0, tracker.Pending())
+
+	// Acknowledged messages keep their acknowledgement
+	acked := addMessage(s, "P 1 Brand")
+	_, err := tracker.Acknowledge(acked.ID, "jan")
+	require.NoError(t, err)
+	require.NoError(t, tracker.Delivered(acked.ID))
+	record, _ = s.Message(acked.ID)
+	assert.Len(t, record.Acknowledgements, 1)
+
+	assert.ErrorIs(t, tracker.Delivered("missing"), store.ErrNotFound)
+}
+
+func TestTracker_FailedStartsChainImmediately(t *testing.T) {
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("pager", 5*time.Minute, nil),
+		r.step("phone", 10*time.Minute, nil),
+	}, nil)
+
+	msg := addMessage(s, "A1 Brand woning")
+	tracker.Failed(msg)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"pager:" + msg.ID}, r.get())
+
+	// Later steps keep their delay relative to the first step
+	advance(4 * time.Minute)
+	tracker.check(context.Background())
+	assert.Len(t, r.get(), 1)
+	advance(time.Minute)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"pager:" + msg.ID, "phone:" + msg.ID}, r.get())
+}
+
+func TestTracker_FailedStepContinuesChain(t *testing.T) {
+	m := metrics.NewMetrics()
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("sms", time.Minute, errors.New("gateway unavailable")),
+		r.step("phone", 2*time.Minute, nil),
+	}, m)
+
+	msg := addMessage(s, "A1 Brand woning")
+	tracker.Track(msg)
+
+	advance(2 * time.Minute)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"sms:" + msg.ID, "phone:" + msg.ID}, r.get())
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Escalations))
+}
+
+func TestTracker_SkippedStepNotCounted(t *testing.T) {
+	m := metrics.NewMetrics()
+	r := &recorder{}
+	tracker, s, advance := newTestTracker(t, []Step{
+		r.step("call", time.Minute, fmt.Errorf("call: %w", notify.ErrSkipped)),
+	}, m)
+
+	msg := addMessage(s, "P 2 Nacontrole")
+	tracker.Track(msg)
+
+	advance(time.Minute)
+	tracker.check(context.Background())
+	assert.Equal(t, []string{"call:" + msg.ID}, r.get())
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.Escalations))
+}
+
+func TestTracker_RunWakesOnFailure(t *testing.T) {
+	r := &recorder{}
+	tracker, s, _ := newTestTracker(t, []Step{r.step("pager", time.Hour, nil)}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tracker.Run(ctx)
+
+	msg := addMessage(s, "A1 Brand woning")
+	tracker.Failed(msg)
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestTracker_TrackWithoutEscalation(t *testing.T) {
+	tracker, _, _ := newTestTracker(t, nil, nil)
+
+	tracker.Track(model.Message{ID: "1"})
+	tracker.Failed(model.Message{ID: "2"})
+	assert.Equal(t, 0, tracker.Pending())
+}
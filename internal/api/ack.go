@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+// ackRequest is the optional body of a POST /api/ack/{id} request
+type ackRequest struct {
+	Author string `json:"author"`
+}
+
+// acknowledge handles POST /api/ack/{id}
+func (s *Server) acknowledge(w http.ResponseWriter, r *http.Request) {
+	var req ackRequest
+	// The body is optional so ntfy http actions can acknowledge without one
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	record, err := s.acks.Acknowledge(r.PathValue("id"), strings.TrimSpace(req.Author))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to acknowledge message")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
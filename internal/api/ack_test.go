@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/ack"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcknowledge(t *testing.T) {
+	s, err := store.Open("", 10, getTestLogger())
+	require.NoError(t, err)
+	tracker := ack.NewTracker(s, nil, nil, getTestLogger())
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s, Acks: tracker}, getTestLogger()).Register(mux)
+
+	r := s.AddMessage(model.Message{Message: "A1 Brand woning"}, true)
+
+	rec := doRequest(mux, http.MethodPost, "/api/ack/"+r.ID, "secret", `{"author": "jan"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var record store.Record
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &record))
+	require.Len(t, record.Acknowledgements, 1)
+	assert.Equal(t, "jan", record.Acknowledgements[0].Author)
+
+	// An empty body acknowledges anonymously
+	rec = doRequest(mux, http.MethodPost, "/api/ack/"+r.ID, "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	got, _ := s.Message(r.ID)
+	assert.Len(t, got.Acknowledgements, 2)
+
+	rec = doRequest(mux, http.MethodPost, "/api/ack/"+r.ID, "secret", `{"author":`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/ack/999", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/ack/"+r.ID, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+// pauseRequest is the optional body of a POST /api/admin/pause request
+type pauseRequest struct {
+	Reason   string `json:"reason"`
+	Author   string `json:"author"`
+	Duration string `json:"duration"` // Resume automatically after this Go duration, e.g. "2h"
+}
+
+// pauseResponse reports whether notification sending is paused
+type pauseResponse struct {
+	Paused bool `json:"paused"`
+	*store.Pause
+}
+
+// getPause handles GET /api/admin/pause
+func (s *Server) getPause(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pauseState())
+}
+
+// pause handles POST /api/admin/pause. Messages are still received, stored
+// and counted while paused, but not notified.
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	var req pauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	p := store.Pause{
+		Reason: strings.TrimSpace(req.Reason),
+		Author: strings.TrimSpace(req.Author),
+		Since:  time.Now().UTC(),
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "duration must be a positive duration such as 2h")
+			return
+		}
+		until := p.Since.Add(d)
+		p.Until = &until
+	}
+	s.store.Pause(p)
+	s.saveStore()
+
+	s.logger.Warn().
+		Str("reason", p.Reason).
+		Str("author", p.Author).
+		Str("duration", req.Duration).
+		Msg("notification sending paused")
+	writeJSON(w, http.StatusOK, s.pauseState())
+}
+
+// resume handles POST /api/admin/resume
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	if s.store.Resume() {
+		s.saveStore()
+		s.logger.Info().Msg("notification sending resumed")
+	}
+	writeJSON(w, http.StatusOK, s.pauseState())
+}
+
+// pauseState returns the current pause
+func (s *Server) pauseState() pauseResponse {
+	p, ok := s.store.Paused()
+	if !ok {
+		return pauseResponse{}
+	}
+	return pauseResponse{Paused: true, Pause: &p}
+}
+
+// saveStore writes a pause or mute to disk right away, so it survives a
+// crash
+func (s *Server) saveStore() {
+	if err := s.store.Save(); err != nil {
+		s.logger.Error().Err(err).Msg("failed to save store")
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := store.Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodGet, "/api/admin/pause", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"paused": false}`, rec.Body.String())
+
+	rec = doRequest(mux, http.MethodPost, "/api/admin/pause", "secret", `{"reason": "ntfy maintenance", "author": "kaije", "duration": "2h"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Paused bool `json:"paused"`
+		store.Pause
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Paused)
+	assert.Equal(t, "ntfy maintenance", resp.Reason)
+	require.NotNil(t, resp.Until)
+	assert.Equal(t, resp.Since.Add(2*time.Hour), *resp.Until)
+
+	// The pause is saved right away
+	reopened, err := store.Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+	_, paused := reopened.Paused()
+	assert.True(t, paused)
+
+	rec = doRequest(mux, http.MethodPost, "/api/admin/resume", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"paused": false}`, rec.Body.String())
+	_, paused = s.Paused()
+	assert.False(t, paused)
+}
+
+func TestPause_Invalid(t *testing.T) {
+	s, err := store.Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodPost, "/api/admin/pause", "secret", `{"duration": "tomorrow"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(mux, http.MethodPost, "/api/admin/pause", "secret", `{`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(mux, http.MethodPost, "/api/admin/pause", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	_, paused := s.Paused()
+	assert.False(t, paused)
+}
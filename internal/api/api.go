@@ -0,0 +1,161 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/ack"
+	"github.com/kaije/p2000-nfty/internal/health"
+	"github.com/kaije/p2000-nfty/internal/receipt"
+	"github.com/kaije/p2000-nfty/internal/stats"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/internal/subscription"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+)
+
+// Services holds the subsystems exposed through the API. Nil services have
+// their endpoints left unregistered.
+type Services struct {
+	Capcodes *capcode.Store
+	Receipts *receipt.Tracker
+	Store    *store.Store
+	Status   *status.Manager
+	Acks     *ack.Tracker
+	Inject   Injector
+	Explain  Explainer
+	Stats    *stats.Aggregator
+	Backends *health.Backends
+
+	// Self-service subscriptions, registered with the admin token or
+	// RegistrationToken when set
+	Subscriptions     *subscription.Store
+	RegistrationToken string
+}
+
+// Server exposes the HTTP management API
+type Server struct {
+	token     string
+	capcodes  *capcode.Store
+	receipts  *receipt.Tracker
+	store     *store.Store
+	status    *status.Manager
+	acks      *ack.Tracker
+	inject    Injector
+	explainer Explainer
+	stats     *stats.Aggregator
+	backends  *health.Backends
+	logger    zerolog.Logger
+
+	subscriptions     *subscription.Store
+	registrationToken string
+}
+
+// NewServer creates a new API server. Endpoints are only registered when a
+// token is configured.
+func NewServer(token string, services Services, logger zerolog.Logger) *Server {
+	return &Server{
+		token:     token,
+		capcodes:  services.Capcodes,
+		receipts:  services.Receipts,
+		store:     services.Store,
+		status:    services.Status,
+		acks:      services.Acks,
+		inject:    services.Inject,
+		explainer: services.Explain,
+		stats:     services.Stats,
+		backends:  services.Backends,
+		logger:    logger,
+
+		subscriptions:     services.Subscriptions,
+		registrationToken: services.RegistrationToken,
+	}
+}
+
+// Register adds the API routes to mux
+func (s *Server) Register(mux *http.ServeMux) {
+	if s.token == "" {
+		s.logger.Info().Msg("api token not configured, admin API disabled")
+		return
+	}
+
+	if s.capcodes != nil {
+		mux.HandleFunc("GET /api/capcodes/{code}", s.authenticated(s.getCapcode))
+		mux.HandleFunc("PUT /api/capcodes/{code}", s.authenticated(s.putCapcode))
+		mux.HandleFunc("DELETE /api/capcodes/{code}", s.authenticated(s.deleteCapcode))
+	}
+	if s.receipts != nil {
+		mux.HandleFunc("GET /api/receipts/{id}", s.authenticated(s.getReceipt))
+	}
+	if s.store != nil {
+		mux.HandleFunc("GET /api/messages", s.authenticated(s.listMessages))
+		mux.HandleFunc("GET /api/messages/export", s.authenticated(s.exportMessages))
+		mux.HandleFunc("GET /api/messages/{id}", s.authenticated(s.getMessage))
+		mux.HandleFunc("GET /api/messages/{id}/trace", s.authenticated(s.getTrace))
+		mux.HandleFunc("POST /api/messages/{id}/annotations", s.authenticated(s.annotateMessage))
+		mux.HandleFunc("GET /api/incidents.geojson", s.authenticated(s.listIncidents))
+		mux.HandleFunc("GET /map", s.showMap)
+		mux.HandleFunc("GET /api/admin/pause", s.authenticated(s.getPause))
+		mux.HandleFunc("POST /api/admin/pause", s.authenticated(s.pause))
+		mux.HandleFunc("POST /api/admin/resume", s.authenticated(s.resume))
+		mux.HandleFunc("GET /api/mutes", s.authenticated(s.listMutes))
+		mux.HandleFunc("POST /api/mutes", s.authenticated(s.createMute))
+		mux.HandleFunc("DELETE /api/mutes/{kind}/{target}", s.authenticated(s.deleteMute))
+	}
+	if s.status != nil {
+		mux.HandleFunc("GET /api/status", s.authenticated(s.getStatus))
+	}
+	if s.backends != nil {
+		mux.HandleFunc("GET /api/backends", s.authenticated(s.listBackends))
+	}
+	if s.acks != nil {
+		mux.HandleFunc("POST /api/ack/{id}", s.authenticated(s.acknowledge))
+	}
+	if s.inject != nil {
+		mux.HandleFunc("POST /api/test-notify", s.authenticated(s.testNotify))
+	}
+	if s.explainer != nil {
+		mux.HandleFunc("POST /api/explain", s.authenticated(s.explain))
+	}
+	if s.stats != nil {
+		mux.HandleFunc("GET /api/stats", s.authenticated(s.getStats))
+		mux.HandleFunc("GET /api/stats/series", s.authenticated(s.getStatsSeries))
+		mux.HandleFunc("GET /api/grafana/{$}", s.authenticated(s.grafanaTest))
+		mux.HandleFunc("POST /api/grafana/search", s.authenticated(s.grafanaSearch))
+		mux.HandleFunc("POST /api/grafana/query", s.authenticated(s.grafanaQuery))
+	}
+	if s.subscriptions != nil {
+		mux.HandleFunc("POST /api/subscriptions", s.canRegister(s.createSubscription))
+		mux.HandleFunc("GET /api/subscriptions", s.authenticated(s.listSubscriptions))
+		mux.HandleFunc("GET /api/subscriptions/{id}", s.ownsSubscription(s.getSubscription))
+		mux.HandleFunc("PUT /api/subscriptions/{id}", s.ownsSubscription(s.putSubscription))
+		mux.HandleFunc("DELETE /api/subscriptions/{id}", s.ownsSubscription(s.deleteSubscription))
+	}
+}
+
+// authenticated wraps a handler with bearer token authentication
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
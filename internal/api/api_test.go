@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func newTestMux(token string, store *capcode.Store) *http.ServeMux {
+	mux := http.NewServeMux()
+	NewServer(token, Services{Capcodes: store}, getTestLogger()).Register(mux)
+	return mux
+}
+
+func doRequest(mux http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRegister_DisabledWithoutToken(t *testing.T) {
+	store := capcode.NewStore(capcode.NewLookupFromRecords(nil), "")
+	mux := newTestMux("", store)
+
+	rec := doRequest(mux, http.MethodGet, "/api/capcodes/0101001", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAuthentication(t *testing.T) {
+	store := capcode.NewStore(capcode.NewLookupFromRecords(nil), "")
+	mux := newTestMux("secret", store)
+
+	rec := doRequest(mux, http.MethodGet, "/api/capcodes/0101001", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/capcodes/0101001", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/capcodes/0101001", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCapcodeCRUD(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords(nil)
+	mux := newTestMux("secret", capcode.NewStore(lookup, ""))
+
+	rec := doRequest(mux, http.MethodPut, "/api/capcodes/0101001", "secret", `{"agency": "Brandweer", "region": "Utrecht"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"capcode":"0101001"`)
+
+	rec = doRequest(mux, http.MethodPut, "/api/capcodes/0101001", "secret", `{"agency": "Brandweer", "station": "Noord"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/capcodes/101001", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"station":"Noord"`)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	rec = doRequest(mux, http.MethodDelete, "/api/capcodes/0101001", "secret", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Nil(t, lookup.Get("0101001"))
+
+	rec = doRequest(mux, http.MethodDelete, "/api/capcodes/0101001", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPutCapcode_InvalidBody(t *testing.T) {
+	mux := newTestMux("secret", capcode.NewStore(capcode.NewLookupFromRecords(nil), ""))
+
+	rec := doRequest(mux, http.MethodPut, "/api/capcodes/0101001", "secret", `not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPutCapcode_PersistFailure(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{{Capcode: "0101001", Agency: "Brandweer"}})
+	mux := newTestMux("secret", capcode.NewStore(lookup, filepath.Join(t.TempDir(), "missing", "capcodes.csv")))
+
+	rec := doRequest(mux, http.MethodPut, "/api/capcodes/0101001", "secret", `{"agency": "Ambulance"}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Brandweer", lookup.Get("0101001").Agency, "unchanged when not persisted")
+
+	rec = doRequest(mux, http.MethodDelete, "/api/capcodes/0101001", "secret", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotNil(t, lookup.Get("0101001"))
+}
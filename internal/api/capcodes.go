@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+)
+
+// capcodeRequest is the body of a PUT /api/capcodes/{code} request
+type capcodeRequest struct {
+	Agency   string `json:"agency"`
+	Region   string `json:"region"`
+	Station  string `json:"station"`
+	Function string `json:"function"`
+}
+
+// getCapcode handles GET /api/capcodes/{code}
+func (s *Server) getCapcode(w http.ResponseWriter, r *http.Request) {
+	info := s.capcodes.Lookup().Get(r.PathValue("code"))
+	if info == nil {
+		writeError(w, http.StatusNotFound, "capcode not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// putCapcode handles PUT /api/capcodes/{code}
+func (s *Server) putCapcode(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	var req capcodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	existed := s.capcodes.Lookup().Get(code) != nil
+	info := capcode.CapcodeInfo{
+		Capcode:  code,
+		Agency:   req.Agency,
+		Region:   req.Region,
+		Station:  req.Station,
+		Function: req.Function,
+	}
+	if err := s.capcodes.Put(info); err != nil {
+		s.logger.Error().Err(err).Str("capcode", code).Msg("failed to persist capcode")
+		writeError(w, http.StatusInternalServerError, "failed to persist capcode")
+		return
+	}
+
+	s.logger.Info().
+		Str("capcode", code).
+		Bool("persistent", s.capcodes.Persistent()).
+		Msg("capcode updated via API")
+
+	status := http.StatusOK
+	if !existed {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, info)
+}
+
+// deleteCapcode handles DELETE /api/capcodes/{code}
+func (s *Server) deleteCapcode(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	existed, err := s.capcodes.Delete(code)
+	if err != nil {
+		s.logger.Error().Err(err).Str("capcode", code).Msg("failed to persist capcode")
+		writeError(w, http.StatusInternalServerError, "failed to persist capcode")
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "capcode not found")
+		return
+	}
+
+	s.logger.Info().
+		Str("capcode", code).
+		Bool("persistent", s.capcodes.Persistent()).
+		Msg("capcode deleted via API")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// Explainer traces how the forwarder handles a message, without forwarding
+// it or changing any state
+type Explainer func(msg model.Message) Explanation
+
+// Explanation is the trace of the filters and rules evaluated for a message
+// and the resulting routing decision
+type Explanation struct {
+	Message      model.Message      `json:"message"`               // Message as enriched and changed by the rules
+	Delivery     notifier.Delivery  `json:"delivery"`              // Routes, priority and tags set by the rules
+	TestAlarm    *TestAlarmTrace    `json:"test_alarm,omitempty"`  // Set when test alarm detection is enabled
+	Maintenance  *MaintenanceTrace  `json:"maintenance,omitempty"` // Set when sent during a maintenance window
+	Rules        []rules.Evaluation `json:"rules,omitempty"`
+	Script       *ScriptTrace       `json:"script,omitempty"` // Set when a message script is configured
+	Filter       filter.Step        `json:"filter"`
+	TypeAllowed  bool               `json:"type_allowed"` // Not suppressed by message type or as a numeric page
+	Forward      bool               `json:"forward"`
+	Reason       string             `json:"reason"`
+	Destinations []string           `json:"destinations,omitempty"`
+}
+
+// TestAlarmTrace is the outcome of test alarm detection
+type TestAlarmTrace struct {
+	Detected bool   `json:"detected"`
+	Action   string `json:"action,omitempty"`
+}
+
+// ScriptTrace is the decision of the message script
+type ScriptTrace struct {
+	Drop         bool     `json:"drop,omitempty"`
+	Destinations []string `json:"destinations,omitempty"`
+	Priority     int      `json:"priority,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Text         string   `json:"text,omitempty"`  // Replaced message text
+	Error        string   `json:"error,omitempty"` // The script failed and left the message unchanged
+}
+
+// MaintenanceTrace is the maintenance window a message was sent in
+type MaintenanceTrace struct {
+	Window string `json:"window"`
+	Action string `json:"action"`
+}
+
+// explain handles POST /api/explain with a message in the feed format
+func (s *Server) explain(w http.ResponseWriter, r *http.Request) {
+	var msg model.Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if msg.Message == "" && len(msg.Capcodes) == 0 {
+		writeError(w, http.StatusBadRequest, "message or capcodes are required")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.explainer(msg))
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	explainer := func(msg model.Message) Explanation {
+		return Explanation{
+			Message: msg,
+			Filter:  filter.Step{Filter: "capcode", Forward: true},
+			Forward: true,
+			Reason:  "accepted by the filters",
+		}
+	}
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Explain: explainer}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodPost, "/api/explain", "secret", `{"type": "FLEX", "capcodes": ["0101001"], "message": "A1 Brand woning"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var exp Explanation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &exp))
+	assert.True(t, exp.Forward)
+	assert.Equal(t, "accepted by the filters", exp.Reason)
+	assert.Equal(t, []string{"0101001"}, exp.Message.Capcodes)
+
+	rec = doRequest(mux, http.MethodPost, "/api/explain", "secret", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/explain", "secret", `{"message":`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/explain", "", `{"message": "A1"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/stats"
+)
+
+// grafanaQuery is the body of a POST /api/grafana/query request of the
+// Grafana JSON datasource
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs int64 `json:"intervalMs"`
+	Targets    []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is a time series in the Grafana JSON datasource format,
+// with datapoints as [value, unix milliseconds]
+type grafanaSeries struct {
+	Target     string     `json:"target"`
+	Datapoints [][2]int64 `json:"datapoints"`
+}
+
+// grafanaTargets lists the queryable targets: a metric, optionally grouped
+func grafanaTargets() []string {
+	var targets []string
+	for _, metric := range []string{stats.MetricReceived, stats.MetricForwarded} {
+		targets = append(targets, metric)
+		for _, by := range []string{stats.ByAgency, stats.ByRegion, stats.ByDiscipline} {
+			targets = append(targets, metric+" by "+by)
+		}
+	}
+	return targets
+}
+
+// grafanaTest handles GET /api/grafana/, the datasource connection test
+func (s *Server) grafanaTest(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// grafanaSearch handles POST /api/grafana/search
+func (s *Server) grafanaSearch(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, grafanaTargets())
+}
+
+// grafanaQuery handles POST /api/grafana/query. The interval is rounded up
+// to whole hours, the resolution of the statistics.
+func (s *Server) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !req.Range.From.Before(req.Range.To) {
+		writeError(w, http.StatusBadRequest, "range from must be before to")
+		return
+	}
+
+	step := time.Duration(req.IntervalMs) * time.Millisecond
+	step = (step + time.Hour - 1).Truncate(time.Hour)
+	if step < time.Hour {
+		step = time.Hour
+	}
+
+	result := []grafanaSeries{}
+	for _, target := range req.Targets {
+		metric, by, _ := strings.Cut(target.Target, " by ")
+		series, err := s.stats.Series(stats.SeriesQuery{
+			Since:  req.Range.From,
+			Until:  req.Range.To,
+			Step:   step,
+			Metric: metric,
+			By:     by,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("target %q: %s", target.Target, err))
+			return
+		}
+		for _, ts := range series {
+			gs := grafanaSeries{Target: ts.Name, Datapoints: make([][2]int64, 0, len(ts.Points))}
+			for _, p := range ts.Points {
+				gs.Datapoints = append(gs.Datapoints, [2]int64{int64(p.Value), p.Time.UnixMilli()})
+			}
+			result = append(result, gs)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
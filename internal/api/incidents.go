@@ -0,0 +1,43 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+// mapPage is a Leaflet map of the incidents served by GET /api/incidents.geojson
+//
+//go:embed map.html
+var mapPage []byte
+
+// listIncidents handles GET /api/incidents.geojson?limit=N, the most recent
+// geocoded messages as a GeoJSON FeatureCollection
+func (s *Server) listIncidents(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r, defaultMessageLimit)
+	if !ok {
+		return
+	}
+
+	var incidents []store.Record
+	for _, record := range s.store.Messages(0) {
+		if record.Message.Coordinates == nil {
+			continue
+		}
+		incidents = append(incidents, record)
+		if limit > 0 && len(incidents) == limit {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	store.WriteGeoJSON(w, incidents)
+}
+
+// showMap handles GET /map. The page itself is public; it asks for the API
+// token to load the incidents.
+func (s *Server) showMap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(mapPage)
+}
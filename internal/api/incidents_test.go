@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidents_GeoJSON(t *testing.T) {
+	mux, s := newMessageMux(t)
+	s.AddMessage(model.Message{Message: "A1 Damrak 1012LG Amsterdam", Coordinates: &model.Coordinates{Lat: 52.3756, Lon: 4.8938}}, true)
+	s.AddMessage(model.Message{Message: "Proefalarm"}, false)
+	latest := s.AddMessage(model.Message{Message: "A2 Domplein Utrecht", Coordinates: &model.Coordinates{Lat: 52.0907, Lon: 5.1214}}, true)
+
+	rec := doRequest(mux, http.MethodGet, "/api/incidents.geojson", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/incidents.geojson?limit=1", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/geo+json", rec.Header().Get("Content-Type"))
+
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				ID string `json:"id"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &collection))
+	require.Len(t, collection.Features, 1)
+	assert.Equal(t, latest.ID, collection.Features[0].Properties.ID)
+	assert.Equal(t, []float64{5.1214, 52.0907}, collection.Features[0].Geometry.Coordinates)
+
+	// Messages without coordinates are not counted towards the limit
+	rec = doRequest(mux, http.MethodGet, "/api/incidents.geojson?limit=2", "secret", "")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &collection))
+	assert.Len(t, collection.Features, 2)
+}
+
+func TestIncidents_MapPage(t *testing.T) {
+	mux, _ := newMessageMux(t)
+
+	rec := doRequest(mux, http.MethodGet, "/map", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Body.String(), "/api/incidents.geojson")
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+const defaultMessageLimit = 100
+
+// annotationRequest is the body of a POST /api/messages/{id}/annotations request
+type annotationRequest struct {
+	Text   string `json:"text"`
+	Author string `json:"author"`
+}
+
+// listMessages handles GET /api/messages?limit=N
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r, defaultMessageLimit)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.store.Messages(limit))
+}
+
+// getMessage handles GET /api/messages/{id}
+func (s *Server) getMessage(w http.ResponseWriter, r *http.Request) {
+	record, ok := s.store.Message(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// traceResponse is the body of a GET /api/messages/{id}/trace response
+type traceResponse struct {
+	ID        string             `json:"id"`
+	Forwarded bool               `json:"forwarded"`
+	Trace     []store.TraceEvent `json:"trace"`
+}
+
+// getTrace handles GET /api/messages/{id}/trace
+func (s *Server) getTrace(w http.ResponseWriter, r *http.Request) {
+	record, ok := s.store.Message(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	trace := record.Trace
+	if trace == nil {
+		trace = []store.TraceEvent{}
+	}
+	writeJSON(w, http.StatusOK, traceResponse{ID: record.ID, Forwarded: record.Forwarded, Trace: trace})
+}
+
+// annotateMessage handles POST /api/messages/{id}/annotations
+func (s *Server) annotateMessage(w http.ResponseWriter, r *http.Request) {
+	var req annotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		writeError(w, http.StatusBadRequest, "annotation text is required")
+		return
+	}
+
+	record, err := s.store.Annotate(r.PathValue("id"), store.Annotation{
+		Text:   req.Text,
+		Author: req.Author,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "message not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to annotate message")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, record)
+}
+
+// exportMessages handles GET /api/messages/export?format=json|csv|geojson
+func (s *Server) exportMessages(w http.ResponseWriter, r *http.Request) {
+	records := s.store.Messages(0)
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="messages.json"`)
+		store.WriteJSON(w, records)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="messages.csv"`)
+		store.WriteCSV(w, records)
+	case "geojson":
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Header().Set("Content-Disposition", `attachment; filename="messages.geojson"`)
+		store.WriteGeoJSON(w, records)
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported export format")
+	}
+}
+
+// parseLimit reads the limit query parameter, writing an error response
+// when it is invalid
+func parseLimit(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return fallback, true
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return 0, false
+	}
+	return limit, true
+}
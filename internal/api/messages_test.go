@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMessageMux(t *testing.T) (*http.ServeMux, *store.Store) {
+	s, err := store.Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s}, getTestLogger()).Register(mux)
+	return mux, s
+}
+
+func TestMessages_ListAndGet(t *testing.T) {
+	mux, s := newMessageMux(t)
+	r := s.AddMessage(model.Message{Message: "Brand woning"}, true)
+	s.AddMessage(model.Message{Message: "Ambulance"}, false)
+
+	rec := doRequest(mux, http.MethodGet, "/api/messages?limit=1", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Ambulance")
+	assert.NotContains(t, rec.Body.String(), "Brand woning")
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages?limit=abc", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/"+r.ID, "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Brand woning")
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/999", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMessages_Trace(t *testing.T) {
+	mux, s := newMessageMux(t)
+	r := s.AddMessage(model.Message{Message: "Brand woning"}, false)
+
+	rec := doRequest(mux, http.MethodGet, "/api/messages/"+r.ID+"/trace", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id": "`+r.ID+`", "forwarded": false, "trace": []}`, rec.Body.String())
+
+	require.NoError(t, s.Trace(r.ID, store.TraceEvent{Step: store.TraceDropped, Detail: "rejected by the filters"}))
+	rec = doRequest(mux, http.MethodGet, "/api/messages/"+r.ID+"/trace", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"step":"dropped","detail":"rejected by the filters"`)
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/999/trace", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMessages_Annotate(t *testing.T) {
+	mux, s := newMessageMux(t)
+	r := s.AddMessage(model.Message{Message: "Brand woning"}, true)
+
+	rec := doRequest(mux, http.MethodPost, "/api/messages/"+r.ID+"/annotations", "secret", `{"text": "false alarm", "author": "jan"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), "false alarm")
+
+	rec = doRequest(mux, http.MethodPost, "/api/messages/"+r.ID+"/annotations", "secret", `{"text": "  "}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/messages/999/annotations", "secret", `{"text": "x"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMessages_Export(t *testing.T) {
+	mux, s := newMessageMux(t)
+	r := s.AddMessage(model.Message{Message: "Brand woning"}, true)
+	_, err := s.Annotate(r.ID, store.Annotation{Text: "our pump attended"})
+	require.NoError(t, err)
+
+	rec := doRequest(mux, http.MethodGet, "/api/messages/export?format=csv", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "our pump attended")
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/export", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"annotations"`)
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/export?format=geojson", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"FeatureCollection"`)
+
+	rec = doRequest(mux, http.MethodGet, "/api/messages/export?format=xml", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+)
+
+// muteRequest is the body of a POST /api/mutes request, muting either a
+// capcode or a rule
+type muteRequest struct {
+	Capcode  string `json:"capcode"`
+	Rule     string `json:"rule"`
+	Duration string `json:"duration"` // Go duration, e.g. "4h"
+	Reason   string `json:"reason"`
+	Author   string `json:"author"`
+}
+
+// listMutes handles GET /api/mutes
+func (s *Server) listMutes(w http.ResponseWriter, r *http.Request) {
+	mutes := s.store.Mutes()
+	if mutes == nil {
+		mutes = []store.Mute{}
+	}
+	writeJSON(w, http.StatusOK, mutes)
+}
+
+// createMute handles POST /api/mutes
+func (s *Server) createMute(w http.ResponseWriter, r *http.Request) {
+	var req muteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m := store.Mute{
+		Reason: strings.TrimSpace(req.Reason),
+		Author: strings.TrimSpace(req.Author),
+		Since:  time.Now().UTC(),
+	}
+	capcode, rule := strings.TrimSpace(req.Capcode), strings.TrimSpace(req.Rule)
+	switch {
+	case capcode != "" && rule == "":
+		m.Kind, m.Target = store.MuteCapcode, capcode
+	case rule != "" && capcode == "":
+		m.Kind, m.Target = store.MuteRule, rule
+	default:
+		writeError(w, http.StatusBadRequest, "either capcode or rule is required")
+		return
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		writeError(w, http.StatusBadRequest, "duration must be a positive duration such as 4h")
+		return
+	}
+	m.Until = m.Since.Add(d)
+
+	m = s.store.Mute(m)
+	s.saveStore()
+
+	s.logger.Info().
+		Str("kind", m.Kind).
+		Str("target", m.Target).
+		Time("until", m.Until).
+		Str("reason", m.Reason).
+		Msg("muted")
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// deleteMute handles DELETE /api/mutes/{kind}/{target}
+func (s *Server) deleteMute(w http.ResponseWriter, r *http.Request) {
+	kind, target := r.PathValue("kind"), r.PathValue("target")
+	if kind != store.MuteCapcode && kind != store.MuteRule {
+		writeError(w, http.StatusNotFound, "unknown mute kind")
+		return
+	}
+	if !s.store.Unmute(kind, target) {
+		writeError(w, http.StatusNotFound, "not muted")
+		return
+	}
+	s.saveStore()
+
+	s.logger.Info().Str("kind", kind).Str("target", target).Msg("unmuted")
+	w.WriteHeader(http.StatusNoContent)
+}
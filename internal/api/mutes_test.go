@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := store.Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodGet, "/api/mutes", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = doRequest(mux, http.MethodPost, "/api/mutes", "secret", `{"capcode": "0101001", "duration": "4h", "reason": "oefening kazerne"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var m store.Mute
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	assert.Equal(t, store.MuteCapcode, m.Kind)
+	assert.Equal(t, "101001", m.Target)
+	assert.Equal(t, m.Since.Add(4*time.Hour), m.Until)
+
+	rec = doRequest(mux, http.MethodPost, "/api/mutes", "secret", `{"rule": "fire-utrecht", "duration": "30m"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// Mutes are saved right away
+	reopened, err := store.Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+	assert.Len(t, reopened.Mutes(), 2)
+
+	rec = doRequest(mux, http.MethodGet, "/api/mutes", "secret", "")
+	var mutes []store.Mute
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &mutes))
+	require.Len(t, mutes, 2)
+	assert.Equal(t, "fire-utrecht", mutes[0].Target)
+
+	rec = doRequest(mux, http.MethodDelete, "/api/mutes/capcode/0101001", "secret", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(mux, http.MethodDelete, "/api/mutes/capcode/0101001", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(mux, http.MethodDelete, "/api/mutes/station/0101001", "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Len(t, s.Mutes(), 1)
+}
+
+func TestCreateMute_Invalid(t *testing.T) {
+	s, err := store.Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Store: s}, getTestLogger()).Register(mux)
+
+	for _, body := range []string{
+		`{"duration": "4h"}`,
+		`{"capcode": "0101001", "rule": "fire", "duration": "4h"}`,
+		`{"capcode": "0101001"}`,
+		`{"capcode": "0101001", "duration": "-1h"}`,
+		``,
+	} {
+		rec := doRequest(mux, http.MethodPost, "/api/mutes", "secret", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	rec := doRequest(mux, http.MethodGet, "/api/mutes", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, s.Mutes())
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+)
+
+// getReceipt handles GET /api/receipts/{id}
+func (s *Server) getReceipt(w http.ResponseWriter, r *http.Request) {
+	receipt, ok := s.receipts.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "receipt not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, receipt)
+}
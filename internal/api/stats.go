@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/stats"
+)
+
+// defaultStatsWindow is the window of GET /api/stats without since
+const defaultStatsWindow = 24 * time.Hour
+
+// getStats handles GET /api/stats?since=RFC3339&until=RFC3339, the message
+// counts per hour, agency, region and discipline. The window defaults to
+// the last 24 hours.
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request) {
+	since, until, ok := statsWindow(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.stats.Aggregate(since, until))
+}
+
+// seriesResponse is the response of GET /api/stats/series
+type seriesResponse struct {
+	Since  time.Time      `json:"since"`
+	Until  time.Time      `json:"until"`
+	Step   string         `json:"step"`
+	Metric string         `json:"metric"`
+	By     string         `json:"by,omitempty"`
+	Series []stats.Series `json:"series"`
+}
+
+// getStatsSeries handles GET /api/stats/series?since=RFC3339&until=RFC3339
+// &step=1h&metric=received&by=discipline, the message counts per step as
+// time series for dashboards
+func (s *Server) getStatsSeries(w http.ResponseWriter, r *http.Request) {
+	since, until, ok := statsWindow(w, r)
+	if !ok {
+		return
+	}
+
+	q := stats.SeriesQuery{
+		Since:  since,
+		Until:  until,
+		Step:   time.Hour,
+		Metric: r.URL.Query().Get("metric"),
+		By:     r.URL.Query().Get("by"),
+	}
+	if q.Metric == "" {
+		q.Metric = stats.MetricReceived
+	}
+	if raw := r.URL.Query().Get("step"); raw != "" {
+		step, err := time.ParseDuration(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid step, expected a duration such as 1h or 24h")
+			return
+		}
+		q.Step = step
+	}
+
+	series, err := s.stats.Series(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, seriesResponse{
+		Since:  since,
+		Until:  until,
+		Step:   q.Step.String(),
+		Metric: q.Metric,
+		By:     q.By,
+		Series: series,
+	})
+}
+
+// statsWindow parses the since and until query parameters, writing an error
+// response when they are invalid. The window defaults to the last 24 hours.
+func statsWindow(w http.ResponseWriter, r *http.Request) (since, until time.Time, ok bool) {
+	until = time.Now()
+	if raw := r.URL.Query().Get("until"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid until, expected an RFC 3339 time")
+			return since, until, false
+		}
+		until = t
+	}
+
+	since = until.Add(-defaultStatsWindow)
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since, expected an RFC 3339 time")
+			return since, until, false
+		}
+		since = t
+	}
+	if !since.Before(until) {
+		writeError(w, http.StatusBadRequest, "since must be before until")
+		return since, until, false
+	}
+	return since, until, true
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/stats"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStats(t *testing.T) {
+	aggregator := stats.NewAggregator(48 * time.Hour)
+	msg := model.Message{CapcodeInfo: []capcode.CapcodeInfo{{Agency: "Brandweer", Region: "Utrecht"}}}
+	aggregator.Record(msg, time.Now().Add(-time.Hour), true)
+	aggregator.Record(msg, time.Now().Add(-30*time.Hour), false)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Stats: aggregator}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodGet, "/api/stats", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var agg stats.Aggregate
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &agg))
+	assert.Equal(t, stats.Count{Received: 1, Forwarded: 1}, agg.Total)
+	assert.Equal(t, stats.Count{Received: 1, Forwarded: 1}, agg.Disciplines["brandweer"])
+	assert.Equal(t, stats.Count{Received: 1, Forwarded: 1}, agg.Regions["Utrecht"])
+	assert.Len(t, agg.Hours, 1)
+
+	since := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	rec = doRequest(mux, http.MethodGet, "/api/stats?since="+since, "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &agg))
+	assert.Equal(t, stats.Count{Received: 2, Forwarded: 1}, agg.Total)
+
+	rec = doRequest(mux, http.MethodGet, "/api/stats?since=yesterday", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/stats?since=2024-03-13T00:00:00Z&until=2024-03-12T00:00:00Z", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, "/api/stats", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func newStatsMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	aggregator := stats.NewAggregator(48 * time.Hour)
+	fire := model.Message{CapcodeInfo: []capcode.CapcodeInfo{{Agency: "Brandweer", Region: "Utrecht"}}}
+	police := model.Message{CapcodeInfo: []capcode.CapcodeInfo{{Agency: "Politie", Region: "Utrecht"}}}
+	aggregator.Record(fire, time.Now().Add(-time.Hour), true)
+	aggregator.Record(police, time.Now().Add(-time.Hour), false)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Stats: aggregator}, getTestLogger()).Register(mux)
+	return mux
+}
+
+func TestGetStatsSeries(t *testing.T) {
+	mux := newStatsMux(t)
+
+	rec := doRequest(mux, http.MethodGet, "/api/stats/series", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Step   string         `json:"step"`
+		Metric string         `json:"metric"`
+		Series []stats.Series `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "1h0m0s", resp.Step)
+	assert.Equal(t, "received", resp.Metric)
+	require.Len(t, resp.Series, 1)
+	total := 0
+	for _, p := range resp.Series[0].Points {
+		total += p.Value
+	}
+	assert.Equal(t, 2, total)
+
+	rec = doRequest(mux, http.MethodGet, "/api/stats/series?metric=forwarded&by=discipline&step=6h", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Series, 2)
+	assert.Equal(t, "brandweer", resp.Series[0].Name)
+	assert.Equal(t, 6*time.Hour, resp.Series[0].Points[1].Time.Sub(resp.Series[0].Points[0].Time))
+
+	for _, query := range []string{"step=30m", "step=soon", "metric=dropped", "by=station"} {
+		rec = doRequest(mux, http.MethodGet, "/api/stats/series?"+query, "secret", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestGrafanaDatasource(t *testing.T) {
+	mux := newStatsMux(t)
+
+	rec := doRequest(mux, http.MethodGet, "/api/grafana/", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(mux, http.MethodGet, "/api/grafana/", "secret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/grafana/search", "secret", `{"target": ""}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"received by discipline"`)
+
+	now := time.Now().UTC().Truncate(time.Hour).Add(30 * time.Minute)
+	body := `{"range": {"from": "` + now.Add(-3*time.Hour).Format(time.RFC3339) + `", "to": "` + now.Format(time.RFC3339) + `"},
+		"intervalMs": 60000, "targets": [{"target": "received"}, {"target": "received by agency"}]}`
+	rec = doRequest(mux, http.MethodPost, "/api/grafana/query", "secret", body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var series []struct {
+		Target     string     `json:"target"`
+		Datapoints [][2]int64 `json:"datapoints"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &series))
+	require.Len(t, series, 3)
+	assert.Equal(t, []string{"received", "Brandweer", "Politie"}, []string{series[0].Target, series[1].Target, series[2].Target})
+	require.Len(t, series[0].Datapoints, 4, "one point per hour")
+	assert.Equal(t, now.Add(-3*time.Hour).Truncate(time.Hour).UnixMilli(), series[0].Datapoints[0][1])
+
+	rec = doRequest(mux, http.MethodPost, "/api/grafana/query", "secret", `{"range": {"from": "`+now.Format(time.RFC3339)+`", "to": "`+now.Add(time.Hour).Format(time.RFC3339)+`"}, "targets": [{"target": "dropped"}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doRequest(mux, http.MethodPost, "/api/grafana/query", "secret", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+)
+
+// getStatus handles GET /api/status
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status.Snapshot())
+}
+
+// listBackends handles GET /api/backends, the delivery state of every
+// destination notified since startup
+func (s *Server) listBackends(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backends.List())
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/health"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatus(t *testing.T) {
+	manager := status.NewManager(nil)
+	manager.SetConnected(true)
+	manager.MessageReceived()
+	manager.SetBreakerState("https://ntfy.sh/p2000", notifier.BreakerOpen)
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Status: manager}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodGet, "/api/status", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var snap status.Snapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snap))
+	assert.True(t, snap.WebsocketConnected)
+	assert.Equal(t, int64(1), snap.MessagesReceived)
+	assert.NotNil(t, snap.LastMessage)
+	assert.Equal(t, notifier.BreakerOpen, snap.CircuitBreakers["https://ntfy.sh/p2000"])
+	assert.Contains(t, rec.Body.String(), `"https://ntfy.sh/p2000":"open"`)
+
+	rec = doRequest(mux, http.MethodGet, "/api/status", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestListBackends(t *testing.T) {
+	backends := health.NewBackends()
+	backends.Record(notifier.DeliveryResult{Destination: "pager", Success: true})
+	backends.Record(notifier.DeliveryResult{Destination: "ntfy", Success: false, Err: errors.New("unexpected status code: 502")})
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Backends: backends}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodGet, "/api/backends", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list []health.Backend
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "ntfy", list[0].Name)
+	assert.Equal(t, "unexpected status code: 502", list[0].LastError)
+	assert.NotNil(t, list[0].LastFailure)
+	assert.Equal(t, 1, list[0].Failed)
+	assert.Equal(t, "pager", list[1].Name)
+	assert.NotNil(t, list[1].LastSuccess)
+
+	rec = doRequest(mux, http.MethodGet, "/api/backends", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/subscription"
+)
+
+// subscriptionRequest is the body of a POST /api/subscriptions or
+// PUT /api/subscriptions/{id} request
+type subscriptionRequest struct {
+	Name     string   `json:"name"`
+	Topic    string   `json:"topic"`
+	Capcodes []string `json:"capcodes"`
+	Regions  []string `json:"regions"`
+	Stations []string `json:"stations"`
+}
+
+// createdSubscription is the response to a new subscription, the only time
+// its secret is shown
+type createdSubscription struct {
+	subscription.Subscription
+	Secret string `json:"secret"`
+}
+
+// bearerToken returns the bearer token of a request
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+// matchesToken reports whether token equals expected, which must be set
+func matchesToken(token, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// canRegister wraps a handler accepting the admin or the registration token
+func (s *Server) canRegister(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if !matchesToken(token, s.token) && !matchesToken(token, s.registrationToken) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ownsSubscription wraps a handler accepting the admin token or the secret
+// of the subscription in the path
+func (s *Server) ownsSubscription(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if !matchesToken(token, s.token) && !s.subscriptions.Verify(r.PathValue("id"), token) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// decodeSubscription reads a subscription from the request body
+func decodeSubscription(r *http.Request) (subscription.Subscription, error) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return subscription.Subscription{}, errors.New("invalid request body")
+	}
+	return subscription.Subscription{
+		Name:     req.Name,
+		Topic:    req.Topic,
+		Capcodes: req.Capcodes,
+		Regions:  req.Regions,
+		Stations: req.Stations,
+	}, nil
+}
+
+// createSubscription handles POST /api/subscriptions
+func (s *Server) createSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, err := decodeSubscription(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := sub.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sub, secret, err := s.subscriptions.Create(sub)
+	if errors.Is(err, subscription.ErrLimit) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to persist subscription")
+		writeError(w, http.StatusInternalServerError, "failed to persist subscription")
+		return
+	}
+
+	s.logger.Info().
+		Str("subscription", sub.ID).
+		Str("topic", sub.Topic).
+		Bool("persistent", s.subscriptions.Persistent()).
+		Msg("subscription created via API")
+
+	writeJSON(w, http.StatusCreated, createdSubscription{Subscription: sub, Secret: secret})
+}
+
+// listSubscriptions handles GET /api/subscriptions
+func (s *Server) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.subscriptions.List())
+}
+
+// getSubscription handles GET /api/subscriptions/{id}
+func (s *Server) getSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscriptions.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "subscription not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// putSubscription handles PUT /api/subscriptions/{id}
+func (s *Server) putSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	sub, err := decodeSubscription(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := sub.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sub, err = s.subscriptions.Update(id, sub)
+	if errors.Is(err, subscription.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "subscription not found")
+		return
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("subscription", id).Msg("failed to persist subscription")
+		writeError(w, http.StatusInternalServerError, "failed to persist subscription")
+		return
+	}
+
+	s.logger.Info().
+		Str("subscription", id).
+		Str("topic", sub.Topic).
+		Msg("subscription updated via API")
+
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// deleteSubscription handles DELETE /api/subscriptions/{id}
+func (s *Server) deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	existed, err := s.subscriptions.Delete(id)
+	if err != nil {
+		s.logger.Error().Err(err).Str("subscription", id).Msg("failed to persist subscription")
+		writeError(w, http.StatusInternalServerError, "failed to persist subscription")
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "subscription not found")
+		return
+	}
+
+	s.logger.Info().Str("subscription", id).Msg("subscription deleted via API")
+
+	w.WriteHeader(http.StatusNoContent)
+}
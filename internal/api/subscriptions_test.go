@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/subscription"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSubscriptionMux(t *testing.T, max int) *http.ServeMux {
+	t.Helper()
+	store, err := subscription.Open("", max)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Subscriptions: store, RegistrationToken: "register"}, getTestLogger()).Register(mux)
+	return mux
+}
+
+func TestSubscriptions_SelfService(t *testing.T) {
+	mux := newSubscriptionMux(t, 0)
+	body := `{"name": "Jan", "topic": "jan-pager", "capcodes": ["0101001"]}`
+
+	rec := doRequest(mux, http.MethodPost, "/api/subscriptions", "", body)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/subscriptions", "register", body)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		ID     string `json:"id"`
+		Topic  string `json:"topic"`
+		Secret string `json:"secret"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "jan-pager", created.Topic)
+	require.NotEmpty(t, created.Secret)
+	path := "/api/subscriptions/" + created.ID
+
+	// The registration token does not give access to subscriptions
+	rec = doRequest(mux, http.MethodGet, path, "register", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(mux, http.MethodGet, "/api/subscriptions", created.Secret, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(mux, http.MethodGet, path, created.Secret, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	rec = doRequest(mux, http.MethodPut, path, created.Secret, `{"topic": "jan-pager", "regions": ["Utrecht"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"regions":["Utrecht"]`)
+
+	rec = doRequest(mux, http.MethodPut, path, created.Secret, `{"topic": "jan-pager"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "at least one capcode")
+
+	rec = doRequest(mux, http.MethodGet, "/api/subscriptions", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), created.ID)
+
+	rec = doRequest(mux, http.MethodDelete, path, created.Secret, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// The secret is gone with the subscription, the admin sees it missing
+	rec = doRequest(mux, http.MethodDelete, path, created.Secret, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(mux, http.MethodDelete, path, "secret", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSubscriptions_Invalid(t *testing.T) {
+	mux := newSubscriptionMux(t, 1)
+
+	rec := doRequest(mux, http.MethodPost, "/api/subscriptions", "secret", `not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/subscriptions", "secret", `{"topic": "a/b", "capcodes": ["1"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/subscriptions", "secret", `{"topic": "a", "capcodes": ["1"]}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(mux, http.MethodPost, "/api/subscriptions", "secret", `{"topic": "b", "capcodes": ["2"]}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// Injector passes a fabricated message through the forwarding pipeline,
+// applying the filters when filter is set. It returns the message history
+// ID and whether the message is forwarded.
+type Injector func(msg model.Message, filter bool) (id string, forwarded bool)
+
+// testNotifyRequest is the optional body of a POST /api/test-notify request
+type testNotifyRequest struct {
+	Message  string   `json:"message"`
+	Capcodes []string `json:"capcodes"`
+	Filter   bool     `json:"filter"`
+}
+
+// testNotifyResponse reports the outcome of a test notification
+type testNotifyResponse struct {
+	ID        string `json:"id,omitempty"`
+	Forwarded bool   `json:"forwarded"`
+}
+
+// testNotify handles POST /api/test-notify. The notification is queued, so
+// the response does not tell whether it was delivered.
+func (s *Server) testNotify(w http.ResponseWriter, r *http.Request) {
+	var req testNotifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	msg := model.NewTestMessage(strings.TrimSpace(req.Message), req.Capcodes, time.Now())
+	id, forwarded := s.inject(msg, req.Filter)
+
+	s.logger.Info().
+		Str("id", id).
+		Bool("filter", req.Filter).
+		Bool("forwarded", forwarded).
+		Msg("test notification injected")
+
+	writeJSON(w, http.StatusOK, testNotifyResponse{ID: id, Forwarded: forwarded})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestNotify(t *testing.T) {
+	var injected []model.Message
+	var filtered []bool
+	inject := func(msg model.Message, filter bool) (string, bool) {
+		injected = append(injected, msg)
+		filtered = append(filtered, filter)
+		return "1", !filter
+	}
+
+	mux := http.NewServeMux()
+	NewServer("secret", Services{Inject: inject}, getTestLogger()).Register(mux)
+
+	rec := doRequest(mux, http.MethodPost, "/api/test-notify", "secret", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp testNotifyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, testNotifyResponse{ID: "1", Forwarded: true}, resp)
+	require.Len(t, injected, 1)
+	assert.Equal(t, model.DefaultTestText, injected[0].Message)
+	assert.False(t, filtered[0])
+
+	rec = doRequest(mux, http.MethodPost, "/api/test-notify", "secret", `{"message": "A1 Proef", "capcodes": ["0101001"], "filter": true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.False(t, resp.Forwarded)
+	require.Len(t, injected, 2)
+	assert.Equal(t, "A1 Proef", injected[1].Message)
+	assert.Equal(t, []string{"0101001"}, injected[1].Capcodes)
+	assert.True(t, filtered[1])
+
+	rec = doRequest(mux, http.MethodPost, "/api/test-notify", "secret", `{"message":`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(mux, http.MethodPost, "/api/test-notify", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Len(t, injected, 2)
+}
@@ -0,0 +1,240 @@
+// Package archive writes the raw P2000 feed to rotating, gzip compressed
+// JSON Lines files for offline analysis, independent of filtering.
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	filePrefix = "p2000-"
+	fileSuffix = ".jsonl.gz"
+	timeFormat = "20060102T150405.000Z"
+)
+
+// record is a line of an archive file. Frames that are valid JSON are
+// stored as is, other frames as a string.
+type record struct {
+	ReceivedAt time.Time       `json:"received_at"`
+	Frame      json.RawMessage `json:"frame,omitempty"`
+	Raw        string          `json:"raw,omitempty"`
+}
+
+// Options configures a Writer. Only Dir is required.
+type Options struct {
+	Dir            string
+	RotateInterval time.Duration // Start a new file every interval, aligned to the clock; never when 0
+	MaxSize        int64         // Compressed bytes per file before starting a new one, unlimited when 0
+	MaxFiles       int           // Archive files kept, the oldest are removed; all are kept when 0
+	Logger         zerolog.Logger
+}
+
+// Writer appends raw feed frames to the current archive file. It is safe
+// for concurrent use.
+type Writer struct {
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	file    *os.File
+	gz      *gzip.Writer
+	size    int64     // Compressed bytes written to the current file
+	period  time.Time // Rotation period of the current file
+	failing bool      // Last write failed, logged once until it recovers
+}
+
+// New creates an archive writer, creating the directory when needed. The
+// first file is opened when the first frame arrives.
+func New(opts Options) (*Writer, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("archive directory must be configured")
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	return &Writer{
+		opts: opts,
+		now:  time.Now,
+	}, nil
+}
+
+// Archive writes a frame and logs failures. It is meant as the frame hook
+// of the websocket client, so a full disk does not stop the forwarder.
+func (w *Writer) Archive(frame []byte) {
+	err := w.Write(frame)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case err != nil && !w.failing:
+		w.opts.Logger.Error().Err(err).Str("dir", w.opts.Dir).Msg("failed to archive feed, dropping frames until it recovers")
+	case err == nil && w.failing:
+		w.opts.Logger.Info().Str("dir", w.opts.Dir).Msg("feed archive recovered")
+	}
+	w.failing = err != nil
+}
+
+// Write appends a frame to the archive, starting a new file when the
+// rotation interval or size limit is reached. Each frame is flushed so a
+// crash loses at most the frame being written.
+func (w *Writer) Write(frame []byte) error {
+	now := w.now().UTC()
+	rec := record{ReceivedAt: now}
+	if json.Valid(frame) {
+		rec.Frame = frame
+	} else {
+		rec.Raw = string(frame)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.needsRotation(now) {
+		if err := w.rotate(now); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.gz.Write(line); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := w.gz.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current archive file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// needsRotation reports whether a new file must be started. The caller
+// must hold the lock.
+func (w *Writer) needsRotation(now time.Time) bool {
+	if w.gz == nil {
+		return true
+	}
+	if w.opts.RotateInterval > 0 && !now.Truncate(w.opts.RotateInterval).Equal(w.period) {
+		return true
+	}
+	return w.opts.MaxSize > 0 && w.size >= w.opts.MaxSize
+}
+
+// rotate closes the current file, opens a new one named after now and
+// removes the oldest files beyond MaxFiles. The caller must hold the lock.
+func (w *Writer) rotate(now time.Time) error {
+	if err := w.closeFile(); err != nil {
+		w.opts.Logger.Warn().Err(err).Msg("failed to close archive file")
+	}
+
+	base := filepath.Join(w.opts.Dir, filePrefix+now.Format(timeFormat))
+	path := base + fileSuffix
+	var file *os.File
+	var err error
+	for i := 1; ; i++ {
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+		path = fmt.Sprintf("%s-%d%s", base, i, fileSuffix)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	w.file = file
+	w.size = 0
+	w.gz = gzip.NewWriter(&countingWriter{w: file, n: &w.size})
+	if w.opts.RotateInterval > 0 {
+		w.period = now.Truncate(w.opts.RotateInterval)
+	}
+
+	w.opts.Logger.Info().Str("path", path).Msg("archive file opened")
+	w.prune()
+	return nil
+}
+
+// closeFile finishes the gzip stream and closes the current file. The
+// caller must hold the lock.
+func (w *Writer) closeFile() error {
+	if w.gz == nil {
+		return nil
+	}
+
+	err := w.gz.Close()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.gz, w.file = nil, nil
+	return err
+}
+
+// prune removes the oldest archive files beyond MaxFiles, including the
+// file being written. The caller must hold the lock.
+func (w *Writer) prune() {
+	if w.opts.MaxFiles <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		w.opts.Logger.Warn().Err(err).Msg("failed to list archive files")
+		return
+	}
+
+	var files []os.DirEntry
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			files = append(files, e)
+		}
+	}
+	if len(files) <= w.opts.MaxFiles {
+		return
+	}
+
+	// File names start with the UTC time they were opened
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	for _, e := range files[:len(files)-w.opts.MaxFiles] {
+		path := filepath.Join(w.opts.Dir, e.Name())
+		if err := os.Remove(path); err != nil {
+			w.opts.Logger.Warn().Err(err).Str("path", path).Msg("failed to remove archive file")
+			continue
+		}
+		w.opts.Logger.Debug().Str("path", path).Msg("archive file removed")
+	}
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// fakeClock returns a controllable time source
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func newTestWriter(t *testing.T, opts Options) (*Writer, func(time.Duration)) {
+	opts.Dir = t.TempDir()
+	opts.Logger = getTestLogger()
+	w, err := New(opts)
+	require.NoError(t, err)
+
+	clock, advance := fakeClock()
+	w.now = clock
+	return w, advance
+}
+
+// archiveFiles returns the archive file names in dir, oldest first
+func archiveFiles(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, filePrefix+"*"+fileSuffix))
+	require.NoError(t, err)
+	return matches
+}
+
+// readArchive returns the records of an archive file
+func readArchive(t *testing.T, path string) []record {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var records []record
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var r record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestWriter_Write(t *testing.T) {
+	w, _ := newTestWriter(t, Options{})
+
+	require.NoError(t, w.Write([]byte(`{"message": "A1 Brand woning",
+		"capcodes": ["0101001"]}`)))
+	require.NoError(t, w.Write([]byte("invalid json {")))
+
+	// Frames are flushed, so the file is readable before it is closed
+	files := archiveFiles(t, w.opts.Dir)
+	require.Len(t, files, 1)
+	assert.Equal(t, "p2000-20240102T150405.000Z.jsonl.gz", filepath.Base(files[0]))
+	require.NoError(t, w.Close())
+
+	records := readArchive(t, files[0])
+	require.Len(t, records, 2)
+	assert.JSONEq(t, `{"message": "A1 Brand woning", "capcodes": ["0101001"]}`, string(records[0].Frame))
+	assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), records[0].ReceivedAt)
+	assert.Equal(t, "invalid json {", records[1].Raw)
+}
+
+func TestWriter_RotateInterval(t *testing.T) {
+	w, advance := newTestWriter(t, Options{RotateInterval: time.Hour})
+
+	require.NoError(t, w.Write([]byte(`{"n": 1}`)))
+	advance(50 * time.Minute)
+	require.NoError(t, w.Write([]byte(`{"n": 2}`)))
+	advance(10 * time.Minute) // 16:04, next clock hour
+	require.NoError(t, w.Write([]byte(`{"n": 3}`)))
+	require.NoError(t, w.Close())
+
+	files := archiveFiles(t, w.opts.Dir)
+	require.Len(t, files, 2)
+	assert.Len(t, readArchive(t, files[0]), 2)
+	assert.Len(t, readArchive(t, files[1]), 1)
+}
+
+func TestWriter_MaxSizeAndMaxFiles(t *testing.T) {
+	w, advance := newTestWriter(t, Options{MaxSize: 1, MaxFiles: 2})
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, w.Write([]byte(`{"message": "A1 Brand woning"}`)))
+		advance(time.Second)
+	}
+	require.NoError(t, w.Close())
+
+	// Every frame exceeds the size limit; only the newest two files are kept
+	files := archiveFiles(t, w.opts.Dir)
+	require.Len(t, files, 2)
+	assert.Equal(t, "p2000-20240102T150407.000Z.jsonl.gz", filepath.Base(files[0]))
+	assert.Equal(t, "p2000-20240102T150408.000Z.jsonl.gz", filepath.Base(files[1]))
+}
+
+func TestWriter_Archive(t *testing.T) {
+	w, _ := newTestWriter(t, Options{})
+
+	require.NoError(t, os.RemoveAll(w.opts.Dir))
+	require.NoError(t, os.WriteFile(w.opts.Dir, nil, 0644))
+	w.Archive([]byte(`{"n": 1}`))
+	assert.True(t, w.failing)
+
+	require.NoError(t, os.Remove(w.opts.Dir))
+	require.NoError(t, os.Mkdir(w.opts.Dir, 0755))
+	w.Archive([]byte(`{"n": 2}`))
+	assert.False(t, w.failing)
+	require.NoError(t, w.Close())
+}
+
+func TestNew_RequiresDir(t *testing.T) {
+	_, err := New(Options{})
+	assert.Error(t, err)
+}
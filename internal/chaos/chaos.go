@@ -0,0 +1,170 @@
+// Package chaos injects controlled faults for resilience testing. Faults
+// are only enabled when the guard environment variable is set, so a stray
+// flag can never break a production deployment.
+package chaos
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// GuardEnv must be set to GuardValue before any fault is injected
+	GuardEnv   = "P2000_CHAOS"
+	GuardValue = "i-understand-this-breaks-things"
+
+	// FlagPrefix marks chaos flags, which are hidden from -help output
+	FlagPrefix = "chaos."
+)
+
+// Config holds the faults to inject
+type Config struct {
+	NotifyFailureRate float64       // Fraction of notifier requests answered with a 500
+	WebsocketReset    time.Duration // Interval between forced websocket disconnects
+	DNSDelay          time.Duration // Delay added before every outbound dial
+}
+
+// RegisterFlags registers the chaos flags on fs
+func RegisterFlags(fs *flag.FlagSet) *Config {
+	cfg := &Config{}
+	fs.Float64Var(&cfg.NotifyFailureRate, FlagPrefix+"notify-failure-rate", 0, "fraction (0-1) of notifier requests that fail with HTTP 500")
+	fs.DurationVar(&cfg.WebsocketReset, FlagPrefix+"ws-reset-interval", 0, "force a websocket reconnect at this interval")
+	fs.DurationVar(&cfg.DNSDelay, FlagPrefix+"dns-delay", 0, "delay added before every outbound dial to simulate slow DNS")
+	return cfg
+}
+
+// Enabled reports whether any fault is configured
+func (c *Config) Enabled() bool {
+	return c.NotifyFailureRate > 0 || c.WebsocketReset > 0 || c.DNSDelay > 0
+}
+
+// Validate refuses configured faults unless the guard environment variable is set
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.NotifyFailureRate < 0 || c.NotifyFailureRate > 1 {
+		return fmt.Errorf("chaos notify failure rate must be between 0 and 1")
+	}
+	if os.Getenv(GuardEnv) != GuardValue {
+		return fmt.Errorf("chaos flags require %s=%s", GuardEnv, GuardValue)
+	}
+	return nil
+}
+
+// LogEnabled warns loudly about the active faults
+func (c *Config) LogEnabled(logger zerolog.Logger) {
+	if !c.Enabled() {
+		return
+	}
+	logger.Warn().
+		Float64("notify_failure_rate", c.NotifyFailureRate).
+		Dur("ws_reset_interval", c.WebsocketReset).
+		Dur("dns_delay", c.DNSDelay).
+		Msg("CHAOS MODE ENABLED: faults are being injected")
+}
+
+// Transport wraps base with injected notifier failures and dial delays.
+// Returns base unchanged when no HTTP faults are configured.
+func (c *Config) Transport(base http.RoundTripper) http.RoundTripper {
+	if c.NotifyFailureRate <= 0 && c.DNSDelay <= 0 {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if c.DNSDelay > 0 {
+		if t, ok := base.(*http.Transport); ok {
+			t = t.Clone()
+			t.DialContext = c.DialContext((&net.Dialer{Timeout: 30 * time.Second}).DialContext)
+			base = t
+		}
+	}
+	return &transport{base: base, failureRate: c.NotifyFailureRate}
+}
+
+// DialContext wraps dial with the configured DNS delay
+func (c *Config) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.DNSDelay <= 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case <-time.After(c.DNSDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// RunWebsocketResets calls reset every WebsocketReset interval until ctx is cancelled
+func (c *Config) RunWebsocketResets(ctx context.Context, reset func(), logger zerolog.Logger) {
+	if c.WebsocketReset <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.WebsocketReset)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			logger.Warn().Msg("chaos: forcing websocket reset")
+			reset()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// transport fails a fraction of requests with a synthetic 500 response
+type transport struct {
+	base        http.RoundTripper
+	failureRate float64
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.failureRate > 0 && rand.Float64() < t.failureRate {
+		// A RoundTripper must close the request body, even on failure
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return &http.Response{
+			Status:     "500 Internal Server Error (chaos)",
+			StatusCode: http.StatusInternalServerError,
+			Proto:      req.Proto,
+			ProtoMajor: req.ProtoMajor,
+			ProtoMinor: req.ProtoMinor,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader("chaos")),
+			Request:    req,
+		}, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// HideFlags replaces fs.Usage so chaos flags are not listed
+func HideFlags(fs *flag.FlagSet) {
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, FlagPrefix) {
+				return
+			}
+			name, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(fs.Output(), "  -%s %s\n    \t%s\n", f.Name, name, usage)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := RegisterFlags(fs)
+
+	err := fs.Parse([]string{"-chaos.notify-failure-rate=0.5", "-chaos.ws-reset-interval=1m", "-chaos.dns-delay=2s"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.5, cfg.NotifyFailureRate)
+	assert.Equal(t, time.Minute, cfg.WebsocketReset)
+	assert.Equal(t, 2*time.Second, cfg.DNSDelay)
+	assert.True(t, cfg.Enabled())
+}
+
+func TestValidate_RequiresGuard(t *testing.T) {
+	cfg := &Config{NotifyFailureRate: 0.1}
+
+	t.Setenv(GuardEnv, "")
+	assert.Error(t, cfg.Validate())
+
+	t.Setenv(GuardEnv, "yes")
+	assert.Error(t, cfg.Validate())
+
+	t.Setenv(GuardEnv, GuardValue)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.NotifyFailureRate = 1.5
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_DisabledNeedsNoGuard(t *testing.T) {
+	t.Setenv(GuardEnv, "")
+	cfg := &Config{}
+	assert.False(t, cfg.Enabled())
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestTransport_Disabled(t *testing.T) {
+	cfg := &Config{}
+	base := http.DefaultTransport
+	assert.Equal(t, base, cfg.Transport(base))
+}
+
+func TestTransport_AlwaysFails(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	cfg := &Config{NotifyFailureRate: 1}
+	client := &http.Client{Transport: cfg.Transport(nil)}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+// closeTracker records whether the request body was closed
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTransport_ClosesRequestBody(t *testing.T) {
+	cfg := &Config{NotifyFailureRate: 1}
+	body := &closeTracker{Reader: strings.NewReader("P 2 Buitenbrand")}
+	req, err := http.NewRequest(http.MethodPost, "http://localhost", body)
+	require.NoError(t, err)
+
+	resp, err := cfg.Transport(nil).RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, body.closed)
+}
+
+func TestDialContext_Delay(t *testing.T) {
+	cfg := &Config{DNSDelay: 50 * time.Millisecond}
+	dial := cfg.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, nil
+	})
+
+	start := time.Now()
+	_, err := dial(context.Background(), "tcp", "example.com:443")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dial(ctx, "tcp", "example.com:443")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunWebsocketResets(t *testing.T) {
+	cfg := &Config{WebsocketReset: 10 * time.Millisecond}
+
+	var resets int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cfg.RunWebsocketResets(ctx, func() { atomic.AddInt32(&resets, 1) }, getTestLogger())
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&resets) >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestHideFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "config path")
+	RegisterFlags(fs)
+	HideFlags(fs)
+
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	fs.Usage()
+
+	assert.Contains(t, out.String(), "-config")
+	assert.NotContains(t, out.String(), "chaos")
+}
@@ -1,55 +1,1068 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/kaije/p2000-nfty/internal/dispatch"
+	"github.com/kaije/p2000-nfty/internal/elastic"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/httpauth"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/internal/influx"
+	"github.com/kaije/p2000-nfty/internal/maintenance"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/postgres"
+	"github.com/kaije/p2000-nfty/internal/proxy"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/secrets"
+	"github.com/kaije/p2000-nfty/internal/stats"
+	"github.com/kaije/p2000-nfty/internal/stream"
+	"github.com/kaije/p2000-nfty/internal/translate"
+	"github.com/kaije/p2000-nfty/internal/weather"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+	"github.com/kaije/p2000-nfty/pkg/websocket"
 	"gopkg.in/yaml.v3"
 )
 
+// secretsTimeout bounds resolving all Vault references on startup
+const secretsTimeout = 30 * time.Second
+
+// DefaultDestination is the name of the destination configured in the ntfy section
+const DefaultDestination = "ntfy"
+
+// Message sources selectable with the source option
+const (
+	SourceWebsocket = "websocket" // The live P2000 websocket feed
+	SourceStdin     = "stdin"     // Decoder output piped to standard input
+	SourceDecoder   = "decoder"   // A decoder command run by the forwarder
+)
+
+// Capcode matching modes selectable with the capcode_matching option
+const (
+	CapcodeMatchingLenient = "lenient" // Leading zeros are ignored, "101001" matches "0101001"
+	CapcodeMatchingStrict  = "strict"  // Capcodes match as written
+)
+
+// DefaultDecoderCommand receives P2000 on 169.65 MHz with an RTL-SDR stick
+const DefaultDecoderCommand = "rtl_fm -f 169.65M -M fm -s 22050 -g 40 - | multimon-ng -a FLEX -t raw -"
+
+// validDisciplines lists the disciplines accepted by the discipline filter
+var validDisciplines = map[string]bool{
+	"brandweer": true,
+	"ambulance": true,
+	"politie":   true,
+	"knrm":      true,
+}
+
 // Config holds the application configuration
 type Config struct {
-	ForwardAll          bool              `yaml:"forward_all"`
-	Capcodes            []string          `yaml:"capcodes"`
-	CapcodeTranslations map[string]string `yaml:"capcode_translations"`
-	CapcodeCSVPath      string            `yaml:"capcode_csv_path"`
-	Ntfy                NtfyConfig        `yaml:"ntfy"`
+	Language            string                           `yaml:"language"`  // Language of notification and status text (nl, en)
+	Source              string                           `yaml:"source"`    // Message source: websocket (default), stdin or decoder
+	Decoder             DecoderConfig                    `yaml:"decoder"`   // Decoder command used by the decoder source
+	WebSocket           WebSocketConfig                  `yaml:"websocket"` // Reconnection of the websocket source
+	SelfHeal            SelfHealConfig                   `yaml:"self_heal"` // Restart of a stale message source
+	ForwardAll          bool                             `yaml:"forward_all"`
+	Capcodes            []string                         `yaml:"capcodes"`
+	ExcludeCapcodes     []string                         `yaml:"exclude_capcodes"`     // Suppress messages containing these capcodes
+	CapcodeMatching     string                           `yaml:"capcode_matching"`     // lenient (default) ignores leading zeros, strict matches as written
+	Regions             []string                         `yaml:"regions"`              // Forward capcodes resolving to these regions
+	Stations            []string                         `yaml:"stations"`             // Forward capcodes resolving to these stations
+	Disciplines         []string                         `yaml:"disciplines"`          // Only forward these disciplines (brandweer, ambulance, politie, knrm)
+	DisciplineRanges    map[string][]string              `yaml:"discipline_ranges"`    // Fallback capcode ranges per discipline
+	MessageTypes        map[string]MessageTypeConfig     `yaml:"message_types"`        // Per feed message type handling (FLEX, POCSAG, ...)
+	SkipNumeric         bool                             `yaml:"skip_numeric"`         // Drop numeric-only status pages
+	DuplicateWindow     int                              `yaml:"duplicate_window"`     // seconds copies of a page with the same message ID are dropped, 0 disables
+	TestAlarms          TestAlarmConfig                  `yaml:"test_alarms"`          // Detection of test pages
+	MaintenanceWindows  []MaintenanceWindowConfig        `yaml:"maintenance_windows"`  // Recurring maintenance and exercise windows per capcode group
+	Severity            SeverityConfig                   `yaml:"severity"`             // Handling of the classified incident severity
+	GRIP                GRIPConfig                       `yaml:"grip"`                 // Dedicated path for GRIP announcements
+	Reanimation         ReanimationConfig                `yaml:"reanimation"`          // Alert mode for resuscitation calls
+	Threads             ThreadConfig                     `yaml:"threads"`              // Grouping of follow-up pages per incident
+	Batching            BatchingConfig                   `yaml:"batching"`             // Combining the frames of an incident burst
+	SpecialUnits        []SpecialUnitConfig              `yaml:"special_units"`        // Tagging of special units, built-in table when unset
+	Templates           TemplateConfig                   `yaml:"templates"`            // Default notification templates for all destinations
+	MapImage            MapImageConfig                   `yaml:"map_image"`            // Static map attached to geocoded incidents
+	Actions             []ActionConfig                   `yaml:"actions"`              // Default notification action buttons for all destinations
+	CapcodeOverrides    map[string]CapcodeOverrideConfig `yaml:"capcode_overrides"`    // Display name, tags and priority per capcode, prefix or range
+	OwnUnit             OwnUnitConfig                    `yaml:"own_unit"`             // Capcodes highlighted at the top of the body
+	CapcodeDetails      CapcodeDetailsConfig             `yaml:"capcode_details"`      // Format of the capcode details in the body
+	CapcodeTranslations map[string]string                `yaml:"capcode_translations"` // Deprecated: display names, use capcode_overrides
+	CapcodeCSVPath      string                           `yaml:"capcode_csv_path"`
+	CapcodeRefresh      int                              `yaml:"capcode_refresh_interval"` // seconds, only used for HTTP(S) CSV paths
+	CapcodeStrict       bool                             `yaml:"capcode_strict"`           // Refuse to start when the capcode database fails to load
+	CapcodeRetry        int                              `yaml:"capcode_retry_interval"`   // seconds between load attempts after a failure, 0 disables
+	Ntfy                NtfyConfig                       `yaml:"ntfy"`
+	Destinations        map[string]NtfyConfig            `yaml:"destinations"` // Additional named ntfy destinations
+	Recipients          []RecipientConfig                `yaml:"recipients"`
+	Pipelines           []PipelineConfig                 `yaml:"pipelines"` // Independent pipelines replacing the top-level filters
+	Rules               []RuleConfig                     `yaml:"rules"`     // Routing rules evaluated in order for every message
+	Plugins             []PluginConfig                   `yaml:"plugins"`   // Custom filters and notifiers built as Go plugins
+	Script              ScriptConfig                     `yaml:"script"`    // Starlark script run for every message after the rules
 	Server              ServerConfig
+	API                 APIConfig           `yaml:"api"`
+	Subscriptions       SubscriptionsConfig `yaml:"subscriptions"` // Self-service subscriptions managed through the API
+	Store               StoreConfig         `yaml:"store"`
+	Report              ReportConfig        `yaml:"report"`
+	Stats               StatsConfig         `yaml:"stats"`
+	Buffer              BufferConfig        `yaml:"buffer"` // Received messages waiting for the pipeline
+	Queue               QueueConfig         `yaml:"queue"`
+	CircuitBreaker      BreakerConfig       `yaml:"circuit_breaker"`
+	HTTPClient          HTTPClientConfig    `yaml:"http_client"` // Connection pool of the notification destinations
+	Escalation          EscalationConfig    `yaml:"escalation"`
+	Geocoding           GeocodingConfig     `yaml:"geocoding"`
+	Translation         TranslationConfig   `yaml:"translation"` // Machine translation of the message text
+	Weather             WeatherConfig       `yaml:"weather"`     // Wind and temperature for storm and nature fire calls
+	Archive             ArchiveConfig       `yaml:"archive"`
+	Stream              StreamConfig        `yaml:"stream"`        // NATS JetStream output of the enriched feed
+	GRPC                GRPCConfig          `yaml:"grpc"`          // gRPC API streaming the processed messages
+	Postgres            PostgresConfig      `yaml:"postgres"`      // Shared PostgreSQL history of messages and notifications
+	Influx              InfluxConfig        `yaml:"influx"`        // InfluxDB time series of the messages
+	Elasticsearch       ElasticConfig       `yaml:"elasticsearch"` // Full-text search index of the messages
+	HA                  HAConfig            `yaml:"ha"`            // Redundant instances sharing the notifications
+	Shard               ShardConfig         `yaml:"shard"`         // Instances splitting the feed by capcode
+	Proxy               ProxyConfig         `yaml:"proxy"`         // Outbound proxy for the feed and notification backends
+	TLS                 TLSConfig           `yaml:"tls"`           // TLS for the websocket feed and ntfy destinations
+	Vault               VaultConfig         `yaml:"vault"`         // Vault server for "vault:" credential references
 }
 
 // NtfyConfig holds ntfy.sh configuration
 type NtfyConfig struct {
+	Backend  string `yaml:"backend"` // ntfy (default), gotify, matrix, sms, call or aprs
 	Server   string `yaml:"server"`
 	Topic    string `yaml:"topic"`
 	Token    string `yaml:"token"`    // Optional authentication token (Bearer)
 	Username string `yaml:"username"` // Optional username for Basic Auth
 	Password string `yaml:"password"` // Optional password for Basic Auth
+
+	TokenFile    string `yaml:"token_file"`    // Read the token from this file, e.g. a Docker or Kubernetes secret
+	PasswordFile string `yaml:"password_file"` // Read the password from this file
+
+	Receipts  ReceiptsConfig `yaml:"receipts"`
+	Templates TemplateConfig `yaml:"templates"` // Overrides the default templates for this destination
+	Actions   []ActionConfig `yaml:"actions"`   // Overrides the default action buttons for this destination
+
+	Headers  map[string]string `yaml:"headers"`  // Extra ntfy headers, e.g. Icon, Email, Call, Delay, Cache or Firebase
+	Markdown bool              `yaml:"markdown"` // Render the notification body as Markdown
+	JSON     bool              `yaml:"json"`     // Publish with the ntfy JSON API instead of headers
+
+	MaxBodyLength int    `yaml:"max_body_length"` // Maximum notification body length in bytes, 0 for no limit
+	Truncate      string `yaml:"truncate"`        // summarize (default) or cut bodies over max_body_length
+
+	GotifyPriorities map[int]int `yaml:"gotify_priorities"` // Gotify priority (0-10) per ntfy priority (1-5)
+	Rooms            []string    `yaml:"rooms"`             // Matrix room IDs, e.g. !abc123:matrix.org
+	SMS              SMSConfig   `yaml:"sms"`               // Provider, sender and numbers of the sms backend
+	Call             CallConfig  `yaml:"call"`              // Twilio account and numbers of the call backend
+	APRS             APRSConfig  `yaml:"aprs"`              // Callsign and addressees of the aprs backend
+
+	Proxy string    `yaml:"proxy"` // Overrides the proxy for this destination, "direct" bypasses it
+	TLS   TLSConfig `yaml:"tls"`   // Overrides the upstream TLS settings for this destination
+
+	Topics []TopicConfig `yaml:"topics"` // Topics on this server with their own filters, replacing the top-level filters
+}
+
+// SMSConfig holds the settings of the sms backend, with the Twilio auth
+// token or MessageBird access key as token
+type SMSConfig struct {
+	Provider string   `yaml:"provider"` // twilio or messagebird
+	Account  string   `yaml:"account"`  // Twilio account SID
+	From     string   `yaml:"from"`     // Sender number or alphanumeric originator
+	Numbers  []string `yaml:"numbers"`  // Recipients in E.164 format, e.g. +31612345678
+	PerHour  int      `yaml:"per_hour"` // Deliveries per hour, 0 uses the default of 10
+}
+
+// CallConfig holds the settings of the call backend, with the Twilio auth
+// token as token
+type CallConfig struct {
+	Account  string   `yaml:"account"`  // Twilio account SID
+	From     string   `yaml:"from"`     // Twilio number the calls come from
+	Numbers  []string `yaml:"numbers"`  // Numbers to call in E.164 format
+	Language string   `yaml:"language"` // Text-to-speech language, default nl-NL
+	PerHour  int      `yaml:"per_hour"` // Calls per hour, 0 uses the default of 4
+}
+
+// APRSConfig holds the settings of the aprs backend, with the APRS-IS
+// passcode of the callsign as token
+type APRSConfig struct {
+	Callsign   string   `yaml:"callsign"`   // Licensed callsign with optional SSID, e.g. PD0ABC-10
+	Addressees []string `yaml:"addressees"` // Callsigns or bulletin groups receiving the messages
+}
+
+// TopicConfig describes an ntfy topic fed by its own filters. Each topic is
+// forwarded to as a pipeline with a destination sharing the server and
+// credentials of the ntfy section.
+type TopicConfig struct {
+	Topic           string         `yaml:"topic"`
+	ForwardAll      bool           `yaml:"forward_all"`
+	Capcodes        []string       `yaml:"capcodes"`
+	ExcludeCapcodes []string       `yaml:"exclude_capcodes"`
+	Regions         []string       `yaml:"regions"`
+	Stations        []string       `yaml:"stations"`
+	Disciplines     []string       `yaml:"disciplines"`
+	Pattern         string         `yaml:"pattern"`   // Regular expression the message text must match
+	Templates       TemplateConfig `yaml:"templates"` // Templates for this topic
+}
+
+// TemplateConfig holds Go text/template sources for notifications. Empty
+// templates keep the built-in layout.
+type TemplateConfig struct {
+	Title string `yaml:"title"`
+	Body  string `yaml:"body"`
+}
+
+// ActionConfig describes an ntfy action button. URL and Body are Go
+// text/template sources using the notification template data.
+type ActionConfig struct {
+	Action  string            `yaml:"action"` // view, http or broadcast
+	Label   string            `yaml:"label"`
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"` // http actions only
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Clear   bool              `yaml:"clear"` // Dismiss the notification after the action
+}
+
+// MapImageConfig configures the static map service used for incident map
+// attachments. The URL is a Go template using .Lat and .Lon.
+type MapImageConfig struct {
+	URL      string `yaml:"url"`      // Disabled when empty
+	Filename string `yaml:"filename"` // Attachment name shown by ntfy, map.png when empty
+}
+
+// ReceiptsConfig controls delivery tracking through the topic's event stream
+type ReceiptsConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	PollInterval int  `yaml:"poll_interval"` // seconds, 0 keeps a streaming subscription open
+}
+
+// MessageTypeConfig controls how messages of a feed message type are handled
+type MessageTypeConfig struct {
+	Tags     string `yaml:"tags"`     // Comma separated ntfy tags
+	Priority int    `yaml:"priority"` // ntfy priority 1-5, 0 keeps the default
+	Suppress bool   `yaml:"suppress"` // Do not forward messages of this type
+}
+
+// Actions taken on detected test pages
+const (
+	TestAlarmOff       = "off"       // Treat test pages like any other page
+	TestAlarmLabel     = "label"     // Add the test alarm tags
+	TestAlarmDowngrade = "downgrade" // Add the tags and lower the priority
+	TestAlarmDrop      = "drop"      // Do not forward test pages
+)
+
+// TestAlarmConfig controls the detection of test pages such as "proefalarm"
+// pages and the monthly siren test
+type TestAlarmConfig struct {
+	Action   string   `yaml:"action"`   // off (default), label, downgrade or drop
+	Keywords []string `yaml:"keywords"` // Replaces the built-in keywords
+	Schedule bool     `yaml:"schedule"` // Detect test pages during the siren test, first Monday of the month at noon
+	Priority int      `yaml:"priority"` // ntfy priority of downgraded test pages
+	Tags     string   `yaml:"tags"`     // Comma separated ntfy tags of labelled and downgraded test pages
+}
+
+// SeverityConfig controls how the severity classified from the message
+// text is shown
+type SeverityConfig struct {
+	Tags map[string]string `yaml:"tags"` // Comma separated ntfy tags per severity (low, medium, high, critical)
+}
+
+// GRIPConfig sends GRIP announcements through a dedicated high-priority
+// path, regardless of the filters
+type GRIPConfig struct {
+	Destinations []string `yaml:"destinations"` // ntfy or names from destinations, e.g. a GRIP topic and a call destination; disabled when empty
+	MinLevel     int      `yaml:"min_level"`    // Lowest GRIP level taking the path, 1 by default
+	Priority     int      `yaml:"priority"`     // ntfy priority of GRIP notifications, 5 by default
+	Window       int      `yaml:"window"`       // seconds a GRIP level counts as active after its last announcement
+}
+
+// ReanimationConfig sends resuscitation calls for the ambulance at maximum
+// priority with a distinct tag, for AED volunteers. With locations set only
+// calls within radius of one of them are alerted this way, like HartslagNu.
+type ReanimationConfig struct {
+	Enabled   bool             `yaml:"enabled"`
+	Keywords  []string         `yaml:"keywords"`  // Replaces the built-in keywords
+	Priority  int              `yaml:"priority"`  // ntfy priority of resuscitation calls, 5 by default
+	Tags      string           `yaml:"tags"`      // Comma separated ntfy tags, e.g. a tag mapped to an alarm sound
+	Radius    int              `yaml:"radius"`    // meters around the locations, 1000 by default
+	Locations []LocationConfig `yaml:"locations"` // Volunteer locations, any location when empty
+}
+
+// LocationConfig is a named WGS84 position
+type LocationConfig struct {
+	Name string  `yaml:"name"`
+	Lat  float64 `yaml:"lat"`
+	Lon  float64 `yaml:"lon"`
+}
+
+// Actions taken on messages during a maintenance window
+const (
+	MaintenanceSuppress = "suppress" // Do not forward the messages
+	MaintenanceLabel    = "label"    // Add the window tags
+)
+
+// MaintenanceWindowConfig is a recurring maintenance or exercise window of a
+// group of capcodes, in Dutch time
+type MaintenanceWindowConfig struct {
+	Name     string   `yaml:"name"`
+	Capcodes []string `yaml:"capcodes"` // Capcodes, prefixes or ranges, every capcode when empty
+	Cron     string   `yaml:"cron"`     // Start of each window, e.g. "0 19 * * tue"
+	RRule    string   `yaml:"rrule"`    // Or an iCalendar recurrence rule, e.g. "FREQ=MONTHLY;BYDAY=1MO;BYHOUR=19"
+	Duration string   `yaml:"duration"` // Length of each window, e.g. "2h"
+	Action   string   `yaml:"action"`   // suppress (default) or label
+	Tags     string   `yaml:"tags"`     // Comma separated ntfy tags of labelled messages, oefening when empty
+}
+
+// ThreadConfig groups follow-up pages for the same incident into a single
+// ntfy notification thread
+type ThreadConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Window  int  `yaml:"window"` // seconds after the last page in which follow-ups join the incident
+}
+
+// BatchingConfig holds the combining of pages an incident sends in separate
+// frames, one per unit, into one notification
+type BatchingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Window  int  `yaml:"window"` // seconds the first page waits for the others
+}
+
+// CapcodeOverrideConfig changes how messages for a capcode are presented,
+// e.g. to make your own station stand out
+type CapcodeOverrideConfig struct {
+	Name         string `yaml:"name"`          // Display name shown instead of the capcode database details
+	Tags         string `yaml:"tags"`          // Comma separated ntfy tags or emoji
+	Priority     int    `yaml:"priority"`      // ntfy priority 1-5, 0 keeps the message priority
+	PriorityBump int    `yaml:"priority_bump"` // Raise the ntfy priority by this many levels, up to 5
+}
+
+// OwnUnitConfig holds the capcodes of the reader's own unit, highlighted
+// in the notification body and tagged
+type OwnUnitConfig struct {
+	Capcodes []string `yaml:"capcodes"` // Capcodes, prefixes or ranges, leading zeros optional
+	Marker   string   `yaml:"marker"`   // Put before own capcodes in the body, ➡️ when empty
+	Tag      string   `yaml:"tag"`      // ntfy tag of messages to the own unit, arrow_right when empty
+}
+
+// CapcodeDetailsConfig shortens the capcode details section of the
+// default body, which overwhelms phones on big incidents
+type CapcodeDetailsConfig struct {
+	Hide   bool   `yaml:"hide"`   // Leave the section out
+	Max    int    `yaml:"max"`    // Entries listed besides the own unit, 0 lists all
+	Format string `yaml:"format"` // verbose (default) or compact, a line per capcode with the station only
+	Group  string `yaml:"group"`  // station to list the capcodes of a station in one entry
+	Dedupe bool   `yaml:"dedupe"` // Omit capcodes resolving to the station of an earlier capcode
+}
+
+// SpecialUnitConfig maps capcodes or keywords of a special unit (e.g.
+// Lifeliner) to distinctive ntfy tags and priority
+type SpecialUnitConfig struct {
+	Name     string   `yaml:"name"`
+	Capcodes []string `yaml:"capcodes"`
+	Keywords []string `yaml:"keywords"` // Matched case-insensitively against the start of words
+	Tags     string   `yaml:"tags"`     // Comma separated ntfy tags
+	Priority int      `yaml:"priority"` // ntfy priority 1-5, 0 keeps the message priority
+}
+
+// RecipientConfig describes a recipient and the destinations they can be
+// reached on, in order of preference. The main ntfy destination is named "ntfy".
+type RecipientConfig struct {
+	Name     string   `yaml:"name"`
+	Channels []string `yaml:"channels"`
+}
+
+// Kinds of plugins
+const (
+	PluginFilter   = "filter"   // Drops the messages it does not allow
+	PluginNotifier = "notifier" // A destination named after the plugin
+)
+
+// PluginConfig loads a custom filter or notifier built as Go plugin
+type PluginConfig struct {
+	Name   string            `yaml:"name"`
+	Type   string            `yaml:"type"`   // PluginFilter or PluginNotifier
+	Path   string            `yaml:"path"`   // Shared object built with -buildmode=plugin
+	Config map[string]string `yaml:"config"` // Settings passed to the plugin
+}
+
+// ScriptConfig holds the message script, see package script
+type ScriptConfig struct {
+	Path string `yaml:"path"` // Starlark file defining process(msg), disabled when empty
+}
+
+// DecoderConfig holds the command run by the decoder source
+type DecoderConfig struct {
+	Command string `yaml:"command"` // Shell command writing multimon-ng FLEX/POCSAG lines to stdout
+}
+
+// WebSocketConfig holds the reconnection settings of the websocket source
+type WebSocketConfig struct {
+	Jitter               float64 `yaml:"jitter"`                 // Random fraction (0-1) added to or removed from each reconnect backoff
+	MaxReconnectAttempts int     `yaml:"max_reconnect_attempts"` // Exit non-zero after this many failed attempts in a row, 0 retries forever
+	IdleTimeout          int     `yaml:"idle_timeout"`           // seconds without data before reconnecting, 0 disables the watchdog
+
+	Compression bool              `yaml:"compression"` // Negotiate permessage-deflate with the feed
+	Origin      string            `yaml:"origin"`      // Origin header sent with the dial
+	UserAgent   string            `yaml:"user_agent"`  // User-Agent header sent with the dial
+	Headers     map[string]string `yaml:"headers"`     // Extra dial headers, e.g. an API key; values may be "vault:" references
+
+	InvalidFrames InvalidFramesConfig `yaml:"invalid_frames"` // Handling of frames that are not valid messages
+}
+
+// SelfHealConfig holds the restart of the websocket or decoder source when
+// the liveness check finds no messages within the health window
+type SelfHealConfig struct {
+	Enabled  bool `yaml:"enabled"`  // Restart the source instead of only reporting unhealthy
+	Cooldown int  `yaml:"cooldown"` // seconds after a restart before the next one
+}
+
+// InvalidFramesConfig holds what happens to feed frames that cannot be
+// decoded as messages, besides logging them
+type InvalidFramesConfig struct {
+	Dir         string `yaml:"dir"`         // Archive them to gzip JSONL files in this directory, rotated like the feed archive
+	Destination string `yaml:"destination"` // Forward them as plain notifications to this destination, e.g. a debug topic
+}
+
+// Window returns the maintenance window w configures
+func (w MaintenanceWindowConfig) Window() (maintenance.Window, error) {
+	var schedule maintenance.Schedule
+	var err error
+	switch {
+	case w.Cron != "" && w.RRule != "":
+		return maintenance.Window{}, fmt.Errorf("cron and rrule cannot be combined")
+	case w.Cron != "":
+		schedule, err = maintenance.ParseCron(w.Cron)
+	case w.RRule != "":
+		schedule, err = maintenance.ParseRRule(w.RRule)
+	default:
+		return maintenance.Window{}, fmt.Errorf("cron or rrule is required")
+	}
+	if err != nil {
+		return maintenance.Window{}, err
+	}
+
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil || duration <= 0 || duration > 7*24*time.Hour {
+		return maintenance.Window{}, fmt.Errorf("duration must be between 1s and 168h")
+	}
+	action, tags := w.Action, w.Tags
+	if action == "" {
+		action = MaintenanceSuppress
+	}
+	if tags == "" {
+		tags = "oefening"
+	}
+	return maintenance.Window{
+		Name:     w.Name,
+		Action:   action,
+		Tags:     tags,
+		Capcodes: w.Capcodes,
+		Schedule: schedule,
+		Duration: duration,
+	}, nil
+}
+
+// DialHeaders returns the extra headers, Origin and User-Agent sent with
+// the dial. Origin and UserAgent take precedence over Headers.
+func (w WebSocketConfig) DialHeaders() map[string]string {
+	headers := make(map[string]string, len(w.Headers)+2)
+	for name, value := range w.Headers {
+		headers[name] = value
+	}
+	if w.Origin != "" {
+		headers["Origin"] = w.Origin
+	}
+	if w.UserAgent != "" {
+		headers["User-Agent"] = w.UserAgent
+	}
+	return headers
+}
+
+// PipelineConfig describes a forwarding pipeline with its own filters,
+// templates and destinations. The main ntfy destination is named "ntfy".
+type PipelineConfig struct {
+	Name            string         `yaml:"name"`
+	ForwardAll      bool           `yaml:"forward_all"`
+	Capcodes        []string       `yaml:"capcodes"`
+	ExcludeCapcodes []string       `yaml:"exclude_capcodes"`
+	Regions         []string       `yaml:"regions"`
+	Stations        []string       `yaml:"stations"`
+	Disciplines     []string       `yaml:"disciplines"`
+	Pattern         string         `yaml:"pattern"`   // Regular expression the message text must match
+	Templates       TemplateConfig `yaml:"templates"` // Default templates for the destinations of this pipeline
+	Destinations    []string       `yaml:"destinations"`
+}
+
+// RuleConfig describes a routing rule. When the condition in When holds for
+// a message, the actions apply.
+type RuleConfig struct {
+	Name         string   `yaml:"name"`
+	When         string   `yaml:"when"`         // Condition expression, see the rules package
+	Drop         bool     `yaml:"drop"`         // Do not forward the message
+	Destinations []string `yaml:"destinations"` // Route to these destinations instead of the default
+	Priority     int      `yaml:"priority"`     // ntfy priority 1-5, 0 keeps the message priority
+	Tags         string   `yaml:"tags"`         // Comma separated extra ntfy tags
+	Email        string   `yaml:"email"`        // Address ntfy also emails the notification to
+	Delay        string   `yaml:"delay"`        // ntfy delayed delivery, e.g. "30m" or "tomorrow, 7am"
+	Stop         bool     `yaml:"stop"`         // Skip the remaining rules
+}
+
+// DefaultPipeline returns the top-level filters and templates as a pipeline,
+// used when no pipelines are configured
+func (c *Config) DefaultPipeline() PipelineConfig {
+	return PipelineConfig{
+		ForwardAll:      c.ForwardAll,
+		Capcodes:        c.Capcodes,
+		ExcludeCapcodes: c.ExcludeCapcodes,
+		Regions:         c.Regions,
+		Stations:        c.Stations,
+		Disciplines:     c.Disciplines,
+		Templates:       c.Templates,
+	}
+}
+
+// ActivePipelines returns the pipelines messages are forwarded through: one
+// per ntfy topic when topics are configured, the configured pipelines
+// otherwise. Without either, the top-level filters are used.
+func (c *Config) ActivePipelines() []PipelineConfig {
+	if len(c.Ntfy.Topics) == 0 {
+		return c.Pipelines
+	}
+	pipelines := make([]PipelineConfig, 0, len(c.Ntfy.Topics))
+	for _, t := range c.Ntfy.Topics {
+		pipelines = append(pipelines, PipelineConfig{
+			Name:            t.Topic,
+			ForwardAll:      t.ForwardAll,
+			Capcodes:        t.Capcodes,
+			ExcludeCapcodes: t.ExcludeCapcodes,
+			Regions:         t.Regions,
+			Stations:        t.Stations,
+			Disciplines:     t.Disciplines,
+			Pattern:         t.Pattern,
+			Templates:       t.Templates,
+			Destinations:    []string{c.topicDestination(t.Topic)},
+		})
+	}
+	return pipelines
+}
+
+// TopicDestinations returns the destination names of the ntfy topics other
+// than the ntfy topic itself, named "ntfy/<topic>"
+func (c *Config) TopicDestinations() []string {
+	var names []string
+	for _, t := range c.Ntfy.Topics {
+		if name := c.topicDestination(t.Topic); name != DefaultDestination {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hasDestination reports whether name is an ntfy destination or a notifier
+// plugin
+func (c *Config) hasDestination(name string) bool {
+	if _, ok := c.Destination(name); ok {
+		return true
+	}
+	return slices.ContainsFunc(c.Plugins, func(p PluginConfig) bool {
+		return p.Type == PluginNotifier && p.Name == name
+	})
+}
+
+// Destination returns the ntfy destination with name: the ntfy section, an
+// ntfy topic or a named destination
+func (c *Config) Destination(name string) (NtfyConfig, bool) {
+	if name == DefaultDestination {
+		return c.Ntfy, true
+	}
+	if topic, ok := strings.CutPrefix(name, DefaultDestination+"/"); ok {
+		for _, t := range c.Ntfy.Topics {
+			if t.Topic == topic {
+				dest := c.Ntfy
+				dest.Topic = topic
+				dest.Topics = nil
+				return dest, true
+			}
+		}
+	}
+	dest, ok := c.Destinations[name]
+	return dest, ok
+}
+
+func (c *Config) topicDestination(topic string) string {
+	if topic == c.Ntfy.Topic {
+		return DefaultDestination
+	}
+	return DefaultDestination + "/" + topic
+}
+
+// LenientCapcodes reports whether capcode filters ignore leading zeros
+func (c *Config) LenientCapcodes() bool {
+	return !strings.EqualFold(c.CapcodeMatching, CapcodeMatchingStrict)
+}
+
+// WebSocketProxy returns the proxy URL for the websocket feed, the general
+// proxy unless overridden
+func (c *Config) WebSocketProxy() string {
+	if c.Proxy.WebSocket != "" {
+		return c.Proxy.WebSocket
+	}
+	return c.Proxy.URL
+}
+
+// DestinationTLS returns the TLS settings for an ntfy destination, the
+// upstream settings unless overridden
+func (c *Config) DestinationTLS(n NtfyConfig) TLSConfig {
+	if n.TLS != (TLSConfig{}) {
+		return n.TLS
+	}
+	return c.TLS
+}
+
+// APIConfig holds management API configuration
+type APIConfig struct {
+	Token     string `yaml:"token"`      // Bearer token required for the admin API, disabled when empty
+	TokenFile string `yaml:"token_file"` // Read the token from this file
+}
+
+// SubscriptionsConfig holds the self-service subscription configuration.
+// Subscriptions are managed through the API and need api.token.
+type SubscriptionsConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	Path                  string `yaml:"path"`                    // JSON file to persist subscriptions to, in-memory only when empty
+	Max                   int    `yaml:"max"`                     // Number of subscriptions allowed, unlimited when 0
+	RegistrationToken     string `yaml:"registration_token"`      // Bearer token allowing users to register, only the admin token when empty
+	RegistrationTokenFile string `yaml:"registration_token_file"` // Read the registration token from this file
+}
+
+// VaultConfig holds the HashiCorp Vault server used to resolve credentials
+// written as "vault:<path>#<key>"
+type VaultConfig struct {
+	Address   string `yaml:"address"`    // e.g. https://vault.example.com:8200
+	Token     string `yaml:"token"`      // Vault token
+	TokenFile string `yaml:"token_file"` // Read the Vault token from this file
+}
+
+// StoreConfig holds message history configuration
+type StoreConfig struct {
+	Path        string `yaml:"path"`         // JSON file to persist history to, in-memory only when empty
+	MaxMessages int    `yaml:"max_messages"` // Number of messages kept
+}
+
+// ReportConfig holds periodic report configuration
+type ReportConfig struct {
+	Interval int `yaml:"interval"` // seconds, 0 disables the report
+}
+
+// StatsConfig holds message statistics and summary configuration
+type StatsConfig struct {
+	Retention int    `yaml:"retention"` // days of hourly counts kept
+	Summary   string `yaml:"summary"`   // daily or weekly summary notification, disabled when empty
+	Time      string `yaml:"time"`      // time of day the summary is sent, HH:MM in Dutch time
+}
+
+// ProxyConfig holds the outbound proxy configuration. Without a URL the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+type ProxyConfig struct {
+	URL       string `yaml:"url"`       // http, https, socks5 or socks5h proxy URL
+	NoProxy   string `yaml:"no_proxy"`  // Comma separated hosts, domains and CIDR ranges reached directly
+	WebSocket string `yaml:"websocket"` // Overrides the proxy for the websocket feed, http or socks5 only
+}
+
+// TLSConfig holds the TLS settings of an upstream connection
+type TLSConfig struct {
+	CAFile   string `yaml:"ca_file"`   // PEM bundle of CAs trusted in addition to the system roots
+	CertFile string `yaml:"cert_file"` // PEM client certificate
+	KeyFile  string `yaml:"key_file"`  // PEM client certificate key
+}
+
+// IsNtfy reports whether the destination is an ntfy server rather than
+// Gotify, Matrix, an SMS or voice provider or APRS-IS
+func (n NtfyConfig) IsNtfy() bool {
+	return n.Backend == "" || n.Backend == notifier.BackendNtfy
+}
+
+// checkBackend reports an unknown backend or settings the backend does not
+// support. Gotify needs an application token, Matrix an access token and
+// rooms, SMS and calls a provider token and numbers, and APRS a callsign,
+// passcode and addressees instead of a topic.
+func (n NtfyConfig) checkBackend() error {
+	switch n.Backend {
+	case "", notifier.BackendNtfy:
+		return nil
+	case notifier.BackendGotify:
+		if n.Token == "" {
+			return fmt.Errorf("gotify requires the application token as token")
+		}
+		if err := notifier.ValidateGotifyPriorities(n.GotifyPriorities); err != nil {
+			return err
+		}
+	case notifier.BackendMatrix:
+		if n.Token == "" {
+			return fmt.Errorf("matrix requires the access token as token")
+		}
+		if err := notifier.ValidateRooms(n.Rooms); err != nil {
+			return err
+		}
+	case notifier.BackendSMS:
+		if n.Token == "" {
+			return fmt.Errorf("sms requires the provider auth token or access key as token")
+		}
+		if err := notifier.ValidateSMS(notifier.SMSConfig(n.SMS)); err != nil {
+			return err
+		}
+	case notifier.BackendCall:
+		if n.Token == "" {
+			return fmt.Errorf("call requires the Twilio auth token as token")
+		}
+		if err := notifier.ValidateCall(notifier.CallConfig(n.Call)); err != nil {
+			return err
+		}
+	case notifier.BackendAPRS:
+		if err := notifier.ValidateAPRS(notifier.APRSConfig(n.APRS), n.Token); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown backend %q, expected ntfy, gotify, matrix, sms, call or aprs", n.Backend)
+	}
+	if n.Receipts.Enabled || len(n.Topics) > 0 {
+		return fmt.Errorf("receipts and topics require the ntfy backend")
+	}
+	return nil
+}
+
+// check reports a client certificate without its key or the other way around
+func (t TLSConfig) check() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// BufferConfig holds the buffer between the message source and the
+// pipeline, which keeps the websocket read loop from blocking on a stall
+type BufferConfig struct {
+	Size       int    `yaml:"size"`        // Received messages waiting to be processed, 0 processes them on the read loop
+	DropPolicy string `yaml:"drop_policy"` // Message dropped when the buffer is full: oldest or newest
+}
+
+// QueueConfig holds notification queue configuration
+type QueueConfig struct {
+	Workers      int `yaml:"workers"`       // Notifications sent concurrently
+	Size         int `yaml:"size"`          // Queued notifications before new ones are dropped
+	DrainTimeout int `yaml:"drain_timeout"` // seconds to finish queued notifications on shutdown
+}
+
+// BreakerConfig holds the circuit breaker configuration applied to every
+// notification destination
+type BreakerConfig struct {
+	Threshold int `yaml:"threshold"` // Consecutive failed notifications before a destination is skipped, 0 disables
+	Cooldown  int `yaml:"cooldown"`  // seconds before a skipped destination is probed again
+}
+
+// HTTPClientConfig tunes the HTTP transport shared by the notification
+// destinations, so connections to the same server are reused
+type HTTPClientConfig struct {
+	MaxIdleConns        int  `yaml:"max_idle_conns"`          // Idle connections kept across all servers, 0 for no limit
+	MaxIdleConnsPerHost int  `yaml:"max_idle_conns_per_host"` // Idle connections kept per server
+	IdleTimeout         int  `yaml:"idle_timeout"`            // seconds an idle connection is kept, 0 for no limit
+	HTTP2               bool `yaml:"http2"`                   // Use HTTP/2 with servers supporting it
+}
+
+// EscalationConfig holds the escalation chain for messages that are not
+// acknowledged in time or fail to deliver
+type EscalationConfig struct {
+	OnFailure bool                   `yaml:"on_failure"` // Start the chain right away when the notification fails
+	Steps     []EscalationStepConfig `yaml:"steps"`      // Ordered by delay, escalation is disabled without steps
+}
+
+// EscalationStepConfig is a stage of the escalation chain
+type EscalationStepConfig struct {
+	After       int    `yaml:"after"`       // seconds after forwarding without acknowledgement
+	Destination string `yaml:"destination"` // ntfy or a name from destinations
+}
+
+// GeocodingConfig holds the geocoding service used to locate incident
+// addresses
+type GeocodingConfig struct {
+	Provider  string  `yaml:"provider"`   // pdok or nominatim, disabled when empty
+	URL       string  `yaml:"url"`        // Overrides the public endpoint of the provider
+	UserAgent string  `yaml:"user_agent"` // Identifies the application, required by the Nominatim usage policy
+	RateLimit float64 `yaml:"rate_limit"` // Requests per second, 0 disables the limit
+	CacheSize int     `yaml:"cache_size"` // Lookups kept in the cache, 0 disables the cache
+	CacheTTL  int     `yaml:"cache_ttl"`  // seconds a lookup is cached
+	BAG       string  `yaml:"bag"`        // Local BAG extract resolving postcodes and house numbers
+}
+
+// TranslationConfig holds the machine translation service that appends a
+// translation of the message text to the notification
+type TranslationConfig struct {
+	Provider   string `yaml:"provider"`     // deepl or libretranslate, disabled when empty
+	URL        string `yaml:"url"`          // Overrides the public endpoint of the provider
+	APIKey     string `yaml:"api_key"`      // Required by DeepL and the public LibreTranslate server
+	APIKeyFile string `yaml:"api_key_file"` // Read the API key from this file
+	Source     string `yaml:"source"`       // Language of the messages
+	Target     string `yaml:"target"`       // Language translated to
+	CacheSize  int    `yaml:"cache_size"`   // Translations kept in the cache, 0 disables the cache
+	CacheTTL   int    `yaml:"cache_ttl"`    // seconds a translation is cached
+}
+
+// WeatherConfig holds the weather service adding the current wind and
+// temperature to storm damage and nature fire calls
+type WeatherConfig struct {
+	Provider   string   `yaml:"provider"`     // openweather, disabled when empty
+	URL        string   `yaml:"url"`          // Overrides the public endpoint of the provider
+	APIKey     string   `yaml:"api_key"`      // API key of the service
+	APIKeyFile string   `yaml:"api_key_file"` // Read the API key from this file
+	Keywords   []string `yaml:"keywords"`     // Words in the message text selecting calls
+	CacheTTL   int      `yaml:"cache_ttl"`    // seconds the weather of an area is cached
+}
+
+// ArchiveConfig holds the raw feed archive configuration
+type ArchiveConfig struct {
+	Dir            string `yaml:"dir"`             // Directory of the gzip JSONL files, disabled when empty
+	RotateInterval int    `yaml:"rotate_interval"` // seconds per file, 0 disables time based rotation
+	MaxSize        int    `yaml:"max_size"`        // MB per file, 0 disables size based rotation
+	MaxFiles       int    `yaml:"max_files"`       // Files kept, 0 keeps all
+}
+
+// StreamConfig holds the NATS JetStream output of the enriched messages,
+// for downstream analytics
+type StreamConfig struct {
+	URL           string `yaml:"url"`            // nats:// or tls:// URL of a NATS server with JetStream, disabled when empty
+	Subject       string `yaml:"subject"`        // Subject captured by the stream
+	Token         string `yaml:"token"`          // Optional authentication token
+	Username      string `yaml:"username"`       // Optional username, preferred over token
+	Password      string `yaml:"password"`       // Optional password
+	TokenFile     string `yaml:"token_file"`     // Read the token from this file
+	PasswordFile  string `yaml:"password_file"`  // Read the password from this file
+	ForwardedOnly bool   `yaml:"forwarded_only"` // Only publish forwarded messages instead of the whole feed
+	Buffer        int    `yaml:"buffer"`         // Messages waiting to be published before new ones are dropped
+}
+
+// GRPCConfig holds the gRPC API, served with the server TLS certificate
+// and authenticated with the api token
+type GRPCConfig struct {
+	Port   int `yaml:"port"`   // Port to listen on, disabled when 0
+	Buffer int `yaml:"buffer"` // Messages waiting per subscriber before new ones are dropped
+}
+
+// PostgresConfig holds the PostgreSQL database the messages and
+// notification outcomes are written to, shared by several instances
+type PostgresConfig struct {
+	DSN      string `yaml:"dsn"`      // postgres:// URL or key=value connection string, disabled when empty
+	DSNFile  string `yaml:"dsn_file"` // Read the DSN from this file
+	Instance string `yaml:"instance"` // Name of this forwarder in the shared tables, the hostname when empty
+	Buffer   int    `yaml:"buffer"`   // Rows waiting to be written before new ones are dropped
+}
+
+// InfluxConfig holds the InfluxDB bucket a point per message is written
+// to, for long-term incident volume analysis
+type InfluxConfig struct {
+	URL           string `yaml:"url"`            // InfluxDB server, disabled when empty
+	Org           string `yaml:"org"`            // Organization of the bucket, unused by InfluxDB 1.8
+	Bucket        string `yaml:"bucket"`         // Bucket, or database/retention-policy for InfluxDB 1.8
+	Token         string `yaml:"token"`          // API token, or username:password for InfluxDB 1.8
+	TokenFile     string `yaml:"token_file"`     // Read the token from this file
+	Measurement   string `yaml:"measurement"`    // Measurement of the points
+	FlushInterval int    `yaml:"flush_interval"` // seconds between writes
+	Buffer        int    `yaml:"buffer"`         // Points waiting to be written before new ones are dropped
+}
+
+// ElasticConfig holds the Elasticsearch or OpenSearch server the messages
+// are indexed in
+type ElasticConfig struct {
+	URL           string `yaml:"url"`            // Elasticsearch or OpenSearch server, disabled when empty
+	Index         string `yaml:"index"`          // Index prefix, messages go to <index>-YYYY.MM
+	Username      string `yaml:"username"`       // Optional basic authentication
+	Password      string `yaml:"password"`       // Optional password
+	PasswordFile  string `yaml:"password_file"`  // Read the password from this file
+	APIKey        string `yaml:"api_key"`        // Optional encoded API key, preferred over username
+	APIKeyFile    string `yaml:"api_key_file"`   // Read the API key from this file
+	FlushInterval int    `yaml:"flush_interval"` // seconds between bulk requests
+	Buffer        int    `yaml:"buffer"`         // Messages waiting to be indexed before new ones are dropped
+}
+
+// HAConfig holds the high-availability mode, in which instances receiving
+// the same feed claim each message in the PostgreSQL database before
+// notifying it
+type HAConfig struct {
+	Enabled bool `yaml:"enabled"` // Only notify the messages this instance claims first, requires postgres
+}
+
+// ShardConfig splits the feed between instances by the hash of the
+// capcodes, each instance processing the messages of its own shard
+type ShardConfig struct {
+	Index int `yaml:"index"` // Shard of this instance, 0 to count-1
+	Count int `yaml:"count"` // Number of instances, 0 or 1 disables sharding
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
 	Port         int
 	HealthPath   string
+	LivePath     string
+	ReadyPath    string
+	HealthWindow int // seconds without messages before liveness fails
 	MetricsPath  string
 	ReadTimeout  int // seconds
 	WriteTimeout int // seconds
+	TLS          ServerTLSConfig
+	Auth         ServerAuthConfig
+}
+
+// ServerAuthConfig protects all endpoints of the HTTP server. The admin API
+// additionally requires the API token.
+type ServerAuthConfig struct {
+	Username     string   `yaml:"username"`      // Basic authentication, disabled when empty
+	Password     string   `yaml:"password"`      // Basic authentication password
+	PasswordFile string   `yaml:"password_file"` // Read the password from this file
+	Token        string   `yaml:"token"`         // Bearer token accepted instead of the basic credentials
+	TokenFile    string   `yaml:"token_file"`    // Read the token from this file
+	AllowedIPs   []string `yaml:"allowed_ips"`   // IP addresses and CIDR ranges allowed to connect, all when empty
+	Public       []string `yaml:"public"`        // Paths served without credentials, e.g. the probes
+}
+
+// ServerTLSConfig holds the certificate the HTTP server is served with over
+// TLS. The files are reloaded when they change.
+type ServerTLSConfig struct {
+	CertFile string `yaml:"cert_file"` // PEM certificate, plain HTTP when empty
+	KeyFile  string `yaml:"key_file"`  // PEM private key
 }
 
 // Load reads configuration from file and environment variables
 func Load(configPath string) (*Config, error) {
 	cfg := &Config{
-		ForwardAll:     true,              // Default to forwarding all messages
-		CapcodeCSVPath: "capcodelijst.csv", // Default CSV path
+		Language:        i18n.DefaultLanguage,
+		ForwardAll:      true,                   // Default to forwarding all messages
+		CapcodeMatching: CapcodeMatchingLenient, // Ignore leading zeros
+		CapcodeCSVPath:  "capcodelijst.csv",     // Default CSV path
+		CapcodeRefresh:  3600,                   // Refresh remote CSV hourly
+		CapcodeRetry:    60,                     // Retry a failed capcode load every minute
+		TestAlarms: TestAlarmConfig{
+			Action:   TestAlarmOff,
+			Schedule: true,
+			Priority: 1,
+			Tags:     "test_tube",
+		},
+		Threads: ThreadConfig{
+			Window: 900,
+		},
+		DuplicateWindow: 600,
+		Batching: BatchingConfig{
+			Window: 5,
+		},
+		GRIP: GRIPConfig{
+			MinLevel: 1,
+			Priority: 5,
+			Window:   4 * 3600,
+		},
+		Reanimation: ReanimationConfig{
+			Priority: 5,
+			Tags:     "heartpulse",
+			Radius:   1000,
+		},
+		Decoder: DecoderConfig{
+			Command: DefaultDecoderCommand,
+		},
+		WebSocket: WebSocketConfig{
+			Jitter:      0.2,
+			IdleTimeout: 300,
+		},
+		SelfHeal: SelfHealConfig{
+			Cooldown: 600,
+		},
+		Store: StoreConfig{
+			MaxMessages: 1000,
+		},
+		Subscriptions: SubscriptionsConfig{
+			Max: 100,
+		},
+		Stats: StatsConfig{
+			Retention: 8,
+			Time:      "08:00",
+		},
+		Buffer: BufferConfig{
+			Size:       1000,
+			DropPolicy: dispatch.DropOldest,
+		},
+		Queue: QueueConfig{
+			Workers:      2,
+			Size:         100,
+			DrainTimeout: 30,
+		},
+		CircuitBreaker: BreakerConfig{
+			Threshold: 5,
+			Cooldown:  60,
+		},
+		HTTPClient: HTTPClientConfig{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleTimeout:         90,
+			HTTP2:               true,
+		},
+		Geocoding: GeocodingConfig{
+			UserAgent: "p2000-nfty",
+			RateLimit: 1,
+			CacheSize: 1000,
+			CacheTTL:  86400,
+		},
+		Translation: TranslationConfig{
+			Source:    "nl",
+			Target:    "en",
+			CacheSize: 1000,
+			CacheTTL:  86400,
+		},
+		Weather: WeatherConfig{
+			Keywords: weather.DefaultKeywords,
+			CacheTTL: 600,
+		},
+		Archive: ArchiveConfig{
+			RotateInterval: 86400,
+			MaxSize:        100,
+		},
+		Stream: StreamConfig{
+			Subject: stream.DefaultSubject,
+			Buffer:  stream.DefaultBuffer,
+		},
+		Postgres: PostgresConfig{
+			Buffer: postgres.DefaultBuffer,
+		},
+		Influx: InfluxConfig{
+			Measurement:   influx.DefaultMeasurement,
+			FlushInterval: int(influx.DefaultFlushInterval / time.Second),
+			Buffer:        influx.DefaultBuffer,
+		},
+		Elasticsearch: ElasticConfig{
+			Index:         elastic.DefaultIndex,
+			FlushInterval: int(elastic.DefaultFlushInterval / time.Second),
+			Buffer:        elastic.DefaultBuffer,
+		},
 		Server: ServerConfig{
 			Port:         8080,
 			HealthPath:   "/health",
+			LivePath:     "/live",
+			ReadyPath:    "/ready",
+			HealthWindow: 300,
 			MetricsPath:  "/metrics",
 			ReadTimeout:  10,
 			WriteTimeout: 10,
 		},
 	}
 
+	var problems Problems
+
 	// Read config file
 	if configPath != "" {
 		data, err := os.ReadFile(configPath)
@@ -57,8 +1070,18 @@ func Load(configPath string) (*Config, error) {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 
-		if err := yaml.Unmarshal(data, cfg); err != nil {
-			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		// Unknown fields are reported with the other problems, so typos
+		// in option names do not silently keep the defaults
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("failed to parse config file: %w", err)
+			}
+			for _, msg := range typeErr.Errors {
+				problems = append(problems, errors.New(msg))
+			}
 		}
 	}
 
@@ -91,26 +1114,783 @@ func Load(configPath string) (*Config, error) {
 	if csvPath := os.Getenv("CAPCODE_CSV_PATH"); csvPath != "" {
 		cfg.CapcodeCSVPath = csvPath
 	}
+	if storePath := os.Getenv("STORE_PATH"); storePath != "" {
+		cfg.Store.Path = storePath
+	}
+	if apiToken := os.Getenv("API_TOKEN"); apiToken != "" {
+		cfg.API.Token = apiToken
+	}
+	if tokenFile := os.Getenv("NTFY_TOKEN_FILE"); tokenFile != "" {
+		cfg.Ntfy.TokenFile = tokenFile
+	}
+	if passwordFile := os.Getenv("NTFY_PASSWORD_FILE"); passwordFile != "" {
+		cfg.Ntfy.PasswordFile = passwordFile
+	}
+	if apiTokenFile := os.Getenv("API_TOKEN_FILE"); apiTokenFile != "" {
+		cfg.API.TokenFile = apiTokenFile
+	}
+	if vaultAddr := os.Getenv("VAULT_ADDR"); vaultAddr != "" {
+		cfg.Vault.Address = vaultAddr
+	}
+	if vaultToken := os.Getenv("VAULT_TOKEN"); vaultToken != "" {
+		cfg.Vault.Token = vaultToken
+	}
+	if shardIndex := os.Getenv("SHARD_INDEX"); shardIndex != "" {
+		if i, err := strconv.Atoi(shardIndex); err == nil {
+			cfg.Shard.Index = i
+		}
+	}
+
+	// The first ntfy topic is the default destination, e.g. for reports
+	if cfg.Ntfy.Topic == "" && len(cfg.Ntfy.Topics) > 0 {
+		cfg.Ntfy.Topic = cfg.Ntfy.Topics[0].Topic
+	}
+
+	// Resolve credentials stored outside the configuration
+	if err := cfg.loadSecrets(); err != nil {
+		problems = append(problems, fmt.Errorf("failed to load secrets: %w", err))
+	}
 
 	// Validate required fields
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", problems)
 	}
 
 	return cfg, nil
 }
 
-// Validate checks if all required configuration fields are set
+// loadSecrets reads the credentials configured with a *_file option, which
+// take precedence over the inline values, and resolves "vault:" references
+func (c *Config) loadSecrets() error {
+	if c.Vault.TokenFile != "" {
+		token, err := secrets.ReadFile(c.Vault.TokenFile)
+		if err != nil {
+			return fmt.Errorf("vault token_file: %w", err)
+		}
+		c.Vault.Token = token
+	}
+
+	var vault *secrets.Vault
+	if c.Vault.Address != "" {
+		vault = secrets.NewVault(c.Vault.Address, c.Vault.Token)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+
+	load := func(name string, value *string, file string) error {
+		if file != "" {
+			secret, err := secrets.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("%s_file: %w", name, err)
+			}
+			*value = secret
+		}
+		if !secrets.IsVaultRef(*value) {
+			return nil
+		}
+		if vault == nil {
+			return fmt.Errorf("%s refers to vault but no vault address is configured", name)
+		}
+		secret, err := vault.Resolve(ctx, *value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*value = secret
+		return nil
+	}
+
+	loadNtfy := func(prefix string, n *NtfyConfig) error {
+		if err := load(prefix+" token", &n.Token, n.TokenFile); err != nil {
+			return err
+		}
+		return load(prefix+" password", &n.Password, n.PasswordFile)
+	}
+
+	if err := loadNtfy("ntfy", &c.Ntfy); err != nil {
+		return err
+	}
+	for name, dest := range c.Destinations {
+		if err := loadNtfy(fmt.Sprintf("destination %q", name), &dest); err != nil {
+			return err
+		}
+		c.Destinations[name] = dest
+	}
+	if err := load("server auth password", &c.Server.Auth.Password, c.Server.Auth.PasswordFile); err != nil {
+		return err
+	}
+	if err := load("server auth token", &c.Server.Auth.Token, c.Server.Auth.TokenFile); err != nil {
+		return err
+	}
+	for name, value := range c.WebSocket.Headers {
+		if err := load(fmt.Sprintf("websocket header %s", name), &value, ""); err != nil {
+			return err
+		}
+		c.WebSocket.Headers[name] = value
+	}
+	if err := load("subscriptions registration token", &c.Subscriptions.RegistrationToken, c.Subscriptions.RegistrationTokenFile); err != nil {
+		return err
+	}
+	if err := load("stream token", &c.Stream.Token, c.Stream.TokenFile); err != nil {
+		return err
+	}
+	if err := load("stream password", &c.Stream.Password, c.Stream.PasswordFile); err != nil {
+		return err
+	}
+	if err := load("postgres dsn", &c.Postgres.DSN, c.Postgres.DSNFile); err != nil {
+		return err
+	}
+	if err := load("influx token", &c.Influx.Token, c.Influx.TokenFile); err != nil {
+		return err
+	}
+	if err := load("elasticsearch password", &c.Elasticsearch.Password, c.Elasticsearch.PasswordFile); err != nil {
+		return err
+	}
+	if err := load("elasticsearch api key", &c.Elasticsearch.APIKey, c.Elasticsearch.APIKeyFile); err != nil {
+		return err
+	}
+	if err := load("translation api key", &c.Translation.APIKey, c.Translation.APIKeyFile); err != nil {
+		return err
+	}
+	if err := load("weather api key", &c.Weather.APIKey, c.Weather.APIKeyFile); err != nil {
+		return err
+	}
+	return load("api token", &c.API.Token, c.API.TokenFile)
+}
+
+// Validate checks if all required configuration fields are set and valid.
+// The returned error is a Problems listing everything wrong.
 func (c *Config) Validate() error {
-	// If ForwardAll is false, we need at least one capcode for filtering
-	if !c.ForwardAll && len(c.Capcodes) == 0 {
-		return fmt.Errorf("at least one capcode must be configured when forward_all is false")
+	if problems := c.problems(); len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// problems collects all validation errors of the configuration
+func (c *Config) problems() Problems {
+	var problems Problems
+
+	// If ForwardAll is false, we need at least one capcode, region or station
+	// for filtering, unless rules route the messages
+	if !c.ForwardAll && len(c.Capcodes) == 0 && len(c.Regions) == 0 && len(c.Stations) == 0 && len(c.Rules) == 0 {
+		problems = append(problems, fmt.Errorf("at least one capcode must be configured when forward_all is false"))
+	}
+	if _, err := i18n.New(c.Language); err != nil {
+		problems = append(problems, err)
 	}
 	if c.Ntfy.Server == "" {
-		return fmt.Errorf("ntfy server must be configured")
+		problems = append(problems, fmt.Errorf("ntfy server must be configured"))
+	}
+	if c.Ntfy.Topic == "" && len(c.Ntfy.Topics) == 0 && c.Ntfy.IsNtfy() {
+		problems = append(problems, fmt.Errorf("ntfy topic must be configured"))
+	}
+	if err := c.Ntfy.checkBackend(); err != nil {
+		problems = append(problems, fmt.Errorf("ntfy: %w", err))
+	}
+	if c.Ntfy.Server != "" && !isHTTPURL(c.Ntfy.Server) {
+		problems = append(problems, fmt.Errorf("ntfy server %q must be an http(s) URL", c.Ntfy.Server))
+	}
+	if c.Ntfy.Receipts.PollInterval < 0 {
+		problems = append(problems, fmt.Errorf("ntfy receipts poll_interval must not be negative"))
+	}
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		problems = append(problems, fmt.Errorf("server port %d must be between 1 and 65535", c.Server.Port))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.HealthWindow < 0 {
+		problems = append(problems, fmt.Errorf("server readtimeout, writetimeout and healthwindow must not be negative"))
+	}
+	for _, path := range []struct{ name, value string }{
+		{"healthpath", c.Server.HealthPath},
+		{"livepath", c.Server.LivePath},
+		{"readypath", c.Server.ReadyPath},
+		{"metricspath", c.Server.MetricsPath},
+	} {
+		if path.value != "" && !strings.HasPrefix(path.value, "/") {
+			problems = append(problems, fmt.Errorf("server %s %q must start with /", path.name, path.value))
+		}
+	}
+	if c.CapcodeRefresh < 0 || c.CapcodeRetry < 0 {
+		problems = append(problems, fmt.Errorf("capcode_refresh_interval and capcode_retry_interval must not be negative"))
+	}
+	if c.Store.MaxMessages < 0 {
+		problems = append(problems, fmt.Errorf("store max_messages must not be negative"))
+	}
+	if c.Report.Interval < 0 {
+		problems = append(problems, fmt.Errorf("report interval must not be negative"))
+	}
+	switch c.Stats.Summary {
+	case "", stats.PeriodDaily, stats.PeriodWeekly:
+	default:
+		problems = append(problems, fmt.Errorf("unknown stats summary %q, expected daily or weekly", c.Stats.Summary))
+	}
+	if c.Stats.Summary != "" {
+		if _, err := stats.ParseTime(c.Stats.Time); err != nil {
+			problems = append(problems, fmt.Errorf("stats time: %w", err))
+		}
+		days := 1
+		if c.Stats.Summary == stats.PeriodWeekly {
+			days = 7
+		}
+		if c.Stats.Retention < days {
+			problems = append(problems, fmt.Errorf("stats retention must cover the summary period"))
+		}
+	}
+	if c.Subscriptions.Enabled && c.API.Token == "" {
+		problems = append(problems, fmt.Errorf("subscriptions require an api token"))
+	}
+	if c.Subscriptions.Enabled && !c.Ntfy.IsNtfy() {
+		problems = append(problems, fmt.Errorf("subscriptions require the ntfy backend"))
+	}
+	if c.GRPC.Port < 0 || c.GRPC.Port > 65535 {
+		problems = append(problems, fmt.Errorf("grpc port %d must be between 1 and 65535", c.GRPC.Port))
+	}
+	if c.GRPC.Port > 0 && c.GRPC.Port == c.Server.Port {
+		problems = append(problems, fmt.Errorf("grpc port must differ from the server port"))
+	}
+	if c.GRPC.Port > 0 && c.API.Token == "" {
+		problems = append(problems, fmt.Errorf("grpc requires an api token"))
+	}
+	if c.GRPC.Buffer < 0 {
+		problems = append(problems, fmt.Errorf("grpc buffer must not be negative"))
+	}
+	if c.Subscriptions.Max < 0 {
+		problems = append(problems, fmt.Errorf("subscriptions max must not be negative"))
+	}
+	if c.Stats.Retention < 0 {
+		problems = append(problems, fmt.Errorf("stats retention must not be negative"))
+	}
+	if c.Proxy.URL != "" {
+		if _, err := proxy.Parse(c.Proxy.URL); err != nil {
+			problems = append(problems, fmt.Errorf("proxy url: %w", err))
+		}
+	}
+	if ws := c.WebSocketProxy(); ws != "" && (c.Source == "" || strings.EqualFold(c.Source, SourceWebsocket)) {
+		if _, err := proxy.ParseWebSocket(ws); err != nil {
+			problems = append(problems, fmt.Errorf("proxy websocket: %w", err))
+		}
+	}
+	if c.Ntfy.Proxy != "" {
+		if _, err := proxy.Parse(c.Ntfy.Proxy); err != nil {
+			problems = append(problems, fmt.Errorf("ntfy proxy: %w", err))
+		}
+	}
+	if err := c.TLS.check(); err != nil {
+		problems = append(problems, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.Ntfy.TLS.check(); err != nil {
+		problems = append(problems, fmt.Errorf("ntfy tls: %w", err))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		problems = append(problems, fmt.Errorf("server tls: cert_file and key_file must be set together"))
+	}
+	if (c.Server.Auth.Username == "") != (c.Server.Auth.Password == "") {
+		problems = append(problems, fmt.Errorf("server auth: username and password must be set together"))
+	}
+	for _, entry := range c.Server.Auth.AllowedIPs {
+		if _, err := httpauth.ParseIPNet(entry); err != nil {
+			problems = append(problems, fmt.Errorf("server auth allowed_ips: %w", err))
+		}
+	}
+	for _, p := range c.Server.Auth.Public {
+		if !strings.HasPrefix(p, "/") {
+			problems = append(problems, fmt.Errorf("server auth public path %q must start with /", p))
+		}
+	}
+	if c.Vault.Address != "" && !isHTTPURL(c.Vault.Address) {
+		problems = append(problems, fmt.Errorf("vault address %q must be an http(s) URL", c.Vault.Address))
+	}
+	if c.Geocoding.URL != "" && !isHTTPURL(c.Geocoding.URL) {
+		problems = append(problems, fmt.Errorf("geocoding url %q must be an http(s) URL", c.Geocoding.URL))
+	}
+	if _, err := notifier.ParseMapImage(c.MapImage.URL, c.MapImage.Filename); err != nil {
+		problems = append(problems, fmt.Errorf("map_image: %w", err))
+	}
+	if err := checkTemplates(c.Templates); err != nil {
+		problems = append(problems, fmt.Errorf("templates: %w", err))
+	}
+	if err := checkTemplates(c.Ntfy.Templates); err != nil {
+		problems = append(problems, fmt.Errorf("ntfy templates: %w", err))
+	}
+	if err := checkCapcodes(c.Capcodes, c.ExcludeCapcodes); err != nil {
+		problems = append(problems, err)
+	}
+	if err := notifier.ValidateCapcodeDetails(notifier.CapcodeDetails(c.CapcodeDetails)); err != nil {
+		problems = append(problems, fmt.Errorf("capcode_details %w", err))
+	}
+	if err := checkCapcodes(c.OwnUnit.Capcodes); err != nil {
+		problems = append(problems, fmt.Errorf("own_unit: %w", err))
+	}
+	for _, d := range c.Disciplines {
+		if !validDisciplines[strings.ToLower(d)] {
+			problems = append(problems, fmt.Errorf("unknown discipline %q", d))
+		}
+	}
+	for d := range c.DisciplineRanges {
+		if !validDisciplines[strings.ToLower(d)] {
+			problems = append(problems, fmt.Errorf("unknown discipline %q in discipline_ranges", d))
+		}
+	}
+	for name, mt := range c.MessageTypes {
+		if mt.Priority < 0 || mt.Priority > 5 {
+			problems = append(problems, fmt.Errorf("message type %q priority must be between 1 and 5", name))
+		}
+	}
+	for _, unit := range c.SpecialUnits {
+		if len(unit.Capcodes) == 0 && len(unit.Keywords) == 0 {
+			problems = append(problems, fmt.Errorf("special unit %q requires capcodes or keywords", unit.Name))
+		}
+		if unit.Priority < 0 || unit.Priority > 5 {
+			problems = append(problems, fmt.Errorf("special unit %q priority must be between 1 and 5", unit.Name))
+		}
+	}
+	for code, o := range c.CapcodeOverrides {
+		if err := filter.ValidateCapcodePattern(code); err != nil {
+			problems = append(problems, fmt.Errorf("capcode override: %w", err))
+		}
+		if o.Priority < 0 || o.Priority > 5 {
+			problems = append(problems, fmt.Errorf("capcode override %s priority must be between 1 and 5", code))
+		}
+		if o.PriorityBump < 0 || o.PriorityBump > 4 {
+			problems = append(problems, fmt.Errorf("capcode override %s priority_bump must be between 0 and 4", code))
+		}
+	}
+	switch c.TestAlarms.Action {
+	case "", TestAlarmOff, TestAlarmLabel, TestAlarmDowngrade, TestAlarmDrop:
+	default:
+		problems = append(problems, fmt.Errorf("unknown test_alarms action %q", c.TestAlarms.Action))
+	}
+	if c.TestAlarms.Priority < 0 || c.TestAlarms.Priority > 5 {
+		problems = append(problems, fmt.Errorf("test_alarms priority must be between 1 and 5"))
+	}
+	for name := range c.Severity.Tags {
+		if s, ok := classify.Parse(name); !ok || string(s) != name {
+			problems = append(problems, fmt.Errorf("unknown severity %q in severity tags, use low, medium, high or critical", name))
+		}
+	}
+	for i, w := range c.MaintenanceWindows {
+		name := w.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+			problems = append(problems, fmt.Errorf("maintenance window %s requires a name", name))
+		}
+		if _, err := w.Window(); err != nil {
+			problems = append(problems, fmt.Errorf("maintenance window %s: %w", name, err))
+		}
+		switch w.Action {
+		case "", MaintenanceSuppress, MaintenanceLabel:
+		default:
+			problems = append(problems, fmt.Errorf("unknown maintenance window %s action %q", name, w.Action))
+		}
+		for _, code := range w.Capcodes {
+			if err := filter.ValidateCapcodePattern(code); err != nil {
+				problems = append(problems, fmt.Errorf("maintenance window %s: %w", name, err))
+			}
+		}
+	}
+	if c.DuplicateWindow < 0 {
+		problems = append(problems, fmt.Errorf("duplicate_window must not be negative"))
+	}
+	if c.Threads.Enabled && c.Threads.Window <= 0 {
+		problems = append(problems, fmt.Errorf("threads window must be positive"))
+	}
+	if c.Batching.Enabled && c.Batching.Window <= 0 {
+		problems = append(problems, fmt.Errorf("batching window must be positive"))
+	}
+	if c.Buffer.Size < 0 {
+		problems = append(problems, fmt.Errorf("buffer size must not be negative"))
+	}
+	if err := dispatch.ValidateDropPolicy(c.Buffer.DropPolicy); err != nil {
+		problems = append(problems, fmt.Errorf("buffer %w", err))
+	}
+	if c.Queue.Workers < 0 {
+		problems = append(problems, fmt.Errorf("queue workers must not be negative"))
+	}
+	if c.Queue.Size < 0 {
+		problems = append(problems, fmt.Errorf("queue size must not be negative"))
+	}
+	if c.Queue.DrainTimeout < 0 {
+		problems = append(problems, fmt.Errorf("queue drain_timeout must not be negative"))
 	}
-	if c.Ntfy.Topic == "" {
-		return fmt.Errorf("ntfy topic must be configured")
+	if c.CircuitBreaker.Threshold < 0 || c.CircuitBreaker.Cooldown < 0 {
+		problems = append(problems, fmt.Errorf("circuit_breaker threshold and cooldown must not be negative"))
+	}
+	if c.HTTPClient.MaxIdleConns < 0 || c.HTTPClient.MaxIdleConnsPerHost < 0 || c.HTTPClient.IdleTimeout < 0 {
+		problems = append(problems, fmt.Errorf("http_client max_idle_conns, max_idle_conns_per_host and idle_timeout must not be negative"))
+	}
+	for i, step := range c.Escalation.Steps {
+		if step.After < 0 {
+			problems = append(problems, fmt.Errorf("escalation step %d delay must not be negative", i+1))
+		}
+		if i > 0 && step.After < c.Escalation.Steps[i-1].After {
+			problems = append(problems, fmt.Errorf("escalation steps must be ordered by delay"))
+		}
+		if !c.hasDestination(step.Destination) {
+			problems = append(problems, fmt.Errorf("escalation step %d references unknown destination %q", i+1, step.Destination))
+		}
+	}
+	for _, dest := range c.GRIP.Destinations {
+		if !c.hasDestination(dest) {
+			problems = append(problems, fmt.Errorf("grip references unknown destination %q", dest))
+		}
+	}
+	if c.GRIP.MinLevel < 0 || c.GRIP.MinLevel > 5 {
+		problems = append(problems, fmt.Errorf("grip min_level must be between 1 and 5"))
+	}
+	if c.GRIP.Priority < 0 || c.GRIP.Priority > 5 {
+		problems = append(problems, fmt.Errorf("grip priority must be between 1 and 5"))
+	}
+	if c.GRIP.Window < 0 {
+		problems = append(problems, fmt.Errorf("grip window must not be negative"))
+	}
+	if c.Reanimation.Priority < 0 || c.Reanimation.Priority > 5 {
+		problems = append(problems, fmt.Errorf("reanimation priority must be between 1 and 5"))
+	}
+	if c.Reanimation.Radius < 0 {
+		problems = append(problems, fmt.Errorf("reanimation radius must not be negative"))
+	}
+	for i, l := range c.Reanimation.Locations {
+		if l.Lat < -90 || l.Lat > 90 || l.Lon < -180 || l.Lon > 180 {
+			problems = append(problems, fmt.Errorf("reanimation location %d has invalid coordinates", i+1))
+		}
+	}
+	switch strings.ToLower(c.CapcodeMatching) {
+	case "", CapcodeMatchingLenient, CapcodeMatchingStrict:
+	default:
+		problems = append(problems, fmt.Errorf("capcode_matching must be %q or %q", CapcodeMatchingLenient, CapcodeMatchingStrict))
+	}
+	switch strings.ToLower(c.Source) {
+	case "", SourceWebsocket, SourceStdin:
+	case SourceDecoder:
+		if strings.TrimSpace(c.Decoder.Command) == "" {
+			problems = append(problems, fmt.Errorf("decoder command must be configured when source is decoder"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("unknown source %q", c.Source))
+	}
+	if c.WebSocket.Jitter < 0 || c.WebSocket.Jitter > 1 {
+		problems = append(problems, fmt.Errorf("websocket jitter %g must be between 0 and 1", c.WebSocket.Jitter))
+	}
+	if c.WebSocket.MaxReconnectAttempts < 0 || c.WebSocket.IdleTimeout < 0 {
+		problems = append(problems, fmt.Errorf("websocket max_reconnect_attempts and idle_timeout must not be negative"))
+	}
+	if c.SelfHeal.Cooldown < 0 {
+		problems = append(problems, fmt.Errorf("self_heal cooldown must not be negative"))
+	}
+	if name := c.WebSocket.InvalidFrames.Destination; name != "" {
+		if _, ok := c.Destination(name); !ok {
+			problems = append(problems, fmt.Errorf("websocket invalid_frames references unknown destination %q", name))
+		}
+	}
+	if dir := c.WebSocket.InvalidFrames.Dir; dir != "" && filepath.Clean(dir) == filepath.Clean(c.Archive.Dir) {
+		problems = append(problems, fmt.Errorf("websocket invalid_frames dir must differ from the archive dir"))
+	}
+	if err := websocket.ValidateHeaders(c.WebSocket.DialHeaders()); err != nil {
+		problems = append(problems, fmt.Errorf("websocket headers: %w", err))
+	}
+	switch strings.ToLower(c.Geocoding.Provider) {
+	case "", "pdok", "nominatim":
+	default:
+		problems = append(problems, fmt.Errorf("unknown geocoding provider %q", c.Geocoding.Provider))
+	}
+	if c.Geocoding.RateLimit < 0 || c.Geocoding.CacheSize < 0 || c.Geocoding.CacheTTL < 0 {
+		problems = append(problems, fmt.Errorf("geocoding rate_limit, cache_size and cache_ttl must not be negative"))
+	}
+	if c.Translation.Provider != "" {
+		switch strings.ToLower(c.Translation.Provider) {
+		case translate.ProviderDeepL:
+			if c.Translation.APIKey == "" {
+				problems = append(problems, fmt.Errorf("translation provider deepl requires an api_key"))
+			}
+		case translate.ProviderLibreTranslate:
+		default:
+			problems = append(problems, fmt.Errorf("unknown translation provider %q", c.Translation.Provider))
+		}
+		if c.Translation.URL != "" && !isHTTPURL(c.Translation.URL) {
+			problems = append(problems, fmt.Errorf("translation url %q must be an http(s) URL", c.Translation.URL))
+		}
+		if c.Translation.Source == "" || c.Translation.Target == "" {
+			problems = append(problems, fmt.Errorf("translation source and target are required"))
+		}
+	}
+	if c.Translation.CacheSize < 0 || c.Translation.CacheTTL < 0 {
+		problems = append(problems, fmt.Errorf("translation cache_size and cache_ttl must not be negative"))
+	}
+	if c.Weather.Provider != "" {
+		if !strings.EqualFold(c.Weather.Provider, weather.ProviderOpenWeather) {
+			problems = append(problems, fmt.Errorf("unknown weather provider %q", c.Weather.Provider))
+		}
+		if c.Weather.APIKey == "" {
+			problems = append(problems, fmt.Errorf("weather provider %s requires an api_key", c.Weather.Provider))
+		}
+		if c.Weather.URL != "" && !isHTTPURL(c.Weather.URL) {
+			problems = append(problems, fmt.Errorf("weather url %q must be an http(s) URL", c.Weather.URL))
+		}
+	}
+	if c.Weather.CacheTTL < 0 {
+		problems = append(problems, fmt.Errorf("weather cache_ttl must not be negative"))
+	}
+	if c.Archive.RotateInterval < 0 || c.Archive.MaxSize < 0 || c.Archive.MaxFiles < 0 {
+		problems = append(problems, fmt.Errorf("archive rotate_interval, max_size and max_files must not be negative"))
+	}
+	if c.Stream.URL != "" {
+		if err := stream.ValidateURL(c.Stream.URL); err != nil {
+			problems = append(problems, err)
+		}
+		if err := stream.ValidateSubject(c.Stream.Subject); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Stream.Buffer < 0 {
+		problems = append(problems, fmt.Errorf("stream buffer must not be negative"))
+	}
+	if c.Postgres.DSN != "" {
+		if err := postgres.ValidateDSN(c.Postgres.DSN); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Postgres.Buffer < 0 {
+		problems = append(problems, fmt.Errorf("postgres buffer must not be negative"))
+	}
+	if c.Influx.URL != "" {
+		if err := influx.ValidateURL(c.Influx.URL); err != nil {
+			problems = append(problems, err)
+		}
+		if c.Influx.Bucket == "" {
+			problems = append(problems, fmt.Errorf("influx bucket is required"))
+		}
+	}
+	if c.Influx.FlushInterval < 0 || c.Influx.Buffer < 0 {
+		problems = append(problems, fmt.Errorf("influx flush_interval and buffer must not be negative"))
+	}
+	if c.Elasticsearch.URL != "" {
+		if err := elastic.ValidateURL(c.Elasticsearch.URL); err != nil {
+			problems = append(problems, err)
+		}
+		if err := elastic.ValidateIndex(c.Elasticsearch.Index); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Elasticsearch.FlushInterval < 0 || c.Elasticsearch.Buffer < 0 {
+		problems = append(problems, fmt.Errorf("elasticsearch flush_interval and buffer must not be negative"))
+	}
+	if c.HA.Enabled && c.Postgres.DSN == "" {
+		problems = append(problems, fmt.Errorf("ha requires postgres.dsn to claim messages in"))
+	}
+	if c.Shard.Count != 0 || c.Shard.Index != 0 {
+		if err := filter.ValidateShard(c.Shard.Index, c.Shard.Count); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if err := notifier.ValidateHeaders(c.Ntfy.Headers); err != nil {
+		problems = append(problems, fmt.Errorf("ntfy headers: %w", err))
+	}
+	if err := notifier.ValidateTruncation(c.Ntfy.MaxBodyLength, c.Ntfy.Truncate); err != nil {
+		problems = append(problems, fmt.Errorf("ntfy %w", err))
+	}
+	for name, dest := range c.Destinations {
+		if name == DefaultDestination {
+			problems = append(problems, fmt.Errorf("destination name %q is reserved", name))
+		}
+		if dest.Server == "" || (dest.Topic == "" && dest.IsNtfy()) {
+			problems = append(problems, fmt.Errorf("destination %q requires server and topic", name))
+		} else if !isHTTPURL(dest.Server) {
+			problems = append(problems, fmt.Errorf("destination %q server %q must be an http(s) URL", name, dest.Server))
+		}
+		if err := dest.checkBackend(); err != nil {
+			problems = append(problems, fmt.Errorf("destination %q: %w", name, err))
+		}
+		if err := checkTemplates(dest.Templates); err != nil {
+			problems = append(problems, fmt.Errorf("destination %q templates: %w", name, err))
+		}
+		if err := notifier.ValidateHeaders(dest.Headers); err != nil {
+			problems = append(problems, fmt.Errorf("destination %q headers: %w", name, err))
+		}
+		if err := notifier.ValidateTruncation(dest.MaxBodyLength, dest.Truncate); err != nil {
+			problems = append(problems, fmt.Errorf("destination %q %w", name, err))
+		}
+		if dest.Proxy != "" {
+			if _, err := proxy.Parse(dest.Proxy); err != nil {
+				problems = append(problems, fmt.Errorf("destination %q proxy: %w", name, err))
+			}
+		}
+		if err := dest.TLS.check(); err != nil {
+			problems = append(problems, fmt.Errorf("destination %q tls: %w", name, err))
+		}
+	}
+	if len(c.Pipelines) > 0 && len(c.Recipients) > 0 {
+		problems = append(problems, fmt.Errorf("recipients cannot be combined with pipelines"))
+	}
+	if len(c.Ntfy.Topics) > 0 && (len(c.Pipelines) > 0 || len(c.Recipients) > 0) {
+		problems = append(problems, fmt.Errorf("ntfy topics cannot be combined with pipelines or recipients"))
+	}
+	topics := make(map[string]bool, len(c.Ntfy.Topics))
+	for _, t := range c.Ntfy.Topics {
+		if t.Topic == "" {
+			problems = append(problems, fmt.Errorf("ntfy topics require a topic"))
+			continue
+		}
+		if topics[t.Topic] {
+			problems = append(problems, fmt.Errorf("duplicate ntfy topic %q", t.Topic))
+		}
+		topics[t.Topic] = true
+		if !t.ForwardAll && len(t.Capcodes) == 0 && len(t.Regions) == 0 && len(t.Stations) == 0 {
+			problems = append(problems, fmt.Errorf("ntfy topic %q requires a capcode, region or station when forward_all is false", t.Topic))
+		}
+		if err := checkCapcodes(t.Capcodes, t.ExcludeCapcodes); err != nil {
+			problems = append(problems, fmt.Errorf("ntfy topic %q: %w", t.Topic, err))
+		}
+		for _, d := range t.Disciplines {
+			if !validDisciplines[strings.ToLower(d)] {
+				problems = append(problems, fmt.Errorf("unknown discipline %q in ntfy topic %q", d, t.Topic))
+			}
+		}
+		if _, err := regexp.Compile(t.Pattern); err != nil {
+			problems = append(problems, fmt.Errorf("ntfy topic %q pattern: %w", t.Topic, err))
+		}
+		if err := checkTemplates(t.Templates); err != nil {
+			problems = append(problems, fmt.Errorf("ntfy topic %q templates: %w", t.Topic, err))
+		}
+	}
+	names := make(map[string]bool, len(c.Pipelines))
+	for _, p := range c.Pipelines {
+		if p.Name == "" {
+			problems = append(problems, fmt.Errorf("pipeline name must be configured"))
+			continue
+		}
+		if names[p.Name] {
+			problems = append(problems, fmt.Errorf("duplicate pipeline %q", p.Name))
+		}
+		names[p.Name] = true
+		if !p.ForwardAll && len(p.Capcodes) == 0 && len(p.Regions) == 0 && len(p.Stations) == 0 {
+			problems = append(problems, fmt.Errorf("pipeline %q requires a capcode, region or station when forward_all is false", p.Name))
+		}
+		if err := checkCapcodes(p.Capcodes, p.ExcludeCapcodes); err != nil {
+			problems = append(problems, fmt.Errorf("pipeline %q: %w", p.Name, err))
+		}
+		for _, d := range p.Disciplines {
+			if !validDisciplines[strings.ToLower(d)] {
+				problems = append(problems, fmt.Errorf("unknown discipline %q in pipeline %q", d, p.Name))
+			}
+		}
+		if len(p.Destinations) == 0 {
+			problems = append(problems, fmt.Errorf("pipeline %q requires at least one destination", p.Name))
+		}
+		for _, dest := range p.Destinations {
+			if _, ok := c.Destination(dest); !ok {
+				problems = append(problems, fmt.Errorf("pipeline %q references unknown destination %q", p.Name, dest))
+			}
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			problems = append(problems, fmt.Errorf("pipeline %q pattern: %w", p.Name, err))
+		}
+		if err := checkTemplates(p.Templates); err != nil {
+			problems = append(problems, fmt.Errorf("pipeline %q templates: %w", p.Name, err))
+		}
+	}
+	for i, rule := range c.Rules {
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		if _, err := rules.Compile(rule.When); err != nil {
+			problems = append(problems, fmt.Errorf("rule %s: invalid condition: %w", name, err))
+		}
+		if rule.Priority < 0 || rule.Priority > 5 {
+			problems = append(problems, fmt.Errorf("rule %s priority must be between 1 and 5", name))
+		}
+		if rule.Drop && len(rule.Destinations) > 0 {
+			problems = append(problems, fmt.Errorf("rule %s cannot both drop and route", name))
+		}
+		if rule.Email != "" {
+			if _, err := mail.ParseAddress(rule.Email); err != nil {
+				problems = append(problems, fmt.Errorf("rule %s email %q is not a valid address", name, rule.Email))
+			}
+		}
+		if d, err := time.ParseDuration(rule.Delay); err == nil && (d < 10*time.Second || d > 72*time.Hour) {
+			problems = append(problems, fmt.Errorf("rule %s delay must be between 10s and 72h", name))
+		}
+		for _, dest := range rule.Destinations {
+			if !c.hasDestination(dest) {
+				problems = append(problems, fmt.Errorf("rule %s references unknown destination %q", name, dest))
+			}
+		}
+	}
+	for _, recipient := range c.Recipients {
+		if recipient.Name == "" {
+			problems = append(problems, fmt.Errorf("recipient name must be configured"))
+			continue
+		}
+		if len(recipient.Channels) == 0 {
+			problems = append(problems, fmt.Errorf("recipient %q requires at least one channel", recipient.Name))
+		}
+		for _, channel := range recipient.Channels {
+			if !c.hasDestination(channel) {
+				problems = append(problems, fmt.Errorf("recipient %q references unknown destination %q", recipient.Name, channel))
+			}
+		}
+	}
+	plugins := make(map[string]bool)
+	for i, p := range c.Plugins {
+		if p.Name == "" {
+			problems = append(problems, fmt.Errorf("plugin %d name must be configured", i+1))
+			continue
+		}
+		if plugins[p.Name] {
+			problems = append(problems, fmt.Errorf("plugin %q is configured more than once", p.Name))
+		}
+		plugins[p.Name] = true
+		if p.Type != PluginFilter && p.Type != PluginNotifier {
+			problems = append(problems, fmt.Errorf("plugin %q has unknown type %q, expected filter or notifier", p.Name, p.Type))
+		}
+		if p.Path == "" {
+			problems = append(problems, fmt.Errorf("plugin %q path must be configured", p.Name))
+		}
+		if _, ok := c.Destination(p.Name); ok && p.Type == PluginNotifier {
+			problems = append(problems, fmt.Errorf("plugin %q has the name of a destination", p.Name))
+		}
+	}
+	return problems
+}
+
+// Problems lists everything wrong with a configuration
+type Problems []error
+
+// Error joins the problems on a single line
+func (p Problems) Error() string {
+	msgs := make([]string, len(p))
+	for i, err := range p {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems
+func (p Problems) Unwrap() []error {
+	return p
+}
+
+// isHTTPURL reports whether s is an absolute http(s) URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// checkCapcodes checks the prefix and range patterns in capcode lists
+func checkCapcodes(lists ...[]string) error {
+	for _, list := range lists {
+		for _, code := range list {
+			if err := filter.ValidateCapcodePattern(code); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
+
+// checkTemplates parses the notification templates
+func checkTemplates(t TemplateConfig) error {
+	_, err := notifier.ParseTemplates(t.Title, t.Body)
+	return err
+}
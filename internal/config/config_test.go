@@ -1,6 +1,8 @@
 package config
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -80,6 +82,36 @@ ntfy:
 	assert.True(t, cfg.ForwardAll)
 	assert.Empty(t, cfg.Capcodes)
 	assert.Equal(t, "capcodelijst.csv", cfg.CapcodeCSVPath)
+	assert.Equal(t, 3600, cfg.CapcodeRefresh)
+	assert.Equal(t, 60, cfg.CapcodeRetry)
+	assert.False(t, cfg.CapcodeStrict)
+	assert.Equal(t, "/ready", cfg.Server.ReadyPath)
+	assert.Equal(t, "/live", cfg.Server.LivePath)
+	assert.Equal(t, 300, cfg.Server.HealthWindow)
+	assert.Equal(t, 2, cfg.Queue.Workers)
+	assert.Equal(t, 100, cfg.Queue.Size)
+	assert.Equal(t, 30, cfg.Queue.DrainTimeout)
+	assert.Equal(t, 5, cfg.CircuitBreaker.Threshold)
+	assert.Equal(t, 60, cfg.CircuitBreaker.Cooldown)
+	assert.Equal(t, HTTPClientConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, IdleTimeout: 90, HTTP2: true}, cfg.HTTPClient)
+	assert.Equal(t, DefaultDecoderCommand, cfg.Decoder.Command)
+	assert.Equal(t, TestAlarmConfig{Action: "off", Schedule: true, Priority: 1, Tags: "test_tube"}, cfg.TestAlarms)
+	assert.Equal(t, ThreadConfig{Window: 900}, cfg.Threads)
+	assert.Equal(t, 600, cfg.DuplicateWindow)
+	assert.Equal(t, BatchingConfig{Window: 5}, cfg.Batching)
+	assert.Equal(t, GRIPConfig{MinLevel: 1, Priority: 5, Window: 14400}, cfg.GRIP)
+	assert.Equal(t, ReanimationConfig{Priority: 5, Tags: "heartpulse", Radius: 1000}, cfg.Reanimation)
+	assert.Equal(t, StatsConfig{Retention: 8, Time: "08:00"}, cfg.Stats)
+	assert.Empty(t, cfg.Geocoding.Provider)
+	assert.Equal(t, 1.0, cfg.Geocoding.RateLimit)
+	assert.Equal(t, 86400, cfg.Geocoding.CacheTTL)
+	assert.Empty(t, cfg.Archive.Dir)
+	assert.Equal(t, 86400, cfg.Archive.RotateInterval)
+	assert.Equal(t, 100, cfg.Archive.MaxSize)
+	assert.Equal(t, StreamConfig{Subject: "p2000.messages", Buffer: 1000}, cfg.Stream)
+	assert.Equal(t, PostgresConfig{Buffer: 1000}, cfg.Postgres)
+	assert.Equal(t, InfluxConfig{Measurement: "p2000_messages", FlushInterval: 10, Buffer: 1000}, cfg.Influx)
+	assert.Equal(t, ElasticConfig{Index: "p2000", FlushInterval: 10, Buffer: 1000}, cfg.Elasticsearch)
 	assert.Equal(t, 8080, cfg.Server.Port)
 }
 
@@ -208,7 +240,7 @@ ntfy:
 	require.NotNil(t, cfg)
 
 	// Should use defaults when env vars are invalid
-	assert.True(t, cfg.ForwardAll) // default
+	assert.True(t, cfg.ForwardAll)         // default
 	assert.Equal(t, 8080, cfg.Server.Port) // default
 }
 
@@ -277,6 +309,1134 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "ntfy topic must be configured",
 		},
+		{
+			name: "Valid: Recipients with destinations",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"backup": {Server: "https://ntfy.example.com", Topic: "backup"}},
+				Recipients:   []RecipientConfig{{Name: "crew", Channels: []string{"ntfy", "backup"}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Recipient with unknown destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Recipients: []RecipientConfig{{Name: "crew", Channels: []string{"telegram"}}},
+			},
+			expectError: true,
+			errorMsg:    `references unknown destination "telegram"`,
+		},
+		{
+			name: "Invalid: Recipient without channels",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Recipients: []RecipientConfig{{Name: "crew"}},
+			},
+			expectError: true,
+			errorMsg:    "requires at least one channel",
+		},
+		{
+			name: "Valid: Pipelines with destinations",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"public": {Server: "https://ntfy.sh", Topic: "public"}},
+				Pipelines: []PipelineConfig{
+					{Name: "brandweer", Capcodes: []string{"0101001"}, Destinations: []string{"ntfy"}},
+					{Name: "public", ForwardAll: true, Destinations: []string{"public"}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Duplicate pipeline",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Pipelines: []PipelineConfig{
+					{Name: "crew", ForwardAll: true, Destinations: []string{"ntfy"}},
+					{Name: "crew", ForwardAll: true, Destinations: []string{"ntfy"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `duplicate pipeline "crew"`,
+		},
+		{
+			name: "Invalid: Pipeline without filter",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Pipelines:  []PipelineConfig{{Name: "crew", Destinations: []string{"ntfy"}}},
+			},
+			expectError: true,
+			errorMsg:    `pipeline "crew" requires a capcode, region or station when forward_all is false`,
+		},
+		{
+			name: "Invalid: Pipeline with unknown destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Pipelines:  []PipelineConfig{{Name: "crew", ForwardAll: true, Destinations: []string{"pager"}}},
+			},
+			expectError: true,
+			errorMsg:    `pipeline "crew" references unknown destination "pager"`,
+		},
+		{
+			name: "Invalid: Pipelines with recipients",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Recipients: []RecipientConfig{{Name: "crew", Channels: []string{"ntfy"}}},
+				Pipelines:  []PipelineConfig{{Name: "crew", ForwardAll: true, Destinations: []string{"ntfy"}}},
+			},
+			expectError: true,
+			errorMsg:    "recipients cannot be combined with pipelines",
+		},
+		{
+			name: "Valid: Rules routing without capcodes",
+			config: Config{
+				ForwardAll:   false,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"utrecht": {Server: "https://ntfy.sh", Topic: "utrecht"}},
+				Rules: []RuleConfig{
+					{Name: "test-alarm", When: `text matches "(?i)proefalarm"`, Drop: true},
+					{Name: "fire-utrecht", When: `"brandweer" in disciplines and regions contains "Utrecht"`, Destinations: []string{"utrecht"}, Priority: 5},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Rule condition",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{Name: "fire", When: `discipline == "brandweer"`}},
+			},
+			expectError: true,
+			errorMsg:    `rule fire: invalid condition: unknown field "discipline"`,
+		},
+		{
+			name: "Invalid: Rule with unknown destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{When: `grip > 0`, Destinations: []string{"pager"}}},
+			},
+			expectError: true,
+			errorMsg:    `rule 1 references unknown destination "pager"`,
+		},
+		{
+			name: "Invalid: Rule dropping and routing",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{Name: "fire", When: `grip > 0`, Drop: true, Destinations: []string{"ntfy"}}},
+			},
+			expectError: true,
+			errorMsg:    "rule fire cannot both drop and route",
+		},
+		{
+			name: "Invalid: Rule email",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{Name: "grip", When: `grip >= 2`, Email: "ovd"}},
+			},
+			expectError: true,
+			errorMsg:    `rule grip email "ovd" is not a valid address`,
+		},
+		{
+			name: "Invalid: Rule delay",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{Name: "low", When: `priority == "P 3"`, Delay: "96h"}},
+			},
+			expectError: true,
+			errorMsg:    "rule low delay must be between 10s and 72h",
+		},
+		{
+			name: "Invalid: Capcode details format",
+			config: Config{
+				ForwardAll:     true,
+				Ntfy:           NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				CapcodeDetails: CapcodeDetailsConfig{Format: "short", Group: "station"},
+			},
+			expectError: true,
+			errorMsg:    `capcode_details unknown format "short"`,
+		},
+		{
+			name: "Invalid: Max body length",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test", MaxBodyLength: 50},
+			},
+			expectError: true,
+			errorMsg:    "ntfy max_body_length must be 0 or at least 100",
+		},
+		{
+			name: "Invalid: Destination truncate",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"pager": {Server: "https://ntfy.sh", Topic: "pager", MaxBodyLength: 4000, Truncate: "drop"}},
+			},
+			expectError: true,
+			errorMsg:    `destination "pager" unknown truncate strategy "drop"`,
+		},
+		{
+			name: "Invalid: GRIP destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				GRIP:       GRIPConfig{Destinations: []string{"grip"}, MinLevel: 1, Priority: 5},
+			},
+			expectError: true,
+			errorMsg:    `grip references unknown destination "grip"`,
+		},
+		{
+			name: "Invalid: GRIP min level",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				GRIP:       GRIPConfig{Destinations: []string{"ntfy"}, MinLevel: 6},
+			},
+			expectError: true,
+			errorMsg:    "grip min_level must be between 1 and 5",
+		},
+		{
+			name: "Valid: Reanimation locations",
+			config: Config{
+				ForwardAll:  true,
+				Ntfy:        NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Reanimation: ReanimationConfig{Enabled: true, Priority: 5, Radius: 1000, Locations: []LocationConfig{{Name: "thuis", Lat: 52.0907, Lon: 5.1214}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Reanimation location",
+			config: Config{
+				ForwardAll:  true,
+				Ntfy:        NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Reanimation: ReanimationConfig{Enabled: true, Locations: []LocationConfig{{Name: "thuis", Lat: 5.1214, Lon: 252.0907}}},
+			},
+			expectError: true,
+			errorMsg:    "reanimation location 1 has invalid coordinates",
+		},
+		{
+			name: "Invalid: Severity tags",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Severity:   SeverityConfig{Tags: map[string]string{"critical": "sos", "urgent": "warning"}},
+			},
+			expectError: true,
+			errorMsg:    `unknown severity "urgent" in severity tags`,
+		},
+		{
+			name: "Valid: Maintenance window",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				MaintenanceWindows: []MaintenanceWindowConfig{
+					{Name: "oefenavond", Capcodes: []string{"0101001", "01015*"}, Cron: "0 19 * * tue", Duration: "3h", Action: "label"},
+					{Name: "onderhoud", RRule: "FREQ=MONTHLY;BYDAY=1MO;BYHOUR=12", Duration: "30m"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Maintenance window schedule",
+			config: Config{
+				ForwardAll:         true,
+				Ntfy:               NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				MaintenanceWindows: []MaintenanceWindowConfig{{Name: "oefenavond", Cron: "0 19 * *", Duration: "3h"}},
+			},
+			expectError: true,
+			errorMsg:    "maintenance window oefenavond: cron expression",
+		},
+		{
+			name: "Invalid: Maintenance window without schedule",
+			config: Config{
+				ForwardAll:         true,
+				Ntfy:               NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				MaintenanceWindows: []MaintenanceWindowConfig{{Name: "oefenavond", Duration: "3h"}},
+			},
+			expectError: true,
+			errorMsg:    "maintenance window oefenavond: cron or rrule is required",
+		},
+		{
+			name: "Invalid: Maintenance window duration",
+			config: Config{
+				ForwardAll:         true,
+				Ntfy:               NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				MaintenanceWindows: []MaintenanceWindowConfig{{Name: "oefenavond", Cron: "0 19 * * tue", Duration: "3"}},
+			},
+			expectError: true,
+			errorMsg:    "maintenance window oefenavond: duration must be between 1s and 168h",
+		},
+		{
+			name: "Invalid: Maintenance window action",
+			config: Config{
+				ForwardAll:         true,
+				Ntfy:               NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				MaintenanceWindows: []MaintenanceWindowConfig{{Cron: "0 19 * * tue", Duration: "3h", Action: "drop"}},
+			},
+			expectError: true,
+			errorMsg:    `unknown maintenance window 1 action "drop"`,
+		},
+		{
+			name: "Invalid: Unknown test alarm action",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				TestAlarms: TestAlarmConfig{Action: "ignore"},
+			},
+			expectError: true,
+			errorMsg:    `unknown test_alarms action "ignore"`,
+		},
+		{
+			name: "Invalid: Test alarm priority",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				TestAlarms: TestAlarmConfig{Action: "downgrade", Priority: 6},
+			},
+			expectError: true,
+			errorMsg:    "test_alarms priority must be between 1 and 5",
+		},
+		{
+			name: "Invalid: Reserved ntfy header",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test", Headers: map[string]string{"Priority": "5"}},
+			},
+			expectError: true,
+			errorMsg:    "ntfy headers: header Priority is set by the notifier",
+		},
+		{
+			name: "Invalid: Capcode override priority bump",
+			config: Config{
+				ForwardAll:       true,
+				Ntfy:             NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				CapcodeOverrides: map[string]CapcodeOverrideConfig{"1420059": {Name: "Mijn kazerne", PriorityBump: 6}},
+			},
+			expectError: true,
+			errorMsg:    "capcode override 1420059 priority_bump must be between 0 and 4",
+		},
+		{
+			name: "Invalid: Threads without window",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Threads:    ThreadConfig{Enabled: true},
+			},
+			expectError: true,
+			errorMsg:    "threads window must be positive",
+		},
+		{
+			name: "Invalid: Batching without window",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Batching:   BatchingConfig{Enabled: true},
+			},
+			expectError: true,
+			errorMsg:    "batching window must be positive",
+		},
+		{
+			name: "Valid: Rule routing to a notifier plugin",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Rules:      []RuleConfig{{Name: "a1", When: `priority == "A1"`, Destinations: []string{"matrix"}}},
+				Plugins:    []PluginConfig{{Name: "matrix", Type: PluginNotifier, Path: "/plugins/matrix.so"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Plugin type",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Plugins:    []PluginConfig{{Name: "matrix", Type: "sender", Path: "/plugins/matrix.so"}},
+			},
+			expectError: true,
+			errorMsg:    "unknown type",
+		},
+		{
+			name: "Invalid: Plugin without path",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Plugins:    []PluginConfig{{Name: "oefening", Type: PluginFilter}},
+			},
+			expectError: true,
+			errorMsg:    "path must be configured",
+		},
+		{
+			name: "Invalid: Notifier plugin named after a destination",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"matrix": {Server: "https://ntfy.sh", Topic: "matrix"}},
+				Plugins:      []PluginConfig{{Name: "matrix", Type: PluginNotifier, Path: "/plugins/matrix.so"}},
+			},
+			expectError: true,
+			errorMsg:    "has the name of a destination",
+		},
+		{
+			name: "Invalid: gRPC without api token",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				GRPC:       GRPCConfig{Port: 9090},
+			},
+			expectError: true,
+			errorMsg:    "grpc requires an api token",
+		},
+		{
+			name: "Invalid: gRPC on the server port",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				API:        APIConfig{Token: "secret"},
+				Server:     ServerConfig{Port: 8080},
+				GRPC:       GRPCConfig{Port: 8080},
+			},
+			expectError: true,
+			errorMsg:    "grpc port must differ from the server port",
+		},
+		{
+			name: "Invalid: Stats summary period",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Stats:      StatsConfig{Summary: "monthly", Retention: 8, Time: "08:00"},
+			},
+			expectError: true,
+			errorMsg:    `unknown stats summary "monthly", expected daily or weekly`,
+		},
+		{
+			name: "Invalid: Weekly stats summary with short retention",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Stats:      StatsConfig{Summary: "weekly", Retention: 2, Time: "8am"},
+			},
+			expectError: true,
+			errorMsg:    "stats retention must cover the summary period",
+		},
+		{
+			name: "Valid: SOCKS proxy with direct destination",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"local": {Server: "http://ntfy.lan", Topic: "test", Proxy: "direct"}},
+				Proxy:        ProxyConfig{URL: "socks5://127.0.0.1:1080", NoProxy: "localhost,.lan"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Proxy scheme",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test", Proxy: "ftp://proxy"},
+			},
+			expectError: true,
+			errorMsg:    "ntfy proxy: proxy url \"ftp://proxy\" must use http, https, socks5 or socks5h",
+		},
+		{
+			name: "Invalid: HTTPS proxy for the websocket feed",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Proxy:      ProxyConfig{URL: "https://proxy:3128"},
+			},
+			expectError: true,
+			errorMsg:    "cannot be used for the websocket feed",
+		},
+		{
+			name: "Valid: HTTPS proxy with stdin source",
+			config: Config{
+				Source:     SourceStdin,
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Proxy:      ProxyConfig{URL: "https://proxy:3128"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Client certificate without key",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"backup": {Server: "https://ntfy.example.com", Topic: "test", TLS: TLSConfig{CertFile: "client.crt"}}},
+			},
+			expectError: true,
+			errorMsg:    `destination "backup" tls: cert_file and key_file must be set together`,
+		},
+		{
+			name: "Invalid: Server TLS without key",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Server:     ServerConfig{Port: 8080, TLS: ServerTLSConfig{CertFile: "server.crt"}},
+			},
+			expectError: true,
+			errorMsg:    "server tls: cert_file and key_file must be set together",
+		},
+		{
+			name: "Invalid: Server auth",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Server: ServerConfig{Port: 8080, Auth: ServerAuthConfig{
+					Username:   "admin",
+					AllowedIPs: []string{"10.0.0.0/8", "10.0.0.300"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "server auth: username and password must be set together",
+		},
+		{
+			name: "Invalid: Server auth allowed IP",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Server:     ServerConfig{Port: 8080, Auth: ServerAuthConfig{AllowedIPs: []string{"10.0.0.300"}, Public: []string{"/live"}}},
+			},
+			expectError: true,
+			errorMsg:    `server auth allowed_ips: invalid IP address "10.0.0.300"`,
+		},
+		{
+			name: "Invalid: Subscriptions without api token",
+			config: Config{
+				ForwardAll:    true,
+				Ntfy:          NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Server:        ServerConfig{Port: 8080},
+				Subscriptions: SubscriptionsConfig{Enabled: true},
+			},
+			expectError: true,
+			errorMsg:    "subscriptions require an api token",
+		},
+		{
+			name: "Invalid: Subscriptions max",
+			config: Config{
+				ForwardAll:    true,
+				Ntfy:          NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Server:        ServerConfig{Port: 8080},
+				Subscriptions: SubscriptionsConfig{Max: -1},
+			},
+			expectError: true,
+			errorMsg:    "subscriptions max must not be negative",
+		},
+		{
+			name: "Invalid: ntfy topics",
+			config: Config{
+				ForwardAll: true,
+				Ntfy: NtfyConfig{Server: "https://ntfy.sh", Topics: []TopicConfig{
+					{Topic: "p2000-all", ForwardAll: true},
+					{Topic: "ts-4231", Capcodes: []string{"0101001"}, Pattern: "(brand"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    `ntfy topic "ts-4231" pattern: error parsing regexp`,
+		},
+		{
+			name: "Invalid: ntfy topics with pipelines",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topics: []TopicConfig{{Topic: "p2000-all", ForwardAll: true}}},
+				Pipelines:  []PipelineConfig{{Name: "all", ForwardAll: true, Destinations: []string{"ntfy"}}},
+			},
+			expectError: true,
+			errorMsg:    "ntfy topics cannot be combined with pipelines or recipients",
+		},
+		{
+			name: "Invalid: Destination without topic",
+			config: Config{
+				ForwardAll:   true,
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{"backup": {Server: "https://ntfy.example.com"}},
+			},
+			expectError: true,
+			errorMsg:    "requires server and topic",
+		},
+		{
+			name: "Valid: ForwardAll false with regions only",
+			config: Config{
+				ForwardAll: false,
+				Regions:    []string{"Utrecht"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Unknown discipline",
+			config: Config{
+				ForwardAll:  true,
+				Disciplines: []string{"brandweer", "kustwacht"},
+				Ntfy:        NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `unknown discipline "kustwacht"`,
+		},
+		{
+			name: "Invalid: Message type priority out of range",
+			config: Config{
+				ForwardAll:   true,
+				MessageTypes: map[string]MessageTypeConfig{"POCSAG": {Priority: 6}},
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `message type "POCSAG" priority must be between 1 and 5`,
+		},
+		{
+			name: "Invalid: Special unit without capcodes or keywords",
+			config: Config{
+				ForwardAll:   true,
+				SpecialUnits: []SpecialUnitConfig{{Name: "Lifeliner", Tags: "helicopter"}},
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `special unit "Lifeliner" requires capcodes or keywords`,
+		},
+		{
+			name: "Invalid: Negative queue drain timeout",
+			config: Config{
+				ForwardAll: true,
+				Queue:      QueueConfig{DrainTimeout: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "queue drain_timeout must not be negative",
+		},
+		{
+			name: "Invalid: Unknown buffer drop policy",
+			config: Config{
+				ForwardAll: true,
+				Buffer:     BufferConfig{Size: 100, DropPolicy: "random"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `buffer drop policy must be "oldest" or "newest"`,
+		},
+		{
+			name: "Invalid: Capcode prefix with letters",
+			config: Config{
+				Capcodes: []string{"0101001", "09A*"},
+				Ntfy:     NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `invalid capcode prefix "09A*": expected digits followed by *`,
+		},
+		{
+			name: "Invalid: Pipeline capcode range",
+			config: Config{
+				ForwardAll: true,
+				Pipelines:  []PipelineConfig{{Name: "brandweer", Capcodes: []string{"1509999-1500000"}, Destinations: []string{"ntfy"}}},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `pipeline "brandweer": invalid capcode range "1509999-1500000": start after end`,
+		},
+		{
+			name: "Invalid: Unknown capcode matching",
+			config: Config{
+				ForwardAll:      true,
+				CapcodeMatching: "fuzzy",
+				Ntfy:            NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `capcode_matching must be "lenient" or "strict"`,
+		},
+		{
+			name: "Invalid: Capcode override priority",
+			config: Config{
+				ForwardAll:       true,
+				CapcodeOverrides: map[string]CapcodeOverrideConfig{"0012*": {Priority: 6}},
+				Ntfy:             NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "capcode override 0012* priority must be between 1 and 5",
+		},
+		{
+			name: "Invalid: Own unit capcode prefix",
+			config: Config{
+				ForwardAll: true,
+				OwnUnit:    OwnUnitConfig{Capcodes: []string{"14A*"}},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "own_unit: invalid capcode prefix",
+		},
+		{
+			name: "Invalid: DeepL translation without api key",
+			config: Config{
+				ForwardAll:  true,
+				Translation: TranslationConfig{Provider: "deepl", Source: "nl", Target: "en"},
+				Ntfy:        NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "translation provider deepl requires an api_key",
+		},
+		{
+			name: "Invalid: Weather provider",
+			config: Config{
+				ForwardAll: true,
+				Weather:    WeatherConfig{Provider: "knmi", APIKey: "secret"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `unknown weather provider "knmi"`,
+		},
+		{
+			name: "Invalid: Negative self heal cooldown",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				SelfHeal:   SelfHealConfig{Enabled: true, Cooldown: -1},
+			},
+			expectError: true,
+			errorMsg:    "self_heal cooldown must not be negative",
+		},
+		{
+			name: "Invalid: Negative queue size",
+			config: Config{
+				ForwardAll: true,
+				Queue:      QueueConfig{Size: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "queue size must not be negative",
+		},
+		{
+			name: "Invalid: Negative circuit breaker cooldown",
+			config: Config{
+				ForwardAll:     true,
+				CircuitBreaker: BreakerConfig{Threshold: 5, Cooldown: -1},
+				Ntfy:           NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "circuit_breaker threshold and cooldown must not be negative",
+		},
+		{
+			name: "Invalid: Negative HTTP client idle timeout",
+			config: Config{
+				ForwardAll: true,
+				HTTPClient: HTTPClientConfig{IdleTimeout: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "http_client max_idle_conns, max_idle_conns_per_host and idle_timeout must not be negative",
+		},
+		{
+			name: "Invalid: Escalation to unknown destination",
+			config: Config{
+				ForwardAll: true,
+				Escalation: EscalationConfig{Steps: []EscalationStepConfig{{After: 600, Destination: "pager"}}},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `escalation step 1 references unknown destination "pager"`,
+		},
+		{
+			name: "Invalid: Escalation steps out of order",
+			config: Config{
+				ForwardAll: true,
+				Escalation: EscalationConfig{Steps: []EscalationStepConfig{
+					{After: 600, Destination: "ntfy"},
+					{After: 300, Destination: "ntfy"},
+				}},
+				Ntfy: NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "escalation steps must be ordered by delay",
+		},
+		{
+			name: "Valid: Escalation chain to configured destinations",
+			config: Config{
+				ForwardAll: true,
+				Escalation: EscalationConfig{OnFailure: true, Steps: []EscalationStepConfig{
+					{After: 300, Destination: "pager"},
+					{After: 900, Destination: "ntfy"},
+				}},
+				Destinations: map[string]NtfyConfig{"pager": {Server: "https://ntfy.sh", Topic: "pager"}},
+				Ntfy:         NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid: Stdin source",
+			config: Config{
+				ForwardAll: true,
+				Source:     "stdin",
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Decoder source without command",
+			config: Config{
+				ForwardAll: true,
+				Source:     "decoder",
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "decoder command must be configured when source is decoder",
+		},
+		{
+			name: "Invalid: Unknown source",
+			config: Config{
+				ForwardAll: true,
+				Source:     "serial",
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `unknown source "serial"`,
+		},
+		{
+			name: "Valid: Gotify backend without topic",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Backend: "gotify", Server: "https://gotify.example.com", Token: "app-token"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Gotify backend without token",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Backend: "gotify", Server: "https://gotify.example.com"},
+			},
+			expectError: true,
+			errorMsg:    "ntfy: gotify requires the application token as token",
+		},
+		{
+			name: "Invalid: Gotify destination with receipts",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"phone": {Backend: "gotify", Server: "https://gotify.example.com", Token: "app-token", Receipts: ReceiptsConfig{Enabled: true}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `destination "phone": receipts and topics require the ntfy backend`,
+		},
+		{
+			name: "Valid: Matrix destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"matrix": {Backend: "matrix", Server: "https://matrix.org", Token: "access-token", Rooms: []string{"!p2000:matrix.org"}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Matrix destination without rooms",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"matrix": {Backend: "matrix", Server: "https://matrix.org", Token: "access-token"},
+				},
+			},
+			expectError: true,
+			errorMsg:    `destination "matrix": matrix requires at least one room`,
+		},
+		{
+			name: "Valid: SMS destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"sms": {Backend: "sms", Server: "https://api.twilio.com", Token: "auth-token", SMS: SMSConfig{Provider: "twilio", Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: SMS destination with local number",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"sms": {Backend: "sms", Server: "https://rest.messagebird.com", Token: "access-key", SMS: SMSConfig{Provider: "messagebird", From: "P2000", Numbers: []string{"0612345678"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `destination "sms": sms number "0612345678" must be in E.164 format like +31612345678`,
+		},
+		{
+			name: "Valid: Call destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"call": {Backend: "call", Server: "https://api.twilio.com", Token: "auth-token", Call: CallConfig{Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Call destination without token",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"call": {Backend: "call", Server: "https://api.twilio.com", Call: CallConfig{Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `destination "call": call requires the Twilio auth token as token`,
+		},
+		{
+			name: "Valid: APRS destination",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"aprs": {Backend: "aprs", Server: "http://rotate.aprs2.net:8080", Token: "20900", APRS: APRSConfig{Callsign: "PD0ABC-10", Addressees: []string{"BLN1P2000"}}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: APRS destination with wrong passcode",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+				Destinations: map[string]NtfyConfig{
+					"aprs": {Backend: "aprs", Server: "http://rotate.aprs2.net:8080", Token: "-1", APRS: APRSConfig{Callsign: "PD0ABC-10", Addressees: []string{"BLN1P2000"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `destination "aprs": aprs passcode does not match callsign PD0ABC-10`,
+		},
+		{
+			name: "Invalid: Unknown backend",
+			config: Config{
+				ForwardAll: true,
+				Ntfy:       NtfyConfig{Backend: "pushover", Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `ntfy: unknown backend "pushover", expected ntfy, gotify, matrix, sms, call or aprs`,
+		},
+		{
+			name: "Invalid: Websocket jitter above 1",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{Jitter: 1.5},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "websocket jitter 1.5 must be between 0 and 1",
+		},
+		{
+			name: "Invalid: Negative websocket max reconnect attempts",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{MaxReconnectAttempts: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "websocket max_reconnect_attempts and idle_timeout must not be negative",
+		},
+		{
+			name: "Invalid: Negative websocket idle timeout",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{IdleTimeout: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "websocket max_reconnect_attempts and idle_timeout must not be negative",
+		},
+		{
+			name: "Invalid: Invalid frames to unknown destination",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{InvalidFrames: InvalidFramesConfig{Destination: "debug"}},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `websocket invalid_frames references unknown destination "debug"`,
+		},
+		{
+			name: "Invalid: Invalid frames in the archive dir",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{InvalidFrames: InvalidFramesConfig{Dir: "/data/archive/"}},
+				Archive:    ArchiveConfig{Dir: "/data/archive"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "websocket invalid_frames dir must differ from the archive dir",
+		},
+		{
+			name: "Invalid: Reserved websocket header",
+			config: Config{
+				ForwardAll: true,
+				WebSocket:  WebSocketConfig{Headers: map[string]string{"Upgrade": "h2c"}},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "websocket headers: header Upgrade is set by the websocket handshake",
+		},
+		{
+			name: "Invalid: Unknown geocoding provider",
+			config: Config{
+				ForwardAll: true,
+				Geocoding:  GeocodingConfig{Provider: "google"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `unknown geocoding provider "google"`,
+		},
+		{
+			name: "Invalid: Negative geocoding rate limit",
+			config: Config{
+				ForwardAll: true,
+				Geocoding:  GeocodingConfig{Provider: "nominatim", RateLimit: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "geocoding rate_limit, cache_size and cache_ttl must not be negative",
+		},
+		{
+			name: "Invalid: Negative archive max files",
+			config: Config{
+				ForwardAll: true,
+				Archive:    ArchiveConfig{Dir: "/data/archive", MaxFiles: -1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "archive rotate_interval, max_size and max_files must not be negative",
+		},
+		{
+			name: "Valid: Stream",
+			config: Config{
+				ForwardAll: true,
+				Stream:     StreamConfig{URL: "nats://nats.example.com:4222", Subject: "p2000.messages"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Stream URL",
+			config: Config{
+				ForwardAll: true,
+				Stream:     StreamConfig{URL: "https://nats.example.com", Subject: "p2000.messages"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `stream url "https://nats.example.com" must be a nats:// or tls:// URL`,
+		},
+		{
+			name: "Invalid: Stream subject with wildcard",
+			config: Config{
+				ForwardAll: true,
+				Stream:     StreamConfig{URL: "nats://nats.example.com", Subject: "p2000.*"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `stream subject "p2000.*" must be dot separated tokens without wildcards`,
+		},
+		{
+			name: "Valid: Postgres",
+			config: Config{
+				ForwardAll: true,
+				Postgres:   PostgresConfig{DSN: "postgres://p2000@db.example.com/p2000"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Postgres DSN",
+			config: Config{
+				ForwardAll: true,
+				Postgres:   PostgresConfig{DSN: "mysql://db.example.com/p2000"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "postgres dsn must be a postgres:// URL or key=value connection string",
+		},
+		{
+			name: "Valid: Influx",
+			config: Config{
+				ForwardAll: true,
+				Influx:     InfluxConfig{URL: "http://influxdb:8086", Bucket: "p2000"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Influx without bucket",
+			config: Config{
+				ForwardAll: true,
+				Influx:     InfluxConfig{URL: "http://influxdb:8086"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "influx bucket is required",
+		},
+		{
+			name: "Valid: Elasticsearch",
+			config: Config{
+				ForwardAll:    true,
+				Elasticsearch: ElasticConfig{URL: "https://elasticsearch:9200", Index: "p2000"},
+				Ntfy:          NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: Elasticsearch index",
+			config: Config{
+				ForwardAll:    true,
+				Elasticsearch: ElasticConfig{URL: "https://elasticsearch:9200", Index: "P2000"},
+				Ntfy:          NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    `elasticsearch index "P2000" must be a lowercase index name`,
+		},
+		{
+			name: "Valid: HA with postgres",
+			config: Config{
+				ForwardAll: true,
+				HA:         HAConfig{Enabled: true},
+				Postgres:   PostgresConfig{DSN: "postgres://p2000@db.example.com/p2000"},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid: HA without postgres",
+			config: Config{
+				ForwardAll: true,
+				HA:         HAConfig{Enabled: true},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "ha requires postgres.dsn to claim messages in",
+		},
+		{
+			name: "Invalid: Shard index without count",
+			config: Config{
+				ForwardAll: true,
+				Shard:      ShardConfig{Index: 1},
+				Ntfy:       NtfyConfig{Server: "https://ntfy.sh", Topic: "test"},
+			},
+			expectError: true,
+			errorMsg:    "shard count must be at least 1",
+		},
 		{
 			name: "Valid: Empty capcodes with ForwardAll true",
 			config: Config{
@@ -379,3 +1539,185 @@ ntfy:
 	assert.Equal(t, "Ambulance Utrecht", cfg.CapcodeTranslations["0101002"])
 	assert.Equal(t, "Politie Utrecht", cfg.CapcodeTranslations["0101003"])
 }
+
+func TestLoadSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenFile := filepath.Join(tmpDir, "ntfy-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		w.Write([]byte(`{"data":{"data":{"password":"vault-pass","api":"api-token"},"metadata":{}}}`))
+	}))
+	defer vault.Close()
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "test"
+  token: "inline-token"
+  token_file: "`+tokenFile+`"
+destinations:
+  backup:
+    server: "https://ntfy.example.com"
+    topic: "backup"
+    username: "p2000"
+    password: "vault:secret/data/p2000#password"
+api:
+  token: "vault:secret/data/p2000#api"
+websocket:
+  headers:
+    X-Api-Key: "vault:secret/data/p2000#api"
+vault:
+  address: "`+vault.URL+`"
+  token: "vault-token"
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", cfg.Ntfy.Token)
+	assert.Equal(t, "vault-pass", cfg.Destinations["backup"].Password)
+	assert.Equal(t, "api-token", cfg.API.Token)
+	assert.Equal(t, "api-token", cfg.WebSocket.Headers["X-Api-Key"])
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "test"
+  password: "vault:secret/data/p2000#password"
+`), 0644))
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "ntfy password refers to vault but no vault address is configured")
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+ntfy:
+  server: "https://ntfy.sh"
+  topic: "test"
+  token_file: "`+filepath.Join(tmpDir, "missing")+`"
+`), 0644))
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "ntfy token_file:")
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+forward_all: true
+ntfy:
+  server: "ntfy.sh"
+  topic: "test"
+  tokn: "typo"
+server:
+  port: 70000
+test_alarms:
+  action: "ignore"
+rules:
+  - name: "broken"
+    when: 'text matches "("'
+templates:
+  title: "{{.Urgency"
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+
+	var problems Problems
+	require.ErrorAs(t, err, &problems)
+	assert.Len(t, problems, 6)
+	assert.ErrorContains(t, err, "line 6: field tokn not found in type config.NtfyConfig")
+	assert.ErrorContains(t, err, `ntfy server "ntfy.sh" must be an http(s) URL`)
+	assert.ErrorContains(t, err, "server port 70000 must be between 1 and 65535")
+	assert.ErrorContains(t, err, `unknown test_alarms action "ignore"`)
+	assert.ErrorContains(t, err, "rule broken: invalid condition")
+	assert.ErrorContains(t, err, "templates: invalid title template")
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, nil, 0644))
+
+	t.Setenv("NTFY_SERVER", "https://ntfy.sh")
+	t.Setenv("NTFY_TOPIC", "test")
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.ForwardAll)
+}
+
+func TestLoad_ShardIndexFromEnvironment(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("shard:\n  count: 3\n"), 0644))
+
+	t.Setenv("NTFY_SERVER", "https://ntfy.sh")
+	t.Setenv("NTFY_TOPIC", "test")
+	t.Setenv("SHARD_INDEX", "2")
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, ShardConfig{Index: 2, Count: 3}, cfg.Shard)
+
+	t.Setenv("SHARD_INDEX", "3")
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "shard index 3 must be between 0 and 2")
+}
+
+func TestDestinationTLS(t *testing.T) {
+	cfg := Config{TLS: TLSConfig{CAFile: "ca.pem"}}
+	assert.Equal(t, TLSConfig{CAFile: "ca.pem"}, cfg.DestinationTLS(NtfyConfig{}))
+
+	own := TLSConfig{CertFile: "client.crt", KeyFile: "client.key"}
+	assert.Equal(t, own, cfg.DestinationTLS(NtfyConfig{TLS: own}))
+}
+
+func TestLoad_NtfyTopics(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+ntfy:
+  server: "https://ntfy.example.com"
+  token: "secret"
+  topics:
+    - topic: "p2000-all"
+      forward_all: true
+    - topic: "ts-4231"
+      capcodes: ["0101001"]
+      pattern: "(?i)brand"
+rules:
+  - when: 'text contains "GRIP"'
+    destinations: ["ntfy/ts-4231"]
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "p2000-all", cfg.Ntfy.Topic)
+	assert.Equal(t, []string{"ntfy/ts-4231"}, cfg.TopicDestinations())
+
+	pipelines := cfg.ActivePipelines()
+	require.Len(t, pipelines, 2)
+	assert.Equal(t, PipelineConfig{Name: "p2000-all", ForwardAll: true, Destinations: []string{"ntfy"}}, pipelines[0])
+	assert.Equal(t, "(?i)brand", pipelines[1].Pattern)
+	assert.Equal(t, []string{"ntfy/ts-4231"}, pipelines[1].Destinations)
+
+	dest, ok := cfg.Destination("ntfy/ts-4231")
+	require.True(t, ok)
+	assert.Equal(t, "ts-4231", dest.Topic)
+	assert.Equal(t, "secret", dest.Token)
+	assert.Nil(t, dest.Topics)
+
+	_, ok = cfg.Destination("ntfy/unknown")
+	assert.False(t, ok)
+}
+
+func TestWebSocketConfig_DialHeaders(t *testing.T) {
+	assert.Empty(t, WebSocketConfig{}.DialHeaders())
+
+	w := WebSocketConfig{
+		Origin:    "https://p2000.example.com",
+		UserAgent: "p2000-nfty",
+		Headers:   map[string]string{"Origin": "https://other.example.com", "X-Api-Key": "secret"},
+	}
+	assert.Equal(t, map[string]string{
+		"Origin":     "https://p2000.example.com",
+		"User-Agent": "p2000-nfty",
+		"X-Api-Key":  "secret",
+	}, w.DialHeaders())
+}
@@ -0,0 +1,191 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Drop policies of a full Buffer
+const (
+	DropOldest = "oldest" // Drop the message waiting longest to make room
+	DropNewest = "newest" // Drop the incoming message
+)
+
+// ValidateDropPolicy checks that policy is a known drop policy, or empty
+// for DropOldest
+func ValidateDropPolicy(policy string) error {
+	switch strings.ToLower(policy) {
+	case "", DropOldest, DropNewest:
+		return nil
+	}
+	return fmt.Errorf("drop policy must be %q or %q", DropOldest, DropNewest)
+}
+
+// Buffer is a bounded ring of received messages between a source and the
+// pipeline. Push never blocks, so a stall in filtering, enrichment or the
+// outputs cannot hold up the websocket read loop until the upstream server
+// disconnects. Messages are handled one at a time in feed order.
+type Buffer struct {
+	handler func(model.Message)
+	policy  string
+	metrics *metrics.Metrics
+	logger  zerolog.Logger
+
+	mu     sync.Mutex
+	ring   []model.Message
+	head   int // Index of the oldest message
+	count  int
+	closed bool
+	ready  chan struct{} // Signals the consumer that messages were pushed
+	done   chan struct{} // Closed when the consumer returns
+}
+
+// NewBuffer creates a buffer of size messages and starts handing them to
+// handler. A full buffer drops messages according to policy. The buffer
+// metrics are updated when m is not nil.
+func NewBuffer(handler func(model.Message), size int, policy string, m *metrics.Metrics, logger zerolog.Logger) (*Buffer, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("buffer size must be at least 1")
+	}
+	if err := ValidateDropPolicy(policy); err != nil {
+		return nil, err
+	}
+	if policy == "" {
+		policy = DropOldest
+	}
+
+	b := &Buffer{
+		handler: handler,
+		policy:  strings.ToLower(policy),
+		metrics: m,
+		logger:  logger,
+		ring:    make([]model.Message, size),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go b.consume()
+	return b, nil
+}
+
+// Push adds msg to the buffer without blocking. When the buffer is full,
+// the oldest or the new message is dropped according to the drop policy.
+func (b *Buffer) Push(msg model.Message) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		b.drop(msg, "closed")
+		return
+	}
+
+	var dropped *model.Message
+	if b.count == len(b.ring) {
+		if b.policy == DropNewest {
+			b.mu.Unlock()
+			b.drop(msg, DropNewest)
+			return
+		}
+		oldest := b.ring[b.head]
+		dropped = &oldest
+		b.ring[b.head] = model.Message{}
+		b.head = (b.head + 1) % len(b.ring)
+		b.count--
+	}
+	b.ring[(b.head+b.count)%len(b.ring)] = msg
+	b.count++
+	b.updateDepth()
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+	b.mu.Unlock()
+
+	if dropped != nil {
+		b.drop(*dropped, DropOldest)
+	}
+}
+
+// Len returns the number of messages waiting to be handled
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Drain stops accepting messages and waits until the buffered messages are
+// handled. When ctx expires first, the remaining messages are dropped.
+func (b *Buffer) Drain(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.ready)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		pending := b.count
+		for b.count > 0 {
+			b.drop(b.pop(), "closed")
+		}
+		b.mu.Unlock()
+		return fmt.Errorf("drain timed out with %d buffered messages: %w", pending, ctx.Err())
+	}
+}
+
+// consume hands buffered messages to the handler until the buffer is
+// drained
+func (b *Buffer) consume() {
+	defer close(b.done)
+
+	for range b.ready {
+		for {
+			b.mu.Lock()
+			if b.count == 0 {
+				b.mu.Unlock()
+				break
+			}
+			msg := b.pop()
+			b.mu.Unlock()
+			b.handler(msg)
+		}
+	}
+}
+
+// pop removes the oldest message; b.mu must be held and the buffer must
+// not be empty
+func (b *Buffer) pop() model.Message {
+	msg := b.ring[b.head]
+	b.ring[b.head] = model.Message{}
+	b.head = (b.head + 1) % len(b.ring)
+	b.count--
+	b.updateDepth()
+	return msg
+}
+
+// drop logs and counts a message that is not handled: the oldest or newest
+// message of a full buffer, or a message pushed or left after the drain
+func (b *Buffer) drop(msg model.Message, reason string) {
+	b.logger.Warn().
+		Str("reason", reason).
+		Str("agency", msg.Agency).
+		Strs("capcodes", msg.Capcodes).
+		Msg("dropping received message from buffer")
+	if b.metrics != nil {
+		b.metrics.RecordBufferDropped(reason)
+	}
+}
+
+func (b *Buffer) updateDepth() {
+	if b.metrics != nil {
+		b.metrics.SetBufferDepth(b.count)
+	}
+}
@@ -0,0 +1,114 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledHandler blocks on the first message until release is closed and
+// records the messages it handles
+type stalledHandler struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	got     []string
+}
+
+func newStalledHandler() *stalledHandler {
+	return &stalledHandler{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (h *stalledHandler) handle(msg model.Message) {
+	h.mu.Lock()
+	first := len(h.got) == 0
+	h.got = append(h.got, msg.Message)
+	h.mu.Unlock()
+	if first {
+		close(h.started)
+		<-h.release
+	}
+}
+
+func (h *stalledHandler) handled() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.got...)
+}
+
+func TestBuffer_DropOldest(t *testing.T) {
+	m := metrics.NewMetrics()
+	h := newStalledHandler()
+	b, err := NewBuffer(h.handle, 2, DropOldest, m, getTestLogger())
+	require.NoError(t, err)
+
+	b.Push(model.Message{Message: "A1"})
+	<-h.started
+
+	// The handler is stalled: pushing does not block and the oldest
+	// waiting messages make room
+	for _, text := range []string{"A2", "A3", "A4"} {
+		b.Push(model.Message{Message: text})
+	}
+	assert.Equal(t, 2, b.Len())
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.BufferDepth))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.BufferDropped.WithLabelValues(DropOldest)))
+
+	close(h.release)
+	require.NoError(t, b.Drain(context.Background()))
+	assert.Equal(t, []string{"A1", "A3", "A4"}, h.handled())
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BufferDepth))
+}
+
+func TestBuffer_DropNewest(t *testing.T) {
+	m := metrics.NewMetrics()
+	h := newStalledHandler()
+	b, err := NewBuffer(h.handle, 2, DropNewest, m, getTestLogger())
+	require.NoError(t, err)
+
+	b.Push(model.Message{Message: "A1"})
+	<-h.started
+	for _, text := range []string{"A2", "A3", "A4"} {
+		b.Push(model.Message{Message: text})
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.BufferDropped.WithLabelValues(DropNewest)))
+
+	close(h.release)
+	require.NoError(t, b.Drain(context.Background()))
+	assert.Equal(t, []string{"A1", "A2", "A3"}, h.handled())
+}
+
+func TestBuffer_DrainTimeout(t *testing.T) {
+	m := metrics.NewMetrics()
+	h := newStalledHandler()
+	b, err := NewBuffer(h.handle, 10, "", m, getTestLogger())
+	require.NoError(t, err)
+
+	b.Push(model.Message{Message: "A1"})
+	<-h.started
+	b.Push(model.Message{Message: "A2"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, b.Drain(ctx), "drain timed out with 1 buffered messages")
+	close(h.release)
+
+	// Messages pushed after the drain are dropped too
+	b.Push(model.Message{Message: "A3"})
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.BufferDropped.WithLabelValues("closed")))
+	assert.Equal(t, []string{"A1"}, h.handled())
+}
+
+func TestNewBuffer_Invalid(t *testing.T) {
+	_, err := NewBuffer(func(model.Message) {}, 0, DropOldest, nil, getTestLogger())
+	assert.EqualError(t, err, "buffer size must be at least 1")
+	_, err = NewBuffer(func(model.Message) {}, 10, "random", nil, getTestLogger())
+	assert.EqualError(t, err, `drop policy must be "oldest" or "newest"`)
+}
@@ -0,0 +1,158 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrClosed is returned by Enqueue once the dispatcher is draining
+	ErrClosed = errors.New("dispatcher is closed")
+	// ErrQueueFull is returned by Enqueue when the message was dropped
+	// because all queue slots are taken
+	ErrQueueFull = errors.New("notification queue is full")
+)
+
+// Handler delivers a single message as d describes. ctx is cancelled when a
+// drain times out.
+type Handler func(ctx context.Context, msg model.Message, d notifier.Delivery)
+
+// job is a queued message with its delivery
+type job struct {
+	msg      model.Message
+	delivery notifier.Delivery
+}
+
+// Dispatcher hands messages to a pool of workers through a bounded queue so
+// the websocket reader is not blocked by slow notification backends, and
+// lets in-flight notifications finish on shutdown. With more than one
+// worker, notifications may be delivered out of feed order.
+type Dispatcher struct {
+	handler Handler
+	metrics *metrics.Metrics
+	logger  zerolog.Logger
+	queue   chan job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// New creates a dispatcher with queueSize slots and starts workers
+// goroutines. The queue metrics are updated when m is not nil.
+func New(handler Handler, workers, queueSize int, m *metrics.Metrics, logger zerolog.Logger) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		handler: handler,
+		metrics: m,
+		logger:  logger,
+		queue:   make(chan job, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Enqueue queues msg for delivery as delivery describes, without blocking.
+// The message is dropped with ErrQueueFull when the queue has no room left.
+func (d *Dispatcher) Enqueue(msg model.Message, delivery notifier.Delivery) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+
+	select {
+	case d.queue <- job{msg: msg, delivery: delivery}:
+		d.updateDepth()
+		return nil
+	default:
+		d.dropped()
+		return ErrQueueFull
+	}
+}
+
+// Len returns the number of messages waiting for a worker
+func (d *Dispatcher) Len() int {
+	return len(d.queue)
+}
+
+// Drain stops accepting messages and waits until all queued and in-flight
+// messages are handled. When ctx expires first, in-flight deliveries are
+// cancelled and the remaining messages are dropped.
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		pending := len(d.queue)
+		d.cancel()
+		<-done
+		return fmt.Errorf("drain timed out with %d queued messages: %w", pending, ctx.Err())
+	}
+}
+
+// work handles queued messages until the queue is closed
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for j := range d.queue {
+		d.updateDepth()
+		if d.ctx.Err() != nil {
+			d.logger.Warn().
+				Str("agency", j.msg.Agency).
+				Strs("capcodes", j.msg.Capcodes).
+				Msg("dropping queued notification after drain timeout")
+			d.dropped()
+			continue
+		}
+		d.handler(d.ctx, j.msg, j.delivery)
+	}
+}
+
+func (d *Dispatcher) updateDepth() {
+	if d.metrics != nil {
+		d.metrics.SetQueueDepth(len(d.queue))
+	}
+}
+
+func (d *Dispatcher) dropped() {
+	if d.metrics != nil {
+		d.metrics.RecordNotificationDropped()
+	}
+}
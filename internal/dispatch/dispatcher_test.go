@@ -0,0 +1,159 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestDispatcher_SingleWorkerDeliversInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var got, routes []string
+	d := New(func(ctx context.Context, msg model.Message, delivery notifier.Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, msg.Message)
+		routes = append(routes, delivery.Routes...)
+	}, 1, 10, nil, getTestLogger())
+
+	for _, text := range []string{"A1", "A2", "B1"} {
+		require.NoError(t, d.Enqueue(model.Message{Message: text}, notifier.Delivery{Routes: []string{"pager-" + text}}))
+	}
+	require.NoError(t, d.Drain(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"A1", "A2", "B1"}, got)
+	assert.Equal(t, []string{"pager-A1", "pager-A2", "pager-B1"}, routes)
+}
+
+func TestDispatcher_DrainWaitsForInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var delivered bool
+	d := New(func(ctx context.Context, msg model.Message, _ notifier.Delivery) {
+		close(started)
+		<-release
+		delivered = true
+	}, 1, 10, nil, getTestLogger())
+
+	require.NoError(t, d.Enqueue(model.Message{Message: "A1"}, notifier.Delivery{}))
+	<-started
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	require.NoError(t, d.Drain(context.Background()))
+	assert.True(t, delivered)
+}
+
+func TestDispatcher_DrainTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var mu sync.Mutex
+	var handled []string
+	d := New(func(ctx context.Context, msg model.Message, _ notifier.Delivery) {
+		started <- struct{}{}
+		<-ctx.Done()
+		mu.Lock()
+		handled = append(handled, msg.Message)
+		mu.Unlock()
+	}, 1, 10, nil, getTestLogger())
+
+	require.NoError(t, d.Enqueue(model.Message{Message: "A1"}, notifier.Delivery{}))
+	require.NoError(t, d.Enqueue(model.Message{Message: "A2"}, notifier.Delivery{}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := d.Drain(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "1 queued messages")
+
+	// The in-flight delivery was cancelled and the queued one dropped
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"A1"}, handled)
+}
+
+func TestDispatcher_EnqueueAfterDrain(t *testing.T) {
+	d := New(func(ctx context.Context, msg model.Message, _ notifier.Delivery) {}, 1, 10, nil, getTestLogger())
+	require.NoError(t, d.Drain(context.Background()))
+
+	assert.ErrorIs(t, d.Enqueue(model.Message{}, notifier.Delivery{}), ErrClosed)
+	// Draining twice is harmless
+	assert.NoError(t, d.Drain(context.Background()))
+}
+
+func TestDispatcher_QueueFullDrops(t *testing.T) {
+	m := metrics.NewMetrics()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	d := New(func(ctx context.Context, msg model.Message, _ notifier.Delivery) {
+		started <- struct{}{}
+		<-release
+	}, 1, 1, m, getTestLogger())
+
+	// The worker holds the first message, the second fills the only slot
+	require.NoError(t, d.Enqueue(model.Message{Message: "A1"}, notifier.Delivery{}))
+	<-started
+	require.NoError(t, d.Enqueue(model.Message{Message: "A2"}, notifier.Delivery{}))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.QueueDepth))
+
+	assert.ErrorIs(t, d.Enqueue(model.Message{Message: "B1"}, notifier.Delivery{}), ErrQueueFull)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.NotificationsDropped))
+
+	close(release)
+	require.NoError(t, d.Drain(context.Background()))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.QueueDepth))
+}
+
+func TestDispatcher_WorkerPool(t *testing.T) {
+	const workers = 3
+	var mu sync.Mutex
+	active, peak := 0, 0
+	release := make(chan struct{})
+	d := New(func(ctx context.Context, msg model.Message, _ notifier.Delivery) {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}, workers, 10, nil, getTestLogger())
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, d.Enqueue(model.Message{}, notifier.Delivery{}))
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return active == workers
+	}, time.Second, 5*time.Millisecond)
+
+	close(release)
+	require.NoError(t, d.Drain(context.Background()))
+	assert.Equal(t, workers, peak)
+}
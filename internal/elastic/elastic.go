@@ -0,0 +1,299 @@
+// Package elastic bulk indexes the enriched messages into Elasticsearch or
+// OpenSearch, in monthly indices created from an embedded index template,
+// for full-text search over years of pages.
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// template is the index template installed for the indices, with mappings
+// for full-text search of the message and location
+//
+//go:embed template.json
+var template []byte
+
+const (
+	DefaultIndex         = "p2000"
+	DefaultFlushInterval = 10 * time.Second
+	DefaultBuffer        = 1000
+
+	maxBatch       = 500 // Documents per bulk request
+	maxAttempts    = 3   // Flushes of a batch before it is dropped
+	requestTimeout = 30 * time.Second
+)
+
+// Options configures an Indexer. Only URL is required.
+type Options struct {
+	URL           string // Elasticsearch or OpenSearch server, e.g. https://elasticsearch:9200
+	Index         string // Index prefix, documents go to <prefix>-YYYY.MM; DefaultIndex when empty
+	Username      string // Optional basic authentication
+	Password      string
+	APIKey        string        // Optional encoded API key, preferred over Username
+	FlushInterval time.Duration // DefaultFlushInterval when 0
+	Buffer        int           // Documents waiting before new ones are dropped, DefaultBuffer when 0
+
+	Metrics *metrics.Metrics // Optional
+	Logger  zerolog.Logger
+}
+
+// Document is the indexed JSON of a message: the enriched message with the
+// page time, the time it was received and whether it was forwarded
+type Document struct {
+	model.Message
+	Timestamp  time.Time `json:"@timestamp"`
+	ReceivedAt time.Time `json:"received_at"`
+	Forwarded  bool      `json:"forwarded"`
+}
+
+// Indexer batches documents in the background and indexes them with the
+// bulk API. Index never blocks the feed: when the buffer is full, documents
+// are dropped.
+type Indexer struct {
+	opts       Options
+	server     string
+	httpClient *http.Client
+	docs       chan []byte
+	installed  bool // Index template installed, owned by Run
+}
+
+// ValidateURL checks that rawURL is an http(s) server URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("elasticsearch url %q must be an http:// or https:// URL", rawURL)
+	}
+	return nil
+}
+
+// ValidateIndex checks that index is a valid index name prefix: lowercase,
+// without the characters Elasticsearch forbids in names
+func ValidateIndex(index string) error {
+	if index != strings.ToLower(index) || strings.ContainsAny(index, `\/*?"<>| ,#:`) || strings.HasPrefix(index, "_") || strings.HasPrefix(index, "-") {
+		return fmt.Errorf("elasticsearch index %q must be a lowercase index name", index)
+	}
+	return nil
+}
+
+// New creates an indexer; Run installs the template and indexes the
+// documents
+func New(opts Options) (*Indexer, error) {
+	if err := ValidateURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.Index == "" {
+		opts.Index = DefaultIndex
+	}
+	if err := ValidateIndex(opts.Index); err != nil {
+		return nil, err
+	}
+	if opts.FlushInterval < 0 || opts.Buffer < 0 {
+		return nil, fmt.Errorf("elasticsearch flush_interval and buffer must not be negative")
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = DefaultBuffer
+	}
+
+	return &Indexer{
+		opts:       opts,
+		server:     strings.TrimRight(opts.URL, "/"),
+		httpClient: &http.Client{Timeout: requestTimeout},
+		docs:       make(chan []byte, opts.Buffer),
+	}, nil
+}
+
+// Index queues msg, paged at t, for indexing. Every document gets a random
+// ID, so a retried bulk request indexes it once.
+func (x *Indexer) Index(msg model.Message, t, receivedAt time.Time, forwarded bool) {
+	doc, err := json.Marshal(Document{Message: msg, Timestamp: t.UTC(), ReceivedAt: receivedAt.UTC(), Forwarded: forwarded})
+	if err != nil {
+		x.opts.Logger.Error().Err(err).Msg("failed to encode elasticsearch document")
+		return
+	}
+	action, err := json.Marshal(map[string]any{
+		"index": map[string]string{"_index": x.opts.Index + "-" + t.UTC().Format("2006.01"), "_id": randomID()},
+	})
+	if err != nil {
+		x.opts.Logger.Error().Err(err).Msg("failed to encode elasticsearch action")
+		return
+	}
+
+	select {
+	case x.docs <- append(append(append(action, '\n'), doc...), '\n'):
+	default:
+		x.record("dropped", 1)
+		x.opts.Logger.Warn().Str("id", msg.ID).Msg("elasticsearch buffer full, dropping message")
+	}
+}
+
+// Run indexes the queued documents every flush interval, or sooner when a
+// batch is full, until ctx is cancelled. A failed batch is retried with the
+// next flush.
+func (x *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(x.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	attempts := 0
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		failed, err := x.bulk(ctx, batch)
+		if err == nil {
+			x.record("indexed", len(batch)-failed)
+			x.record("failed", failed)
+			batch, attempts = nil, 0
+			return
+		}
+		attempts++
+		x.opts.Logger.Debug().Err(err).Int("attempt", attempts).Msg("failed to index in elasticsearch")
+		if attempts == maxAttempts {
+			x.record("failed", len(batch))
+			x.opts.Logger.Error().Err(err).Int("documents", len(batch)).Msg("failed to index messages in elasticsearch")
+			batch, attempts = nil, 0
+		}
+	}
+
+	for {
+		select {
+		case doc := <-x.docs:
+			batch = append(batch, doc)
+			if len(batch) == maxBatch {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			// Index what is left, without the cancelled context
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			flush(ctx)
+			cancel()
+			return
+		}
+	}
+}
+
+// bulk indexes batch, installing the index template first when needed. It
+// returns the number of documents the server rejected, e.g. for a mapping
+// conflict; those are not retried.
+func (x *Indexer) bulk(ctx context.Context, batch [][]byte) (int, error) {
+	if !x.installed {
+		if err := x.installTemplate(ctx); err != nil {
+			return 0, fmt.Errorf("failed to install index template: %w", err)
+		}
+		x.installed = true
+	}
+
+	resp, err := x.do(ctx, "POST", "/_bulk", "application/x-ndjson", bytes.Join(batch, nil))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid bulk response: %w", err)
+	}
+	if !result.Errors {
+		return 0, nil
+	}
+
+	failed := 0
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Status >= 300 {
+				failed++
+				x.opts.Logger.Warn().Int("status", r.Status).Str("type", r.Error.Type).Str("reason", r.Error.Reason).Msg("elasticsearch rejected message")
+			}
+		}
+	}
+	return failed, nil
+}
+
+// installTemplate puts the embedded index template, matching the indices
+// of the configured prefix
+func (x *Indexer) installTemplate(ctx context.Context) error {
+	var t map[string]any
+	if err := json.Unmarshal(template, &t); err != nil {
+		return err
+	}
+	t["index_patterns"] = []string{x.opts.Index + "-*"}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	resp, err := x.do(ctx, "PUT", "/_index_template/"+url.PathEscape(x.opts.Index), "application/json", data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do makes an authenticated request, returning an error for unsuccessful
+// status codes
+func (x *Indexer) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, x.server+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case x.opts.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+x.opts.APIKey)
+	case x.opts.Username != "":
+		req.SetBasicAuth(x.opts.Username, x.opts.Password)
+	}
+
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// record counts n documents by their result when metrics are configured
+func (x *Indexer) record(result string, n int) {
+	if x.opts.Metrics != nil && n > 0 {
+		x.opts.Metrics.RecordElasticsearchDocuments(result, n)
+	}
+}
+
+// randomID returns a random hexadecimal document ID
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,178 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer records the template and bulk requests of an indexer
+type fakeServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	templates map[string]map[string]any
+	actions   []map[string]map[string]string
+	docs      []Document
+	auth      []string
+	failBulk  int  // Bulk requests to fail with 503
+	reject    bool // Reject the documents with a mapping error
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{templates: make(map[string]map[string]any)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+
+	switch {
+	case r.Method == "PUT" && len(r.URL.Path) > len("/_index_template/"):
+		var t map[string]any
+		json.Unmarshal(body, &t)
+		s.templates[r.URL.Path[len("/_index_template/"):]] = t
+	case r.Method == "POST" && r.URL.Path == "/_bulk":
+		if s.failBulk > 0 {
+			s.failBulk--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var items []string
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			json.Unmarshal(scanner.Bytes(), &action)
+			scanner.Scan()
+			var doc Document
+			json.Unmarshal(scanner.Bytes(), &doc)
+			if s.reject {
+				items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+				continue
+			}
+			s.actions = append(s.actions, action)
+			s.docs = append(s.docs, doc)
+			items = append(items, `{"index":{"status":201}}`)
+		}
+		resp := `{"errors":` + map[bool]string{true: "true", false: "false"}[s.reject] + `,"items":[`
+		for i, item := range items {
+			if i > 0 {
+				resp += ","
+			}
+			resp += item
+		}
+		w.Write([]byte(resp + "]}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	server := newFakeServer(t)
+	server.failBulk = 1
+	x, err := New(Options{URL: server.URL, Index: "p2000-utrecht", APIKey: "a2V5", FlushInterval: 20 * time.Millisecond, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go x.Run(ctx)
+
+	paged := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	msg := model.Message{ID: "42", Message: "P 1 Brand woning Dorpsstraat Utrecht", Priority: "P 1", Coordinates: &model.Coordinates{Lat: 52.09, Lon: 5.12}}
+	x.Index(msg, paged, paged.Add(2*time.Second), true)
+
+	// The first bulk request fails and is retried with the next flush
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.docs) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Contains(t, server.templates, "p2000-utrecht")
+	assert.Equal(t, []any{"p2000-utrecht-*"}, server.templates["p2000-utrecht"]["index_patterns"])
+	assert.Equal(t, "p2000-utrecht-2026.10", server.actions[0]["index"]["_index"])
+	assert.Len(t, server.actions[0]["index"]["_id"], 24)
+	assert.Equal(t, "P 1 Brand woning Dorpsstraat Utrecht", server.docs[0].Message.Message)
+	assert.Equal(t, paged, server.docs[0].Timestamp)
+	assert.Equal(t, paged.Add(2*time.Second), server.docs[0].ReceivedAt)
+	assert.True(t, server.docs[0].Forwarded)
+	assert.Equal(t, "ApiKey a2V5", server.auth[0])
+}
+
+func TestIndexer_Rejected(t *testing.T) {
+	server := newFakeServer(t)
+	server.reject = true
+	x, err := New(Options{URL: server.URL, Username: "p2000", Password: "secret", Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	x.Index(model.Message{Message: "A1"}, time.Now(), time.Now(), true)
+	failed, err := x.bulk(context.Background(), [][]byte{<-x.docs})
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+	assert.Contains(t, server.templates, DefaultIndex)
+}
+
+func TestIndexer_DropsWhenFull(t *testing.T) {
+	x, err := New(Options{URL: "http://elasticsearch:9200", Buffer: 1, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	// Without Run nothing is taken from the buffer
+	x.Index(model.Message{Message: "1"}, time.Now(), time.Now(), true)
+	x.Index(model.Message{Message: "2"}, time.Now(), time.Now(), true)
+	assert.Len(t, x.docs, 1)
+}
+
+func TestTemplate(t *testing.T) {
+	var tmpl struct {
+		IndexPatterns []string `json:"index_patterns"`
+		Template      struct {
+			Mappings struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"mappings"`
+		} `json:"template"`
+	}
+	require.NoError(t, json.Unmarshal(template, &tmpl))
+	assert.Equal(t, []string{"p2000-*"}, tmpl.IndexPatterns)
+
+	// Every document field is mapped
+	data, err := json.Marshal(Document{Message: model.Message{Coordinates: &model.Coordinates{}}})
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(data, &doc))
+	for field := range doc {
+		if field == "signal" || field == "frequency_error" || field == "priority_override" {
+			continue // Receiver details, not searched
+		}
+		assert.Contains(t, tmpl.Template.Mappings.Properties, field)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Options{URL: "elasticsearch:9200"})
+	assert.EqualError(t, err, `elasticsearch url "elasticsearch:9200" must be an http:// or https:// URL`)
+
+	_, err = New(Options{URL: "http://elasticsearch:9200", Index: "P2000"})
+	assert.EqualError(t, err, `elasticsearch index "P2000" must be a lowercase index name`)
+
+	_, err = New(Options{URL: "http://elasticsearch:9200", Buffer: -1})
+	assert.EqualError(t, err, "elasticsearch flush_interval and buffer must not be negative")
+}
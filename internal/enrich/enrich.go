@@ -0,0 +1,74 @@
+// Package enrich adds context from external sources to messages before they
+// are notified, such as the weather at the incident. Enrichers run in order
+// after geocoding, so they can use the incident coordinates.
+package enrich
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Enricher adds context to messages
+type Enricher interface {
+	// Name identifies the enricher in logs and metrics
+	Name() string
+	// Enrich adds context to msg. Messages the enricher does not apply to
+	// are left unchanged without error.
+	Enrich(ctx context.Context, msg *model.Message) error
+}
+
+// Chain runs enrichers in order. A failing enricher is logged and skipped,
+// so the notification is sent without its context.
+type Chain struct {
+	enrichers []Enricher
+	timeout   time.Duration
+	metrics   *metrics.Metrics
+	logger    zerolog.Logger
+}
+
+// NewChain creates a chain giving each enricher at most timeout per
+// message, or the deadline of the context when timeout is 0. Errors are
+// counted when m is not nil.
+func NewChain(timeout time.Duration, m *metrics.Metrics, logger zerolog.Logger) *Chain {
+	return &Chain{timeout: timeout, metrics: m, logger: logger}
+}
+
+// Add appends an enricher to the chain
+func (c *Chain) Add(e Enricher) {
+	c.enrichers = append(c.enrichers, e)
+}
+
+// Len returns the number of enrichers
+func (c *Chain) Len() int {
+	return len(c.enrichers)
+}
+
+// Enrich runs every enricher on msg
+func (c *Chain) Enrich(ctx context.Context, msg *model.Message) {
+	for _, e := range c.enrichers {
+		if err := c.run(ctx, e, msg); err != nil {
+			c.logger.Warn().
+				Err(err).
+				Str("enricher", e.Name()).
+				Str("id", msg.ID).
+				Msg("failed to enrich message")
+			if c.metrics != nil {
+				c.metrics.RecordEnrichmentError(e.Name())
+			}
+		}
+	}
+}
+
+// run runs a single enricher within the timeout
+func (c *Chain) run(ctx context.Context, e Enricher, msg *model.Message) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	return e.Enrich(ctx, msg)
+}
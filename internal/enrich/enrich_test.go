@@ -0,0 +1,62 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// funcEnricher adapts a function to an Enricher
+type funcEnricher struct {
+	name string
+	fn   func(ctx context.Context, msg *model.Message) error
+}
+
+func (f funcEnricher) Name() string { return f.name }
+
+func (f funcEnricher) Enrich(ctx context.Context, msg *model.Message) error { return f.fn(ctx, msg) }
+
+func TestChain_Enrich(t *testing.T) {
+	m := metrics.NewMetrics()
+	c := NewChain(time.Second, m, getTestLogger())
+	c.Add(funcEnricher{"failing", func(context.Context, *model.Message) error {
+		return errors.New("service unavailable")
+	}})
+	c.Add(funcEnricher{"location", func(_ context.Context, msg *model.Message) error {
+		msg.Location = "Damrak, Amsterdam"
+		return nil
+	}})
+	assert.Equal(t, 2, c.Len())
+
+	msg := model.Message{Message: "P 1 Stormschade"}
+	c.Enrich(context.Background(), &msg)
+
+	assert.Equal(t, "Damrak, Amsterdam", msg.Location, "a failing enricher does not stop the chain")
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.EnrichmentErrors.WithLabelValues("failing")))
+}
+
+func TestChain_Timeout(t *testing.T) {
+	c := NewChain(10*time.Millisecond, nil, getTestLogger())
+	var deadline bool
+	c.Add(funcEnricher{"slow", func(ctx context.Context, _ *model.Message) error {
+		_, deadline = ctx.Deadline()
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	c.Enrich(context.Background(), &model.Message{})
+	assert.True(t, deadline)
+}
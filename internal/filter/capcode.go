@@ -4,20 +4,16 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// CapcodeFilter filters messages based on exact capcode matches
+// CapcodeFilter filters messages based on capcodes, prefixes and ranges
 type CapcodeFilter struct {
-	forwardAll  bool
-	allowedCaps map[string]struct{}
-	logger      zerolog.Logger
+	forwardAll bool
+	allowed    *Matcher
+	logger     zerolog.Logger
 }
 
-// NewCapcodeFilter creates a new capcode filter
-func NewCapcodeFilter(forwardAll bool, capcodes []string, logger zerolog.Logger) *CapcodeFilter {
-	allowedCaps := make(map[string]struct{}, len(capcodes))
-	for _, capcode := range capcodes {
-		allowedCaps[capcode] = struct{}{}
-	}
-
+// NewCapcodeFilter creates a new capcode filter. capcodes are patterns as
+// accepted by NewMatcher; with lenient, leading zeros are ignored.
+func NewCapcodeFilter(forwardAll bool, capcodes []string, lenient bool, logger zerolog.Logger) *CapcodeFilter {
 	if forwardAll {
 		logger.Info().Msg("capcode filter initialized with forward_all=true (all messages will be forwarded)")
 	} else {
@@ -27,9 +23,9 @@ func NewCapcodeFilter(forwardAll bool, capcodes []string, logger zerolog.Logger)
 	}
 
 	return &CapcodeFilter{
-		forwardAll:  forwardAll,
-		allowedCaps: allowedCaps,
-		logger:      logger,
+		forwardAll: forwardAll,
+		allowed:    NewMatcher(capcodes, lenient),
+		logger:     logger,
 	}
 }
 
@@ -49,7 +45,7 @@ func (f *CapcodeFilter) ShouldForward(capcodes []string) bool {
 	}
 
 	for _, capcode := range capcodes {
-		if _, exists := f.allowedCaps[capcode]; exists {
+		if f.allowed.Match(capcode) {
 			f.logger.Debug().
 				Str("matched_capcode", capcode).
 				Msg("capcode match found")
@@ -65,5 +61,5 @@ func (f *CapcodeFilter) ShouldForward(capcodes []string) bool {
 
 // Count returns the number of configured capcodes
 func (f *CapcodeFilter) Count() int {
-	return len(f.allowedCaps)
+	return f.allowed.Len()
 }
@@ -56,7 +56,7 @@ func TestNewCapcodeFilter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			filter := NewCapcodeFilter(tt.forwardAll, tt.capcodes, logger)
+			filter := NewCapcodeFilter(tt.forwardAll, tt.capcodes, false, logger)
 			assert.NotNil(t, filter)
 			assert.Equal(t, tt.forwardAll, filter.forwardAll)
 			assert.Equal(t, tt.wantCount, filter.Count())
@@ -66,7 +66,7 @@ func TestNewCapcodeFilter(t *testing.T) {
 
 func TestShouldForward_ForwardAllEnabled(t *testing.T) {
 	logger := getTestLogger()
-	filter := NewCapcodeFilter(true, []string{"0101001"}, logger)
+	filter := NewCapcodeFilter(true, []string{"0101001"}, false, logger)
 
 	tests := []struct {
 		name     string
@@ -111,7 +111,7 @@ func TestShouldForward_ForwardAllEnabled(t *testing.T) {
 func TestShouldForward_ForwardAllDisabled(t *testing.T) {
 	logger := getTestLogger()
 	allowedCapcodes := []string{"0101001", "0101002", "0101003"}
-	filter := NewCapcodeFilter(false, allowedCapcodes, logger)
+	filter := NewCapcodeFilter(false, allowedCapcodes, false, logger)
 
 	tests := []struct {
 		name     string
@@ -177,46 +177,46 @@ func TestShouldForward_EdgeCases(t *testing.T) {
 	logger := getTestLogger()
 
 	t.Run("Empty filter with empty capcodes", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{}, logger)
+		filter := NewCapcodeFilter(false, []string{}, false, logger)
 		result := filter.ShouldForward([]string{})
 		assert.False(t, result)
 	})
 
 	t.Run("Empty filter with non-empty capcodes", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{}, logger)
+		filter := NewCapcodeFilter(false, []string{}, false, logger)
 		result := filter.ShouldForward([]string{"0101001"})
 		assert.False(t, result)
 	})
 
 	t.Run("Special characters in capcodes", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{"ABC-123", "DEF_456"}, logger)
+		filter := NewCapcodeFilter(false, []string{"ABC-123", "DEF_456"}, false, logger)
 		assert.True(t, filter.ShouldForward([]string{"ABC-123"}))
 		assert.True(t, filter.ShouldForward([]string{"DEF_456"}))
 		assert.False(t, filter.ShouldForward([]string{"ABC123"}))
 	})
 
 	t.Run("Case sensitivity", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{"abc123"}, logger)
+		filter := NewCapcodeFilter(false, []string{"abc123"}, false, logger)
 		assert.True(t, filter.ShouldForward([]string{"abc123"}))
 		assert.False(t, filter.ShouldForward([]string{"ABC123"}))
 		assert.False(t, filter.ShouldForward([]string{"Abc123"}))
 	})
 
 	t.Run("Leading zeros", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{"0101001"}, logger)
+		filter := NewCapcodeFilter(false, []string{"0101001"}, false, logger)
 		assert.True(t, filter.ShouldForward([]string{"0101001"}))
 		assert.False(t, filter.ShouldForward([]string{"101001"}))
 	})
 
 	t.Run("Whitespace in capcodes", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{"0101001", " 0101002"}, logger)
+		filter := NewCapcodeFilter(false, []string{"0101001", " 0101002"}, false, logger)
 		assert.True(t, filter.ShouldForward([]string{"0101001"}))
 		assert.True(t, filter.ShouldForward([]string{" 0101002"}))
 		assert.False(t, filter.ShouldForward([]string{"0101002"}))
 	})
 
 	t.Run("Duplicate capcodes in allowed list", func(t *testing.T) {
-		filter := NewCapcodeFilter(false, []string{"0101001", "0101001", "0101002"}, logger)
+		filter := NewCapcodeFilter(false, []string{"0101001", "0101001", "0101002"}, false, logger)
 		// Map deduplicates, so count should be 2
 		assert.Equal(t, 2, filter.Count())
 		assert.True(t, filter.ShouldForward([]string{"0101001"}))
@@ -261,7 +261,7 @@ func TestCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			filter := NewCapcodeFilter(false, tt.capcodes, logger)
+			filter := NewCapcodeFilter(false, tt.capcodes, false, logger)
 			assert.Equal(t, tt.wantCount, filter.Count())
 		})
 	}
@@ -272,7 +272,7 @@ func TestPerformance(t *testing.T) {
 
 	// Test with large number of capcodes
 	largeCapcodeList := generateCapcodes(10000)
-	filter := NewCapcodeFilter(false, largeCapcodeList, logger)
+	filter := NewCapcodeFilter(false, largeCapcodeList, false, logger)
 
 	assert.Equal(t, 10000, filter.Count())
 
@@ -291,7 +291,7 @@ func TestPerformance(t *testing.T) {
 
 func TestConcurrentAccess(t *testing.T) {
 	logger := getTestLogger()
-	filter := NewCapcodeFilter(false, []string{"0101001", "0101002"}, logger)
+	filter := NewCapcodeFilter(false, []string{"0101001", "0101002"}, false, logger)
 
 	// Test concurrent reads (should be safe since no writes)
 	done := make(chan bool, 10)
@@ -332,7 +332,7 @@ func padCapcode(num int) string {
 
 func BenchmarkShouldForward_ForwardAll(b *testing.B) {
 	logger := getTestLogger()
-	filter := NewCapcodeFilter(true, []string{}, logger)
+	filter := NewCapcodeFilter(true, []string{}, false, logger)
 	capcodes := []string{"0101001", "0101002", "0101003"}
 
 	b.ResetTimer()
@@ -343,7 +343,7 @@ func BenchmarkShouldForward_ForwardAll(b *testing.B) {
 
 func BenchmarkShouldForward_SmallFilter(b *testing.B) {
 	logger := getTestLogger()
-	filter := NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, logger)
+	filter := NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, false, logger)
 	capcodes := []string{"0101001"}
 
 	b.ResetTimer()
@@ -355,7 +355,7 @@ func BenchmarkShouldForward_SmallFilter(b *testing.B) {
 func BenchmarkShouldForward_LargeFilter(b *testing.B) {
 	logger := getTestLogger()
 	largeList := generateCapcodes(10000)
-	filter := NewCapcodeFilter(false, largeList, logger)
+	filter := NewCapcodeFilter(false, largeList, false, logger)
 	capcodes := []string{"0005000"}
 
 	b.ResetTimer()
@@ -366,7 +366,7 @@ func BenchmarkShouldForward_LargeFilter(b *testing.B) {
 
 func BenchmarkShouldForward_NoMatch(b *testing.B) {
 	logger := getTestLogger()
-	filter := NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, logger)
+	filter := NewCapcodeFilter(false, []string{"0101001", "0101002", "0101003"}, false, logger)
 	capcodes := []string{"9999999"}
 
 	b.ResetTimer()
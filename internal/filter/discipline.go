@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+)
+
+// Discipline is an emergency service discipline
+type Discipline string
+
+const (
+	DisciplineBrandweer Discipline = "brandweer"
+	DisciplineAmbulance Discipline = "ambulance"
+	DisciplinePolitie   Discipline = "politie"
+	DisciplineKNRM      Discipline = "knrm"
+	DisciplineUnknown   Discipline = ""
+)
+
+// ClassifyAgency maps a capcode database agency name to a discipline
+func ClassifyAgency(agency string) Discipline {
+	a := strings.ToLower(agency)
+	switch {
+	case strings.Contains(a, "brandweer"), strings.Contains(a, "fire"):
+		return DisciplineBrandweer
+	case strings.Contains(a, "ambu"), strings.Contains(a, "ghor"), strings.Contains(a, "mmt"), strings.Contains(a, "lifeliner"):
+		return DisciplineAmbulance
+	case strings.Contains(a, "politie"), strings.Contains(a, "police"), strings.Contains(a, "marechaussee"):
+		return DisciplinePolitie
+	case strings.Contains(a, "knrm"), strings.Contains(a, "reddingsbrigade"):
+		return DisciplineKNRM
+	default:
+		return DisciplineUnknown
+	}
+}
+
+// CapcodeRange is an inclusive numeric capcode range
+type CapcodeRange struct {
+	From int
+	To   int
+}
+
+// Contains reports whether the numeric value of capcode lies in the range
+func (r CapcodeRange) Contains(code string) bool {
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return false
+	}
+	return n >= r.From && n <= r.To
+}
+
+// ParseCapcodeRange parses "1500000-1509999" into a range
+func ParseCapcodeRange(s string) (CapcodeRange, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return CapcodeRange{}, fmt.Errorf("invalid capcode range %q: expected from-to", s)
+	}
+	f, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return CapcodeRange{}, fmt.Errorf("invalid capcode range %q: %w", s, err)
+	}
+	t, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil {
+		return CapcodeRange{}, fmt.Errorf("invalid capcode range %q: %w", s, err)
+	}
+	if f > t {
+		return CapcodeRange{}, fmt.Errorf("invalid capcode range %q: start after end", s)
+	}
+	return CapcodeRange{From: f, To: t}, nil
+}
+
+// DisciplineFilter forwards messages when any capcode belongs to one of the
+// selected disciplines. Capcodes are classified by their agency in the
+// capcode database, falling back to configured numeric ranges.
+type DisciplineFilter struct {
+	lookup      *capcode.Lookup
+	disciplines map[Discipline]struct{}
+	ranges      map[Discipline][]CapcodeRange
+	logger      zerolog.Logger
+}
+
+// NewDisciplineFilter creates a new discipline filter. ranges maps a
+// discipline to capcode ranges like "1500000-1509999".
+func NewDisciplineFilter(lookup *capcode.Lookup, disciplines []string, ranges map[string][]string, logger zerolog.Logger) (*DisciplineFilter, error) {
+	f := &DisciplineFilter{
+		lookup:      lookup,
+		disciplines: make(map[Discipline]struct{}, len(disciplines)),
+		ranges:      make(map[Discipline][]CapcodeRange, len(ranges)),
+		logger:      logger,
+	}
+
+	for _, d := range disciplines {
+		f.disciplines[Discipline(strings.ToLower(d))] = struct{}{}
+	}
+	for d, specs := range ranges {
+		for _, spec := range specs {
+			r, err := ParseCapcodeRange(spec)
+			if err != nil {
+				return nil, err
+			}
+			key := Discipline(strings.ToLower(d))
+			f.ranges[key] = append(f.ranges[key], r)
+		}
+	}
+
+	logger.Info().
+		Strs("disciplines", disciplines).
+		Msg("discipline filter initialized")
+
+	return f, nil
+}
+
+// Classify determines the discipline of a single capcode
+func (f *DisciplineFilter) Classify(code string) Discipline {
+	if f.lookup != nil {
+		if info, ok := f.lookup.Find(code); ok {
+			if d := ClassifyAgency(info.Agency); d != DisciplineUnknown {
+				return d
+			}
+		}
+	}
+
+	for d, ranges := range f.ranges {
+		for _, r := range ranges {
+			if r.Contains(code) {
+				return d
+			}
+		}
+	}
+
+	return DisciplineUnknown
+}
+
+// ShouldForward checks if any capcode belongs to a selected discipline
+func (f *DisciplineFilter) ShouldForward(capcodes []string) bool {
+	for _, code := range capcodes {
+		d := f.Classify(code)
+		if _, ok := f.disciplines[d]; ok && d != DisciplineUnknown {
+			f.logger.Debug().
+				Str("matched_capcode", code).
+				Str("discipline", string(d)).
+				Msg("discipline match found")
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyAgency(t *testing.T) {
+	tests := []struct {
+		agency string
+		want   Discipline
+	}{
+		{"Brandweer", DisciplineBrandweer},
+		{"brandweer Utrecht", DisciplineBrandweer},
+		{"Ambulance", DisciplineAmbulance},
+		{"GHOR", DisciplineAmbulance},
+		{"Lifeliner", DisciplineAmbulance},
+		{"Politie", DisciplinePolitie},
+		{"KNRM", DisciplineKNRM},
+		{"Reddingsbrigade", DisciplineKNRM},
+		{"Gemeente", DisciplineUnknown},
+		{"", DisciplineUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agency, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyAgency(tt.agency))
+		})
+	}
+}
+
+func TestParseCapcodeRange(t *testing.T) {
+	r, err := ParseCapcodeRange("1500000-1509999")
+	require.NoError(t, err)
+	assert.Equal(t, CapcodeRange{From: 1500000, To: 1509999}, r)
+	assert.True(t, r.Contains("1505000"))
+	assert.True(t, r.Contains("01500000"))
+	assert.False(t, r.Contains("1510000"))
+	assert.False(t, r.Contains("abc"))
+
+	for _, invalid := range []string{"1500000", "a-b", "10-x", "20-10"} {
+		_, err := ParseCapcodeRange(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDisciplineFilter_ShouldForward(t *testing.T) {
+	f, err := NewDisciplineFilter(testLookup(), []string{"Brandweer"}, map[string][]string{
+		"brandweer": {"1500000-1509999"},
+		"ambulance": {"1600000-1609999"},
+	}, getTestLogger())
+	require.NoError(t, err)
+
+	assert.True(t, f.ShouldForward([]string{"0101001"}))            // Brandweer via lookup
+	assert.False(t, f.ShouldForward([]string{"0101002"}))           // Ambulance via lookup
+	assert.True(t, f.ShouldForward([]string{"0101002", "0345678"})) // Any capcode
+	assert.True(t, f.ShouldForward([]string{"1500123"}))            // Brandweer via range
+	assert.False(t, f.ShouldForward([]string{"1600123"}))           // Ambulance via range
+	assert.False(t, f.ShouldForward([]string{"9999999"}))           // Unknown
+	assert.Equal(t, DisciplineAmbulance, f.Classify("1600123"))
+}
+
+func TestDisciplineFilter_InvalidRange(t *testing.T) {
+	_, err := NewDisciplineFilter(nil, []string{"brandweer"}, map[string][]string{
+		"brandweer": {"invalid"},
+	}, getTestLogger())
+	assert.Error(t, err)
+}
+
+func TestAll(t *testing.T) {
+	logger := getTestLogger()
+	base := NewCapcodeFilter(true, nil, false, logger)
+	disciplines, err := NewDisciplineFilter(testLookup(), []string{"brandweer"}, nil, logger)
+	require.NoError(t, err)
+
+	f := All(base, disciplines)
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.False(t, f.ShouldForward([]string{"0234567"}))
+}
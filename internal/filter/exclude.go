@@ -0,0 +1,42 @@
+package filter
+
+import (
+	"github.com/rs/zerolog"
+)
+
+// ExcludeFilter rejects messages containing any blocked capcode, e.g. weekly
+// test alarms or monitor codes. Combine it with All so it overrides other
+// matches and forward_all.
+type ExcludeFilter struct {
+	blocked *Matcher
+	logger  zerolog.Logger
+}
+
+// NewExcludeFilter creates a new capcode blocklist filter. capcodes are
+// patterns as accepted by NewMatcher; with lenient, leading zeros are
+// ignored.
+func NewExcludeFilter(capcodes []string, lenient bool, logger zerolog.Logger) *ExcludeFilter {
+	blocked := NewMatcher(capcodes, lenient)
+
+	logger.Info().
+		Int("count", len(capcodes)).
+		Msg("capcode exclude filter initialized")
+
+	return &ExcludeFilter{
+		blocked: blocked,
+		logger:  logger,
+	}
+}
+
+// ShouldForward returns false when any capcode is blocked
+func (f *ExcludeFilter) ShouldForward(capcodes []string) bool {
+	for _, capcode := range capcodes {
+		if f.blocked.Match(capcode) {
+			f.logger.Debug().
+				Str("excluded_capcode", capcode).
+				Msg("message suppressed by exclude list")
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcludeFilter_ShouldForward(t *testing.T) {
+	f := NewExcludeFilter([]string{"0100999", "0200999"}, false, getTestLogger())
+
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.True(t, f.ShouldForward(nil))
+	assert.False(t, f.ShouldForward([]string{"0100999"}))
+	assert.False(t, f.ShouldForward([]string{"0101001", "0200999"}))
+}
+
+func TestExcludeFilter_OverridesForwardAll(t *testing.T) {
+	logger := getTestLogger()
+	f := All(
+		NewCapcodeFilter(true, nil, false, logger),
+		NewExcludeFilter([]string{"0100999"}, false, logger),
+	)
+
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.False(t, f.ShouldForward([]string{"0101001", "0100999"}))
+}
+
+func TestExcludeFilter_OverridesCapcodeMatch(t *testing.T) {
+	logger := getTestLogger()
+	f := All(
+		NewCapcodeFilter(false, []string{"0101001"}, false, logger),
+		NewExcludeFilter([]string{"0100999"}, false, logger),
+	)
+
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.False(t, f.ShouldForward([]string{"0101001", "0100999"}))
+}
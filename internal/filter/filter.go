@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter decides whether a message with the given capcodes is forwarded
+type Filter interface {
+	ShouldForward(capcodes []string) bool
+}
+
+// anyFilter forwards when at least one of its filters matches
+type anyFilter []Filter
+
+// Any combines filters so a message is forwarded when any of them matches
+func Any(filters ...Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return anyFilter(filters)
+}
+
+// ShouldForward checks the filters in order and stops at the first match
+func (a anyFilter) ShouldForward(capcodes []string) bool {
+	for _, f := range a {
+		if f.ShouldForward(capcodes) {
+			return true
+		}
+	}
+	return false
+}
+
+// allFilter forwards only when every filter matches
+type allFilter []Filter
+
+// All combines filters so a message is only forwarded when all of them match
+func All(filters ...Filter) Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return allFilter(filters)
+}
+
+// ShouldForward checks the filters in order and stops at the first rejection
+func (a allFilter) ShouldForward(capcodes []string) bool {
+	for _, f := range a {
+		if !f.ShouldForward(capcodes) {
+			return false
+		}
+	}
+	return true
+}
+
+// Step is the outcome of a filter for a message, with the outcomes of the
+// filters it combines
+type Step struct {
+	Filter  string `json:"filter"`
+	Name    string `json:"name,omitempty"`
+	Forward bool   `json:"forward"`
+	Steps   []Step `json:"steps,omitempty"`
+}
+
+// Explainer is implemented by filters that explain their outcome
+// themselves, such as the pipeline router
+type Explainer interface {
+	Explain(capcodes []string) Step
+}
+
+// Explain evaluates f for capcodes, recording the outcome of every filter
+// it combines. Unlike ShouldForward every combined filter is evaluated.
+func Explain(f Filter, capcodes []string) Step {
+	switch f := f.(type) {
+	case Explainer:
+		return f.Explain(capcodes)
+	case anyFilter:
+		step := Step{Filter: "any"}
+		for _, sub := range f {
+			s := Explain(sub, capcodes)
+			step.Forward = step.Forward || s.Forward
+			step.Steps = append(step.Steps, s)
+		}
+		return step
+	case allFilter:
+		step := Step{Filter: "all", Forward: true}
+		for _, sub := range f {
+			s := Explain(sub, capcodes)
+			step.Forward = step.Forward && s.Forward
+			step.Steps = append(step.Steps, s)
+		}
+		return step
+	}
+	return Step{Filter: filterName(f), Forward: f.ShouldForward(capcodes)}
+}
+
+// filterName returns the kind of a filter
+func filterName(f Filter) string {
+	switch f.(type) {
+	case *CapcodeFilter:
+		return "capcode"
+	case *RegionFilter:
+		return "region"
+	case *DisciplineFilter:
+		return "discipline"
+	case *ExcludeFilter:
+		return "exclude"
+	}
+	return fmt.Sprintf("%T", f)
+}
+
+// hasFold reports whether the lowercase set built by toSet holds name in
+// any case. Unlike lowering name first it does not allocate, which matters
+// for the capcode database names checked for every message.
+func hasFold(set map[string]struct{}, name string) bool {
+	if _, ok := set[name]; ok {
+		return true
+	}
+	for v := range set {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet builds a lowercase lookup set, ignoring empty values
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			set[v] = struct{}{}
+		}
+	}
+	return set
+}
@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	f := All(
+		Any(
+			NewCapcodeFilter(false, []string{"0101001"}, false, getTestLogger()),
+			NewCapcodeFilter(false, []string{"0101002"}, false, getTestLogger()),
+		),
+		NewExcludeFilter([]string{"0101999"}, false, getTestLogger()),
+	)
+
+	step := Explain(f, []string{"0101002", "0101999"})
+	assert.Equal(t, Step{
+		Filter:  "all",
+		Forward: false,
+		Steps: []Step{
+			{Filter: "any", Forward: true, Steps: []Step{
+				{Filter: "capcode", Forward: false},
+				{Filter: "capcode", Forward: true},
+			}},
+			{Filter: "exclude", Forward: false},
+		},
+	}, step)
+	assert.Equal(t, f.ShouldForward([]string{"0101002", "0101999"}), step.Forward)
+
+	assert.Equal(t, Step{Filter: "capcode", Forward: true}, Explain(NewCapcodeFilter(true, nil, false, getTestLogger()), nil))
+}
@@ -0,0 +1,192 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+)
+
+// Matcher matches capcodes against a list of patterns: exact capcodes,
+// prefixes like "09*" and inclusive numeric ranges like "1500000-1509999".
+// Safety regions are assigned contiguous blocks of capcodes, so a region
+// is one prefix or range instead of thousands of capcodes. Prefixes match
+// the capcode as received, as its leading zeros tell the block apart;
+// ranges compare its numeric value, so leading zeros do not matter.
+// Exact capcodes match as received, or normalized when lenient.
+type Matcher struct {
+	lenient  bool
+	exact    map[string]struct{}
+	prefixes *trieNode
+	ranges   []CapcodeRange // Sorted by From and merged
+	count    int
+}
+
+// trieNode is a node of the prefix trie, indexed by digit
+type trieNode struct {
+	children [10]*trieNode
+	end      bool // A prefix ends at this node
+}
+
+// NewMatcher creates a matcher for patterns. A pattern that is neither a
+// valid prefix nor a valid range is matched as an exact capcode; use
+// ValidateCapcodePattern to reject those when loading the configuration.
+// With lenient, exact capcodes are compared by capcode.Normalize, so
+// "101001" matches "0101001".
+func NewMatcher(patterns []string, lenient bool) *Matcher {
+	m := &Matcher{lenient: lenient, exact: make(map[string]struct{}, len(patterns))}
+	seen := make(map[string]struct{}, len(patterns))
+	for _, p := range patterns {
+		if _, dup := seen[p]; dup {
+			continue
+		}
+		seen[p] = struct{}{}
+		switch {
+		case isPrefixPattern(p):
+			if ValidateCapcodePattern(p) == nil {
+				m.addPrefix(strings.TrimSuffix(p, "*"))
+				continue
+			}
+		case strings.Contains(p, "-"):
+			if r, err := ParseCapcodeRange(p); err == nil {
+				m.ranges = append(m.ranges, r)
+				continue
+			}
+		}
+		m.exact[m.key(p)] = struct{}{}
+	}
+	m.count = len(seen)
+	m.ranges = mergeRanges(m.ranges)
+	return m
+}
+
+// ValidateCapcodePattern checks that a prefix or range pattern is valid.
+// Other values are exact capcodes and always valid.
+func ValidateCapcodePattern(p string) error {
+	switch {
+	case isPrefixPattern(p):
+		prefix := strings.TrimSuffix(p, "*")
+		if prefix == "" {
+			return fmt.Errorf("invalid capcode prefix %q: use forward_all to match every capcode", p)
+		}
+		for i := 0; i < len(prefix); i++ {
+			if prefix[i] < '0' || prefix[i] > '9' {
+				return fmt.Errorf("invalid capcode prefix %q: expected digits followed by *", p)
+			}
+		}
+	case strings.Contains(p, "-"):
+		if _, err := ParseCapcodeRange(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsCapcodePattern reports whether p is a prefix or range rather than a
+// single capcode
+func IsCapcodePattern(p string) bool {
+	return isPrefixPattern(p) || strings.Contains(p, "-")
+}
+
+// isPrefixPattern reports whether p is meant as a prefix
+func isPrefixPattern(p string) bool {
+	return strings.HasSuffix(p, "*")
+}
+
+// Match reports whether code matches any pattern, without allocating
+func (m *Matcher) Match(code string) bool {
+	if _, ok := m.exact[m.key(code)]; ok {
+		return true
+	}
+	if m.prefixes != nil {
+		node := m.prefixes
+		for i := 0; i < len(code); i++ {
+			c := code[i]
+			if c < '0' || c > '9' {
+				break
+			}
+			if node = node.children[c-'0']; node == nil {
+				break
+			}
+			if node.end {
+				return true
+			}
+		}
+	}
+	if len(m.ranges) > 0 {
+		n, ok := parseCapcode(code)
+		if !ok {
+			return false
+		}
+		// The first range ending at or after n is the only candidate, as
+		// merged ranges do not overlap
+		i := sort.Search(len(m.ranges), func(i int) bool { return m.ranges[i].To >= n })
+		return i < len(m.ranges) && m.ranges[i].From <= n
+	}
+	return false
+}
+
+// Len returns the number of distinct patterns
+func (m *Matcher) Len() int {
+	return m.count
+}
+
+// key returns the key of an exact capcode
+func (m *Matcher) key(code string) string {
+	if m.lenient {
+		return capcode.Normalize(code)
+	}
+	return code
+}
+
+// addPrefix adds a prefix of digits to the trie
+func (m *Matcher) addPrefix(prefix string) {
+	if m.prefixes == nil {
+		m.prefixes = &trieNode{}
+	}
+	node := m.prefixes
+	for i := 0; i < len(prefix); i++ {
+		d := prefix[i] - '0'
+		if node.children[d] == nil {
+			node.children[d] = &trieNode{}
+		}
+		node = node.children[d]
+	}
+	node.end = true
+}
+
+// mergeRanges sorts ranges and merges the overlapping and adjacent ones
+func mergeRanges(ranges []CapcodeRange) []CapcodeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.From <= last.To+1 {
+			last.To = max(last.To, r.To)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// parseCapcode returns the numeric value of a capcode of digits. Unlike
+// strconv.Atoi it does not allocate an error for other values.
+func parseCapcode(code string) (int, bool) {
+	if code == "" || len(code) > 18 {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
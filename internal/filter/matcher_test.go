@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	m := NewMatcher([]string{"0101001", "09*", "1500000-1509999", "1505000-1519999", "1600000-1600009"}, false)
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"0101001", true},
+		{"0101002", false},
+		{"0920001", true},
+		{"09", true},
+		{"0820001", false},
+		{"0009*", false},
+		{"1500000", true},
+		{"001509999", true},
+		{"1519999", true}, // Overlapping ranges are merged
+		{"1520000", false},
+		{"1600009", true},
+		{"1600010", false},
+		{"1499999", false},
+		{"", false},
+		{"abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, m.Match(tt.code))
+		})
+	}
+	assert.Equal(t, 5, m.Len())
+}
+
+func TestMatcher_InvalidPatternsAreExact(t *testing.T) {
+	m := NewMatcher([]string{"0A*", "20-10"}, false)
+	assert.True(t, m.Match("0A*"))
+	assert.True(t, m.Match("20-10"))
+	assert.False(t, m.Match("0A1"))
+	assert.False(t, m.Match("15"))
+}
+
+func TestMatcher_DoesNotAllocate(t *testing.T) {
+	m := NewMatcher([]string{"0101001", "09*", "1500000-1509999"}, false)
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Match("0920001")
+		m.Match("1505000")
+		m.Match("P2000")
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestValidateCapcodePattern(t *testing.T) {
+	assert.NoError(t, ValidateCapcodePattern("0101001"))
+	assert.NoError(t, ValidateCapcodePattern("09*"))
+	assert.NoError(t, ValidateCapcodePattern("1500000-1509999"))
+	assert.EqualError(t, ValidateCapcodePattern("*"), `invalid capcode prefix "*": use forward_all to match every capcode`)
+	assert.EqualError(t, ValidateCapcodePattern("0A*"), `invalid capcode prefix "0A*": expected digits followed by *`)
+	assert.EqualError(t, ValidateCapcodePattern("1509999-1500000"), `invalid capcode range "1509999-1500000": start after end`)
+}
+
+func TestCapcodeFilter_Patterns(t *testing.T) {
+	f := NewCapcodeFilter(false, []string{"09*", "1500000-1509999"}, false, getTestLogger())
+	assert.True(t, f.ShouldForward([]string{"0101001", "0920001"}))
+	assert.True(t, f.ShouldForward([]string{"1500123"}))
+	assert.False(t, f.ShouldForward([]string{"0101001"}))
+
+	exclude := NewExcludeFilter([]string{"0999*"}, false, getTestLogger())
+	assert.False(t, exclude.ShouldForward([]string{"0920001", "0999001"}))
+	assert.True(t, exclude.ShouldForward([]string{"0920001"}))
+}
+
+func BenchmarkMatcher_Match(b *testing.B) {
+	// A safety region as 10000 capcodes, one prefix or one range
+	capcodes := make([]string, 10000)
+	for i := range capcodes {
+		capcodes[i] = fmt.Sprintf("09%05d", i)
+	}
+	for _, bm := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"Exact", capcodes},
+		{"Prefix", []string{"09*"}},
+		{"Range", []string{"0900000-0909999"}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			m := NewMatcher(bm.patterns, false)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Match("0905000")
+			}
+		})
+	}
+}
+
+func TestMatcher_Lenient(t *testing.T) {
+	m := NewMatcher([]string{"101001", "000120901", "0012*"}, true)
+	assert.True(t, m.Match("0101001"))
+	assert.True(t, m.Match("101001"))
+	assert.True(t, m.Match("120901"))
+	assert.True(t, m.Match(" 0120901"))
+	assert.True(t, m.Match("001201234"), "prefixes match as written")
+	assert.False(t, m.Match("1201234"))
+
+	strict := NewMatcher([]string{"101001"}, false)
+	assert.False(t, strict.Match("0101001"))
+}
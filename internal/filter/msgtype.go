@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+// TypeFilter suppresses messages by feed message type (FLEX, POCSAG, ...)
+// and optionally numeric-only status pages. Unlike capcode filters it looks
+// at the message itself, so it is applied separately from the Filter chain.
+type TypeFilter struct {
+	suppressed  map[string]struct{}
+	skipNumeric bool
+	logger      zerolog.Logger
+}
+
+// NewTypeFilter creates a filter rejecting the suppressed message types and,
+// when skipNumeric is set, messages that only contain digits
+func NewTypeFilter(suppressed []string, skipNumeric bool, logger zerolog.Logger) *TypeFilter {
+	logger.Info().
+		Strs("suppressed_types", suppressed).
+		Bool("skip_numeric", skipNumeric).
+		Msg("message type filter initialized")
+
+	return &TypeFilter{
+		suppressed:  toSet(suppressed),
+		skipNumeric: skipNumeric,
+		logger:      logger,
+	}
+}
+
+// Allow reports whether a message of msgType with the given text is forwarded
+func (f *TypeFilter) Allow(msgType, text string) bool {
+	if _, exists := f.suppressed[strings.ToLower(msgType)]; exists {
+		f.logger.Debug().
+			Str("type", msgType).
+			Msg("message suppressed by type")
+		return false
+	}
+
+	if f.skipNumeric && IsNumeric(text) {
+		f.logger.Debug().
+			Str("type", msgType).
+			Msg("numeric-only message suppressed")
+		return false
+	}
+
+	return true
+}
+
+// IsNumeric reports whether text is a numeric-only page: at least one digit
+// and otherwise only whitespace or separators
+func IsNumeric(text string) bool {
+	digits := 0
+	for _, r := range text {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsSpace(r), r == '-', r == '.', r == ':', r == '/':
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
@@ -0,0 +1,46 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeFilter_Suppressed(t *testing.T) {
+	f := NewTypeFilter([]string{"POCSAG"}, false, getTestLogger())
+
+	assert.True(t, f.Allow("FLEX", "P 1 Brand woning"))
+	assert.False(t, f.Allow("POCSAG", "P 1 Brand woning"))
+	assert.False(t, f.Allow("pocsag", "P 1 Brand woning"), "types are case-insensitive")
+	assert.True(t, f.Allow("FLEX", "12345"), "numeric messages are kept unless skipped")
+}
+
+func TestTypeFilter_SkipNumeric(t *testing.T) {
+	f := NewTypeFilter(nil, true, getTestLogger())
+
+	assert.False(t, f.Allow("FLEX", "12345"))
+	assert.False(t, f.Allow("FLEX", " 0101 - 12:30 "))
+	assert.True(t, f.Allow("FLEX", "A1 12345 Rit 1"))
+	assert.True(t, f.Allow("FLEX", ""))
+}
+
+func TestIsNumeric(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"12345", true},
+		{"12 34.56", true},
+		{"2024-01-01 12:00", true},
+		{"", false},
+		{" - ", false},
+		{"P 1 12345", false},
+		{"TEST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsNumeric(tt.text))
+		})
+	}
+}
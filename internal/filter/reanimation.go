@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// DefaultReanimationKeywords mark an ambulance page as a resuscitation call
+var DefaultReanimationKeywords = []string{
+	"reanimatie",
+	"reanimeren",
+	"hartstilstand",
+	"circulatiestilstand",
+}
+
+// ReanimationDetector recognizes resuscitation calls, for alerting AED
+// volunteers
+type ReanimationDetector struct {
+	keywords []string
+	logger   zerolog.Logger
+}
+
+// NewReanimationDetector creates a detector matching keywords anywhere in the
+// text, case-insensitively. DefaultReanimationKeywords are used when keywords
+// is empty.
+func NewReanimationDetector(keywords []string, logger zerolog.Logger) *ReanimationDetector {
+	if len(keywords) == 0 {
+		keywords = DefaultReanimationKeywords
+	}
+	d := &ReanimationDetector{logger: logger}
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			d.keywords = append(d.keywords, k)
+		}
+	}
+	return d
+}
+
+// IsReanimation reports whether an enriched message is a resuscitation
+// call: it contains a keyword and is for the ambulance, by a capcode of an
+// ambulance agency or an A urgency code
+func (d *ReanimationDetector) IsReanimation(msg model.Message) bool {
+	if !ambulance(msg) {
+		return false
+	}
+	lower := strings.ToLower(msg.Message)
+	for _, k := range d.keywords {
+		if strings.Contains(lower, k) {
+			d.logger.Debug().Str("keyword", k).Msg("reanimation detected")
+			return true
+		}
+	}
+	return false
+}
+
+// ambulance reports whether a message is for the ambulance discipline
+func ambulance(msg model.Message) bool {
+	if strings.HasPrefix(msg.Priority, "A") {
+		return true
+	}
+	for _, info := range msg.CapcodeInfo {
+		if ClassifyAgency(info.Agency) == DisciplineAmbulance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReanimationDetector(t *testing.T) {
+	d := NewReanimationDetector(nil, getTestLogger())
+	detect := func(text string, agencies ...string) bool {
+		msg := model.Message{Message: text, Priority: model.ParsePriority(text)}
+		for _, a := range agencies {
+			msg.CapcodeInfo = append(msg.CapcodeInfo, capcode.CapcodeInfo{Agency: a})
+		}
+		return d.IsReanimation(msg)
+	}
+
+	assert.True(t, detect("A1 Reanimatie Dorpsstraat Utrecht"))
+	assert.True(t, detect("A1 HARTSTILSTAND Oudegracht Utrecht"))
+	assert.True(t, detect("Reanimeren Dorpsstraat Utrecht", "Ambulance"), "ambulance by capcode")
+	assert.False(t, detect("P 1 Assistentie reanimatie Dorpsstraat Utrecht", "Brandweer"), "not for the ambulance")
+	assert.False(t, detect("A1 Verkeersongeval Rijksweg A2"))
+
+	d = NewReanimationDetector([]string{"AED", " "}, getTestLogger())
+	assert.True(t, detect("A1 AED inzet Utrecht"))
+	assert.False(t, detect("A1 Reanimatie Utrecht"), "custom keywords replace the defaults")
+}
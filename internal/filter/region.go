@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+)
+
+// RegionFilter forwards messages when any capcode resolves to a configured
+// region or station in the capcode database
+type RegionFilter struct {
+	lookup   *capcode.Lookup
+	regions  map[string]struct{}
+	stations map[string]struct{}
+	logger   zerolog.Logger
+}
+
+// NewRegionFilter creates a new region/station filter. Names are matched
+// case-insensitively.
+func NewRegionFilter(lookup *capcode.Lookup, regions, stations []string, logger zerolog.Logger) *RegionFilter {
+	if lookup == nil {
+		logger.Warn().Msg("region filter configured without capcode lookup, no messages will match on region")
+	}
+
+	logger.Info().
+		Strs("regions", regions).
+		Strs("stations", stations).
+		Msg("region filter initialized")
+
+	return &RegionFilter{
+		lookup:   lookup,
+		regions:  toSet(regions),
+		stations: toSet(stations),
+		logger:   logger,
+	}
+}
+
+// ShouldForward checks if any capcode belongs to a configured region or station
+func (f *RegionFilter) ShouldForward(capcodes []string) bool {
+	if f.lookup == nil {
+		return false
+	}
+
+	for _, code := range capcodes {
+		info, ok := f.lookup.Find(code)
+		if !ok {
+			continue
+		}
+		if hasFold(f.regions, info.Region) {
+			f.logger.Debug().
+				Str("matched_capcode", code).
+				Str("region", info.Region).
+				Msg("region match found")
+			return true
+		}
+		if hasFold(f.stations, info.Station) {
+			f.logger.Debug().
+				Str("matched_capcode", code).
+				Str("station", info.Station).
+				Msg("station match found")
+			return true
+		}
+	}
+
+	return false
+}
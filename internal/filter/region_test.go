@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLookup() *capcode.Lookup {
+	return capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Centrum"},
+		{Capcode: "0101002", Agency: "Ambulance", Region: "Utrecht", Station: "Oost"},
+		{Capcode: "0234567", Agency: "Politie", Region: "Amsterdam-Amstelland", Station: "Centrum"},
+		{Capcode: "0345678", Agency: "Brandweer", Region: "Gelderland-Zuid", Station: "Nijmegen"},
+	})
+}
+
+func TestRegionFilter_ShouldForward(t *testing.T) {
+	logger := getTestLogger()
+
+	tests := []struct {
+		name     string
+		regions  []string
+		stations []string
+		capcodes []string
+		want     bool
+	}{
+		{"Region match", []string{"Utrecht"}, nil, []string{"0101001"}, true},
+		{"Region match case insensitive", []string{"utrecht"}, nil, []string{"0101002"}, true},
+		{"Region match without leading zero", []string{"Utrecht"}, nil, []string{"101001"}, true},
+		{"Region no match", []string{"Utrecht"}, nil, []string{"0234567"}, false},
+		{"Station match", nil, []string{"Nijmegen"}, []string{"0345678"}, true},
+		{"Any capcode matches", []string{"Utrecht"}, nil, []string{"0234567", "0101001"}, true},
+		{"Unknown capcode", []string{"Utrecht"}, nil, []string{"9999999"}, false},
+		{"No capcodes", []string{"Utrecht"}, nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewRegionFilter(testLookup(), tt.regions, tt.stations, logger)
+			assert.Equal(t, tt.want, f.ShouldForward(tt.capcodes))
+		})
+	}
+}
+
+func TestRegionFilter_NoLookup(t *testing.T) {
+	f := NewRegionFilter(nil, []string{"Utrecht"}, nil, getTestLogger())
+	assert.False(t, f.ShouldForward([]string{"0101001"}))
+}
+
+func TestAny(t *testing.T) {
+	logger := getTestLogger()
+	capcodes := NewCapcodeFilter(false, []string{"0345678"}, false, logger)
+	regions := NewRegionFilter(testLookup(), []string{"Utrecht"}, nil, logger)
+
+	f := Any(capcodes, regions)
+	assert.True(t, f.ShouldForward([]string{"0345678"}))
+	assert.True(t, f.ShouldForward([]string{"0101001"}))
+	assert.False(t, f.ShouldForward([]string{"0234567"}))
+
+	assert.Same(t, capcodes, Any(capcodes))
+}
+
+func TestRegionFilter_DoesNotAllocate(t *testing.T) {
+	f := NewRegionFilter(testLookup(), []string{"Gelderland-Zuid"}, []string{"nijmegen"}, getTestLogger())
+	capcodes := []string{"0101001", "0234567", "0345678"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		f.ShouldForward(capcodes)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkRegionFilter_ShouldForward(b *testing.B) {
+	f := NewRegionFilter(testLookup(), []string{"Gelderland-Zuid"}, []string{"Nijmegen"}, getTestLogger())
+	capcodes := []string{"0101001", "0234567", "0345678"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.ShouldForward(capcodes)
+	}
+}
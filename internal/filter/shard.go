@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// ShardFilter splits the feed between instances that all receive it. The
+// 32-bit hash space of the capcodes is divided in count equal ranges; a
+// message belongs to the shard of the range holding the hash of its lowest
+// capcode, so every message has exactly one owner and all pages to a
+// capcode go to the same instance.
+type ShardFilter struct {
+	index  int
+	count  int
+	logger zerolog.Logger
+}
+
+// ValidateShard checks that index is one of count shards
+func ValidateShard(index, count int) error {
+	if count < 1 {
+		return fmt.Errorf("shard count must be at least 1")
+	}
+	if index < 0 || index >= count {
+		return fmt.Errorf("shard index %d must be between 0 and %d", index, count-1)
+	}
+	return nil
+}
+
+// NewShardFilter creates the filter of shard index out of count
+func NewShardFilter(index, count int, logger zerolog.Logger) (*ShardFilter, error) {
+	if err := ValidateShard(index, count); err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Int("index", index).
+		Int("count", count).
+		Msg("shard filter initialized")
+
+	return &ShardFilter{index: index, count: count, logger: logger}, nil
+}
+
+// Owns reports whether msg belongs to this shard. Messages without
+// capcodes belong to shard 0.
+func (f *ShardFilter) Owns(msg model.Message) bool {
+	shard := Shard(msg.Capcodes, f.count)
+	if shard != f.index {
+		f.logger.Debug().
+			Strs("capcodes", msg.Capcodes).
+			Int("shard", shard).
+			Msg("message belongs to another shard")
+		return false
+	}
+	return true
+}
+
+// Shard returns the shard out of count of a message to capcodes
+func Shard(capcodes []string, count int) int {
+	lowest := ""
+	for _, code := range capcodes {
+		code = strings.TrimLeft(strings.TrimSpace(code), "0")
+		if lowest == "" || len(code) < len(lowest) || (len(code) == len(lowest) && code < lowest) {
+			lowest = code
+		}
+	}
+	if lowest == "" {
+		return 0
+	}
+
+	// The ranges need the high bits of the hash to differ for consecutive
+	// capcodes, such as those of one station
+	sum := sha256.Sum256([]byte(lowest))
+	return int(uint64(binary.BigEndian.Uint32(sum[:4])) * uint64(count) >> 32)
+}
@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShard(t *testing.T) {
+	assert.Equal(t, 0, Shard(nil, 3), "messages without capcodes go to shard 0")
+	assert.Equal(t, 0, Shard([]string{"001180000"}, 1))
+
+	// Leading zeros and the order of the capcodes do not matter
+	a := Shard([]string{"001180000", "000120901"}, 4)
+	assert.Equal(t, a, Shard([]string{"0120901", "1180000"}, 4))
+	assert.Equal(t, a, Shard([]string{"120901"}, 4), "the lowest capcode decides")
+
+	// Consecutive capcodes are spread over all shards
+	counts := make([]int, 4)
+	for i := 0; i < 1000; i++ {
+		counts[Shard([]string{fmt.Sprintf("%07d", 1420000+i)}, 4)]++
+	}
+	for shard, n := range counts {
+		assert.Greater(t, n, 150, "shard %d", shard)
+	}
+}
+
+func TestShardFilter_Owns(t *testing.T) {
+	msg := model.Message{Capcodes: []string{"001180000"}}
+	owners := 0
+	for index := 0; index < 3; index++ {
+		f, err := NewShardFilter(index, 3, getTestLogger())
+		require.NoError(t, err)
+		if f.Owns(msg) {
+			owners++
+		}
+	}
+	assert.Equal(t, 1, owners, "every message has exactly one owner")
+}
+
+func TestValidateShard(t *testing.T) {
+	assert.NoError(t, ValidateShard(0, 1))
+	assert.NoError(t, ValidateShard(2, 3))
+	assert.EqualError(t, ValidateShard(0, 0), "shard count must be at least 1")
+	assert.EqualError(t, ValidateShard(3, 3), "shard index 3 must be between 0 and 2")
+	assert.EqualError(t, ValidateShard(-1, 3), "shard index -1 must be between 0 and 2")
+}
@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultTestKeywords mark a page as a test at any time
+var DefaultTestKeywords = []string{
+	"proefalarm",
+	"proefoproep",
+	"testalarm",
+	"testoproep",
+	"testbericht",
+	"test oproep",
+	"test alarm",
+}
+
+// sirenTestPattern marks a page as a test during the monthly siren test
+var sirenTestPattern = regexp.MustCompile(`(?i)\b(test|sirenes?|sirenetest)\b`)
+
+// Window around the siren test, held at noon on the first Monday of the month
+const (
+	sirenTestStart = 11*time.Hour + 55*time.Minute
+	sirenTestEnd   = 12*time.Hour + 15*time.Minute
+)
+
+// TestAlarmDetector recognizes test pages, such as periodic "proefalarm"
+// pages and pages sent around the monthly siren test
+type TestAlarmDetector struct {
+	keywords []string
+	schedule bool
+	location *time.Location
+	logger   zerolog.Logger
+}
+
+// NewTestAlarmDetector creates a detector matching keywords anywhere in the
+// text, case-insensitively. DefaultTestKeywords are used when keywords is
+// empty. With schedule set, pages mentioning a test or the sirens are
+// detected during the siren test as well.
+func NewTestAlarmDetector(keywords []string, schedule bool, logger zerolog.Logger) *TestAlarmDetector {
+	if len(keywords) == 0 {
+		keywords = DefaultTestKeywords
+	}
+
+	// The siren test follows Dutch time
+	location, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		logger.Warn().Err(err).Msg("timezone data unavailable, using local time for the siren test schedule")
+		location = time.Local
+	}
+
+	d := &TestAlarmDetector{
+		schedule: schedule,
+		location: location,
+		logger:   logger,
+	}
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			d.keywords = append(d.keywords, k)
+		}
+	}
+	return d
+}
+
+// IsTest reports whether text sent at t is a test page
+func (d *TestAlarmDetector) IsTest(text string, t time.Time) bool {
+	lower := strings.ToLower(text)
+	for _, k := range d.keywords {
+		if strings.Contains(lower, k) {
+			d.logger.Debug().Str("keyword", k).Msg("test alarm detected")
+			return true
+		}
+	}
+
+	if d.schedule && d.sirenTest(t) && sirenTestPattern.MatchString(text) {
+		d.logger.Debug().Msg("test alarm detected during siren test")
+		return true
+	}
+	return false
+}
+
+// sirenTest reports whether t falls within the monthly siren test
+func (d *TestAlarmDetector) sirenTest(t time.Time) bool {
+	t = t.In(d.location)
+	if t.Weekday() != time.Monday || t.Day() > 7 {
+		return false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, d.location)
+	offset := t.Sub(midnight)
+	return offset >= sirenTestStart && offset < sirenTestEnd
+}
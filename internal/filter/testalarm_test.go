@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestAlarmDetector_Keywords(t *testing.T) {
+	d := NewTestAlarmDetector(nil, false, getTestLogger())
+	at := time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)
+
+	assert.True(t, d.IsTest("B2 PROEFALARM Kazerne Utrecht", at))
+	assert.True(t, d.IsTest("Testoproep brandweer", at))
+	assert.True(t, d.IsTest("Dit is een test alarm", at))
+	assert.False(t, d.IsTest("A1 Brand woning Teststraat Utrecht", at))
+	assert.False(t, d.IsTest("Test sirenes", at), "outside the siren test window")
+
+	d = NewTestAlarmDetector([]string{"oefening", " "}, false, getTestLogger())
+	assert.True(t, d.IsTest("P 2 Oefening ambulance", at))
+	assert.False(t, d.IsTest("Proefalarm", at), "custom keywords replace the defaults")
+}
+
+func TestTestAlarmDetector_Schedule(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	d := NewTestAlarmDetector(nil, true, getTestLogger())
+
+	// The first Monday of March 2024 is the 4th
+	tests := []struct {
+		name     string
+		text     string
+		at       time.Time
+		expected bool
+	}{
+		{"siren test", "Test sirenes", time.Date(2024, 3, 4, 12, 0, 0, 0, amsterdam), true},
+		{"start of window", "TEST", time.Date(2024, 3, 4, 11, 55, 0, 0, amsterdam), true},
+		{"utc time", "Test", time.Date(2024, 3, 4, 11, 5, 0, 0, time.UTC), true},
+		{"end of window", "Test", time.Date(2024, 3, 4, 12, 15, 0, 0, amsterdam), false},
+		{"second monday", "Test", time.Date(2024, 3, 11, 12, 0, 0, 0, amsterdam), false},
+		{"first tuesday", "Test", time.Date(2024, 3, 5, 12, 0, 0, 0, amsterdam), false},
+		{"real incident", "A1 Brand woning", time.Date(2024, 3, 4, 12, 0, 0, 0, amsterdam), false},
+		{"word match only", "A1 Contest hall", time.Date(2024, 3, 4, 12, 0, 0, 0, amsterdam), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, d.IsTest(tt.text, tt.at))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package geocode
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+var (
+	// postcodePattern matches a Dutch postcode as written in P2000 messages,
+	// e.g. "1012LG"
+	postcodePattern = regexp.MustCompile(`\b[1-9]\d{3}[A-Z]{2}\b`)
+
+	// streetPattern matches a street name by its common Dutch suffix with an
+	// optional house number, e.g. "Kerkstraat 12a" or "Hoofdweg"
+	streetPattern = regexp.MustCompile(`\b([A-Z][\p{L}'-]*(?:straat|weg|laan|plein|dijk|kade|gracht|singel|pad|dreef|hof|steeg|baan|markt|ring|park|wal))\b(?: (\d+[a-zA-Z]?)\b)?`)
+
+	// cityPattern matches a place name at the start of the remaining text.
+	// Names in capitals are skipped, these are region or station codes.
+	cityPattern = regexp.MustCompile(`^\s*('s-[A-Z][\p{L}-]+|[A-Z]\p{Ll}[\p{L}'-]*)`)
+)
+
+// Query returns the address to geocode for a message: the location provided
+// by the source, or the address parsed from the message text
+func Query(msg model.Message) string {
+	if msg.Location != "" {
+		return msg.Location
+	}
+	return ParseAddress(msg.Message)
+}
+
+// ParseAddress extracts the street, postcode and city from the text of a
+// P2000 message, e.g. "Kerkstraat 12, 3481AB Harmelen". It returns an empty
+// string when the text contains neither a postcode nor a street followed by
+// a city.
+func ParseAddress(text string) string {
+	// The street precedes the postcode when there is one
+	var place []string
+	head, rest := text, ""
+	if m := postcodePattern.FindStringIndex(text); m != nil {
+		place = append(place, text[m[0]:m[1]])
+		head, rest = text[:m[0]], text[m[1]:]
+	}
+
+	var street string
+	if m := streetPattern.FindStringSubmatchIndex(head); m != nil {
+		street = head[m[2]:m[3]]
+		if m[4] >= 0 {
+			street += " " + head[m[4]:m[5]]
+		}
+		if len(place) == 0 {
+			rest = head[m[1]:]
+		}
+	}
+
+	if m := cityPattern.FindStringSubmatch(rest); m != nil {
+		place = append(place, m[1])
+	}
+	if len(place) == 0 {
+		return ""
+	}
+
+	if street == "" {
+		return strings.Join(place, " ")
+	}
+	return street + ", " + strings.Join(place, " ")
+}
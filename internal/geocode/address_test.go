@@ -0,0 +1,32 @@
+package geocode
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := map[string]string{
+		"A1 Kerkstraat 12 3481AB Harmelen UTRECHT 12345":            "Kerkstraat 12, 3481AB Harmelen",
+		"P 1 BR woning (schoorsteen) Dorpsstraat Zoetermeer 151234": "Dorpsstraat, Zoetermeer",
+		"A2 Nieuwendijk 1012MR Amsterdam Rit 12345":                 "Nieuwendijk, 1012MR Amsterdam",
+		"P 2 Ass. Ambulance Hoofdweg 's-Hertogenbosch 123456":       "Hoofdweg, 's-Hertogenbosch",
+		"B2 3811AB Amersfoort Rit 54321":                            "3811AB Amersfoort",
+		"A1 Rijksweg A2 HMP 34,5":                                   "",
+		"Proefalarm":                                                "",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, ParseAddress(text), text)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	assert.Equal(t, "Stationsplein 1, Utrecht", Query(model.Message{
+		Message:  "A1 Damrak 1012LG Amsterdam",
+		Location: "Stationsplein 1, Utrecht",
+	}))
+	assert.Equal(t, "1012LG Amsterdam", Query(model.Message{Message: "A1 Damrak 1012LG Amsterdam"}))
+}
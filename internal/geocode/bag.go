@@ -0,0 +1,194 @@
+package geocode
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+var (
+	// numberBeforePattern matches a house number directly preceding a
+	// postcode, e.g. the "1" of "Damrak 1, 1012LG"
+	numberBeforePattern = regexp.MustCompile(`\b(\d+[a-zA-Z]?),?\s+$`)
+
+	// numberAfterPattern matches a house number directly following a
+	// postcode, e.g. the "12" of "3481AB 12 Harmelen"
+	numberAfterPattern = regexp.MustCompile(`^\s+(\d+[a-zA-Z]?)\b`)
+)
+
+// BAGAddress is an address of the Basisregistratie Adressen en Gebouwen
+type BAGAddress struct {
+	Postcode     string
+	Number       string // House number including its letter, empty for a whole postcode
+	Street       string
+	City         string
+	Municipality string
+	Coordinates  model.Coordinates
+}
+
+// String formats the address like the text of P2000 messages, e.g.
+// "Kerkstraat 12, 3481AB Harmelen"
+func (a BAGAddress) String() string {
+	street := a.Street
+	if a.Number != "" {
+		street += " " + a.Number
+	}
+	return street + ", " + a.Postcode + " " + a.City
+}
+
+// BAG resolves postcode and house number fragments of P2000 messages
+// against a local extract of the BAG, so messages get a verified address
+// and municipality without depending on free text parsing or a geocoding
+// service. It is safe for concurrent use once loaded.
+type BAG struct {
+	postcodes map[string][]BAGAddress
+	count     int
+}
+
+// LoadBAG reads a BAG extract from a file, see ReadBAG
+func LoadBAG(path string) (*BAG, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BAG extract: %w", err)
+	}
+	defer f.Close()
+	return ReadBAG(f)
+}
+
+// ReadBAG reads a semicolon separated BAG extract:
+// postcode;huisnummer;straat;woonplaats;gemeente;lat;lon
+// The house number may include its letter, e.g. "12a". A header row and
+// incomplete rows are skipped.
+func ReadBAG(r io.Reader) (*BAG, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.ReuseRecord = true   // Extracts of whole provinces hold millions of rows
+
+	b := &BAG{postcodes: make(map[string][]BAGAddress)}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read BAG extract: %w", err)
+		}
+		if len(record) < 7 || (line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "postcode")) {
+			continue
+		}
+
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[5]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("BAG extract line %d: invalid latitude %q", line, record[5])
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(record[6]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("BAG extract line %d: invalid longitude %q", line, record[6])
+		}
+
+		postcode := normalizePostcode(record[0])
+		b.postcodes[postcode] = append(b.postcodes[postcode], BAGAddress{
+			Postcode:     postcode,
+			Number:       strings.ToLower(strings.TrimSpace(record[1])),
+			Street:       strings.TrimSpace(record[2]),
+			City:         strings.TrimSpace(record[3]),
+			Municipality: strings.TrimSpace(record[4]),
+			Coordinates:  model.Coordinates{Lat: lat, Lon: lon},
+		})
+		b.count++
+	}
+	return b, nil
+}
+
+// Len returns the number of addresses
+func (b *BAG) Len() int {
+	return b.count
+}
+
+// Resolve looks up the postcode of a message text and the house number
+// before or after it, e.g. "Kerkstraat 12, 3481AB Harmelen" or "3481AB 12",
+// or after the street before it. Without a known house number the postcode
+// is resolved to its street and the centre of its addresses. It reports
+// false when the text has no postcode or the postcode is not in the
+// extract.
+func (b *BAG) Resolve(text string) (BAGAddress, bool) {
+	m := postcodePattern.FindStringIndex(text)
+	if m == nil {
+		return BAGAddress{}, false
+	}
+	addresses := b.postcodes[text[m[0]:m[1]]]
+	if len(addresses) == 0 {
+		return BAGAddress{}, false
+	}
+
+	// Other numbers, such as a vehicle code, may precede the postcode as
+	// well, so every candidate is tried
+	var numbers []string
+	if n := numberBeforePattern.FindStringSubmatch(text[:m[0]]); n != nil {
+		numbers = append(numbers, n[1])
+	}
+	if n := numberAfterPattern.FindStringSubmatch(text[m[1]:]); n != nil {
+		numbers = append(numbers, n[1])
+	}
+	if s := streetPattern.FindStringSubmatch(text[:m[0]]); s != nil && s[2] != "" {
+		numbers = append(numbers, s[2])
+	}
+	for _, number := range numbers {
+		number = strings.ToLower(number)
+		// Fall back to the number without its letter, which the BAG may not
+		// list separately
+		for _, candidate := range []string{number, strings.TrimRight(number, "abcdefghijklmnopqrstuvwxyz")} {
+			for _, a := range addresses {
+				if a.Number == candidate {
+					return a, true
+				}
+			}
+		}
+	}
+
+	// The whole postcode, usually one side of one street
+	a := addresses[0]
+	a.Number = ""
+	a.Coordinates = model.Coordinates{}
+	for _, address := range addresses {
+		a.Coordinates.Lat += address.Coordinates.Lat
+		a.Coordinates.Lon += address.Coordinates.Lon
+	}
+	a.Coordinates.Lat /= float64(len(addresses))
+	a.Coordinates.Lon /= float64(len(addresses))
+	return a, true
+}
+
+// Enrich sets the verified address, municipality and coordinates of a
+// message resolved from the location provided by the source or the text.
+// Messages with coordinates are left unchanged. It reports whether the
+// message was resolved.
+func (b *BAG) Enrich(msg *model.Message) bool {
+	if msg.Coordinates != nil {
+		return false
+	}
+	a, ok := b.Resolve(msg.Location)
+	if !ok {
+		if a, ok = b.Resolve(msg.Message); !ok {
+			return false
+		}
+	}
+	msg.Location = a.String()
+	msg.Municipality = a.Municipality
+	msg.Coordinates = &a.Coordinates
+	return true
+}
+
+// normalizePostcode writes a postcode like P2000 messages, e.g. "1012 lg"
+// as "1012LG"
+func normalizePostcode(postcode string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(postcode), " ", ""))
+}
@@ -0,0 +1,86 @@
+package geocode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBAG = `postcode;huisnummer;straat;woonplaats;gemeente;lat;lon
+3481AB;10;Kerkstraat;Harmelen;Woerden;52.0900;4.9600
+3481AB;12;Kerkstraat;Harmelen;Woerden;52.0910;4.9610
+3481AB;12A;Kerkstraat;Harmelen;Woerden;52.0912;4.9612
+1012 lg;1;Damrak;Amsterdam;Amsterdam;52.3756;4.8938
+incomplete;row
+`
+
+func TestReadBAG(t *testing.T) {
+	b, err := ReadBAG(strings.NewReader(testBAG))
+	require.NoError(t, err)
+	assert.Equal(t, 4, b.Len())
+
+	_, err = ReadBAG(strings.NewReader("3481AB;10;Kerkstraat;Harmelen;Woerden;noord;4.96\n"))
+	assert.EqualError(t, err, `BAG extract line 1: invalid latitude "noord"`)
+}
+
+func TestBAG_Resolve(t *testing.T) {
+	b, err := ReadBAG(strings.NewReader(testBAG))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		text         string
+		address      string
+		municipality string
+		coordinates  model.Coordinates
+	}{
+		{"Street number before postcode", "P 1 Brand woning Kerkstraat 12 3481AB Harmelen", "Kerkstraat 12, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0910, Lon: 4.9610}},
+		{"Number after postcode", "A1 3481AB 10 Harmelen", "Kerkstraat 10, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0900, Lon: 4.9600}},
+		{"House letter", "A2 Kerkstraat 12a 3481AB Harmelen", "Kerkstraat 12a, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0912, Lon: 4.9612}},
+		{"Unknown letter falls back to the number", "A2 Kerkstraat 10b 3481AB Harmelen", "Kerkstraat 10, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0900, Lon: 4.9600}},
+		{"Vehicle code before postcode", "A1 Ambu 17101 Kerkstraat 12 17101 3481AB Harmelen", "Kerkstraat 12, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0910, Lon: 4.9610}},
+		{"Postcode only", "P 2 Dienstverlening 3481AB Harmelen", "Kerkstraat, 3481AB Harmelen", "Woerden", model.Coordinates{Lat: 52.0907333, Lon: 4.9607333}},
+		{"Postcode with spaces in the extract", "A1 1012LG Amsterdam", "Damrak, 1012LG Amsterdam", "Amsterdam", model.Coordinates{Lat: 52.3756, Lon: 4.8938}},
+		{"Unknown postcode", "A1 9999ZZ Nergens", "", "", model.Coordinates{}},
+		{"No postcode", "P 1 Brand Kerkstraat Harmelen", "", "", model.Coordinates{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, ok := b.Resolve(tt.text)
+			assert.Equal(t, tt.address != "", ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, tt.address, a.String())
+			assert.Equal(t, tt.municipality, a.Municipality)
+			assert.InDelta(t, tt.coordinates.Lat, a.Coordinates.Lat, 1e-6)
+			assert.InDelta(t, tt.coordinates.Lon, a.Coordinates.Lon, 1e-6)
+		})
+	}
+}
+
+func TestBAG_Enrich(t *testing.T) {
+	b, err := ReadBAG(strings.NewReader(testBAG))
+	require.NoError(t, err)
+
+	msg := model.Message{Message: "P 1 Brand woning Kerkstraat 12 3481AB Harmelen"}
+	require.True(t, b.Enrich(&msg))
+	assert.Equal(t, "Kerkstraat 12, 3481AB Harmelen", msg.Location)
+	assert.Equal(t, "Woerden", msg.Municipality)
+	assert.Equal(t, &model.Coordinates{Lat: 52.0910, Lon: 4.9610}, msg.Coordinates)
+
+	// The location provided by the source is preferred over the text
+	msg = model.Message{Message: "A1 Ambu 17101", Location: "Damrak 1, 1012LG Amsterdam"}
+	require.True(t, b.Enrich(&msg))
+	assert.Equal(t, "Damrak 1, 1012LG Amsterdam", msg.Location)
+
+	// Coordinates from the source are kept
+	coordinates := &model.Coordinates{Lat: 52, Lon: 5}
+	msg = model.Message{Message: "A1 3481AB 10 Harmelen", Coordinates: coordinates}
+	assert.False(t, b.Enrich(&msg))
+	assert.Same(t, coordinates, msg.Coordinates)
+}
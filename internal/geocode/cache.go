@@ -0,0 +1,95 @@
+package geocode
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// result is a cached lookup. Addresses that could not be found are cached
+// as well, so unknown streets are not looked up for every page.
+type result struct {
+	found       bool
+	coordinates model.Coordinates
+	address     string
+}
+
+// entry is a cache element
+type entry struct {
+	key     string
+	result  result
+	expires time.Time
+}
+
+// cache keeps the most recently used lookups for a limited time
+type cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // Most recently used first
+	entries map[string]*list.Element
+}
+
+// newCache creates a cache of at most size entries. Caching is disabled
+// when size or ttl is zero.
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the unexpired result for key
+func (c *cache) get(key string, now time.Time) (result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return result{}, false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return result{}, false
+	}
+	c.order.MoveToFront(el)
+	return e.result, true
+}
+
+// put stores a result and evicts the least recently used entry when the
+// cache is full
+func (c *cache) put(key string, r result, now time.Time) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &entry{key: key, result: r, expires: now.Add(c.ttl)}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, result: r, expires: now.Add(c.ttl)})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// len returns the number of cached entries
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,241 @@
+// Package geocode resolves the incident address of P2000 messages to
+// coordinates and, for sources that only provide coordinates, coordinates
+// back to an address. Lookups are cached and rate limited to respect the
+// usage policies of the public geocoding services.
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// ErrNotFound is returned when the service has no result for a lookup
+var ErrNotFound = errors.New("address not found")
+
+const (
+	requestTimeout  = 10 * time.Second
+	maxResponseSize = 1 << 20
+)
+
+// Options configures a Geocoder. Only Provider is required.
+type Options struct {
+	Provider  string // ProviderPDOK or ProviderNominatim
+	URL       string // Overrides the public endpoint of the provider
+	UserAgent string // Identifies the application to the service
+
+	RateLimit float64       // Requests per second, unlimited when 0
+	CacheSize int           // Lookups kept in the cache, disabled when 0
+	CacheTTL  time.Duration // Time a lookup is cached
+
+	Transport http.RoundTripper // Defaults to http.DefaultTransport
+	Metrics   *metrics.Metrics  // Optional
+	Logger    zerolog.Logger
+}
+
+// Geocoder looks up addresses and coordinates with a geocoding service. It
+// is safe for concurrent use.
+type Geocoder struct {
+	provider   provider
+	httpClient *http.Client
+	userAgent  string
+	interval   time.Duration
+	cache      *cache
+	metrics    *metrics.Metrics
+	logger     zerolog.Logger
+	now        func() time.Time
+
+	limitMu sync.Mutex
+	next    time.Time // Earliest time of the next request
+}
+
+// New creates a geocoder for the configured provider
+func New(opts Options) (*Geocoder, error) {
+	p, err := newProvider(opts.Provider, opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RateLimit < 0 {
+		return nil, fmt.Errorf("geocoding rate limit must not be negative")
+	}
+
+	var interval time.Duration
+	if opts.RateLimit > 0 {
+		interval = time.Duration(float64(time.Second) / opts.RateLimit)
+	}
+
+	return &Geocoder{
+		provider: p,
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: opts.Transport,
+		},
+		userAgent: opts.UserAgent,
+		interval:  interval,
+		cache:     newCache(opts.CacheSize, opts.CacheTTL),
+		metrics:   opts.Metrics,
+		logger:    opts.Logger,
+		now:       time.Now,
+	}, nil
+}
+
+// Geocode returns the coordinates of an address. It returns ErrNotFound when
+// the service does not know the address.
+func (g *Geocoder) Geocode(ctx context.Context, address string) (model.Coordinates, error) {
+	key := "search:" + strings.ToLower(strings.TrimSpace(address))
+	r, err := g.lookup(ctx, key, g.provider.searchURL(address), g.provider.parseSearch)
+	if err != nil {
+		return model.Coordinates{}, err
+	}
+	return r.coordinates, nil
+}
+
+// Reverse returns the address nearest to the coordinates. It returns
+// ErrNotFound when the service has no address for the position.
+func (g *Geocoder) Reverse(ctx context.Context, c model.Coordinates) (string, error) {
+	key := "reverse:" + formatCoordinate(c.Lat) + "," + formatCoordinate(c.Lon)
+	r, err := g.lookup(ctx, key, g.provider.reverseURL(c), g.provider.parseReverse)
+	if err != nil {
+		return "", err
+	}
+	return r.address, nil
+}
+
+// Locate fills in the coordinates of a message from its address, or its
+// location from the coordinates provided by the source. Messages without a
+// recognisable address are left unchanged.
+func (g *Geocoder) Locate(ctx context.Context, msg *model.Message) error {
+	if msg.Coordinates != nil {
+		if msg.Location != "" {
+			return nil
+		}
+		address, err := g.Reverse(ctx, *msg.Coordinates)
+		if err != nil {
+			return err
+		}
+		msg.Location = address
+		return nil
+	}
+
+	query := Query(*msg)
+	if query == "" {
+		return nil
+	}
+	c, err := g.Geocode(ctx, query)
+	if err != nil {
+		return err
+	}
+	msg.Coordinates = &c
+	return nil
+}
+
+// lookup returns the cached result for key, or requests url and caches the
+// parsed result
+func (g *Geocoder) lookup(ctx context.Context, key, url string, parse func([]byte) (result, error)) (result, error) {
+	if r, ok := g.cache.get(key, g.now()); ok {
+		g.record("cached")
+		if !r.found {
+			return result{}, ErrNotFound
+		}
+		return r, nil
+	}
+
+	body, err := g.get(ctx, url)
+	if err != nil {
+		g.record("error")
+		return result{}, err
+	}
+	r, err := parse(body)
+	if err != nil {
+		g.record("error")
+		return result{}, err
+	}
+
+	g.cache.put(key, r, g.now())
+	if !r.found {
+		g.record("not_found")
+		return result{}, ErrNotFound
+	}
+	g.record("found")
+	return r, nil
+}
+
+// get waits for the rate limit and returns the body of a successful GET
+// request
+func (g *Geocoder) get(ctx context.Context, url string) ([]byte, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if g.userAgent != "" {
+		req.Header.Set("User-Agent", g.userAgent)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// wait blocks until the next request is allowed by the rate limit or ctx
+// is cancelled. Requests are spaced evenly, without bursts.
+func (g *Geocoder) wait(ctx context.Context) error {
+	if g.interval <= 0 {
+		return nil
+	}
+
+	g.limitMu.Lock()
+	now := g.now()
+	at := g.next
+	if at.Before(now) {
+		at = now
+	}
+	g.next = at.Add(g.interval)
+	g.limitMu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record counts a lookup result when metrics are enabled
+func (g *Geocoder) record(result string) {
+	if g.metrics != nil {
+		g.metrics.RecordGeocodeLookup(result)
+	}
+}
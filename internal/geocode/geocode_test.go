@@ -0,0 +1,211 @@
+package geocode
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// newPDOKServer serves a Locatieserver result for Damrak and counts requests
+func newPDOKServer(t *testing.T, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch {
+		case r.URL.Path == "/free" && strings.Contains(r.URL.Query().Get("q"), "1012LG Amsterdam"):
+			w.Write([]byte(`{"response":{"numFound":1,"docs":[{"weergavenaam":"Damrak 1, 1012LG Amsterdam","centroide_ll":"POINT(4.89378 52.37561)"}]}}`))
+		case r.URL.Path == "/reverse":
+			w.Write([]byte(`{"response":{"numFound":1,"docs":[{"weergavenaam":"Damrak 1, 1012LG Amsterdam"}]}}`))
+		default:
+			w.Write([]byte(`{"response":{"numFound":0,"docs":[]}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGeocoder_PDOK(t *testing.T) {
+	var requests int32
+	server := newPDOKServer(t, &requests)
+
+	g, err := New(Options{Provider: ProviderPDOK, URL: server.URL, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	c, err := g.Geocode(context.Background(), "Damrak 1, 1012LG Amsterdam")
+	require.NoError(t, err)
+	assert.Equal(t, model.Coordinates{Lat: 52.37561, Lon: 4.89378}, c)
+
+	_, err = g.Geocode(context.Background(), "Onbekendestraat, Nergenshuizen")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	address, err := g.Reverse(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, "Damrak 1, 1012LG Amsterdam", address)
+}
+
+func TestGeocoder_Nominatim(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		switch r.URL.Path {
+		case "/search":
+			assert.Equal(t, "nl", r.URL.Query().Get("countrycodes"))
+			w.Write([]byte(`[{"lat":"52.0907","lon":"5.1214","display_name":"Domplein, Utrecht"}]`))
+		case "/reverse":
+			w.Write([]byte(`{"error":"Unable to geocode"}`))
+		}
+	}))
+	defer server.Close()
+
+	g, err := New(Options{
+		Provider:  ProviderNominatim,
+		URL:       server.URL,
+		UserAgent: "p2000-nfty/test",
+		Logger:    getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	c, err := g.Geocode(context.Background(), "Domplein, Utrecht")
+	require.NoError(t, err)
+	assert.Equal(t, model.Coordinates{Lat: 52.0907, Lon: 5.1214}, c)
+	assert.Equal(t, "p2000-nfty/test", userAgent)
+
+	_, err = g.Reverse(context.Background(), model.Coordinates{Lat: 53.5, Lon: 3.1})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGeocoder_Cache(t *testing.T) {
+	var requests int32
+	server := newPDOKServer(t, &requests)
+	m := metrics.NewMetrics()
+
+	g, err := New(Options{
+		Provider:  ProviderPDOK,
+		URL:       server.URL,
+		CacheSize: 10,
+		CacheTTL:  time.Hour,
+		Metrics:   m,
+		Logger:    getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := g.Geocode(context.Background(), "Damrak 1, 1012LG Amsterdam")
+		require.NoError(t, err)
+		_, err = g.Geocode(context.Background(), "Onbekendestraat, Nergenshuizen")
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+
+	// Unknown addresses are cached as well
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, 4.0, testutil.ToFloat64(m.GeocodeLookups.WithLabelValues("cached")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.GeocodeLookups.WithLabelValues("found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.GeocodeLookups.WithLabelValues("not_found")))
+}
+
+func TestGeocoder_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	g, err := New(Options{Provider: ProviderPDOK, URL: server.URL, CacheSize: 10, CacheTTL: time.Hour, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	_, err = g.Geocode(context.Background(), "Damrak 1, 1012LG Amsterdam")
+	assert.ErrorContains(t, err, "unexpected status code: 429")
+	assert.Equal(t, 0, g.cache.len(), "failed lookups are not cached")
+}
+
+func TestGeocoder_RateLimit(t *testing.T) {
+	var requests int32
+	server := newPDOKServer(t, &requests)
+
+	g, err := New(Options{Provider: ProviderPDOK, URL: server.URL, RateLimit: 20, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := g.Geocode(context.Background(), "Damrak 1, 1012LG Amsterdam")
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// A cancelled lookup does not wait for its turn
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = g.Geocode(ctx, "Damrak 1, 1012LG Amsterdam")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGeocoder_Locate(t *testing.T) {
+	var requests int32
+	server := newPDOKServer(t, &requests)
+
+	g, err := New(Options{Provider: ProviderPDOK, URL: server.URL, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	msg := model.Message{Message: "A1 Damrak 1 1012LG Amsterdam"}
+	require.NoError(t, g.Locate(context.Background(), &msg))
+	require.NotNil(t, msg.Coordinates)
+	assert.Equal(t, 52.37561, msg.Coordinates.Lat)
+
+	// Coordinates from the source are reverse geocoded
+	msg = model.Message{Coordinates: &model.Coordinates{Lat: 52.37561, Lon: 4.89378}}
+	require.NoError(t, g.Locate(context.Background(), &msg))
+	assert.Equal(t, "Damrak 1, 1012LG Amsterdam", msg.Location)
+
+	// Messages without an address are not looked up
+	before := atomic.LoadInt32(&requests)
+	msg = model.Message{Message: "Proefalarm"}
+	require.NoError(t, g.Locate(context.Background(), &msg))
+	assert.Nil(t, msg.Coordinates)
+	assert.Equal(t, before, atomic.LoadInt32(&requests))
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Options{Provider: "google"})
+	assert.ErrorContains(t, err, `unknown geocoding provider "google"`)
+
+	_, err = New(Options{Provider: ProviderPDOK, RateLimit: -1})
+	assert.Error(t, err)
+}
+
+func TestCache_EvictionAndExpiry(t *testing.T) {
+	now := time.Now()
+	c := newCache(2, time.Minute)
+
+	c.put("a", result{found: true, address: "a"}, now)
+	c.put("b", result{found: true, address: "b"}, now)
+	_, ok := c.get("a", now)
+	require.True(t, ok)
+
+	// b is the least recently used entry
+	c.put("c", result{found: true, address: "c"}, now)
+	_, ok = c.get("b", now)
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.len())
+
+	_, ok = c.get("a", now.Add(time.Minute))
+	assert.False(t, ok, "expired")
+
+	disabled := newCache(0, time.Minute)
+	disabled.put("a", result{}, now)
+	assert.Equal(t, 0, disabled.len())
+}
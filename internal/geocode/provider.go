@@ -0,0 +1,173 @@
+package geocode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// Supported geocoding services
+const (
+	ProviderPDOK      = "pdok"      // PDOK Locatieserver, Dutch addresses only
+	ProviderNominatim = "nominatim" // OpenStreetMap Nominatim
+)
+
+const (
+	pdokURL      = "https://api.pdok.nl/bzk/locatieserver/search/v3_1"
+	nominatimURL = "https://nominatim.openstreetmap.org"
+)
+
+// provider builds the requests and parses the responses of a geocoding
+// service
+type provider interface {
+	searchURL(query string) string
+	parseSearch(body []byte) (result, error)
+	reverseURL(c model.Coordinates) string
+	parseReverse(body []byte) (result, error)
+}
+
+// newProvider returns the provider with the given name, using baseURL
+// instead of its public endpoint when set
+func newProvider(name, baseURL string) (provider, error) {
+	switch strings.ToLower(name) {
+	case ProviderPDOK:
+		if baseURL == "" {
+			baseURL = pdokURL
+		}
+		return &pdok{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case ProviderNominatim:
+		if baseURL == "" {
+			baseURL = nominatimURL
+		}
+		return &nominatim{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unknown geocoding provider %q", name)
+	}
+}
+
+// formatCoordinate formats a latitude or longitude for a request
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
+
+// pdok queries the PDOK Locatieserver
+type pdok struct {
+	baseURL string
+}
+
+// pdokResponse is the Solr response of the Locatieserver
+type pdokResponse struct {
+	Response struct {
+		Docs []struct {
+			Weergavenaam string `json:"weergavenaam"`
+			CentroideLL  string `json:"centroide_ll"` // WKT, e.g. "POINT(4.8936 52.3731)"
+		} `json:"docs"`
+	} `json:"response"`
+}
+
+func (p *pdok) searchURL(query string) string {
+	return p.baseURL + "/free?" + url.Values{"q": {query}, "rows": {"1"}}.Encode()
+}
+
+func (p *pdok) parseSearch(body []byte) (result, error) {
+	var resp pdokResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return result{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(resp.Response.Docs) == 0 {
+		return result{}, nil
+	}
+
+	doc := resp.Response.Docs[0]
+	var c model.Coordinates
+	if _, err := fmt.Sscanf(doc.CentroideLL, "POINT(%g %g)", &c.Lon, &c.Lat); err != nil {
+		return result{}, fmt.Errorf("invalid centroid %q: %w", doc.CentroideLL, err)
+	}
+	return result{found: true, coordinates: c, address: doc.Weergavenaam}, nil
+}
+
+func (p *pdok) reverseURL(c model.Coordinates) string {
+	return p.baseURL + "/reverse?" + url.Values{
+		"lat":  {formatCoordinate(c.Lat)},
+		"lon":  {formatCoordinate(c.Lon)},
+		"rows": {"1"},
+	}.Encode()
+}
+
+func (p *pdok) parseReverse(body []byte) (result, error) {
+	var resp pdokResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return result{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(resp.Response.Docs) == 0 {
+		return result{}, nil
+	}
+	return result{found: true, address: resp.Response.Docs[0].Weergavenaam}, nil
+}
+
+// nominatim queries an OpenStreetMap Nominatim server, limited to the
+// Netherlands
+type nominatim struct {
+	baseURL string
+}
+
+// nominatimPlace is a search or reverse result of Nominatim
+type nominatimPlace struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	Error       string `json:"error"` // Set by reverse lookups without result
+}
+
+func (n *nominatim) searchURL(query string) string {
+	return n.baseURL + "/search?" + url.Values{
+		"q":            {query},
+		"format":       {"jsonv2"},
+		"limit":        {"1"},
+		"countrycodes": {"nl"},
+	}.Encode()
+}
+
+func (n *nominatim) parseSearch(body []byte) (result, error) {
+	var places []nominatimPlace
+	if err := json.Unmarshal(body, &places); err != nil {
+		return result{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(places) == 0 {
+		return result{}, nil
+	}
+
+	place := places[0]
+	lat, err := strconv.ParseFloat(place.Lat, 64)
+	if err != nil {
+		return result{}, fmt.Errorf("invalid latitude %q: %w", place.Lat, err)
+	}
+	lon, err := strconv.ParseFloat(place.Lon, 64)
+	if err != nil {
+		return result{}, fmt.Errorf("invalid longitude %q: %w", place.Lon, err)
+	}
+	return result{found: true, coordinates: model.Coordinates{Lat: lat, Lon: lon}, address: place.DisplayName}, nil
+}
+
+func (n *nominatim) reverseURL(c model.Coordinates) string {
+	return n.baseURL + "/reverse?" + url.Values{
+		"lat":    {formatCoordinate(c.Lat)},
+		"lon":    {formatCoordinate(c.Lon)},
+		"format": {"jsonv2"},
+	}.Encode()
+}
+
+func (n *nominatim) parseReverse(body []byte) (result, error) {
+	var place nominatimPlace
+	if err := json.Unmarshal(body, &place); err != nil {
+		return result{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if place.Error != "" || place.DisplayName == "" {
+		return result{}, nil
+	}
+	return result{found: true, address: place.DisplayName}, nil
+}
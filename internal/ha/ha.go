@@ -0,0 +1,73 @@
+// Package ha lets several forwarder instances receive the same feed while
+// only one of them notifies each message. Every instance claims a message
+// in a shared store before notifying it; the first claim wins. Unlike
+// leader election there is no failover delay: a surviving instance keeps
+// claiming every message when the other one stops.
+package ha
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// claimTimeout limits how long the feed waits for the store
+const claimTimeout = 2 * time.Second
+
+// Store records claims shared by the instances
+type Store interface {
+	// Claim records key for this instance, reporting false when another
+	// instance claimed it first
+	Claim(ctx context.Context, key string) (bool, error)
+}
+
+// Deduplicator decides which instance notifies a message
+type Deduplicator struct {
+	store   Store
+	metrics *metrics.Metrics // Optional
+	logger  zerolog.Logger
+}
+
+// New creates a deduplicator claiming messages in store
+func New(store Store, m *metrics.Metrics, logger zerolog.Logger) *Deduplicator {
+	return &Deduplicator{store: store, metrics: m, logger: logger}
+}
+
+// Key identifies msg across instances: the same page has the same type,
+// timestamp, capcodes and text in every copy of the feed, and so the same
+// message ID
+func Key(msg model.Message) string {
+	return model.MessageID(msg)
+}
+
+// Claim reports whether this instance notifies msg. When the store fails,
+// the message is notified anyway: a duplicate push is better than a missed
+// page.
+func (d *Deduplicator) Claim(msg model.Message) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), claimTimeout)
+	defer cancel()
+
+	claimed, err := d.store.Claim(ctx, Key(msg))
+	switch {
+	case err != nil:
+		d.record("error")
+		d.logger.Warn().Err(err).Str("id", msg.ID).Msg("failed to claim message, notifying anyway")
+		return true
+	case !claimed:
+		d.record("skipped")
+		d.logger.Debug().Str("id", msg.ID).Msg("message claimed by another instance")
+		return false
+	}
+	d.record("claimed")
+	return true
+}
+
+// record counts a claim by its result when metrics are configured
+func (d *Deduplicator) record(result string) {
+	if d.metrics != nil {
+		d.metrics.RecordClaim(result)
+	}
+}
@@ -0,0 +1,69 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is a Store shared by the deduplicators of a test
+type memoryStore struct {
+	mu     sync.Mutex
+	claims map[string]bool
+	err    error
+}
+
+func (s *memoryStore) Claim(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.claims[key] {
+		return false, nil
+	}
+	s.claims[key] = true
+	return true, nil
+}
+
+func TestKey(t *testing.T) {
+	msg := model.Message{Type: "FLEX", Timestamp: 1760522400, Capcodes: []string{"001180000", "000120901"}, Message: "P 1 Brand woning Utrecht"}
+
+	// Copies of the page match, also with the capcodes in another order
+	other := msg
+	other.Capcodes = []string{"000120901", "001180000"}
+	other.ID = "7"
+	assert.Equal(t, Key(msg), Key(other))
+	assert.Len(t, Key(msg), 64)
+
+	// A repeated page at another time is a new message
+	repeat := msg
+	repeat.Timestamp++
+	assert.NotEqual(t, Key(msg), Key(repeat))
+}
+
+func TestClaim(t *testing.T) {
+	store := &memoryStore{claims: make(map[string]bool)}
+	a := New(store, nil, zerolog.Nop())
+	b := New(store, nil, zerolog.Nop())
+
+	msg := model.Message{Type: "FLEX", Timestamp: 1760522400, Message: "A1 Dorpsstraat Utrecht"}
+	assert.True(t, a.Claim(msg))
+	assert.False(t, b.Claim(msg))
+	assert.False(t, a.Claim(msg))
+}
+
+func TestClaim_StoreFailureNotifies(t *testing.T) {
+	store := &memoryStore{claims: make(map[string]bool), err: errors.New("connection refused")}
+	a := New(store, nil, zerolog.Nop())
+	b := New(store, nil, zerolog.Nop())
+
+	msg := model.Message{Type: "FLEX", Timestamp: 1760522400, Message: "A1 Dorpsstraat Utrecht"}
+	assert.True(t, a.Claim(msg))
+	assert.True(t, b.Claim(msg))
+}
@@ -0,0 +1,163 @@
+// Package health builds the JSON documents served by the health, liveness
+// and readiness endpoints.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+)
+
+// Status values of a report
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check is the outcome of a single health check
+type Check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// Delivery describes the most recent notification attempt
+type Delivery struct {
+	Destination string    `json:"destination"`
+	Success     bool      `json:"success"`
+	At          time.Time `json:"at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Backend summarizes the delivery state of a notification destination
+type Backend struct {
+	Name        string     `json:"name"`
+	Healthy     bool       `json:"healthy"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Sent        int        `json:"sent"`
+	Failed      int        `json:"failed"`
+}
+
+// Report is the JSON document returned by the health endpoints
+type Report struct {
+	Status                 string    `json:"status"`
+	Checks                 []Check   `json:"checks"`
+	Uptime                 float64   `json:"uptime_seconds"`
+	WebsocketConnected     bool      `json:"websocket_connected"`
+	Reconnects             int       `json:"reconnects"`
+	LastMessageAge         float64   `json:"last_message_age_seconds"`
+	CapcodeLookupAvailable bool      `json:"capcode_lookup_available"`
+	LastNotification       *Delivery `json:"last_notification,omitempty"`
+	Backends               []Backend `json:"backends"`
+}
+
+// Add appends a check and marks the report unavailable when it failed
+func (r *Report) Add(name string, ok bool, message string) {
+	if r.Status == "" {
+		r.Status = StatusOK
+	}
+	if !ok {
+		r.Status = StatusUnavailable
+	}
+	r.Checks = append(r.Checks, Check{Name: name, OK: ok, Message: message})
+}
+
+// Write sends the report as JSON, with 503 Service Unavailable when a
+// check failed
+func (r Report) Write(w http.ResponseWriter) {
+	if r.Status == "" {
+		r.Status = StatusOK
+	}
+	if r.Checks == nil {
+		r.Checks = []Check{}
+	}
+	if r.Backends == nil {
+		r.Backends = []Backend{}
+	}
+
+	status := http.StatusOK
+	if r.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(r)
+}
+
+// Backends tracks the outcome of notification deliveries per destination
+type Backends struct {
+	mu       sync.Mutex
+	backends map[string]*Backend
+	last     *Delivery
+	now      func() time.Time
+}
+
+// NewBackends creates an empty backend tracker
+func NewBackends() *Backends {
+	return &Backends{
+		backends: make(map[string]*Backend),
+		now:      time.Now,
+	}
+}
+
+// Record stores a delivery outcome; it can be registered directly as a
+// notifier.DeliveryHook
+func (b *Backends) Record(result notifier.DeliveryResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	backend, ok := b.backends[result.Destination]
+	if !ok {
+		backend = &Backend{Name: result.Destination}
+		b.backends[result.Destination] = backend
+	}
+
+	delivery := &Delivery{Destination: result.Destination, Success: result.Success, At: now}
+	backend.Healthy = result.Success
+	if result.Success {
+		backend.Sent++
+		backend.LastSuccess = &now
+	} else {
+		backend.Failed++
+		backend.LastFailure = &now
+		if result.Err != nil {
+			backend.LastError = result.Err.Error()
+			delivery.Error = backend.LastError
+		}
+	}
+	b.last = delivery
+}
+
+// Last returns the most recent delivery, nil before the first one
+func (b *Backends) Last() *Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last == nil {
+		return nil
+	}
+	last := *b.last
+	return &last
+}
+
+// List returns the state of all destinations that were delivered to,
+// sorted by name
+func (b *Backends) List() []Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := make([]Backend, 0, len(b.backends))
+	for _, backend := range b.backends {
+		list = append(list, *backend)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Add(t *testing.T) {
+	var r Report
+	r.Add("websocket", true, "")
+	assert.Equal(t, StatusOK, r.Status)
+
+	r.Add("messages", false, "no messages")
+	r.Add("capcode_lookup", true, "")
+	assert.Equal(t, StatusUnavailable, r.Status)
+	assert.Len(t, r.Checks, 3)
+}
+
+func TestReport_Write(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Report{}.Write(rec)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"status": "ok",
+		"checks": [],
+		"uptime_seconds": 0,
+		"websocket_connected": false,
+		"reconnects": 0,
+		"last_message_age_seconds": 0,
+		"capcode_lookup_available": false,
+		"backends": []
+	}`, rec.Body.String())
+
+	var r Report
+	r.Add("websocket", false, "websocket disconnected")
+	rec = httptest.NewRecorder()
+	r.Write(rec)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var decoded Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	assert.Equal(t, []Check{{Name: "websocket", Message: "websocket disconnected"}}, decoded.Checks)
+}
+
+func TestBackends_Record(t *testing.T) {
+	b := NewBackends()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	assert.Nil(t, b.Last())
+	assert.Empty(t, b.List())
+
+	b.Record(notifier.DeliveryResult{Destination: "b", Success: true})
+	b.Record(notifier.DeliveryResult{Destination: "a", Success: true})
+	b.Record(notifier.DeliveryResult{Destination: "a", Success: false, Err: errors.New("unexpected status code: 502")})
+
+	list := b.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "a", list[0].Name)
+	assert.False(t, list[0].Healthy)
+	assert.Equal(t, &now, list[0].LastSuccess)
+	assert.Equal(t, &now, list[0].LastFailure)
+	assert.Equal(t, "unexpected status code: 502", list[0].LastError)
+	assert.Equal(t, 1, list[0].Sent)
+	assert.Equal(t, 1, list[0].Failed)
+	assert.True(t, list[1].Healthy)
+
+	last := b.Last()
+	require.NotNil(t, last)
+	assert.Equal(t, "a", last.Destination)
+	assert.False(t, last.Success)
+	assert.Equal(t, "unexpected status code: 502", last.Error)
+}
@@ -0,0 +1,158 @@
+// Package httpauth protects the HTTP server with basic authentication,
+// bearer tokens and an IP allowlist, as its endpoints expose message content
+// and admin operations.
+package httpauth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// realm is the basic authentication realm shown by browsers
+const realm = "p2000-forwarder"
+
+// Options configures the authentication of the HTTP server
+type Options struct {
+	Username string // Basic authentication, disabled when empty
+	Password string
+
+	// Bearer tokens accepted instead of the basic credentials. Empty
+	// tokens are ignored.
+	Tokens []string
+
+	// IP addresses and CIDR ranges allowed to connect, all when empty. The
+	// address of the direct peer is used, not X-Forwarded-For.
+	AllowedIPs []string
+
+	// Paths served without credentials, e.g. Kubernetes probes. A path
+	// ending in / also covers everything below it. The IP allowlist still
+	// applies.
+	Public []string
+}
+
+// Auth checks the credentials and address of requests
+type Auth struct {
+	username string
+	password string
+	tokens   []string
+	nets     []*net.IPNet
+	public   []string
+}
+
+// New creates the authentication for opts
+func New(opts Options) (*Auth, error) {
+	a := &Auth{
+		username: opts.Username,
+		password: opts.Password,
+		public:   opts.Public,
+	}
+	for _, token := range opts.Tokens {
+		if token != "" {
+			a.tokens = append(a.tokens, token)
+		}
+	}
+	for _, entry := range opts.AllowedIPs {
+		n, err := ParseIPNet(entry)
+		if err != nil {
+			return nil, err
+		}
+		a.nets = append(a.nets, n)
+	}
+	return a, nil
+}
+
+// ParseIPNet parses an IP address or CIDR range
+func ParseIPNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", s)
+		}
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Enabled reports whether requests need credentials
+func (a *Auth) Enabled() bool {
+	return a.username != "" || len(a.tokens) > 0
+}
+
+// Wrap returns next protected by the authentication
+func (a *Auth) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.allowed(r.RemoteAddr) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if a.Enabled() && !a.isPublic(r.URL.Path) && !a.authorized(r) {
+			if a.username != "" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPublic reports whether path is served without credentials
+func (a *Auth) isPublic(path string) bool {
+	for _, p := range a.public {
+		if path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether the peer at remoteAddr is on the allowlist
+func (a *Auth) allowed(remoteAddr string) bool {
+	if len(a.nets) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized reports whether the request carries valid credentials
+func (a *Auth) authorized(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		for _, t := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+	if a.username == "" {
+		return false
+	}
+	username, password, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(a.username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+}
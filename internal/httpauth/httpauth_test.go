@@ -0,0 +1,113 @@
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func serve(t *testing.T, a *Auth, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.Wrap(ok).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestWrap_Disabled(t *testing.T) {
+	a, err := New(Options{})
+	require.NoError(t, err)
+	assert.False(t, a.Enabled())
+	assert.Equal(t, http.StatusOK, serve(t, a, httptest.NewRequest("GET", "/metrics", nil)).Code)
+}
+
+func TestWrap_BasicAndBearer(t *testing.T) {
+	a, err := New(Options{
+		Username: "admin",
+		Password: "secret",
+		Tokens:   []string{"", "scrape-token", "api-token"},
+		Public:   []string{"/live"},
+	})
+	require.NoError(t, err)
+	assert.True(t, a.Enabled())
+
+	rec := serve(t, a, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="p2000-forwarder"`, rec.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.SetBasicAuth("admin", "secret")
+	assert.Equal(t, http.StatusOK, serve(t, a, req).Code)
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.SetBasicAuth("admin", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, req).Code)
+
+	for token, code := range map[string]int{"scrape-token": http.StatusOK, "api-token": http.StatusOK, "wrong": http.StatusUnauthorized, "": http.StatusUnauthorized} {
+		req = httptest.NewRequest("GET", "/api/status", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		assert.Equal(t, code, serve(t, a, req).Code, token)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(t, a, httptest.NewRequest("GET", "/live", nil)).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, httptest.NewRequest("GET", "/live/extra", nil)).Code)
+}
+
+func TestWrap_PublicPrefix(t *testing.T) {
+	a, err := New(Options{Tokens: []string{"token"}, Public: []string{"/api/subscriptions/"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, serve(t, a, httptest.NewRequest("GET", "/api/subscriptions/abc", nil)).Code)
+	assert.Equal(t, http.StatusOK, serve(t, a, httptest.NewRequest("GET", "/api/subscriptions/", nil)).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, httptest.NewRequest("GET", "/api/subscriptions", nil)).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, httptest.NewRequest("GET", "/api/status", nil)).Code)
+}
+
+func TestWrap_TokenOnly(t *testing.T) {
+	a, err := New(Options{Tokens: []string{"token"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.SetBasicAuth("", "token")
+	rec := serve(t, a, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestWrap_AllowedIPs(t *testing.T) {
+	a, err := New(Options{AllowedIPs: []string{"127.0.0.1", "10.0.0.0/8", "::1"}, Public: []string{"/live"}})
+	require.NoError(t, err)
+
+	for addr, code := range map[string]int{
+		"127.0.0.1:5000":   http.StatusOK,
+		"10.42.0.7:5000":   http.StatusOK,
+		"[::1]:5000":       http.StatusOK,
+		"192.168.1.4:5000": http.StatusForbidden,
+		"not-an-address":   http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/live", nil)
+		req.RemoteAddr = addr
+		assert.Equal(t, code, serve(t, a, req).Code, addr)
+	}
+}
+
+func TestParseIPNet(t *testing.T) {
+	n, err := ParseIPNet("192.168.1.4")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.4/32", n.String())
+
+	n, err = ParseIPNet("fd00::/8")
+	require.NoError(t, err)
+	assert.Equal(t, "fd00::/8", n.String())
+
+	_, err = ParseIPNet("10.0.0.0/33")
+	assert.ErrorContains(t, err, "invalid CIDR range")
+	_, err = ParseIPNet("localhost")
+	assert.ErrorContains(t, err, "invalid IP address")
+}
@@ -0,0 +1,118 @@
+// Package i18n translates the static strings used in notifications and
+// status responses. Translations are embedded JSON files in locales/, one
+// per language, mapping message keys to fmt format strings.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is used when no language is configured
+const DefaultLanguage = "nl"
+
+// fallbackLanguage provides strings missing from a translation
+const fallbackLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Translator looks up strings for a single language
+type Translator struct {
+	lang     string
+	messages map[string]string
+	fallback map[string]string
+}
+
+// New creates a translator for lang, e.g. "nl" or "en". An empty lang
+// selects DefaultLanguage.
+func New(lang string) (*Translator, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
+	messages, err := load(lang)
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := load(fallbackLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Translator{
+		lang:     lang,
+		messages: messages,
+		fallback: fallback,
+	}, nil
+}
+
+var (
+	defaultOnce       sync.Once
+	defaultTranslator *Translator
+)
+
+// Default returns the shared translator for DefaultLanguage
+func Default() *Translator {
+	defaultOnce.Do(func() {
+		t, err := New(DefaultLanguage)
+		if err != nil {
+			panic(err) // embedded files are checked by the tests
+		}
+		defaultTranslator = t
+	})
+	return defaultTranslator
+}
+
+// Languages lists the available languages
+func Languages() []string {
+	entries, _ := locales.ReadDir("locales")
+
+	langs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		langs = append(langs, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Language returns the language code of the translator
+func (t *Translator) Language() string {
+	return t.lang
+}
+
+// T returns the translation for key formatted with args. Keys missing from
+// the language fall back to English, unknown keys are returned as is.
+func (t *Translator) T(key string, args ...any) string {
+	format, ok := t.messages[key]
+	if !ok {
+		if format, ok = t.fallback[key]; !ok {
+			format = key
+		}
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// load reads the embedded translation file for lang
+func load(lang string) (map[string]string, error) {
+	data, err := locales.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(Languages(), ", "))
+	}
+
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("invalid translation file for %q: %w", lang, err)
+	}
+	return messages, nil
+}
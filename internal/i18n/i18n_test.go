@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	nl, err := New("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLanguage, nl.Language())
+	assert.Equal(t, "overig", nl.T("agency.unknown"))
+
+	en, err := New(" EN ")
+	require.NoError(t, err)
+	assert.Equal(t, "en", en.Language())
+	assert.Equal(t, "other", en.T("agency.unknown"))
+}
+
+func TestNew_Unsupported(t *testing.T) {
+	_, err := New("fr")
+	assert.ErrorContains(t, err, `unsupported language "fr" (available: en, nl)`)
+}
+
+func TestT_Formatting(t *testing.T) {
+	en, err := New("en")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Messages: 3 received, 1 forwarded", en.T("report.messages", 3, 1))
+	assert.Equal(t, "missing.key", en.T("missing.key"))
+}
+
+func TestT_FallsBackToEnglish(t *testing.T) {
+	tr := &Translator{
+		lang:     "nl",
+		messages: map[string]string{},
+		fallback: map[string]string{"health.healthy": "healthy"},
+	}
+	assert.Equal(t, "healthy", tr.T("health.healthy"))
+}
+
+func TestLocales_SameKeys(t *testing.T) {
+	keys := func(lang string) []string {
+		data, err := locales.ReadFile("locales/" + lang + ".json")
+		require.NoError(t, err)
+
+		var messages map[string]string
+		require.NoError(t, json.Unmarshal(data, &messages))
+
+		var result []string
+		for key := range messages {
+			result = append(result, key)
+		}
+		return result
+	}
+
+	english := keys("en")
+	for _, lang := range Languages() {
+		assert.ElementsMatch(t, english, keys(lang), "locale %s", lang)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	assert.Same(t, Default(), Default())
+	assert.Equal(t, DefaultLanguage, Default().Language())
+}
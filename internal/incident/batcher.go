@@ -0,0 +1,146 @@
+package incident
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// DefaultBatchWindow is how long the first page of an incident waits for
+// the pages of the other responding units
+const DefaultBatchWindow = 5 * time.Second
+
+// TextSeparator separates the differing texts of a combined message. The
+// text ends up in the ntfy title header, so it must stay on one line.
+const TextSeparator = " | "
+
+// FlushFunc receives a combined message with its delivery and the IDs of
+// the pages merged into it besides its own
+type FlushFunc func(msg model.Message, d notifier.Delivery, merged []string)
+
+// batch is an incident waiting for the end of its window
+type batch struct {
+	msg      model.Message
+	delivery notifier.Delivery
+	merged   []string
+	timer    *time.Timer
+}
+
+// Batcher combines the pages of an incident sent in separate frames, such as
+// one frame per responding unit, into one message listing all capcodes.
+// Pages belong to the same incident by their Key; the first page waits the
+// window for the others, so the delay is bounded regardless of how many
+// follow.
+type Batcher struct {
+	window time.Duration
+	flush  FlushFunc
+
+	mu      sync.Mutex
+	batches map[string]*batch
+	closed  bool
+}
+
+// NewBatcher creates a batcher handing combined messages to flush,
+// DefaultBatchWindow is used when window is not positive
+func NewBatcher(window time.Duration, flush FlushFunc) *Batcher {
+	if window <= 0 {
+		window = DefaultBatchWindow
+	}
+	return &Batcher{
+		window:  window,
+		flush:   flush,
+		batches: make(map[string]*batch),
+	}
+}
+
+// Add holds msg and its delivery until the window of its incident ends,
+// reporting whether it was merged into an earlier page. Messages without an
+// incident key are flushed right away, as are all messages after Close.
+func (b *Batcher) Add(msg model.Message, d notifier.Delivery) bool {
+	key := Key(msg)
+
+	b.mu.Lock()
+	if key == "" || b.closed {
+		b.mu.Unlock()
+		b.flush(msg, d, nil)
+		return false
+	}
+	if bt, ok := b.batches[key]; ok {
+		merge(&bt.msg, &bt.delivery, msg, d)
+		bt.merged = append(bt.merged, msg.ID)
+		b.mu.Unlock()
+		return true
+	}
+	bt := &batch{msg: msg, delivery: d}
+	bt.timer = time.AfterFunc(b.window, func() { b.release(key, bt) })
+	b.batches[key] = bt
+	b.mu.Unlock()
+	return false
+}
+
+// Len returns the number of incidents waiting for their window to end
+func (b *Batcher) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.batches)
+}
+
+// Close flushes the waiting incidents right away, e.g. on shutdown. Later
+// messages are not batched.
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	batches := b.batches
+	b.batches = make(map[string]*batch)
+	b.mu.Unlock()
+
+	for _, bt := range batches {
+		bt.timer.Stop()
+		b.flush(bt.msg, bt.delivery, bt.merged)
+	}
+}
+
+// release flushes bt when its window ends, unless Close did already
+func (b *Batcher) release(key string, bt *batch) {
+	b.mu.Lock()
+	if b.batches[key] != bt {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.batches, key)
+	b.mu.Unlock()
+
+	b.flush(bt.msg, bt.delivery, bt.merged)
+}
+
+// merge adds the capcodes of msg to into, and the routes and tags of d to
+// delivery. A text differing from the texts merged so far is appended after
+// TextSeparator, so no page is lost when pages of different incidents share
+// a key. The urgency of the first page is kept, the highest priority
+// override wins.
+func merge(into *model.Message, delivery *notifier.Delivery, msg model.Message, d notifier.Delivery) {
+	if !slices.Contains(strings.Split(into.Message, TextSeparator), msg.Message) {
+		into.Message += TextSeparator + msg.Message
+	}
+	into.Capcodes = appendMissing(into.Capcodes, msg.Capcodes)
+	into.Reanimation = into.Reanimation || msg.Reanimation
+	delivery.Routes = appendMissing(delivery.Routes, d.Routes)
+	delivery.Tags = appendMissing(delivery.Tags, d.Tags)
+	delivery.PriorityOverride = max(delivery.PriorityOverride, d.PriorityOverride)
+}
+
+// appendMissing appends the values of add not in list yet, without writing
+// to the array of list
+func appendMissing(list, add []string) []string {
+	list = slices.Clip(list)
+	for _, v := range add {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
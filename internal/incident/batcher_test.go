@@ -0,0 +1,91 @@
+package incident
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flushRecorder collects the messages flushed by a batcher
+type flushRecorder struct {
+	mu         sync.Mutex
+	msgs       []model.Message
+	deliveries []notifier.Delivery
+	merged     [][]string
+}
+
+func (r *flushRecorder) flush(msg model.Message, d notifier.Delivery, merged []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+	r.deliveries = append(r.deliveries, d)
+	r.merged = append(r.merged, merged)
+}
+
+func (r *flushRecorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.msgs)
+}
+
+func TestBatcher(t *testing.T) {
+	rec := &flushRecorder{}
+	b := NewBatcher(50*time.Millisecond, rec.flush)
+
+	text := "P 1 BDH-01 Brand woning Kerkstraat 12 Harmelen"
+	assert.False(t, b.Add(model.Message{ID: "1", Capcodes: []string{"0101001"}, Message: text}, notifier.Delivery{}))
+	assert.True(t, b.Add(model.Message{ID: "2", Capcodes: []string{"0101002"}, Message: text}, notifier.Delivery{Tags: []string{"fire"}}))
+	assert.True(t, b.Add(model.Message{ID: "3", Capcodes: []string{"0101001", "0101003"}, Message: text}, notifier.Delivery{PriorityOverride: 5}))
+	assert.False(t, b.Add(model.Message{ID: "4", Capcodes: []string{"0101004"}, Message: "A1 12345"}, notifier.Delivery{}), "no incident key")
+	require.Equal(t, 1, rec.len(), "sent right away")
+	assert.Equal(t, 1, b.Len())
+
+	require.Eventually(t, func() bool { return rec.len() == 2 }, time.Second, 5*time.Millisecond)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	msg := rec.msgs[1]
+	assert.Equal(t, "1", msg.ID)
+	assert.Equal(t, text, msg.Message, "identical texts are kept once")
+	assert.Equal(t, []string{"0101001", "0101002", "0101003"}, msg.Capcodes)
+	assert.Equal(t, []string{"fire"}, rec.deliveries[1].Tags)
+	assert.Equal(t, 5, rec.deliveries[1].PriorityOverride)
+	assert.Equal(t, []string{"2", "3"}, rec.merged[1])
+	assert.Equal(t, 0, b.Len())
+}
+
+func TestBatcher_DifferentTexts(t *testing.T) {
+	rec := &flushRecorder{}
+	b := NewBatcher(time.Hour, rec.flush)
+
+	// Different incidents at the same address share the key
+	first := "A1 Ambulance Damstraat 3 Utrecht"
+	second := "P 2 BDH-01 Brand Damstraat 3 Utrecht"
+	require.Equal(t, Key(model.Message{Message: first}), Key(model.Message{Message: second}))
+
+	b.Add(model.Message{ID: "1", Capcodes: []string{"0202001"}, Message: first}, notifier.Delivery{})
+	assert.True(t, b.Add(model.Message{ID: "2", Capcodes: []string{"0101001"}, Message: second}, notifier.Delivery{}))
+	assert.True(t, b.Add(model.Message{ID: "3", Capcodes: []string{"0101002"}, Message: second}, notifier.Delivery{}))
+
+	b.Close()
+	require.Equal(t, 1, rec.len())
+	assert.Equal(t, first+TextSeparator+second, rec.msgs[0].Message)
+}
+
+func TestBatcher_Close(t *testing.T) {
+	rec := &flushRecorder{}
+	b := NewBatcher(time.Hour, rec.flush)
+
+	b.Add(model.Message{ID: "1", Capcodes: []string{"0101001"}, Message: "P 1 Brand woning Kerkstraat 12 Harmelen"}, notifier.Delivery{})
+	b.Add(model.Message{ID: "2", Capcodes: []string{"0202001"}, Message: "A1 Ambulance Damstraat 1 Utrecht"}, notifier.Delivery{})
+	assert.Equal(t, 0, rec.len())
+
+	b.Close()
+	assert.Equal(t, 2, rec.len(), "waiting incidents are flushed")
+	assert.False(t, b.Add(model.Message{ID: "3", Capcodes: []string{"0101002"}, Message: "P 1 Brand woning Kerkstraat 12 Harmelen"}, notifier.Delivery{}))
+	assert.Equal(t, 3, rec.len(), "not batched after close")
+}
@@ -0,0 +1,117 @@
+// Package incident groups follow-up pages for the same incident, so they can
+// be sent as updates of a single notification thread, and combines the
+// frames of an incident burst into one notification.
+package incident
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kaije/p2000-nfty/internal/geocode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// DefaultWindow is how long an incident accepts follow-up pages after the
+// last page
+const DefaultWindow = 15 * time.Minute
+
+// thread is an incident that received a page within the window
+type thread struct {
+	id       string
+	lastSeen time.Time
+}
+
+// Correlator assigns pages to incident threads. Pages with the same address,
+// or without an address the same text apart from the urgency code, belong
+// to the same incident while each follows the previous one within the
+// window.
+type Correlator struct {
+	window  time.Duration
+	mu      sync.Mutex
+	threads map[string]*thread
+}
+
+// NewCorrelator creates a correlator, DefaultWindow is used when window is
+// not positive
+func NewCorrelator(window time.Duration) *Correlator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Correlator{
+		window:  window,
+		threads: make(map[string]*thread),
+	}
+}
+
+// Correlate returns the thread ID of the incident msg, sent at t, belongs to
+// and whether it is a follow-up of an earlier page. Messages without an
+// address or words are not correlated and get an empty thread ID.
+func (c *Correlator) Correlate(msg model.Message, t time.Time) (string, bool) {
+	key := Key(msg)
+	if key == "" {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(t)
+	if th, ok := c.threads[key]; ok {
+		th.lastSeen = t
+		return th.id, true
+	}
+
+	th := &thread{id: threadID(key, t), lastSeen: t}
+	c.threads[key] = th
+	return th.id, false
+}
+
+// Len returns the number of open incidents
+func (c *Correlator) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.threads)
+}
+
+// expire removes incidents without pages within the window before t
+func (c *Correlator) expire(t time.Time) {
+	for key, th := range c.threads {
+		if t.Sub(th.lastSeen) > c.window {
+			delete(c.threads, key)
+		}
+	}
+}
+
+// Key returns the incident key of a message: its address when known,
+// otherwise the text without the urgency code. Numbers such as ride numbers
+// and capcodes are kept, they tell apart incidents paged with the same words.
+// Texts of numbers only have no key.
+func Key(msg model.Message) string {
+	if address := geocode.Query(msg); address != "" {
+		return "address:" + strings.ToLower(address)
+	}
+
+	text := strings.TrimSpace(msg.Message)
+	if priority := model.ParsePriority(text); priority != "" {
+		text = strings.TrimSpace(strings.TrimPrefix(text, priority))
+	}
+
+	if strings.IndexFunc(text, unicode.IsLetter) < 0 {
+		return ""
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return "text:" + strings.Join(words, " ")
+}
+
+// threadID derives a stable ID for an incident first paged at t
+func threadID(key string, t time.Time) string {
+	sum := sha256.Sum256([]byte(key + "|" + strconv.FormatInt(t.UnixNano(), 10)))
+	return "p2000-" + hex.EncodeToString(sum[:8])
+}
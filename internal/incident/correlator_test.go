@@ -0,0 +1,69 @@
+package incident
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      model.Message
+		expected string
+	}{
+		{"address", model.Message{Message: "A1 Brand woning Kerkstraat 12 3481AB Harmelen"}, "address:kerkstraat 12, 3481ab harmelen"},
+		{"location", model.Message{Message: "P 1 BR woning", Location: "Damstraat, Utrecht"}, "address:damstraat, utrecht"},
+		{"keywords", model.Message{Message: "A2 Ambu 17143 Rit 71234"}, "text:ambu 17143 rit 71234"},
+		{"numbers only", model.Message{Message: "A1 12345"}, ""},
+		{"empty", model.Message{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Key(tt.msg))
+		})
+	}
+}
+
+func TestCorrelator_Correlate(t *testing.T) {
+	c := NewCorrelator(10 * time.Minute)
+	start := time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)
+
+	first, update := c.Correlate(model.Message{Message: "P 1 BR woning Kerkstraat 12 Harmelen"}, start)
+	assert.NotEmpty(t, first)
+	assert.False(t, update)
+
+	// An upgrade of the same incident continues the thread
+	id, update := c.Correlate(model.Message{Message: "P 1 GRIP 1 BR woning Kerkstraat 12 Harmelen"}, start.Add(8*time.Minute))
+	assert.Equal(t, first, id)
+	assert.True(t, update)
+
+	// Each follow-up extends the window
+	id, update = c.Correlate(model.Message{Message: "P 2 BR woning Kerkstraat 12 Harmelen"}, start.Add(16*time.Minute))
+	assert.Equal(t, first, id)
+	assert.True(t, update)
+
+	other, update := c.Correlate(model.Message{Message: "A1 Ambulance Damstraat 3 Utrecht"}, start.Add(16*time.Minute))
+	assert.NotEqual(t, first, other)
+	assert.False(t, update)
+	assert.Equal(t, 2, c.Len())
+
+	// After the window a new incident starts at the same address
+	id, update = c.Correlate(model.Message{Message: "P 1 BR woning Kerkstraat 12 Harmelen"}, start.Add(time.Hour))
+	assert.NotEqual(t, first, id)
+	assert.False(t, update)
+	assert.Equal(t, 1, c.Len(), "expired incidents are removed")
+
+	// Different incidents paged with the same words in the same town
+	first, _ = c.Correlate(model.Message{Message: "A1 17124 Rit 12345 Utrecht"}, start)
+	other, update = c.Correlate(model.Message{Message: "A1 17101 Rit 54321 Utrecht"}, start.Add(time.Minute))
+	assert.NotEqual(t, first, other)
+	assert.False(t, update)
+
+	id, update = c.Correlate(model.Message{Message: "A1 12345"}, start)
+	assert.Empty(t, id)
+	assert.False(t, update)
+}
@@ -0,0 +1,260 @@
+// Package influx writes an event per message to InfluxDB, tagged with its
+// agency, region, discipline and priority, for long-term incident volume
+// analysis outside Prometheus.
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultMeasurement   = "p2000_messages"
+	DefaultFlushInterval = 10 * time.Second
+	DefaultBuffer        = 1000
+
+	maxBatch       = 500 // Points per write request
+	maxAttempts    = 3   // Flushes of a batch before it is dropped
+	requestTimeout = 10 * time.Second
+)
+
+// Options configures a Writer. URL and Bucket are required.
+type Options struct {
+	URL           string        // InfluxDB server, e.g. http://influxdb:8086
+	Org           string        // Organization of the bucket, unused by InfluxDB 1.8
+	Bucket        string        // Bucket, or database/retention-policy for InfluxDB 1.8
+	Token         string        // API token, or username:password for InfluxDB 1.8
+	Measurement   string        // DefaultMeasurement when empty
+	FlushInterval time.Duration // DefaultFlushInterval when 0
+	Buffer        int           // Points waiting before new ones are dropped, DefaultBuffer when 0
+
+	Metrics *metrics.Metrics // Optional
+	Logger  zerolog.Logger
+}
+
+// point is a message waiting to be written
+type point struct {
+	msg       model.Message
+	t         time.Time
+	forwarded bool
+}
+
+// Writer batches points in the background and writes them with the
+// InfluxDB v2 write API, which InfluxDB 1.8 and later support. Record
+// never blocks the feed: when the buffer is full, points are dropped.
+type Writer struct {
+	opts       Options
+	writeURL   string
+	httpClient *http.Client
+	points     chan point
+	last       int64 // Timestamp of the last encoded point, owned by Run
+}
+
+// ValidateURL checks that rawURL is an http(s) server URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("influx url %q must be an http:// or https:// URL", rawURL)
+	}
+	return nil
+}
+
+// New creates a writer; Run writes the recorded points
+func New(opts Options) (*Writer, error) {
+	if err := ValidateURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("influx bucket is required")
+	}
+	if opts.FlushInterval < 0 || opts.Buffer < 0 {
+		return nil, fmt.Errorf("influx flush_interval and buffer must not be negative")
+	}
+	if opts.Measurement == "" {
+		opts.Measurement = DefaultMeasurement
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = DefaultBuffer
+	}
+
+	query := url.Values{"bucket": {opts.Bucket}, "precision": {"ns"}}
+	if opts.Org != "" {
+		query.Set("org", opts.Org)
+	}
+	return &Writer{
+		opts:       opts,
+		writeURL:   strings.TrimRight(opts.URL, "/") + "/api/v2/write?" + query.Encode(),
+		httpClient: &http.Client{Timeout: requestTimeout},
+		points:     make(chan point, opts.Buffer),
+	}, nil
+}
+
+// Record queues a point for msg, paged at t
+func (w *Writer) Record(msg model.Message, t time.Time, forwarded bool) {
+	select {
+	case w.points <- point{msg: msg, t: t, forwarded: forwarded}:
+	default:
+		w.record("dropped", 1)
+		w.opts.Logger.Warn().Str("id", msg.ID).Msg("influx buffer full, dropping point")
+	}
+}
+
+// Run writes the queued points every flush interval, or sooner when a
+// batch is full, until ctx is cancelled. A failed batch is retried with the
+// next flush.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []string
+	attempts := 0
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		err := w.write(ctx, batch)
+		if err == nil {
+			w.record("written", len(batch))
+			batch, attempts = nil, 0
+			return
+		}
+		attempts++
+		w.opts.Logger.Debug().Err(err).Int("attempt", attempts).Msg("failed to write to influx")
+		if attempts == maxAttempts {
+			w.record("failed", len(batch))
+			w.opts.Logger.Error().Err(err).Int("points", len(batch)).Msg("failed to write points to influx")
+			batch, attempts = nil, 0
+		}
+	}
+
+	for {
+		select {
+		case p := <-w.points:
+			batch = append(batch, w.line(p))
+			if len(batch) == maxBatch {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			// Write what is left, without the cancelled context
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			flush(ctx)
+			cancel()
+			return
+		}
+	}
+}
+
+// write sends lines in a single request
+func (w *Writer) write(ctx context.Context, lines []string) error {
+	body := strings.Join(lines, "\n") + "\n"
+	req, err := http.NewRequestWithContext(ctx, "POST", w.writeURL, bytes.NewBufferString(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.opts.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.opts.Token)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// line encodes p in line protocol. Points with the same tags and time
+// overwrite each other, so messages paged in the same second get distinct
+// nanoseconds.
+func (w *Writer) line(p point) string {
+	ts := p.t.UnixNano()
+	if ts <= w.last && ts/int64(time.Second) == w.last/int64(time.Second) {
+		ts = w.last + 1
+	}
+	w.last = ts
+
+	tags := map[string]string{
+		"priority":  p.msg.Priority,
+		"forwarded": strconv.FormatBool(p.forwarded),
+		"test":      strconv.FormatBool(p.msg.Test),
+	}
+	agency, region := primary(p.msg)
+	tags["agency"], tags["region"] = agency, region
+	tags["discipline"] = string(filter.ClassifyAgency(agency))
+
+	keys := make([]string, 0, len(tags))
+	for key, value := range tags {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys) // The order InfluxDB stores them in
+
+	var b strings.Builder
+	b.WriteString(escape(w.opts.Measurement, ", "))
+	for _, key := range keys {
+		b.WriteString("," + key + "=" + escape(tags[key], ", ="))
+	}
+	fmt.Fprintf(&b, " count=1i,capcodes=%di,grip=%di %d", len(p.msg.Capcodes), p.msg.GRIP, ts)
+	return b.String()
+}
+
+// primary returns the agency and region of the first capcode in the
+// capcode database, or the feed agency
+func primary(msg model.Message) (agency, region string) {
+	for _, info := range msg.CapcodeInfo {
+		if agency == "" {
+			agency = info.Agency
+		}
+		if region == "" {
+			region = info.Region
+		}
+	}
+	if agency == "" {
+		agency = msg.Agency
+	}
+	return agency, region
+}
+
+// escape backslash escapes the characters special to a line protocol
+// element; line breaks become spaces
+func escape(s, special string) string {
+	s = strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// record counts points by their result when metrics are configured
+func (w *Writer) record(result string, n int) {
+	if w.opts.Metrics != nil {
+		w.opts.Metrics.RecordInfluxPoints(result, n)
+	}
+}
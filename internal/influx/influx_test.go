@@ -0,0 +1,127 @@
+package influx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLine(t *testing.T) {
+	w, err := New(Options{URL: "http://influxdb:8086", Bucket: "p2000"})
+	require.NoError(t, err)
+
+	paged := time.Unix(1760522400, 0)
+	msg := model.Message{
+		Capcodes:    []string{"001180000", "001180001"},
+		Priority:    "P 1",
+		GRIP:        2,
+		CapcodeInfo: []capcode.CapcodeInfo{{Agency: "Brandweer", Region: "Utrecht, stad"}},
+	}
+	assert.Equal(t,
+		`p2000_messages,agency=Brandweer,discipline=brandweer,forwarded=true,priority=P\ 1,region=Utrecht\,\ stad,test=false count=1i,capcodes=2i,grip=2i 1760522400000000000`,
+		w.line(point{msg: msg, t: paged, forwarded: true}))
+
+	// A second message in the same second gets the next nanosecond
+	assert.Equal(t,
+		`p2000_messages,agency=Ambulance,discipline=ambulance,forwarded=false,test=true count=1i,capcodes=0i,grip=0i 1760522400000000001`,
+		w.line(point{msg: model.Message{Agency: "Ambulance", Test: true}, t: paged}))
+
+	// Later seconds keep their own time
+	assert.True(t, strings.HasSuffix(w.line(point{t: paged.Add(time.Second)}), " 1760522401000000000"))
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\ b\,c\=d`, escape("a b,c=d", ", ="))
+	assert.Equal(t, `line\ one\ two`, escape("line one\ntwo", ", ="))
+	assert.Equal(t, `p2000=x`, escape("p2000=x", ", "))
+}
+
+func TestWriter_Run(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		bodies   []string
+		requests []*http.Request
+		fail     = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		if fail > 0 {
+			fail--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w, err := New(Options{
+		URL:           server.URL,
+		Org:           "brandweer",
+		Bucket:        "p2000",
+		Token:         "secret",
+		FlushInterval: 20 * time.Millisecond,
+		Logger:        zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// The first flush fails, the next one writes both points
+	w.Record(model.Message{Agency: "Brandweer"}, time.Unix(1760522400, 0), true)
+	w.Record(model.Message{Agency: "Politie"}, time.Unix(1760522460, 0), false)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, strings.Count(bodies[0], "\n"))
+	assert.Contains(t, bodies[0], "agency=Politie")
+	req := requests[len(requests)-1]
+	assert.Equal(t, "/api/v2/write", req.URL.Path)
+	assert.Equal(t, "p2000", req.URL.Query().Get("bucket"))
+	assert.Equal(t, "brandweer", req.URL.Query().Get("org"))
+	assert.Equal(t, "ns", req.URL.Query().Get("precision"))
+	assert.Equal(t, "Token secret", req.Header.Get("Authorization"))
+}
+
+func TestWriter_DropsWhenFull(t *testing.T) {
+	w, err := New(Options{URL: "http://influxdb:8086", Bucket: "p2000", Buffer: 1, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	// Without Run nothing is taken from the buffer
+	w.Record(model.Message{Message: "1"}, time.Now(), true)
+	w.Record(model.Message{Message: "2"}, time.Now(), true)
+	assert.Len(t, w.points, 1)
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Options{URL: "udp://influxdb:8089", Bucket: "p2000"})
+	assert.EqualError(t, err, `influx url "udp://influxdb:8089" must be an http:// or https:// URL`)
+
+	_, err = New(Options{URL: "http://influxdb:8086"})
+	assert.EqualError(t, err, "influx bucket is required")
+
+	_, err = New(Options{URL: "http://influxdb:8086", Bucket: "p2000", Buffer: -1})
+	assert.EqualError(t, err, "influx flush_interval and buffer must not be negative")
+}
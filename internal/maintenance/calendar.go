@@ -0,0 +1,120 @@
+// Package maintenance recognizes messages sent during recurring maintenance
+// and exercise windows of capcode groups, such as the weekly exercise
+// evening of a fire station.
+package maintenance
+
+import (
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/rs/zerolog"
+)
+
+// Schedule gives the start times of recurring windows
+type Schedule interface {
+	// starts returns the start times of the windows on the day of day, which
+	// is midnight in the timezone of the calendar
+	starts(day time.Time) []time.Time
+}
+
+// Window is a recurring window for a group of capcodes
+type Window struct {
+	Name     string
+	Action   string // What to do with messages in the window, up to the caller
+	Tags     string // Comma separated tags of labelled messages
+	Capcodes []string
+	Schedule Schedule
+	Duration time.Duration
+}
+
+// window is a Window with its capcodes compiled
+type window struct {
+	Window
+	capcodes *filter.Matcher
+}
+
+// Calendar finds the windows messages were sent in
+type Calendar struct {
+	windows  []window
+	location *time.Location
+}
+
+// NewCalendar creates a calendar of windows in Dutch time. A window without
+// capcodes applies to every message; capcodes are matched like the capcodes
+// filter, so prefixes and ranges work and leading zeros are optional.
+func NewCalendar(windows []Window, logger zerolog.Logger) *Calendar {
+	// Exercise evenings follow Dutch time
+	location, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		logger.Warn().Err(err).Msg("timezone data unavailable, using local time for maintenance windows")
+		location = time.Local
+	}
+
+	c := &Calendar{location: location}
+	for _, w := range windows {
+		var capcodes *filter.Matcher
+		if len(w.Capcodes) > 0 {
+			capcodes = filter.NewMatcher(w.Capcodes, true)
+		}
+		c.windows = append(c.windows, window{Window: w, capcodes: capcodes})
+	}
+	return c
+}
+
+// Match returns the first window, in configuration order, that one of
+// capcodes falls in at t
+func (c *Calendar) Match(capcodes []string, t time.Time) (Window, bool) {
+	for _, w := range c.windows {
+		if w.covers(capcodes) && c.active(w.Window, t) {
+			return w.Window, true
+		}
+	}
+	return Window{}, false
+}
+
+// covers reports whether the window applies to a message for capcodes
+func (w window) covers(capcodes []string) bool {
+	if w.capcodes == nil {
+		return true
+	}
+	for _, code := range capcodes {
+		if w.capcodes.Match(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// active reports whether a window of w started at most its duration before
+// t. Windows may run past midnight, so earlier days are checked as well.
+func (c *Calendar) active(w Window, t time.Time) bool {
+	t = t.In(c.location)
+	first := midnight(t.Add(-w.Duration))
+	for day := first; !day.After(t); day = midnight(day.AddDate(0, 0, 1)) {
+		for _, start := range w.Schedule.starts(day) {
+			if !t.Before(start) && t.Before(start.Add(w.Duration)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Next returns the start of the first window of w after t, or false when
+// there is none within a year
+func (c *Calendar) Next(w Window, t time.Time) (time.Time, bool) {
+	t = t.In(c.location)
+	for day, i := midnight(t), 0; i <= 366; day, i = midnight(day.AddDate(0, 0, 1)), i+1 {
+		for _, start := range w.Schedule.starts(day) {
+			if start.After(t) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// midnight returns the start of the day of t
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
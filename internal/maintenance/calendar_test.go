@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar_Match(t *testing.T) {
+	exercise, err := ParseCron("0 19 * * tue")
+	require.NoError(t, err)
+	night, err := ParseRRule("FREQ=DAILY;BYHOUR=23")
+	require.NoError(t, err)
+
+	c := NewCalendar([]Window{
+		{Name: "oefenavond", Action: "label", Capcodes: []string{"0101001", "01015*"}, Schedule: exercise, Duration: 3 * time.Hour},
+		{Name: "onderhoud", Action: "suppress", Schedule: night, Duration: 2 * time.Hour},
+	}, zerolog.Nop())
+
+	tests := []struct {
+		name     string
+		capcodes []string
+		at       time.Time
+		expected string
+	}{
+		{"in window", []string{"101001"}, time.Date(2024, 3, 5, 20, 0, 0, 0, amsterdam), "oefenavond"},
+		{"prefix", []string{"0100005", "0101599"}, time.Date(2024, 3, 5, 19, 0, 0, 0, amsterdam), "oefenavond"},
+		{"other capcode", []string{"0200001"}, time.Date(2024, 3, 5, 20, 0, 0, 0, amsterdam), ""},
+		{"before window", []string{"0101001"}, time.Date(2024, 3, 5, 18, 59, 0, 0, amsterdam), ""},
+		{"end of window", []string{"0101001"}, time.Date(2024, 3, 5, 22, 0, 0, 0, amsterdam), ""},
+		{"utc time", []string{"0101001"}, time.Date(2024, 3, 5, 18, 30, 0, 0, time.UTC), "oefenavond"},
+		{"past midnight", []string{"0200001"}, time.Date(2024, 3, 6, 0, 30, 0, 0, amsterdam), "onderhoud"},
+		{"every capcode", []string{"0101001"}, time.Date(2024, 3, 5, 23, 30, 0, 0, amsterdam), "onderhoud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, ok := c.Match(tt.capcodes, tt.at)
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, w.Name)
+		})
+	}
+
+	// Overlapping windows match in configuration order
+	c = NewCalendar([]Window{
+		{Name: "first", Schedule: exercise, Duration: time.Hour},
+		{Name: "second", Schedule: exercise, Duration: time.Hour},
+	}, zerolog.Nop())
+	w, ok := c.Match([]string{"0101001"}, time.Date(2024, 3, 5, 19, 30, 0, 0, amsterdam))
+	assert.True(t, ok)
+	assert.Equal(t, "first", w.Name)
+}
+
+func TestCalendar_Next(t *testing.T) {
+	exercise, err := ParseCron("0 19 * * tue")
+	require.NoError(t, err)
+	w := Window{Name: "oefenavond", Schedule: exercise, Duration: 3 * time.Hour}
+	c := NewCalendar([]Window{w}, zerolog.Nop())
+
+	next, ok := c.Next(w, time.Date(2024, 3, 5, 19, 0, 0, 0, amsterdam))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 12, 19, 0, 0, 0, amsterdam), next)
+
+	never, err := ParseRRule("FREQ=DAILY;UNTIL=20240101")
+	require.NoError(t, err)
+	_, ok = c.Next(Window{Schedule: never}, time.Date(2024, 3, 5, 0, 0, 0, 0, amsterdam))
+	assert.False(t, ok)
+}
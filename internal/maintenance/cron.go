@@ -0,0 +1,147 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names accepted in the month and day of week fields of a cron expression
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	weekdayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// cronSchedule starts a window at every minute matching a five field cron
+// expression
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays []bool
+	anyDay, anyWeekday                     bool
+}
+
+// ParseCron parses a standard five field cron expression (minute, hour, day
+// of month, month and day of week) such as "0 19 * * tue". Fields may be
+// lists, ranges and steps; months and days of the week may be named. Like
+// cron, a day matches either field when both day fields are restricted.
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	// Sunday is both 0 and 7
+	s.weekdays[0] = s.weekdays[0] || s.weekdays[7]
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+	return &s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+// between min and max, returning the matching values
+func parseCronField(field string, min, max int, names map[string]int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		from, to := min, max
+		if rng != "*" {
+			lo, hi, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = cronValue(lo, min, max, names); err != nil {
+				return nil, err
+			}
+			to = from
+			if isRange {
+				if to, err = cronValue(hi, min, max, names); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				// "5/15" runs from 5 to the end of the range
+				to = max
+			}
+			if to < from {
+				return nil, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a single number or name between min and max
+func cronValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q, must be between %d and %d", s, min, max)
+	}
+	return v, nil
+}
+
+// starts implements Schedule
+func (s *cronSchedule) starts(day time.Time) []time.Time {
+	if !s.months[day.Month()] || !s.matchDay(day) {
+		return nil
+	}
+	var starts []time.Time
+	for h, ok := range s.hours {
+		if !ok {
+			continue
+		}
+		for m, ok := range s.minutes {
+			if ok {
+				starts = append(starts, time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location()))
+			}
+		}
+	}
+	return starts
+}
+
+// matchDay applies the day of month and day of week fields
+func (s *cronSchedule) matchDay(day time.Time) bool {
+	dom, dow := s.days[day.Day()], s.weekdays[day.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return dow
+	case s.anyWeekday:
+		return dom
+	default:
+		return dom || dow
+	}
+}
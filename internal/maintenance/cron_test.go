@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		day      time.Time
+		expected []string
+	}{
+		{"weekly", "0 19 * * 2", date(2024, 3, 5), []string{"19:00"}},
+		{"named weekday", "30 19 * * tue", date(2024, 3, 5), []string{"19:30"}},
+		{"other weekday", "0 19 * * 2", date(2024, 3, 6), nil},
+		{"sunday as 7", "0 10 * * 7", date(2024, 3, 3), []string{"10:00"}},
+		{"steps", "*/30 8-9 * * *", date(2024, 3, 6), []string{"08:00", "08:30", "09:00", "09:30"}},
+		{"list", "0 8,20 1 * *", date(2024, 3, 1), []string{"08:00", "20:00"}},
+		{"named month", "0 12 * mar-apr *", date(2024, 5, 1), nil},
+		{"day of month or weekday", "0 12 15 * mon", date(2024, 3, 4), []string{"12:00"}},
+		{"day of month or weekday", "0 12 15 * mon", date(2024, 3, 15), []string{"12:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, clock(s.starts(tt.day)))
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "0 19 * *", "60 19 * * *", "0 19 * * 8", "0 19-18 * * *", "*/0 * * * *", "0 19 * foo *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+// date returns midnight of a day in Dutch time
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, amsterdam)
+}
+
+// clock formats start times as hh:mm
+func clock(starts []time.Time) []string {
+	var s []string
+	for _, t := range starts {
+		s = append(s, t.Format("15:04"))
+	}
+	return s
+}
+
+var amsterdam = func() *time.Location {
+	loc, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}()
@@ -0,0 +1,344 @@
+package maintenance
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of a recurrence rule
+const (
+	freqDaily   = "DAILY"
+	freqWeekly  = "WEEKLY"
+	freqMonthly = "MONTHLY"
+	freqYearly  = "YEARLY"
+)
+
+// rruleWeekdays maps the two letter days of RFC 5545 to time.Weekday
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// byDay is a BYDAY entry, with ordinal 2 for "2TU" and -1 for "-1FR"
+type byDay struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+// rruleSchedule starts a window at every occurrence of an iCalendar
+// recurrence rule
+type rruleSchedule struct {
+	freq     string
+	interval int
+	start    time.Time // DTSTART, zero when not given
+	floating bool      // DTSTART has no timezone and follows the calendar
+	until    time.Time
+	months   []int
+	monthDay []int
+	days     []byDay
+	hours    []int
+	minutes  []int
+}
+
+// ParseRRule parses an iCalendar recurrence rule (RFC 5545) such as
+// "FREQ=MONTHLY;BYDAY=1MO;BYHOUR=19", optionally preceded by a DTSTART
+// line anchoring INTERVAL and giving the default day and time:
+//
+//	DTSTART:20240102T190000
+//	RRULE:FREQ=WEEKLY;INTERVAL=2
+//
+// FREQ DAILY, WEEKLY, MONTHLY and YEARLY are supported with INTERVAL, UNTIL,
+// BYMONTH, BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE. Windows start on whole
+// minutes, so BYSECOND and the finer frequencies are not.
+func ParseRRule(rule string) (Schedule, error) {
+	s := &rruleSchedule{interval: 1}
+	var parts []string
+	for _, line := range strings.Fields(rule) {
+		name, value, _ := strings.Cut(line, ":")
+		switch {
+		case strings.HasPrefix(strings.ToUpper(name), "DTSTART"):
+			t, floating, err := parseRRuleTime(name, value)
+			if err != nil {
+				return nil, fmt.Errorf("rrule DTSTART: %w", err)
+			}
+			s.start, s.floating = t, floating
+		case strings.EqualFold(name, "RRULE"):
+			parts = append(parts, strings.Split(value, ";")...)
+		default:
+			parts = append(parts, strings.Split(line, ";")...)
+		}
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rrule part %q is not KEY=VALUE", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			s.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			if s.interval, err = strconv.Atoi(value); err != nil || s.interval <= 0 {
+				err = fmt.Errorf("INTERVAL must be a positive number")
+			}
+		case "UNTIL":
+			s.until, _, err = parseRRuleTime("UNTIL", value)
+		case "BYMONTH":
+			s.months, err = parseRRuleInts(value, 1, 12, false)
+		case "BYMONTHDAY":
+			s.monthDay, err = parseRRuleInts(value, 1, 31, true)
+		case "BYHOUR":
+			s.hours, err = parseRRuleInts(value, 0, 23, false)
+		case "BYMINUTE":
+			s.minutes, err = parseRRuleInts(value, 0, 59, false)
+		case "BYDAY":
+			s.days, err = parseByDay(value)
+		case "WKST":
+			// Weeks start on Monday, the default
+			if !strings.EqualFold(value, "MO") {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		case "COUNT":
+			err = fmt.Errorf("COUNT is not supported, use UNTIL")
+		default:
+			err = fmt.Errorf("%s is not supported", strings.ToUpper(key))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: %w", err)
+		}
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("rrule: %w", err)
+	}
+	return s, nil
+}
+
+// validate checks the combination of parts. Missing days and times default
+// to those of DTSTART, the way RFC 5545 does.
+func (s *rruleSchedule) validate() error {
+	switch s.freq {
+	case freqDaily, freqWeekly, freqMonthly, freqYearly:
+	case "":
+		return fmt.Errorf("FREQ is required")
+	default:
+		return fmt.Errorf("FREQ %s is not supported", s.freq)
+	}
+	hasStart := !s.start.IsZero()
+	if s.interval > 1 && !hasStart {
+		return fmt.Errorf("INTERVAL requires DTSTART")
+	}
+	for _, d := range s.days {
+		if d.ordinal != 0 && s.freq != freqMonthly && !(s.freq == freqYearly && len(s.months) > 0) {
+			return fmt.Errorf("numbered BYDAY is only supported with FREQ=MONTHLY, or YEARLY with BYMONTH")
+		}
+	}
+
+	if !hasStart && len(s.days) == 0 && len(s.monthDay) == 0 {
+		switch s.freq {
+		case freqWeekly:
+			return fmt.Errorf("FREQ=WEEKLY requires BYDAY or DTSTART")
+		case freqMonthly, freqYearly:
+			return fmt.Errorf("FREQ=%s requires BYMONTHDAY, BYDAY or DTSTART", s.freq)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns the rule with the days and times it leaves out taken
+// from start, DTSTART in the timezone of the calendar
+func (s *rruleSchedule) withDefaults(start time.Time) rruleSchedule {
+	r := *s
+	if len(r.days) == 0 && len(r.monthDay) == 0 {
+		switch r.freq {
+		case freqWeekly:
+			r.days = []byDay{{weekday: start.Weekday()}}
+		case freqMonthly:
+			r.monthDay = []int{start.Day()}
+		case freqYearly:
+			r.monthDay = []int{start.Day()}
+			if len(r.months) == 0 {
+				r.months = []int{int(start.Month())}
+			}
+		}
+	}
+	if len(r.hours) == 0 {
+		r.hours = []int{start.Hour()}
+	}
+	if len(r.minutes) == 0 {
+		r.minutes = []int{start.Minute()}
+	}
+	return r
+}
+
+// parseRRuleTime parses a DATE or DATE-TIME value, reporting whether it is
+// floating, without a timezone. A TZID parameter of name is honoured.
+func parseRRuleTime(name, value string) (time.Time, bool, error) {
+	location, floating := time.UTC, true
+	for _, param := range strings.Split(name, ";")[1:] {
+		if k, v, _ := strings.Cut(param, "="); strings.EqualFold(k, "TZID") {
+			loc, err := time.LoadLocation(v)
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("unknown timezone %q", v)
+			}
+			location, floating = loc, false
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		value, location, floating = strings.TrimSuffix(value, "Z"), time.UTC, false
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, floating, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q, e.g. 20240102T190000", value)
+}
+
+// parseRRuleInts parses a comma separated list of numbers between min and
+// max, or their negatives counting from the end when negative is allowed
+func parseRRuleInts(value string, min, max int, negative bool) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(s)
+		abs := v
+		if negative && v < 0 {
+			abs = -v
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("invalid value %q, must be between %d and %d", s, min, max)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// parseByDay parses a BYDAY list such as "MO,WE" or "1MO,-1FR"
+func parseByDay(value string) ([]byDay, error) {
+	var days []byDay
+	for _, s := range strings.Split(strings.ToUpper(value), ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		weekday, ok := rruleWeekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		d := byDay{weekday: weekday}
+		if n := s[:len(s)-2]; n != "" {
+			ordinal, err := strconv.Atoi(n)
+			if err != nil || ordinal == 0 || ordinal < -5 || ordinal > 5 {
+				return nil, fmt.Errorf("invalid BYDAY %q", s)
+			}
+			d.ordinal = ordinal
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// starts implements Schedule
+func (s *rruleSchedule) starts(day time.Time) []time.Time {
+	var start time.Time
+	switch {
+	case s.floating:
+		start = time.Date(s.start.Year(), s.start.Month(), s.start.Day(), s.start.Hour(), s.start.Minute(), s.start.Second(), 0, day.Location())
+	case !s.start.IsZero():
+		start = s.start.In(day.Location())
+	}
+	if !s.start.IsZero() && dayNumber(day) < dayNumber(start) {
+		return nil
+	}
+	r := s.withDefaults(start)
+	if !r.matchDay(day, start) {
+		return nil
+	}
+
+	var starts []time.Time
+	for _, h := range r.hours {
+		for _, m := range r.minutes {
+			t := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location())
+			if !s.start.IsZero() && t.Before(start) || !s.until.IsZero() && t.After(s.until) {
+				continue
+			}
+			starts = append(starts, t)
+		}
+	}
+	return starts
+}
+
+// matchDay reports whether day falls in the recurrence
+func (s *rruleSchedule) matchDay(day, start time.Time) bool {
+	if s.interval > 1 && !s.inInterval(day, start) {
+		return false
+	}
+	if len(s.months) > 0 && !slices.Contains(s.months, int(day.Month())) {
+		return false
+	}
+	if len(s.monthDay) > 0 {
+		last := daysIn(day)
+		ok := slices.ContainsFunc(s.monthDay, func(d int) bool {
+			return d == day.Day() || d < 0 && last+d+1 == day.Day()
+		})
+		if !ok {
+			return false
+		}
+	}
+	if len(s.days) > 0 {
+		return slices.ContainsFunc(s.days, func(d byDay) bool { return d.match(day) })
+	}
+	return true
+}
+
+// inInterval reports whether day falls in a period of the rule counted in
+// intervals from start
+func (s *rruleSchedule) inInterval(day, start time.Time) bool {
+	var n int
+	switch s.freq {
+	case freqDaily:
+		n = dayNumber(day) - dayNumber(start)
+	case freqWeekly:
+		n = (weekStart(day) - weekStart(start)) / 7
+	case freqMonthly:
+		n = (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+	case freqYearly:
+		n = day.Year() - start.Year()
+	}
+	return n%s.interval == 0
+}
+
+// match reports whether day is the weekday, and for an ordinal the nth such
+// day of its month, counting from the end when negative
+func (d byDay) match(day time.Time) bool {
+	if day.Weekday() != d.weekday {
+		return false
+	}
+	switch {
+	case d.ordinal > 0:
+		return (day.Day()-1)/7+1 == d.ordinal
+	case d.ordinal < 0:
+		return (daysIn(day)-day.Day())/7+1 == -d.ordinal
+	}
+	return true
+}
+
+// dayNumber counts the days of the date of t since the Unix epoch
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// weekStart returns the day number of the Monday starting the week of t
+func weekStart(t time.Time) int {
+	return dayNumber(t) - (int(t.Weekday())+6)%7
+}
+
+// daysIn returns the number of days in the month of t
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		day      time.Time
+		expected []string
+	}{
+		{"weekly", "FREQ=WEEKLY;BYDAY=TU;BYHOUR=19", date(2024, 3, 5), []string{"19:00"}},
+		{"rrule prefix", "RRULE:FREQ=WEEKLY;BYDAY=TU,TH;BYHOUR=19;BYMINUTE=30", date(2024, 3, 7), []string{"19:30"}},
+		{"other weekday", "FREQ=WEEKLY;BYDAY=TU;BYHOUR=19", date(2024, 3, 6), nil},
+		{"first monday", "FREQ=MONTHLY;BYDAY=1MO;BYHOUR=12", date(2024, 3, 4), []string{"12:00"}},
+		{"second monday", "FREQ=MONTHLY;BYDAY=1MO;BYHOUR=12", date(2024, 3, 11), nil},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR;BYHOUR=20", date(2024, 3, 29), []string{"20:00"}},
+		{"last day", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2024, 2, 29), []string{"00:00"}},
+		{"yearly", "FREQ=YEARLY;BYMONTH=10;BYDAY=2SA;BYHOUR=9", date(2024, 10, 12), []string{"09:00"}},
+		{"daily hours", "FREQ=DAILY;BYHOUR=6,18", date(2024, 3, 6), []string{"06:00", "18:00"}},
+		{"start defaults", "DTSTART:20240305T190000\nRRULE:FREQ=WEEKLY", date(2024, 3, 12), []string{"19:00"}},
+		{"before start", "DTSTART:20240305T190000\nRRULE:FREQ=WEEKLY", date(2024, 2, 27), nil},
+		{"every other week", "DTSTART:20240305T190000\nRRULE:FREQ=WEEKLY;INTERVAL=2", date(2024, 3, 19), []string{"19:00"}},
+		{"off week", "DTSTART:20240305T190000\nRRULE:FREQ=WEEKLY;INTERVAL=2", date(2024, 3, 12), nil},
+		{"until", "FREQ=DAILY;BYHOUR=19;UNTIL=20240310T000000Z", date(2024, 3, 10), nil},
+		{"utc start", "DTSTART:20240305T180000Z\nRRULE:FREQ=DAILY", date(2024, 3, 6), []string{"19:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseRRule(tt.rule)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, clock(s.starts(tt.day)))
+		})
+	}
+}
+
+func TestParseRRule_Invalid(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{"BYDAY=TU", "FREQ is required"},
+		{"FREQ=HOURLY", "FREQ HOURLY is not supported"},
+		{"FREQ=WEEKLY", "requires BYDAY or DTSTART"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "INTERVAL requires DTSTART"},
+		{"FREQ=DAILY;COUNT=5", "COUNT is not supported"},
+		{"FREQ=WEEKLY;BYDAY=1TU", "numbered BYDAY"},
+		{"FREQ=DAILY;BYHOUR=24", "invalid value"},
+		{"FREQ=DAILY;BYSECOND=5", "BYSECOND is not supported"},
+		{"DTSTART:2024\nRRULE:FREQ=DAILY", "invalid date"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRRule(tt.rule)
+		if assert.Error(t, err, tt.rule) {
+			assert.Contains(t, err.Error(), tt.expected)
+		}
+	}
+}
@@ -1,56 +1,320 @@
 package metrics
 
 import (
+	"errors"
+
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 // Metrics holds all Prometheus metrics for the application
 type Metrics struct {
-	MessagesReceived    prometheus.Counter
-	MessagesFiltered    prometheus.Counter
-	NotificationsSent   prometheus.Counter
-	NotificationsFailed prometheus.Counter
-	NotificationDuration prometheus.Histogram
-	WebsocketConnected  prometheus.Gauge
+	MessagesReceived       prometheus.Counter
+	MessagesFiltered       prometheus.Counter
+	MessageSeverities      *prometheus.CounterVec
+	NotificationsSent      prometheus.Counter
+	NotificationsFailed    prometheus.Counter
+	NotificationDuration   prometheus.Histogram
+	NotificationLatency    prometheus.Histogram
+	FeedLag                prometheus.Gauge
+	WebsocketConnected     prometheus.Gauge
+	WebsocketReconnects    prometheus.Counter
+	WebsocketDialAttempts  prometheus.Counter
+	WebsocketDialFailures  prometheus.Counter
+	WebsocketReadErrors    prometheus.Counter
+	WebsocketParseFailures prometheus.Counter
+	WebsocketIdleTimeouts  prometheus.Counter
+	WebsocketBackoff       prometheus.Gauge
+	CapcodeLookupAvailable prometheus.Gauge
+	BufferDepth            prometheus.Gauge
+	BufferDropped          *prometheus.CounterVec
+	QueueDepth             prometheus.Gauge
+	NotificationsDropped   prometheus.Counter
+	NotificationsPaused    prometheus.Counter
+	NotificationsMuted     *prometheus.CounterVec
+	BackendSent            *prometheus.CounterVec
+	BackendFailed          *prometheus.CounterVec
+	BackendDuration        *prometheus.HistogramVec
+	CircuitBreakerState    *prometheus.GaugeVec
+	Acknowledgements       prometheus.Counter
+	AcknowledgementLatency prometheus.Histogram
+	Escalations            prometheus.Counter
+	GeocodeLookups         *prometheus.CounterVec
+	Translations           *prometheus.CounterVec
+	EnrichmentErrors       *prometheus.CounterVec
+	SelfHeals              *prometheus.CounterVec
+	RuleMatches            *prometheus.CounterVec
+	ScriptErrors           prometheus.Counter
+	TestAlarms             *prometheus.CounterVec
+	MaintenanceMessages    *prometheus.CounterVec
+	GRIPLevel              prometheus.Gauge
+	ReanimationAlerts      *prometheus.CounterVec
+	IncidentUpdates        prometheus.Counter
+	BatchedPages           prometheus.Counter
+	DuplicateMessages      prometheus.Counter
+	StreamEvents           *prometheus.CounterVec
+	GRPCMessages           *prometheus.CounterVec
+	GRPCSubscribers        prometheus.Gauge
+	PostgresWrites         *prometheus.CounterVec
+	InfluxPoints           *prometheus.CounterVec
+	ElasticsearchDocuments *prometheus.CounterVec
+	Claims                 *prometheus.CounterVec
+	ShardSkipped           prometheus.Counter
+
+	SubscriptionNotifications *prometheus.CounterVec
+	Subscriptions             prometheus.Gauge
 }
 
-// NewMetrics creates and registers all Prometheus metrics
+// NewMetrics creates and registers all Prometheus metrics. Metrics registered
+// by a previous call are replaced, so it is safe to call more than once.
 func NewMetrics() *Metrics {
 	return &Metrics{
-		MessagesReceived: promauto.NewCounter(prometheus.CounterOpts{
+		MessagesReceived: register(prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "p2000_messages_received_total",
 			Help: "Total number of P2000 messages received from WebSocket",
-		}),
-		MessagesFiltered: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		MessagesFiltered: register(prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "p2000_messages_filtered_total",
 			Help: "Total number of P2000 messages that matched capcode filters",
-		}),
-		NotificationsSent: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		MessageSeverities: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_message_severity_total",
+			Help: "Total number of P2000 messages received by classified severity (low, medium, high, critical, unknown)",
+		}, []string{"severity"})),
+		NotificationsSent: register(prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "p2000_notifications_sent_total",
 			Help: "Total number of notifications successfully sent to ntfy",
-		}),
-		NotificationsFailed: promauto.NewCounter(prometheus.CounterOpts{
+		})),
+		NotificationsFailed: register(prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "p2000_notifications_failed_total",
 			Help: "Total number of notifications that failed to send",
-		}),
-		NotificationDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+		})),
+		NotificationDuration: register(prometheus.NewHistogram(prometheus.HistogramOpts{
 			Name:    "p2000_notification_duration_seconds",
 			Help:    "Duration of notification sending in seconds",
 			Buckets: prometheus.DefBuckets,
-		}),
-		WebsocketConnected: promauto.NewGauge(prometheus.GaugeOpts{
+		})),
+		NotificationLatency: register(prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "p2000_notification_latency_seconds",
+			Help:    "Time from the page timestamp to the successful notification",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+		})),
+		FeedLag: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_feed_lag_seconds",
+			Help: "Time between the page timestamp and receiving the latest message",
+		})),
+		WebsocketConnected: register(prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "p2000_websocket_connected",
 			Help: "WebSocket connection status (1 = connected, 0 = disconnected)",
-		}),
+		})),
+		WebsocketReconnects: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_reconnects_total",
+			Help: "Total number of WebSocket reconnections after a connection loss",
+		})),
+		WebsocketDialAttempts: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_dial_attempts_total",
+			Help: "Total number of attempts to connect to the WebSocket feed",
+		})),
+		WebsocketDialFailures: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_dial_failures_total",
+			Help: "Total number of failed attempts to connect to the WebSocket feed",
+		})),
+		WebsocketReadErrors: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_read_errors_total",
+			Help: "Total number of WebSocket connections lost by a read error or timeout",
+		})),
+		WebsocketParseFailures: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_parse_failures_total",
+			Help: "Total number of WebSocket frames that are not valid messages",
+		})),
+		WebsocketIdleTimeouts: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_websocket_idle_timeouts_total",
+			Help: "Total number of WebSocket connections dropped by the watchdog after receiving no data",
+		})),
+		WebsocketBackoff: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_websocket_backoff_seconds",
+			Help: "Delay before the next WebSocket connection attempt, 0 while connected",
+		})),
+		CapcodeLookupAvailable: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_capcode_lookup_available",
+			Help: "Capcode database status (1 = loaded, 0 = unavailable)",
+		})),
+		BufferDepth: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_message_buffer_depth",
+			Help: "Number of received messages waiting in the buffer to be processed",
+		})),
+		BufferDropped: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_message_buffer_dropped_total",
+			Help: "Total number of received messages dropped from the buffer, by reason (oldest, newest, closed)",
+		}, []string{"reason"})),
+		QueueDepth: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_notification_queue_depth",
+			Help: "Number of notifications waiting in the queue",
+		})),
+		NotificationsDropped: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_notifications_dropped_total",
+			Help: "Total number of notifications dropped because the queue was full or not drained",
+		})),
+		NotificationsPaused: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_notifications_paused_total",
+			Help: "Total number of messages not notified because sending was paused",
+		})),
+		NotificationsMuted: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_notifications_muted_total",
+			Help: "Total number of messages not notified because a capcode or rule was muted",
+		}, []string{"kind"})),
+		CircuitBreakerState: register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "p2000_circuit_breaker_state",
+			Help: "Circuit breaker state per notification destination (0 = closed, 1 = half-open, 2 = open)",
+		}, []string{"destination"})),
+		Acknowledgements: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_acknowledgements_total",
+			Help: "Total number of messages acknowledged for the first time",
+		})),
+		AcknowledgementLatency: register(prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "p2000_acknowledgement_latency_seconds",
+			Help:    "Time between receiving a message and its first acknowledgement",
+			Buckets: []float64{10, 30, 60, 120, 300, 600, 1800, 3600},
+		})),
+		Escalations: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_escalations_total",
+			Help: "Total number of messages re-notified because they were not acknowledged in time",
+		})),
+		GeocodeLookups: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_geocode_lookups_total",
+			Help: "Total number of address lookups by result (bag, cached, found, not_found, error)",
+		}, []string{"result"})),
+		Translations: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_translations_total",
+			Help: "Total number of message translations by result (cached, translated, error)",
+		}, []string{"result"})),
+		EnrichmentErrors: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_enrichment_errors_total",
+			Help: "Total number of messages sent without the context of a failing enricher",
+		}, []string{"enricher"})),
+		BackendSent: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_backend_notifications_sent_total",
+			Help: "Total number of notifications successfully sent per destination",
+		}, []string{"backend"})),
+		BackendFailed: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_backend_notifications_failed_total",
+			Help: "Total number of notifications that failed to send per destination",
+		}, []string{"backend"})),
+		BackendDuration: register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "p2000_backend_notification_duration_seconds",
+			Help:    "Duration of notification sending per destination in seconds, including retries",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"backend"})),
+		SelfHeals: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_self_heals_total",
+			Help: "Total number of restarts of a stale message source",
+		}, []string{"source"})),
+		RuleMatches: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_rule_matches_total",
+			Help: "Total number of messages matching each routing rule",
+		}, []string{"rule"})),
+		ScriptErrors: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_script_errors_total",
+			Help: "Total number of messages left unchanged by a failing message script",
+		})),
+		TestAlarms: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_test_alarms_total",
+			Help: "Total number of detected test pages by action (label, downgrade, drop)",
+		}, []string{"action"})),
+		MaintenanceMessages: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_maintenance_messages_total",
+			Help: "Total number of messages sent during a maintenance window by window and action (suppress, label)",
+		}, []string{"window", "action"})),
+		GRIPLevel: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_grip_level",
+			Help: "Highest GRIP level announced within the GRIP window, 0 when none",
+		})),
+		ReanimationAlerts: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_reanimation_alerts_total",
+			Help: "Total number of forwarded resuscitation calls by result (alerted, out_of_range)",
+		}, []string{"result"})),
+		DuplicateMessages: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_duplicate_messages_total",
+			Help: "Total number of dropped copies of an earlier received message",
+		})),
+		IncidentUpdates: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_incident_updates_total",
+			Help: "Total number of forwarded follow-up pages of an earlier incident",
+		})),
+		BatchedPages: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_batched_pages_total",
+			Help: "Total number of pages combined into the notification of an earlier page of their incident",
+		})),
+		StreamEvents: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_stream_events_total",
+			Help: "Total number of messages for the NATS stream by result (published, failed, dropped)",
+		}, []string{"result"})),
+		GRPCMessages: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_grpc_messages_total",
+			Help: "Total number of messages for gRPC subscribers by result (sent, dropped)",
+		}, []string{"result"})),
+		GRPCSubscribers: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_grpc_subscribers",
+			Help: "Number of open gRPC Subscribe calls",
+		})),
+		PostgresWrites: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_postgres_writes_total",
+			Help: "Total number of rows for PostgreSQL by table and result (written, failed, dropped)",
+		}, []string{"table", "result"})),
+		InfluxPoints: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_influx_points_total",
+			Help: "Total number of message points for InfluxDB by result (written, failed, dropped)",
+		}, []string{"result"})),
+		ElasticsearchDocuments: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_elasticsearch_documents_total",
+			Help: "Total number of messages for Elasticsearch by result (indexed, failed, dropped)",
+		}, []string{"result"})),
+		Claims: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_ha_claims_total",
+			Help: "Total number of message claims in high-availability mode by result (claimed, skipped, error)",
+		}, []string{"result"})),
+		ShardSkipped: register(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "p2000_shard_skipped_total",
+			Help: "Total number of received messages belonging to the shard of another instance",
+		})),
+		SubscriptionNotifications: register(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "p2000_subscription_notifications_total",
+			Help: "Total number of notifications to self-service subscriptions by result (sent, failed)",
+		}, []string{"result"})),
+		Subscriptions: register(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "p2000_subscriptions",
+			Help: "Number of registered self-service subscriptions",
+		})),
 	}
 }
 
+// register registers c with the default registry, replacing a collector
+// with the same description that was registered before
+func register[T prometheus.Collector](c T) T {
+	if err := prometheus.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+		prometheus.Unregister(are.ExistingCollector)
+		prometheus.MustRegister(c)
+	}
+	return c
+}
+
 // RecordMessageReceived increments the messages received counter
 func (m *Metrics) RecordMessageReceived() {
 	m.MessagesReceived.Inc()
 }
 
+// RecordMessageSeverity counts a received message by its classified
+// severity, unknown when none was recognized
+func (m *Metrics) RecordMessageSeverity(severity string) {
+	if severity == "" {
+		severity = "unknown"
+	}
+	m.MessageSeverities.WithLabelValues(severity).Inc()
+}
+
 // RecordMessageFiltered increments the filtered messages counter
 func (m *Metrics) RecordMessageFiltered() {
 	m.MessagesFiltered.Inc()
@@ -66,6 +330,28 @@ func (m *Metrics) RecordNotificationFailed() {
 	m.NotificationsFailed.Inc()
 }
 
+// RecordBackendDelivery counts a notification sent to or failed at a
+// destination and observes how long the delivery took
+func (m *Metrics) RecordBackendDelivery(backend string, success bool, duration float64) {
+	if success {
+		m.BackendSent.WithLabelValues(backend).Inc()
+	} else {
+		m.BackendFailed.WithLabelValues(backend).Inc()
+	}
+	m.BackendDuration.WithLabelValues(backend).Observe(duration)
+}
+
+// RecordNotificationLatency observes the end-to-end delay of a notification
+// in seconds
+func (m *Metrics) RecordNotificationLatency(latency float64) {
+	m.NotificationLatency.Observe(latency)
+}
+
+// SetFeedLag sets the delay of the latest received message in seconds
+func (m *Metrics) SetFeedLag(lag float64) {
+	m.FeedLag.Set(lag)
+}
+
 // SetWebsocketConnected sets the WebSocket connection status
 func (m *Metrics) SetWebsocketConnected(connected bool) {
 	if connected {
@@ -74,3 +360,217 @@ func (m *Metrics) SetWebsocketConnected(connected bool) {
 		m.WebsocketConnected.Set(0)
 	}
 }
+
+// RecordWebsocketReconnect increments the WebSocket reconnects counter
+func (m *Metrics) RecordWebsocketReconnect() {
+	m.WebsocketReconnects.Inc()
+}
+
+// RecordWebsocketDial counts a WebSocket connection attempt and whether it
+// failed
+func (m *Metrics) RecordWebsocketDial(failed bool) {
+	m.WebsocketDialAttempts.Inc()
+	if failed {
+		m.WebsocketDialFailures.Inc()
+	}
+}
+
+// RecordWebsocketReadError increments the WebSocket read errors counter
+func (m *Metrics) RecordWebsocketReadError() {
+	m.WebsocketReadErrors.Inc()
+}
+
+// RecordWebsocketParseFailure increments the WebSocket parse failures
+// counter
+func (m *Metrics) RecordWebsocketParseFailure() {
+	m.WebsocketParseFailures.Inc()
+}
+
+// RecordWebsocketIdleTimeout increments the WebSocket idle timeouts counter
+func (m *Metrics) RecordWebsocketIdleTimeout() {
+	m.WebsocketIdleTimeouts.Inc()
+}
+
+// SetWebsocketBackoff sets the delay before the next connection attempt
+func (m *Metrics) SetWebsocketBackoff(backoff float64) {
+	m.WebsocketBackoff.Set(backoff)
+}
+
+// SetCapcodeLookupAvailable sets the capcode database status
+func (m *Metrics) SetCapcodeLookupAvailable(available bool) {
+	if available {
+		m.CapcodeLookupAvailable.Set(1)
+	} else {
+		m.CapcodeLookupAvailable.Set(0)
+	}
+}
+
+// SetBufferDepth sets the number of buffered received messages
+func (m *Metrics) SetBufferDepth(depth int) {
+	m.BufferDepth.Set(float64(depth))
+}
+
+// RecordBufferDropped counts a received message dropped from the buffer
+func (m *Metrics) RecordBufferDropped(reason string) {
+	m.BufferDropped.WithLabelValues(reason).Inc()
+}
+
+// SetQueueDepth sets the number of queued notifications
+func (m *Metrics) SetQueueDepth(depth int) {
+	m.QueueDepth.Set(float64(depth))
+}
+
+// RecordNotificationDropped increments the dropped notifications counter
+func (m *Metrics) RecordNotificationDropped() {
+	m.NotificationsDropped.Inc()
+}
+
+// RecordNotificationPaused counts a message not notified while sending was
+// paused
+func (m *Metrics) RecordNotificationPaused() {
+	m.NotificationsPaused.Inc()
+}
+
+// RecordNotificationMuted counts a message not notified because of a mute
+// of kind capcode or rule
+func (m *Metrics) RecordNotificationMuted(kind string) {
+	m.NotificationsMuted.WithLabelValues(kind).Inc()
+}
+
+// SetCircuitBreakerState sets the circuit breaker state of a destination
+// (0 = closed, 1 = half-open, 2 = open)
+func (m *Metrics) SetCircuitBreakerState(destination string, state int) {
+	m.CircuitBreakerState.WithLabelValues(destination).Set(float64(state))
+}
+
+// RecordAcknowledgement counts a first acknowledgement and its latency in
+// seconds
+func (m *Metrics) RecordAcknowledgement(latency float64) {
+	m.Acknowledgements.Inc()
+	m.AcknowledgementLatency.Observe(latency)
+}
+
+// RecordEscalation increments the escalations counter
+func (m *Metrics) RecordEscalation() {
+	m.Escalations.Inc()
+}
+
+// RecordGeocodeLookup counts an address lookup by its result
+func (m *Metrics) RecordGeocodeLookup(result string) {
+	m.GeocodeLookups.WithLabelValues(result).Inc()
+}
+
+// RecordTranslation counts a message translation by its result
+func (m *Metrics) RecordTranslation(result string) {
+	m.Translations.WithLabelValues(result).Inc()
+}
+
+// RecordEnrichmentError counts a message an enricher failed to add its
+// context to
+func (m *Metrics) RecordEnrichmentError(enricher string) {
+	m.EnrichmentErrors.WithLabelValues(enricher).Inc()
+}
+
+// RecordSelfHeal counts a restart of the stale message source
+func (m *Metrics) RecordSelfHeal(source string) {
+	m.SelfHeals.WithLabelValues(source).Inc()
+}
+
+// RecordScriptError counts a message the message script failed on
+func (m *Metrics) RecordScriptError() {
+	m.ScriptErrors.Inc()
+}
+
+// RecordRuleMatch counts a message matching a routing rule
+func (m *Metrics) RecordRuleMatch(rule string) {
+	m.RuleMatches.WithLabelValues(rule).Inc()
+}
+
+// RecordTestAlarm counts a detected test page by the action taken
+func (m *Metrics) RecordTestAlarm(action string) {
+	m.TestAlarms.WithLabelValues(action).Inc()
+}
+
+// RecordMaintenanceMessage counts a message sent during a maintenance
+// window by the action taken
+func (m *Metrics) RecordMaintenanceMessage(window, action string) {
+	m.MaintenanceMessages.WithLabelValues(window, action).Inc()
+}
+
+// SetGRIPLevel sets the highest active GRIP level
+func (m *Metrics) SetGRIPLevel(level int) {
+	m.GRIPLevel.Set(float64(level))
+}
+
+// RecordReanimationAlert counts a forwarded resuscitation call by whether
+// it was alerted in the reanimation mode
+func (m *Metrics) RecordReanimationAlert(result string) {
+	m.ReanimationAlerts.WithLabelValues(result).Inc()
+}
+
+// RecordDuplicateMessage increments the dropped duplicate messages counter
+func (m *Metrics) RecordDuplicateMessage() {
+	m.DuplicateMessages.Inc()
+}
+
+// RecordIncidentUpdate increments the incident follow-up pages counter
+func (m *Metrics) RecordIncidentUpdate() {
+	m.IncidentUpdates.Inc()
+}
+
+// RecordBatchedPages adds pages combined into an earlier notification
+func (m *Metrics) RecordBatchedPages(n int) {
+	m.BatchedPages.Add(float64(n))
+}
+
+// RecordStreamEvent counts a message for the stream by its result
+func (m *Metrics) RecordStreamEvent(result string) {
+	m.StreamEvents.WithLabelValues(result).Inc()
+}
+
+// RecordGRPCMessage counts a message for a gRPC subscriber by its result
+func (m *Metrics) RecordGRPCMessage(result string) {
+	m.GRPCMessages.WithLabelValues(result).Inc()
+}
+
+// SetGRPCSubscribers sets the number of open gRPC Subscribe calls
+func (m *Metrics) SetGRPCSubscribers(n int) {
+	m.GRPCSubscribers.Set(float64(n))
+}
+
+// RecordPostgresWrite counts a row for PostgreSQL by its table and result
+func (m *Metrics) RecordPostgresWrite(table, result string) {
+	m.PostgresWrites.WithLabelValues(table, result).Inc()
+}
+
+// RecordInfluxPoints counts n points for InfluxDB by their result
+func (m *Metrics) RecordInfluxPoints(result string, n int) {
+	m.InfluxPoints.WithLabelValues(result).Add(float64(n))
+}
+
+// RecordElasticsearchDocuments counts n messages for Elasticsearch by their
+// result
+func (m *Metrics) RecordElasticsearchDocuments(result string, n int) {
+	m.ElasticsearchDocuments.WithLabelValues(result).Add(float64(n))
+}
+
+// RecordClaim counts a message claim by its result
+func (m *Metrics) RecordClaim(result string) {
+	m.Claims.WithLabelValues(result).Inc()
+}
+
+// RecordShardSkipped increments the messages of other shards counter
+func (m *Metrics) RecordShardSkipped() {
+	m.ShardSkipped.Inc()
+}
+
+// RecordSubscriptionNotification counts a notification to a subscription by
+// its result
+func (m *Metrics) RecordSubscriptionNotification(result string) {
+	m.SubscriptionNotifications.WithLabelValues(result).Inc()
+}
+
+// SetSubscriptions sets the number of registered subscriptions
+func (m *Metrics) SetSubscriptions(count int) {
+	m.Subscriptions.Set(float64(count))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -171,6 +172,141 @@ func TestSetWebsocketConnected(t *testing.T) {
 	assert.Equal(t, 0.0, testutil.ToFloat64(m.WebsocketConnected))
 }
 
+func TestSetCapcodeLookupAvailable(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "test_capcode_lookup_available",
+		Help: "Test gauge",
+	})
+
+	m := &Metrics{
+		CapcodeLookupAvailable: gauge,
+	}
+
+	m.SetCapcodeLookupAvailable(true)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CapcodeLookupAvailable))
+
+	m.SetCapcodeLookupAvailable(false)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.CapcodeLookupAvailable))
+}
+
+func TestRecordWebsocketReconnect(t *testing.T) {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "test_websocket_reconnects_total",
+		Help: "Test counter",
+	})
+
+	m := &Metrics{
+		WebsocketReconnects: counter,
+	}
+
+	m.RecordWebsocketReconnect()
+	m.RecordWebsocketReconnect()
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.WebsocketReconnects))
+}
+
+func TestSetQueueDepth(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "test_notification_queue_depth",
+		Help: "Test gauge",
+	})
+
+	m := &Metrics{
+		QueueDepth: gauge,
+	}
+
+	m.SetQueueDepth(7)
+	assert.Equal(t, 7.0, testutil.ToFloat64(m.QueueDepth))
+
+	m.SetQueueDepth(0)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.QueueDepth))
+}
+
+func TestRecordNotificationDropped(t *testing.T) {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "test_notifications_dropped_total",
+		Help: "Test counter",
+	})
+
+	m := &Metrics{
+		NotificationsDropped: counter,
+	}
+
+	m.RecordNotificationDropped()
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.NotificationsDropped))
+}
+
+func TestSetCircuitBreakerState(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_circuit_breaker_state",
+		Help: "Test gauge",
+	}, []string{"destination"})
+
+	m := &Metrics{
+		CircuitBreakerState: gauge,
+	}
+
+	m.SetCircuitBreakerState("https://ntfy.sh/a", 2)
+	m.SetCircuitBreakerState("https://ntfy.sh/b", 0)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.CircuitBreakerState.WithLabelValues("https://ntfy.sh/a")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.CircuitBreakerState.WithLabelValues("https://ntfy.sh/b")))
+}
+
+func TestRecordGeocodeLookup(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_geocode_lookups_total",
+		Help: "Test counter",
+	}, []string{"result"})
+
+	m := &Metrics{
+		GeocodeLookups: counter,
+	}
+
+	m.RecordGeocodeLookup("found")
+	m.RecordGeocodeLookup("cached")
+	m.RecordGeocodeLookup("cached")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.GeocodeLookups.WithLabelValues("found")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.GeocodeLookups.WithLabelValues("cached")))
+}
+
+func TestRecordAcknowledgement(t *testing.T) {
+	m := &Metrics{
+		Acknowledgements: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "test_acknowledgements_total",
+			Help: "Test counter",
+		}),
+		AcknowledgementLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "test_acknowledgement_latency_seconds",
+			Help: "Test histogram",
+		}),
+		Escalations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "test_escalations_total",
+			Help: "Test counter",
+		}),
+	}
+
+	m.RecordAcknowledgement(42)
+	m.RecordEscalation()
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Acknowledgements))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Escalations))
+
+	var metric dto.Metric
+	require.NoError(t, m.AcknowledgementLatency.Write(&metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+	assert.Equal(t, 42.0, metric.GetHistogram().GetSampleSum())
+}
+
+func TestNewMetrics_ReplacesRegisteredCollectors(t *testing.T) {
+	NewMetrics().RecordMessageReceived()
+	m := NewMetrics()
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.MessagesReceived))
+
+	m.RecordMessageReceived()
+	count, err := testutil.GatherAndCount(prometheus.DefaultGatherer, "p2000_messages_received_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
 func TestNotificationDurationHistogram(t *testing.T) {
 	registry := prometheus.NewRegistry()
 
@@ -314,8 +450,10 @@ func TestMetrics_MultipleNotificationDurations(t *testing.T) {
 		m.NotificationDuration.Observe(d)
 	}
 
-	count := testutil.ToFloat64(m.NotificationDuration)
-	assert.Equal(t, float64(len(durations)), count)
+	var metric dto.Metric
+	require.NoError(t, m.NotificationDuration.Write(&metric))
+	count := metric.GetHistogram().GetSampleCount()
+	assert.Equal(t, uint64(len(durations)), count)
 }
 
 func BenchmarkRecordMessageReceived(b *testing.B) {
@@ -375,3 +513,76 @@ func BenchmarkConcurrentMetrics(b *testing.B) {
 		}
 	})
 }
+
+func TestRecordRuleMatch(t *testing.T) {
+	m := &Metrics{
+		RuleMatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_rule_matches_total",
+			Help: "Test counter",
+		}, []string{"rule"}),
+	}
+
+	m.RecordRuleMatch("fire-utrecht")
+	m.RecordRuleMatch("fire-utrecht")
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.RuleMatches.WithLabelValues("fire-utrecht")))
+}
+
+func TestRecordTestAlarm(t *testing.T) {
+	m := &Metrics{
+		TestAlarms: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_test_alarms_total",
+			Help: "Test counter",
+		}, []string{"action"}),
+	}
+
+	m.RecordTestAlarm("drop")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.TestAlarms.WithLabelValues("drop")))
+}
+
+func TestRecordIncidentUpdate(t *testing.T) {
+	m := &Metrics{
+		IncidentUpdates: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "test_incident_updates_total",
+			Help: "Test counter",
+		}),
+	}
+
+	m.RecordIncidentUpdate()
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.IncidentUpdates))
+}
+
+func TestRecordNotificationLatency(t *testing.T) {
+	m := &Metrics{
+		NotificationLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "test_notification_latency_seconds",
+			Help: "Test histogram",
+		}),
+		FeedLag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "test_feed_lag_seconds",
+			Help: "Test gauge",
+		}),
+	}
+
+	m.RecordNotificationLatency(3.5)
+	m.SetFeedLag(2)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.FeedLag))
+
+	var metric dto.Metric
+	require.NoError(t, m.NotificationLatency.Write(&metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+	assert.Equal(t, 3.5, metric.GetHistogram().GetSampleSum())
+}
+
+func TestRecordBackendDelivery(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordBackendDelivery("pager", true, 0.2)
+	m.RecordBackendDelivery("pager", false, 1.5)
+	m.RecordBackendDelivery("ntfy", true, 0.1)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.BackendSent.WithLabelValues("pager")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.BackendFailed.WithLabelValues("pager")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.BackendSent.WithLabelValues("ntfy")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.BackendFailed.WithLabelValues("ntfy")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.BackendDuration, "p2000_backend_notification_duration_seconds"))
+}
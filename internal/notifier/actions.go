@@ -0,0 +1,140 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// maxActions is the number of action buttons ntfy accepts per notification
+const maxActions = 3
+
+// Action is an ntfy action button. URL and Body are Go text/template
+// sources executed with TemplateData.
+type Action struct {
+	Action  string            // view, http or broadcast
+	Label   string            // Button text
+	URL     string            // Opened by view actions, requested by http actions
+	Method  string            // HTTP method of http actions, ntfy defaults to POST
+	Headers map[string]string // HTTP headers of http actions
+	Body    string            // HTTP body of http actions
+	Clear   bool              // Dismiss the notification after the action succeeded
+}
+
+// Actions renders the action buttons of a notification
+type Actions struct {
+	actions []parsedAction
+}
+
+type parsedAction struct {
+	action Action
+	url    *template.Template
+	body   *template.Template
+}
+
+// actionJSON is the ntfy JSON representation of an action
+type actionJSON struct {
+	Action  string            `json:"action"`
+	Label   string            `json:"label"`
+	URL     string            `json:"url,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Clear   bool              `json:"clear,omitempty"`
+}
+
+// ParseActions validates actions and parses their templates. No actions
+// disables action buttons.
+func ParseActions(actions []Action) (*Actions, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	if len(actions) > maxActions {
+		return nil, fmt.Errorf("at most %d actions are supported, got %d", maxActions, len(actions))
+	}
+
+	a := &Actions{}
+	for _, action := range actions {
+		action.Action = strings.ToLower(action.Action)
+		switch action.Action {
+		case "view", "http":
+			if action.URL == "" {
+				return nil, fmt.Errorf("%s action %q requires a url", action.Action, action.Label)
+			}
+		case "broadcast":
+		default:
+			return nil, fmt.Errorf("unknown action type %q", action.Action)
+		}
+		if action.Label == "" {
+			return nil, fmt.Errorf("%s action requires a label", action.Action)
+		}
+
+		p := parsedAction{action: action}
+		var err error
+		if p.url, err = template.New(action.Label).Funcs(templateFuncs).Parse(action.URL); err != nil {
+			return nil, fmt.Errorf("invalid url template of action %q: %w", action.Label, err)
+		}
+		if p.body, err = template.New(action.Label).Funcs(templateFuncs).Parse(action.Body); err != nil {
+			return nil, fmt.Errorf("invalid body template of action %q: %w", action.Label, err)
+		}
+		a.actions = append(a.actions, p)
+	}
+	return a, nil
+}
+
+// SetActions configures the action buttons added to notifications
+func (n *Notifier) SetActions(a *Actions) {
+	n.actions = a
+}
+
+// actionsHeader renders the ntfy Actions header for msg. Actions whose
+// templates fail or render an empty url, e.g. a map link for a message that
+// was not geocoded, are left out.
+func (n *Notifier) actionsHeader(msg model.Message) string {
+	if n.actions == nil {
+		return ""
+	}
+
+	data := n.templateData(msg)
+	var rendered []actionJSON
+	for _, p := range n.actions.actions {
+		a := p.action
+		var url, body bytes.Buffer
+		if err := p.url.Execute(&url, data); err != nil {
+			n.logger.Debug().Err(err).Str("action", a.Label).Msg("skipping action")
+			continue
+		}
+		if err := p.body.Execute(&body, data); err != nil {
+			n.logger.Debug().Err(err).Str("action", a.Label).Msg("skipping action")
+			continue
+		}
+		if a.Action != "broadcast" && strings.TrimSpace(url.String()) == "" {
+			continue
+		}
+
+		rendered = append(rendered, actionJSON{
+			Action:  a.Action,
+			Label:   a.Label,
+			URL:     strings.TrimSpace(url.String()),
+			Method:  a.Method,
+			Headers: a.Headers,
+			Body:    body.String(),
+			Clear:   a.Clear,
+		})
+	}
+	if len(rendered) == 0 {
+		return ""
+	}
+
+	// ntfy accepts a JSON array in the Actions header, which avoids quoting
+	// commas and semicolons of the simple format
+	header, err := json.Marshal(rendered)
+	if err != nil {
+		return ""
+	}
+	return string(header)
+}
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActions_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		actions  []Action
+		errorMsg string
+	}{
+		{
+			name:    "View action",
+			actions: []Action{{Action: "VIEW", Label: "Map", URL: "https://example.com"}},
+		},
+		{
+			name:     "Unknown type",
+			actions:  []Action{{Action: "call", Label: "Call"}},
+			errorMsg: `unknown action type "call"`,
+		},
+		{
+			name:     "Missing url",
+			actions:  []Action{{Action: "http", Label: "Ack"}},
+			errorMsg: `http action "Ack" requires a url`,
+		},
+		{
+			name:     "Missing label",
+			actions:  []Action{{Action: "broadcast"}},
+			errorMsg: "broadcast action requires a label",
+		},
+		{
+			name:     "Invalid template",
+			actions:  []Action{{Action: "view", Label: "Map", URL: "{{.Message"}},
+			errorMsg: `invalid url template of action "Map"`,
+		},
+		{
+			name: "Too many actions",
+			actions: []Action{
+				{Action: "broadcast", Label: "1"},
+				{Action: "broadcast", Label: "2"},
+				{Action: "broadcast", Label: "3"},
+				{Action: "broadcast", Label: "4"},
+			},
+			errorMsg: "at most 3 actions are supported, got 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseActions(tt.actions)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.errorMsg)
+			}
+		})
+	}
+
+	actions, err := ParseActions(nil)
+	require.NoError(t, err)
+	assert.Nil(t, actions)
+}
+
+func TestSend_Actions(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("Actions"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	actions, err := ParseActions([]Action{
+		{
+			Action: "view",
+			Label:  "Bekijk op kaart",
+			URL:    "https://www.openstreetmap.org/?mlat={{.Message.Coordinates.Lat}}&mlon={{.Message.Coordinates.Lon}}",
+		},
+		{
+			Action:  "http",
+			Label:   "Bevestigen",
+			URL:     "https://forwarder.example.com/api/ack/{{.Message.ID}}",
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+			Body:    `{"capcodes":"{{join .Message.Capcodes ","}}"}`,
+			Clear:   true,
+		},
+	})
+	require.NoError(t, err)
+
+	n, err := New(Options{Server: server.URL, Topic: "p2000", Actions: actions, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	geocoded := model.Message{
+		ID:          "42",
+		Message:     "A1 Damrak Amsterdam",
+		Capcodes:    []string{"0101001", "0101002"},
+		Coordinates: &model.Coordinates{Lat: 52.3757, Lon: 4.8971},
+	}
+	require.NoError(t, n.Send(context.Background(), geocoded))
+	require.NoError(t, n.Send(context.Background(), model.Message{ID: "43", Message: "A2 Amsterdam"}))
+	require.Len(t, headers, 2)
+
+	var got []actionJSON
+	require.NoError(t, json.Unmarshal([]byte(headers[0]), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, actionJSON{
+		Action: "view",
+		Label:  "Bekijk op kaart",
+		URL:    "https://www.openstreetmap.org/?mlat=52.3757&mlon=4.8971",
+	}, got[0])
+	assert.Equal(t, actionJSON{
+		Action:  "http",
+		Label:   "Bevestigen",
+		URL:     "https://forwarder.example.com/api/ack/42",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Body:    `{"capcodes":"0101001,0101002"}`,
+		Clear:   true,
+	}, got[1])
+
+	// The map action is left out when the message was not geocoded
+	require.NoError(t, json.Unmarshal([]byte(headers[1]), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "https://forwarder.example.com/api/ack/43", got[0].URL)
+}
+
+func TestSend_NoActions(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Actions")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(server.URL, "p2000", "", "", "", nil, nil, getTestLogger())
+	require.NoError(t, n.Send(context.Background(), model.Message{Message: "A1 Test"}))
+	assert.Empty(t, header)
+}
@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	aprsToCall       = "APZP2K" // Destination field of experimental APRS software
+	aprsMaxLength    = 67       // Characters in the text of an APRS message
+	aprsSoftware     = "p2000-nfty"
+	aprsVersion      = "1.0"
+	aprsMaxAddressee = 9 // Characters in an addressee
+)
+
+// aprsCallsign matches a callsign with optional SSID, e.g. PD0ABC-10
+var aprsCallsign = regexp.MustCompile(`^[A-Z0-9]{3,6}(-([0-9]|1[0-5]))?$`)
+
+// APRSConfig configures the APRS backend
+type APRSConfig struct {
+	Callsign   string   // Licensed callsign the messages are sent from, e.g. PD0ABC-10
+	Addressees []string // Callsigns or bulletin groups (e.g. BLN1P2000) receiving the messages
+}
+
+// ValidateAPRS checks the callsign, addressees and the APRS-IS passcode of
+// the callsign
+func ValidateAPRS(cfg APRSConfig, passcode string) error {
+	if !aprsCallsign.MatchString(cfg.Callsign) {
+		return fmt.Errorf("aprs callsign %q must be an uppercase callsign with optional SSID like PD0ABC-10", cfg.Callsign)
+	}
+	if code, err := strconv.Atoi(passcode); err != nil || code != APRSPasscode(cfg.Callsign) {
+		return fmt.Errorf("aprs passcode does not match callsign %s", cfg.Callsign)
+	}
+	if len(cfg.Addressees) == 0 {
+		return fmt.Errorf("aprs requires at least one addressee")
+	}
+	for _, addressee := range cfg.Addressees {
+		if addressee == "" || len(addressee) > aprsMaxAddressee || strings.ContainsAny(addressee, ": ") {
+			return fmt.Errorf("aprs addressee %q must be 1-9 characters without spaces", addressee)
+		}
+	}
+	return nil
+}
+
+// APRSPasscode returns the APRS-IS passcode of a callsign, which is
+// computed from the callsign without SSID
+func APRSPasscode(callsign string) int {
+	call, _, _ := strings.Cut(strings.ToUpper(callsign), "-")
+	hash := 0x73e2
+	for i := 0; i < len(call); i += 2 {
+		hash ^= int(call[i]) << 8
+		if i+1 < len(call) {
+			hash ^= int(call[i+1])
+		}
+	}
+	return hash & 0x7fff
+}
+
+// SetAPRS sends notifications as APRS messages through an APRS-IS server
+// instead of ntfy, logged in with the callsign and the token as passcode
+func (n *Notifier) SetAPRS(cfg APRSConfig) {
+	n.backend = BackendAPRS
+	n.aprs = cfg
+}
+
+// aprsText renders notif as the single line of ASCII text of an APRS
+// message, truncated to aprsMaxLength characters. The characters |, ~ and
+// { are reserved by the protocol.
+func aprsText(notif notification) string {
+	text := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' || strings.ContainsRune("|~{", r) {
+			return -1
+		}
+		return r
+	}, speakable(notif))
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > aprsMaxLength {
+		text = text[:aprsMaxLength]
+	}
+	return text
+}
+
+// aprsPacket returns the APRS message packet for addressee in TNC2 format
+func (n *Notifier) aprsPacket(addressee, text string) string {
+	return fmt.Sprintf("%s>%s,TCPIP*::%-9s:%s", n.aprs.Callsign, aprsToCall, addressee, text)
+}
+
+// publishAPRS sends notif to every addressee that did not receive it on an
+// earlier attempt of the delivery
+func (n *Notifier) publishAPRS(ctx context.Context, notif notification) error {
+	text := aprsText(notif)
+	return n.publishEach(notif, "addressee", n.aprs.Addressees, func(to string) (*http.Request, error) {
+		return n.newAPRSRequest(ctx, n.aprsPacket(to, text))
+	})
+}
+
+// newAPRSRequest creates an APRS-IS HTTP submit request, which carries the
+// login line and a single packet
+func (n *Notifier) newAPRSRequest(ctx context.Context, packet string) (*http.Request, error) {
+	login := fmt.Sprintf("user %s pass %s vers %s %s", n.aprs.Callsign, n.token, aprsSoftware, aprsVersion)
+	req, err := http.NewRequestWithContext(ctx, "POST", n.server+"/", strings.NewReader(login+"\r\n"+packet+"\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept-Type", "text/plain")
+	return req, nil
+}
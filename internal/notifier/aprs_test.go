@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_APRS(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		data, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(data))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "20900",
+		Backend: BackendAPRS,
+		APRS:    APRSConfig{Callsign: "PD0ABC-10", Addressees: []string{"PA1XYZ", "BLN1P2000"}},
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 BDH-01 Brand woning {kelder} Dorpsstraat 1 Utrecht 123456"}))
+	require.Len(t, bodies, 2)
+	lines := strings.Split(bodies[0], "\r\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "user PD0ABC-10 pass 20900 vers p2000-nfty 1.0", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "PD0ABC-10>APZP2K,TCPIP*::PA1XYZ   :P 1 BDH-01 Brand woning kelder}"), lines[1])
+	assert.LessOrEqual(t, len(lines[1][strings.Index(lines[1], "::")+12:]), aprsMaxLength)
+	assert.Contains(t, bodies[1], "::BLN1P2000:")
+}
+
+func TestAPRSPasscode(t *testing.T) {
+	assert.Equal(t, 13023, APRSPasscode("N0CALL"))
+	assert.Equal(t, 20900, APRSPasscode("PD0ABC-10"))
+	assert.Equal(t, 20900, APRSPasscode("pd0abc"))
+}
+
+func TestValidateAPRS(t *testing.T) {
+	valid := APRSConfig{Callsign: "PD0ABC-10", Addressees: []string{"PA1XYZ"}}
+	assert.NoError(t, ValidateAPRS(valid, "20900"))
+
+	assert.EqualError(t, ValidateAPRS(APRSConfig{Callsign: "pd0abc", Addressees: []string{"PA1XYZ"}}, "20900"),
+		`aprs callsign "pd0abc" must be an uppercase callsign with optional SSID like PD0ABC-10`)
+	assert.EqualError(t, ValidateAPRS(valid, "12345"), "aprs passcode does not match callsign PD0ABC-10")
+	assert.EqualError(t, ValidateAPRS(APRSConfig{Callsign: "PD0ABC"}, "20900"), "aprs requires at least one addressee")
+	assert.EqualError(t, ValidateAPRS(APRSConfig{Callsign: "PD0ABC", Addressees: []string{"BLN1P2000NL"}}, "20900"),
+		`aprs addressee "BLN1P2000NL" must be 1-9 characters without spaces`)
+}
@@ -0,0 +1,166 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Send when the destination's circuit breaker
+// is open and the message was not attempted
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Deliveries are attempted
+	BreakerHalfOpen                     // A single probe delivery is attempted
+	BreakerOpen                         // Deliveries fail fast
+)
+
+// String returns the state name used in logs and the API
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText encodes the state by name
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state name
+func (s *BreakerState) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "closed":
+		*s = BreakerClosed
+	case "half_open":
+		*s = BreakerHalfOpen
+	case "open":
+		*s = BreakerOpen
+	default:
+		return fmt.Errorf("unknown circuit breaker state %q", text)
+	}
+	return nil
+}
+
+// BreakerConfig configures a circuit breaker
+type BreakerConfig struct {
+	Threshold int           // Consecutive failed deliveries before opening, 0 disables the breaker
+	Cooldown  time.Duration // Time the breaker stays open before a probe is allowed
+}
+
+// BreakerHook is called with the destination name whenever its breaker
+// changes state, and once with the initial state. It must not call back
+// into the breaker.
+type BreakerHook func(destination string, state BreakerState)
+
+// Breaker skips a failing destination after Threshold consecutive failures.
+// Once the cooldown has passed a single probe is let through; its outcome
+// closes or re-opens the breaker. It is safe for concurrent use.
+type Breaker struct {
+	cfg      BreakerConfig
+	now      func() time.Time
+	onChange func(BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker creates a closed circuit breaker. onChange may be nil.
+func NewBreaker(cfg BreakerConfig, onChange func(BreakerState)) *Breaker {
+	return newBreaker(cfg, onChange, time.Now)
+}
+
+func newBreaker(cfg BreakerConfig, onChange func(BreakerState), now func() time.Time) *Breaker {
+	b := &Breaker{
+		cfg:      cfg,
+		now:      now,
+		onChange: onChange,
+	}
+	if onChange != nil {
+		onChange(BreakerClosed)
+	}
+	return b
+}
+
+// Allow reports whether a delivery may be attempted and the state it is
+// attempted in. In the half-open state only one probe is allowed at a time.
+func (b *Breaker) Allow() (BreakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.Cooldown {
+			return b.state, false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return b.state, true
+	case BreakerHalfOpen:
+		if b.probing {
+			return b.state, false
+		}
+		b.probing = true
+		return b.state, true
+	default:
+		return b.state, true
+	}
+}
+
+// Record records the outcome of an allowed delivery
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.Threshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// Cancel releases an allowed delivery that was not attempted, without
+// counting it as an outcome, so a half-open breaker lets the next one probe
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State returns the current state
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState changes the state and notifies the hook; b.mu must be held
+func (b *Breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
@@ -0,0 +1,206 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a controllable time source
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	clock, _ := fakeClock()
+	var states []BreakerState
+	b := newBreaker(BreakerConfig{Threshold: 2, Cooldown: time.Minute}, func(s BreakerState) {
+		states = append(states, s)
+	}, clock)
+
+	_, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(false)
+	assert.Equal(t, BreakerClosed, b.State())
+
+	b.Record(false)
+	assert.Equal(t, BreakerOpen, b.State())
+
+	state, ok := b.Allow()
+	assert.False(t, ok)
+	assert.Equal(t, BreakerOpen, state)
+	assert.Equal(t, []BreakerState{BreakerClosed, BreakerOpen}, states)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	clock, _ := fakeClock()
+	b := newBreaker(BreakerConfig{Threshold: 2, Cooldown: time.Minute}, nil, clock)
+
+	b.Record(false)
+	b.Record(true)
+	b.Record(false)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	clock, advance := fakeClock()
+	b := newBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}, nil, clock)
+	b.Record(false)
+
+	advance(time.Minute)
+	state, ok := b.Allow()
+	require.True(t, ok)
+	assert.Equal(t, BreakerHalfOpen, state)
+
+	// Only one probe at a time
+	_, ok = b.Allow()
+	assert.False(t, ok)
+
+	// A failed probe re-opens the breaker for another cooldown
+	b.Record(false)
+	assert.Equal(t, BreakerOpen, b.State())
+	advance(30 * time.Second)
+	_, ok = b.Allow()
+	assert.False(t, ok)
+
+	advance(30 * time.Second)
+	_, ok = b.Allow()
+	require.True(t, ok)
+	b.Record(true)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreaker_CancelReleasesProbe(t *testing.T) {
+	clock, advance := fakeClock()
+	b := newBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}, nil, clock)
+	b.Record(false)
+
+	advance(time.Minute)
+	_, ok := b.Allow()
+	require.True(t, ok)
+	b.Cancel()
+	assert.Equal(t, BreakerHalfOpen, b.State())
+
+	_, ok = b.Allow()
+	assert.True(t, ok, "next delivery probes")
+}
+
+func TestBreakerState_JSON(t *testing.T) {
+	data, err := json.Marshal(map[string]BreakerState{"a": BreakerHalfOpen})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"half_open"}`, string(data))
+
+	var decoded map[string]BreakerState
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, BreakerHalfOpen, decoded["a"])
+
+	assert.Error(t, json.Unmarshal([]byte(`{"a":"broken"}`), &decoded))
+}
+
+func TestSend_CircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var changes []BreakerState
+	n, err := New(Options{
+		Server:  server.URL,
+		Topic:   "p2000",
+		Breaker: BreakerConfig{Threshold: 1, Cooldown: time.Hour},
+		OnBreakerChange: func(destination string, state BreakerState) {
+			assert.Equal(t, server.URL+"/p2000", destination)
+			changes = append(changes, state)
+		},
+		Logger: getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	var results []DeliveryResult
+	n.OnDelivery(func(r DeliveryResult) { results = append(results, r) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	msg := model.Message{Message: "A1 Test", Capcodes: []string{"0101001"}}
+
+	require.Error(t, n.Send(ctx, msg))
+	assert.Equal(t, int32(maxRetries), requests.Load())
+
+	// The open breaker fails fast without contacting the server
+	err = n.Send(ctx, msg)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(maxRetries), requests.Load())
+
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Success)
+	assert.Equal(t, 0, results[1].Attempts)
+	assert.Equal(t, []BreakerState{BreakerClosed, BreakerOpen}, changes)
+}
+
+func TestSend_HalfOpenProbeNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	clock, advance := fakeClock()
+	n := NewNotifier(server.URL, "p2000", "", "", "", nil, nil, getTestLogger())
+	b := newBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}, nil, clock)
+	b.Record(false)
+	n.SetBreaker(b)
+
+	advance(time.Minute)
+	err := n.Send(context.Background(), model.Message{Message: "A1 Test"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, BreakerOpen, b.State())
+}
+
+func TestSend_HalfOpenProbeRateLimited(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "access-key",
+		Backend: BackendSMS,
+		SMS:     SMSConfig{Provider: SMSProviderMessageBird, From: "P2000", Numbers: []string{"+31612345678"}, PerHour: 1},
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+	clock, advance := fakeClock()
+	b := newBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}, nil, clock)
+	b.Record(false)
+	n.SetBreaker(b)
+	require.True(t, n.limiter.allow(time.Now()))
+
+	// The cooldown passes while the limiter is full
+	advance(time.Minute)
+	err = n.SendText(context.Background(), "Rapport", "1")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, BreakerHalfOpen, b.State())
+
+	// Once the hour has passed the probe goes out and closes the breaker
+	n.limiter.sent = nil
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "2"))
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, BreakerClosed, b.State())
+}
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	DefaultCallLanguage = "nl-NL" // Text-to-speech language when Language is empty
+	DefaultCallPerHour  = 4       // Calls per hour when PerHour is 0
+	callPriority        = "5"     // Only notifications with this ntfy priority place calls
+)
+
+// CallConfig configures the voice call backend
+type CallConfig struct {
+	Account  string   // Twilio account SID
+	From     string   // Twilio number the calls come from
+	Numbers  []string // Numbers to call in E.164 format, e.g. +31612345678
+	Language string   // Text-to-speech language, DefaultCallLanguage when empty
+	PerHour  int      // Calls per hour, DefaultCallPerHour when 0
+}
+
+// ValidateCall checks the Twilio account, numbers and rate limit
+func ValidateCall(cfg CallConfig) error {
+	if cfg.Account == "" {
+		return fmt.Errorf("call requires the Twilio account SID as account")
+	}
+	if !isE164(cfg.From) {
+		return fmt.Errorf("call from %q must be a Twilio number in E.164 format", cfg.From)
+	}
+	if len(cfg.Numbers) == 0 {
+		return fmt.Errorf("call requires at least one number")
+	}
+	for _, number := range cfg.Numbers {
+		if !isE164(number) {
+			return fmt.Errorf("call number %q must be in E.164 format like +31612345678", number)
+		}
+	}
+	if cfg.PerHour < 0 {
+		return fmt.Errorf("call per_hour must not be negative")
+	}
+	return nil
+}
+
+// SetCall places Twilio voice calls reading the notification out loud
+// instead of publishing to ntfy. Only notifications with the highest
+// priority are called, at most cfg.PerHour per hour.
+func (n *Notifier) SetCall(cfg CallConfig) {
+	if cfg.Language == "" {
+		cfg.Language = DefaultCallLanguage
+	}
+	if cfg.PerHour == 0 {
+		cfg.PerHour = DefaultCallPerHour
+	}
+	n.backend = BackendCall
+	n.call = cfg
+	n.limiter = &rateLimiter{limit: cfg.PerHour, window: time.Hour}
+}
+
+// speakable turns the notification into text for text-to-speech, without
+// emoji, Markdown markup and blank lines
+func speakable(notif notification) string {
+	text := strings.Map(func(r rune) rune {
+		if unicode.IsSymbol(r) || strings.ContainsRune("*_`#~", r) {
+			return -1
+		}
+		return r
+	}, notif.title+"\n"+notif.message)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, ". ")
+}
+
+// callTwiML returns the TwiML reading notif out loud twice
+func (n *Notifier) callTwiML(notif notification) string {
+	return fmt.Sprintf(`<Response><Say language="%s" loop="2">%s</Say></Response>`,
+		html.EscapeString(n.call.Language), html.EscapeString(speakable(notif)))
+}
+
+// publishCall calls every number that was not reached on an earlier attempt
+// of the delivery
+func (n *Notifier) publishCall(ctx context.Context, notif notification) error {
+	twiml := n.callTwiML(notif)
+	return n.publishEach(notif, "number", n.call.Numbers, func(to string) (*http.Request, error) {
+		return n.newCallRequest(ctx, to, twiml)
+	})
+}
+
+// newCallRequest creates a Twilio Calls API request, authenticated with the
+// account SID and auth token
+func (n *Notifier) newCallRequest(ctx context.Context, to, twiml string) (*http.Request, error) {
+	form := url.Values{
+		"From":  {n.call.From},
+		"To":    {to},
+		"Twiml": {twiml},
+	}
+	u := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Calls.json", n.server, url.PathEscape(n.call.Account))
+	req, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(n.call.Account, n.token)
+	return req, nil
+}
@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_Call(t *testing.T) {
+	var twiml []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Calls.json", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "auth-token", pass)
+		assert.Equal(t, "+3197010000000", r.FormValue("From"))
+		assert.Equal(t, "+31612345678", r.FormValue("To"))
+		twiml = append(twiml, r.FormValue("Twiml"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "auth-token",
+		Backend: BackendCall,
+		Call:    CallConfig{Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}},
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	// Only the highest priority places a call
+	err = n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A2 Ambulance Rotterdam"})
+	assert.ErrorIs(t, err, ErrSkipped)
+	assert.Empty(t, twiml)
+
+	ctx := WithDelivery(context.Background(), Delivery{PriorityOverride: 5})
+	require.NoError(t, n.Send(ctx, model.Message{Type: "FLEX", Message: "A1 Reanimatie Dorpsstraat & Kerkweg Utrecht"}))
+	require.Len(t, twiml, 1)
+	assert.Contains(t, twiml[0], `<Say language="nl-NL" loop="2">`)
+	assert.Contains(t, twiml[0], "A1 Reanimatie Dorpsstraat &amp; Kerkweg Utrecht")
+	assert.NotContains(t, twiml[0], "🚨")
+}
+
+func TestSpeakable(t *testing.T) {
+	notif := notification{
+		title:   "🚨 A1 Reanimatie",
+		message: "**Dorpsstraat** 1 Utrecht\n\n📟 Ambulance   17-101",
+	}
+	assert.Equal(t, "A1 Reanimatie. Dorpsstraat 1 Utrecht. Ambulance 17-101", speakable(notif))
+}
+
+func TestValidateCall(t *testing.T) {
+	valid := CallConfig{Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}}
+	assert.NoError(t, ValidateCall(valid))
+
+	tests := []struct {
+		name   string
+		modify func(cfg *CallConfig)
+		err    string
+	}{
+		{"no account", func(cfg *CallConfig) { cfg.Account = "" }, "call requires the Twilio account SID as account"},
+		{"alphanumeric sender", func(cfg *CallConfig) { cfg.From = "P2000" }, `call from "P2000" must be a Twilio number in E.164 format`},
+		{"no numbers", func(cfg *CallConfig) { cfg.Numbers = nil }, "call requires at least one number"},
+		{"local number", func(cfg *CallConfig) { cfg.Numbers = []string{"0612345678"} }, `call number "0612345678" must be in E.164 format like +31612345678`},
+		{"negative limit", func(cfg *CallConfig) { cfg.PerHour = -1 }, "call per_hour must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			assert.EqualError(t, ValidateCall(cfg), tt.err)
+		})
+	}
+}
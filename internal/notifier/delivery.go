@@ -0,0 +1,33 @@
+package notifier
+
+import (
+	"context"
+)
+
+// Delivery holds how the forwarder's routing wants a message delivered. It
+// is kept apart from model.Message, which stays the page as received and
+// enriched, and travels with the message through the context of Send.
+type Delivery struct {
+	Routes           []string `json:"routes,omitempty"`            // Destinations chosen by routing rules, the default when empty
+	PriorityOverride int      `json:"priority_override,omitempty"` // ntfy priority 1-5 set by routing rules, 0 keeps the default
+	Tags             []string `json:"tags,omitempty"`              // Extra ntfy tags set by routing rules
+	Email            string   `json:"email,omitempty"`             // Address ntfy also emails the notification to, set by routing rules
+	Delay            string   `json:"delay,omitempty"`             // ntfy delayed delivery set by routing rules, e.g. "tomorrow, 7am"
+	Thread           string   `json:"thread,omitempty"`            // Incident thread shared by follow-up pages
+	Update           bool     `json:"update,omitempty"`            // Follow-up page of an earlier notified incident
+}
+
+// deliveryKey is the context key of the Delivery
+type deliveryKey struct{}
+
+// WithDelivery returns a copy of ctx carrying d to the senders
+func WithDelivery(ctx context.Context, d Delivery) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, d)
+}
+
+// DeliveryFrom returns the Delivery carried by ctx, the zero Delivery for
+// the default destination and notification when there is none
+func DeliveryFrom(ctx context.Context) Delivery {
+	d, _ := ctx.Value(deliveryKey{}).(Delivery)
+	return d
+}
@@ -0,0 +1,203 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Formats and groupings of the capcode details section
+const (
+	DetailsVerbose      = "verbose" // A paragraph per capcode with region, station and function
+	DetailsCompact      = "compact" // A line per capcode with the station only
+	DetailsGroupStation = "station" // One entry per station listing its capcodes
+)
+
+// CapcodeDetails configures the capcode details section of the default
+// body, which lists every capcode with its capcode database details. Big
+// incidents page dozens of capcodes, so it can be shortened.
+type CapcodeDetails struct {
+	Hide   bool   // Leave the section out
+	Max    int    // Entries listed besides the own unit, the rest counted as "+N more units"; 0 lists all
+	Format string // DetailsVerbose (default) or DetailsCompact
+	Group  string // DetailsGroupStation, or empty for an entry per capcode
+	Dedupe bool   // Omit capcodes resolving to the station of an earlier capcode
+}
+
+// ValidateCapcodeDetails checks the format and grouping of the capcode
+// details
+func ValidateCapcodeDetails(d CapcodeDetails) error {
+	if d.Max < 0 {
+		return fmt.Errorf("max must not be negative")
+	}
+	switch d.Format {
+	case "", DetailsVerbose, DetailsCompact:
+	default:
+		return fmt.Errorf("unknown format %q, expected %s or %s", d.Format, DetailsVerbose, DetailsCompact)
+	}
+	switch d.Group {
+	case "", DetailsGroupStation:
+	default:
+		return fmt.Errorf("unknown group %q, expected %s", d.Group, DetailsGroupStation)
+	}
+	return nil
+}
+
+// SetCapcodeDetails configures the capcode details section
+func (n *Notifier) SetCapcodeDetails(d CapcodeDetails) {
+	n.details = d
+}
+
+// detailsEntry is an entry of the capcode details section, a capcode or the
+// capcodes of a station
+type detailsEntry struct {
+	capcodes []string
+	own      bool
+}
+
+// formatUnits formats the capcode details section with the own unit on
+// top, returning the entries, how many of them are of the own unit, and the
+// number of capcodes left out by the max setting
+func (n *Notifier) formatUnits(capcodes []string) (units []string, own, omitted int) {
+	if n.details.Hide {
+		return nil, 0, 0
+	}
+
+	var entries []detailsEntry
+	groups := make(map[string]int) // Entry of each station
+	seen := make(map[string]bool)
+	for _, code := range n.ownFirst(capcodes) {
+		isOwn := n.isOwn(code)
+		station := n.stationKey(code)
+		if !isOwn && station != "" {
+			if i, ok := groups[station]; ok {
+				entries[i].capcodes = append(entries[i].capcodes, code)
+				continue
+			}
+			if n.details.Dedupe && seen[station] {
+				continue
+			}
+			if n.details.Group == DetailsGroupStation {
+				groups[station] = len(entries)
+			}
+		}
+		if station != "" {
+			seen[station] = true
+		}
+		entries = append(entries, detailsEntry{capcodes: []string{code}, own: isOwn})
+	}
+
+	listed := 0
+	for _, e := range entries {
+		line := n.formatEntry(e)
+		if !e.own && line != "" {
+			if n.details.Max > 0 && listed == n.details.Max {
+				omitted += len(e.capcodes)
+				continue
+			}
+			listed++
+		}
+		if n.compactDetails() {
+			if line == "" {
+				continue
+			}
+		} else if len(units) > 0 {
+			line = "\n" + line
+		}
+		if e.own {
+			own++
+		}
+		units = append(units, line)
+	}
+	return units, own, omitted
+}
+
+// compactDetails reports whether capcodes are listed a line each
+func (n *Notifier) compactDetails() bool {
+	return n.details.Format == DetailsCompact
+}
+
+// moreUnits returns the line counting capcodes left out of the body
+func (n *Notifier) moreUnits(omitted int) string {
+	line := n.translator.T("notification.more_units", omitted) + "\n"
+	if n.compactDetails() {
+		return line
+	}
+	return "\n" + line
+}
+
+// formatEntry formats a capcode details entry, empty for a capcode that is
+// neither known nor of the own unit
+func (n *Notifier) formatEntry(e detailsEntry) string {
+	if len(e.capcodes) == 1 {
+		return n.formatUnit(e.capcodes[0], e.own)
+	}
+	// Grouped capcodes all resolve to the same station
+	info := n.capcodeLookup.Get(e.capcodes[0])
+	label := joinDetails(info.Region, info.Station)
+	if n.compactDetails() {
+		label = info.Station
+	}
+	return fmt.Sprintf("%s: %s\n", label, strings.Join(e.capcodes, ", "))
+}
+
+// formatUnit formats the body line of a capcode, empty for capcodes that
+// are neither known nor of the own unit
+func (n *Notifier) formatUnit(capcode string, own bool) string {
+	var sb strings.Builder
+	if own {
+		sb.WriteString(n.ownUnit.marker + " ")
+	}
+	details, known := n.describe(capcode, n.compactDetails())
+	switch {
+	case details != "":
+		sb.WriteString(fmt.Sprintf("%s - %s\n", capcode, details))
+	case known || own:
+		sb.WriteString(capcode + "\n")
+	}
+	return sb.String()
+}
+
+// describe returns the display name or capcode database details of a
+// capcode, the station only when compact, and whether the capcode is known
+func (n *Notifier) describe(capcode string, compact bool) (string, bool) {
+	// A configured display name replaces the database details
+	if o, ok := n.capcodeOverride(capcode); ok && o.Name != "" {
+		return o.Name, true
+	}
+
+	if n.capcodeLookup == nil {
+		return "", false
+	}
+	info := n.capcodeLookup.Get(capcode)
+	if info == nil {
+		return "", false
+	}
+	if compact && info.Station != "" {
+		return info.Station, true
+	}
+	// Build the details string: regio, kazerne, functie
+	return joinDetails(info.Region, info.Station, info.Function), true
+}
+
+// stationKey identifies the station of a capcode, empty when unknown or
+// when the capcode has a display name of its own
+func (n *Notifier) stationKey(capcode string) string {
+	if o, ok := n.capcodeOverride(capcode); n.capcodeLookup == nil || ok && o.Name != "" {
+		return ""
+	}
+	if info := n.capcodeLookup.Get(capcode); info != nil && info.Station != "" {
+		return info.Region + "/" + info.Station
+	}
+	return ""
+}
+
+// joinDetails joins the non-empty capcode details
+func joinDetails(details ...string) string {
+	var parts []string
+	for _, d := range details {
+		if d != "" {
+			parts = append(parts, d)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
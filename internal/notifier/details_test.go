@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMessage_CapcodeDetails(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Centrum", Function: "Kazernealarm"},
+		{Capcode: "0101002", Agency: "Brandweer", Region: "Utrecht", Station: "Centrum", Function: "Bevelvoerder"},
+		{Capcode: "0101003", Agency: "Brandweer", Region: "Utrecht", Station: "Overvecht", Function: "Kazernealarm"},
+		{Capcode: "0101004", Agency: "Brandweer", Region: "Utrecht", Station: "Overvecht", Function: "Bevelvoerder"},
+		{Capcode: "0101005", Agency: "Brandweer", Region: "Utrecht", Station: "Leidsche Rijn", Function: "Kazernealarm"},
+	})
+	msg := model.Message{Capcodes: []string{"0101001", "0101002", "0101003", "0101004", "0101005", "0999001"}}
+
+	tests := []struct {
+		name     string
+		details  CapcodeDetails
+		own      []string
+		expected string
+	}{
+		{
+			name:    "compact",
+			details: CapcodeDetails{Format: DetailsCompact},
+			expected: "Brandweer\n" +
+				"0101001 - Centrum\n" +
+				"0101002 - Centrum\n" +
+				"0101003 - Overvecht\n" +
+				"0101004 - Overvecht\n" +
+				"0101005 - Leidsche Rijn\n",
+		},
+		{
+			name:    "grouped by station",
+			details: CapcodeDetails{Group: DetailsGroupStation},
+			expected: "Brandweer\n" +
+				"Utrecht, Centrum: 0101001, 0101002\n" +
+				"\nUtrecht, Overvecht: 0101003, 0101004\n" +
+				"\n0101005 - Utrecht, Leidsche Rijn, Kazernealarm\n" +
+				"\n",
+		},
+		{
+			name:    "compact grouped with own unit",
+			details: CapcodeDetails{Format: DetailsCompact, Group: DetailsGroupStation},
+			own:     []string{"0101004"},
+			expected: "Brandweer\n" +
+				"➡️ 0101004 - Overvecht\n" +
+				"Centrum: 0101001, 0101002\n" +
+				"0101003 - Overvecht\n" +
+				"0101005 - Leidsche Rijn\n",
+		},
+		{
+			name:    "dedupe",
+			details: CapcodeDetails{Format: DetailsCompact, Dedupe: true},
+			expected: "Brandweer\n" +
+				"0101001 - Centrum\n" +
+				"0101003 - Overvecht\n" +
+				"0101005 - Leidsche Rijn\n",
+		},
+		{
+			name:    "max entries",
+			details: CapcodeDetails{Format: DetailsCompact, Max: 2},
+			own:     []string{"0101005"},
+			expected: "Brandweer\n" +
+				"➡️ 0101005 - Leidsche Rijn\n" +
+				"0101001 - Centrum\n" +
+				"0101002 - Centrum\n" +
+				"+2 andere eenheden\n",
+		},
+		{
+			name:     "hidden",
+			details:  CapcodeDetails{Hide: true},
+			own:      []string{"0101005"},
+			expected: "Brandweer\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+			n.SetCapcodeDetails(tt.details)
+			n.SetOwnUnit(OwnUnit{Capcodes: tt.own})
+			assert.Equal(t, tt.expected, n.formatMessage(msg))
+		})
+	}
+}
+
+func TestFormatMessage_CapcodeDetailsSummarize(t *testing.T) {
+	lookup, msg := largePage()
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+	n.SetCapcodeDetails(CapcodeDetails{Format: DetailsCompact, Max: 5})
+	n.SetTruncation(100, "")
+
+	assert.Equal(t, "Brandweer\n"+
+		"0101001 - Post 1\n"+
+		"0101002 - Post 2\n"+
+		"0101003 - Post 3\n"+
+		"0101004 - Post 4\n"+
+		"+16 andere eenheden\n", n.formatMessage(msg), "capcodes left out by max and the length are counted together")
+}
+
+func TestValidateCapcodeDetails(t *testing.T) {
+	assert.NoError(t, ValidateCapcodeDetails(CapcodeDetails{}))
+	assert.NoError(t, ValidateCapcodeDetails(CapcodeDetails{Format: DetailsCompact, Group: DetailsGroupStation, Max: 5}))
+	assert.EqualError(t, ValidateCapcodeDetails(CapcodeDetails{Max: -1}), "max must not be negative")
+	assert.EqualError(t, ValidateCapcodeDetails(CapcodeDetails{Format: "short"}), `unknown format "short", expected verbose or compact`)
+	assert.EqualError(t, ValidateCapcodeDetails(CapcodeDetails{Group: "region"}), `unknown group "region", expected station`)
+}
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// DefaultGotifyPriorities maps the ntfy priorities 1-5 to Gotify priorities
+// 0-10. Gotify clients are silent below 4 and show a popup from 8.
+var DefaultGotifyPriorities = map[int]int{1: 0, 2: 2, 3: 5, 4: 8, 5: 10}
+
+// gotifyMessage is the body of a request to the Gotify message API
+type gotifyMessage struct {
+	Title    string         `json:"title,omitempty"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// ValidateGotifyPriorities checks a mapping of ntfy priorities 1-5 to
+// Gotify priorities 0-10
+func ValidateGotifyPriorities(priorities map[int]int) error {
+	for from, to := range priorities {
+		if from < 1 || from > 5 {
+			return fmt.Errorf("gotify priority mapping of priority %d, expected 1-5", from)
+		}
+		if to < 0 || to > 10 {
+			return fmt.Errorf("gotify priority %d for priority %d must be between 0 and 10", to, from)
+		}
+	}
+	return nil
+}
+
+// SetGotify publishes notifications to a Gotify server instead of ntfy,
+// authenticated with the application token. priorities overrides entries
+// of DefaultGotifyPriorities.
+func (n *Notifier) SetGotify(priorities map[int]int) {
+	n.backend = BackendGotify
+	n.gotifyPriorities = make(map[int]int, len(DefaultGotifyPriorities))
+	for from, to := range DefaultGotifyPriorities {
+		n.gotifyPriorities[from] = to
+	}
+	for from, to := range priorities {
+		n.gotifyPriorities[from] = to
+	}
+}
+
+// newGotifyRequest creates a Gotify message request for notif. Markdown,
+// the map image and the first view action are passed as client extras; tags
+// and other ntfy features have no Gotify equivalent.
+func (n *Notifier) newGotifyRequest(ctx context.Context, notif notification) (*http.Request, error) {
+	msg := gotifyMessage{
+		Title:   notif.title,
+		Message: notif.message,
+		Extras:  map[string]any{},
+	}
+	priority, err := strconv.Atoi(notif.priority)
+	if err != nil {
+		return nil, fmt.Errorf("invalid priority %q", notif.priority)
+	}
+	msg.Priority = n.gotifyPriorities[priority]
+
+	if n.markdown {
+		msg.Extras["client::display"] = map[string]string{"contentType": "text/markdown"}
+	}
+	notification := map[string]any{}
+	if notif.attach != "" {
+		notification["bigImageUrl"] = notif.attach
+	}
+	if notif.actions != "" {
+		var actions []actionJSON
+		if err := json.Unmarshal([]byte(notif.actions), &actions); err == nil {
+			for _, a := range actions {
+				if a.Action == "view" {
+					notification["click"] = map[string]string{"url": a.URL}
+					break
+				}
+			}
+		}
+	}
+	if len(notification) > 0 {
+		msg.Extras["client::notification"] = notification
+	}
+	if len(msg.Extras) == 0 {
+		msg.Extras = nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", n.server+"/message", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", n.token)
+	return req, nil
+}
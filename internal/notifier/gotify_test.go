@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_Gotify(t *testing.T) {
+	var path string
+	var header http.Header
+	var body gotifyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		header = r.Header.Clone()
+		body = gotifyMessage{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{"id":25,"appid":1}`))
+	}))
+	defer server.Close()
+
+	actions, err := ParseActions([]Action{
+		{Action: "http", Label: "Ack", URL: "https://example.com/ack"},
+		{Action: "view", Label: "Open", URL: "https://example.com/incident"},
+	})
+	require.NoError(t, err)
+	n, err := New(Options{
+		Server:           server.URL,
+		Token:            "app-token",
+		Backend:          BackendGotify,
+		GotifyPriorities: map[int]int{5: 9},
+		MessageTypes:     map[string]MessageType{"FLEX": {Priority: 5}},
+		Markdown:         true,
+		Actions:          actions,
+		Logger:           getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 Brand woning"}))
+	assert.Equal(t, "/message", path)
+	assert.Equal(t, "app-token", header.Get("X-Gotify-Key"))
+	assert.Empty(t, header.Get("Authorization"))
+	assert.Equal(t, "🚨 A1 Brand woning", body.Title)
+	assert.NotEmpty(t, body.Message)
+	assert.Equal(t, 9, body.Priority)
+	assert.Equal(t, map[string]any{"contentType": "text/markdown"}, body.Extras["client::display"])
+	assert.Equal(t, map[string]any{"click": map[string]any{"url": "https://example.com/incident"}}, body.Extras["client::notification"])
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1 melding"))
+	assert.Equal(t, "Rapport", body.Title)
+	assert.Equal(t, 5, body.Priority)
+	assert.Nil(t, body.Extras["client::notification"])
+}
@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reservedHeaders are set by the notifier itself and cannot be configured
+// as extra headers, including their ntfy aliases
+var reservedHeaders = map[string]bool{
+	"Title":         true,
+	"T":             true,
+	"Priority":      true,
+	"Prio":          true,
+	"P":             true,
+	"Tags":          true,
+	"Tag":           true,
+	"Ta":            true,
+	"Message":       true,
+	"M":             true,
+	"Attach":        true,
+	"A":             true,
+	"Filename":      true,
+	"File":          true,
+	"F":             true,
+	"Actions":       true,
+	"Action":        true,
+	"Markdown":      true,
+	"Md":            true,
+	"Sequence-Id":   true,
+	"Message-Id":    true,
+	"Sid":           true,
+	"Authorization": true,
+}
+
+// ValidateHeaders checks extra ntfy headers such as Icon, Email, Call,
+// Delay, Cache or Firebase
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s must be a single line", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if reservedHeaders[strings.TrimPrefix(canonical, "X-")] {
+			return fmt.Errorf("header %s is set by the notifier", name)
+		}
+	}
+	return nil
+}
+
+// SetHeaders adds extra headers to every notification, e.g. an Icon URL or
+// an Email address to forward to
+func (n *Notifier) SetHeaders(headers map[string]string) {
+	n.headers = make(http.Header, len(headers))
+	for name, value := range headers {
+		n.headers.Set(name, value)
+	}
+}
+
+// SetMarkdown marks notification bodies as Markdown, so ntfy clients render
+// templates using Markdown formatting
+func (n *Notifier) SetMarkdown(markdown bool) {
+	n.markdown = markdown
+}
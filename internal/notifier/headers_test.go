@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		errorMsg string
+	}{
+		{name: "Valid", headers: map[string]string{"Icon": "https://example.com/p2000.png", "X-Email": "ops@example.com", "Cache": "no"}},
+		{name: "None"},
+		{name: "Reserved", headers: map[string]string{"priority": "5"}, errorMsg: "header priority is set by the notifier"},
+		{name: "Reserved alias", headers: map[string]string{"X-Title": "P2000"}, errorMsg: "header X-Title is set by the notifier"},
+		{name: "Invalid name", headers: map[string]string{"X Icon": "a"}, errorMsg: `invalid header name "X Icon"`},
+		{name: "Multiple lines", headers: map[string]string{"Email": "a@example.com\nBcc: b@example.com"}, errorMsg: "header Email must be a single line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHeaders(tt.headers)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestSend_HeadersAndMarkdown(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:   server.URL,
+		Topic:    "p2000",
+		Headers:  map[string]string{"icon": "https://example.com/p2000.png", "X-Delay": "10s", "Firebase": "no"},
+		Markdown: true,
+		Logger:   getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 Brand woning"}))
+	assert.Equal(t, "https://example.com/p2000.png", header.Get("Icon"))
+	assert.Equal(t, "10s", header.Get("X-Delay"))
+	assert.Equal(t, "no", header.Get("Firebase"))
+	assert.Equal(t, "yes", header.Get("Markdown"))
+	assert.Equal(t, "🚨 A1 Brand woning", header.Get("Title"))
+
+	require.NoError(t, n.SendText(context.Background(), "Report", "**1** message"))
+	assert.Equal(t, "https://example.com/p2000.png", header.Get("Icon"))
+	assert.Equal(t, "yes", header.Get("Markdown"))
+
+	n.SetMarkdown(false)
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 Brand woning"}))
+	assert.Empty(t, header.Get("Markdown"))
+}
+
+func TestSend_EmailAndDelay(t *testing.T) {
+	var header http.Header
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body = nil
+		if r.Header.Get("Content-Type") == "application/json" {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Topic:   "p2000",
+		Headers: map[string]string{"X-Email": "ops@example.com", "X-In": "10s"},
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	// Routing rules replace the extra headers of the destination
+	ctx := WithDelivery(context.Background(), Delivery{Email: "ovd@example.com", Delay: "tomorrow, 7am"})
+	msg := model.Message{Type: "FLEX", Message: "P 2 Dienstverlening"}
+	require.NoError(t, n.Send(ctx, msg))
+	assert.Equal(t, "ovd@example.com", header.Get("Email"))
+	assert.Equal(t, "tomorrow, 7am", header.Get("Delay"))
+	assert.Empty(t, header.Get("X-Email"))
+	assert.Empty(t, header.Get("X-In"))
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 Brand"}))
+	assert.Equal(t, "ops@example.com", header.Get("X-Email"))
+	assert.Empty(t, header.Get("Delay"))
+
+	n.SetJSON(true)
+	require.NoError(t, n.Send(ctx, msg))
+	assert.Equal(t, "ovd@example.com", body["email"])
+	assert.Equal(t, "tomorrow, 7am", body["delay"])
+}
@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by Send when an SMS or call destination used
+// up its deliveries for the hour and the message was not attempted
+var ErrRateLimited = errors.New("rate limit reached")
+
+// rateLimiter allows limit deliveries in any sliding window
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time
+}
+
+// allow reports whether a delivery may be made now, and counts it if so
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := now.Add(-l.window)
+	for len(l.sent) > 0 && !l.sent[0].After(cutoff) {
+		l.sent = l.sent[1:]
+	}
+	if len(l.sent) >= l.limit {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
@@ -0,0 +1,20 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{limit: 2, window: time.Hour}
+	start := time.Now()
+
+	assert.True(t, l.allow(start))
+	assert.True(t, l.allow(start.Add(time.Minute)))
+	assert.False(t, l.allow(start.Add(30*time.Minute)))
+	// The first delivery leaves the window
+	assert.True(t, l.allow(start.Add(time.Hour)))
+	assert.False(t, l.allow(start.Add(time.Hour+time.Second)))
+}
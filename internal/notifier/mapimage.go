@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+const defaultMapFilename = "map.png"
+
+// MapImage builds the URL of a static map image for geocoded incidents,
+// which ntfy downloads and attaches to the notification
+type MapImage struct {
+	url      *template.Template
+	filename string
+}
+
+// ParseMapImage parses a static map service URL template. The template can
+// use .Lat and .Lon, e.g.
+// "https://maps.example.com/staticmap?center={{.Lat}},{{.Lon}}&zoom=15".
+// An empty url disables map images; filename defaults to map.png.
+func ParseMapImage(url, filename string) (*MapImage, error) {
+	if url == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("map").Option("missingkey=error").Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid map image url: %w", err)
+	}
+	if filename == "" {
+		filename = defaultMapFilename
+	}
+	return &MapImage{url: tmpl, filename: filename}, nil
+}
+
+// SetMapImage attaches map images to notifications of geocoded incidents,
+// nil disables them
+func (n *Notifier) SetMapImage(m *MapImage) {
+	n.mapImage = m
+}
+
+// attachment returns the attachment URL and filename for a position. The
+// URL is empty when the template fails, so the notification is still sent.
+func (m *MapImage) attachment(c model.Coordinates) (string, string) {
+	var sb strings.Builder
+	if err := m.url.Execute(&sb, c); err != nil {
+		return "", ""
+	}
+	return sb.String(), m.filename
+}
@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMapImage(t *testing.T) {
+	m, err := ParseMapImage("", "")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = ParseMapImage("https://maps.example.com/?c={{.Lat", "")
+	assert.ErrorContains(t, err, "invalid map image url")
+
+	m, err = ParseMapImage("https://maps.example.com/staticmap?center={{.Lat}},{{.Lon}}&zoom=15", "")
+	require.NoError(t, err)
+	url, filename := m.attachment(model.Coordinates{Lat: 52.3702, Lon: 4.8952})
+	assert.Equal(t, "https://maps.example.com/staticmap?center=52.3702,4.8952&zoom=15", url)
+	assert.Equal(t, "map.png", filename)
+}
+
+func TestMapImage_UnknownField(t *testing.T) {
+	m, err := ParseMapImage("https://maps.example.com/?c={{.Latitude}}", "incident.png")
+	require.NoError(t, err)
+
+	url, filename := m.attachment(model.Coordinates{Lat: 52, Lon: 4})
+	assert.Empty(t, url)
+	assert.Empty(t, filename)
+}
+
+func TestSend_MapImageAttachment(t *testing.T) {
+	var attach, filename []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attach = append(attach, r.Header.Get("Attach"))
+		filename = append(filename, r.Header.Get("Filename"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mapImage, err := ParseMapImage("https://maps.example.com/staticmap?center={{.Lat}},{{.Lon}}", "incident.png")
+	require.NoError(t, err)
+	n, err := New(Options{Server: server.URL, Topic: "p2000", MapImage: mapImage, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	geocoded := model.Message{Message: "A1 Damrak Amsterdam", Coordinates: &model.Coordinates{Lat: 52.3757, Lon: 4.8971}}
+	require.NoError(t, n.Send(context.Background(), geocoded))
+	require.NoError(t, n.Send(context.Background(), model.Message{Message: "A2 Amsterdam"}))
+
+	assert.Equal(t, []string{"https://maps.example.com/staticmap?center=52.3757,4.8971", ""}, attach)
+	assert.Equal(t, []string{"incident.png", ""}, filename)
+}
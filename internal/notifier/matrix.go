@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// matrixMessage is an m.room.message event with an HTML formatted body
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// ValidateRooms checks Matrix room IDs, which start with "!" and include
+// the server name
+func ValidateRooms(rooms []string) error {
+	if len(rooms) == 0 {
+		return fmt.Errorf("matrix requires at least one room")
+	}
+	for _, room := range rooms {
+		if !strings.HasPrefix(room, "!") || !strings.Contains(room, ":") {
+			return fmt.Errorf("matrix room %q must be a room ID like !abc123:matrix.org", room)
+		}
+	}
+	return nil
+}
+
+// SetMatrix posts notifications to Matrix rooms instead of ntfy, using the
+// token as access token of the (bot) user, which must have joined the rooms
+func (n *Notifier) SetMatrix(rooms []string) {
+	n.backend = BackendMatrix
+	n.matrixRooms = rooms
+}
+
+// newTxnID returns a random Matrix transaction ID
+func newTxnID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// matrixHTML formats a notification as HTML, with the map image and view
+// actions as links
+func matrixHTML(notif notification) string {
+	var b strings.Builder
+	b.WriteString("<strong>" + html.EscapeString(notif.title) + "</strong><br>")
+	b.WriteString(strings.ReplaceAll(html.EscapeString(notif.message), "\n", "<br>"))
+
+	var links []string
+	if notif.attach != "" {
+		links = append(links, fmt.Sprintf(`<a href="%s">🗺️</a>`, html.EscapeString(notif.attach)))
+	}
+	if notif.actions != "" {
+		var actions []actionJSON
+		if err := json.Unmarshal([]byte(notif.actions), &actions); err == nil {
+			for _, a := range actions {
+				if a.Action == "view" {
+					links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(a.URL), html.EscapeString(a.Label)))
+				}
+			}
+		}
+	}
+	if len(links) > 0 {
+		b.WriteString("<br>" + strings.Join(links, " · "))
+	}
+	return b.String()
+}
+
+// publishMatrix sends notif to every room. The transaction ID of the
+// delivery makes retries idempotent, so rooms that already received the
+// message on an earlier attempt do not get it twice.
+func (n *Notifier) publishMatrix(ctx context.Context, notif notification) error {
+	data, err := json.Marshal(matrixMessage{
+		MsgType:       "m.text",
+		Body:          notif.title + "\n" + notif.message,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: matrixHTML(notif),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var errs []error
+	for _, room := range n.matrixRooms {
+		if err := n.sendMatrix(ctx, room, notif.txn, data); err != nil {
+			errs = append(errs, fmt.Errorf("room %s: %w", room, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendMatrix puts a message event into room
+func (n *Notifier) sendMatrix(ctx context.Context, room, txn string, data []byte) error {
+	u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", n.server, url.PathEscape(room), url.PathEscape(txn))
+	req, err := http.NewRequestWithContext(ctx, "PUT", u, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.token)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
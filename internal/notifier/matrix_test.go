@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_Matrix(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var body matrixMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.EscapedPath())
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{"event_id":"$abc"}`))
+	}))
+	defer server.Close()
+
+	actions, err := ParseActions([]Action{{Action: "view", Label: "Kaart", URL: "https://example.com/map?a=1&b=2"}})
+	require.NoError(t, err)
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "access-token",
+		Backend: BackendMatrix,
+		Rooms:   []string{"!brandweer:matrix.org", "!ambulance:example.com"},
+		Actions: actions,
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 <Brand> woning"}))
+	require.Len(t, paths, 2)
+	assert.True(t, strings.HasPrefix(paths[0], "/_matrix/client/v3/rooms/%21brandweer:matrix.org/send/m.room.message/"), paths[0])
+	assert.True(t, strings.HasPrefix(paths[1], "/_matrix/client/v3/rooms/%21ambulance:example.com/send/m.room.message/"), paths[1])
+	// Both rooms get the same transaction ID
+	assert.Equal(t, paths[0][strings.LastIndex(paths[0], "/"):], paths[1][strings.LastIndex(paths[1], "/"):])
+
+	assert.Equal(t, "m.text", body.MsgType)
+	assert.Equal(t, "org.matrix.custom.html", body.Format)
+	assert.Contains(t, body.Body, "A1 <Brand> woning")
+	assert.Contains(t, body.FormattedBody, "<strong>🚨 A1 &lt;Brand&gt; woning</strong><br>")
+	assert.Contains(t, body.FormattedBody, `<a href="https://example.com/map?a=1&amp;b=2">Kaart</a>`)
+}
+
+func TestSend_MatrixRetryReusesTransaction(t *testing.T) {
+	var txns []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txns = append(txns, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		if len(txns) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"event_id":"$abc"}`))
+	}))
+	defer server.Close()
+
+	n, err := New(Options{Server: server.URL, Token: "access-token", Backend: BackendMatrix, Rooms: []string{"!p2000:matrix.org"}, Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1 melding"))
+	require.Len(t, txns, 2)
+	assert.Equal(t, txns[0], txns[1])
+}
+
+func TestValidateRooms(t *testing.T) {
+	assert.NoError(t, ValidateRooms([]string{"!abc123:matrix.org"}))
+	assert.EqualError(t, ValidateRooms(nil), "matrix requires at least one room")
+	assert.EqualError(t, ValidateRooms([]string{"#p2000:matrix.org"}), `matrix room "#p2000:matrix.org" must be a room ID like !abc123:matrix.org`)
+}
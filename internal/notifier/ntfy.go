@@ -3,13 +3,16 @@ package notifier
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/kaije/p2000-nfty/internal/capcode"
-	"github.com/kaije/p2000-nfty/internal/websocket"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
 	"github.com/rs/zerolog"
 )
 
@@ -22,51 +25,279 @@ const (
 
 // Notifier sends notifications to ntfy.sh
 type Notifier struct {
+	name          string
 	server        string
 	topic         string
 	token         string
 	username      string
 	password      string
-	translations  map[string]string
+	overrides     map[string]CapcodeOverride
+	patterns      []patternOverride // Overrides of capcode prefixes and ranges
 	capcodeLookup *capcode.Lookup
 	httpClient    *http.Client
 	logger        zerolog.Logger
+	onPublished   PublishHook
+	onDelivery    []DeliveryHook
+	messageTypes  map[string]MessageType
+	specialUnits  []SpecialUnit
+	ownUnit       *ownUnit // Highlighted capcodes, nil when not configured
+	details       CapcodeDetails
+	templates     *Templates
+	translator    *i18n.Translator
+	breaker       *Breaker
+	mapImage      *MapImage
+	actions       *Actions
+	headers       http.Header
+	markdown      bool
+	json          bool   // Publish with the JSON API instead of headers
+	maxBody       int    // Maximum body length in bytes, 0 for no limit
+	truncate      string // Truncation strategy of bodies over maxBody
+
+	backend          string      // BackendNtfy when empty
+	gotifyPriorities map[int]int // ntfy to Gotify priorities
+	matrixRooms      []string
+	sms              SMSConfig
+	call             CallConfig
+	aprs             APRSConfig
+	limiter          *rateLimiter // Deliveries per hour of SMS and calls
+}
+
+// MessageType overrides how messages of a feed type (e.g. POCSAG) are
+// presented. Empty fields keep the defaults.
+type MessageType struct {
+	Tags     string // Comma separated ntfy tags
+	Priority int    // ntfy priority 1-5, 0 keeps the default
+}
+
+// notification is a single ntfy publish request
+type notification struct {
+	title     string
+	message   string
+	priority  string
+	tags      string
+	attach    string // URL of an attachment, e.g. a map image
+	filename  string
+	actions   string          // ntfy Actions header
+	sequence  string          // ntfy sequence ID, follow-ups with the same ID replace the notification
+	email     string          // ntfy Email header, set by routing rules
+	delay     string          // ntfy Delay header, set by routing rules
+	id        string          // Message ID, empty for notifications not based on a message
+	txn       string          // Matrix transaction ID, shared by the attempts of a delivery
+	delivered map[string]bool // Recipients reached on an earlier attempt, e.g. SMS numbers
+}
+
+// DeliveryResult describes the outcome of a single Send call
+type DeliveryResult struct {
+	Destination string
+	Success     bool
+	Attempts    int
+	Duration    time.Duration
+	Err         error
 }
 
-// NewNotifier creates a new ntfy notifier
-func NewNotifier(server, topic, token, username, password string, translations map[string]string, capcodeLookup *capcode.Lookup, logger zerolog.Logger) *Notifier {
-	return &Notifier{
+// DeliveryHook is called after every Send with its outcome
+type DeliveryHook func(result DeliveryResult)
+
+// PublishHook is called with the ntfy message ID after a message was
+// accepted by the ntfy server
+type PublishHook func(id string, msg model.Message)
+
+// NewNotifier creates a new ntfy notifier from positional parameters. New
+// with Options is preferred as it also configures the optional features.
+func NewNotifier(server, topic, token, username, password string, overrides map[string]CapcodeOverride, capcodeLookup *capcode.Lookup, logger zerolog.Logger) *Notifier {
+	n := &Notifier{
 		server:        strings.TrimSuffix(server, "/"),
 		topic:         topic,
 		token:         token,
 		username:      username,
 		password:      password,
-		translations:  translations,
 		capcodeLookup: capcodeLookup,
 		httpClient: &http.Client{
 			Timeout: requestTimeout,
 		},
-		logger: logger,
+		logger:     logger,
+		translator: i18n.Default(),
 	}
+	n.SetCapcodeOverrides(overrides)
+	return n
+}
+
+// SetTranslator sets the language used for static notification text
+func (n *Notifier) SetTranslator(t *i18n.Translator) {
+	n.translator = t
 }
 
-// Send sends a P2000 message to ntfy with retry logic
-func (n *Notifier) Send(ctx context.Context, msg websocket.P2000Message) error {
+// Name identifies the destination by the name it was given, or by server
+// and topic
+func (n *Notifier) Name() string {
+	if n.name != "" {
+		return n.name
+	}
+	return n.server + "/" + n.topic
+}
+
+// SetName names the destination in delivery results, breaker hooks and
+// errors
+func (n *Notifier) SetName(name string) {
+	n.name = name
+}
+
+// SetTransport replaces the HTTP transport used to reach ntfy
+func (n *Notifier) SetTransport(rt http.RoundTripper) {
+	n.httpClient.Transport = rt
+}
+
+// OnPublished registers a hook that is called after every successful publish
+func (n *Notifier) OnPublished(hook PublishHook) {
+	n.onPublished = hook
+}
+
+// SetMessageTypes configures tag and priority overrides per message type
+func (n *Notifier) SetMessageTypes(types map[string]MessageType) {
+	n.messageTypes = make(map[string]MessageType, len(types))
+	for name, mt := range types {
+		n.messageTypes[strings.ToUpper(name)] = mt
+	}
+}
+
+// SetBreaker guards deliveries with a circuit breaker, nil disables it
+func (n *Notifier) SetBreaker(b *Breaker) {
+	n.breaker = b
+}
+
+// OnDelivery registers a hook that is called with the outcome of every Send
+func (n *Notifier) OnDelivery(hook DeliveryHook) {
+	n.onDelivery = append(n.onDelivery, hook)
+}
+
+// Send sends a P2000 message to ntfy with retry logic, with the priority,
+// tags, email, delay and thread of the Delivery carried by ctx
+func (n *Notifier) Send(ctx context.Context, msg model.Message) error {
+	d := DeliveryFrom(ctx)
+
 	// Format message body
 	message := n.formatMessage(msg)
 
 	// Format title using capcode lookup
 	title := n.formatTitle(msg)
 
-	priority := defaultPriority
-	tags := n.getTags(msg.Type)
+	notif := notification{
+		id:       msg.ID,
+		title:    title,
+		message:  message,
+		priority: n.getPriority(msg.Type),
+		tags:     n.getTags(msg.Type),
+	}
+	if units := n.matchSpecialUnits(msg.Capcodes, msg.Message); len(units) > 0 {
+		notif.priority, notif.tags = applySpecialUnits(units, notif.priority, notif.tags)
+	}
+	notif.priority, notif.tags = n.applyCapcodeOverrides(msg.Capcodes, notif.priority, notif.tags)
+	notif.tags = n.applyOwnUnit(msg.Capcodes, notif.tags)
+	if d.PriorityOverride > 0 {
+		notif.priority = strconv.Itoa(d.PriorityOverride)
+	}
+	if len(d.Tags) > 0 {
+		notif.tags = strings.Join(append([]string{notif.tags}, d.Tags...), ",")
+	}
+	if n.mapImage != nil && msg.Coordinates != nil {
+		notif.attach, notif.filename = n.mapImage.attachment(*msg.Coordinates)
+	}
+	notif.actions = n.actionsHeader(msg)
+	notif.email, notif.delay = d.Email, d.Delay
+	if d.Thread != "" {
+		notif.sequence = d.Thread
+		if d.Update {
+			notif.title = n.translator.T("notification.update", notif.title)
+		}
+	}
+
+	return n.deliver(ctx, notif, func(id string) {
+		if n.onPublished != nil && id != "" {
+			n.onPublished(id, msg)
+		}
+	})
+}
+
+// SendText sends a plain notification that is not based on a P2000 message,
+// such as periodic reports
+func (n *Notifier) SendText(ctx context.Context, title, message string) error {
+	return n.deliver(ctx, notification{
+		title:    title,
+		message:  message,
+		priority: defaultPriority,
+		tags:     "bar_chart",
+	}, nil)
+}
+
+// deliver publishes with retry logic and reports the outcome to the
+// delivery hooks. While the circuit breaker is open the message fails
+// without being attempted, and a half-open probe is not retried. A call
+// destination skips notifications below the highest priority with
+// ErrSkipped.
+func (n *Notifier) deliver(ctx context.Context, notif notification, onSuccess func(id string)) error {
+	start := time.Now()
+	attempts := 0
+	tries := maxRetries
+	switch n.backend {
+	case BackendMatrix:
+		notif.txn = newTxnID()
+	case BackendCall:
+		if notif.priority != callPriority {
+			n.logger.Debug().
+				Str("title", notif.title).
+				Str("priority", notif.priority).
+				Msg("not calling for a notification below the highest priority")
+			return fmt.Errorf("%s: %w", n.Name(), ErrSkipped)
+		}
+		notif.delivered = make(map[string]bool)
+	case BackendSMS, BackendAPRS:
+		notif.delivered = make(map[string]bool)
+	}
 
+	var err error
+	if n.breaker != nil {
+		state, ok := n.breaker.Allow()
+		if state == BreakerHalfOpen {
+			tries = 1
+		}
+		if !ok {
+			err = fmt.Errorf("%s: %w", n.Name(), ErrCircuitOpen)
+		}
+	}
+	if err == nil && n.limiter != nil && !n.limiter.allow(time.Now()) {
+		err = fmt.Errorf("%s: %w", n.Name(), ErrRateLimited)
+		if n.breaker != nil {
+			n.breaker.Cancel()
+		}
+	}
+	if err == nil {
+		err = n.retry(ctx, notif, tries, &attempts, onSuccess)
+		if n.breaker != nil {
+			n.breaker.Record(err == nil)
+		}
+	}
+
+	result := DeliveryResult{
+		Destination: n.Name(),
+		Success:     err == nil,
+		Attempts:    attempts,
+		Duration:    time.Since(start),
+		Err:         err,
+	}
+	for _, hook := range n.onDelivery {
+		hook(result)
+	}
+	return err
+}
+
+// retry publishes the notification, making up to tries attempts
+func (n *Notifier) retry(ctx context.Context, notif notification, tries int, attempts *int, onSuccess func(id string)) error {
 	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for attempt := 0; attempt < tries; attempt++ {
 		if attempt > 0 {
 			n.logger.Debug().
 				Int("attempt", attempt+1).
-				Int("max_retries", maxRetries).
+				Int("max_retries", tries).
 				Msg("retrying notification")
 
 			select {
@@ -76,38 +307,101 @@ func (n *Notifier) Send(ctx context.Context, msg websocket.P2000Message) error {
 			}
 		}
 
-		if err := n.sendRequest(ctx, title, message, priority, tags); err != nil {
+		*attempts = attempt + 1
+		id, err := n.publish(ctx, notif)
+		if err != nil {
 			lastErr = err
 			n.logger.Warn().
 				Err(err).
+				Str("id", notif.id).
 				Int("attempt", attempt+1).
 				Msg("failed to send notification")
 			continue
 		}
 
 		n.logger.Info().
-			Str("title", title).
-			Str("priority", priority).
+			Str("id", notif.id).
+			Str("title", notif.title).
+			Str("priority", notif.priority).
 			Msg("notification sent successfully")
+
+		if onSuccess != nil {
+			onSuccess(id)
+		}
 		return nil
 	}
 
-	return fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
+	return fmt.Errorf("failed after %d attempts: %w", tries, lastErr)
 }
 
 // sendRequest sends HTTP request to ntfy
 func (n *Notifier) sendRequest(ctx context.Context, title, message, priority, tags string) error {
-	url := fmt.Sprintf("%s/%s", n.server, n.topic)
+	_, err := n.publish(ctx, notification{title: title, message: message, priority: priority, tags: tags})
+	return err
+}
+
+// publish sends HTTP request to ntfy and returns the ID assigned to the
+// message by the server, if any
+func (n *Notifier) publish(ctx context.Context, notif notification) (string, error) {
+	switch n.backend {
+	case BackendMatrix:
+		return "", n.publishMatrix(ctx, notif)
+	case BackendSMS:
+		return "", n.publishSMS(ctx, notif)
+	case BackendCall:
+		return "", n.publishCall(ctx, notif)
+	case BackendAPRS:
+		return "", n.publishAPRS(ctx, notif)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBufferString(message))
+	req, err := n.newRequest(ctx, notif)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
 
-	// Set headers
-	req.Header.Set("Title", title)
-	req.Header.Set("Priority", priority)
-	req.Header.Set("Tags", tags)
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// ntfy responds with the published message; the ID is best effort
+	var published struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&published)
+
+	return published.ID, nil
+}
+
+// newRequest creates the publish request for notif, with the notification
+// in headers or, with JSON enabled, in a JSON body. Gotify has its own
+// message API.
+func (n *Notifier) newRequest(ctx context.Context, notif notification) (*http.Request, error) {
+	if n.backend == BackendGotify {
+		return n.newGotifyRequest(ctx, notif)
+	}
+
+	var req *http.Request
+	var err error
+	if n.json {
+		req, err = n.newJSONRequest(ctx, notif)
+	} else {
+		req, err = n.newHeaderRequest(ctx, notif)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The message ID lets proxies and webhooks behind the server correlate
+	// and deduplicate deliveries
+	if notif.id != "" {
+		req.Header.Set("X-Message-ID", notif.id)
+	}
 
 	// Set authentication: prefer Basic Auth if password is set, otherwise use Bearer token
 	if n.password != "" {
@@ -117,23 +411,66 @@ func (n *Notifier) sendRequest(ctx context.Context, title, message, priority, ta
 		// Use Bearer token for access token authentication
 		req.Header.Set("Authorization", "Bearer "+n.token)
 	}
+	return req, nil
+}
 
-	resp, err := n.httpClient.Do(req)
+// newHeaderRequest creates a publish request with the notification in
+// headers and the message as body
+func (n *Notifier) newHeaderRequest(ctx context.Context, notif notification) (*http.Request, error) {
+	url := fmt.Sprintf("%s/%s", n.server, n.topic)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBufferString(notif.message))
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	// Set headers, the configured extra headers first so they cannot
+	// replace the notification headers
+	for name, values := range n.headers {
+		req.Header[name] = values
 	}
-
-	return nil
+	req.Header.Set("Title", notif.title)
+	req.Header.Set("Priority", notif.priority)
+	req.Header.Set("Tags", notif.tags)
+	if notif.attach != "" {
+		req.Header.Set("Attach", notif.attach)
+		if notif.filename != "" {
+			req.Header.Set("Filename", notif.filename)
+		}
+	}
+	if notif.actions != "" {
+		req.Header.Set("Actions", notif.actions)
+	}
+	if notif.sequence != "" {
+		req.Header.Set("X-Sequence-ID", notif.sequence)
+	}
+	// Routing rules take precedence over the extra headers of the
+	// destination, under any of their names
+	if notif.email != "" {
+		req.Header.Del("X-Email")
+		req.Header.Set("Email", notif.email)
+	}
+	if notif.delay != "" {
+		for _, name := range []string{"X-Delay", "X-At", "At", "X-In", "In"} {
+			req.Header.Del(name)
+		}
+		req.Header.Set("Delay", notif.delay)
+	}
+	if n.markdown {
+		req.Header.Set("Markdown", "yes")
+	}
+	return req, nil
 }
 
 // formatTitle creates the notification title
 // Format: 🚨 P2000 {CSV-Agency}
-func (n *Notifier) formatTitle(msg websocket.P2000Message) string {
+func (n *Notifier) formatTitle(msg model.Message) string {
+	if n.templates != nil {
+		if title, ok := n.render(n.templates.title, msg); ok {
+			return title
+		}
+	}
+
 	// Try to get agency from first capcode if lookup is available
 	message := msg.Message
 
@@ -141,14 +478,20 @@ func (n *Notifier) formatTitle(msg websocket.P2000Message) string {
 		return fmt.Sprintf("🚨 %s", message)
 	}
 
-	return "🚨 P2000"
+	return "🚨 " + n.translator.T("notification.title")
 }
 
-// formatMessage formats the notification message body with capcodes and translations
-func (n *Notifier) formatMessage(msg websocket.P2000Message) string {
-	var sb strings.Builder
+// formatMessage formats the notification message body with capcodes and
+// their display names or capcode database details, shortened to the
+// maximum body length
+func (n *Notifier) formatMessage(msg model.Message) string {
+	if n.templates != nil {
+		if body, ok := n.render(n.templates.body, msg); ok {
+			return n.shorten(body)
+		}
+	}
 
-	agency := "overig"
+	agency := n.translator.T("agency.unknown")
 
 	if n.capcodeLookup != nil && len(msg.Capcodes) > 0 {
 		if info := n.capcodeLookup.Get(msg.Capcodes[0]); info != nil {
@@ -156,46 +499,45 @@ func (n *Notifier) formatMessage(msg websocket.P2000Message) string {
 		}
 	}
 
-	sb.WriteString(agency)
-	sb.WriteString("\n")
+	b := body{header: agency + "\n"}
 
-	// Capcode details section
-	if len(msg.Capcodes) > 0 {
-		for i, capcode := range msg.Capcodes {
-			if i > 0 {
-				sb.WriteString("\n")
-			}
+	// Capcode details section, with the own unit on top
+	b.units, b.own, b.omitted = n.formatUnits(msg.Capcodes)
 
-			// Try to get detailed info from CSV lookup
-			if n.capcodeLookup != nil {
-				if info := n.capcodeLookup.Get(capcode); info != nil {
-					// Build the details string: capcode - regio, kazerne, functie
-					var details []string
-					if info.Region != "" {
-						details = append(details, info.Region)
-					}
-					if info.Station != "" {
-						details = append(details, info.Station)
-					}
-					if info.Function != "" {
-						details = append(details, info.Function)
-					}
-					if len(details) > 0 {
-						sb.WriteString(fmt.Sprintf("%s - %s\n", capcode, strings.Join(details, ", ")))
-					} else {
-						sb.WriteString(fmt.Sprintf("%s\n", capcode))
-					}
-					continue
-				}
-			}
-		}
+	var sb strings.Builder
+	if msg.Weather != nil {
+		sb.WriteString("\n🌬️ ")
+		sb.WriteString(n.formatWeather(*msg.Weather))
 	}
 
-	return sb.String()
+	// Machine translation of the message text, when enabled
+	if msg.Translation != "" {
+		sb.WriteString("\n🌐 ")
+		sb.WriteString(msg.Translation)
+	}
+	b.footer = sb.String()
+
+	return n.summarize(b)
+}
+
+// formatWeather formats the wind and temperature at the incident, e.g.
+// "Wind SW 6 Bft (12 m/s), 8.5 °C, gusts 18 m/s"
+func (n *Notifier) formatWeather(w model.Weather) string {
+	directions := strings.Split(n.translator.T("weather.directions"), ",")
+	direction := directions[((w.WindDirection%360+360)%360*len(directions)+180)/360%len(directions)]
+	s := n.translator.T("notification.weather", direction, w.Beaufort(), w.WindSpeed, w.Temperature)
+	if w.WindGust > w.WindSpeed {
+		s += ", " + n.translator.T("notification.weather_gusts", w.WindGust)
+	}
+	return s
 }
 
 // getTags returns appropriate emoji tags based on message type
 func (n *Notifier) getTags(msgType string) string {
+	if mt, ok := n.messageTypes[strings.ToUpper(msgType)]; ok && mt.Tags != "" {
+		return mt.Tags
+	}
+
 	switch msgType {
 	case "FLEX":
 		return "rotating_light,emergency"
@@ -203,3 +545,11 @@ func (n *Notifier) getTags(msgType string) string {
 		return "warning"
 	}
 }
+
+// getPriority returns the ntfy priority for a message type
+func (n *Notifier) getPriority(msgType string) string {
+	if mt, ok := n.messageTypes[strings.ToUpper(msgType)]; ok && mt.Priority > 0 {
+		return strconv.Itoa(mt.Priority)
+	}
+	return defaultPriority
+}
@@ -10,8 +10,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/kaije/p2000-nfty/internal/capcode"
-	"github.com/kaije/p2000-nfty/internal/websocket"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,7 +25,7 @@ func getTestLogger() zerolog.Logger {
 
 func TestNewNotifier(t *testing.T) {
 	logger := getTestLogger()
-	translations := map[string]string{"0101001": "Fire Dept"}
+	overrides := map[string]CapcodeOverride{"0101001": {Name: "Fire Dept"}}
 
 	tests := []struct {
 		name     string
@@ -66,7 +67,7 @@ func TestNewNotifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			notifier := NewNotifier(tt.server, tt.topic, tt.token, tt.username, tt.password, translations, nil, logger)
+			notifier := NewNotifier(tt.server, tt.topic, tt.token, tt.username, tt.password, overrides, nil, logger)
 			assert.NotNil(t, notifier)
 			assert.Equal(t, tt.wantURL, notifier.server)
 			assert.Equal(t, tt.topic, notifier.topic)
@@ -94,7 +95,7 @@ func TestSend_Success(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:     "FLEX",
 		Message:  "Test alert",
 		Capcodes: []string{"0101001"},
@@ -105,6 +106,57 @@ func TestSend_Success(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSend_RuleOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "5", r.Header.Get("Priority"))
+		assert.Equal(t, "rotating_light,emergency,fire,utrecht", r.Header.Get("Tags"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	ctx := WithDelivery(context.Background(), Delivery{PriorityOverride: 5, Tags: []string{"fire", "utrecht"}})
+	err := notifier.Send(ctx, model.Message{Type: "FLEX", Message: "A1 Brand woning"})
+	assert.NoError(t, err)
+}
+
+func TestSend_Thread(t *testing.T) {
+	var titles, sequences []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		titles = append(titles, r.Header.Get("Title"))
+		sequences = append(sequences, r.Header.Get("X-Sequence-ID"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	ctx := context.Background()
+	require.NoError(t, notifier.Send(ctx, model.Message{Type: "FLEX", Message: "P 1 BR woning"}))
+	require.NoError(t, notifier.Send(WithDelivery(ctx, Delivery{Thread: "p2000-1"}), model.Message{Type: "FLEX", Message: "P 1 BR woning"}))
+	require.NoError(t, notifier.Send(WithDelivery(ctx, Delivery{Thread: "p2000-1", Update: true}), model.Message{Type: "FLEX", Message: "P 1 GRIP 1 BR woning"}))
+
+	assert.Equal(t, []string{"", "p2000-1", "p2000-1"}, sequences)
+	assert.Equal(t, []string{"🚨 P 1 BR woning", "🚨 P 1 BR woning", "Vervolg: 🚨 P 1 GRIP 1 BR woning"}, titles)
+}
+
+func TestSend_MessageID(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Message-ID"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	ctx := context.Background()
+	require.NoError(t, notifier.Send(ctx, model.Message{ID: "3f2a9c", Type: "FLEX", Message: "P 1 BR woning"}))
+	require.NoError(t, notifier.SendText(ctx, "Rapport", "Geen meldingen"))
+	notifier.SetJSON(true)
+	require.NoError(t, notifier.Send(ctx, model.Message{ID: "3f2a9c", Type: "FLEX", Message: "P 1 BR woning"}))
+
+	assert.Equal(t, []string{"3f2a9c", "", "3f2a9c"}, ids)
+}
+
 func TestSend_WithBearerToken(t *testing.T) {
 	logger := getTestLogger()
 
@@ -117,7 +169,7 @@ func TestSend_WithBearerToken(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "test-token-123", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test alert",
 	}
@@ -141,7 +193,7 @@ func TestSend_WithBasicAuth(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "testuser", "testpass", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test alert",
 	}
@@ -171,7 +223,7 @@ func TestSend_BasicAuthPreferredOverToken(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "token", "user", "pass", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -196,7 +248,7 @@ func TestSend_RetryOnFailure(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -218,7 +270,7 @@ func TestSend_MaxRetriesExceeded(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -239,7 +291,7 @@ func TestSend_ContextCancellation(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -263,7 +315,7 @@ func TestSend_ContextTimeout(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -281,24 +333,24 @@ func TestFormatTitle(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		msg      websocket.P2000Message
+		msg      model.Message
 		expected string
 	}{
 		{
 			name: "With message",
-			msg: websocket.P2000Message{
+			msg: model.Message{
 				Message: "Brand woning",
 			},
 			expected: "🚨 Brand woning",
 		},
 		{
 			name:     "Without message",
-			msg:      websocket.P2000Message{},
+			msg:      model.Message{},
 			expected: "🚨 P2000",
 		},
 		{
 			name: "Empty message",
-			msg: websocket.P2000Message{
+			msg: model.Message{
 				Message: "",
 			},
 			expected: "🚨 P2000",
@@ -317,7 +369,7 @@ func TestFormatMessage_NoLookup(t *testing.T) {
 	logger := getTestLogger()
 	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Capcodes: []string{"0101001", "0101002"},
 	}
 
@@ -342,7 +394,7 @@ func TestFormatMessage_WithLookup(t *testing.T) {
 
 	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Capcodes: []string{"0101001", "0101002"},
 	}
 
@@ -370,7 +422,7 @@ func TestFormatMessage_WithPartialLookup(t *testing.T) {
 	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, logger)
 
 	// First capcode exists, second doesn't
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Capcodes: []string{"0101001", "9999999"},
 	}
 
@@ -394,7 +446,7 @@ func TestFormatMessage_EmptyFields(t *testing.T) {
 
 	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Capcodes: []string{"0101001"},
 	}
 
@@ -551,7 +603,7 @@ func TestSend_FullIntegration(t *testing.T) {
 
 	notifier := NewNotifier(server.URL, "alerts", "my-token", "", "", nil, lookup, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Type:     "FLEX",
 		Message:  "Brand in gebouw",
 		Capcodes: []string{"0101001"},
@@ -592,7 +644,7 @@ func TestNotifier_MultipleCapcodes(t *testing.T) {
 
 	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, logger)
 
-	msg := websocket.P2000Message{
+	msg := model.Message{
 		Capcodes: []string{"0101001", "0101002", "0101003"},
 	}
 
@@ -612,3 +664,129 @@ func TestNotifier_MultipleCapcodes(t *testing.T) {
 	assert.Contains(t, result, "Oost")
 	assert.Contains(t, result, "West")
 }
+
+func TestSend_PublishHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"msg-123","event":"message"}`))
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+
+	var publishedID string
+	notifier.OnPublished(func(id string, msg model.Message) {
+		publishedID = id
+	})
+
+	err := notifier.Send(context.Background(), model.Message{Message: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "msg-123", publishedID)
+}
+
+func TestSend_DeliveryHook(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+
+	var results []DeliveryResult
+	notifier.OnDelivery(func(result DeliveryResult) {
+		results = append(results, result)
+	})
+
+	err := notifier.Send(context.Background(), model.Message{Message: "test"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, server.URL+"/test-topic", results[0].Destination)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, 2, results[0].Attempts)
+	assert.NoError(t, results[0].Err)
+}
+
+func TestSendText(t *testing.T) {
+	var title, tags, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title = r.Header.Get("Title")
+		tags = r.Header.Get("Tags")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+
+	err := notifier.SendText(context.Background(), "Report", "all good")
+	require.NoError(t, err)
+	assert.Equal(t, "Report", title)
+	assert.Equal(t, "bar_chart", tags)
+	assert.Equal(t, "all good", body)
+}
+
+func TestMessageTypeOverrides(t *testing.T) {
+	var priority, tags string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority = r.Header.Get("Priority")
+		tags = r.Header.Get("Tags")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	notifier.SetMessageTypes(map[string]MessageType{
+		"pocsag": {Tags: "pager", Priority: 2},
+		"FLEX":   {Priority: 5},
+	})
+
+	err := notifier.Send(context.Background(), model.Message{Type: "POCSAG", Message: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "2", priority)
+	assert.Equal(t, "pager", tags)
+
+	err = notifier.Send(context.Background(), model.Message{Type: "FLEX", Message: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "5", priority)
+	assert.Equal(t, "rotating_light,emergency", tags, "empty tags keep the default")
+
+	assert.Equal(t, defaultPriority, notifier.getPriority("UNKNOWN"))
+}
+
+func TestFormatMessage_Translated(t *testing.T) {
+	translator, err := i18n.New("en")
+	require.NoError(t, err)
+
+	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+	notifier.SetTranslator(translator)
+
+	msg := model.Message{Capcodes: []string{"0101001"}}
+	assert.Equal(t, "other\n", notifier.formatMessage(msg))
+	assert.Equal(t, "🚨 P2000", notifier.formatTitle(msg))
+}
+
+func TestFormatMessage_Translation(t *testing.T) {
+	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+
+	msg := model.Message{Message: "P 1 Brand woning", Translation: "P 1 Fire house"}
+	assert.Equal(t, "overig\n\n🌐 P 1 Fire house", notifier.formatMessage(msg))
+}
+
+func TestFormatMessage_Weather(t *testing.T) {
+	notifier := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+
+	msg := model.Message{
+		Message: "P 1 Natuurbrand Heide",
+		Weather: &model.Weather{Temperature: 8.5, WindSpeed: 12, WindGust: 18, WindDirection: 225},
+	}
+	assert.Equal(t, "overig\n\n🌬️ Wind ZW 6 Bft (12 m/s), 8.5 °C, windstoten 18 m/s", notifier.formatMessage(msg))
+
+	msg.Weather = &model.Weather{Temperature: -1, WindSpeed: 3, WindDirection: 350}
+	assert.Equal(t, "Wind N 2 Bft (3 m/s), -1.0 °C", notifier.formatWeather(*msg.Weather))
+}
@@ -0,0 +1,217 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+)
+
+// Notification backends
+const (
+	BackendNtfy   = "ntfy"
+	BackendGotify = "gotify"
+	BackendMatrix = "matrix"
+	BackendSMS    = "sms"
+	BackendCall   = "call"
+	BackendAPRS   = "aprs"
+)
+
+// Options configures a Notifier. Only Server and Topic are required, or
+// Server and Token for Gotify, Server, Token and Rooms for Matrix, and
+// Server, Token and SMS for SMS, Server, Token and Call for calls, and
+// Server, Token and APRS for APRS-IS.
+type Options struct {
+	Name     string // Identifies the destination, server/topic when empty
+	Server   string // The homeserver URL for Matrix, the provider API URL for SMS and calls, the APRS-IS HTTP submit URL for APRS
+	Topic    string // Unused by backends other than ntfy
+	Token    string // Optional authentication token (Bearer), the application token for Gotify, access token for Matrix, provider token for SMS and calls or passcode for APRS
+	Username string // Optional username for Basic Auth, preferred over Token
+	Password string
+
+	Backend          string      // BackendNtfy (default), BackendGotify, BackendMatrix, BackendSMS, BackendCall or BackendAPRS
+	GotifyPriorities map[int]int // Overrides DefaultGotifyPriorities
+	Rooms            []string    // Matrix room IDs, e.g. !abc123:matrix.org
+	SMS              SMSConfig   // Provider, sender, numbers and rate limit of the SMS backend
+	Call             CallConfig  // Twilio account, numbers and rate limit of the call backend
+	APRS             APRSConfig  // Callsign and addressees of the APRS backend
+
+	CapcodeOverrides map[string]CapcodeOverride // Display name, tags and priority per capcode, prefix or range
+	CapcodeLookup    *capcode.Lookup
+
+	MessageTypes map[string]MessageType
+	SpecialUnits []SpecialUnit
+	Templates    *Templates
+	MapImage     *MapImage         // Static map attached to geocoded incidents
+	Actions      *Actions          // Action buttons added to notifications
+	OwnUnit      OwnUnit           // Capcodes highlighted in the body and tagged
+	Details      CapcodeDetails    // Format of the capcode details section
+	Translator   *i18n.Translator  // Defaults to i18n.Default()
+	Headers      map[string]string // Extra ntfy headers, e.g. Icon, Email or Delay
+	Markdown     bool              // Render the body as Markdown
+	JSON         bool              // Publish with the JSON API instead of headers
+
+	MaxBodyLength int    // Maximum body length in bytes, 0 for no limit
+	Truncate      string // TruncateSummarize (default) or TruncateCut
+
+	Transport   http.RoundTripper // Defaults to http.DefaultTransport
+	OnPublished PublishHook
+	OnDelivery  []DeliveryHook
+
+	Breaker         BreakerConfig // Circuit breaker, disabled when Threshold is 0
+	OnBreakerChange BreakerHook
+
+	Logger zerolog.Logger
+}
+
+// Validate checks the options for missing or invalid values
+func (o Options) Validate() error {
+	if o.Server == "" {
+		return fmt.Errorf("ntfy server must be configured")
+	}
+	if u, err := url.Parse(o.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ntfy server %q must be an http(s) URL", o.Server)
+	}
+	switch o.Backend {
+	case "", BackendNtfy:
+		if o.Topic == "" {
+			return fmt.Errorf("ntfy topic must be configured")
+		}
+	case BackendGotify:
+		if o.Token == "" {
+			return fmt.Errorf("gotify application token must be configured")
+		}
+		if err := ValidateGotifyPriorities(o.GotifyPriorities); err != nil {
+			return err
+		}
+	case BackendMatrix:
+		if o.Token == "" {
+			return fmt.Errorf("matrix access token must be configured")
+		}
+		if err := ValidateRooms(o.Rooms); err != nil {
+			return err
+		}
+	case BackendSMS:
+		if o.Token == "" {
+			return fmt.Errorf("sms provider token must be configured")
+		}
+		if err := ValidateSMS(o.SMS); err != nil {
+			return err
+		}
+	case BackendCall:
+		if o.Token == "" {
+			return fmt.Errorf("twilio auth token must be configured")
+		}
+		if err := ValidateCall(o.Call); err != nil {
+			return err
+		}
+	case BackendAPRS:
+		if err := ValidateAPRS(o.APRS, o.Token); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown backend %q, expected ntfy, gotify, matrix, sms, call or aprs", o.Backend)
+	}
+	if o.Breaker.Threshold < 0 {
+		return fmt.Errorf("circuit breaker threshold must not be negative")
+	}
+	for name, mt := range o.MessageTypes {
+		if mt.Priority < 0 || mt.Priority > 5 {
+			return fmt.Errorf("message type %q priority must be between 1 and 5", name)
+		}
+	}
+	for code, override := range o.CapcodeOverrides {
+		if override.Priority < 0 || override.Priority > 5 {
+			return fmt.Errorf("capcode %s priority must be between 1 and 5", code)
+		}
+		if override.PriorityBump < 0 || override.PriorityBump > 4 {
+			return fmt.Errorf("capcode %s priority bump must be between 0 and 4", code)
+		}
+	}
+	for _, unit := range o.SpecialUnits {
+		if unit.Priority < 0 || unit.Priority > 5 {
+			return fmt.Errorf("special unit %q priority must be between 1 and 5", unit.Name)
+		}
+	}
+	if err := ValidateHeaders(o.Headers); err != nil {
+		return err
+	}
+	if err := ValidateTruncation(o.MaxBodyLength, o.Truncate); err != nil {
+		return err
+	}
+	if err := ValidateCapcodeDetails(o.Details); err != nil {
+		return fmt.Errorf("capcode details %w", err)
+	}
+	return nil
+}
+
+// New creates a ntfy notifier from validated options
+func New(opts Options) (*Notifier, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	n := NewNotifier(
+		opts.Server,
+		opts.Topic,
+		opts.Token,
+		opts.Username,
+		opts.Password,
+		opts.CapcodeOverrides,
+		opts.CapcodeLookup,
+		opts.Logger,
+	)
+	if opts.Name != "" {
+		n.SetName(opts.Name)
+	}
+	if opts.Transport != nil {
+		n.SetTransport(opts.Transport)
+	}
+	if opts.OnPublished != nil {
+		n.OnPublished(opts.OnPublished)
+	}
+	for _, hook := range opts.OnDelivery {
+		n.OnDelivery(hook)
+	}
+	n.SetMessageTypes(opts.MessageTypes)
+	n.SetSpecialUnits(opts.SpecialUnits)
+	n.SetOwnUnit(opts.OwnUnit)
+	n.SetCapcodeDetails(opts.Details)
+	n.SetTemplates(opts.Templates)
+	n.SetMapImage(opts.MapImage)
+	n.SetActions(opts.Actions)
+	n.SetHeaders(opts.Headers)
+	n.SetMarkdown(opts.Markdown)
+	n.SetJSON(opts.JSON)
+	n.SetTruncation(opts.MaxBodyLength, opts.Truncate)
+	switch opts.Backend {
+	case BackendGotify:
+		n.SetGotify(opts.GotifyPriorities)
+	case BackendMatrix:
+		n.SetMatrix(opts.Rooms)
+	case BackendSMS:
+		n.SetSMS(opts.SMS)
+	case BackendCall:
+		n.SetCall(opts.Call)
+	case BackendAPRS:
+		n.SetAPRS(opts.APRS)
+	}
+	if opts.Translator != nil {
+		n.SetTranslator(opts.Translator)
+	}
+	if opts.Breaker.Threshold > 0 {
+		var onChange func(BreakerState)
+		if opts.OnBreakerChange != nil {
+			name := n.Name()
+			onChange = func(state BreakerState) {
+				opts.OnBreakerChange(name, state)
+			}
+		}
+		n.SetBreaker(NewBreaker(opts.Breaker, onChange))
+	}
+
+	return n, nil
+}
@@ -0,0 +1,164 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		errorMsg string
+	}{
+		{
+			name: "Valid",
+			opts: Options{Server: "https://ntfy.sh", Topic: "p2000"},
+		},
+		{
+			name:     "Missing server",
+			opts:     Options{Topic: "p2000"},
+			errorMsg: "ntfy server must be configured",
+		},
+		{
+			name:     "Server without scheme",
+			opts:     Options{Server: "ntfy.sh", Topic: "p2000"},
+			errorMsg: `ntfy server "ntfy.sh" must be an http(s) URL`,
+		},
+		{
+			name:     "Missing topic",
+			opts:     Options{Server: "https://ntfy.sh"},
+			errorMsg: "ntfy topic must be configured",
+		},
+		{
+			name: "Valid gotify without topic",
+			opts: Options{Server: "https://gotify.example.com", Token: "app-token", Backend: BackendGotify},
+		},
+		{
+			name:     "Gotify without token",
+			opts:     Options{Server: "https://gotify.example.com", Backend: BackendGotify},
+			errorMsg: "gotify application token must be configured",
+		},
+		{
+			name:     "Invalid gotify priority",
+			opts:     Options{Server: "https://gotify.example.com", Token: "app-token", Backend: BackendGotify, GotifyPriorities: map[int]int{5: 11}},
+			errorMsg: "gotify priority 11 for priority 5 must be between 0 and 10",
+		},
+		{
+			name:     "Unknown backend",
+			opts:     Options{Server: "https://ntfy.sh", Topic: "p2000", Backend: "pushover"},
+			errorMsg: `unknown backend "pushover", expected ntfy, gotify, matrix, sms, call or aprs`,
+		},
+		{
+			name: "Invalid capcode priority bump",
+			opts: Options{
+				Server:           "https://ntfy.sh",
+				Topic:            "p2000",
+				CapcodeOverrides: map[string]CapcodeOverride{"1420059": {PriorityBump: 5}},
+			},
+			errorMsg: "capcode 1420059 priority bump must be between 0 and 4",
+		},
+		{
+			name: "Invalid message type priority",
+			opts: Options{
+				Server:       "https://ntfy.sh",
+				Topic:        "p2000",
+				MessageTypes: map[string]MessageType{"POCSAG": {Priority: 9}},
+			},
+			errorMsg: `message type "POCSAG" priority must be between 1 and 5`,
+		},
+		{
+			name: "Invalid special unit priority",
+			opts: Options{
+				Server:       "https://ntfy.sh",
+				Topic:        "p2000",
+				SpecialUnits: []SpecialUnit{{Name: "MMT", Priority: -1}},
+			},
+			errorMsg: `special unit "MMT" priority must be between 1 and 5`,
+		},
+		{
+			name: "Negative circuit breaker threshold",
+			opts: Options{
+				Server:  "https://ntfy.sh",
+				Topic:   "p2000",
+				Breaker: BreakerConfig{Threshold: -1},
+			},
+			errorMsg: "circuit breaker threshold must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestNew_AppliesOptions(t *testing.T) {
+	var priority, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority = r.Header.Get("Priority")
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"id":"abc"}`))
+	}))
+	defer server.Close()
+
+	var publishedID string
+	var results []DeliveryResult
+
+	n, err := New(Options{
+		Server:       server.URL + "/",
+		Topic:        "p2000",
+		Token:        "secret",
+		MessageTypes: map[string]MessageType{"FLEX": {Priority: 4}},
+		OnPublished: func(id string, msg model.Message) {
+			publishedID = id
+		},
+		OnDelivery: []DeliveryHook{func(result DeliveryResult) {
+			results = append(results, result)
+		}},
+		Logger: getTestLogger(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/p2000", n.Name())
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "test"}))
+	assert.Equal(t, "4", priority)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, "abc", publishedID)
+	assert.Len(t, results, 1)
+}
+
+func TestNew_Name(t *testing.T) {
+	var breakerNames []string
+	n, err := New(Options{
+		Name:            "ovd",
+		Server:          "https://gotify.example.com",
+		Token:           "app-token",
+		Backend:         BackendGotify,
+		Breaker:         BreakerConfig{Threshold: 1, Cooldown: time.Minute},
+		OnBreakerChange: func(destination string, _ BreakerState) { breakerNames = append(breakerNames, destination) },
+		Logger:          getTestLogger(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ovd", n.Name())
+	assert.Equal(t, []string{"ovd"}, breakerNames)
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	n, err := New(Options{Server: "https://ntfy.sh"})
+	assert.Error(t, err)
+	assert.Nil(t, n)
+}
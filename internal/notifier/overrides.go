@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+)
+
+// maxPriority is the highest ntfy priority
+const maxPriority = 5
+
+// CapcodeOverride changes how messages for a capcode are presented, e.g. to
+// make the alarms of your own station stand out
+type CapcodeOverride struct {
+	Name         string // Display name shown instead of the capcode database details
+	Tags         string // Comma separated ntfy tags or emoji
+	Priority     int    // Sets the ntfy priority 1-5, 0 keeps it
+	PriorityBump int    // Raises the ntfy priority by this many levels, up to 5
+}
+
+// patternOverride is the override of a capcode prefix or range
+type patternOverride struct {
+	matcher  *filter.Matcher
+	override CapcodeOverride
+}
+
+// SetCapcodeOverrides configures the per-capcode overrides. Keys are
+// capcodes, matching regardless of leading zeros, or prefixes and ranges
+// as accepted by filter.NewMatcher, e.g. for a whole safety region. The
+// override of a capcode takes precedence over those of prefixes and ranges.
+func (n *Notifier) SetCapcodeOverrides(overrides map[string]CapcodeOverride) {
+	n.overrides = make(map[string]CapcodeOverride, len(overrides))
+	n.patterns = nil
+	keys := make([]string, 0, len(overrides))
+	for code := range overrides {
+		keys = append(keys, code)
+	}
+	sort.Strings(keys)
+	for _, code := range keys {
+		if filter.IsCapcodePattern(code) {
+			n.patterns = append(n.patterns, patternOverride{
+				matcher:  filter.NewMatcher([]string{code}, true),
+				override: overrides[code],
+			})
+			continue
+		}
+		n.overrides[capcode.Normalize(code)] = overrides[code]
+	}
+}
+
+// capcodeOverride returns the override of a capcode, if any
+func (n *Notifier) capcodeOverride(code string) (CapcodeOverride, bool) {
+	if o, ok := n.overrides[capcode.Normalize(code)]; ok {
+		return o, true
+	}
+	for _, p := range n.patterns {
+		if p.matcher.Match(code) {
+			return p.override, true
+		}
+	}
+	return CapcodeOverride{}, false
+}
+
+// applyCapcodeOverrides prepends the tags of overridden capcodes, sets the
+// highest priority among them and raises it by the largest bump
+func (n *Notifier) applyCapcodeOverrides(capcodes []string, priority, tags string) (string, string) {
+	if len(n.overrides) == 0 && len(n.patterns) == 0 {
+		return priority, tags
+	}
+
+	var level, bump int
+	var extra []string
+	seen := make(map[string]bool)
+	for _, code := range capcodes {
+		o, ok := n.capcodeOverride(code)
+		if !ok {
+			continue
+		}
+		for _, tag := range strings.Split(o.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" && !seen[tag] {
+				seen[tag] = true
+				extra = append(extra, tag)
+			}
+		}
+		level = max(level, o.Priority)
+		bump = max(bump, o.PriorityBump)
+	}
+
+	if level > 0 {
+		priority = strconv.Itoa(level)
+	}
+	if bump > 0 {
+		level, _ := strconv.Atoi(priority)
+		priority = strconv.Itoa(min(level+bump, maxPriority))
+	}
+	if len(extra) > 0 {
+		tags = strings.Join(extra, ",") + "," + tags
+	}
+	return priority, tags
+}
@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyCapcodeOverrides(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", map[string]CapcodeOverride{
+		"1420059": {Name: "Mijn kazerne", Tags: "fire_engine,star", PriorityBump: 2},
+		"0101001": {Tags: "star"},
+		"0101002": {PriorityBump: 4},
+	}, nil, getTestLogger())
+
+	tests := []struct {
+		name         string
+		capcodes     []string
+		wantPriority string
+		wantTags     string
+	}{
+		{"Leading zeros", []string{"001420059"}, "5", "fire_engine,star,warning"},
+		{"Tags only", []string{"0101001"}, "3", "star,warning"},
+		{"Duplicate tags and largest bump", []string{"0101001", "1420059", "0101002"}, "5", "star,fire_engine,warning"},
+		{"No override", []string{"0999999"}, "3", "warning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priority, tags := n.applyCapcodeOverrides(tt.capcodes, "3", "warning")
+			assert.Equal(t, tt.wantPriority, priority)
+			assert.Equal(t, tt.wantTags, tags)
+		})
+	}
+
+	priority, _ := n.applyCapcodeOverrides([]string{"1420059"}, "1", "warning")
+	assert.Equal(t, "3", priority)
+}
+
+func TestApplyCapcodeOverrides_PriorityPerFilterEntry(t *testing.T) {
+	// The own kazerne is urgent, the rest of its region is not
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", map[string]CapcodeOverride{
+		"001420059":       {Name: "Mijn kazerne", Tags: "fire_engine", Priority: 5},
+		"00142*":          {Priority: 2},
+		"1500000-1509999": {Tags: "ambulance", Priority: 3},
+	}, nil, getTestLogger())
+
+	tests := []struct {
+		name         string
+		capcodes     []string
+		wantPriority string
+		wantTags     string
+	}{
+		{"Own kazerne", []string{"1420059"}, "5", "fire_engine,warning"},
+		{"Region prefix", []string{"001420060"}, "2", "warning"},
+		{"Kazerne and region", []string{"001420060", "001420059"}, "5", "fire_engine,warning"},
+		{"Range", []string{"1505000"}, "3", "ambulance,warning"},
+		{"No override", []string{"001430000"}, "4", "warning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priority, tags := n.applyCapcodeOverrides(tt.capcodes, "4", "warning")
+			assert.Equal(t, tt.wantPriority, priority)
+			assert.Equal(t, tt.wantTags, tags)
+		})
+	}
+
+	o, ok := n.capcodeOverride("001420059")
+	require.True(t, ok)
+	assert.Equal(t, "Mijn kazerne", o.Name, "the capcode takes precedence over its prefix")
+}
+
+func TestSend_CapcodeOverrides(t *testing.T) {
+	var priority, tags, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority, tags = r.Header.Get("Priority"), r.Header.Get("Tags")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:           server.URL,
+		Topic:            "p2000",
+		CapcodeOverrides: map[string]CapcodeOverride{"1420059": {Name: "Mijn kazerne", Tags: "fire_engine", PriorityBump: 1}},
+		Logger:           getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 BR woning", Capcodes: []string{"1420059"}}))
+	assert.Equal(t, "4", priority)
+	assert.Equal(t, "fire_engine,rotating_light,emergency", tags)
+	assert.Contains(t, body, "1420059 - Mijn kazerne")
+
+	templates, err := ParseTemplates("", `{{range .Capcodes}}{{.Name}}{{end}}`)
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 BR woning", Capcodes: []string{"1420059"}}))
+	assert.Equal(t, "Mijn kazerne", body)
+}
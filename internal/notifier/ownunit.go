@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+)
+
+// Defaults of an OwnUnit
+const (
+	DefaultOwnUnitMarker = "➡️"
+	DefaultOwnUnitTag    = "arrow_right"
+)
+
+// OwnUnit highlights the capcodes of the reader's own unit, so responders
+// find their unit first in a page to several units
+type OwnUnit struct {
+	Capcodes []string // Capcodes, prefixes or ranges, matching regardless of leading zeros
+	Marker   string   // Put before own capcodes in the body, DefaultOwnUnitMarker when empty
+	Tag      string   // ntfy tag of messages to the own unit, DefaultOwnUnitTag when empty
+}
+
+// ownUnit is an OwnUnit prepared for matching
+type ownUnit struct {
+	matcher *filter.Matcher
+	marker  string
+	tag     string
+}
+
+// SetOwnUnit configures the capcodes highlighted as the own unit; without
+// capcodes nothing is highlighted
+func (n *Notifier) SetOwnUnit(u OwnUnit) {
+	if len(u.Capcodes) == 0 {
+		n.ownUnit = nil
+		return
+	}
+	n.ownUnit = &ownUnit{
+		matcher: filter.NewMatcher(u.Capcodes, true),
+		marker:  u.Marker,
+		tag:     u.Tag,
+	}
+	if n.ownUnit.marker == "" {
+		n.ownUnit.marker = DefaultOwnUnitMarker
+	}
+	if n.ownUnit.tag == "" {
+		n.ownUnit.tag = DefaultOwnUnitTag
+	}
+}
+
+// isOwn reports whether code belongs to the own unit
+func (n *Notifier) isOwn(code string) bool {
+	return n.ownUnit != nil && n.ownUnit.matcher.Match(code)
+}
+
+// ownFirst returns capcodes with those of the own unit moved to the top,
+// keeping the feed order otherwise
+func (n *Notifier) ownFirst(capcodes []string) []string {
+	if n.ownUnit == nil {
+		return capcodes
+	}
+	sorted := make([]string, 0, len(capcodes))
+	for _, code := range capcodes {
+		if n.isOwn(code) {
+			sorted = append(sorted, code)
+		}
+	}
+	for _, code := range capcodes {
+		if !n.isOwn(code) {
+			sorted = append(sorted, code)
+		}
+	}
+	return sorted
+}
+
+// applyOwnUnit prepends the own unit tag when a capcode of the own unit is
+// paged
+func (n *Notifier) applyOwnUnit(capcodes []string, tags string) string {
+	for _, code := range capcodes {
+		if n.isOwn(code) {
+			for _, tag := range strings.Split(tags, ",") {
+				if strings.TrimSpace(tag) == n.ownUnit.tag {
+					return tags
+				}
+			}
+			if tags == "" {
+				return n.ownUnit.tag
+			}
+			return n.ownUnit.tag + "," + tags
+		}
+	}
+	return tags
+}
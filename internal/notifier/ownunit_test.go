@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatMessage_OwnUnitFirst(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Centrum", Function: "Kazernealarm"},
+		{Capcode: "0101002", Agency: "Brandweer", Region: "Utrecht", Station: "Overvecht", Function: "Kazernealarm"},
+	})
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"101002", "0999*"}})
+
+	msg := model.Message{Capcodes: []string{"0101001", "0101002", "0999001", "0888001"}}
+	assert.Equal(t, "Brandweer\n"+
+		"➡️ 0101002 - Utrecht, Overvecht, Kazernealarm\n"+
+		"\n➡️ 0999001\n"+
+		"\n0101001 - Utrecht, Centrum, Kazernealarm\n\n", n.formatMessage(msg))
+
+	n.SetOwnUnit(OwnUnit{})
+	assert.Equal(t, "Brandweer\n0101001 - Utrecht, Centrum, Kazernealarm\n\n0101002 - Utrecht, Overvecht, Kazernealarm\n\n\n", n.formatMessage(msg))
+}
+
+func TestFormatMessage_OwnUnitMarker(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"0101001"}, Marker: "**EIGEN**"})
+
+	msg := model.Message{Capcodes: []string{"0999001", "0101001"}}
+	assert.Equal(t, "overig\n**EIGEN** 0101001\n\n", n.formatMessage(msg))
+}
+
+func TestApplyOwnUnit(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+	assert.Equal(t, "warning", n.applyOwnUnit([]string{"0101001"}, "warning"), "no own unit configured")
+
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"0101001"}})
+	assert.Equal(t, "arrow_right,warning", n.applyOwnUnit([]string{"0999001", "0101001"}, "warning"))
+	assert.Equal(t, "arrow_right", n.applyOwnUnit([]string{"0101001"}, ""))
+	assert.Equal(t, "warning, arrow_right", n.applyOwnUnit([]string{"0101001"}, "warning, arrow_right"), "tag is added once")
+	assert.Equal(t, "warning", n.applyOwnUnit([]string{"0999001"}, "warning"))
+}
+
+func TestTemplates_OwnUnit(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"0101001"}})
+
+	templates, err := ParseTemplates("", `{{range .Capcodes}}{{if .Own}}{{.Capcode}}{{end}}{{end}}`)
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+
+	assert.Equal(t, "0101001", n.formatMessage(model.Message{Capcodes: []string{"0999001", "0101001"}}))
+}
+
+func TestSend_OwnUnit(t *testing.T) {
+	var tags string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags = r.Header.Get("Tags")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"0101001"}, Tag: "house"})
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 Brand", Capcodes: []string{"0101001"}}))
+	assert.Equal(t, "house,rotating_light,emergency", tags)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "P 1 Brand", Capcodes: []string{"0999001"}}))
+	assert.Equal(t, "rotating_light,emergency", tags)
+}
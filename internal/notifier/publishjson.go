@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// jsonFields set the fields of the ntfy JSON publish API that extra headers
+// map to, by header name without X- prefix. They are sent in the body, so
+// UTF-8 values need no header encoding.
+var jsonFields = map[string]func(body *publishJSON, value string){
+	"Icon":  func(body *publishJSON, value string) { body.Icon = value },
+	"Click": func(body *publishJSON, value string) { body.Click = value },
+	"Email": func(body *publishJSON, value string) { body.Email = value },
+	"Call":  func(body *publishJSON, value string) { body.Call = value },
+	"Delay": func(body *publishJSON, value string) { body.Delay = value },
+}
+
+// publishJSON is the body of a request to the ntfy JSON publish API
+type publishJSON struct {
+	Topic    string          `json:"topic"`
+	Message  string          `json:"message"`
+	Title    string          `json:"title,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	Attach   string          `json:"attach,omitempty"`
+	Filename string          `json:"filename,omitempty"`
+	Actions  json.RawMessage `json:"actions,omitempty"`
+	Markdown bool            `json:"markdown,omitempty"`
+	Icon     string          `json:"icon,omitempty"`
+	Click    string          `json:"click,omitempty"`
+	Email    string          `json:"email,omitempty"`
+	Call     string          `json:"call,omitempty"`
+	Delay    string          `json:"delay,omitempty"`
+}
+
+// SetJSON publishes notifications with the ntfy JSON API instead of
+// headers, avoiding header encoding problems with emoji and other UTF-8 in
+// titles, tags and extra fields
+func (n *Notifier) SetJSON(enabled bool) {
+	n.json = enabled
+}
+
+// newJSONRequest creates a publish request with the notification as JSON
+// body, posted to the server root. Extra headers without a JSON field, and
+// the sequence ID, are still sent as headers.
+func (n *Notifier) newJSONRequest(ctx context.Context, notif notification) (*http.Request, error) {
+	body := publishJSON{
+		Topic:    n.topic,
+		Message:  notif.message,
+		Title:    notif.title,
+		Attach:   notif.attach,
+		Markdown: n.markdown,
+	}
+	if notif.attach != "" {
+		body.Filename = notif.filename
+	}
+	for _, tag := range strings.Split(notif.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			body.Tags = append(body.Tags, tag)
+		}
+	}
+	if notif.priority != "" {
+		priority, err := strconv.Atoi(notif.priority)
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority %q", notif.priority)
+		}
+		body.Priority = priority
+	}
+	if notif.actions != "" {
+		body.Actions = json.RawMessage(notif.actions)
+	}
+
+	header := make(http.Header, len(n.headers))
+	for name, values := range n.headers {
+		if set, ok := jsonFields[strings.TrimPrefix(name, "X-")]; ok {
+			set(&body, values[0])
+		} else {
+			header[name] = values
+		}
+	}
+	if notif.sequence != "" {
+		header.Set("X-Sequence-ID", notif.sequence)
+	}
+	if notif.email != "" {
+		body.Email = notif.email
+	}
+	if notif.delay != "" {
+		body.Delay = notif.delay
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", n.server+"/", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_JSON(t *testing.T) {
+	var path string
+	var header http.Header
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		header = r.Header.Clone()
+		body = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{"id":"abc123"}`))
+	}))
+	defer server.Close()
+
+	actions, err := ParseActions([]Action{{Action: "view", Label: "Open", URL: "https://example.com"}})
+	require.NoError(t, err)
+	var published string
+	n, err := New(Options{
+		Server:      server.URL,
+		Topic:       "p2000",
+		Token:       "secret",
+		Headers:     map[string]string{"X-Icon": "https://example.com/p2000.png", "Email": "ops@example.com", "Firebase": "no"},
+		Markdown:    true,
+		JSON:        true,
+		Actions:     actions,
+		OnPublished: func(id string, msg model.Message) { published = id },
+		Logger:      getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 Brand woning"}))
+	assert.Equal(t, "/", path)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+	assert.Equal(t, "no", header.Get("Firebase"))
+	assert.Empty(t, header.Get("Title"))
+	assert.Empty(t, header.Get("X-Icon"))
+	assert.Equal(t, "abc123", published)
+
+	assert.Equal(t, "p2000", body["topic"])
+	assert.NotEmpty(t, body["message"])
+	assert.Equal(t, "🚨 A1 Brand woning", body["title"])
+	assert.Equal(t, float64(3), body["priority"])
+	assert.NotEmpty(t, body["tags"])
+	assert.Equal(t, true, body["markdown"])
+	assert.Equal(t, "https://example.com/p2000.png", body["icon"])
+	assert.Equal(t, "ops@example.com", body["email"])
+	assert.Equal(t, []any{map[string]any{"action": "view", "label": "Open", "url": "https://example.com"}}, body["actions"])
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1 melding"))
+	assert.Equal(t, "Rapport", body["title"])
+	assert.Equal(t, []any{"bar_chart"}, body["tags"])
+	assert.Nil(t, body["actions"])
+}
@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/rs/zerolog"
+)
+
+// Sender delivers P2000 messages to a single destination
+type Sender = notify.Sender
+
+// ErrSkipped is returned by Send when the destination did not deliver the
+// message on purpose
+var ErrSkipped = notify.ErrSkipped
+
+// Recipient is a person or group reachable through one or more channels,
+// listed in order of preference
+type Recipient struct {
+	Name     string
+	Channels []Sender
+}
+
+// RecipientDispatcher delivers each message once per recipient on their
+// preferred channel, falling back to the next channel when delivery fails
+// or the channel skips the message. A destination shared by several
+// recipients is only sent to once.
+type RecipientDispatcher struct {
+	recipients []Recipient
+	logger     zerolog.Logger
+}
+
+// NewRecipientDispatcher creates a new recipient dispatcher
+func NewRecipientDispatcher(recipients []Recipient, logger zerolog.Logger) *RecipientDispatcher {
+	return &RecipientDispatcher{
+		recipients: recipients,
+		logger:     logger,
+	}
+}
+
+// Name returns the dispatcher name
+func (d *RecipientDispatcher) Name() string {
+	return "recipients"
+}
+
+// Send delivers msg to every recipient. A recipient whose channels all
+// skipped msg is not a failure; ErrSkipped is returned when no recipient
+// was notified for that reason.
+func (d *RecipientDispatcher) Send(ctx context.Context, msg model.Message) error {
+	// Delivery result per destination, so shared destinations are tried once
+	results := make(map[string]error)
+	var failed []string
+	notified := 0
+
+	for _, recipient := range d.recipients {
+		delivered := false
+		skipped := len(recipient.Channels) > 0
+
+		for i, channel := range recipient.Channels {
+			err, tried := results[channel.Name()]
+			if !tried {
+				err = channel.Send(ctx, msg)
+				results[channel.Name()] = err
+			}
+
+			if err == nil {
+				d.logger.Debug().
+					Str("recipient", recipient.Name).
+					Str("channel", channel.Name()).
+					Bool("shared", tried).
+					Msg("recipient notified")
+				delivered = true
+				break
+			}
+			if errors.Is(err, ErrSkipped) {
+				d.logger.Debug().
+					Str("recipient", recipient.Name).
+					Str("channel", channel.Name()).
+					Msg("channel skipped message")
+				continue
+			}
+			skipped = false
+
+			if i < len(recipient.Channels)-1 {
+				d.logger.Warn().
+					Err(err).
+					Str("recipient", recipient.Name).
+					Str("channel", channel.Name()).
+					Msg("channel failed, falling back to next channel")
+			}
+		}
+
+		switch {
+		case delivered:
+			notified++
+		case !skipped:
+			failed = append(failed, recipient.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("delivery failed for recipients: %s", strings.Join(failed, ", "))
+	}
+	if notified == 0 && len(d.recipients) > 0 {
+		return ErrSkipped
+	}
+	return nil
+}
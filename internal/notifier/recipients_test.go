@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSender records deliveries and optionally fails
+type fakeSender struct {
+	name string
+	err  error
+	mu   sync.Mutex
+	sent int
+}
+
+func (f *fakeSender) Name() string {
+	return f.name
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg model.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent++
+	return f.err
+}
+
+func TestRecipientDispatcher_PreferredChannel(t *testing.T) {
+	primary := &fakeSender{name: "ntfy"}
+	fallback := &fakeSender{name: "backup"}
+
+	d := NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{primary, fallback}},
+	}, getTestLogger())
+
+	err := d.Send(context.Background(), model.Message{Message: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, primary.sent)
+	assert.Equal(t, 0, fallback.sent)
+}
+
+func TestRecipientDispatcher_Fallback(t *testing.T) {
+	primary := &fakeSender{name: "ntfy", err: errors.New("down")}
+	fallback := &fakeSender{name: "backup"}
+
+	d := NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{primary, fallback}},
+	}, getTestLogger())
+
+	err := d.Send(context.Background(), model.Message{Message: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, primary.sent)
+	assert.Equal(t, 1, fallback.sent)
+}
+
+func TestRecipientDispatcher_SharedDestinationSentOnce(t *testing.T) {
+	shared := &fakeSender{name: "ntfy"}
+	private := &fakeSender{name: "private"}
+
+	d := NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{shared}},
+		{Name: "bob", Channels: []Sender{shared, private}},
+		{Name: "carol", Channels: []Sender{private}},
+	}, getTestLogger())
+
+	err := d.Send(context.Background(), model.Message{Message: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, shared.sent)
+	assert.Equal(t, 1, private.sent)
+}
+
+func TestRecipientDispatcher_FailedDestinationNotRetried(t *testing.T) {
+	broken := &fakeSender{name: "ntfy", err: errors.New("down")}
+	fallback := &fakeSender{name: "backup"}
+
+	d := NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{broken, fallback}},
+		{Name: "bob", Channels: []Sender{broken}},
+	}, getTestLogger())
+
+	err := d.Send(context.Background(), model.Message{Message: "test"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "bob")
+	assert.NotContains(t, err.Error(), "alice")
+	assert.Equal(t, 1, broken.sent)
+	assert.Equal(t, 1, fallback.sent)
+}
+
+func TestRecipientDispatcher_SkippedChannel(t *testing.T) {
+	call := &fakeSender{name: "call", err: fmt.Errorf("call: %w", ErrSkipped)}
+	sms := &fakeSender{name: "sms"}
+
+	d := NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{call, sms}},
+	}, getTestLogger())
+	assert.NoError(t, d.Send(context.Background(), model.Message{Message: "test"}))
+	assert.Equal(t, 1, sms.sent, "falls back on a skipped channel")
+
+	// Skipped by every channel is neither delivered nor failed
+	d = NewRecipientDispatcher([]Recipient{
+		{Name: "alice", Channels: []Sender{call}},
+	}, getTestLogger())
+	assert.ErrorIs(t, d.Send(context.Background(), model.Message{Message: "test"}), ErrSkipped)
+}
+
+func TestNotifier_Name(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh/", "alerts", "", "", "", nil, nil, getTestLogger())
+	assert.Equal(t, "https://ntfy.sh/alerts", n.Name())
+}
@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SMS providers
+const (
+	SMSProviderTwilio      = "twilio"
+	SMSProviderMessageBird = "messagebird"
+)
+
+const (
+	DefaultSMSPerHour = 10  // SMS deliveries per hour when PerHour is 0
+	smsMaxLength      = 320 // Characters, two concatenated SMS
+)
+
+// SMSConfig configures the SMS backend
+type SMSConfig struct {
+	Provider string   // SMSProviderTwilio or SMSProviderMessageBird
+	Account  string   // Twilio account SID, unused for MessageBird
+	From     string   // Sender number or alphanumeric originator
+	Numbers  []string // Recipients in E.164 format, e.g. +31612345678
+	PerHour  int      // Deliveries per hour, DefaultSMSPerHour when 0
+}
+
+// smsProviders create the request sending body to a single number. The
+// token is the Twilio auth token or the MessageBird access key.
+var smsProviders = map[string]func(ctx context.Context, n *Notifier, to, body string) (*http.Request, error){
+	SMSProviderTwilio:      newTwilioRequest,
+	SMSProviderMessageBird: newMessageBirdRequest,
+}
+
+// ValidateSMS checks the SMS provider, sender, numbers and rate limit
+func ValidateSMS(cfg SMSConfig) error {
+	if _, ok := smsProviders[cfg.Provider]; !ok {
+		return fmt.Errorf("unknown sms provider %q, expected twilio or messagebird", cfg.Provider)
+	}
+	if cfg.Provider == SMSProviderTwilio && cfg.Account == "" {
+		return fmt.Errorf("twilio requires the account SID as account")
+	}
+	if cfg.From == "" {
+		return fmt.Errorf("sms sender must be configured as from")
+	}
+	if len(cfg.Numbers) == 0 {
+		return fmt.Errorf("sms requires at least one number")
+	}
+	for _, number := range cfg.Numbers {
+		if !isE164(number) {
+			return fmt.Errorf("sms number %q must be in E.164 format like +31612345678", number)
+		}
+	}
+	if cfg.PerHour < 0 {
+		return fmt.Errorf("sms per_hour must not be negative")
+	}
+	return nil
+}
+
+// isE164 reports whether number is a + followed by 8 to 15 digits
+func isE164(number string) bool {
+	digits, ok := strings.CutPrefix(number, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSMS sends notifications as SMS through the provider instead of ntfy,
+// at most cfg.PerHour deliveries per hour
+func (n *Notifier) SetSMS(cfg SMSConfig) {
+	if cfg.PerHour == 0 {
+		cfg.PerHour = DefaultSMSPerHour
+	}
+	n.backend = BackendSMS
+	n.sms = cfg
+	n.limiter = &rateLimiter{limit: cfg.PerHour, window: time.Hour}
+}
+
+// smsText renders notif as a single SMS text, truncated to smsMaxLength
+// characters
+func smsText(notif notification) string {
+	text := []rune(notif.title + "\n" + notif.message)
+	if len(text) > smsMaxLength {
+		text = append(text[:smsMaxLength-1], '…')
+	}
+	return string(text)
+}
+
+// publishSMS sends notif as SMS to every number that did not receive it on
+// an earlier attempt of the delivery
+func (n *Notifier) publishSMS(ctx context.Context, notif notification) error {
+	body := smsText(notif)
+	newRequest := smsProviders[n.sms.Provider]
+	return n.publishEach(notif, "number", n.sms.Numbers, func(to string) (*http.Request, error) {
+		return newRequest(ctx, n, to, body)
+	})
+}
+
+// publishEach makes the request of newRequest for every recipient, such as
+// a phone number, that was not reached on an earlier attempt of the
+// delivery. kind names the recipients in errors.
+func (n *Notifier) publishEach(notif notification, kind string, recipients []string, newRequest func(to string) (*http.Request, error)) error {
+	var errs []error
+	for _, to := range recipients {
+		if notif.delivered[to] {
+			continue
+		}
+		if err := n.sendTo(newRequest, to); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", kind, to, err))
+			continue
+		}
+		notif.delivered[to] = true
+	}
+	return errors.Join(errs...)
+}
+
+// sendTo makes the request for a single recipient
+func (n *Notifier) sendTo(newRequest func(to string) (*http.Request, error), to string) error {
+	req, err := newRequest(to)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newTwilioRequest creates a Twilio Messages API request, authenticated
+// with the account SID and auth token
+func newTwilioRequest(ctx context.Context, n *Notifier, to, body string) (*http.Request, error) {
+	form := url.Values{
+		"From": {n.sms.From},
+		"To":   {to},
+		"Body": {body},
+	}
+	u := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", n.server, url.PathEscape(n.sms.Account))
+	req, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(n.sms.Account, n.token)
+	return req, nil
+}
+
+// newMessageBirdRequest creates a MessageBird Messages API request,
+// authenticated with the access key
+func newMessageBirdRequest(ctx context.Context, n *Notifier, to, body string) (*http.Request, error) {
+	data, err := json.Marshal(map[string]any{
+		"originator": n.sms.From,
+		"recipients": []string{to},
+		"body":       body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", n.server+"/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "AccessKey "+n.token)
+	return req, nil
+}
@@ -0,0 +1,161 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_SMSTwilio(t *testing.T) {
+	var to []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "auth-token", pass)
+		assert.Equal(t, "+3197010000000", r.FormValue("From"))
+		assert.Equal(t, "Rapport\n1 melding", r.FormValue("Body"))
+		to = append(to, r.FormValue("To"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "auth-token",
+		Backend: BackendSMS,
+		SMS: SMSConfig{
+			Provider: SMSProviderTwilio,
+			Account:  "AC123",
+			From:     "+3197010000000",
+			Numbers:  []string{"+31612345678", "+31687654321"},
+		},
+		Logger: getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1 melding"))
+	assert.Equal(t, []string{"+31612345678", "+31687654321"}, to)
+}
+
+func TestSend_SMSMessageBird(t *testing.T) {
+	var body struct {
+		Originator string   `json:"originator"`
+		Recipients []string `json:"recipients"`
+		Body       string   `json:"body"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "AccessKey access-key", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "access-key",
+		Backend: BackendSMS,
+		SMS:     SMSConfig{Provider: SMSProviderMessageBird, From: "P2000", Numbers: []string{"+31612345678"}},
+		Logger:  getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", strings.Repeat("x", 400)))
+	assert.Equal(t, "P2000", body.Originator)
+	assert.Equal(t, []string{"+31612345678"}, body.Recipients)
+	assert.Len(t, []rune(body.Body), smsMaxLength)
+	assert.True(t, strings.HasSuffix(body.Body, "…"))
+}
+
+func TestSend_SMSRetrySkipsDeliveredNumbers(t *testing.T) {
+	var to []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		number := r.FormValue("To")
+		to = append(to, number)
+		if number == "+31687654321" && len(to) == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	n, err := New(Options{
+		Server:  server.URL,
+		Token:   "auth-token",
+		Backend: BackendSMS,
+		SMS: SMSConfig{
+			Provider: SMSProviderTwilio,
+			Account:  "AC123",
+			From:     "+3197010000000",
+			Numbers:  []string{"+31612345678", "+31687654321"},
+		},
+		Logger: getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1 melding"))
+	assert.Equal(t, []string{"+31612345678", "+31687654321", "+31687654321"}, to)
+}
+
+func TestSend_SMSRateLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var results []DeliveryResult
+	n, err := New(Options{
+		Server:     server.URL,
+		Token:      "access-key",
+		Backend:    BackendSMS,
+		SMS:        SMSConfig{Provider: SMSProviderMessageBird, From: "P2000", Numbers: []string{"+31612345678"}, PerHour: 2},
+		OnDelivery: []DeliveryHook{func(result DeliveryResult) { results = append(results, result) }},
+		Logger:     getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "1"))
+	require.NoError(t, n.SendText(context.Background(), "Rapport", "2"))
+	err = n.SendText(context.Background(), "Rapport", "3")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 2, requests)
+	require.Len(t, results, 3)
+	assert.False(t, results[2].Success)
+	assert.Equal(t, 0, results[2].Attempts)
+}
+
+func TestValidateSMS(t *testing.T) {
+	valid := SMSConfig{Provider: SMSProviderTwilio, Account: "AC123", From: "+3197010000000", Numbers: []string{"+31612345678"}}
+	assert.NoError(t, ValidateSMS(valid))
+
+	tests := []struct {
+		name   string
+		modify func(cfg *SMSConfig)
+		err    string
+	}{
+		{"unknown provider", func(cfg *SMSConfig) { cfg.Provider = "sinch" }, `unknown sms provider "sinch", expected twilio or messagebird`},
+		{"twilio without account", func(cfg *SMSConfig) { cfg.Account = "" }, "twilio requires the account SID as account"},
+		{"no sender", func(cfg *SMSConfig) { cfg.From = "" }, "sms sender must be configured as from"},
+		{"no numbers", func(cfg *SMSConfig) { cfg.Numbers = nil }, "sms requires at least one number"},
+		{"local number", func(cfg *SMSConfig) { cfg.Numbers = []string{"0612345678"} }, `sms number "0612345678" must be in E.164 format like +31612345678`},
+		{"negative limit", func(cfg *SMSConfig) { cfg.PerHour = -1 }, "sms per_hour must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			assert.EqualError(t, ValidateSMS(cfg), tt.err)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+)
+
+// SpecialUnit recognizes messages for special units such as trauma
+// helicopters by capcode or keyword and marks them with distinctive tags
+type SpecialUnit struct {
+	Name     string
+	Capcodes []string
+	Keywords []string // Matched case-insensitively against the start of words
+	Tags     string   // Comma separated ntfy tags
+	Priority int      // ntfy priority 1-5, 0 keeps the message priority
+}
+
+// DefaultSpecialUnits is the built-in mapping used when none is configured
+var DefaultSpecialUnits = []SpecialUnit{
+	{Name: "Lifeliner", Keywords: []string{"lifeliner"}, Tags: "helicopter", Priority: 5},
+	{Name: "MMT", Keywords: []string{"mmt"}, Tags: "helicopter", Priority: 5},
+	{Name: "Traumaheli", Keywords: []string{"traumaheli", "traumahelikopter"}, Tags: "helicopter", Priority: 5},
+	{Name: "Reddingsbrigade", Keywords: []string{"reddingsbrigade"}, Tags: "swimmer", Priority: 4},
+	{Name: "KNRM", Keywords: []string{"knrm"}, Tags: "ship", Priority: 4},
+}
+
+// SetSpecialUnits configures the special unit mapping table
+func (n *Notifier) SetSpecialUnits(units []SpecialUnit) {
+	n.specialUnits = units
+}
+
+// matchSpecialUnits returns the special units a message belongs to
+func (n *Notifier) matchSpecialUnits(capcodes []string, text string) []SpecialUnit {
+	if len(n.specialUnits) == 0 {
+		return nil
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var matched []SpecialUnit
+	for _, unit := range n.specialUnits {
+		if unit.matches(capcodes, words) {
+			matched = append(matched, unit)
+		}
+	}
+	return matched
+}
+
+// matches reports whether any capcode or word belongs to the unit
+func (u SpecialUnit) matches(capcodes, words []string) bool {
+	for _, want := range u.Capcodes {
+		for _, code := range capcodes {
+			if capcode.Normalize(code) == capcode.Normalize(want) {
+				return true
+			}
+		}
+	}
+
+	for _, keyword := range u.Keywords {
+		keyword = strings.ToLower(keyword)
+		for _, word := range words {
+			if strings.HasPrefix(word, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applySpecialUnits prepends the tags of matched units and raises the
+// priority to the highest one configured
+func applySpecialUnits(units []SpecialUnit, priority, tags string) (string, string) {
+	highest, _ := strconv.Atoi(priority)
+	var unitTags []string
+	seen := make(map[string]bool)
+
+	for _, unit := range units {
+		for _, tag := range strings.Split(unit.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" && !seen[tag] {
+				seen[tag] = true
+				unitTags = append(unitTags, tag)
+			}
+		}
+		if unit.Priority > highest {
+			highest = unit.Priority
+		}
+	}
+
+	if len(unitTags) > 0 {
+		tags = strings.Join(unitTags, ",") + "," + tags
+	}
+	return strconv.Itoa(highest), tags
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchSpecialUnits(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+	n.SetSpecialUnits(append(append([]SpecialUnit{}, DefaultSpecialUnits...), SpecialUnit{
+		Name:     "Rescue boat",
+		Capcodes: []string{"0123456"},
+		Tags:     "boat",
+	}))
+
+	tests := []struct {
+		name     string
+		capcodes []string
+		text     string
+		expected []string
+	}{
+		{"Lifeliner keyword", nil, "A1 Lifeliner1 Ambu 17101 Utrecht", []string{"Lifeliner"}},
+		{"Keyword prefix in uppercase", nil, "A1 MMT-UT Rit 12345", []string{"MMT"}},
+		{"Multiple units", nil, "P 1 KNRM en Reddingsbrigade Strand", []string{"Reddingsbrigade", "KNRM"}},
+		{"Capcode without leading zeros", []string{"123456"}, "P 2 Assistentie", []string{"Rescue boat"}},
+		{"No match", []string{"0101001"}, "P 1 Brand woning", nil},
+		{"Keyword inside word", nil, "P 2 Summt", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, unit := range n.matchSpecialUnits(tt.capcodes, tt.text) {
+				names = append(names, unit.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestApplySpecialUnits(t *testing.T) {
+	priority, tags := applySpecialUnits([]SpecialUnit{
+		{Tags: "helicopter", Priority: 5},
+		{Tags: "helicopter,ambulance", Priority: 4},
+	}, "3", "rotating_light")
+
+	assert.Equal(t, "5", priority)
+	assert.Equal(t, "helicopter,ambulance,rotating_light", tags)
+
+	priority, _ = applySpecialUnits([]SpecialUnit{{Tags: "ship"}}, "4", "warning")
+	assert.Equal(t, "4", priority, "units without priority keep the message priority")
+}
+
+func TestSend_SpecialUnit(t *testing.T) {
+	var priority, tags string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priority = r.Header.Get("Priority")
+		tags = r.Header.Get("Tags")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewNotifier(server.URL, "test-topic", "", "", "", nil, nil, getTestLogger())
+	n.SetSpecialUnits(DefaultSpecialUnits)
+
+	err := n.Send(context.Background(), model.Message{Type: "FLEX", Message: "A1 Lifeliner2 Rotterdam"})
+	require.NoError(t, err)
+	assert.Equal(t, "5", priority)
+	assert.Equal(t, "helicopter,rotating_light,emergency", tags)
+}
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// templateFuncs are available in notification templates
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+// TemplateData is passed to notification templates
+type TemplateData struct {
+	Message  model.Message // Raw message from the feed
+	Text     string        // Message text
+	Urgency  string        // Urgency code parsed from the text (A1, P 1, ...), empty when absent
+	Agency   string        // Agency of the first known capcode, translated "overig" when unknown
+	Capcodes []CapcodeData
+}
+
+// CapcodeData describes a capcode of the message in templates
+type CapcodeData struct {
+	Capcode string
+	Name    string               // Display name from the capcode overrides, empty when not configured
+	Info    *capcode.CapcodeInfo // nil when the capcode is not in the capcode database
+	Own     bool                 // The capcode belongs to the own unit
+}
+
+// Templates renders notification titles and bodies. An empty template keeps
+// the built-in layout.
+type Templates struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// ParseTemplates parses Go text/template sources for the title and body
+func ParseTemplates(title, body string) (*Templates, error) {
+	t := &Templates{}
+
+	if title != "" {
+		tmpl, err := template.New("title").Funcs(templateFuncs).Parse(title)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title template: %w", err)
+		}
+		t.title = tmpl
+	}
+	if body != "" {
+		tmpl, err := template.New("body").Funcs(templateFuncs).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+		t.body = tmpl
+	}
+
+	return t, nil
+}
+
+// SetTemplates configures the templates used for titles and bodies
+func (n *Notifier) SetTemplates(t *Templates) {
+	n.templates = t
+}
+
+// templateData collects the template data for a message
+func (n *Notifier) templateData(msg model.Message) TemplateData {
+	data := TemplateData{
+		Message: msg,
+		Text:    msg.Message,
+		Agency:  n.translator.T("agency.unknown"),
+	}
+
+	data.Urgency = msg.Priority
+	if data.Urgency == "" {
+		data.Urgency = model.ParsePriority(msg.Message)
+	}
+
+	for i, code := range msg.Capcodes {
+		var info *capcode.CapcodeInfo
+		if n.capcodeLookup != nil {
+			info = n.capcodeLookup.Get(code)
+		}
+		if i == 0 && info != nil {
+			data.Agency = info.Agency
+		}
+		override, _ := n.capcodeOverride(code)
+		data.Capcodes = append(data.Capcodes, CapcodeData{Capcode: code, Name: override.Name, Info: info, Own: n.isOwn(code)})
+	}
+
+	return data
+}
+
+// render executes tmpl for msg, reporting false when no template is set or
+// it fails so the caller falls back to the built-in layout
+func (n *Notifier) render(tmpl *template.Template, msg model.Message) (string, bool) {
+	if tmpl == nil {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n.templateData(msg)); err != nil {
+		n.logger.Warn().
+			Err(err).
+			Str("template", tmpl.Name()).
+			Msg("failed to render notification template, using default layout")
+		return "", false
+	}
+	return buf.String(), true
+}
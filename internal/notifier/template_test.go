@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates_TitleAndBody(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Centrum", Function: "Kazernealarm"},
+	})
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+
+	templates, err := ParseTemplates(
+		`[{{.Urgency}}] {{upper .Agency}}`,
+		`{{.Text}}{{range .Capcodes}}
+{{.Capcode}}{{with .Info}} {{.Station}}{{else}} onbekend{{end}}{{end}}`,
+	)
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+
+	msg := model.Message{
+		Message:  "P 1 Brand woning Utrecht",
+		Capcodes: []string{"0101001", "0999999"},
+	}
+
+	assert.Equal(t, "[P 1] BRANDWEER", n.formatTitle(msg))
+	assert.Equal(t, "P 1 Brand woning Utrecht\n0101001 Centrum\n0999999 onbekend", n.formatMessage(msg))
+}
+
+func TestTemplates_EmptyKeepsDefault(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+
+	templates, err := ParseTemplates(`{{.Agency}}`, "")
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+
+	msg := model.Message{Message: "A1 Rit", Capcodes: []string{"0101001"}}
+	assert.Equal(t, "overig", n.formatTitle(msg))
+	assert.Equal(t, "overig\n", n.formatMessage(msg))
+}
+
+func TestTemplates_ExecutionErrorFallsBack(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+
+	templates, err := ParseTemplates(`{{.Missing}}`, "")
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+
+	assert.Equal(t, "🚨 test", n.formatTitle(model.Message{Message: "test"}))
+}
+
+func TestParseTemplates_Invalid(t *testing.T) {
+	_, err := ParseTemplates(`{{.Text`, "")
+	assert.ErrorContains(t, err, "invalid title template")
+
+	_, err = ParseTemplates("", `{{unknown .Text}}`)
+	assert.ErrorContains(t, err, "invalid body template")
+}
+
+func TestTemplateData_Urgency(t *testing.T) {
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, nil, getTestLogger())
+
+	tests := map[string]string{
+		"A1 Rit 12345":       "A1",
+		"A2 Ambu":            "A2",
+		"B2 Besteld vervoer": "B2",
+		"P 1 BR woning":      "P 1",
+		"P2 Assistentie":     "P2",
+		"Proefalarm":         "",
+		"":                   "",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, n.templateData(model.Message{Message: text}).Urgency, text)
+	}
+}
@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Truncation strategies of bodies over the maximum length
+const (
+	TruncateSummarize = "summarize" // Keep the agency line and own unit, and count the other units that don't fit
+	TruncateCut       = "cut"       // Cut the body at the maximum length
+)
+
+// MinBodyLength is the smallest maximum body length, leaving room for the
+// agency line and a summary of the units
+const MinBodyLength = 100
+
+// body is a default notification body in parts, so it can be shortened
+// without losing the agency and the own unit
+type body struct {
+	header  string   // Agency line
+	units   []string // Capcode details entries, the own unit first
+	own     int      // Number of entries of the own unit
+	omitted int      // Capcodes left out of the entries by the capcode details
+	footer  string   // Weather and translation
+}
+
+// joinUnits joins the header with the first kept entries of b, counting
+// the other capcodes in a "+N more units" line
+func (n *Notifier) joinUnits(b body, kept int) string {
+	s := b.header + strings.Join(b.units[:kept], "")
+	if omitted := len(b.units) - kept + b.omitted; omitted > 0 {
+		s += n.moreUnits(omitted)
+	}
+	return s
+}
+
+// ValidateTruncation checks a maximum body length in bytes, 0 for no
+// limit, and its truncation strategy
+func ValidateTruncation(maxLength int, strategy string) error {
+	if maxLength != 0 && maxLength < MinBodyLength {
+		return fmt.Errorf("max_body_length must be 0 or at least %d", MinBodyLength)
+	}
+	switch strategy {
+	case "", TruncateSummarize, TruncateCut:
+		return nil
+	default:
+		return fmt.Errorf("unknown truncate strategy %q, expected %s or %s", strategy, TruncateSummarize, TruncateCut)
+	}
+}
+
+// SetTruncation limits notification bodies to maxLength bytes, shortening
+// longer ones with strategy, TruncateSummarize when empty. Large pages to
+// many units would otherwise exceed the payload limits of ntfy and FCM.
+func (n *Notifier) SetTruncation(maxLength int, strategy string) {
+	if strategy == "" {
+		strategy = TruncateSummarize
+	}
+	n.maxBody, n.truncate = maxLength, strategy
+}
+
+// shorten cuts a body to the maximum length
+func (n *Notifier) shorten(s string) string {
+	if n.maxBody == 0 {
+		return s
+	}
+	return cut(s, n.maxBody)
+}
+
+// summarize fits a default body in the maximum length. The agency line and
+// the own unit are kept, followed by as many other units as fit and a
+// "+N more units" line; the weather and translation are kept when they fit.
+// Whatever is still too long is cut.
+func (n *Notifier) summarize(b body) string {
+	s := n.joinUnits(b, len(b.units)) + b.footer
+	if n.maxBody == 0 || len(s) <= n.maxBody || n.truncate == TruncateCut {
+		return n.shorten(s)
+	}
+
+	kept := b.own
+	for kept < len(b.units) && len(n.joinUnits(b, kept+1))+len(b.footer) <= n.maxBody {
+		kept++
+	}
+	short := n.joinUnits(b, kept)
+	if len(short)+len(b.footer) <= n.maxBody {
+		short += b.footer
+	}
+	return cut(short, n.maxBody)
+}
+
+// cut shortens s to at most limit bytes ending in an ellipsis, without
+// splitting a UTF-8 sequence
+func cut(s string, limit int) string {
+	const ellipsis = "…"
+	if len(s) <= limit {
+		return s
+	}
+	i := limit - len(ellipsis)
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + ellipsis
+}
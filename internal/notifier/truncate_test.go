@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largePage returns a lookup and a message paging 20 fire stations
+func largePage() (*capcode.Lookup, model.Message) {
+	var records []capcode.CapcodeInfo
+	var msg model.Message
+	for i := 1; i <= 20; i++ {
+		code := fmt.Sprintf("01010%02d", i)
+		records = append(records, capcode.CapcodeInfo{Capcode: code, Agency: "Brandweer", Region: "Utrecht", Station: fmt.Sprintf("Post %d", i)})
+		msg.Capcodes = append(msg.Capcodes, code)
+	}
+	return capcode.NewLookupFromRecords(records), msg
+}
+
+func TestFormatMessage_Summarize(t *testing.T) {
+	lookup, msg := largePage()
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+	n.SetOwnUnit(OwnUnit{Capcodes: []string{"0101017"}})
+	n.SetTruncation(120, "")
+
+	assert.Equal(t, "Brandweer\n"+
+		"➡️ 0101017 - Utrecht, Post 17\n"+
+		"\n0101001 - Utrecht, Post 1\n"+
+		"\n0101002 - Utrecht, Post 2\n"+
+		"\n+17 andere eenheden\n", n.formatMessage(msg))
+
+	msg.Translation = "Fire"
+	body := n.formatMessage(msg)
+	assert.True(t, strings.HasSuffix(body, "\n+18 andere eenheden\n\n🌐 Fire"), body)
+	assert.LessOrEqual(t, len(body), 120)
+
+	n.SetTruncation(0, "")
+	assert.Contains(t, n.formatMessage(msg), "0101020 - Utrecht, Post 20\n\n🌐 Fire", "no limit")
+}
+
+func TestFormatMessage_Cut(t *testing.T) {
+	lookup, msg := largePage()
+	n := NewNotifier("https://ntfy.sh", "topic", "", "", "", nil, lookup, getTestLogger())
+	n.SetTruncation(100, TruncateCut)
+
+	body := n.formatMessage(msg)
+	assert.Len(t, body, 100)
+	assert.True(t, strings.HasSuffix(body, "…"))
+
+	templates, err := ParseTemplates("", strings.Repeat("🚒", 50))
+	require.NoError(t, err)
+	n.SetTemplates(templates)
+	n.SetTruncation(100, TruncateSummarize)
+	assert.Equal(t, strings.Repeat("🚒", 24)+"…", n.formatMessage(msg), "templates are cut at a character boundary")
+}
+
+func TestValidateTruncation(t *testing.T) {
+	assert.NoError(t, ValidateTruncation(0, ""))
+	assert.NoError(t, ValidateTruncation(4000, TruncateCut))
+	assert.EqualError(t, ValidateTruncation(50, ""), "max_body_length must be 0 or at least 100")
+	assert.EqualError(t, ValidateTruncation(4000, "drop"), `unknown truncate strategy "drop", expected summarize or cut`)
+}
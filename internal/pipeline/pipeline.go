@@ -0,0 +1,157 @@
+// Package pipeline routes messages from a single source through several
+// independent forwarding pipelines, each with its own filters and senders.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Pipeline forwards the messages accepted by its filter, and matching its
+// pattern when set, to its senders
+type Pipeline struct {
+	Name    string
+	Filter  filter.Filter
+	Pattern *regexp.Regexp // Matched against the message text, nil matches all
+	Senders []notifier.Sender
+}
+
+// accepts reports whether the pipeline forwards msg
+func (p Pipeline) accepts(msg model.Message) bool {
+	return p.Filter.ShouldForward(msg.Capcodes) && (p.Pattern == nil || p.Pattern.MatchString(msg.Message))
+}
+
+// Router delivers each message to every pipeline whose filter accepts it.
+// It is a filter itself, accepting messages that any pipeline accepts.
+type Router struct {
+	pipelines []Pipeline
+	logger    zerolog.Logger
+}
+
+// NewRouter creates a router for pipelines
+func NewRouter(pipelines []Pipeline, logger zerolog.Logger) *Router {
+	return &Router{
+		pipelines: pipelines,
+		logger:    logger,
+	}
+}
+
+// Name returns the router name
+func (r *Router) Name() string {
+	return "pipelines"
+}
+
+// ShouldForward reports whether any pipeline accepts the capcodes, without
+// checking the patterns
+func (r *Router) ShouldForward(capcodes []string) bool {
+	return len(r.Match(capcodes)) > 0
+}
+
+// Accepts reports whether any pipeline accepts msg, including the patterns
+func (r *Router) Accepts(msg model.Message) bool {
+	for _, p := range r.pipelines {
+		if p.accepts(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the names of the pipelines accepting the capcodes, without
+// checking the patterns
+func (r *Router) Match(capcodes []string) []string {
+	var names []string
+	for _, p := range r.pipelines {
+		if p.Filter.ShouldForward(capcodes) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Explain reports the outcome of the filter of each pipeline, without
+// checking the patterns
+func (r *Router) Explain(capcodes []string) filter.Step {
+	step := filter.Step{Filter: "pipelines"}
+	for _, p := range r.pipelines {
+		s := filter.Explain(p.Filter, capcodes)
+		s.Name = p.Name
+		step.Forward = step.Forward || s.Forward
+		step.Steps = append(step.Steps, s)
+	}
+	return step
+}
+
+// ExplainMessage reports the outcome of the filter and pattern of each
+// pipeline for msg
+func (r *Router) ExplainMessage(msg model.Message) filter.Step {
+	step := filter.Step{Filter: "pipelines"}
+	for _, p := range r.pipelines {
+		s := filter.Explain(p.Filter, msg.Capcodes)
+		if p.Pattern != nil {
+			pattern := filter.Step{Filter: "pattern", Forward: p.Pattern.MatchString(msg.Message)}
+			s = filter.Step{Filter: "all", Forward: s.Forward && pattern.Forward, Steps: []filter.Step{s, pattern}}
+		}
+		s.Name = p.Name
+		step.Forward = step.Forward || s.Forward
+		step.Steps = append(step.Steps, s)
+	}
+	return step
+}
+
+// Send delivers msg through every matching pipeline. A failing pipeline
+// does not keep the others from being notified. notifier.ErrSkipped is
+// returned when every destination skipped msg.
+func (r *Router) Send(ctx context.Context, msg model.Message) error {
+	var failed []string
+	sent, skipped := 0, 0
+
+	for _, p := range r.pipelines {
+		if !p.accepts(msg) {
+			continue
+		}
+
+		delivered := true
+		for _, sender := range p.Senders {
+			err := sender.Send(ctx, msg)
+			switch {
+			case err == nil:
+				sent++
+			case errors.Is(err, notifier.ErrSkipped):
+				skipped++
+			default:
+				r.logger.Warn().
+					Err(err).
+					Str("pipeline", p.Name).
+					Str("destination", sender.Name()).
+					Msg("pipeline delivery failed")
+				delivered = false
+			}
+		}
+		if !delivered {
+			failed = append(failed, p.Name)
+			continue
+		}
+
+		r.logger.Debug().
+			Str("pipeline", p.Name).
+			Int("destinations", len(p.Senders)).
+			Msg("pipeline notified")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("delivery failed for pipelines: %s", strings.Join(failed, ", "))
+	}
+	if sent == 0 && skipped > 0 {
+		return notifier.ErrSkipped
+	}
+	return nil
+}
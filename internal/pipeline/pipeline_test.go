@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// fakeSender records deliveries and optionally fails
+type fakeSender struct {
+	name string
+	err  error
+	mu   sync.Mutex
+	sent int
+}
+
+func (f *fakeSender) Name() string {
+	return f.name
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg model.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent++
+	return f.err
+}
+
+func newTestRouter(fire, ambulance, public notifier.Sender) *Router {
+	logger := getTestLogger()
+	return NewRouter([]Pipeline{
+		{Name: "brandweer", Filter: filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger), Senders: []notifier.Sender{fire}},
+		{Name: "ambulance", Filter: filter.NewCapcodeFilter(false, []string{"1420059"}, false, logger), Senders: []notifier.Sender{ambulance}},
+		{Name: "public", Filter: filter.NewCapcodeFilter(true, nil, false, logger), Senders: []notifier.Sender{public}},
+	}, logger)
+}
+
+func TestRouter_Match(t *testing.T) {
+	r := newTestRouter(&fakeSender{}, &fakeSender{}, &fakeSender{})
+
+	assert.Equal(t, []string{"brandweer", "public"}, r.Match([]string{"0101001"}))
+	assert.Equal(t, []string{"brandweer", "ambulance", "public"}, r.Match([]string{"0101001", "1420059"}))
+	assert.True(t, r.ShouldForward([]string{"9999999"}))
+}
+
+func TestRouter_Explain(t *testing.T) {
+	r := newTestRouter(&fakeSender{}, &fakeSender{}, &fakeSender{})
+
+	assert.Equal(t, filter.Step{
+		Filter:  "pipelines",
+		Forward: true,
+		Steps: []filter.Step{
+			{Filter: "capcode", Name: "brandweer", Forward: true},
+			{Filter: "capcode", Name: "ambulance", Forward: false},
+			{Filter: "capcode", Name: "public", Forward: true},
+		},
+	}, filter.Explain(r, []string{"0101001"}))
+}
+
+func TestRouter_ShouldForwardWithoutMatch(t *testing.T) {
+	logger := getTestLogger()
+	r := NewRouter([]Pipeline{
+		{Name: "brandweer", Filter: filter.NewCapcodeFilter(false, []string{"0101001"}, false, logger)},
+	}, logger)
+
+	assert.False(t, r.ShouldForward([]string{"9999999"}))
+	assert.Empty(t, r.Match([]string{"9999999"}))
+}
+
+func TestRouter_SendsToMatchingPipelines(t *testing.T) {
+	fire := &fakeSender{name: "fire"}
+	ambulance := &fakeSender{name: "ambulance"}
+	public := &fakeSender{name: "public"}
+	r := newTestRouter(fire, ambulance, public)
+
+	err := r.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fire.sent)
+	assert.Equal(t, 0, ambulance.sent)
+	assert.Equal(t, 1, public.sent)
+}
+
+func TestRouter_FailingPipeline(t *testing.T) {
+	fire := &fakeSender{name: "fire", err: errors.New("down")}
+	ambulance := &fakeSender{name: "ambulance"}
+	public := &fakeSender{name: "public"}
+	r := newTestRouter(fire, ambulance, public)
+
+	err := r.Send(context.Background(), model.Message{Capcodes: []string{"0101001", "1420059"}})
+	assert.EqualError(t, err, "delivery failed for pipelines: brandweer")
+	assert.Equal(t, 1, ambulance.sent)
+	assert.Equal(t, 1, public.sent)
+}
+
+func TestRouter_SkippedPipeline(t *testing.T) {
+	fire := &fakeSender{name: "fire", err: notifier.ErrSkipped}
+	ambulance := &fakeSender{name: "ambulance"}
+	public := &fakeSender{name: "public", err: notifier.ErrSkipped}
+	r := newTestRouter(fire, ambulance, public)
+
+	assert.NoError(t, r.Send(context.Background(), model.Message{Capcodes: []string{"0101001", "1420059"}}))
+	assert.ErrorIs(t, r.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}}), notifier.ErrSkipped)
+}
+
+func TestRouter_Pattern(t *testing.T) {
+	logger := getTestLogger()
+	fire, public := &fakeSender{}, &fakeSender{}
+	r := NewRouter([]Pipeline{
+		{Name: "brand", Filter: filter.NewCapcodeFilter(true, nil, false, logger), Pattern: regexp.MustCompile(`(?i)\bbrand\b`), Senders: []notifier.Sender{fire}},
+		{Name: "public", Filter: filter.NewCapcodeFilter(false, []string{"1420059"}, false, logger), Senders: []notifier.Sender{public}},
+	}, logger)
+
+	brand := model.Message{Capcodes: []string{"0101001"}, Message: "P 1 BRT-01 Brand woning Utrecht"}
+	other := model.Message{Capcodes: []string{"0101001"}, Message: "P 2 BRT-01 Nacontrole Utrecht"}
+
+	assert.True(t, r.Accepts(brand))
+	assert.False(t, r.Accepts(other))
+	// Capcodes alone do not check the pattern
+	assert.True(t, r.ShouldForward(other.Capcodes))
+
+	assert.NoError(t, r.Send(context.Background(), brand))
+	assert.NoError(t, r.Send(context.Background(), other))
+	assert.Equal(t, 1, fire.sent)
+	assert.Equal(t, 0, public.sent)
+
+	step := r.ExplainMessage(other)
+	assert.False(t, step.Forward)
+	assert.Equal(t, "all", step.Steps[0].Filter)
+	assert.Equal(t, "brand", step.Steps[0].Name)
+	assert.True(t, step.Steps[0].Steps[0].Forward)
+	assert.Equal(t, filter.Step{Filter: "pattern"}, step.Steps[0].Steps[1])
+	assert.Equal(t, "public", step.Steps[1].Name)
+	assert.True(t, r.ExplainMessage(brand).Forward)
+}
@@ -0,0 +1,75 @@
+// Package plugins loads custom filters and notifiers built as Go plugins,
+// see pkg/plugin. Go plugins need a forwarder built with cgo on Linux, macOS
+// or FreeBSD; elsewhere loading fails.
+package plugins
+
+import (
+	"fmt"
+	goplugin "plugin"
+
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/kaije/p2000-nfty/pkg/plugin"
+)
+
+// symbolTable is the part of a *plugin.Plugin the loader uses
+type symbolTable interface {
+	Lookup(name string) (goplugin.Symbol, error)
+}
+
+// open opens a plugin, replaced in tests
+var open = func(path string) (symbolTable, error) {
+	return goplugin.Open(path)
+}
+
+// Filter is a loaded filter plugin
+type Filter struct {
+	Name string
+	plugin.Filter
+}
+
+// LoadFilter opens the plugin at path and creates its filter with config
+func LoadFilter(name, path string, config map[string]string) (Filter, error) {
+	sym, err := lookup(path, plugin.FilterSymbol)
+	if err != nil {
+		return Filter{}, err
+	}
+	newFilter, ok := sym.(plugin.NewFilterFunc)
+	if !ok {
+		return Filter{}, fmt.Errorf("plugin %s: %s has type %T, expected %T", path, plugin.FilterSymbol, sym, newFilter)
+	}
+	f, err := newFilter(config)
+	if err != nil {
+		return Filter{}, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	return Filter{Name: name, Filter: f}, nil
+}
+
+// LoadNotifier opens the plugin at path and creates its sender with config
+func LoadNotifier(path string, config map[string]string) (notify.Sender, error) {
+	sym, err := lookup(path, plugin.NotifierSymbol)
+	if err != nil {
+		return nil, err
+	}
+	newNotifier, ok := sym.(plugin.NewNotifierFunc)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: %s has type %T, expected %T", path, plugin.NotifierSymbol, sym, newNotifier)
+	}
+	s, err := newNotifier(config)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// lookup opens the plugin at path and returns the symbol it exports as name
+func lookup(path, name string) (goplugin.Symbol, error) {
+	p, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
+	}
+	sym, err := p.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	return sym, nil
+}
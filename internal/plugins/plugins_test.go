@@ -0,0 +1,95 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goplugin "plugin"
+	"strings"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+	"github.com/kaije/p2000-nfty/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePlugin exports symbols by name
+type fakePlugin map[string]goplugin.Symbol
+
+func (p fakePlugin) Lookup(name string) (goplugin.Symbol, error) {
+	sym, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("symbol %s not found", name)
+	}
+	return sym, nil
+}
+
+// keywordFilter drops messages containing a keyword
+type keywordFilter string
+
+func (k keywordFilter) Allow(msg model.Message) bool {
+	return !strings.Contains(msg.Message, string(k))
+}
+
+// usePlugins makes open return the fake plugins by path
+func usePlugins(t *testing.T, plugins map[string]fakePlugin) {
+	t.Helper()
+	orig := open
+	open = func(path string) (symbolTable, error) {
+		p, ok := plugins[path]
+		if !ok {
+			return nil, errors.New("no such file")
+		}
+		return p, nil
+	}
+	t.Cleanup(func() { open = orig })
+}
+
+func TestLoadFilter(t *testing.T) {
+	usePlugins(t, map[string]fakePlugin{
+		"keyword.so": {plugin.FilterSymbol: func(config map[string]string) (plugin.Filter, error) {
+			if config["keyword"] == "" {
+				return nil, errors.New("keyword is required")
+			}
+			return keywordFilter(config["keyword"]), nil
+		}},
+		"wrong.so": {plugin.FilterSymbol: func() bool { return true }},
+	})
+
+	f, err := LoadFilter("no-proefalarm", "keyword.so", map[string]string{"keyword": "Proefalarm"})
+	require.NoError(t, err)
+	assert.Equal(t, "no-proefalarm", f.Name)
+	assert.False(t, f.Allow(model.Message{Message: "Proefalarm brandweer"}))
+	assert.True(t, f.Allow(model.Message{Message: "P 1 Brand woning"}))
+
+	_, err = LoadFilter("f", "keyword.so", nil)
+	assert.ErrorContains(t, err, "keyword is required")
+	_, err = LoadFilter("f", "wrong.so", nil)
+	assert.ErrorContains(t, err, "NewFilter has type func() bool")
+	_, err = LoadFilter("f", "missing.so", nil)
+	assert.ErrorContains(t, err, "failed to open plugin")
+}
+
+func TestLoadNotifier(t *testing.T) {
+	var sent []string
+	usePlugins(t, map[string]fakePlugin{
+		"log.so": {plugin.NotifierSymbol: func(config map[string]string) (notify.Sender, error) {
+			return notify.SenderFunc(config["name"], func(ctx context.Context, msg model.Message) error {
+				sent = append(sent, msg.Message)
+				return nil
+			}), nil
+		}},
+		"filter.so": {plugin.FilterSymbol: func(config map[string]string) (plugin.Filter, error) { return nil, nil }},
+	})
+
+	s, err := LoadNotifier("log.so", map[string]string{"name": "log"})
+	require.NoError(t, err)
+	assert.Equal(t, "log", s.Name())
+	require.NoError(t, s.Send(context.Background(), model.Message{Message: "A1 Reanimatie"}))
+	assert.Equal(t, []string{"A1 Reanimatie"}, sent)
+
+	_, err = LoadNotifier("filter.so", nil)
+	assert.ErrorContains(t, err, "NewNotifier not found")
+}
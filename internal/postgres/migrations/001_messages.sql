@@ -0,0 +1,13 @@
+CREATE TABLE messages (
+    id          bigserial PRIMARY KEY,
+    instance    text        NOT NULL,
+    message_id  text,
+    received_at timestamptz NOT NULL,
+    forwarded   boolean     NOT NULL,
+    capcodes    jsonb       NOT NULL,
+    text        text        NOT NULL,
+    message     jsonb       NOT NULL,
+    UNIQUE (instance, message_id)
+);
+
+CREATE INDEX messages_received_at ON messages (received_at);
@@ -0,0 +1,11 @@
+CREATE TABLE notifications (
+    id          bigserial PRIMARY KEY,
+    instance    text        NOT NULL,
+    message_id  text,
+    sent_at     timestamptz NOT NULL,
+    success     boolean     NOT NULL,
+    duration_ms integer     NOT NULL,
+    error       text
+);
+
+CREATE INDEX notifications_message ON notifications (instance, message_id);
@@ -0,0 +1,7 @@
+CREATE TABLE claims (
+    key        text        PRIMARY KEY,
+    instance   text        NOT NULL,
+    claimed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX claims_claimed_at ON claims (claimed_at);
@@ -0,0 +1,245 @@
+// Package postgres writes the messages and notification outcomes to a
+// PostgreSQL database, so several forwarder instances can share one
+// history, and records which instance notifies each message. The schema is
+// created and upgraded by embedded migrations.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Driver is the database/sql driver name used to open the database. The
+// driver itself, e.g. github.com/jackc/pgx/v5/stdlib, must be registered by
+// the binary.
+var Driver = "pgx"
+
+const (
+	DefaultBuffer = 1000
+
+	writeTimeout = 5 * time.Second
+
+	claimRetention = 24 * time.Hour // Instances receive a message within seconds of each other
+	pruneInterval  = time.Hour
+)
+
+// Options configures a Sink. Only DSN is required.
+type Options struct {
+	DSN      string // postgres:// URL or key=value connection string
+	Instance string // Name of this forwarder in the shared tables, the hostname when empty
+	Buffer   int    // Writes waiting before new ones are dropped, DefaultBuffer when 0
+
+	Metrics *metrics.Metrics // Optional
+	Logger  zerolog.Logger
+}
+
+// write is a queued INSERT
+type write struct {
+	table string
+	query string
+	args  []any
+}
+
+// Sink writes rows in the background, in the order they were queued.
+// Writes never block the feed: when the buffer is full, because the
+// database is down or slow, rows are dropped.
+type Sink struct {
+	db       *sql.DB
+	instance string
+	writes   chan write
+	metrics  *metrics.Metrics
+	logger   zerolog.Logger
+}
+
+// ValidateDSN checks that dsn is a postgres:// URL or a key=value
+// connection string
+func ValidateDSN(dsn string) error {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			return fmt.Errorf("postgres dsn must be a postgres:// URL or key=value connection string")
+		}
+		return nil
+	}
+	if !strings.Contains(dsn, "=") {
+		return fmt.Errorf("postgres dsn must be a postgres:// URL or key=value connection string")
+	}
+	return nil
+}
+
+// Open connects to the database and applies the missing migrations
+func Open(ctx context.Context, opts Options) (*Sink, error) {
+	if err := ValidateDSN(opts.DSN); err != nil {
+		return nil, err
+	}
+	if opts.Buffer < 0 {
+		return nil, fmt.Errorf("postgres buffer must not be negative")
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	if opts.Instance == "" {
+		opts.Instance, _ = os.Hostname()
+	}
+
+	db, err := sql.Open(Driver, opts.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	applied, err := migrate(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if applied > 0 {
+		opts.Logger.Info().Int("migrations", applied).Msg("postgres schema migrated")
+	}
+
+	return &Sink{
+		db:       db,
+		instance: opts.Instance,
+		writes:   make(chan write, opts.Buffer),
+		metrics:  opts.Metrics,
+		logger:   opts.Logger,
+	}, nil
+}
+
+// RecordMessage queues msg for the messages table. A message written
+// before by this instance, with the same history ID, is skipped.
+func (s *Sink) RecordMessage(msg model.Message, receivedAt time.Time, forwarded bool) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to encode message for postgres")
+		return
+	}
+	capcodes, err := json.Marshal(msg.Capcodes)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to encode capcodes for postgres")
+		return
+	}
+	if msg.Capcodes == nil {
+		capcodes = []byte("[]")
+	}
+
+	s.queue(write{
+		table: "messages",
+		query: `INSERT INTO messages (instance, message_id, received_at, forwarded, capcodes, text, message)
+VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7::jsonb)
+ON CONFLICT (instance, message_id) DO NOTHING`,
+		args: []any{s.instance, nullable(msg.ID), receivedAt.UTC(), forwarded, string(capcodes), msg.Message, string(data)},
+	})
+}
+
+// RecordNotification queues the outcome of notifying msg for the
+// notifications table; sendErr is nil when it was delivered
+func (s *Sink) RecordNotification(msg model.Message, sentAt time.Time, duration time.Duration, sendErr error) {
+	var errText any
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+	s.queue(write{
+		table: "notifications",
+		query: `INSERT INTO notifications (instance, message_id, sent_at, success, duration_ms, error)
+VALUES ($1, $2, $3, $4, $5, $6)`,
+		args: []any{s.instance, nullable(msg.ID), sentAt.UTC(), sendErr == nil, duration.Milliseconds(), errText},
+	})
+}
+
+// Claim records that this instance notifies the message identified by
+// key, reporting false when another instance claimed it first
+func (s *Sink) Claim(ctx context.Context, key string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO claims (key, instance) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, key, s.instance)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// queue adds w to the buffer, dropping it when the buffer is full
+func (s *Sink) queue(w write) {
+	select {
+	case s.writes <- w:
+	default:
+		s.record(w.table, "dropped")
+		s.logger.Warn().Str("table", w.table).Msg("postgres buffer full, dropping row")
+	}
+}
+
+// Run writes queued rows and prunes expired claims until ctx is
+// cancelled, then closes the database
+func (s *Sink) Run(ctx context.Context) {
+	defer s.db.Close()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case w := <-s.writes:
+			s.write(ctx, w)
+		case <-ticker.C:
+			s.pruneClaims(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write executes w once. The connection pool of database/sql reconnects,
+// so a failure only loses this row.
+func (s *Sink) write(ctx context.Context, w write) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, w.query, w.args...); err != nil {
+		s.record(w.table, "failed")
+		s.logger.Error().Err(err).Str("table", w.table).Msg("failed to write to postgres")
+		return
+	}
+	s.record(w.table, "written")
+}
+
+// pruneClaims deletes the claims older than claimRetention. Every
+// instance prunes; deleting twice is harmless.
+func (s *Sink) pruneClaims(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	cutoff := time.Now().Add(-claimRetention).UTC()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM claims WHERE claimed_at < $1`, cutoff); err != nil {
+		s.logger.Warn().Err(err).Msg("failed to prune claims")
+	}
+}
+
+// record counts a row by its table and result when metrics are configured
+func (s *Sink) record(table, result string) {
+	if s.metrics != nil {
+		s.metrics.RecordPostgresWrite(table, result)
+	}
+}
+
+// nullable returns nil for an empty string, stored as NULL
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
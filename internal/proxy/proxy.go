@@ -0,0 +1,132 @@
+// Package proxy resolves the outbound HTTP or SOCKS5 proxy used to reach
+// the feed and the notification backends.
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Direct disables the proxy for a backend
+const Direct = "direct"
+
+// Func returns the proxy URL for a request, nil for a direct connection
+type Func func(*http.Request) (*url.URL, error)
+
+// Parse parses an http, https, socks5 or socks5h proxy URL. Direct is
+// accepted as well.
+func Parse(raw string) (*url.URL, error) {
+	if raw == Direct {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy url %q must use http, https, socks5 or socks5h", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", raw)
+	}
+	return u, nil
+}
+
+// ParseWebSocket parses a proxy URL for the WebSocket feed, which only
+// supports http and socks5 proxies
+func ParseWebSocket(raw string) (*url.URL, error) {
+	u, err := Parse(raw)
+	if err != nil || u == nil {
+		return u, err
+	}
+	if u.Scheme != "http" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("proxy url %q cannot be used for the websocket feed, use http or socks5", raw)
+	}
+	return u, nil
+}
+
+// New returns the proxy function for raw. An empty raw uses HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY from the environment, Direct connects directly.
+// Hosts matching noProxy, a comma separated list of host names, domains
+// (".example.com" or "example.com" including subdomains), IP addresses or
+// CIDR ranges, are reached directly.
+func New(raw, noProxy string) (Func, error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return func(*http.Request) (*url.URL, error) { return nil, nil }, nil
+	}
+
+	bypass := parseNoProxy(noProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass.match(req.URL.Hostname()) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// noProxy holds the hosts reached without the proxy
+type noProxy struct {
+	all     bool
+	hosts   []string
+	domains []string // With a leading dot
+	nets    []*net.IPNet
+}
+
+func parseNoProxy(s string) noProxy {
+	var np noProxy
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case entry == "*":
+			np.all = true
+		case strings.Contains(entry, "/"):
+			if _, n, err := net.ParseCIDR(entry); err == nil {
+				np.nets = append(np.nets, n)
+			}
+		case strings.HasPrefix(entry, "."):
+			np.domains = append(np.domains, entry)
+		default:
+			np.hosts = append(np.hosts, entry)
+			np.domains = append(np.domains, "."+entry)
+		}
+	}
+	return np
+}
+
+func (np noProxy) match(host string) bool {
+	host = strings.ToLower(host)
+	if np.all {
+		return true
+	}
+	for _, h := range np.hosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, d := range np.domains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range np.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
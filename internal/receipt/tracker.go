@@ -0,0 +1,285 @@
+package receipt
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	maxReceipts    = 1000
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 60 * time.Second
+	pollTimeout    = 30 * time.Second
+)
+
+// Receipt records the delivery state of a published notification. ID is the
+// ntfy message ID, MessageID the ID of the P2000 message it notified.
+type Receipt struct {
+	ID          string     `json:"id"`
+	MessageID   string     `json:"message_id,omitempty"`
+	Capcodes    []string   `json:"capcodes"`
+	Message     string     `json:"message"`
+	PublishedAt time.Time  `json:"published_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Delivered reports whether the ntfy server broadcast the message to subscribers
+func (r Receipt) Delivered() bool {
+	return r.DeliveredAt != nil
+}
+
+// event is a single line of the ntfy JSON stream
+type event struct {
+	ID    string `json:"id"`
+	Time  int64  `json:"time"`
+	Event string `json:"event"`
+}
+
+// Tracker subscribes to an ntfy topic and records when published
+// notifications show up in the topic's event stream. ntfy does not expose
+// per-device open events, so delivery means the server fanned the message
+// out to its subscribers.
+type Tracker struct {
+	server       string
+	topic        string
+	token        string
+	username     string
+	password     string
+	pollInterval time.Duration
+	httpClient   *http.Client
+	logger       zerolog.Logger
+
+	mu          sync.Mutex
+	receipts    map[string]*Receipt
+	order       []string
+	seen        map[string]time.Time // events received before their publish response
+	lastID      string
+	onDelivered []func(Receipt)
+}
+
+// NewTracker creates a receipt tracker for an ntfy topic. A pollInterval of
+// zero keeps a streaming subscription open instead of polling.
+func NewTracker(server, topic, token, username, password string, pollInterval time.Duration, logger zerolog.Logger) *Tracker {
+	return &Tracker{
+		server:       strings.TrimSuffix(server, "/"),
+		topic:        topic,
+		token:        token,
+		username:     username,
+		password:     password,
+		pollInterval: pollInterval,
+		httpClient:   &http.Client{},
+		logger:       logger,
+		receipts:     make(map[string]*Receipt),
+		seen:         make(map[string]time.Time),
+	}
+}
+
+// OnDelivered registers a callback invoked when a notification is delivered
+func (t *Tracker) OnDelivered(fn func(Receipt)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onDelivered = append(t.onDelivered, fn)
+}
+
+// Published records a notification accepted by the ntfy server
+func (t *Tracker) Published(id string, msg model.Message) {
+	t.mu.Lock()
+
+	r := &Receipt{
+		ID:          id,
+		MessageID:   msg.ID,
+		Capcodes:    msg.Capcodes,
+		Message:     msg.Message,
+		PublishedAt: time.Now(),
+	}
+	t.receipts[id] = r
+	t.order = append(t.order, id)
+	if len(t.order) > maxReceipts {
+		delete(t.receipts, t.order[0])
+		t.order = t.order[1:]
+	}
+
+	// The stream may deliver the event before the publish response arrives
+	deliveredAt, early := t.seen[id]
+	delete(t.seen, id)
+	t.mu.Unlock()
+
+	if early {
+		t.markDelivered(id, deliveredAt)
+	}
+}
+
+// Get returns the receipt for a ntfy message ID
+func (t *Tracker) Get(id string) (Receipt, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.receipts[id]
+	if !ok {
+		return Receipt{}, false
+	}
+	return *r, true
+}
+
+// Run subscribes to the topic until ctx is cancelled, reconnecting with
+// exponential backoff
+func (t *Tracker) Run(ctx context.Context) {
+	backoff := initialBackoff
+
+	for {
+		var err error
+		if t.pollInterval > 0 {
+			err = t.poll(ctx)
+		} else {
+			err = t.stream(ctx)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := t.pollInterval
+		if err != nil {
+			t.logger.Warn().Err(err).Dur("backoff", backoff).Msg("receipt subscription failed, retrying")
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			backoff = initialBackoff
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stream keeps a long-lived JSON stream subscription open
+func (t *Tracker) stream(ctx context.Context) error {
+	return t.subscribe(ctx, false)
+}
+
+// poll fetches all events since the last seen event once
+func (t *Tracker) poll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+	return t.subscribe(ctx, true)
+}
+
+// subscribe reads events from the topic's JSON endpoint
+func (t *Tracker) subscribe(ctx context.Context, poll bool) error {
+	t.mu.Lock()
+	since := t.lastID
+	t.mu.Unlock()
+
+	query := url.Values{}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if poll {
+		query.Set("poll", "1")
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/json", t.server, t.topic)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	} else if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var ev event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.logger.Debug().Err(err).Msg("failed to parse ntfy event")
+			continue
+		}
+		if ev.Event != "message" || ev.ID == "" {
+			continue
+		}
+		t.handleEvent(ev)
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("stream read failed: %w", err)
+	}
+	return nil
+}
+
+// handleEvent records a message event from the topic
+func (t *Tracker) handleEvent(ev event) {
+	at := time.Now()
+	if ev.Time > 0 {
+		at = time.Unix(ev.Time, 0)
+	}
+
+	t.mu.Lock()
+	t.lastID = ev.ID
+	_, known := t.receipts[ev.ID]
+	if !known {
+		t.seen[ev.ID] = at
+		if len(t.seen) > maxReceipts {
+			for id := range t.seen {
+				delete(t.seen, id)
+				break
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	if known {
+		t.markDelivered(ev.ID, at)
+	}
+}
+
+// markDelivered sets the delivery time and runs the delivery callbacks
+func (t *Tracker) markDelivered(id string, at time.Time) {
+	t.mu.Lock()
+	r, ok := t.receipts[id]
+	if !ok || r.DeliveredAt != nil {
+		t.mu.Unlock()
+		return
+	}
+	r.DeliveredAt = &at
+	receipt := *r
+	callbacks := append([]func(Receipt){}, t.onDelivered...)
+	t.mu.Unlock()
+
+	t.logger.Debug().
+		Str("id", id).
+		Dur("latency", at.Sub(receipt.PublishedAt)).
+		Msg("notification delivered")
+
+	for _, fn := range callbacks {
+		fn(receipt)
+	}
+}
@@ -0,0 +1,122 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestTracker_PublishedThenDelivered(t *testing.T) {
+	tracker := NewTracker("https://ntfy.sh", "test", "", "", "", 0, getTestLogger())
+
+	var delivered []Receipt
+	tracker.OnDelivered(func(r Receipt) {
+		delivered = append(delivered, r)
+	})
+
+	tracker.Published("abc", model.Message{ID: "p2000-1", Message: "Brand woning", Capcodes: []string{"0101001"}})
+
+	r, ok := tracker.Get("abc")
+	require.True(t, ok)
+	assert.False(t, r.Delivered())
+
+	tracker.handleEvent(event{ID: "abc", Event: "message", Time: time.Now().Unix()})
+	tracker.handleEvent(event{ID: "abc", Event: "message", Time: time.Now().Unix()})
+
+	r, ok = tracker.Get("abc")
+	require.True(t, ok)
+	assert.True(t, r.Delivered())
+	require.Len(t, delivered, 1)
+	assert.Equal(t, "Brand woning", delivered[0].Message)
+	assert.Equal(t, "p2000-1", delivered[0].MessageID)
+}
+
+func TestTracker_EventBeforePublish(t *testing.T) {
+	tracker := NewTracker("https://ntfy.sh", "test", "", "", "", 0, getTestLogger())
+
+	tracker.handleEvent(event{ID: "abc", Event: "message"})
+	tracker.Published("abc", model.Message{Message: "test"})
+
+	r, ok := tracker.Get("abc")
+	require.True(t, ok)
+	assert.True(t, r.Delivered())
+}
+
+func TestTracker_Eviction(t *testing.T) {
+	tracker := NewTracker("https://ntfy.sh", "test", "", "", "", 0, getTestLogger())
+
+	for i := 0; i < maxReceipts+10; i++ {
+		tracker.Published(fmt.Sprintf("id-%d", i), model.Message{})
+	}
+
+	_, ok := tracker.Get("id-0")
+	assert.False(t, ok)
+	_, ok = tracker.Get(fmt.Sprintf("id-%d", maxReceipts+9))
+	assert.True(t, ok)
+}
+
+func TestTracker_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/test/json", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		fmt.Fprintln(w, `{"id":"x1","time":1700000000,"event":"open"}`)
+		fmt.Fprintln(w, `not json`)
+		fmt.Fprintln(w, `{"id":"abc","time":1700000000,"event":"message"}`)
+	}))
+	defer server.Close()
+
+	tracker := NewTracker(server.URL, "test", "secret", "", "", 0, getTestLogger())
+	tracker.Published("abc", model.Message{})
+
+	require.NoError(t, tracker.stream(context.Background()))
+
+	r, ok := tracker.Get("abc")
+	require.True(t, ok)
+	assert.True(t, r.Delivered())
+	assert.Equal(t, int64(1700000000), r.DeliveredAt.Unix())
+}
+
+func TestTracker_PollUsesSince(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "1", r.URL.Query().Get("poll"))
+		if n > 1 {
+			assert.Equal(t, "first", r.URL.Query().Get("since"))
+		}
+		fmt.Fprintln(w, `{"id":"first","event":"message"}`)
+	}))
+	defer server.Close()
+
+	tracker := NewTracker(server.URL, "test", "", "", "", time.Second, getTestLogger())
+	require.NoError(t, tracker.poll(context.Background()))
+	require.NoError(t, tracker.poll(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestTracker_SubscribeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	tracker := NewTracker(server.URL, "test", "", "", "", 0, getTestLogger())
+	err := tracker.stream(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code: 403")
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+)
+
+// delivery is a single recorded notification outcome
+type delivery struct {
+	at      time.Time
+	success bool
+	retries int
+	latency time.Duration
+}
+
+// DestinationStats summarizes deliveries to one destination over a window
+type DestinationStats struct {
+	Destination   string        `json:"destination"`
+	Sent          int           `json:"sent"`
+	Failed        int           `json:"failed"`
+	Retries       int           `json:"retries"`
+	MedianLatency time.Duration `json:"median_latency"`
+}
+
+// Summary holds the statistics for a report window
+type Summary struct {
+	Since        time.Time          `json:"since"`
+	Until        time.Time          `json:"until"`
+	Received     int                `json:"received"`
+	Forwarded    int                `json:"forwarded"`
+	Destinations []DestinationStats `json:"destinations"`
+}
+
+// Collector records messages and deliveries for periodic reports.
+// Events older than the retention window are discarded.
+type Collector struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu         sync.Mutex
+	messages   []time.Time
+	forwarded  []time.Time
+	deliveries map[string][]delivery
+}
+
+// NewCollector creates a collector that keeps events for retention
+func NewCollector(retention time.Duration) *Collector {
+	return &Collector{
+		retention:  retention,
+		now:        time.Now,
+		deliveries: make(map[string][]delivery),
+	}
+}
+
+// RecordMessage counts a received message
+func (c *Collector) RecordMessage(forwarded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.messages = append(prune(c.messages, now.Add(-c.retention)), now)
+	if forwarded {
+		c.forwarded = append(prune(c.forwarded, now.Add(-c.retention)), now)
+	}
+}
+
+// RecordDelivery records a notifier delivery outcome; it can be registered
+// directly as a notifier.DeliveryHook
+func (c *Collector) RecordDelivery(result notifier.DeliveryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	retries := result.Attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+
+	events := c.deliveries[result.Destination]
+	cutoff := now.Add(-c.retention)
+	for len(events) > 0 && events[0].at.Before(cutoff) {
+		events = events[1:]
+	}
+	c.deliveries[result.Destination] = append(events, delivery{
+		at:      now,
+		success: result.Success,
+		retries: retries,
+		latency: result.Duration,
+	})
+}
+
+// Summary returns the statistics of the last window
+func (c *Collector) Summary(window time.Duration) Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	since := now.Add(-window)
+
+	summary := Summary{
+		Since:     since,
+		Until:     now,
+		Received:  countSince(c.messages, since),
+		Forwarded: countSince(c.forwarded, since),
+	}
+
+	for dest, events := range c.deliveries {
+		stats := DestinationStats{Destination: dest}
+		var latencies []time.Duration
+		for _, e := range events {
+			if e.at.Before(since) {
+				continue
+			}
+			if e.success {
+				stats.Sent++
+				latencies = append(latencies, e.latency)
+			} else {
+				stats.Failed++
+			}
+			stats.Retries += e.retries
+		}
+		if stats.Sent == 0 && stats.Failed == 0 {
+			continue
+		}
+		stats.MedianLatency = median(latencies)
+		summary.Destinations = append(summary.Destinations, stats)
+	}
+
+	sort.Slice(summary.Destinations, func(i, j int) bool {
+		return summary.Destinations[i].Destination < summary.Destinations[j].Destination
+	})
+	return summary
+}
+
+// prune drops timestamps before cutoff from a chronologically sorted slice
+func prune(times []time.Time, cutoff time.Time) []time.Time {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(cutoff) })
+	return times[i:]
+}
+
+// countSince counts timestamps at or after since
+func countSince(times []time.Time, since time.Time) int {
+	return len(prune(times, since))
+}
+
+// median returns the median of the durations, zero when empty
+func median(values []time.Duration) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a controllable time source
+func fakeClock(start time.Time) (func() time.Time, func(time.Duration)) {
+	now := start
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCollector_Summary(t *testing.T) {
+	c := NewCollector(time.Hour)
+	clock, advance := fakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c.now = clock
+
+	c.RecordMessage(true)
+	c.RecordMessage(false)
+	c.RecordDelivery(notifier.DeliveryResult{Destination: "b", Success: true, Attempts: 1, Duration: 100 * time.Millisecond})
+	c.RecordDelivery(notifier.DeliveryResult{Destination: "a", Success: true, Attempts: 3, Duration: 300 * time.Millisecond})
+	c.RecordDelivery(notifier.DeliveryResult{Destination: "a", Success: true, Attempts: 1, Duration: 100 * time.Millisecond})
+	c.RecordDelivery(notifier.DeliveryResult{Destination: "a", Success: false, Attempts: 3, Duration: 5 * time.Second})
+	advance(time.Minute)
+
+	s := c.Summary(time.Hour)
+	assert.Equal(t, 2, s.Received)
+	assert.Equal(t, 1, s.Forwarded)
+	require.Len(t, s.Destinations, 2)
+
+	assert.Equal(t, DestinationStats{
+		Destination:   "a",
+		Sent:          2,
+		Failed:        1,
+		Retries:       4,
+		MedianLatency: 200 * time.Millisecond,
+	}, s.Destinations[0])
+	assert.Equal(t, "b", s.Destinations[1].Destination)
+	assert.Equal(t, 100*time.Millisecond, s.Destinations[1].MedianLatency)
+}
+
+func TestCollector_Window(t *testing.T) {
+	c := NewCollector(time.Hour)
+	clock, advance := fakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c.now = clock
+
+	c.RecordMessage(true)
+	c.RecordDelivery(notifier.DeliveryResult{Destination: "a", Success: true, Attempts: 1})
+	advance(2 * time.Hour)
+	c.RecordMessage(false)
+
+	s := c.Summary(time.Hour)
+	assert.Equal(t, 1, s.Received)
+	assert.Equal(t, 0, s.Forwarded)
+	assert.Empty(t, s.Destinations)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, time.Duration(0), median(nil))
+	assert.Equal(t, 2*time.Second, median([]time.Duration{3 * time.Second, time.Second, 2 * time.Second}))
+	assert.Equal(t, 1500*time.Millisecond, median([]time.Duration{2 * time.Second, time.Second}))
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/rs/zerolog"
+)
+
+const sendTimeout = 30 * time.Second
+
+// TextSender delivers a plain text notification
+type TextSender interface {
+	SendText(ctx context.Context, title, message string) error
+}
+
+// Reporter periodically sends a summary of the collected statistics
+type Reporter struct {
+	collector  *Collector
+	sender     TextSender
+	interval   time.Duration
+	translator *i18n.Translator
+	logger     zerolog.Logger
+}
+
+// NewReporter creates a reporter sending a report every interval in the
+// language of translator
+func NewReporter(collector *Collector, sender TextSender, interval time.Duration, translator *i18n.Translator, logger zerolog.Logger) *Reporter {
+	return &Reporter{
+		collector:  collector,
+		sender:     sender,
+		interval:   interval,
+		translator: translator,
+		logger:     logger,
+	}
+}
+
+// Run sends a report every interval until ctx is cancelled
+func (r *Reporter) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Send(ctx); err != nil {
+				r.logger.Error().Err(err).Msg("failed to send report")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Send sends a report covering the last interval
+func (r *Reporter) Send(ctx context.Context) error {
+	summary := r.collector.Summary(r.interval)
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	title := "📊 " + r.translator.T("report.title", r.interval)
+	return r.sender.SendText(ctx, title, Format(summary, r.translator))
+}
+
+// Format renders a summary as notification text
+func Format(s Summary, t *i18n.Translator) string {
+	var sb strings.Builder
+
+	sb.WriteString(t.T("report.messages", s.Received, s.Forwarded))
+	sb.WriteString("\n")
+
+	if len(s.Destinations) == 0 {
+		sb.WriteString(t.T("report.no_notifications"))
+		sb.WriteString("\n")
+		return sb.String()
+	}
+
+	for _, d := range s.Destinations {
+		sb.WriteString(t.T("report.destination",
+			d.Destination, d.Sent, d.Failed, d.MedianLatency.Round(time.Millisecond), d.Retries))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+type fakeSender struct {
+	title   string
+	message string
+}
+
+func (f *fakeSender) SendText(ctx context.Context, title, message string) error {
+	f.title = title
+	f.message = message
+	return nil
+}
+
+func getTestTranslator(t *testing.T) *i18n.Translator {
+	translator, err := i18n.New("en")
+	require.NoError(t, err)
+	return translator
+}
+
+func TestFormat(t *testing.T) {
+	text := Format(Summary{
+		Received:  10,
+		Forwarded: 4,
+		Destinations: []DestinationStats{
+			{Destination: "https://ntfy.sh/p2000", Sent: 3, Failed: 1, Retries: 2, MedianLatency: 250 * time.Millisecond},
+		},
+	}, getTestTranslator(t))
+
+	assert.Contains(t, text, "Messages: 10 received, 4 forwarded")
+	assert.Contains(t, text, "https://ntfy.sh/p2000: 3 sent, 1 failed, median 250ms, 2 retries")
+}
+
+func TestFormat_NoDeliveries(t *testing.T) {
+	assert.Contains(t, Format(Summary{}, getTestTranslator(t)), "No notifications sent")
+}
+
+func TestFormat_Dutch(t *testing.T) {
+	text := Format(Summary{Received: 2, Forwarded: 1}, i18n.Default())
+	assert.Contains(t, text, "Berichten: 2 ontvangen, 1 doorgestuurd")
+	assert.Contains(t, text, "Geen meldingen verstuurd")
+}
+
+func TestReporter_Send(t *testing.T) {
+	collector := NewCollector(time.Hour)
+	collector.RecordMessage(true)
+	collector.RecordDelivery(notifier.DeliveryResult{Destination: "ntfy", Success: true, Attempts: 1})
+
+	sender := &fakeSender{}
+	reporter := NewReporter(collector, sender, time.Hour, getTestTranslator(t), getTestLogger())
+
+	require.NoError(t, reporter.Send(context.Background()))
+	assert.Contains(t, sender.title, "1h0m0s")
+	assert.Contains(t, sender.message, "ntfy: 1 sent, 0 failed")
+}
@@ -0,0 +1,319 @@
+// Package rpc serves the gRPC API defined in proto/p2000/v1: a stream of
+// the processed messages, and the message history and capcode database.
+// Clients authenticate with the admin API token.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/p2000v1"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultBuffer = 100 // Messages waiting per subscriber before new ones are dropped
+
+	defaultMessageLimit = 100
+)
+
+// Options configures a Server. Token is required.
+type Options struct {
+	Token    string          // Admin API token clients send as bearer token
+	Store    *store.Store    // Message history, the history calls fail when nil
+	Capcodes *capcode.Lookup // Capcode database, LookupCapcode fails when nil
+	Buffer   int             // DefaultBuffer when 0
+
+	Metrics *metrics.Metrics // Optional
+	Logger  zerolog.Logger
+}
+
+// subscriber is a Subscribe call waiting for messages
+type subscriber struct {
+	capcodes        []string // Normalized, all messages when empty
+	when            *rules.Expr
+	includeFiltered bool
+	messages        chan *p2000v1.Message
+}
+
+// matches reports whether the subscriber asked for msg
+func (sub *subscriber) matches(msg model.Message, forwarded bool) bool {
+	if !forwarded && !sub.includeFiltered {
+		return false
+	}
+	if len(sub.capcodes) > 0 && !slices.ContainsFunc(msg.Capcodes, func(code string) bool {
+		return slices.Contains(sub.capcodes, capcode.Normalize(code))
+	}) {
+		return false
+	}
+	return sub.when == nil || sub.when.Eval(rules.NewEnv(msg))
+}
+
+// Server implements the P2000Service. Publish never blocks the feed: a
+// subscriber that cannot keep up loses messages.
+type Server struct {
+	p2000v1.UnimplementedP2000ServiceServer
+
+	opts Options
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// New creates a server
+func New(opts Options) (*Server, error) {
+	if opts.Token == "" {
+		return nil, errors.New("grpc requires api.token")
+	}
+	if opts.Buffer < 0 {
+		return nil, errors.New("grpc buffer must not be negative")
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	return &Server{
+		opts:        opts,
+		subscribers: make(map[*subscriber]struct{}),
+	}, nil
+}
+
+// GRPCServer creates a gRPC server authenticating every call and serving
+// s, over TLS when tlsConfig is not nil
+func (s *Server) GRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.authenticateUnary),
+		grpc.StreamInterceptor(s.authenticateStream),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	p2000v1.RegisterP2000ServiceServer(server, s)
+	return server
+}
+
+// Publish sends a processed message in incident thread to the matching
+// subscribers
+func (s *Server) Publish(msg model.Message, thread string, received time.Time, forwarded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pb *p2000v1.Message
+	for sub := range s.subscribers {
+		if !sub.matches(msg, forwarded) {
+			continue
+		}
+		if pb == nil {
+			pb = toMessage(msg, thread, received, forwarded)
+		}
+		select {
+		case sub.messages <- pb:
+		default:
+			s.record("dropped")
+			s.opts.Logger.Warn().Str("id", msg.ID).Msg("grpc subscriber too slow, message dropped")
+		}
+	}
+}
+
+// Subscribers returns the number of open Subscribe calls
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// Subscribe implements P2000Service.Subscribe
+func (s *Server) Subscribe(req *p2000v1.SubscribeRequest, stream grpc.ServerStreamingServer[p2000v1.SubscribeResponse]) error {
+	sub := &subscriber{
+		includeFiltered: req.GetIncludeFiltered(),
+		messages:        make(chan *p2000v1.Message, s.opts.Buffer),
+	}
+	for _, code := range req.GetCapcodes() {
+		if code = capcode.Normalize(code); code != "" {
+			sub.capcodes = append(sub.capcodes, code)
+		}
+	}
+	if req.GetWhen() != "" {
+		when, err := rules.Compile(req.GetWhen())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid condition: %v", err)
+		}
+		sub.when = when
+	}
+
+	s.subscribe(sub)
+	defer s.unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case msg := <-sub.messages:
+			if err := stream.Send(&p2000v1.SubscribeResponse{Message: msg}); err != nil {
+				return err
+			}
+			s.record("sent")
+		}
+	}
+}
+
+// subscribe adds sub to the subscribers
+func (s *Server) subscribe(sub *subscriber) {
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	n := len(s.subscribers)
+	s.mu.Unlock()
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.SetGRPCSubscribers(n)
+	}
+}
+
+// unsubscribe removes sub from the subscribers
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	n := len(s.subscribers)
+	s.mu.Unlock()
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.SetGRPCSubscribers(n)
+	}
+}
+
+// record counts a message for a subscriber by its result
+func (s *Server) record(result string) {
+	if s.opts.Metrics != nil {
+		s.opts.Metrics.RecordGRPCMessage(result)
+	}
+}
+
+// ListMessages implements P2000Service.ListMessages
+func (s *Server) ListMessages(_ context.Context, req *p2000v1.ListMessagesRequest) (*p2000v1.ListMessagesResponse, error) {
+	if s.opts.Store == nil {
+		return nil, status.Error(codes.Unimplemented, "message history not available")
+	}
+	limit := int(req.GetLimit())
+	if limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	if limit == 0 {
+		limit = defaultMessageLimit
+	}
+
+	records := s.opts.Store.Messages(limit)
+	resp := &p2000v1.ListMessagesResponse{Messages: make([]*p2000v1.Message, 0, len(records))}
+	for _, r := range records {
+		resp.Messages = append(resp.Messages, toMessage(r.Message, r.Thread, r.ReceivedAt, r.Forwarded))
+	}
+	return resp, nil
+}
+
+// GetMessage implements P2000Service.GetMessage
+func (s *Server) GetMessage(_ context.Context, req *p2000v1.GetMessageRequest) (*p2000v1.GetMessageResponse, error) {
+	if s.opts.Store == nil {
+		return nil, status.Error(codes.Unimplemented, "message history not available")
+	}
+	r, ok := s.opts.Store.Message(req.GetId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	return &p2000v1.GetMessageResponse{Message: toMessage(r.Message, r.Thread, r.ReceivedAt, r.Forwarded)}, nil
+}
+
+// LookupCapcode implements P2000Service.LookupCapcode
+func (s *Server) LookupCapcode(_ context.Context, req *p2000v1.LookupCapcodeRequest) (*p2000v1.LookupCapcodeResponse, error) {
+	if s.opts.Capcodes == nil {
+		return nil, status.Error(codes.Unimplemented, "capcode database not available")
+	}
+	info, ok := s.opts.Capcodes.Find(req.GetCapcode())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "capcode not found")
+	}
+	return &p2000v1.LookupCapcodeResponse{Capcode: toCapcode(info)}, nil
+}
+
+// authenticateUnary rejects unary calls without the token
+func (s *Server) authenticateUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream rejects streaming calls without the token
+func (s *Server) authenticateStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authenticate checks the bearer token in the authorization metadata
+func (s *Server) authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// toMessage converts a message to its protobuf form
+func toMessage(msg model.Message, thread string, received time.Time, forwarded bool) *p2000v1.Message {
+	pb := &p2000v1.Message{
+		Id:           msg.ID,
+		ReceivedAt:   timestamppb.New(received),
+		Type:         msg.Type,
+		Capcodes:     msg.Capcodes,
+		Text:         msg.Message,
+		Agency:       msg.Agency,
+		Priority:     msg.Priority,
+		Grip:         int32(msg.GRIP),
+		Severity:     string(msg.Severity),
+		Test:         msg.Test,
+		Reanimation:  msg.Reanimation,
+		Location:     msg.Location,
+		Municipality: msg.Municipality,
+		Forwarded:    forwarded,
+		Thread:       thread,
+	}
+	if msg.Timestamp > 0 {
+		pb.SentAt = timestamppb.New(time.Unix(msg.Timestamp, 0))
+	}
+	if msg.Coordinates != nil {
+		pb.Coordinates = &p2000v1.Coordinates{Lat: msg.Coordinates.Lat, Lon: msg.Coordinates.Lon}
+	}
+	for _, info := range msg.CapcodeInfo {
+		pb.CapcodeInfo = append(pb.CapcodeInfo, toCapcode(info))
+	}
+	return pb
+}
+
+// toCapcode converts a capcode database entry to its protobuf form
+func toCapcode(info capcode.CapcodeInfo) *p2000v1.Capcode {
+	return &p2000v1.Capcode{
+		Capcode:  info.Capcode,
+		Agency:   info.Agency,
+		Region:   info.Region,
+		Station:  info.Station,
+		Function: info.Function,
+	}
+}
@@ -0,0 +1,190 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/store"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/p2000v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "secret"
+
+// serve starts s on an in-memory listener and returns a client
+func serve(t *testing.T, s *Server) p2000v1.P2000ServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := s.GRPCServer(nil)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return p2000v1.NewP2000ServiceClient(conn)
+}
+
+// authenticated returns a context with the bearer token
+func authenticated(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func newTestServer(t *testing.T) (*Server, *store.Store) {
+	t.Helper()
+	history, err := store.Open("", 10, zerolog.Nop())
+	require.NoError(t, err)
+	s, err := New(Options{
+		Token:    testToken,
+		Store:    history,
+		Capcodes: capcode.NewLookupFromRecords([]capcode.CapcodeInfo{{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht"}}),
+		Metrics:  metrics.NewMetrics(),
+		Logger:   zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	return s, history
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Options{})
+	assert.ErrorContains(t, err, "api.token")
+
+	_, err = New(Options{Token: testToken, Buffer: -1})
+	assert.Error(t, err)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := serve(t, s)
+
+	_, err := client.ListMessages(context.Background(), &p2000v1.ListMessagesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListMessages(authenticated(context.Background(), "wrong"), &p2000v1.ListMessagesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := client.Subscribe(context.Background(), &p2000v1.SubscribeRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_History(t *testing.T) {
+	s, history := newTestServer(t)
+	client := serve(t, s)
+	ctx := authenticated(context.Background(), testToken)
+
+	msg := model.Message{Type: "FLEX", Timestamp: 1714564800, Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand Utrecht", Priority: "P 2"}
+	msg.ID = model.MessageID(msg)
+	history.AddMessage(msg, true)
+	_, err := history.SetThread(msg.ID, "p2000-1")
+	require.NoError(t, err)
+	history.AddMessage(model.Message{ID: "other", Message: "B Besteld vervoer"}, false)
+
+	list, err := client.ListMessages(ctx, &p2000v1.ListMessagesRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, list.Messages, 1)
+	assert.Equal(t, "other", list.Messages[0].Id)
+
+	got, err := client.GetMessage(ctx, &p2000v1.GetMessageRequest{Id: msg.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "P 2 Buitenbrand Utrecht", got.Message.Text)
+	assert.Equal(t, "P 2", got.Message.Priority)
+	assert.True(t, got.Message.Forwarded)
+	assert.Equal(t, "p2000-1", got.Message.Thread)
+	assert.Equal(t, int64(1714564800), got.Message.SentAt.AsTime().Unix())
+
+	_, err = client.GetMessage(ctx, &p2000v1.GetMessageRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListMessages(ctx, &p2000v1.ListMessagesRequest{Limit: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_LookupCapcode(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := serve(t, s)
+	ctx := authenticated(context.Background(), testToken)
+
+	resp, err := client.LookupCapcode(ctx, &p2000v1.LookupCapcodeRequest{Capcode: "101001"})
+	require.NoError(t, err)
+	assert.Equal(t, "Brandweer", resp.Capcode.Agency)
+	assert.Equal(t, "Utrecht", resp.Capcode.Region)
+
+	_, err = client.LookupCapcode(ctx, &p2000v1.LookupCapcodeRequest{Capcode: "9999999"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := serve(t, s)
+	ctx, cancel := context.WithCancel(authenticated(context.Background(), testToken))
+	defer cancel()
+
+	capcodes, err := client.Subscribe(ctx, &p2000v1.SubscribeRequest{Capcodes: []string{"101001"}})
+	require.NoError(t, err)
+	all, err := client.Subscribe(ctx, &p2000v1.SubscribeRequest{When: `priority == "A1"`, IncludeFiltered: true})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return s.Subscribers() == 2 }, time.Second, 10*time.Millisecond)
+
+	received := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.Publish(model.Message{ID: "1", Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand"}, "", received, false)
+	s.Publish(model.Message{ID: "2", Capcodes: []string{"0101001"}, Message: "P 2 Buitenbrand"}, "p2000-1", received, true)
+	s.Publish(model.Message{ID: "3", Capcodes: []string{"0202002"}, Message: "A1 Utrecht", Priority: "A1"}, "", received, false)
+
+	resp, err := capcodes.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "2", resp.Message.Id, "filtered message skipped")
+	assert.Equal(t, received, resp.Message.ReceivedAt.AsTime())
+	assert.Equal(t, "p2000-1", resp.Message.Thread)
+
+	resp, err = all.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "3", resp.Message.Id)
+	assert.False(t, resp.Message.Forwarded)
+
+	cancel()
+	assert.Eventually(t, func() bool { return s.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2.0, testutil.ToFloat64(s.opts.Metrics.GRPCMessages.WithLabelValues("sent")))
+}
+
+func TestServer_SubscribeInvalidCondition(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := serve(t, s)
+
+	stream, err := client.Subscribe(authenticated(context.Background(), testToken), &p2000v1.SubscribeRequest{When: `priority ==`})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_PublishSlowSubscriber(t *testing.T) {
+	s, _ := newTestServer(t)
+	sub := &subscriber{messages: make(chan *p2000v1.Message, 1)}
+	s.subscribe(sub)
+
+	s.Publish(model.Message{ID: "1"}, "", time.Now(), true)
+	s.Publish(model.Message{ID: "2"}, "", time.Now(), true)
+
+	assert.Len(t, sub.messages, 1)
+	assert.Equal(t, "1", (<-sub.messages).Id)
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.opts.Metrics.GRPCMessages.WithLabelValues("dropped")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.opts.Metrics.GRPCSubscribers))
+}
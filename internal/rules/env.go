@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// field is a message field available in conditions
+type field struct {
+	t   valueType
+	get func(e *Env) any
+}
+
+// fields lists the message fields by name. The capcode metadata lists have
+// an entry per capcode known in the capcode database.
+var fields = map[string]field{
+	"type":           {typeString, func(e *Env) any { return e.msg.Type }},
+	"text":           {typeString, func(e *Env) any { return e.msg.Message }},
+	"priority":       {typeString, func(e *Env) any { return e.msg.Priority }},
+	"grip":           {typeNumber, func(e *Env) any { return float64(e.msg.GRIP) }},
+	"severity":       {typeString, func(e *Env) any { return string(e.msg.Severity) }},
+	"severity_level": {typeNumber, func(e *Env) any { return float64(e.msg.Severity.Level()) }},
+	"agency":         {typeString, func(e *Env) any { return e.msg.Agency }},
+	"location":       {typeString, func(e *Env) any { return e.msg.Location }},
+	"test":           {typeBool, func(e *Env) any { return e.msg.Test }},
+	"reanimation":    {typeBool, func(e *Env) any { return e.msg.Reanimation }},
+	"capcodes":       {typeList, func(e *Env) any { return e.msg.Capcodes }},
+	"agencies":       {typeList, func(e *Env) any { return e.agencies }},
+	"regions":        {typeList, func(e *Env) any { return e.regions }},
+	"stations":       {typeList, func(e *Env) any { return e.stations }},
+	"functions":      {typeList, func(e *Env) any { return e.functions }},
+	"disciplines":    {typeList, func(e *Env) any { return e.disciplines }},
+}
+
+// Env holds the values of the fields for a message
+type Env struct {
+	msg         model.Message
+	agencies    []string
+	regions     []string
+	stations    []string
+	functions   []string
+	disciplines []string
+}
+
+// NewEnv collects the field values of an enriched message
+func NewEnv(msg model.Message) *Env {
+	e := &Env{msg: msg}
+	for _, info := range msg.CapcodeInfo {
+		e.agencies = append(e.agencies, info.Agency)
+		e.regions = append(e.regions, info.Region)
+		e.stations = append(e.stations, info.Station)
+		e.functions = append(e.functions, info.Function)
+		if d := filter.ClassifyAgency(info.Agency); d != filter.DisciplineUnknown {
+			e.disciplines = append(e.disciplines, string(d))
+		}
+	}
+	return e
+}
+
+// Values returns the value of every field by name: a string, float64, bool
+// or []string
+func (e *Env) Values() map[string]any {
+	values := make(map[string]any, len(fields))
+	for name, f := range fields {
+		values[name] = f.get(e)
+	}
+	return values
+}
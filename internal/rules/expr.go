@@ -0,0 +1,501 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// valueType is the static type of an expression
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeString
+	typeNumber
+	typeList
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeBool:
+		return "bool"
+	case typeString:
+		return "string"
+	case typeNumber:
+		return "number"
+	default:
+		return "list"
+	}
+}
+
+// Expr is a compiled condition over message fields
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses a condition such as
+//
+//	"brandweer" in disciplines and regions contains "Utrecht" and not text matches "(?i)proefalarm"
+//
+// Comparisons of strings ignore case, and a list matches when any of its
+// elements matches.
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	if root.typ() != typeBool {
+		return nil, fmt.Errorf("condition must be a comparison, got a %s", root.typ())
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of the expression
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Eval reports whether the condition holds for env
+func (x *Expr) Eval(e *Env) bool {
+	return x.root.eval(e).(bool)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string // Unquoted for strings
+	pos  int
+}
+
+// operators are the symbolic operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+// tokenize splits src into tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := unquote(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at position %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i += n
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:j], pos: i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// unquote reads a quoted string at the start of s, returning its value and
+// the number of bytes consumed. Backslash escapes the next character.
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				sb.WriteByte(s[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// parser is a recursive descent parser. From low to high precedence:
+// or, and, not, comparison.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token when it is one of words, which are
+// keywords or operators
+func (p *parser) accept(words ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenIdent && tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, w := range words {
+		if tok.text == w {
+			p.next()
+			return w, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", text, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogic(false, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogic(true, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != typeBool {
+			return nil, fmt.Errorf("not requires a condition, got a %s", x.typ())
+		}
+		return notNode{x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	pos := p.peek().pos
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "contains", "in", "matches")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	cmp, err := newComparison(op, left, right)
+	if err != nil {
+		return nil, fmt.Errorf("%w at position %d", err, pos)
+	}
+	return cmp, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return literal{t: typeString, v: tok.text}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literal{t: typeNumber, v: n}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return literal{t: typeBool, v: tok.text == "true"}, nil
+		}
+		f, ok := fields[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", tok.text, tok.pos)
+		}
+		return fieldRef{name: tok.text, field: f}, nil
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// parseList parses the rest of a list of strings after "["
+func (p *parser) parseList() (node, error) {
+	var items []string
+	if _, ok := p.accept("]"); ok {
+		return literal{t: typeList, v: items}, nil
+	}
+	for {
+		tok := p.next()
+		if tok.kind != tokenString && tok.kind != tokenNumber {
+			return nil, fmt.Errorf("list items must be strings at position %d", tok.pos)
+		}
+		items = append(items, tok.text)
+		if _, ok := p.accept("]"); ok {
+			return literal{t: typeList, v: items}, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// node is an expression in the syntax tree. eval returns a bool, string,
+// float64 or []string matching typ.
+type node interface {
+	typ() valueType
+	eval(e *Env) any
+}
+
+type literal struct {
+	t valueType
+	v any
+}
+
+func (l literal) typ() valueType { return l.t }
+func (l literal) eval(*Env) any  { return l.v }
+
+type fieldRef struct {
+	name  string
+	field field
+}
+
+func (f fieldRef) typ() valueType  { return f.field.t }
+func (f fieldRef) eval(e *Env) any { return f.field.get(e) }
+
+type notNode struct {
+	x node
+}
+
+func (n notNode) typ() valueType { return typeBool }
+func (n notNode) eval(e *Env) any {
+	return !n.x.eval(e).(bool)
+}
+
+type logicNode struct {
+	and         bool
+	left, right node
+}
+
+func newLogic(and bool, left, right node) (node, error) {
+	if left.typ() != typeBool || right.typ() != typeBool {
+		op := "or"
+		if and {
+			op = "and"
+		}
+		return nil, fmt.Errorf("%s requires conditions on both sides", op)
+	}
+	return logicNode{and: and, left: left, right: right}, nil
+}
+
+func (n logicNode) typ() valueType { return typeBool }
+func (n logicNode) eval(e *Env) any {
+	if n.and {
+		return n.left.eval(e).(bool) && n.right.eval(e).(bool)
+	}
+	return n.left.eval(e).(bool) || n.right.eval(e).(bool)
+}
+
+type comparison struct {
+	op          string
+	left, right node
+	re          *regexp.Regexp // matches only
+}
+
+// newComparison type checks a comparison
+func newComparison(op string, left, right node) (node, error) {
+	lt, rt := left.typ(), right.typ()
+	c := comparison{op: op, left: left, right: right}
+
+	switch op {
+	case "==", "!=":
+		// A list compares equal when any element does
+		if lt == rt && lt != typeList || lt == typeList && rt == typeString || lt == typeString && rt == typeList {
+			return c, nil
+		}
+	case "<", "<=", ">", ">=":
+		if lt == typeNumber && rt == typeNumber {
+			return c, nil
+		}
+	case "contains":
+		if (lt == typeString || lt == typeList) && rt == typeString {
+			return c, nil
+		}
+	case "in":
+		if (lt == typeString || lt == typeList) && rt == typeList {
+			return c, nil
+		}
+	case "matches":
+		lit, ok := right.(literal)
+		if ok && lit.t == typeString && (lt == typeString || lt == typeList) {
+			re, err := regexp.Compile(lit.v.(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %w", err)
+			}
+			c.re = re
+			return c, nil
+		}
+		return nil, fmt.Errorf("matches requires a string or list and a quoted pattern")
+	}
+	return nil, fmt.Errorf("cannot compare %s %s %s", lt, op, rt)
+}
+
+func (c comparison) typ() valueType { return typeBool }
+
+func (c comparison) eval(e *Env) any {
+	left, right := c.left.eval(e), c.right.eval(e)
+
+	switch c.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "<":
+		return left.(float64) < right.(float64)
+	case "<=":
+		return left.(float64) <= right.(float64)
+	case ">":
+		return left.(float64) > right.(float64)
+	case ">=":
+		return left.(float64) >= right.(float64)
+	case "contains":
+		if list, ok := left.([]string); ok {
+			return anyOf(list, func(s string) bool { return strings.EqualFold(s, right.(string)) })
+		}
+		return strings.Contains(strings.ToLower(left.(string)), strings.ToLower(right.(string)))
+	case "in":
+		list := right.([]string)
+		return anyOf(asList(left), func(s string) bool {
+			return anyOf(list, func(item string) bool { return strings.EqualFold(s, item) })
+		})
+	case "matches":
+		return anyOf(asList(left), c.re.MatchString)
+	}
+	return false
+}
+
+// equal compares two values of matching types, strings ignoring case
+func equal(left, right any) bool {
+	if list, ok := left.([]string); ok {
+		return anyOf(list, func(s string) bool { return strings.EqualFold(s, right.(string)) })
+	}
+	if list, ok := right.([]string); ok {
+		return anyOf(list, func(s string) bool { return strings.EqualFold(s, left.(string)) })
+	}
+	if s, ok := left.(string); ok {
+		return strings.EqualFold(s, right.(string))
+	}
+	return left == right
+}
+
+// asList returns a list value, or a string as a list of one
+func asList(v any) []string {
+	if list, ok := v.([]string); ok {
+		return list
+	}
+	return []string{v.(string)}
+}
+
+func anyOf(list []string, match func(string) bool) bool {
+	for _, s := range list {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
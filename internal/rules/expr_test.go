@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage() model.Message {
+	return model.Message{
+		Type:     "FLEX",
+		Message:  "A1 Brand woning Damstraat Utrecht",
+		Priority: "A1",
+		GRIP:     2,
+		Severity: classify.SeverityCritical,
+		Capcodes: []string{"0101001", "1420059"},
+		CapcodeInfo: []capcode.CapcodeInfo{
+			{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Utrecht Centrum", Function: "Bevelvoerder"},
+		},
+	}
+}
+
+func TestCompile_Eval(t *testing.T) {
+	env := NewEnv(testMessage())
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`type == "FLEX"`, true},
+		{`type == "flex"`, true},
+		{`type != "POCSAG"`, true},
+		{`priority == "A1" and grip >= 2`, true},
+		{`grip > 2 || priority == "A2"`, false},
+		{`severity == "critical"`, true},
+		{`severity_level >= 3`, true},
+		{`text contains "brand"`, true},
+		{`text matches "^A[12] "`, true},
+		{`not text matches "(?i)proefalarm"`, true},
+		{`!(text contains "test")`, true},
+		{`capcodes contains "1420059"`, true},
+		{`"1420059" in capcodes`, true},
+		{`capcodes in ["0101001", "0101002"]`, true},
+		{`capcodes in ["0101002"]`, false},
+		{`regions == "utrecht"`, true},
+		{`regions != "Utrecht"`, false},
+		{`"brandweer" in disciplines and regions contains "Utrecht" and not text matches "(?i)proefalarm"`, true},
+		{`disciplines contains "ambulance"`, false},
+		{`stations matches "Centrum$"`, true},
+		{`location == ""`, true},
+		{`(type == "POCSAG" or grip == 2) and true`, true},
+		{`not type == "FLEX" or grip == 2`, true},
+		{`not test and grip > 0`, true},
+		{`test == false`, true},
+		{`reanimation or test`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			x, err := Compile(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, x.Eval(env))
+			assert.Equal(t, tt.expr, x.String())
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, "unexpected end of condition"},
+		{`text`, "condition must be a comparison, got a string"},
+		{`town == "Utrecht"`, `unknown field "town" at position 0`},
+		{`text == "A1`, "unterminated string at position 8"},
+		{`grip > "2"`, "cannot compare number > string at position 5"},
+		{`text contains 1`, "cannot compare string contains number"},
+		{`text matches type`, "matches requires a string or list and a quoted pattern"},
+		{`text matches "("`, "invalid pattern"},
+		{`(type == "FLEX"`, `expected ")" at position 15`},
+		{`type == "FLEX" grip`, `unexpected "grip" at position 15`},
+		{`type == "FLEX" and grip`, "and requires conditions on both sides"},
+		{`not grip`, "not requires a condition, got a number"},
+		{`type ~ "FLEX"`, `unexpected '~' at position 5`},
+		{`capcodes in [type]`, "list items must be strings at position 13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
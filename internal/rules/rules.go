@@ -0,0 +1,207 @@
+// Package rules evaluates routing rules: conditions over message fields and
+// capcode metadata with actions that drop, route, prioritize or tag a
+// message.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Rule applies its actions to the messages matching its condition
+type Rule struct {
+	Name         string
+	When         *Expr
+	Drop         bool     // Do not forward the message
+	Destinations []string // Route to these destinations instead of the default
+	Priority     int      // ntfy priority 1-5, 0 keeps the message priority
+	Tags         []string // Extra ntfy tags
+	Email        string   // Address ntfy also emails the notification to
+	Delay        string   // ntfy delayed delivery, e.g. "30m" or "tomorrow, 7am"
+	Stop         bool     // Skip the remaining rules
+}
+
+// Result is the combined outcome of the matching rules
+type Result struct {
+	Matched      []string // Names of the matching rules
+	Drop         bool
+	Destinations []string
+	Priority     int // Highest priority of the matching rules
+	Tags         []string
+	Email        string // Of the first matching rule with an email address
+	Delay        string // Of the first matching rule with a delay
+}
+
+// Evaluation is the outcome of a single rule for a message. Rules after a
+// dropping or stopping rule are skipped.
+type Evaluation struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	Match     bool   `json:"match"`
+	Skipped   bool   `json:"skipped,omitempty"`
+}
+
+// Engine evaluates rules in order
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine creates an engine for rules
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Evaluate applies every matching rule until a rule drops the message or
+// stops evaluation
+func (e *Engine) Evaluate(msg model.Message) Result {
+	res, _ := e.evaluate(msg, false)
+	return res
+}
+
+// Explain evaluates the rules like Evaluate, also returning the outcome of
+// each rule
+func (e *Engine) Explain(msg model.Message) (Result, []Evaluation) {
+	return e.evaluate(msg, true)
+}
+
+func (e *Engine) evaluate(msg model.Message, explain bool) (Result, []Evaluation) {
+	var res Result
+	var trace []Evaluation
+	env := NewEnv(msg)
+
+	for i, rule := range e.rules {
+		match := rule.When.Eval(env)
+		if explain {
+			trace = append(trace, Evaluation{Name: rule.Name, Condition: rule.When.String(), Match: match})
+		}
+		if !match {
+			continue
+		}
+		res.Matched = append(res.Matched, rule.Name)
+
+		if rule.Drop {
+			res.Drop = true
+			return res, skipped(trace, e.rules[i+1:], explain)
+		}
+		for _, dest := range rule.Destinations {
+			if !contains(res.Destinations, dest) {
+				res.Destinations = append(res.Destinations, dest)
+			}
+		}
+		if rule.Priority > res.Priority {
+			res.Priority = rule.Priority
+		}
+		for _, tag := range rule.Tags {
+			if !contains(res.Tags, tag) {
+				res.Tags = append(res.Tags, tag)
+			}
+		}
+		if res.Email == "" {
+			res.Email = rule.Email
+		}
+		if res.Delay == "" {
+			res.Delay = rule.Delay
+		}
+		if rule.Stop {
+			return res, skipped(trace, e.rules[i+1:], explain)
+		}
+	}
+	return res, trace
+}
+
+// skipped adds the rules left unevaluated to trace
+func skipped(trace []Evaluation, rest []Rule, explain bool) []Evaluation {
+	if !explain {
+		return nil
+	}
+	for _, rule := range rest {
+		trace = append(trace, Evaluation{Name: rule.Name, Condition: rule.When.String(), Skipped: true})
+	}
+	return trace
+}
+
+// Apply stores the routing, priority, tags, email and delay of res in d
+func (res Result) Apply(d *notifier.Delivery) {
+	d.Routes = res.Destinations
+	d.PriorityOverride = res.Priority
+	d.Tags = res.Tags
+	d.Email = res.Email
+	d.Delay = res.Delay
+}
+
+// Router sends messages routed by rules to their destinations, and all
+// other messages to the default sender
+type Router struct {
+	destinations map[string]notifier.Sender
+	fallback     notifier.Sender
+	logger       zerolog.Logger
+}
+
+// NewRouter creates a router for the named destinations
+func NewRouter(destinations map[string]notifier.Sender, fallback notifier.Sender, logger zerolog.Logger) *Router {
+	return &Router{
+		destinations: destinations,
+		fallback:     fallback,
+		logger:       logger,
+	}
+}
+
+// Name returns the name of the default sender
+func (r *Router) Name() string {
+	return r.fallback.Name()
+}
+
+// Send delivers msg to the destinations of the notifier.Delivery carried by
+// ctx, or to the default sender when it was not routed. notifier.ErrSkipped
+// is returned when every destination skipped msg.
+func (r *Router) Send(ctx context.Context, msg model.Message) error {
+	routes := notifier.DeliveryFrom(ctx).Routes
+	if len(routes) == 0 {
+		return r.fallback.Send(ctx, msg)
+	}
+
+	var failed []string
+	skipped := 0
+	for _, name := range routes {
+		dest, ok := r.destinations[name]
+		if !ok {
+			failed = append(failed, name)
+			continue
+		}
+		err := dest.Send(ctx, msg)
+		if errors.Is(err, notifier.ErrSkipped) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			r.logger.Warn().
+				Err(err).
+				Str("destination", name).
+				Msg("routed delivery failed")
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("delivery failed for destinations: %s", strings.Join(failed, ", "))
+	}
+	if skipped == len(routes) {
+		return notifier.ErrSkipped
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// fakeSender records deliveries and optionally fails
+type fakeSender struct {
+	name string
+	err  error
+	mu   sync.Mutex
+	sent int
+}
+
+func (f *fakeSender) Name() string {
+	return f.name
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg model.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent++
+	return f.err
+}
+
+func mustCompile(t *testing.T, src string) *Expr {
+	t.Helper()
+	x, err := Compile(src)
+	require.NoError(t, err)
+	return x
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	e := NewEngine([]Rule{
+		{Name: "fire-utrecht", When: mustCompile(t, `"brandweer" in disciplines and regions contains "Utrecht"`), Destinations: []string{"utrecht"}, Priority: 4, Tags: []string{"fire"}},
+		{Name: "a1", When: mustCompile(t, `priority == "A1"`), Destinations: []string{"utrecht", "pager"}, Priority: 5, Tags: []string{"fire", "a1"}},
+		{Name: "pocsag", When: mustCompile(t, `type == "POCSAG"`), Priority: 1},
+	})
+
+	res := e.Evaluate(testMessage())
+	assert.Equal(t, Result{
+		Matched:      []string{"fire-utrecht", "a1"},
+		Destinations: []string{"utrecht", "pager"},
+		Priority:     5,
+		Tags:         []string{"fire", "a1"},
+	}, res)
+
+	var d notifier.Delivery
+	res.Apply(&d)
+	assert.Equal(t, []string{"utrecht", "pager"}, d.Routes)
+	assert.Equal(t, 5, d.PriorityOverride)
+	assert.Equal(t, []string{"fire", "a1"}, d.Tags)
+}
+
+func TestEngine_DropAndStop(t *testing.T) {
+	msg := testMessage()
+
+	e := NewEngine([]Rule{
+		{Name: "test-alarm", When: mustCompile(t, `text contains "brand"`), Drop: true},
+		{Name: "a1", When: mustCompile(t, `priority == "A1"`), Priority: 5},
+	})
+	assert.Equal(t, Result{Matched: []string{"test-alarm"}, Drop: true}, e.Evaluate(msg))
+
+	e = NewEngine([]Rule{
+		{Name: "a1", When: mustCompile(t, `priority == "A1"`), Tags: []string{"a1"}, Stop: true},
+		{Name: "flex", When: mustCompile(t, `type == "FLEX"`), Tags: []string{"flex"}},
+	})
+	assert.Equal(t, Result{Matched: []string{"a1"}, Tags: []string{"a1"}}, e.Evaluate(msg))
+
+	assert.Equal(t, Result{}, NewEngine(nil).Evaluate(msg))
+}
+
+func TestEngine_Explain(t *testing.T) {
+	e := NewEngine([]Rule{
+		{Name: "pocsag", When: mustCompile(t, `type == "POCSAG"`), Priority: 1},
+		{Name: "a1", When: mustCompile(t, `priority == "A1"`), Tags: []string{"a1"}, Stop: true},
+		{Name: "flex", When: mustCompile(t, `type == "FLEX"`), Tags: []string{"flex"}},
+	})
+
+	res, trace := e.Explain(testMessage())
+	assert.Equal(t, e.Evaluate(testMessage()), res)
+	assert.Equal(t, []Evaluation{
+		{Name: "pocsag", Condition: `type == "POCSAG"`},
+		{Name: "a1", Condition: `priority == "A1"`, Match: true},
+		{Name: "flex", Condition: `type == "FLEX"`, Skipped: true},
+	}, trace)
+}
+
+// routed returns a context routing a message to destinations
+func routed(destinations ...string) context.Context {
+	return notifier.WithDelivery(context.Background(), notifier.Delivery{Routes: destinations})
+}
+
+func TestRouter_Send(t *testing.T) {
+	fallback := &fakeSender{name: "ntfy"}
+	utrecht := &fakeSender{name: "utrecht"}
+	pager := &fakeSender{name: "pager", err: errors.New("down")}
+	r := NewRouter(map[string]notifier.Sender{"utrecht": utrecht, "pager": pager}, fallback, getTestLogger())
+
+	require.NoError(t, r.Send(context.Background(), model.Message{}))
+	assert.Equal(t, 1, fallback.sent)
+
+	require.NoError(t, r.Send(routed("utrecht"), model.Message{}))
+	assert.Equal(t, 1, utrecht.sent)
+	assert.Equal(t, 1, fallback.sent, "routed messages skip the default sender")
+
+	err := r.Send(routed("utrecht", "pager"), model.Message{})
+	assert.EqualError(t, err, "delivery failed for destinations: pager")
+	assert.Equal(t, 2, utrecht.sent)
+	assert.Equal(t, "ntfy", r.Name())
+}
+
+func TestRouter_SendSkipped(t *testing.T) {
+	utrecht := &fakeSender{name: "utrecht"}
+	call := &fakeSender{name: "call", err: notifier.ErrSkipped}
+	r := NewRouter(map[string]notifier.Sender{"utrecht": utrecht, "call": call}, &fakeSender{name: "ntfy"}, getTestLogger())
+
+	assert.NoError(t, r.Send(routed("utrecht", "call"), model.Message{}))
+	assert.ErrorIs(t, r.Send(routed("call"), model.Message{}), notifier.ErrSkipped)
+}
+
+func TestEngine_EmailAndDelay(t *testing.T) {
+	e := NewEngine([]Rule{
+		{Name: "grip", When: mustCompile(t, `grip >= 2`), Email: "ovd@example.com"},
+		{Name: "p3", When: mustCompile(t, `priority == "P 3"`), Delay: "tomorrow, 7am"},
+		{Name: "all", When: mustCompile(t, `true`), Email: "archive@example.com", Delay: "1h"},
+	})
+
+	res := e.Evaluate(model.Message{Message: "P 1 GRIP 2 Brand industrie", GRIP: 2})
+	assert.Equal(t, "ovd@example.com", res.Email, "the first matching rule sets the email")
+	assert.Equal(t, "1h", res.Delay)
+
+	res = e.Evaluate(model.Message{Message: "P 3 Dienstverlening", Priority: "P 3"})
+	assert.Equal(t, "archive@example.com", res.Email)
+	assert.Equal(t, "tomorrow, 7am", res.Delay)
+
+	var d notifier.Delivery
+	res.Apply(&d)
+	assert.Equal(t, "archive@example.com", d.Email)
+	assert.Equal(t, "tomorrow, 7am", d.Delay)
+}
@@ -0,0 +1,213 @@
+// Package script runs a user-provided Starlark script for every message, for
+// routing logic too custom for rules. The script defines a process function
+// receiving the enriched message as a dict with the fields of rule
+// conditions, and returns None or a dict with its decision:
+//
+//	def process(msg):
+//	    if "oefening" in msg["text"].lower():
+//	        return {"drop": True}
+//	    if msg["grip"] >= 2 and "Rotterdam-Rijnmond" in msg["regions"]:
+//	        return {"destinations": ["ovd"], "priority": 5, "tags": ["rotating_light"]}
+//	    return {"text": msg["text"].replace("BR ", "Brand ")}
+//
+// Starlark has no access to files, the network or the clock, and every run
+// is bounded in steps and time.
+package script
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/internal/rules"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Function is the name of the function scripts define
+const Function = "process"
+
+// Limits of a single run, stopping scripts stuck in a loop
+const (
+	maxSteps = 1_000_000
+	timeout  = 250 * time.Millisecond
+)
+
+// Decision is what a script decided for a message
+type Decision struct {
+	Drop         bool     // Do not forward the message
+	Destinations []string // Route to these destinations as well
+	Priority     int      // ntfy priority 1-5, 0 keeps the message priority
+	Tags         []string // Extra ntfy tags
+	Text         string   // Replaces the message text when not empty
+}
+
+// Apply stores the text of d in msg, and its routing, priority and tags in
+// delivery
+func (d Decision) Apply(msg *model.Message, delivery *notifier.Delivery) {
+	for _, dest := range d.Destinations {
+		if !slices.Contains(delivery.Routes, dest) {
+			delivery.Routes = append(delivery.Routes, dest)
+		}
+	}
+	if d.Priority > 0 {
+		delivery.PriorityOverride = d.Priority
+	}
+	delivery.Tags = append(delivery.Tags, d.Tags...)
+	if d.Text != "" {
+		msg.Message = d.Text
+	}
+}
+
+// Script is a loaded script. It is safe for concurrent use.
+type Script struct {
+	path    string
+	process *starlark.Function
+	logger  zerolog.Logger
+}
+
+// Load runs the script at path once and returns it, failing when it does
+// not define the process function. Output of print goes to the debug log.
+func Load(path string, logger zerolog.Logger) (*Script, error) {
+	s := &Script{path: path, logger: logger}
+	thread := s.thread()
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script: %w", err)
+	}
+	globals.Freeze()
+
+	fn, ok := globals[Function].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define %s(msg)", path, Function)
+	}
+	if fn.NumParams() != 1 {
+		return nil, fmt.Errorf("script %s: %s takes %d parameters, expected 1", path, Function, fn.NumParams())
+	}
+	s.process = fn
+	return s, nil
+}
+
+// Run calls the process function of the script for msg
+func (s *Script) Run(msg model.Message) (Decision, error) {
+	thread := s.thread()
+	timer := time.AfterFunc(timeout, func() { thread.Cancel("timeout") })
+	defer timer.Stop()
+
+	result, err := starlark.Call(thread, s.process, starlark.Tuple{messageDict(msg)}, nil)
+	if err != nil {
+		return Decision{}, fmt.Errorf("script %s: %w", s.path, err)
+	}
+	d, err := decision(result)
+	if err != nil {
+		return Decision{}, fmt.Errorf("script %s: %w", s.path, err)
+	}
+	return d, nil
+}
+
+// thread creates a thread for a single load or run
+func (s *Script) thread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: s.path,
+		Print: func(_ *starlark.Thread, text string) {
+			s.logger.Debug().Str("script", s.path).Msg(text)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	return thread
+}
+
+// messageDict converts the rule fields of msg to a Starlark dict
+func messageDict(msg model.Message) *starlark.Dict {
+	values := rules.NewEnv(msg).Values()
+	dict := starlark.NewDict(len(values))
+	for name, v := range values {
+		var value starlark.Value
+		switch v := v.(type) {
+		case string:
+			value = starlark.String(v)
+		case float64:
+			value = starlark.MakeInt(int(v))
+		case bool:
+			value = starlark.Bool(v)
+		case []string:
+			list := make([]starlark.Value, len(v))
+			for i, s := range v {
+				list[i] = starlark.String(s)
+			}
+			value = starlark.NewList(list)
+		default:
+			continue
+		}
+		_ = dict.SetKey(starlark.String(name), value)
+	}
+	return dict
+}
+
+// decision converts the result of process to a Decision
+func decision(result starlark.Value) (Decision, error) {
+	var d Decision
+	if result == starlark.None {
+		return d, nil
+	}
+	dict, ok := result.(*starlark.Dict)
+	if !ok {
+		return d, fmt.Errorf("%s returned %s, expected a dict or None", Function, result.Type())
+	}
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return d, fmt.Errorf("%s returned key %s, expected a string", Function, item[0])
+		}
+		var err error
+		switch key {
+		case "drop":
+			d.Drop = bool(item[1].Truth())
+		case "destinations":
+			d.Destinations, err = stringList(item[1])
+		case "priority":
+			err = starlark.AsInt(item[1], &d.Priority)
+			if err == nil && (d.Priority < 0 || d.Priority > 5) {
+				err = fmt.Errorf("must be between 1 and 5")
+			}
+		case "tags":
+			d.Tags, err = stringList(item[1])
+		case "text":
+			var ok bool
+			if d.Text, ok = starlark.AsString(item[1]); !ok {
+				err = fmt.Errorf("got %s, expected a string", item[1].Type())
+			}
+		default:
+			err = fmt.Errorf("unknown key, expected drop, destinations, priority, tags or text")
+		}
+		if err != nil {
+			return d, fmt.Errorf("%s returned invalid %s: %w", Function, key, err)
+		}
+	}
+	return d, nil
+}
+
+// stringList converts a list or tuple of strings
+func stringList(v starlark.Value) ([]string, error) {
+	var iterable starlark.Indexable
+	switch v := v.(type) {
+	case *starlark.List:
+		iterable = v
+	case starlark.Tuple:
+		iterable = v
+	default:
+		return nil, fmt.Errorf("got %s, expected a list of strings", v.Type())
+	}
+	list := make([]string, iterable.Len())
+	for i := range list {
+		s, ok := starlark.AsString(iterable.Index(i))
+		if !ok {
+			return nil, fmt.Errorf("got %s in the list, expected a string", iterable.Index(i).Type())
+		}
+		list[i] = s
+	}
+	return list, nil
+}
@@ -0,0 +1,122 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes src to a script file and loads it
+func writeScript(t *testing.T, src string) (*Script, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "route.star")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	return Load(path, zerolog.Nop())
+}
+
+func TestScript_Run(t *testing.T) {
+	s, err := writeScript(t, `
+def process(msg):
+    if "oefening" in msg["text"].lower():
+        return {"drop": True}
+    if msg["grip"] >= 2 and "Rotterdam-Rijnmond" in msg["regions"]:
+        return {"destinations": ["ovd"], "priority": 5, "tags": ["rotating_light"]}
+    if msg["text"].startswith("BR "):
+        return {"text": "Brand " + msg["text"][3:]}
+    return None
+`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		msg  model.Message
+		want Decision
+	}{
+		{
+			name: "Drop",
+			msg:  model.Message{Message: "P 2 Oefening brandweer"},
+			want: Decision{Drop: true},
+		},
+		{
+			name: "Route",
+			msg: model.Message{Message: "P 1 GRIP 2 Brand", GRIP: 2, CapcodeInfo: []capcode.CapcodeInfo{
+				{Capcode: "0101001", Agency: "Brandweer", Region: "Rotterdam-Rijnmond"},
+			}},
+			want: Decision{Destinations: []string{"ovd"}, Priority: 5, Tags: []string{"rotating_light"}},
+		},
+		{
+			name: "Rewrite",
+			msg:  model.Message{Message: "BR Rotterdam"},
+			want: Decision{Text: "Brand Rotterdam"},
+		},
+		{
+			name: "Unchanged",
+			msg:  model.Message{Message: "A1 Utrecht"},
+			want: Decision{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := s.Run(tt.msg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, d)
+		})
+	}
+}
+
+func TestScript_Load(t *testing.T) {
+	_, err := writeScript(t, `def handle(msg): return None`)
+	assert.ErrorContains(t, err, "does not define process(msg)")
+
+	_, err = writeScript(t, `def process(msg, extra): return None`)
+	assert.ErrorContains(t, err, "takes 2 parameters")
+
+	_, err = writeScript(t, `def process(msg) return None`)
+	assert.ErrorContains(t, err, "failed to load script")
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.star"), zerolog.Nop())
+	assert.Error(t, err)
+}
+
+func TestScript_RunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"Not a dict", `def process(msg): return "drop"`, "expected a dict or None"},
+		{"Unknown key", `def process(msg): return {"route": ["ovd"]}`, "invalid route: unknown key"},
+		{"Priority", `def process(msg): return {"priority": 9}`, "invalid priority"},
+		{"Destinations", `def process(msg): return {"destinations": "ovd"}`, "invalid destinations"},
+		{"Failure", `def process(msg): return {"text": msg["missing"]}`, "key \"missing\" not in dict"},
+		{"Endless loop", "def process(msg):\n    for i in range(1000000000):\n        pass\n", "too many steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := writeScript(t, tt.src)
+			require.NoError(t, err)
+			_, err = s.Run(model.Message{Message: "P 2 Buitenbrand"})
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestDecision_Apply(t *testing.T) {
+	msg := model.Message{Message: "BR Rotterdam"}
+	d := notifier.Delivery{Routes: []string{"ovd"}, PriorityOverride: 4, Tags: []string{"fire"}}
+	Decision{Destinations: []string{"ovd", "pager"}, Tags: []string{"rotating_light"}, Text: "Brand Rotterdam"}.Apply(&msg, &d)
+
+	assert.Equal(t, []string{"ovd", "pager"}, d.Routes)
+	assert.Equal(t, 4, d.PriorityOverride, "kept without a script priority")
+	assert.Equal(t, []string{"fire", "rotating_light"}, d.Tags)
+	assert.Equal(t, "Brand Rotterdam", msg.Message)
+}
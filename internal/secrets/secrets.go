@@ -0,0 +1,119 @@
+// Package secrets loads credentials from files, such as Docker and
+// Kubernetes secrets, and from a HashiCorp Vault KV secrets engine, so they
+// do not have to be stored in plain text in the configuration.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultPrefix marks a value as a reference to a Vault secret, e.g.
+// "vault:secret/data/p2000#ntfy_token"
+const VaultPrefix = "vault:"
+
+const vaultTimeout = 10 * time.Second
+
+// ReadFile returns the contents of a secret file without the trailing
+// newline most editors and secret tools add
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// IsVaultRef reports whether value refers to a Vault secret
+func IsVaultRef(value string) bool {
+	return strings.HasPrefix(value, VaultPrefix)
+}
+
+// Vault reads secrets from a Vault server with a token
+type Vault struct {
+	address    string
+	token      string
+	httpClient *http.Client
+	cache      map[string]map[string]any
+}
+
+// NewVault creates a client for the Vault server at address, e.g.
+// "https://vault.example.com:8200"
+func NewVault(address, token string) *Vault {
+	return &Vault{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: vaultTimeout,
+		},
+		cache: make(map[string]map[string]any),
+	}
+}
+
+// Resolve returns the secret a "vault:<path>#<key>" reference points to.
+// Both KV version 1 paths ("secret/p2000") and version 2 paths
+// ("secret/data/p2000") are supported. Each path is read once.
+func (v *Vault) Resolve(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(strings.TrimPrefix(ref, VaultPrefix), "#")
+	path = strings.Trim(path, "/")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, expected vault:<path>#<key>", ref)
+	}
+
+	data, ok := v.cache[path]
+	if !ok {
+		var err error
+		if data, err = v.read(ctx, path); err != nil {
+			return "", err
+		}
+		v.cache[path] = data
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s key %q is not a string", path, key)
+	}
+	return s, nil
+}
+
+// read fetches the key/value pairs stored at path
+func (v *Vault) read(ctx context.Context, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault secret %s: unexpected status code: %d", path, resp.StatusCode)
+	}
+
+	// KV version 2 nests the pairs in data.data next to data.metadata
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault secret %s: invalid response: %w", path, err)
+	}
+	if nested, ok := body.Data["data"].(map[string]any); ok {
+		if _, ok := body.Data["metadata"]; ok {
+			return nested, nil
+		}
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+
+	secret, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret)
+
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestVault_Resolve(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		switch r.URL.Path {
+		case "/v1/secret/data/p2000":
+			w.Write([]byte(`{"data":{"data":{"ntfy_token":"tk_123","port":8080},"metadata":{"version":3}}}`))
+		case "/v1/kv/p2000":
+			w.Write([]byte(`{"data":{"password":"hunter2"}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	v := NewVault(server.URL+"/", "vault-token")
+	ctx := context.Background()
+
+	secret, err := v.Resolve(ctx, "vault:secret/data/p2000#ntfy_token")
+	require.NoError(t, err)
+	assert.Equal(t, "tk_123", secret)
+
+	secret, err = v.Resolve(ctx, "vault:/kv/p2000#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	_, err = v.Resolve(ctx, "vault:secret/data/p2000#missing")
+	assert.EqualError(t, err, `vault secret secret/data/p2000 has no key "missing"`)
+	_, err = v.Resolve(ctx, "vault:secret/data/p2000#port")
+	assert.EqualError(t, err, `vault secret secret/data/p2000 key "port" is not a string`)
+	assert.Equal(t, 2, requests, "paths are read once")
+
+	_, err = v.Resolve(ctx, "vault:secret/data/other#token")
+	assert.EqualError(t, err, "vault secret secret/data/other: unexpected status code: 403")
+	_, err = v.Resolve(ctx, "vault:secret/data/p2000")
+	assert.ErrorContains(t, err, "expected vault:<path>#<key>")
+}
+
+func TestIsVaultRef(t *testing.T) {
+	assert.True(t, IsVaultRef("vault:secret/data/p2000#token"))
+	assert.False(t, IsVaultRef("tk_123"))
+	assert.False(t, IsVaultRef(""))
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// IsWindowsService reports whether the process was started by the service
+// control manager, which is never the case outside Windows
+func IsWindowsService() bool {
+	return false
+}
+
+// Run runs fn directly with a nil stop channel; stop signals are handled
+// by the caller
+func Run(name string, fn func(stop <-chan struct{}) int) int {
+	return fn(nil)
+}
+
+// Install is only supported on Windows. Other systems run the forwarder
+// from a systemd unit or a container.
+func Install(name, description, exe string, args []string) error {
+	return fmt.Errorf("services can only be installed on windows, not %s", runtime.GOOS)
+}
+
+// Uninstall is only supported on Windows
+func Uninstall(name string) error {
+	return fmt.Errorf("services can only be uninstalled on windows, not %s", runtime.GOOS)
+}
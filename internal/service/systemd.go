@@ -0,0 +1,94 @@
+// Package service integrates the forwarder with service managers: readiness
+// and watchdog notifications for systemd, and the service control manager of
+// Windows.
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Notify sends a state such as "READY=1" to systemd over the socket in
+// NOTIFY_SOCKET. It does nothing when the forwarder was not started by
+// systemd with Type=notify.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// Abstract sockets are written with a leading @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return nil
+}
+
+// Ready tells systemd the forwarder has started, with a status line shown by
+// systemctl status
+func Ready(status string) error {
+	return Notify("READY=1\nSTATUS=" + status)
+}
+
+// Stopping tells systemd the forwarder is shutting down, so the stop is not
+// mistaken for a crash while queued notifications are drained
+func Stopping() error {
+	return Notify("STOPPING=1")
+}
+
+// WatchdogInterval returns the interval systemd expects watchdog pings in,
+// from WatchdogSec= of the unit, or 0 when the watchdog is disabled
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog pings the systemd watchdog at half the interval while alive
+// reports true, until ctx is cancelled. When alive fails the pings stop, so
+// systemd restarts the forwarder once the interval has passed. It returns
+// immediately when the watchdog is disabled.
+func Watchdog(ctx context.Context, alive func() bool, logger zerolog.Logger) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	logger.Info().Dur("interval", interval).Msg("systemd watchdog enabled")
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !alive() {
+				logger.Warn().Msg("liveness check failed, skipping systemd watchdog ping")
+				continue
+			}
+			if err := Notify("WATCHDOG=1"); err != nil {
+				logger.Error().Err(err).Msg("failed to ping systemd watchdog")
+			}
+		}
+	}
+}
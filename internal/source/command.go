@@ -0,0 +1,171 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	initialBackoff    = 1 * time.Second
+	maxBackoff        = 30 * time.Second
+	backoffMultiplier = 2
+
+	// stableRunTime is how long the decoder must run before a failure is
+	// no longer counted towards the restart backoff
+	stableRunTime = time.Minute
+
+	// waitDelay bounds the wait for the decoder output to close after the
+	// process exited or was stopped
+	waitDelay = 5 * time.Second
+)
+
+// errRestarted is returned by run when the decoder was stopped by Restart
+var errRestarted = errors.New("decoder restarted")
+
+// Command runs a decoder command, typically rtl_fm piped into multimon-ng,
+// and parses its output like Reader. The command runs through sh so it may
+// be a pipeline, and is restarted with exponential backoff when it exits.
+type Command struct {
+	command    string
+	handler    func(model.Message)
+	logger     zerolog.Logger
+	statusChan chan bool // true = running, false = stopped
+	backoff    time.Duration
+
+	mu   sync.Mutex
+	stop context.CancelFunc // Stops the running decoder
+}
+
+// NewCommand creates a source supervising the decoder command
+func NewCommand(command string, handler func(model.Message), logger zerolog.Logger) *Command {
+	return &Command{
+		command:    command,
+		handler:    handler,
+		logger:     logger,
+		statusChan: make(chan bool, 1),
+		backoff:    initialBackoff,
+	}
+}
+
+// Connect runs the decoder until ctx is cancelled
+func (c *Command) Connect(ctx context.Context) error {
+	c.logger.Info().Str("command", c.command).Msg("starting decoder")
+
+	for {
+		started := time.Now()
+		err := c.run(ctx)
+		c.notifyStatus(false)
+		if ctx.Err() != nil {
+			c.logger.Info().Msg("decoder shutting down")
+			return ctx.Err()
+		}
+		if time.Since(started) >= stableRunTime || errors.Is(err, errRestarted) {
+			c.backoff = initialBackoff
+		}
+
+		c.logger.Error().Err(err).
+			Dur("backoff", c.backoff).
+			Msg("decoder stopped, restarting")
+
+		select {
+		case <-time.After(c.backoff):
+			c.backoff *= backoffMultiplier
+			if c.backoff > maxBackoff {
+				c.backoff = maxBackoff
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Restart stops the running decoder; Connect starts a new one after the
+// initial backoff
+func (c *Command) Restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// run starts the decoder and handles its output until it exits
+func (c *Command) run(parent context.Context) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	c.mu.Lock()
+	c.stop = cancel
+	c.mu.Unlock()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.WaitDelay = waitDelay
+	stopProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open decoder output: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open decoder output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start decoder: %w", err)
+	}
+	c.notifyStatus(true)
+	c.logger.Info().Int("pid", cmd.Process.Pid).Msg("decoder started")
+
+	// Decoders report problems such as a missing receiver on stderr, the
+	// last line is added to the exit error
+	var wg sync.WaitGroup
+	var lastErr string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		readLines(ctx, stderr, func(line string) {
+			if line = strings.TrimSpace(line); line != "" {
+				c.logger.Debug().Str("stderr", line).Msg("decoder output")
+				lastErr = line
+			}
+		})
+	}()
+
+	readLines(ctx, stdout, func(line string) {
+		handleDecoderLine(line, c.handler, c.logger)
+	})
+	wg.Wait()
+
+	err = cmd.Wait()
+	if ctx.Err() != nil && parent.Err() == nil {
+		return errRestarted
+	}
+	if err == nil {
+		err = fmt.Errorf("decoder exited")
+	}
+	if lastErr != "" {
+		err = fmt.Errorf("%w: %s", err, lastErr)
+	}
+	return err
+}
+
+// StatusChan returns a channel that receives decoder status updates
+func (c *Command) StatusChan() <-chan bool {
+	return c.statusChan
+}
+
+// notifyStatus sends a decoder status update
+func (c *Command) notifyStatus(running bool) {
+	select {
+	case c.statusChan <- running:
+	default:
+		// Channel full, skip
+	}
+}
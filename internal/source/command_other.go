@@ -0,0 +1,9 @@
+//go:build !unix
+
+package source
+
+import "os/exec"
+
+// stopProcessGroup keeps the default cancellation, which only stops the
+// shell running the decoder
+func stopProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,84 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_RestartsDecoder(t *testing.T) {
+	received := make(chan string, 10)
+	c := NewCommand(`echo "multimon-ng 1.2.0"; echo '{"message": "A1 Brand woning"}'; echo "no receiver" >&2`,
+		func(msg model.Message) { received <- msg.Message }, getTestLogger())
+	c.backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- c.Connect(ctx) }()
+
+	// The decoder exits after each message and is started again
+	for i := 0; i < 2; i++ {
+		select {
+		case text := <-received:
+			assert.Equal(t, "A1 Brand woning", text)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message from decoder")
+		}
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestCommand_RunError(t *testing.T) {
+	c := NewCommand(`echo "usb_open error -3" >&2; exit 1`, func(model.Message) {}, getTestLogger())
+
+	err := c.run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 1: usb_open error -3")
+	assert.True(t, <-c.StatusChan())
+}
+
+func TestCommand_StopsPipeline(t *testing.T) {
+	c := NewCommand("sleep 30 | cat", func(model.Message) {}, getTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- c.Connect(ctx) }()
+	assert.True(t, <-c.StatusChan())
+
+	cancel()
+	select {
+	case err := <-errc:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(waitDelay + time.Second):
+		t.Fatal("decoder pipeline did not stop")
+	}
+}
+
+func TestCommand_Restart(t *testing.T) {
+	received := make(chan string, 10)
+	c := NewCommand(`echo '{"message": "A1 Brand woning"}'; sleep 30`,
+		func(msg model.Message) { received <- msg.Message }, getTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- c.Connect(ctx) }()
+
+	// The stalled decoder is stopped and started again
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(waitDelay + 5*time.Second):
+			t.Fatal("no message from decoder")
+		}
+		c.Restart()
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
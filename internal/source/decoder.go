@@ -0,0 +1,120 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// capcodeLength is the number of digits of a P2000 capcode
+const capcodeLength = 7
+
+var (
+	// flexPattern matches the classic multimon-ng FLEX output, e.g.
+	// "FLEX: 2024-01-02 15:04:05 1600/2/K/A 10.120 [001420059] ALN A1 ..."
+	flexPattern = regexp.MustCompile(`^FLEX:\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\s+(\d+)/\S+\s+\d+\.(\d+)\s+\[([\d ]+)\]\s+(\w+)\s?(.*)$`)
+
+	// pocsagPattern matches multimon-ng POCSAG output, e.g.
+	// "POCSAG1200: Address: 1234567  Function: 3  Alpha:   A1 ..."
+	pocsagPattern = regexp.MustCompile(`^POCSAG(\d+):\s+Address:\s+(\d+)\s+Function:\s+(\d)\s+(?:(Alpha|Numeric):\s*(.*))?$`)
+)
+
+// ParseDecoderLine parses a line of decoder output: a JSON message as sent by the
+// websocket feed, or a FLEX or POCSAG line as written by multimon-ng. Both
+// the classic and the pipe separated FLEX formats are accepted.
+func ParseDecoderLine(line string) (model.Message, error) {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(line, "{"):
+		var msg model.Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return model.Message{}, fmt.Errorf("invalid JSON message: %w", err)
+		}
+		return msg, nil
+	case strings.HasPrefix(line, "FLEX|"):
+		return parseFlexFields(line)
+	case strings.HasPrefix(line, "FLEX:"):
+		m := flexPattern.FindStringSubmatch(line)
+		if m == nil {
+			return model.Message{}, fmt.Errorf("invalid FLEX line")
+		}
+		return newFlexMessage(m[1], m[2], m[3], m[4], m[5], m[6])
+	case strings.HasPrefix(line, "POCSAG"):
+		m := pocsagPattern.FindStringSubmatch(line)
+		if m == nil {
+			return model.Message{}, fmt.Errorf("invalid POCSAG line")
+		}
+		baudrate, _ := strconv.Atoi(m[1])
+		return model.Message{
+			Type:      "POCSAG",
+			Timestamp: time.Now().Unix(),
+			Signal:    model.Signal{Baudrate: baudrate, Subtype: strings.ToUpper(m[4]), Function: m[3]},
+			Capcodes:  []string{normalizeCapcode(m[2])},
+			Message:   strings.TrimSpace(m[5]),
+		}, nil
+	default:
+		return model.Message{}, fmt.Errorf("unrecognized line")
+	}
+}
+
+// parseFlexFields parses the pipe separated FLEX format of newer multimon-ng
+// versions, e.g. "FLEX|2024-01-02 15:04:05|1600/2/K/A|10.120|001420059|ALN|A1 ..."
+func parseFlexFields(line string) (model.Message, error) {
+	fields := strings.SplitN(line, "|", 7)
+	if len(fields) != 7 {
+		return model.Message{}, fmt.Errorf("invalid FLEX line: expected 7 fields, got %d", len(fields))
+	}
+
+	baudrate, _, _ := strings.Cut(fields[2], "/")
+	_, frame, _ := strings.Cut(fields[3], ".")
+	return newFlexMessage(fields[1], baudrate, frame, fields[4], fields[5], fields[6])
+}
+
+// newFlexMessage builds a FLEX message from the fields of a decoder line.
+// The time is local time, as written by multimon-ng.
+func newFlexMessage(timestamp, baudrate, frame, capcodes, subtype, text string) (model.Message, error) {
+	t, err := time.ParseInLocation(time.DateTime, timestamp, time.Local)
+	if err != nil {
+		return model.Message{}, fmt.Errorf("invalid FLEX time %q: %w", timestamp, err)
+	}
+	baud, err := strconv.Atoi(baudrate)
+	if err != nil {
+		return model.Message{}, fmt.Errorf("invalid FLEX baudrate %q", baudrate)
+	}
+	frameNumber, _ := strconv.Atoi(frame)
+
+	var codes []string
+	for _, code := range strings.Fields(capcodes) {
+		codes = append(codes, normalizeCapcode(code))
+	}
+	if len(codes) == 0 {
+		return model.Message{}, fmt.Errorf("FLEX line has no capcodes")
+	}
+
+	return model.Message{
+		Type:      "FLEX",
+		Timestamp: t.Unix(),
+		Signal:    model.Signal{Baudrate: baud, Frame: frameNumber, Subtype: subtype},
+		Capcodes:  codes,
+		Message:   strings.TrimSpace(text),
+	}, nil
+}
+
+// normalizeCapcode converts a decoder address to the 7 digit P2000 capcode,
+// e.g. "001420059" to "1420059" and "12345" to "0012345"
+func normalizeCapcode(code string) string {
+	if len(code) > capcodeLength {
+		trimmed := strings.TrimLeft(code[:len(code)-capcodeLength], "0")
+		if trimmed == "" {
+			return code[len(code)-capcodeLength:]
+		}
+		return code
+	}
+	return strings.Repeat("0", capcodeLength-len(code)) + code
+}
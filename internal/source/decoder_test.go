@@ -0,0 +1,136 @@
+package source
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecoderLine(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local).Unix()
+
+	tests := []struct {
+		name     string
+		line     string
+		expected model.Message
+	}{
+		{
+			name: "JSON message",
+			line: `{"type": "FLEX", "capcodes": ["1420059"], "message": "A1 Brand woning"}`,
+			expected: model.Message{
+				Type:     "FLEX",
+				Capcodes: []string{"1420059"},
+				Message:  "A1 Brand woning",
+			},
+		},
+		{
+			name: "FLEX pipe format",
+			line: "FLEX|2024-01-02 15:04:05|1600/2/K/A|10.120|001420059 002029568|ALN|A1 Brand woning Damstraat Utrecht",
+			expected: model.Message{
+				Type:      "FLEX",
+				Timestamp: timestamp,
+				Signal:    model.Signal{Baudrate: 1600, Frame: 120, Subtype: "ALN"},
+				Capcodes:  []string{"1420059", "2029568"},
+				Message:   "A1 Brand woning Damstraat Utrecht",
+			},
+		},
+		{
+			name: "FLEX classic format",
+			line: "FLEX: 2024-01-02 15:04:05 1600/2/K/A 10.120 [001420059] ALN A2 Ambulance",
+			expected: model.Message{
+				Type:      "FLEX",
+				Timestamp: timestamp,
+				Signal:    model.Signal{Baudrate: 1600, Frame: 120, Subtype: "ALN"},
+				Capcodes:  []string{"1420059"},
+				Message:   "A2 Ambulance",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseDecoderLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, msg)
+		})
+	}
+}
+
+func TestParseDecoderLine_POCSAG(t *testing.T) {
+	msg, err := ParseDecoderLine("POCSAG1200: Address:   12345  Function: 3  Alpha:   P 1 Test")
+	require.NoError(t, err)
+
+	assert.Equal(t, "POCSAG", msg.Type)
+	assert.Equal(t, model.Signal{Baudrate: 1200, Subtype: "ALPHA", Function: "3"}, msg.Signal)
+	assert.Equal(t, []string{"0012345"}, msg.Capcodes)
+	assert.Equal(t, "P 1 Test", msg.Message)
+	assert.NotZero(t, msg.Timestamp)
+}
+
+func TestParseDecoderLine_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"multimon-ng 1.2.0",
+		"Enabled demodulators: FLEX",
+		"{invalid json",
+		"FLEX|2024-01-02 15:04:05|1600/2/K/A",
+		"FLEX|not a time|1600/2/K/A|10.120|001420059|ALN|A1",
+		"FLEX|2024-01-02 15:04:05|1600/2/K/A|10.120||ALN|A1",
+		"FLEX: garbage",
+		"POCSAG1200: garbage",
+	} {
+		_, err := ParseDecoderLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestNormalizeCapcode(t *testing.T) {
+	assert.Equal(t, "1420059", normalizeCapcode("001420059"))
+	assert.Equal(t, "1420059", normalizeCapcode("1420059"))
+	assert.Equal(t, "0012345", normalizeCapcode("12345"))
+	assert.Equal(t, "101420059", normalizeCapcode("101420059"))
+}
+
+func TestReader(t *testing.T) {
+	input := strings.Join([]string{
+		"multimon-ng 1.2.0",
+		"",
+		"FLEX|2024-01-02 15:04:05|1600/2/K/A|10.120|001420059|ALN|A1 Brand woning",
+		`{"message": "A2 Ambulance"}`,
+	}, "\n")
+
+	var texts []string
+	r := NewReader(strings.NewReader(input), collect(&texts), getTestLogger())
+	require.NoError(t, r.Connect(context.Background()))
+	assert.Equal(t, []string{"A1 Brand woning", "A2 Ambulance"}, texts)
+}
+
+func TestReader_Cancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan string, 1)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- NewReader(pr, func(msg model.Message) { received <- msg.Message }, getTestLogger()).Connect(ctx)
+	}()
+
+	_, err := io.WriteString(pw, `{"message": "A1 Brand woning"}`+"\n")
+	require.NoError(t, err)
+	assert.Equal(t, "A1 Brand woning", <-received)
+
+	// A blocked read does not keep Connect from returning
+	cancel()
+	select {
+	case err := <-errc:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("Connect did not return after cancellation")
+	}
+}
@@ -0,0 +1,174 @@
+package source
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// maxLineSize is the longest archive line accepted
+const maxLineSize = 1 << 20
+
+// archiveRecord is a line of a feed archive written by the archive package.
+// Lines without a frame are read as a bare message.
+type archiveRecord struct {
+	ReceivedAt time.Time       `json:"received_at"`
+	Frame      json.RawMessage `json:"frame"`
+	Raw        string          `json:"raw"`
+}
+
+// FileStats counts the lines read by a File source
+type FileStats struct {
+	Messages int // Messages passed to the handler
+	Skipped  int // Lines that are not a valid message
+}
+
+// File replays archived feed files, plain or gzip compressed JSON Lines,
+// in order. Messages are delivered with their original spacing divided by
+// the speed factor.
+type File struct {
+	paths   []string
+	speed   float64
+	handler func(model.Message)
+	logger  zerolog.Logger
+	stats   FileStats
+}
+
+// NewFile creates a source replaying paths. A speed of 1 keeps the original
+// timing, 60 replays an hour per minute and 0 replays as fast as possible.
+func NewFile(paths []string, speed float64, handler func(model.Message), logger zerolog.Logger) *File {
+	return &File{
+		paths:   paths,
+		speed:   speed,
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Connect replays all files and returns nil once they are read, or the
+// error of the first file that cannot be read
+func (f *File) Connect(ctx context.Context) error {
+	var first time.Time // Receive time of the first message
+	start := time.Now()
+
+	for _, path := range f.paths {
+		f.logger.Info().Str("path", path).Msg("replaying archive file")
+		err := f.replay(ctx, path, func(receivedAt time.Time) error {
+			if f.speed <= 0 || receivedAt.IsZero() {
+				return nil
+			}
+			if first.IsZero() {
+				first = receivedAt
+				return nil
+			}
+			offset := time.Duration(float64(receivedAt.Sub(first)) / f.speed)
+			return sleep(ctx, time.Until(start.Add(offset)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	f.logger.Info().
+		Int("messages", f.stats.Messages).
+		Int("skipped", f.stats.Skipped).
+		Msg("archive replay finished")
+	return nil
+}
+
+// Stats returns the counts of the lines read so far. It must not be called
+// while Connect is running.
+func (f *File) Stats() FileStats {
+	return f.stats
+}
+
+// replay reads a single file, calling wait before each message is handled
+func (f *File) replay(ctx context.Context, path string, wait func(receivedAt time.Time) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to open archive %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		msg, receivedAt, err := parseLine(scanner.Bytes())
+		if err != nil {
+			f.stats.Skipped++
+			f.logger.Debug().Err(err).Str("path", path).Int("line", line).Msg("skipping archive line")
+			continue
+		}
+
+		if err := wait(receivedAt); err != nil {
+			return err
+		}
+		f.stats.Messages++
+		f.handler(msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseLine decodes an archive line into a message and the time it was
+// received, which is zero for bare messages
+func parseLine(data []byte) (model.Message, time.Time, error) {
+	var rec archiveRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return model.Message{}, time.Time{}, err
+	}
+	if rec.Raw != "" {
+		return model.Message{}, time.Time{}, fmt.Errorf("frame is not a valid message")
+	}
+
+	frame := []byte(rec.Frame)
+	if len(frame) == 0 {
+		frame = data
+	}
+	var msg model.Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return model.Message{}, time.Time{}, err
+	}
+	return msg, rec.ReceivedAt, nil
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
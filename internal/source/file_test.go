@@ -0,0 +1,103 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/archive"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// collect returns a handler that appends the message texts to texts
+func collect(texts *[]string) func(model.Message) {
+	return func(msg model.Message) {
+		*texts = append(*texts, msg.Message)
+	}
+}
+
+func TestFile_ReplaysArchive(t *testing.T) {
+	dir := t.TempDir()
+	w, err := archive.New(archive.Options{Dir: dir, Logger: getTestLogger()})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]byte(`{"message": "A1 Brand woning", "capcodes": ["0101001"]}`)))
+	require.NoError(t, w.Write([]byte("invalid json {")))
+	require.NoError(t, w.Write([]byte(`{"message": "A2 Ambulance"}`)))
+	require.NoError(t, w.Close())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	var texts []string
+	f := NewFile(paths, 0, collect(&texts), getTestLogger())
+	require.NoError(t, f.Connect(context.Background()))
+
+	assert.Equal(t, []string{"A1 Brand woning", "A2 Ambulance"}, texts)
+	assert.Equal(t, FileStats{Messages: 2, Skipped: 1}, f.Stats())
+}
+
+func TestFile_BareMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"message": "A1 Brand woning"}
+
+{"message": "A2 Ambulance"}
+`), 0644))
+
+	var texts []string
+	f := NewFile([]string{path}, 1, collect(&texts), getTestLogger())
+	require.NoError(t, f.Connect(context.Background()))
+	assert.Equal(t, []string{"A1 Brand woning", "A2 Ambulance"}, texts)
+}
+
+func TestFile_Speed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"received_at": "2024-01-02T15:04:05Z", "frame": {"message": "1"}}
+{"received_at": "2024-01-02T15:04:07Z", "frame": {"message": "2"}}
+`), 0644))
+
+	// Two seconds at 20x speed
+	var texts []string
+	start := time.Now()
+	require.NoError(t, NewFile([]string{path}, 20, collect(&texts), getTestLogger()).Connect(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Len(t, texts, 2)
+
+	// Replaying as fast as possible ignores the timestamps
+	start = time.Now()
+	require.NoError(t, NewFile([]string{path}, 0, collect(&texts), getTestLogger()).Connect(context.Background()))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestFile_Cancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"received_at": "2024-01-02T15:04:05Z", "frame": {"message": "1"}}
+{"received_at": "2024-01-02T16:04:05Z", "frame": {"message": "2"}}
+`), 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var texts []string
+	err := NewFile([]string{path}, 1, collect(&texts), getTestLogger()).Connect(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"1"}, texts)
+}
+
+func TestFile_MissingFile(t *testing.T) {
+	f := NewFile([]string{filepath.Join(t.TempDir(), "missing.jsonl")}, 0, func(model.Message) {}, getTestLogger())
+	assert.Error(t, f.Connect(context.Background()))
+}
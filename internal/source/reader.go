@@ -0,0 +1,97 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// Reader reads decoder output line by line, e.g. multimon-ng piped to
+// stdin. Lines that are not a message, such as decoder status output, are
+// skipped.
+type Reader struct {
+	r       io.Reader
+	handler func(model.Message)
+	logger  zerolog.Logger
+}
+
+// NewReader creates a source reading messages from r
+func NewReader(r io.Reader, handler func(model.Message), logger zerolog.Logger) *Reader {
+	return &Reader{
+		r:       r,
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Connect delivers the messages read from r until it is closed or ctx is
+// cancelled. It returns nil at the end of the input.
+func (s *Reader) Connect(ctx context.Context) error {
+	s.logger.Info().Msg("reading messages from input")
+
+	if err := readLines(ctx, s.r, func(line string) {
+		handleDecoderLine(line, s.handler, s.logger)
+	}); err != nil {
+		return err
+	}
+	s.logger.Info().Msg("end of input")
+	return nil
+}
+
+// readLines calls handle for each line read from r until the end of the
+// input or until ctx is cancelled
+func readLines(ctx context.Context, r io.Reader, handle func(line string)) error {
+	// Reads cannot be interrupted, so they run in their own goroutine
+	lines := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+		errc <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				err := <-errc
+				if err != nil && err != ctx.Err() {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				return err
+			}
+			handle(line)
+		}
+	}
+}
+
+// handleDecoderLine parses a line of decoder output and passes the message
+// to handler
+func handleDecoderLine(line string, handler func(model.Message), logger zerolog.Logger) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	msg, err := ParseDecoderLine(line)
+	if err != nil {
+		logger.Debug().Err(err).Str("line", line).Msg("skipping input line")
+		return
+	}
+	handler(msg)
+}
@@ -0,0 +1,12 @@
+// Package source defines where P2000 messages come from. The live feed is
+// the websocket client; File replays archived feed files.
+package source
+
+import "context"
+
+// Source delivers P2000 messages to the handler it was created with
+type Source interface {
+	// Connect delivers messages until ctx is cancelled. Finite sources
+	// return nil once all messages are delivered.
+	Connect(ctx context.Context) error
+}
@@ -0,0 +1,195 @@
+// Package stats aggregates message counts per hour, agency, region and
+// discipline, and sends daily or weekly summaries of them.
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// OtherDiscipline counts messages without a known discipline
+const OtherDiscipline = "other"
+
+// Count is the number of messages received and forwarded
+type Count struct {
+	Received  int `json:"received"`
+	Forwarded int `json:"forwarded"`
+}
+
+func (c *Count) add(o Count) {
+	c.Received += o.Received
+	c.Forwarded += o.Forwarded
+}
+
+// HourCount is the count of messages received in the hour from Start
+type HourCount struct {
+	Start time.Time `json:"start"`
+	Count
+}
+
+// Aggregate holds the counts of a window. A message to several agencies or
+// regions is counted once for each.
+type Aggregate struct {
+	Since       time.Time        `json:"since"`
+	Until       time.Time        `json:"until"`
+	Total       Count            `json:"total"`
+	Agencies    map[string]Count `json:"agencies"`
+	Regions     map[string]Count `json:"regions"`
+	Disciplines map[string]Count `json:"disciplines"`
+	Hours       []HourCount      `json:"hours"`
+}
+
+// bucket holds the counts of one hour
+type bucket struct {
+	start       time.Time
+	total       Count
+	agencies    map[string]Count
+	regions     map[string]Count
+	disciplines map[string]Count
+}
+
+// Aggregator counts messages in hourly buckets. Buckets older than the
+// retention are discarded.
+type Aggregator struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets []*bucket // Sorted by start
+}
+
+// NewAggregator creates an aggregator keeping counts for retention
+func NewAggregator(retention time.Duration) *Aggregator {
+	return &Aggregator{
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Record counts an enriched message received at t
+func (a *Aggregator) Record(msg model.Message, t time.Time, forwarded bool) {
+	c := Count{Received: 1}
+	if forwarded {
+		c.Forwarded = 1
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.prune()
+	if t.Before(a.now().Add(-a.retention)) {
+		return
+	}
+	b := a.bucket(t.Truncate(time.Hour))
+	b.total.add(c)
+
+	agencies, regions := messageKeys(msg)
+	disciplines := make(map[string]bool)
+	for _, agency := range agencies {
+		addKey(b.agencies, agency, c)
+		d := string(filter.ClassifyAgency(agency))
+		if d == string(filter.DisciplineUnknown) {
+			d = OtherDiscipline
+		}
+		disciplines[d] = true
+	}
+	if len(agencies) == 0 {
+		disciplines[OtherDiscipline] = true
+	}
+	for _, region := range regions {
+		addKey(b.regions, region, c)
+	}
+	for d := range disciplines {
+		addKey(b.disciplines, d, c)
+	}
+}
+
+// Aggregate sums the counts of the hours starting in [since, until)
+func (a *Aggregator) Aggregate(since, until time.Time) Aggregate {
+	agg := Aggregate{
+		Since:       since,
+		Until:       until,
+		Agencies:    make(map[string]Count),
+		Regions:     make(map[string]Count),
+		Disciplines: make(map[string]Count),
+		Hours:       []HourCount{},
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, b := range a.buckets {
+		if b.start.Before(since.Truncate(time.Hour)) || !b.start.Before(until) {
+			continue
+		}
+		agg.Total.add(b.total)
+		agg.Hours = append(agg.Hours, HourCount{Start: b.start, Count: b.total})
+		merge(agg.Agencies, b.agencies)
+		merge(agg.Regions, b.regions)
+		merge(agg.Disciplines, b.disciplines)
+	}
+	return agg
+}
+
+// bucket returns the bucket of the hour starting at start, adding it when
+// missing. Messages may arrive out of order, e.g. when replaying archives.
+func (a *Aggregator) bucket(start time.Time) *bucket {
+	i := sort.Search(len(a.buckets), func(i int) bool { return !a.buckets[i].start.Before(start) })
+	if i < len(a.buckets) && a.buckets[i].start.Equal(start) {
+		return a.buckets[i]
+	}
+
+	b := &bucket{
+		start:       start,
+		agencies:    make(map[string]Count),
+		regions:     make(map[string]Count),
+		disciplines: make(map[string]Count),
+	}
+	a.buckets = append(a.buckets, nil)
+	copy(a.buckets[i+1:], a.buckets[i:])
+	a.buckets[i] = b
+	return b
+}
+
+// prune drops the buckets that ended before the retention
+func (a *Aggregator) prune() {
+	cutoff := a.now().Add(-a.retention).Truncate(time.Hour)
+	i := sort.Search(len(a.buckets), func(i int) bool { return !a.buckets[i].start.Before(cutoff) })
+	a.buckets = a.buckets[i:]
+}
+
+// messageKeys returns the distinct agencies and regions of a message. The
+// feed agency is used when no capcode is in the capcode database.
+func messageKeys(msg model.Message) (agencies, regions []string) {
+	seen := make(map[string]bool)
+	for _, info := range msg.CapcodeInfo {
+		if info.Agency != "" && !seen["a:"+info.Agency] {
+			seen["a:"+info.Agency] = true
+			agencies = append(agencies, info.Agency)
+		}
+		if info.Region != "" && !seen["r:"+info.Region] {
+			seen["r:"+info.Region] = true
+			regions = append(regions, info.Region)
+		}
+	}
+	if len(agencies) == 0 && msg.Agency != "" {
+		agencies = append(agencies, msg.Agency)
+	}
+	return agencies, regions
+}
+
+func addKey(counts map[string]Count, key string, c Count) {
+	total := counts[key]
+	total.add(c)
+	counts[key] = total
+}
+
+func merge(dst, src map[string]Count) {
+	for key, c := range src {
+		addKey(dst, key, c)
+	}
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage(infos ...capcode.CapcodeInfo) model.Message {
+	return model.Message{Message: "A1 Brand woning", CapcodeInfo: infos}
+}
+
+func TestAggregator_Record(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 30, 0, 0, time.UTC)
+	a := NewAggregator(48 * time.Hour)
+	a.now = func() time.Time { return now }
+
+	fire := capcode.CapcodeInfo{Agency: "Brandweer", Region: "Utrecht"}
+	ambulance := capcode.CapcodeInfo{Agency: "Ambulance", Region: "Utrecht"}
+	police := capcode.CapcodeInfo{Agency: "Politie", Region: "Amsterdam"}
+
+	a.Record(testMessage(fire, ambulance), now.Add(-10*time.Minute), true)
+	a.Record(testMessage(fire, fire), now.Add(-20*time.Minute), true)
+	a.Record(testMessage(police), now.Add(-2*time.Hour), false)
+	a.Record(model.Message{Agency: "Onbekend"}, now.Add(-3*time.Hour), true)
+	a.Record(testMessage(fire), now.Add(-72*time.Hour), true) // beyond the retention
+
+	agg := a.Aggregate(now.Add(-24*time.Hour), now)
+	assert.Equal(t, Count{Received: 4, Forwarded: 3}, agg.Total)
+	assert.Equal(t, map[string]Count{
+		"Brandweer": {Received: 2, Forwarded: 2},
+		"Ambulance": {Received: 1, Forwarded: 1},
+		"Politie":   {Received: 1},
+		"Onbekend":  {Received: 1, Forwarded: 1},
+	}, agg.Agencies)
+	assert.Equal(t, map[string]Count{
+		"Utrecht":   {Received: 2, Forwarded: 2},
+		"Amsterdam": {Received: 1},
+	}, agg.Regions)
+	assert.Equal(t, map[string]Count{
+		"brandweer": {Received: 2, Forwarded: 2},
+		"ambulance": {Received: 1, Forwarded: 1},
+		"politie":   {Received: 1},
+		"other":     {Received: 1, Forwarded: 1},
+	}, agg.Disciplines)
+
+	require.Len(t, agg.Hours, 3)
+	assert.Equal(t, time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC), agg.Hours[0].Start)
+	assert.Equal(t, HourCount{Start: time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC), Count: Count{Received: 2, Forwarded: 2}}, agg.Hours[2])
+
+	agg = a.Aggregate(now.Add(-time.Hour), now)
+	assert.Equal(t, Count{Received: 2, Forwarded: 2}, agg.Total)
+}
+
+func TestAggregator_Prune(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	a := NewAggregator(2 * time.Hour)
+	a.now = func() time.Time { return now }
+
+	a.Record(testMessage(), now.Add(-90*time.Minute), true)
+	now = now.Add(3 * time.Hour)
+	a.Record(testMessage(), now, true)
+
+	assert.Len(t, a.buckets, 1)
+	assert.Equal(t, 1, a.Aggregate(now.Add(-24*time.Hour), now.Add(time.Hour)).Total.Received)
+}
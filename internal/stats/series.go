@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Series metrics
+const (
+	MetricReceived  = "received"
+	MetricForwarded = "forwarded"
+)
+
+// Series groupings
+const (
+	ByAgency     = "agency"
+	ByRegion     = "region"
+	ByDiscipline = "discipline"
+)
+
+// Point is the count of a series in the interval starting at Time
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value int       `json:"value"`
+}
+
+// Series is a named time series of message counts
+type Series struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// SeriesQuery selects the time series returned by Series
+type SeriesQuery struct {
+	Since, Until time.Time
+	Step         time.Duration // A whole number of hours
+	Metric       string        // MetricReceived or MetricForwarded
+	By           string        // Empty for the total, or ByAgency, ByRegion or ByDiscipline
+}
+
+// Check reports an invalid metric, grouping or step
+func (q SeriesQuery) Check() error {
+	switch q.Metric {
+	case MetricReceived, MetricForwarded:
+	default:
+		return fmt.Errorf("unknown metric %q, expected received or forwarded", q.Metric)
+	}
+	switch q.By {
+	case "", ByAgency, ByRegion, ByDiscipline:
+	default:
+		return fmt.Errorf("unknown grouping %q, expected agency, region or discipline", q.By)
+	}
+	if q.Step <= 0 || q.Step%time.Hour != 0 {
+		return fmt.Errorf("step %s must be a whole number of hours", q.Step)
+	}
+	return nil
+}
+
+// Series returns the counts of q per step, one series for the total or one
+// per agency, region or discipline sorted by name. Intervals start at Since
+// truncated to the hour; intervals without messages are zero. The window is
+// limited to the retention.
+func (a *Aggregator) Series(q SeriesQuery) ([]Series, error) {
+	if err := q.Check(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	since := q.Since.Truncate(time.Hour)
+	if earliest := now.Add(-a.retention).Truncate(time.Hour); since.Before(earliest) {
+		since = earliest
+	}
+	until := q.Until
+	if latest := now.Truncate(time.Hour).Add(time.Hour); until.After(latest) {
+		until = latest
+	}
+	if !since.Before(until) {
+		return []Series{}, nil
+	}
+	intervals := int((until.Sub(since) + q.Step - 1) / q.Step)
+
+	values := make(map[string][]int)
+	add := func(name string, i int, c Count) {
+		v, ok := values[name]
+		if !ok {
+			v = make([]int, intervals)
+			values[name] = v
+		}
+		if q.Metric == MetricForwarded {
+			v[i] += c.Forwarded
+		} else {
+			v[i] += c.Received
+		}
+	}
+
+	for _, b := range a.buckets {
+		if b.start.Before(since) || !b.start.Before(until) {
+			continue
+		}
+		i := int(b.start.Sub(since) / q.Step)
+		var counts map[string]Count
+		switch q.By {
+		case "":
+			add(q.Metric, i, b.total)
+		case ByAgency:
+			counts = b.agencies
+		case ByRegion:
+			counts = b.regions
+		case ByDiscipline:
+			counts = b.disciplines
+		}
+		for key, c := range counts {
+			add(key, i, c)
+		}
+	}
+	if q.By == "" && len(values) == 0 {
+		values[q.Metric] = make([]int, intervals)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	series := make([]Series, 0, len(names))
+	for _, name := range names {
+		s := Series{Name: name, Points: make([]Point, intervals)}
+		for i, v := range values[name] {
+			s.Points[i] = Point{Time: since.Add(time.Duration(i) * q.Step), Value: v}
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_Series(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 30, 0, 0, time.UTC)
+	a := NewAggregator(48 * time.Hour)
+	a.now = func() time.Time { return now }
+
+	fire := capcode.CapcodeInfo{Agency: "Brandweer", Region: "Utrecht"}
+	police := capcode.CapcodeInfo{Agency: "Politie", Region: "Amsterdam"}
+	a.Record(testMessage(fire), now.Add(-10*time.Minute), true)
+	a.Record(testMessage(fire), now.Add(-20*time.Minute), false)
+	a.Record(testMessage(police), now.Add(-2*time.Hour), true)
+
+	series, err := a.Series(SeriesQuery{Since: now.Add(-4 * time.Hour), Until: now, Step: time.Hour, Metric: MetricReceived})
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+	assert.Equal(t, "received", series[0].Name)
+	assert.Equal(t, []Point{
+		{Time: time.Date(2024, 3, 13, 8, 0, 0, 0, time.UTC), Value: 0},
+		{Time: time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC), Value: 0},
+		{Time: time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC), Value: 1},
+		{Time: time.Date(2024, 3, 13, 11, 0, 0, 0, time.UTC), Value: 0},
+		{Time: time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC), Value: 2},
+	}, series[0].Points)
+
+	series, err = a.Series(SeriesQuery{Since: now.Add(-4 * time.Hour), Until: now, Step: 2 * time.Hour, Metric: MetricForwarded, By: ByDiscipline})
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, "brandweer", series[0].Name)
+	assert.Equal(t, []int{0, 0, 1}, values(series[0]))
+	assert.Equal(t, "politie", series[1].Name)
+	assert.Equal(t, []int{0, 1, 0}, values(series[1]))
+
+	// The window is limited to the retention
+	series, err = a.Series(SeriesQuery{Since: now.AddDate(-1, 0, 0), Until: now.AddDate(1, 0, 0), Step: time.Hour, Metric: MetricReceived})
+	require.NoError(t, err)
+	assert.Len(t, series[0].Points, 49)
+
+	series, err = a.Series(SeriesQuery{Since: now.Add(-4 * time.Hour), Until: now, Step: time.Hour, Metric: MetricReceived, By: ByRegion})
+	require.NoError(t, err)
+	assert.Equal(t, "Amsterdam", series[0].Name)
+	assert.Equal(t, "Utrecht", series[1].Name)
+}
+
+func TestSeriesQuery_Check(t *testing.T) {
+	valid := SeriesQuery{Step: time.Hour, Metric: MetricReceived}
+	assert.NoError(t, valid.Check())
+
+	q := valid
+	q.Metric = "dropped"
+	assert.ErrorContains(t, q.Check(), "unknown metric")
+	q = valid
+	q.By = "station"
+	assert.ErrorContains(t, q.Check(), "unknown grouping")
+	q = valid
+	q.Step = 30 * time.Minute
+	assert.ErrorContains(t, q.Check(), "whole number of hours")
+}
+
+func values(s Series) []int {
+	result := make([]int, 0, len(s.Points))
+	for _, p := range s.Points {
+		result = append(result, p.Value)
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/rs/zerolog"
+)
+
+// Summary periods
+const (
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+)
+
+const sendTimeout = 30 * time.Second
+
+// disciplineOrder lists the disciplines in the order they are summarized
+var disciplineOrder = []string{
+	string(filter.DisciplineBrandweer),
+	string(filter.DisciplineAmbulance),
+	string(filter.DisciplinePolitie),
+	string(filter.DisciplineKNRM),
+	OtherDiscipline,
+}
+
+// TextSender delivers a plain text notification
+type TextSender interface {
+	SendText(ctx context.Context, title, message string) error
+}
+
+// Summarizer sends a summary of the previous day, or on Mondays of the
+// previous week, at a fixed time of day
+type Summarizer struct {
+	aggregator *Aggregator
+	sender     TextSender
+	period     string
+	at         time.Duration // Offset from midnight
+	location   *time.Location
+	translator *i18n.Translator
+	logger     zerolog.Logger
+}
+
+// NewSummarizer creates a summarizer sending the summary of period at the
+// time of day at, in location
+func NewSummarizer(aggregator *Aggregator, sender TextSender, period string, at time.Duration, location *time.Location, translator *i18n.Translator, logger zerolog.Logger) *Summarizer {
+	return &Summarizer{
+		aggregator: aggregator,
+		sender:     sender,
+		period:     period,
+		at:         at,
+		location:   location,
+		translator: translator,
+		logger:     logger,
+	}
+}
+
+// ParseTime parses a time of day written as HH:MM into the offset from
+// midnight
+func ParseTime(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Run sends the summaries until ctx is cancelled
+func (s *Summarizer) Run(ctx context.Context) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(s.next(now).Sub(now))
+
+		select {
+		case t := <-timer.C:
+			if err := s.Send(ctx, t); err != nil {
+				s.logger.Error().Err(err).Str("period", s.period).Msg("failed to send summary")
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// next returns the time the first summary after now is due
+func (s *Summarizer) next(now time.Time) time.Time {
+	now = now.In(s.location)
+	day := midnight(now)
+	for {
+		due := day.Add(s.at)
+		if due.After(now) && (s.period != PeriodWeekly || day.Weekday() == time.Monday) {
+			return due
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+// Send sends the summary of the day or week before now
+func (s *Summarizer) Send(ctx context.Context, now time.Time) error {
+	until := midnight(now.In(s.location))
+	since := until.AddDate(0, 0, -1)
+	if s.period == PeriodWeekly {
+		since = until.AddDate(0, 0, -7)
+	}
+	agg := s.aggregator.Aggregate(since, until)
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	title := "📊 " + s.translator.T("stats.title."+s.period)
+	return s.sender.SendText(ctx, title, Format(agg, s.period, s.translator))
+}
+
+// Format renders the summary of an aggregate as notification text. The
+// disciplines are counted over the forwarded messages, the pages in the
+// configured region.
+func Format(agg Aggregate, period string, t *i18n.Translator) string {
+	var sb strings.Builder
+
+	var parts []string
+	for _, d := range disciplineOrder {
+		if c := agg.Disciplines[d]; c.Forwarded > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.Forwarded, t.T("stats.discipline."+d)))
+		}
+	}
+	if len(parts) == 0 {
+		sb.WriteString(t.T("stats.none." + period))
+	} else {
+		sb.WriteString(t.T("stats.calls."+period, strings.Join(parts, ", ")))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString(t.T("report.messages", agg.Total.Received, agg.Total.Forwarded))
+	sb.WriteString("\n")
+
+	if busiest, ok := busiestHour(agg.Hours); ok {
+		sb.WriteString(t.T("stats.busiest", busiest.Start.In(agg.Until.Location()).Format("15:04"), busiest.Forwarded))
+		sb.WriteString("\n")
+	}
+	if regions := topRegions(agg.Regions, 3); len(regions) > 0 {
+		sb.WriteString(t.T("stats.regions", strings.Join(regions, ", ")))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// busiestHour returns the hour with the most forwarded messages
+func busiestHour(hours []HourCount) (HourCount, bool) {
+	var busiest HourCount
+	for _, h := range hours {
+		if h.Forwarded > busiest.Forwarded {
+			busiest = h
+		}
+	}
+	return busiest, busiest.Forwarded > 0
+}
+
+// topRegions returns up to n regions with the most forwarded messages,
+// formatted with their count
+func topRegions(regions map[string]Count, n int) []string {
+	names := make([]string, 0, len(regions))
+	for name, c := range regions {
+		if c.Forwarded > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := regions[names[i]].Forwarded, regions[names[j]].Forwarded
+		if ci != cj {
+			return ci > cj
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s (%d)", name, regions[name].Forwarded)
+	}
+	return names
+}
+
+// midnight returns the start of the day of t in its location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/i18n"
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+type fakeSender struct {
+	title   string
+	message string
+}
+
+func (f *fakeSender) SendText(ctx context.Context, title, message string) error {
+	f.title = title
+	f.message = message
+	return nil
+}
+
+func getTestTranslator(t *testing.T) *i18n.Translator {
+	translator, err := i18n.New("en")
+	require.NoError(t, err)
+	return translator
+}
+
+func TestParseTime(t *testing.T) {
+	at, err := ParseTime("08:30")
+	require.NoError(t, err)
+	assert.Equal(t, 8*time.Hour+30*time.Minute, at)
+
+	_, err = ParseTime("25:00")
+	assert.ErrorContains(t, err, `invalid time of day "25:00"`)
+}
+
+func TestSummarizer_Next(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	// Wednesday 13 March 2024
+	now := time.Date(2024, 3, 13, 9, 0, 0, 0, amsterdam)
+
+	s := NewSummarizer(nil, nil, PeriodDaily, 8*time.Hour, amsterdam, nil, getTestLogger())
+	assert.Equal(t, time.Date(2024, 3, 14, 8, 0, 0, 0, amsterdam), s.next(now))
+	assert.Equal(t, time.Date(2024, 3, 13, 8, 0, 0, 0, amsterdam), s.next(now.Add(-2*time.Hour)))
+
+	s = NewSummarizer(nil, nil, PeriodWeekly, 8*time.Hour, amsterdam, nil, getTestLogger())
+	assert.Equal(t, time.Date(2024, 3, 18, 8, 0, 0, 0, amsterdam), s.next(now))
+}
+
+func TestSummarizer_Send(t *testing.T) {
+	now := time.Date(2024, 3, 13, 8, 0, 0, 0, time.UTC)
+	a := NewAggregator(8 * 24 * time.Hour)
+	a.now = func() time.Time { return now }
+
+	fire := capcode.CapcodeInfo{Agency: "Brandweer", Region: "Utrecht"}
+	ambulance := capcode.CapcodeInfo{Agency: "Ambulance", Region: "Utrecht"}
+	yesterday := time.Date(2024, 3, 12, 14, 10, 0, 0, time.UTC)
+	a.Record(testMessage(fire), yesterday, true)
+	a.Record(testMessage(fire), yesterday.Add(5*time.Minute), true)
+	a.Record(testMessage(ambulance), yesterday.Add(-5*time.Hour), true)
+	a.Record(testMessage(ambulance), yesterday.Add(-5*time.Hour), false)
+	a.Record(testMessage(fire), now.Add(-time.Hour), true) // today
+
+	sender := &fakeSender{}
+	s := NewSummarizer(a, sender, PeriodDaily, 8*time.Hour, time.UTC, getTestTranslator(t), getTestLogger())
+	require.NoError(t, s.Send(context.Background(), now))
+
+	assert.Equal(t, "📊 P2000 daily summary", sender.title)
+	assert.Equal(t, "Yesterday: 2 fire, 1 ambulance calls\n"+
+		"Messages: 4 received, 3 forwarded\n"+
+		"Busiest hour: 14:00 (2 calls)\n"+
+		"Regions: Utrecht (3)\n", sender.message)
+}
+
+func TestFormat_Empty(t *testing.T) {
+	text := Format(Aggregate{}, PeriodWeekly, i18n.Default())
+	assert.Equal(t, "Vorige week: geen meldingen\nBerichten: 0 ontvangen, 0 doorgestuurd\n", text)
+}
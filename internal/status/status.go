@@ -0,0 +1,175 @@
+// Package status tracks the runtime state of the forwarder, such as the
+// websocket connection and message flow, for health checks, metrics and
+// the API.
+package status
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+)
+
+// Snapshot is a point in time copy of the tracked state
+type Snapshot struct {
+	StartedAt              time.Time  `json:"started_at"`
+	Uptime                 float64    `json:"uptime_seconds"`
+	WebsocketConnected     bool       `json:"websocket_connected"`
+	ConnectedSince         *time.Time `json:"connected_since,omitempty"`
+	Reconnects             int        `json:"reconnects"`
+	LastMessage            *time.Time `json:"last_message,omitempty"`
+	LastMessageAge         float64    `json:"last_message_age_seconds"`
+	MessagesReceived       int64      `json:"messages_received"`
+	CapcodeLookupAvailable bool       `json:"capcode_lookup_available"`
+
+	CircuitBreakers map[string]notifier.BreakerState `json:"circuit_breakers,omitempty"`
+}
+
+// Manager tracks connection state and message flow. It is safe for
+// concurrent use.
+type Manager struct {
+	metrics *metrics.Metrics
+	now     func() time.Time
+
+	mu                sync.RWMutex
+	startedAt         time.Time
+	connected         bool
+	everConnected     bool
+	connectedSince    time.Time
+	reconnects        int
+	lastMessage       time.Time
+	messagesReceived  int64
+	capcodesAvailable bool
+	breakers          map[string]notifier.BreakerState
+}
+
+// NewManager creates a status manager. The metrics are updated on every
+// change when m is not nil.
+func NewManager(m *metrics.Metrics) *Manager {
+	return newManager(m, time.Now)
+}
+
+func newManager(m *metrics.Metrics, now func() time.Time) *Manager {
+	started := now()
+	return &Manager{
+		metrics:   m,
+		now:       now,
+		startedAt: started,
+		// Count from startup so liveness is not failed before the first
+		// message had a chance to arrive
+		lastMessage: started,
+	}
+}
+
+// SetConnected records a websocket connection state change. Connecting
+// again after a disconnect counts as a reconnect.
+func (s *Manager) SetConnected(connected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if connected && !s.connected {
+		if s.everConnected {
+			s.reconnects++
+			if s.metrics != nil {
+				s.metrics.RecordWebsocketReconnect()
+			}
+		}
+		s.everConnected = true
+		s.connectedSince = s.now()
+	}
+	s.connected = connected
+
+	if s.metrics != nil {
+		s.metrics.SetWebsocketConnected(connected)
+	}
+}
+
+// MessageReceived records the arrival of a message
+func (s *Manager) MessageReceived() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastMessage = s.now()
+	s.messagesReceived++
+}
+
+// SetCapcodesAvailable records whether the capcode database is loaded
+func (s *Manager) SetCapcodesAvailable(available bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.capcodesAvailable = available
+	if s.metrics != nil {
+		s.metrics.SetCapcodeLookupAvailable(available)
+	}
+}
+
+// SetBreakerState records the circuit breaker state of a notification
+// destination. It can be used as a notifier.BreakerHook.
+func (s *Manager) SetBreakerState(destination string, state notifier.BreakerState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.breakers == nil {
+		s.breakers = make(map[string]notifier.BreakerState)
+	}
+	s.breakers[destination] = state
+	if s.metrics != nil {
+		s.metrics.SetCircuitBreakerState(destination, int(state))
+	}
+}
+
+// Connected reports whether the websocket is connected
+func (s *Manager) Connected() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connected
+}
+
+// CapcodesAvailable reports whether the capcode database is loaded
+func (s *Manager) CapcodesAvailable() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.capcodesAvailable
+}
+
+// LastMessageAge returns the time since the last message, or since startup
+// when no message was received yet
+func (s *Manager) LastMessageAge() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now().Sub(s.lastMessage)
+}
+
+// Snapshot returns a copy of the current state
+func (s *Manager) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	snap := Snapshot{
+		StartedAt:              s.startedAt,
+		Uptime:                 now.Sub(s.startedAt).Seconds(),
+		WebsocketConnected:     s.connected,
+		Reconnects:             s.reconnects,
+		LastMessageAge:         now.Sub(s.lastMessage).Seconds(),
+		MessagesReceived:       s.messagesReceived,
+		CapcodeLookupAvailable: s.capcodesAvailable,
+	}
+	if s.connected {
+		since := s.connectedSince
+		snap.ConnectedSince = &since
+	}
+	if s.messagesReceived > 0 {
+		last := s.lastMessage
+		snap.LastMessage = &last
+	}
+	if len(s.breakers) > 0 {
+		snap.CircuitBreakers = make(map[string]notifier.BreakerState, len(s.breakers))
+		for name, state := range s.breakers {
+			snap.CircuitBreakers[name] = state
+		}
+	}
+	return snap
+}
@@ -0,0 +1,113 @@
+package status
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a controllable time source
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestManager_Reconnects(t *testing.T) {
+	m := metrics.NewMetrics()
+	clock, advance := fakeClock()
+	s := newManager(m, clock)
+
+	s.SetConnected(true)
+	assert.Equal(t, 0, s.Snapshot().Reconnects, "first connection is not a reconnect")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketConnected))
+
+	s.SetConnected(false)
+	assert.False(t, s.Connected())
+	assert.Nil(t, s.Snapshot().ConnectedSince)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.WebsocketConnected))
+
+	advance(time.Minute)
+	s.SetConnected(true)
+	s.SetConnected(true)
+
+	snap := s.Snapshot()
+	assert.Equal(t, 1, snap.Reconnects)
+	require.NotNil(t, snap.ConnectedSince)
+	assert.Equal(t, clock(), *snap.ConnectedSince)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketReconnects))
+}
+
+func TestManager_Messages(t *testing.T) {
+	clock, advance := fakeClock()
+	s := newManager(nil, clock)
+
+	advance(30 * time.Second)
+	assert.Equal(t, 30*time.Second, s.LastMessageAge(), "age counts from startup")
+	assert.Nil(t, s.Snapshot().LastMessage)
+
+	s.MessageReceived()
+	advance(10 * time.Second)
+
+	snap := s.Snapshot()
+	assert.Equal(t, int64(1), snap.MessagesReceived)
+	assert.Equal(t, 10.0, snap.LastMessageAge)
+	assert.Equal(t, 40.0, snap.Uptime)
+	require.NotNil(t, snap.LastMessage)
+}
+
+func TestManager_CapcodesAvailable(t *testing.T) {
+	m := metrics.NewMetrics()
+	s := NewManager(m)
+
+	assert.False(t, s.CapcodesAvailable())
+	s.SetCapcodesAvailable(true)
+	assert.True(t, s.CapcodesAvailable())
+	assert.True(t, s.Snapshot().CapcodeLookupAvailable)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CapcodeLookupAvailable))
+}
+
+func TestManager_BreakerState(t *testing.T) {
+	m := metrics.NewMetrics()
+	s := NewManager(m)
+
+	assert.Nil(t, s.Snapshot().CircuitBreakers)
+
+	s.SetBreakerState("https://ntfy.sh/a", notifier.BreakerClosed)
+	s.SetBreakerState("https://ntfy.sh/b", notifier.BreakerOpen)
+
+	snap := s.Snapshot()
+	assert.Equal(t, map[string]notifier.BreakerState{
+		"https://ntfy.sh/a": notifier.BreakerClosed,
+		"https://ntfy.sh/b": notifier.BreakerOpen,
+	}, snap.CircuitBreakers)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.CircuitBreakerState.WithLabelValues("https://ntfy.sh/b")))
+
+	// The snapshot is a copy
+	snap.CircuitBreakers["https://ntfy.sh/a"] = notifier.BreakerOpen
+	assert.Equal(t, notifier.BreakerClosed, s.Snapshot().CircuitBreakers["https://ntfy.sh/a"])
+}
+
+func TestManager_ConcurrentAccess(t *testing.T) {
+	s := NewManager(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.SetConnected(i%2 == 0)
+			s.MessageReceived()
+			s.Snapshot()
+			s.LastMessageAge()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(50), s.Snapshot().MessagesReceived)
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteJSON exports records as a JSON array
+func WriteJSON(w io.Writer, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		return fmt.Errorf("failed to write JSON export: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV exports records as CSV, one row per message with annotations
+// joined into a single column
+func WriteCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"id", "received_at", "type", "agency", "capcodes", "message", "forwarded", "annotations"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV export: %w", err)
+	}
+
+	for _, r := range records {
+		notes := make([]string, 0, len(r.Annotations))
+		for _, a := range r.Annotations {
+			note := a.Text
+			if a.Author != "" {
+				note = a.Author + ": " + note
+			}
+			notes = append(notes, note)
+		}
+
+		row := []string{
+			r.ID,
+			r.ReceivedAt.Format(time.RFC3339),
+			r.Message.Type,
+			r.Message.Agency,
+			strings.Join(r.Message.Capcodes, " "),
+			r.Message.Message,
+			strconv.FormatBool(r.Forwarded),
+			strings.Join(notes, " | "),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV export: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV export: %w", err)
+	}
+	return nil
+}
+
+// geoJSONFeature is a GeoJSON point feature of a geocoded message
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPoint      `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON point geometry, longitude first
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSONProperties describes the incident of a feature
+type geoJSONProperties struct {
+	ID           string    `json:"id"`
+	ReceivedAt   time.Time `json:"received_at"`
+	Agency       string    `json:"agency,omitempty"`
+	Priority     string    `json:"priority,omitempty"`
+	Capcodes     []string  `json:"capcodes"`
+	Message      string    `json:"message"`
+	Location     string    `json:"location,omitempty"`
+	Forwarded    bool      `json:"forwarded"`
+	Acknowledged bool      `json:"acknowledged"`
+}
+
+// WriteGeoJSON exports the geocoded records as a GeoJSON FeatureCollection.
+// Records without coordinates are left out.
+func WriteGeoJSON(w io.Writer, records []Record) error {
+	features := make([]geoJSONFeature, 0, len(records))
+	for _, r := range records {
+		c := r.Message.Coordinates
+		if c == nil {
+			continue
+		}
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONPoint{Type: "Point", Coordinates: [2]float64{c.Lon, c.Lat}},
+			Properties: geoJSONProperties{
+				ID:           r.ID,
+				ReceivedAt:   r.ReceivedAt,
+				Agency:       r.Message.Agency,
+				Priority:     r.Message.Priority,
+				Capcodes:     r.Message.Capcodes,
+				Message:      r.Message.Message,
+				Location:     r.Message.Location,
+				Forwarded:    r.Forwarded,
+				Acknowledged: len(r.Acknowledgements) > 0,
+			},
+		})
+	}
+
+	collection := struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: features}
+	if err := json.NewEncoder(w).Encode(collection); err != nil {
+		return fmt.Errorf("failed to write GeoJSON export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// Annotation is a free-text note attached to a stored message
+type Annotation struct {
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Acknowledgement records that a crew member acknowledged a page
+type Acknowledgement struct {
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Pause records that notification sending was paused, e.g. during a drill
+// or ntfy maintenance
+type Pause struct {
+	Reason string     `json:"reason,omitempty"`
+	Author string     `json:"author,omitempty"`
+	Since  time.Time  `json:"since"`
+	Until  *time.Time `json:"until,omitempty"` // Resumes automatically, nil until resumed
+}
+
+// Kinds of mutes
+const (
+	MuteCapcode = "capcode"
+	MuteRule    = "rule"
+)
+
+// Mute silences the messages of a capcode or routing rule for a while, e.g.
+// the exercise traffic of one station
+type Mute struct {
+	Kind   string    `json:"kind"`   // MuteCapcode or MuteRule
+	Target string    `json:"target"` // Capcode without leading zeros, or rule name
+	Reason string    `json:"reason,omitempty"`
+	Author string    `json:"author,omitempty"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// Steps of a message trace
+const (
+	TraceReceived    = "received"
+	TraceDuplicate   = "duplicate"   // A copy was received again and dropped
+	TraceRule        = "rule"        // Matched a routing rule
+	TraceScript      = "script"      // Changed by the message script, or the script failed
+	TraceTestAlarm   = "test_alarm"  // Detected as test page
+	TraceMaintenance = "maintenance" // Sent during a maintenance window
+	TraceRouted      = "routed"
+	TraceAccepted    = "accepted" // Accepted by the filters
+	TraceDropped     = "dropped"
+	TraceSilenced    = "silenced" // Sending paused or muted
+	TraceSkipped     = "skipped"  // Claimed by a redundant instance, or skipped by the destinations
+	TraceBatched     = "batched"  // Combined with other pages of its incident
+	TraceQueued      = "queued"
+	TraceSent        = "sent"
+	TraceFailed      = "failed"
+)
+
+// maxTraceEvents bounds the trace of a record, as copies of a message keep
+// adding to it
+const maxTraceEvents = 50
+
+// TraceEvent is a decision the pipeline made about a message
+type TraceEvent struct {
+	Time   time.Time `json:"time"`
+	Step   string    `json:"step"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Record is a received P2000 message with its processing outcome
+type Record struct {
+	ID          string        `json:"id"`
+	ReceivedAt  time.Time     `json:"received_at"`
+	Message     model.Message `json:"message"`
+	Forwarded   bool          `json:"forwarded"`
+	Thread      string        `json:"thread,omitempty"` // Incident thread shared by follow-up pages
+	Annotations []Annotation  `json:"annotations,omitempty"`
+
+	Acknowledgements []Acknowledgement `json:"acknowledgements,omitempty"`
+	Trace            []TraceEvent      `json:"trace,omitempty"` // Pipeline decisions, oldest first
+}
+
+// snapshot is the on-disk representation of the store
+type snapshot struct {
+	NextID   uint64    `json:"next_id"`
+	Messages []*Record `json:"messages"`
+	Paused   *Pause    `json:"paused,omitempty"`
+	Mutes    []Mute    `json:"mutes,omitempty"`
+}
+
+// Store keeps a bounded history of received messages in memory and
+// optionally persists it to a JSON file
+type Store struct {
+	path        string
+	maxMessages int
+	logger      zerolog.Logger
+
+	mu      sync.RWMutex
+	records []*Record
+	index   map[string]*Record
+	nextID  uint64
+	paused  *Pause
+	mutes   []Mute
+	dirty   bool
+}
+
+// Open creates a store keeping at most maxMessages records. When path is
+// set, existing data is loaded from it and Save writes back to it.
+func Open(path string, maxMessages int, logger zerolog.Logger) (*Store, error) {
+	s := &Store{
+		path:        path,
+		maxMessages: maxMessages,
+		logger:      logger,
+		index:       make(map[string]*Record),
+		nextID:      1,
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse store: %w", err)
+	}
+
+	for _, r := range snap.Messages {
+		s.append(r)
+	}
+	if snap.NextID > s.nextID {
+		s.nextID = snap.NextID
+	}
+	s.paused = snap.Paused
+	s.mutes = snap.Mutes
+
+	return s, nil
+}
+
+// AddMessage records a received message and returns the stored record. The
+// record takes the ID of the message; messages without one, or with the ID
+// of a record already stored, are numbered instead.
+func (s *Store) AddMessage(msg model.Message, forwarded bool) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := msg.ID
+	if _, taken := s.index[id]; id == "" || taken {
+		id = strconv.FormatUint(s.nextID, 10)
+		s.nextID++
+	}
+	msg.ID = id
+	r := &Record{
+		ID:         id,
+		ReceivedAt: time.Now(),
+		Message:    msg,
+		Forwarded:  forwarded,
+	}
+	s.append(r)
+	s.dirty = true
+
+	return r.copy()
+}
+
+// append adds a record and evicts the oldest ones beyond the size limit.
+// The caller must hold the write lock.
+func (s *Store) append(r *Record) {
+	s.records = append(s.records, r)
+	s.index[r.ID] = r
+
+	if s.maxMessages > 0 && len(s.records) > s.maxMessages {
+		evict := len(s.records) - s.maxMessages
+		for _, old := range s.records[:evict] {
+			delete(s.index, old.ID)
+		}
+		s.records = append([]*Record(nil), s.records[evict:]...)
+	}
+}
+
+// Message returns a single record by ID
+func (s *Store) Message(id string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return Record{}, false
+	}
+	return r.copy(), true
+}
+
+// Messages returns up to limit records, newest first. A limit of zero
+// returns all records.
+func (s *Store) Messages(limit int) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.records)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	result := make([]Record, 0, n)
+	for i := len(s.records) - 1; i >= 0 && len(result) < n; i-- {
+		result = append(result, s.records[i].copy())
+	}
+	return result
+}
+
+// Annotate attaches an annotation to a stored message
+func (s *Store) Annotate(id string, a Annotation) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	r.Annotations = append(r.Annotations, a)
+	s.dirty = true
+
+	return r.copy(), nil
+}
+
+// Trace appends pipeline decisions to a stored message, keeping the latest
+// maxTraceEvents
+func (s *Store) Trace(id string, events ...TraceEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	r.Trace = append(r.Trace, events...)
+	if len(r.Trace) > maxTraceEvents {
+		r.Trace = append([]TraceEvent(nil), r.Trace[len(r.Trace)-maxTraceEvents:]...)
+	}
+	s.dirty = true
+	return nil
+}
+
+// SetLocation stores the geocoded location and coordinates of a message
+func (s *Store) SetLocation(id, location string, coordinates *model.Coordinates) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	if coordinates != nil {
+		c := *coordinates
+		coordinates = &c
+	}
+	r.Message.Location = location
+	r.Message.Coordinates = coordinates
+	s.dirty = true
+
+	return r.copy(), nil
+}
+
+// SetThread stores the incident thread of a message
+func (s *Store) SetThread(id, thread string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	r.Thread = thread
+	s.dirty = true
+
+	return r.copy(), nil
+}
+
+// Acknowledge records an acknowledgement of a stored message. Repeated
+// acknowledgements by the same author are recorded once.
+func (s *Store) Acknowledge(id string, a Acknowledgement) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.index[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	for _, existing := range r.Acknowledgements {
+		if existing.Author == a.Author {
+			return r.copy(), nil
+		}
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	r.Acknowledgements = append(r.Acknowledgements, a)
+	s.dirty = true
+
+	return r.copy(), nil
+}
+
+// Pause pauses notification sending until Resume or p.Until, replacing an
+// earlier pause
+func (s *Store) Pause(p Pause) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = &p
+	s.dirty = true
+}
+
+// Resume ends a pause, reporting whether sending was paused
+func (s *Store) Resume() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.pausedAt(time.Now())
+	if s.paused != nil {
+		s.paused = nil
+		s.dirty = true
+	}
+	return ok
+}
+
+// Paused returns the current pause, or false when sending is not paused
+func (s *Store) Paused() (Pause, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pausedAt(time.Now())
+}
+
+// pausedAt returns the pause in effect at now. The caller must hold the
+// lock.
+func (s *Store) pausedAt(now time.Time) (Pause, bool) {
+	if s.paused == nil || (s.paused.Until != nil && !now.Before(*s.paused.Until)) {
+		return Pause{}, false
+	}
+	return *s.paused, true
+}
+
+// Mute adds a mute, replacing an earlier one of the same target. Capcodes
+// are stored without leading zeros.
+func (s *Store) Mute(m Mute) Mute {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m.Kind == MuteCapcode {
+		m.Target = capcode.Normalize(m.Target)
+	}
+	now := time.Now()
+	mutes := s.mutes[:0]
+	for _, old := range s.mutes {
+		if now.Before(old.Until) && (old.Kind != m.Kind || old.Target != m.Target) {
+			mutes = append(mutes, old)
+		}
+	}
+	s.mutes = append(mutes, m)
+	s.dirty = true
+	return m
+}
+
+// Unmute removes the mute of a capcode or rule, reporting whether it was
+// muted
+func (s *Store) Unmute(kind, target string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kind == MuteCapcode {
+		target = capcode.Normalize(target)
+	}
+	now := time.Now()
+	for i, m := range s.mutes {
+		if m.Kind == kind && m.Target == target {
+			s.mutes = slices.Delete(s.mutes, i, i+1)
+			s.dirty = true
+			return now.Before(m.Until)
+		}
+	}
+	return false
+}
+
+// Mutes returns the mutes in effect, the first to expire first
+func (s *Store) Mutes() []Mute {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var mutes []Mute
+	for _, m := range s.mutes {
+		if now.Before(m.Until) {
+			mutes = append(mutes, m)
+		}
+	}
+	slices.SortFunc(mutes, func(a, b Mute) int { return a.Until.Compare(b.Until) })
+	return mutes
+}
+
+// Muted returns the mute in effect for a message to capcodes that matched
+// rules, if any
+func (s *Store) Muted(capcodes, rules []string) (Mute, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.mutes) == 0 {
+		return Mute{}, false
+	}
+	now := time.Now()
+	for _, m := range s.mutes {
+		if !now.Before(m.Until) {
+			continue
+		}
+		switch m.Kind {
+		case MuteCapcode:
+			for _, c := range capcodes {
+				if capcode.Normalize(c) == m.Target {
+					return m, true
+				}
+			}
+		case MuteRule:
+			if slices.Contains(rules, m.Target) {
+				return m, true
+			}
+		}
+	}
+	return Mute{}, false
+}
+
+// Save writes the store to disk if it changed since the last save
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(snapshot{NextID: s.nextID, Messages: s.records, Paused: s.paused, Mutes: s.mutes})
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".store-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace store: %w", err)
+	}
+	return nil
+}
+
+// Run saves the store every interval and once more when ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				s.logger.Error().Err(err).Msg("failed to save store")
+			}
+		case <-ctx.Done():
+			if err := s.Save(); err != nil {
+				s.logger.Error().Err(err).Msg("failed to save store")
+			}
+			return
+		}
+	}
+}
+
+// copy returns a copy of the record that does not share slices
+func (r *Record) copy() Record {
+	c := *r
+	c.Annotations = append([]Annotation(nil), r.Annotations...)
+	c.Acknowledgements = append([]Acknowledgement(nil), r.Acknowledgements...)
+	c.Trace = append([]TraceEvent(nil), r.Trace...)
+	c.Message.Capcodes = append([]string(nil), r.Message.Capcodes...)
+	return c
+}
@@ -0,0 +1,390 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestStore_AddAndGet(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r1 := s.AddMessage(model.Message{Message: "first", Capcodes: []string{"0101001"}}, true)
+	r2 := s.AddMessage(model.Message{Message: "second"}, false)
+	assert.NotEqual(t, r1.ID, r2.ID)
+
+	got, ok := s.Message(r1.ID)
+	require.True(t, ok)
+	assert.Equal(t, "first", got.Message.Message)
+	assert.True(t, got.Forwarded)
+
+	_, ok = s.Message("missing")
+	assert.False(t, ok)
+
+	messages := s.Messages(0)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "second", messages[0].Message.Message)
+
+	assert.Len(t, s.Messages(1), 1)
+}
+
+func TestStore_AddMessageID(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{ID: "3f2a", Message: "first"}, true)
+	assert.Equal(t, "3f2a", r.ID)
+	assert.Equal(t, "3f2a", r.Message.ID)
+
+	dup := s.AddMessage(model.Message{ID: "3f2a", Message: "first"}, true)
+	assert.Equal(t, "1", dup.ID, "taken IDs are numbered")
+
+	got, ok := s.Message("3f2a")
+	require.True(t, ok)
+	assert.Equal(t, "first", got.Message.Message)
+}
+
+func TestStore_Eviction(t *testing.T) {
+	s, err := Open("", 3, getTestLogger())
+	require.NoError(t, err)
+
+	var first Record
+	for i := 0; i < 5; i++ {
+		r := s.AddMessage(model.Message{}, false)
+		if i == 0 {
+			first = r
+		}
+	}
+
+	assert.Len(t, s.Messages(0), 3)
+	_, ok := s.Message(first.ID)
+	assert.False(t, ok)
+}
+
+func TestStore_Annotate(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "Brand woning"}, true)
+
+	updated, err := s.Annotate(r.ID, Annotation{Text: "false alarm", Author: "jan"})
+	require.NoError(t, err)
+	require.Len(t, updated.Annotations, 1)
+	assert.Equal(t, "false alarm", updated.Annotations[0].Text)
+	assert.False(t, updated.Annotations[0].CreatedAt.IsZero())
+
+	// Returned records must not share state with the store
+	updated.Annotations[0].Text = "modified"
+	got, _ := s.Message(r.ID)
+	assert.Equal(t, "false alarm", got.Annotations[0].Text)
+
+	_, err = s.Annotate("missing", Annotation{Text: "x"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_Trace(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "P 1 Brand woning"}, true)
+
+	require.NoError(t, s.Trace(r.ID, TraceEvent{Step: TraceReceived}, TraceEvent{Step: TraceRule, Detail: "brandweer"}))
+	require.NoError(t, s.Trace(r.ID, TraceEvent{Step: TraceSent}))
+	got, _ := s.Message(r.ID)
+	require.Len(t, got.Trace, 3)
+	assert.Equal(t, "brandweer", got.Trace[1].Detail)
+	assert.Equal(t, TraceSent, got.Trace[2].Step)
+
+	// Only the latest events are kept
+	for range maxTraceEvents {
+		require.NoError(t, s.Trace(r.ID, TraceEvent{Step: TraceDuplicate}))
+	}
+	got, _ = s.Message(r.ID)
+	assert.Len(t, got.Trace, maxTraceEvents)
+	assert.Equal(t, TraceDuplicate, got.Trace[0].Step)
+
+	assert.ErrorIs(t, s.Trace("missing", TraceEvent{Step: TraceSent}), ErrNotFound)
+}
+
+func TestStore_Acknowledge(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "A1 Brand woning"}, true)
+
+	updated, err := s.Acknowledge(r.ID, Acknowledgement{Author: "jan"})
+	require.NoError(t, err)
+	require.Len(t, updated.Acknowledgements, 1)
+	assert.False(t, updated.Acknowledgements[0].CreatedAt.IsZero())
+
+	// The same author is recorded once
+	updated, err = s.Acknowledge(r.ID, Acknowledgement{Author: "jan"})
+	require.NoError(t, err)
+	assert.Len(t, updated.Acknowledgements, 1)
+
+	updated, err = s.Acknowledge(r.ID, Acknowledgement{Author: "piet"})
+	require.NoError(t, err)
+	assert.Len(t, updated.Acknowledgements, 2)
+
+	_, err = s.Acknowledge("missing", Acknowledgement{Author: "jan"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SetLocation(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "A1 Damrak 1012LG Amsterdam"}, true)
+
+	updated, err := s.SetLocation(r.ID, "Damrak, 1012LG Amsterdam", &model.Coordinates{Lat: 52.3756, Lon: 4.8938})
+	require.NoError(t, err)
+	assert.Equal(t, "Damrak, 1012LG Amsterdam", updated.Message.Location)
+	require.NotNil(t, updated.Message.Coordinates)
+	assert.Equal(t, 52.3756, updated.Message.Coordinates.Lat)
+
+	_, err = s.SetLocation("missing", "", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SetThread(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "P 1 BR woning Kerkstraat 12 Harmelen"}, true)
+
+	updated, err := s.SetThread(r.ID, "p2000-1")
+	require.NoError(t, err)
+	assert.Equal(t, "p2000-1", updated.Thread)
+	got, _ := s.Message(r.ID)
+	assert.Equal(t, "p2000-1", got.Thread)
+
+	_, err = s.SetThread("missing", "p2000-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	s, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	r := s.AddMessage(model.Message{Message: "persisted"}, true)
+	_, err = s.Annotate(r.ID, Annotation{Text: "our pump attended"})
+	require.NoError(t, err)
+	require.NoError(t, s.Save())
+
+	reopened, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	got, ok := reopened.Message(r.ID)
+	require.True(t, ok)
+	assert.Equal(t, "persisted", got.Message.Message)
+	assert.Equal(t, "our pump attended", got.Annotations[0].Text)
+
+	// IDs continue after reload
+	next := reopened.AddMessage(model.Message{}, false)
+	assert.NotEqual(t, r.ID, next.ID)
+}
+
+func TestStore_Pause(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	_, paused := s.Paused()
+	assert.False(t, paused)
+	assert.False(t, s.Resume())
+
+	since := time.Now().UTC().Truncate(time.Second)
+	s.Pause(Pause{Reason: "drill", Author: "ovd", Since: since})
+	require.NoError(t, s.Save())
+
+	// The pause survives a restart
+	reopened, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+	p, paused := reopened.Paused()
+	require.True(t, paused)
+	assert.Equal(t, Pause{Reason: "drill", Author: "ovd", Since: since}, p)
+
+	assert.True(t, reopened.Resume())
+	_, paused = reopened.Paused()
+	assert.False(t, paused)
+}
+
+func TestStore_PauseUntil(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	until := time.Now().Add(time.Hour)
+	s.Pause(Pause{Since: time.Now(), Until: &until})
+	_, paused := s.Paused()
+	assert.True(t, paused)
+
+	// Sending resumes by itself once the pause expired
+	expired := time.Now().Add(-time.Second)
+	s.Pause(Pause{Since: time.Now().Add(-time.Hour), Until: &expired})
+	_, paused = s.Paused()
+	assert.False(t, paused)
+	assert.False(t, s.Resume())
+}
+
+func TestStore_Mute(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	m := s.Mute(Mute{Kind: MuteCapcode, Target: "0101001", Reason: "oefening", Since: now, Until: now.Add(4 * time.Hour)})
+	assert.Equal(t, "101001", m.Target, "stored without leading zeros")
+	s.Mute(Mute{Kind: MuteRule, Target: "fire-utrecht", Since: now, Until: now.Add(time.Hour)})
+	require.NoError(t, s.Save())
+
+	// Mutes survive a restart
+	reopened, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+	mutes := reopened.Mutes()
+	require.Len(t, mutes, 2)
+	assert.Equal(t, "fire-utrecht", mutes[0].Target, "first to expire first")
+	assert.Equal(t, m, mutes[1])
+
+	got, ok := reopened.Muted([]string{"1420059", "101001"}, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "101001", got.Target)
+	_, ok = reopened.Muted([]string{"1420059"}, []string{"fire-utrecht"})
+	assert.True(t, ok)
+	_, ok = reopened.Muted([]string{"1420059"}, []string{"ambulance"})
+	assert.False(t, ok)
+
+	assert.True(t, reopened.Unmute(MuteCapcode, "101001"))
+	assert.False(t, reopened.Unmute(MuteCapcode, "101001"))
+	_, ok = reopened.Muted([]string{"0101001"}, nil)
+	assert.False(t, ok)
+}
+
+func TestStore_MuteExpires(t *testing.T) {
+	s, err := Open("", 10, getTestLogger())
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.Mute(Mute{Kind: MuteCapcode, Target: "0101001", Since: now.Add(-time.Hour), Until: now.Add(-time.Second)})
+	_, ok := s.Muted([]string{"0101001"}, nil)
+	assert.False(t, ok)
+	assert.Empty(t, s.Mutes())
+
+	// A new mute of the same capcode replaces the old one
+	s.Mute(Mute{Kind: MuteCapcode, Target: "101001", Since: now, Until: now.Add(time.Hour)})
+	s.Mute(Mute{Kind: MuteCapcode, Target: "0101001", Since: now, Until: now.Add(2 * time.Hour)})
+	require.Len(t, s.Mutes(), 1)
+	assert.Equal(t, now.Add(2*time.Hour), s.Mutes()[0].Until)
+}
+
+func TestStore_OpenInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0644))
+
+	_, err := Open(path, 10, getTestLogger())
+	assert.Error(t, err)
+}
+
+func TestStore_RunSavesOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := Open(path, 10, getTestLogger())
+	require.NoError(t, err)
+
+	s.AddMessage(model.Message{Message: "x"}, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	records := []Record{{
+		ID:         "1",
+		ReceivedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message:    model.Message{Type: "FLEX", Agency: "Brandweer", Capcodes: []string{"0101001", "0101002"}, Message: "Brand woning"},
+		Forwarded:  true,
+		Annotations: []Annotation{
+			{Text: "false alarm", Author: "jan"},
+			{Text: "checked"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, records))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "id,received_at,type,agency,capcodes,message,forwarded,annotations", lines[0])
+	assert.Equal(t, "1,2024-01-02T03:04:05Z,FLEX,Brandweer,0101001 0101002,Brand woning,true,jan: false alarm | checked", lines[1])
+}
+
+func TestWriteJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	records := []Record{
+		{
+			ID:         "2",
+			ReceivedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Message: model.Message{
+				Message:     "A1 Damrak 1012LG Amsterdam",
+				Priority:    "A1",
+				Capcodes:    []string{"1420001"},
+				Coordinates: &model.Coordinates{Lat: 52.3756, Lon: 4.8938},
+			},
+			Forwarded:        true,
+			Acknowledgements: []Acknowledgement{{Author: "jan"}},
+		},
+		{ID: "1", Message: model.Message{Message: "Proefalarm"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGeoJSON(&buf, records))
+	assert.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"features": [{
+			"type": "Feature",
+			"geometry": {"type": "Point", "coordinates": [4.8938, 52.3756]},
+			"properties": {
+				"id": "2",
+				"received_at": "2024-01-02T03:04:05Z",
+				"priority": "A1",
+				"capcodes": ["1420001"],
+				"message": "A1 Damrak 1012LG Amsterdam",
+				"forwarded": true,
+				"acknowledged": true
+			}
+		}]
+	}`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteGeoJSON(&buf, nil))
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, buf.String())
+}
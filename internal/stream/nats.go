@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// conn is a NATS connection publishing to JetStream. The client reconnects
+// on its own after the first connection succeeded.
+type conn struct {
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+// dial connects to the NATS server of opts. tls:// URLs and servers
+// requiring TLS are upgraded by the client.
+func dial(opts Options) (*conn, error) {
+	natsOpts := []nats.Option{
+		nats.Name("p2000-forwarder"),
+		nats.Timeout(requestTimeout),
+	}
+	switch {
+	case opts.Username != "":
+		natsOpts = append(natsOpts, nats.UserInfo(opts.Username, opts.Password))
+	case opts.Token != "":
+		natsOpts = append(natsOpts, nats.Token(opts.Token))
+	}
+
+	nc, err := nats.Connect(opts.URL, natsOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if !nc.HeadersSupported() {
+		nc.Close()
+		return nil, fmt.Errorf("server does not support headers, NATS 2.2 or later is required")
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &conn{nc: nc, js: js}, nil
+}
+
+// publish sends data to subject with msgID as JetStream deduplication ID,
+// and waits for the acknowledgement of the stream
+func (c *conn) publish(ctx context.Context, subject, msgID string, data []byte) error {
+	_, err := c.js.Publish(ctx, subject, data, jetstream.WithMsgID(msgID))
+	if errors.Is(err, jetstream.ErrNoStreamResponse) {
+		return fmt.Errorf("no stream captures the subject")
+	}
+	return err
+}
+
+// closed reports whether the client gave up on the connection
+func (c *conn) closed() bool {
+	return c.nc.IsClosed()
+}
+
+// close closes the connection
+func (c *conn) close() {
+	c.nc.Close()
+}
+
+// randomID returns a random hexadecimal ID
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,194 @@
+// Package stream publishes the enriched P2000 messages to a NATS JetStream
+// stream, so downstream analytics systems can consume the feed reliably
+// with their own consumers, independent of the notifications.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultSubject = "p2000.messages"
+	DefaultBuffer  = 1000
+
+	requestTimeout = 5 * time.Second
+	maxAttempts    = 3
+	retryBackoff   = time.Second
+)
+
+// Options configures a Publisher. Only URL is required.
+type Options struct {
+	URL      string // nats:// or tls:// URL of a NATS server with JetStream
+	Subject  string // Subject captured by the stream, DefaultSubject when empty
+	Token    string // Optional authentication token
+	Username string // Optional username, preferred over Token
+	Password string
+	Buffer   int // Messages waiting to be published before new ones are dropped, DefaultBuffer when 0
+
+	Metrics *metrics.Metrics // Optional
+	Logger  zerolog.Logger
+}
+
+// Event is the JSON published for every message: the enriched message
+// with the time it was received and whether it was forwarded
+type Event struct {
+	model.Message
+	ReceivedAt time.Time `json:"received_at"`
+	Forwarded  bool      `json:"forwarded"`
+}
+
+// event is an encoded Event waiting to be published
+type event struct {
+	id   string // JetStream deduplication ID
+	data []byte
+}
+
+// Publisher publishes events in the background, in the order they were
+// received. Publish never blocks the feed: when the buffer is full,
+// because the server is down or slow, events are dropped.
+type Publisher struct {
+	opts   Options
+	events chan event
+	conn   *conn // Owned by Run
+}
+
+// New creates a publisher; Run connects to the server
+func New(opts Options) (*Publisher, error) {
+	if err := ValidateURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.Subject == "" {
+		opts.Subject = DefaultSubject
+	}
+	if err := ValidateSubject(opts.Subject); err != nil {
+		return nil, err
+	}
+	if opts.Buffer < 0 {
+		return nil, fmt.Errorf("stream buffer must not be negative")
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = DefaultBuffer
+	}
+
+	return &Publisher{
+		opts:   opts,
+		events: make(chan event, opts.Buffer),
+	}, nil
+}
+
+// ValidateURL checks that rawURL is a nats:// or tls:// server URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+		return fmt.Errorf("stream url %q must be a nats:// or tls:// URL", rawURL)
+	}
+	return nil
+}
+
+// ValidateSubject checks that messages can be published to subject: dot
+// separated tokens without wildcards or whitespace
+func ValidateSubject(subject string) error {
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return fmt.Errorf("stream subject %q must be dot separated tokens without wildcards", subject)
+		}
+	}
+	return nil
+}
+
+// Publish queues msg for publishing. Messages without history ID get a
+// random deduplication ID, so retries are still published once.
+func (p *Publisher) Publish(msg model.Message, receivedAt time.Time, forwarded bool) {
+	data, err := json.Marshal(Event{Message: msg, ReceivedAt: receivedAt.UTC(), Forwarded: forwarded})
+	if err != nil {
+		p.opts.Logger.Error().Err(err).Msg("failed to encode stream event")
+		return
+	}
+	id := msg.ID
+	if id == "" {
+		id = randomID()
+	}
+
+	select {
+	case p.events <- event{id: id, data: data}:
+	default:
+		p.record("dropped")
+		p.opts.Logger.Warn().Str("id", msg.ID).Msg("stream buffer full, dropping message")
+	}
+}
+
+// Run publishes queued events until ctx is cancelled
+func (p *Publisher) Run(ctx context.Context) {
+	defer func() {
+		if p.conn != nil {
+			p.conn.close()
+		}
+	}()
+
+	for {
+		select {
+		case ev := <-p.events:
+			p.publish(ctx, ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publish makes up to maxAttempts attempts to publish ev. The
+// deduplication ID makes the stream store ev once, also when an
+// acknowledgement was lost.
+func (p *Publisher) publish(ctx context.Context, ev event) {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retryBackoff * time.Duration(attempt)):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err = p.attempt(ctx, ev); err == nil {
+			p.record("published")
+			return
+		}
+		p.opts.Logger.Debug().Err(err).Int("attempt", attempt+1).Msg("failed to publish to stream")
+	}
+
+	p.record("failed")
+	p.opts.Logger.Error().Err(err).Str("id", ev.id).Msg("failed to publish message to stream")
+}
+
+// attempt publishes ev once, connecting first when there is no connection
+// yet or the client gave up reconnecting
+func (p *Publisher) attempt(ctx context.Context, ev event) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	if p.conn == nil || p.conn.closed() {
+		c, err := dial(p.opts)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		p.conn = c
+		p.opts.Logger.Info().Str("subject", p.opts.Subject).Msg("connected to stream")
+	}
+
+	return p.conn.publish(ctx, p.opts.Subject, ev.id, ev.data)
+}
+
+// record counts an event by its result when metrics are configured
+func (p *Publisher) record(result string) {
+	if p.opts.Metrics != nil {
+		p.opts.Metrics.RecordStreamEvent(result)
+	}
+}
@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runServer starts a NATS server with JetStream, configured by auth, and
+// returns its URL
+func runServer(t *testing.T, auth func(*server.Options)) string {
+	t.Helper()
+	opts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	}
+	if auth != nil {
+		auth(opts)
+	}
+	s, err := server.NewServer(opts)
+	require.NoError(t, err)
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.ReadyForConnections(5*time.Second))
+	return s.ClientURL()
+}
+
+// createStream creates the P2000 stream capturing p2000.> on the server
+func createStream(t *testing.T, opts Options) jetstream.Stream {
+	t.Helper()
+	c, err := dial(opts)
+	require.NoError(t, err)
+	t.Cleanup(c.close)
+	stream, err := c.js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "P2000", Subjects: []string{"p2000.>"}})
+	require.NoError(t, err)
+	return stream
+}
+
+// storedMessages returns the messages stored in stream
+func storedMessages(t *testing.T, stream jetstream.Stream) []*jetstream.RawStreamMsg {
+	t.Helper()
+	info, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	var messages []*jetstream.RawStreamMsg
+	for seq := info.State.FirstSeq; seq > 0 && seq <= info.State.LastSeq; seq++ {
+		msg, err := stream.GetMsg(context.Background(), seq)
+		require.NoError(t, err)
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestConnPublish(t *testing.T) {
+	opts := Options{URL: runServer(t, func(o *server.Options) { o.Authorization = "secret" }), Token: "secret"}
+	stream := createStream(t, opts)
+
+	c, err := dial(opts)
+	require.NoError(t, err)
+	defer c.close()
+
+	// A retry with the same ID is stored once
+	ctx := context.Background()
+	require.NoError(t, c.publish(ctx, "p2000.messages", "abc", []byte(`{"message":"A1"}`)))
+	require.NoError(t, c.publish(ctx, "p2000.messages", "abc", []byte(`{"message":"A1"}`)))
+
+	messages := storedMessages(t, stream)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "p2000.messages", messages[0].Subject)
+	assert.Equal(t, "abc", messages[0].Header.Get(jetstream.MsgIDHeader))
+	assert.Equal(t, `{"message":"A1"}`, string(messages[0].Data))
+
+	_, err = dial(Options{URL: opts.URL, Token: "wrong"})
+	assert.Error(t, err)
+}
+
+func TestConnPublish_NoStream(t *testing.T) {
+	c, err := dial(Options{URL: runServer(t, nil)})
+	require.NoError(t, err)
+	defer c.close()
+
+	assert.EqualError(t, c.publish(context.Background(), "p2000.messages", "abc", []byte(`{}`)), "no stream captures the subject")
+}
+
+func TestPublisher(t *testing.T) {
+	url := runServer(t, func(o *server.Options) { o.Username, o.Password = "p2000", "secret" })
+	m := metrics.NewMetrics()
+	p, err := New(Options{URL: url, Username: "p2000", Password: "secret", Metrics: m, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	stream := createStream(t, p.opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	received := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	p.Publish(model.Message{ID: "42", Message: "A1 Dorpsstraat Utrecht", Capcodes: []string{"001180000"}}, received, true)
+
+	require.Eventually(t, func() bool { return testutil.ToFloat64(m.StreamEvents.WithLabelValues("published")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	messages := storedMessages(t, stream)
+	require.Len(t, messages, 1)
+	assert.Equal(t, DefaultSubject, messages[0].Subject)
+	assert.Equal(t, "42", messages[0].Header.Get(jetstream.MsgIDHeader))
+
+	var ev Event
+	require.NoError(t, json.Unmarshal(messages[0].Data, &ev))
+	assert.Equal(t, "A1 Dorpsstraat Utrecht", ev.Message.Message)
+	assert.Equal(t, received, ev.ReceivedAt)
+	assert.True(t, ev.Forwarded)
+}
+
+func TestPublisher_Failed(t *testing.T) {
+	m := metrics.NewMetrics()
+	p, err := New(Options{URL: runServer(t, nil), Metrics: m, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	// Without a stream every attempt fails
+	p.Publish(model.Message{ID: "42", Message: "A1 Dorpsstraat Utrecht"}, time.Now(), true)
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(m.StreamEvents.WithLabelValues("failed")) == 1 }, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestPublisher_DropsWhenFull(t *testing.T) {
+	p, err := New(Options{URL: "nats://127.0.0.1:4222", Buffer: 1, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	// Without Run nothing is taken from the buffer
+	p.Publish(model.Message{Message: "1"}, time.Now(), true)
+	p.Publish(model.Message{Message: "2"}, time.Now(), false)
+	assert.Len(t, p.events, 1)
+	assert.NotEmpty(t, (<-p.events).id)
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Options{URL: "http://nats.example.com"})
+	assert.EqualError(t, err, `stream url "http://nats.example.com" must be a nats:// or tls:// URL`)
+
+	_, err = New(Options{URL: "nats://nats.example.com", Subject: "p2000.>"})
+	assert.EqualError(t, err, `stream subject "p2000.>" must be dot separated tokens without wildcards`)
+
+	_, err = New(Options{URL: "nats://nats.example.com", Buffer: -1})
+	assert.EqualError(t, err, "stream buffer must not be negative")
+}
@@ -0,0 +1,90 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+// NewTopicSender creates the sender for a subscribed ntfy topic
+type NewTopicSender func(topic string) (notifier.Sender, error)
+
+// Sender notifies every subscription matching a message on its own topic.
+// The sender of each topic is created on first use and reused afterwards.
+type Sender struct {
+	store    *Store
+	newTopic NewTopicSender
+	metrics  *metrics.Metrics
+	logger   zerolog.Logger
+
+	mu     sync.Mutex
+	topics map[string]notifier.Sender
+}
+
+// NewSender creates a sender for the subscriptions in store. The metrics
+// are updated when m is not nil.
+func NewSender(store *Store, newTopic NewTopicSender, m *metrics.Metrics, logger zerolog.Logger) *Sender {
+	return &Sender{
+		store:    store,
+		newTopic: newTopic,
+		metrics:  m,
+		logger:   logger,
+		topics:   make(map[string]notifier.Sender),
+	}
+}
+
+// Name returns the sender name
+func (s *Sender) Name() string {
+	return "subscriptions"
+}
+
+// Send notifies the subscriptions matching msg. A topic shared by several
+// subscriptions is notified once.
+func (s *Sender) Send(ctx context.Context, msg model.Message) error {
+	var errs []error
+	sent := make(map[string]bool)
+	for _, sub := range s.store.Match(msg) {
+		if sent[sub.Topic] {
+			continue
+		}
+		sent[sub.Topic] = true
+
+		err := s.send(ctx, sub.Topic, msg)
+		result := "sent"
+		if err != nil {
+			result = "failed"
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+			s.logger.Warn().
+				Err(err).
+				Str("subscription", sub.ID).
+				Str("topic", sub.Topic).
+				Msg("failed to notify subscription")
+		}
+		if s.metrics != nil {
+			s.metrics.RecordSubscriptionNotification(result)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sender) send(ctx context.Context, topic string, msg model.Message) error {
+	s.mu.Lock()
+	sender, ok := s.topics[topic]
+	if !ok {
+		var err error
+		if sender, err = s.newTopic(topic); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.topics[topic] = sender
+	}
+	s.mu.Unlock()
+
+	return sender.Send(ctx, msg)
+}
@@ -0,0 +1,74 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/internal/notifier"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+// topicSender records the messages sent to a topic
+type topicSender struct {
+	topic string
+	sent  *[]string
+	err   error
+}
+
+func (s topicSender) Name() string { return s.topic }
+
+func (s topicSender) Send(ctx context.Context, msg model.Message) error {
+	*s.sent = append(*s.sent, s.topic)
+	return s.err
+}
+
+func TestSender_Send(t *testing.T) {
+	store, err := Open("", 0)
+	require.NoError(t, err)
+	for _, sub := range []Subscription{
+		{Topic: "jan", Capcodes: []string{"0101001"}},
+		{Topic: "piet", Capcodes: []string{"0101001"}},
+		{Topic: "piet", Capcodes: []string{"101001"}}, // Same topic, notified once
+		{Topic: "kees", Capcodes: []string{"0202002"}},
+		{Topic: "broken", Capcodes: []string{"0101001"}},
+	} {
+		_, _, err := store.Create(sub)
+		require.NoError(t, err)
+	}
+
+	var sent []string
+	created := 0
+	sender := NewSender(store, func(topic string) (notifier.Sender, error) {
+		created++
+		s := topicSender{topic: topic, sent: &sent}
+		if topic == "broken" {
+			s.err = errors.New("unavailable")
+		}
+		return s, nil
+	}, nil, getTestLogger())
+	assert.Equal(t, "subscriptions", sender.Name())
+
+	err = sender.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}})
+	assert.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, []string{"jan", "piet", "broken"}, sent)
+
+	// Topic senders are reused
+	sent = nil
+	_ = sender.Send(context.Background(), model.Message{Capcodes: []string{"0101001"}})
+	assert.Len(t, sent, 3)
+	assert.Equal(t, 3, created)
+
+	sent = nil
+	assert.NoError(t, sender.Send(context.Background(), model.Message{Capcodes: []string{"0303003"}}))
+	assert.Empty(t, sent)
+}
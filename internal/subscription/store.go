@@ -0,0 +1,335 @@
+// Package subscription lets users register their own ntfy topic with the
+// capcodes, regions and stations they want to be paged for, so a single
+// forwarder can serve a whole brigade.
+package subscription
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+var (
+	// ErrNotFound is returned when a subscription does not exist
+	ErrNotFound = errors.New("subscription not found")
+	// ErrLimit is returned when the maximum number of subscriptions is
+	// reached
+	ErrLimit = errors.New("maximum number of subscriptions reached")
+)
+
+// topicPattern matches the topic names ntfy accepts
+var topicPattern = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// Subscription is a user's ntfy topic with the pages it receives
+type Subscription struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Topic     string    `json:"topic"`
+	Capcodes  []string  `json:"capcodes,omitempty"`
+	Regions   []string  `json:"regions,omitempty"`
+	Stations  []string  `json:"stations,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the topic and that the subscription selects any pages
+func (s Subscription) Validate() error {
+	if !topicPattern.MatchString(s.Topic) {
+		return fmt.Errorf("topic %q must be 1-64 letters, digits, - or _", s.Topic)
+	}
+	if len(s.Capcodes) == 0 && len(s.Regions) == 0 && len(s.Stations) == 0 {
+		return fmt.Errorf("at least one capcode, region or station is required")
+	}
+	return nil
+}
+
+// Matches reports whether msg is sent to the subscription: one of its
+// capcodes was paged, or a paged capcode belongs to one of its regions or
+// stations
+func (s Subscription) Matches(msg model.Message) bool {
+	for _, code := range msg.Capcodes {
+		code = capcode.Normalize(code)
+		for _, c := range s.Capcodes {
+			if capcode.Normalize(c) == code {
+				return true
+			}
+		}
+	}
+	for _, info := range msg.CapcodeInfo {
+		if containsFold(s.Regions, info.Region) || containsFold(s.Stations, info.Station) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// record is a stored subscription with the hash of its secret
+type record struct {
+	Subscription
+	SecretHash string `json:"secret_hash"`
+}
+
+// Store keeps the subscriptions in memory and persists every change to a
+// JSON file
+type Store struct {
+	path     string
+	max      int
+	onChange func(count int)
+
+	mu      sync.RWMutex
+	records []*record
+}
+
+// Open creates a store holding at most max subscriptions, unlimited when 0.
+// When path is set, existing subscriptions are loaded from it and changes
+// are written back to it.
+func Open(path string, max int) (*Store, error) {
+	s := &Store{path: path, max: max}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("failed to parse subscriptions: %w", err)
+	}
+	return s, nil
+}
+
+// Persistent reports whether changes are written to disk
+func (s *Store) Persistent() bool {
+	return s.path != ""
+}
+
+// OnChange sets a hook called with the number of subscriptions after every
+// change, e.g. to update a metric. It must be set before the store is used.
+func (s *Store) OnChange(fn func(count int)) {
+	s.onChange = fn
+}
+
+// Len returns the number of subscriptions
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Create adds a subscription and returns it with the secret its owner uses
+// to manage it. The secret is only stored as a hash.
+func (s *Store) Create(sub Subscription) (Subscription, string, error) {
+	if err := sub.Validate(); err != nil {
+		return Subscription{}, "", err
+	}
+	id, err := randomString(8)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+	secret, err := randomString(24)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.max > 0 && len(s.records) >= s.max {
+		return Subscription{}, "", ErrLimit
+	}
+	sub.ID = id
+	sub.CreatedAt = time.Now().UTC()
+	sub.UpdatedAt = sub.CreatedAt
+	s.records = append(s.records, &record{Subscription: sub, SecretHash: hashSecret(secret)})
+	if err := s.save(); err != nil {
+		s.records = s.records[:len(s.records)-1]
+		return Subscription{}, "", err
+	}
+	s.changed()
+	return sub.copy(), secret, nil
+}
+
+// Get returns a subscription by ID
+func (s *Store) Get(id string) (Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if r := s.find(id); r != nil {
+		return r.Subscription.copy(), true
+	}
+	return Subscription{}, false
+}
+
+// List returns all subscriptions in creation order
+func (s *Store) List() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Subscription, 0, len(s.records))
+	for _, r := range s.records {
+		result = append(result, r.Subscription.copy())
+	}
+	return result
+}
+
+// Update replaces the name, topic and selection of a subscription
+func (s *Store) Update(id string, sub Subscription) (Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return Subscription{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.find(id)
+	if r == nil {
+		return Subscription{}, ErrNotFound
+	}
+	previous := r.Subscription
+	sub.ID, sub.CreatedAt, sub.UpdatedAt = r.ID, r.CreatedAt, time.Now().UTC()
+	r.Subscription = sub
+	if err := s.save(); err != nil {
+		r.Subscription = previous
+		return Subscription{}, err
+	}
+	return sub.copy(), nil
+}
+
+// Delete removes a subscription, reporting whether it existed
+func (s *Store) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.records, func(r *record) bool { return r.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	previous := s.records
+	s.records = slices.Delete(slices.Clone(s.records), i, i+1)
+	if err := s.save(); err != nil {
+		s.records = previous
+		return true, err
+	}
+	s.changed()
+	return true, nil
+}
+
+// Verify reports whether secret belongs to the subscription with id
+func (s *Store) Verify(id, secret string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := s.find(id)
+	return r != nil && secret != "" &&
+		subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(r.SecretHash)) == 1
+}
+
+// Match returns the subscriptions msg is sent to
+func (s *Store) Match(msg model.Message) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Subscription
+	for _, r := range s.records {
+		if r.Matches(msg) {
+			result = append(result, r.Subscription.copy())
+		}
+	}
+	return result
+}
+
+// find returns the record with id, the caller must hold the lock
+func (s *Store) find(id string) *record {
+	for _, r := range s.records {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// changed calls the change hook, the caller must hold the write lock
+func (s *Store) changed() {
+	if s.onChange != nil {
+		s.onChange(len(s.records))
+	}
+}
+
+// save writes all subscriptions to a temporary file and renames it, so
+// readers never see a partial file. The caller must hold the write lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode subscriptions: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".subscriptions-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace subscriptions: %w", err)
+	}
+	return nil
+}
+
+// copy returns a copy of the subscription that does not share slices
+func (s Subscription) copy() Subscription {
+	s.Capcodes = slices.Clone(s.Capcodes)
+	s.Regions = slices.Clone(s.Regions)
+	s.Stations = slices.Clone(s.Stations)
+	return s
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded for use in URLs
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,118 @@
+package subscription
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_Validate(t *testing.T) {
+	assert.NoError(t, Subscription{Topic: "brandweer-noord_1", Capcodes: []string{"0101001"}}.Validate())
+	assert.ErrorContains(t, Subscription{Topic: "no spaces", Capcodes: []string{"0101001"}}.Validate(), "must be 1-64")
+	assert.ErrorContains(t, Subscription{Capcodes: []string{"0101001"}}.Validate(), "must be 1-64")
+	assert.ErrorContains(t, Subscription{Topic: "alerts"}.Validate(), "at least one capcode")
+}
+
+func TestSubscription_Matches(t *testing.T) {
+	msg := model.Message{
+		Capcodes:    []string{"0101001", "1420999"},
+		CapcodeInfo: []capcode.CapcodeInfo{{Capcode: "1420999", Region: "Utrecht", Station: "Noord"}},
+	}
+
+	assert.True(t, Subscription{Capcodes: []string{"101001"}}.Matches(msg))
+	assert.True(t, Subscription{Regions: []string{"utrecht"}}.Matches(msg))
+	assert.True(t, Subscription{Stations: []string{"NOORD"}}.Matches(msg))
+	assert.False(t, Subscription{Capcodes: []string{"0101002"}, Regions: []string{"Amsterdam"}}.Matches(msg))
+	assert.False(t, Subscription{Stations: []string{""}}.Matches(model.Message{CapcodeInfo: []capcode.CapcodeInfo{{Capcode: "1"}}}))
+}
+
+func TestStore_CRUD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	s, err := Open(path, 0)
+	require.NoError(t, err)
+	assert.True(t, s.Persistent())
+
+	var count int
+	s.OnChange(func(n int) { count = n })
+
+	sub, secret, err := s.Create(Subscription{Name: "Jan", Topic: "jan-pager", Capcodes: []string{"0101001"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.ID)
+	assert.NotEmpty(t, secret)
+	assert.False(t, sub.CreatedAt.IsZero())
+	assert.Equal(t, 1, count)
+
+	assert.True(t, s.Verify(sub.ID, secret))
+	assert.False(t, s.Verify(sub.ID, "wrong"))
+	assert.False(t, s.Verify(sub.ID, ""))
+	assert.False(t, s.Verify("unknown", secret))
+
+	updated, err := s.Update(sub.ID, Subscription{Topic: "jan-pager", Regions: []string{"Utrecht"}})
+	require.NoError(t, err)
+	assert.Equal(t, sub.ID, updated.ID)
+	assert.Equal(t, sub.CreatedAt, updated.CreatedAt)
+	assert.Empty(t, updated.Capcodes)
+
+	_, err = s.Update("unknown", Subscription{Topic: "x", Capcodes: []string{"1"}})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// The secret is not written in plain text and survives a reload
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), secret)
+
+	reopened, err := Open(path, 0)
+	require.NoError(t, err)
+	got, ok := reopened.Get(sub.ID)
+	require.True(t, ok)
+	assert.Equal(t, []string{"Utrecht"}, got.Regions)
+	assert.True(t, reopened.Verify(sub.ID, secret))
+
+	existed, err := s.Delete(sub.ID)
+	require.NoError(t, err)
+	assert.True(t, existed)
+	assert.Equal(t, 0, count)
+	existed, err = s.Delete(sub.ID)
+	require.NoError(t, err)
+	assert.False(t, existed)
+	assert.Empty(t, s.List())
+}
+
+func TestStore_Limit(t *testing.T) {
+	s, err := Open("", 1)
+	require.NoError(t, err)
+	assert.False(t, s.Persistent())
+
+	_, _, err = s.Create(Subscription{Topic: "a", Capcodes: []string{"1"}})
+	require.NoError(t, err)
+	_, _, err = s.Create(Subscription{Topic: "b", Capcodes: []string{"2"}})
+	assert.ErrorIs(t, err, ErrLimit)
+	_, _, err = s.Create(Subscription{Topic: "c"})
+	assert.ErrorContains(t, err, "at least one capcode")
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestStore_Match(t *testing.T) {
+	s, err := Open("", 0)
+	require.NoError(t, err)
+	a, _, err := s.Create(Subscription{Topic: "a", Capcodes: []string{"0101001"}})
+	require.NoError(t, err)
+	_, _, err = s.Create(Subscription{Topic: "b", Capcodes: []string{"0202002"}})
+	require.NoError(t, err)
+
+	matches := s.Match(model.Message{Capcodes: []string{"0101001"}})
+	require.Len(t, matches, 1)
+	assert.Equal(t, a.ID, matches[0].ID)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+	_, err := Open(path, 0)
+	assert.ErrorContains(t, err, "failed to parse subscriptions")
+}
@@ -0,0 +1,128 @@
+// Package tlsconfig builds TLS configurations from PEM files for the
+// upstream connections and the HTTP server. Certificates are reloaded when
+// their files change, so renewed certificates are used without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client returns the TLS configuration for connections to a server. caFile
+// adds a PEM bundle of trusted CAs to the system roots; certFile and keyFile
+// set a client certificate. It returns nil when all are empty, to keep the
+// default configuration.
+func Client(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		kp, err := newKeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return kp.get()
+		}
+	}
+	return cfg, nil
+}
+
+// Server returns the TLS configuration for serving with the certificate in
+// certFile and keyFile
+func Server(certFile, keyFile string) (*tls.Config, error) {
+	kp, err := newKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return kp.get()
+		},
+	}, nil
+}
+
+// keyPair holds a certificate loaded from files, reloaded when the files are
+// modified. A certificate that fails to reload, e.g. while the files are
+// being replaced, keeps the previous one in use.
+type keyPair struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both cert_file and key_file are required")
+	}
+	kp := &keyPair{certFile: certFile, keyFile: keyFile}
+	if err := kp.load(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+// get returns the certificate, reloading it when the files changed
+func (kp *keyPair) get() (*tls.Certificate, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	if modTime, err := kp.latestModTime(); err == nil && modTime.After(kp.modTime) {
+		_ = kp.load()
+	}
+	return kp.cert, nil
+}
+
+// load reads the certificate, called with mu held or before kp is shared
+func (kp *keyPair) load() error {
+	modTime, err := kp.latestModTime()
+	if err != nil {
+		return fmt.Errorf("failed to read certificate: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s: %w", kp.certFile, err)
+	}
+	kp.cert = &cert
+	kp.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the last modification time of the certificate and
+// key files
+func (kp *keyPair) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{kp.certFile, kp.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,142 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key to
+// dir, returning the file paths
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestClient_Empty(t *testing.T) {
+	cfg, err := Client("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestClient_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Client(filepath.Join(dir, "missing.pem"), "", "")
+	assert.ErrorContains(t, err, "failed to read CA bundle")
+
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+	_, err = Client(empty, "", "")
+	assert.ErrorContains(t, err, "no certificates found")
+
+	certFile, _ := writeCert(t, dir, "client")
+	_, err = Client("", certFile, "")
+	assert.ErrorContains(t, err, "both cert_file and key_file are required")
+}
+
+func TestServerAndClient(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeCert(t, dir, "server")
+	clientCert, clientKey := writeCert(t, dir, "client")
+
+	serverTLS, err := Server(serverCert, serverKey)
+	require.NoError(t, err)
+	clientPool := x509.NewCertPool()
+	pemData, err := os.ReadFile(clientCert)
+	require.NoError(t, err)
+	require.True(t, clientPool.AppendCertsFromPEM(pemData))
+	serverTLS.ClientAuth = tls.RequireAndVerifyClientCert
+	serverTLS.ClientCAs = clientPool
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	// StartTLS would replace GetCertificate with its own certificate
+	server.Listener = tls.NewListener(server.Listener, serverTLS)
+	server.Start()
+	defer server.Close()
+	url := strings.Replace(server.URL, "http://", "https://", 1)
+
+	clientTLS, err := Client(serverCert, clientCert, clientKey)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Without the CA bundle the server certificate is not trusted
+	_, err = (&http.Client{}).Get(url)
+	assert.Error(t, err)
+}
+
+func TestKeyPair_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "old")
+	kp, err := newKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	cert, err := kp.get()
+	require.NoError(t, err)
+	old := cert.Certificate[0]
+
+	// A broken file keeps the previous certificate
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	cert, err = kp.get()
+	require.NoError(t, err)
+	assert.Equal(t, old, cert.Certificate[0])
+
+	// A renewed certificate is picked up
+	newCert, newKey := writeCert(t, t.TempDir(), "new")
+	for src, dst := range map[string]string{newCert: certFile, newKey: keyFile} {
+		data, err := os.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dst, data, 0o600))
+		later := future.Add(time.Minute)
+		require.NoError(t, os.Chtimes(dst, later, later))
+	}
+	cert, err = kp.get()
+	require.NoError(t, err)
+	assert.NotEqual(t, old, cert.Certificate[0])
+}
@@ -0,0 +1,79 @@
+package translate
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// entry is a cache element
+type entry struct {
+	text        string
+	translation string
+	expires     time.Time
+}
+
+// cache keeps the most recently used translations for a limited time
+type cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // Most recently used first
+	entries map[string]*list.Element
+}
+
+// newCache creates a cache of at most size entries. Caching is disabled
+// when size or ttl is zero.
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the unexpired translation of text
+func (c *cache) get(text string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[text]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, text)
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return e.translation, true
+}
+
+// put stores a translation and evicts the least recently used entry when
+// the cache is full
+func (c *cache) put(text, translation string, now time.Time) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &entry{text: text, translation: translation, expires: now.Add(c.ttl)}
+	if el, ok := c.entries[text]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[text] = c.order.PushFront(e)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).text)
+	}
+}
@@ -0,0 +1,145 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Supported translation services
+const (
+	ProviderDeepL          = "deepl"          // DeepL API, free or pro
+	ProviderLibreTranslate = "libretranslate" // LibreTranslate, public or self-hosted
+)
+
+const (
+	deeplURL          = "https://api.deepl.com"
+	deeplFreeURL      = "https://api-free.deepl.com"
+	libreTranslateURL = "https://libretranslate.com"
+)
+
+// provider builds the requests and parses the responses of a translation
+// service
+type provider interface {
+	url() string
+	request(text, source, target, apiKey string) any
+	authorize(req *http.Request, apiKey string)
+	parse(body []byte) (string, error)
+}
+
+// newProvider returns the provider with the given name, using baseURL
+// instead of its public endpoint when set
+func newProvider(name, baseURL, apiKey string) (provider, error) {
+	switch strings.ToLower(name) {
+	case ProviderDeepL:
+		if apiKey == "" {
+			return nil, fmt.Errorf("deepl requires an api key")
+		}
+		if baseURL == "" {
+			// Keys of the free plan end in ":fx" and have their own endpoint
+			baseURL = deeplURL
+			if strings.HasSuffix(apiKey, ":fx") {
+				baseURL = deeplFreeURL
+			}
+		}
+		return &deepl{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case ProviderLibreTranslate:
+		if baseURL == "" {
+			baseURL = libreTranslateURL
+		}
+		return &libreTranslate{baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q", name)
+	}
+}
+
+// deepl queries the DeepL API
+type deepl struct {
+	baseURL string
+}
+
+type deeplRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+func (d *deepl) url() string {
+	return d.baseURL + "/v2/translate"
+}
+
+func (d *deepl) request(text, source, target, _ string) any {
+	return deeplRequest{
+		Text:       []string{text},
+		SourceLang: strings.ToUpper(source),
+		TargetLang: strings.ToUpper(target),
+	}
+}
+
+func (d *deepl) authorize(req *http.Request, apiKey string) {
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+apiKey)
+}
+
+func (d *deepl) parse(body []byte) (string, error) {
+	var resp deeplResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("response without translation")
+	}
+	return resp.Translations[0].Text, nil
+}
+
+// libreTranslate queries a LibreTranslate server
+type libreTranslate struct {
+	baseURL string
+}
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+func (l *libreTranslate) url() string {
+	return l.baseURL + "/translate"
+}
+
+func (l *libreTranslate) request(text, source, target, apiKey string) any {
+	return libreTranslateRequest{
+		Q:      text,
+		Source: strings.ToLower(source),
+		Target: strings.ToLower(target),
+		Format: "text",
+		APIKey: apiKey,
+	}
+}
+
+// authorize does nothing, as LibreTranslate takes the key in the body
+func (l *libreTranslate) authorize(*http.Request, string) {}
+
+func (l *libreTranslate) parse(body []byte) (string, error) {
+	var resp libreTranslateResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("translation failed: %s", resp.Error)
+	}
+	return resp.TranslatedText, nil
+}
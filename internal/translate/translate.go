@@ -0,0 +1,148 @@
+// Package translate translates the text of P2000 messages with a machine
+// translation service, e.g. to English for responders who do not read
+// Dutch. Translations are cached, as the same texts are paged to several
+// capcodes and repeated in follow-up pages.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	requestTimeout  = 10 * time.Second
+	maxResponseSize = 1 << 20
+)
+
+// Options configures a Translator. Only Provider is required.
+type Options struct {
+	Provider string // ProviderDeepL or ProviderLibreTranslate
+	URL      string // Overrides the public endpoint of the provider
+	APIKey   string // Required by DeepL and the public LibreTranslate server
+	Source   string // Language of the messages, e.g. "nl"
+	Target   string // Language translated to, e.g. "en"
+
+	CacheSize int           // Translations kept in the cache, disabled when 0
+	CacheTTL  time.Duration // Time a translation is cached
+
+	Transport http.RoundTripper // Defaults to http.DefaultTransport
+	Metrics   *metrics.Metrics  // Optional
+	Logger    zerolog.Logger
+}
+
+// Translator translates texts with a translation service. It is safe for
+// concurrent use.
+type Translator struct {
+	provider   provider
+	httpClient *http.Client
+	apiKey     string
+	source     string
+	target     string
+	cache      *cache
+	metrics    *metrics.Metrics
+	logger     zerolog.Logger
+	now        func() time.Time
+}
+
+// New creates a translator for the configured provider
+func New(opts Options) (*Translator, error) {
+	p, err := newProvider(opts.Provider, opts.URL, opts.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Source == "" || opts.Target == "" {
+		return nil, fmt.Errorf("translation source and target languages are required")
+	}
+
+	return &Translator{
+		provider: p,
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: opts.Transport,
+		},
+		apiKey:  opts.APIKey,
+		source:  opts.Source,
+		target:  opts.Target,
+		cache:   newCache(opts.CacheSize, opts.CacheTTL),
+		metrics: opts.Metrics,
+		logger:  opts.Logger,
+		now:     time.Now,
+	}, nil
+}
+
+// Translate returns the translation of text. Blank texts are returned
+// unchanged without a request.
+func (t *Translator) Translate(ctx context.Context, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil
+	}
+	if translation, ok := t.cache.get(text, t.now()); ok {
+		t.record("cached")
+		return translation, nil
+	}
+
+	resp, err := t.post(ctx, t.provider.request(text, t.source, t.target, t.apiKey))
+	if err != nil {
+		t.record("error")
+		return "", err
+	}
+	translation, err := t.provider.parse(resp)
+	if err != nil {
+		t.record("error")
+		return "", err
+	}
+
+	t.cache.put(text, translation, t.now())
+	t.record("translated")
+	return translation, nil
+}
+
+// post sends a JSON request to the provider and returns the body of a
+// successful response
+func (t *Translator) post(ctx context.Context, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.provider.url(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	t.provider.authorize(req, t.apiKey)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+// record counts a translation by its result when metrics are enabled
+func (t *Translator) record(result string) {
+	if t.metrics != nil {
+		t.metrics.RecordTranslation(result)
+	}
+}
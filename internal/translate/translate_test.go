@@ -0,0 +1,141 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestTranslator_DeepL(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "/v2/translate", r.URL.Path)
+		assert.Equal(t, "DeepL-Auth-Key secret:fx", r.Header.Get("Authorization"))
+
+		var req deeplRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, deeplRequest{Text: []string{"P 1 Brand woning Utrecht"}, SourceLang: "NL", TargetLang: "EN-GB"}, req)
+		w.Write([]byte(`{"translations":[{"detected_source_language":"NL","text":"P 1 Fire house Utrecht"}]}`))
+	}))
+	defer server.Close()
+
+	m := metrics.NewMetrics()
+	tr, err := New(Options{
+		Provider:  ProviderDeepL,
+		URL:       server.URL,
+		APIKey:    "secret:fx",
+		Source:    "nl",
+		Target:    "en-gb",
+		CacheSize: 10,
+		CacheTTL:  time.Hour,
+		Metrics:   m,
+		Logger:    getTestLogger(),
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		translation, err := tr.Translate(context.Background(), "P 1 Brand woning Utrecht")
+		require.NoError(t, err)
+		assert.Equal(t, "P 1 Fire house Utrecht", translation)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "second translation is cached")
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Translations.WithLabelValues("translated")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Translations.WithLabelValues("cached")))
+}
+
+func TestTranslator_LibreTranslate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/translate", r.URL.Path)
+
+		var req libreTranslateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, libreTranslateRequest{Q: "A1 Ambulance", Source: "nl", Target: "en", Format: "text", APIKey: "key"}, req)
+		w.Write([]byte(`{"translatedText":"A1 Ambulance"}`))
+	}))
+	defer server.Close()
+
+	tr, err := New(Options{Provider: ProviderLibreTranslate, URL: server.URL + "/", APIKey: "key", Source: "nl", Target: "en", Logger: getTestLogger()})
+	require.NoError(t, err)
+
+	translation, err := tr.Translate(context.Background(), " A1 Ambulance ")
+	require.NoError(t, err)
+	assert.Equal(t, "A1 Ambulance", translation)
+
+	translation, err = tr.Translate(context.Background(), "  ")
+	require.NoError(t, err)
+	assert.Empty(t, translation, "blank texts are not sent")
+}
+
+func TestTranslator_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	m := metrics.NewMetrics()
+	tr, err := New(Options{Provider: ProviderDeepL, URL: server.URL, APIKey: "expired", Source: "nl", Target: "en", Metrics: m})
+	require.NoError(t, err)
+	_, err = tr.Translate(context.Background(), "P 2 Test")
+	assert.EqualError(t, err, "unexpected status code: 403")
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Translations.WithLabelValues("error")))
+
+	p := &libreTranslate{}
+	_, err = p.parse([]byte(`{"error":"nl is not supported"}`))
+	assert.EqualError(t, err, "translation failed: nl is not supported")
+	_, err = (&deepl{}).parse([]byte(`{"translations":[]}`))
+	assert.EqualError(t, err, "response without translation")
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Options{Provider: "google", Source: "nl", Target: "en"})
+	assert.EqualError(t, err, `unknown translation provider "google"`)
+	_, err = New(Options{Provider: ProviderDeepL, Source: "nl", Target: "en"})
+	assert.EqualError(t, err, "deepl requires an api key")
+	_, err = New(Options{Provider: ProviderLibreTranslate})
+	assert.EqualError(t, err, "translation source and target languages are required")
+}
+
+func TestNewProvider_DeepLFreeEndpoint(t *testing.T) {
+	p, err := newProvider(ProviderDeepL, "", "abc:fx")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api-free.deepl.com/v2/translate", p.url())
+
+	p, err = newProvider(ProviderDeepL, "", "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.deepl.com/v2/translate", p.url())
+}
+
+func TestCache_Eviction(t *testing.T) {
+	now := time.Now()
+	c := newCache(2, time.Minute)
+	c.put("a", "A", now)
+	c.put("b", "B", now)
+	_, _ = c.get("a", now)
+	c.put("c", "C", now)
+
+	_, ok := c.get("b", now)
+	assert.False(t, ok, "least recently used entry is evicted")
+	translation, ok := c.get("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, "A", translation)
+
+	_, ok = c.get("c", now.Add(time.Minute))
+	assert.False(t, ok, "expired entry")
+}
@@ -0,0 +1,232 @@
+// Package weather adds the current wind and temperature at the incident to
+// storm damage and nature fire calls, where the wind decides how a fire
+// spreads and whether it is safe to work at height.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// Supported weather services
+const (
+	ProviderOpenWeather = "openweather" // OpenWeather current weather API
+)
+
+const (
+	openWeatherURL  = "https://api.openweathermap.org"
+	requestTimeout  = 10 * time.Second
+	maxResponseSize = 1 << 20
+)
+
+// DefaultKeywords select the calls the weather is added to
+var DefaultKeywords = []string{"stormschade", "natuurbrand", "bosbrand", "heidebrand", "duinbrand", "buitenbrand"}
+
+// Options configures a Provider. Provider and APIKey are required.
+type Options struct {
+	Provider string        // ProviderOpenWeather
+	URL      string        // Overrides the public endpoint of the provider
+	APIKey   string        // API key of the service
+	Keywords []string      // Words in the message text selecting calls, DefaultKeywords when empty
+	CacheTTL time.Duration // Time the weather of an area is cached, disabled when 0
+
+	Transport http.RoundTripper // Defaults to http.DefaultTransport
+}
+
+// Provider looks up the current weather at geocoded incidents. It
+// implements enrich.Enricher and is safe for concurrent use.
+type Provider struct {
+	baseURL    string
+	apiKey     string
+	keywords   []string
+	ttl        time.Duration
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[cell]cached
+}
+
+// cell is a grid cell of about 10 km, sharing its weather
+type cell struct {
+	lat, lon int
+}
+
+// cached is the weather of a cell
+type cached struct {
+	weather model.Weather
+	expires time.Time
+}
+
+// New creates a weather provider
+func New(opts Options) (*Provider, error) {
+	if !strings.EqualFold(opts.Provider, ProviderOpenWeather) {
+		return nil, fmt.Errorf("unknown weather provider %q", opts.Provider)
+	}
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("weather provider %s requires an api key", ProviderOpenWeather)
+	}
+
+	baseURL := opts.URL
+	if baseURL == "" {
+		baseURL = openWeatherURL
+	}
+	keywords := opts.Keywords
+	if len(keywords) == 0 {
+		keywords = DefaultKeywords
+	}
+	lower := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		lower = append(lower, strings.ToLower(k))
+	}
+
+	return &Provider{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   opts.APIKey,
+		keywords: lower,
+		ttl:      opts.CacheTTL,
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: opts.Transport,
+		},
+		now:   time.Now,
+		cache: make(map[cell]cached),
+	}, nil
+}
+
+// Name returns the name of the enricher
+func (p *Provider) Name() string {
+	return "weather"
+}
+
+// Enrich adds the current weather to calls matching a keyword that have
+// coordinates
+func (p *Provider) Enrich(ctx context.Context, msg *model.Message) error {
+	if msg.Coordinates == nil || !p.Matches(msg.Message) {
+		return nil
+	}
+	w, err := p.Current(ctx, *msg.Coordinates)
+	if err != nil {
+		return err
+	}
+	msg.Weather = &w
+	return nil
+}
+
+// Matches reports whether text contains one of the keywords
+func (p *Provider) Matches(text string) bool {
+	text = strings.ToLower(text)
+	for _, k := range p.keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the current weather at c, cached per grid cell
+func (p *Provider) Current(ctx context.Context, c model.Coordinates) (model.Weather, error) {
+	key := cell{lat: int(math.Round(c.Lat * 10)), lon: int(math.Round(c.Lon * 10))}
+	p.mu.Lock()
+	if entry, ok := p.cache[key]; ok && p.now().Before(entry.expires) {
+		p.mu.Unlock()
+		return entry.weather, nil
+	}
+	p.mu.Unlock()
+
+	w, err := p.fetch(ctx, c)
+	if err != nil {
+		return model.Weather{}, err
+	}
+
+	if p.ttl > 0 {
+		now := p.now()
+		p.mu.Lock()
+		for k, entry := range p.cache {
+			if !now.Before(entry.expires) {
+				delete(p.cache, k)
+			}
+		}
+		p.cache[key] = cached{weather: w, expires: now.Add(p.ttl)}
+		p.mu.Unlock()
+	}
+	return w, nil
+}
+
+// openWeatherResponse is the current weather of the OpenWeather API
+type openWeatherResponse struct {
+	Weather []struct {
+		Description string `json:"description"`
+	} `json:"weather"`
+	Main struct {
+		Temp float64 `json:"temp"`
+	} `json:"main"`
+	Wind struct {
+		Speed float64 `json:"speed"`
+		Deg   int     `json:"deg"`
+		Gust  float64 `json:"gust"`
+	} `json:"wind"`
+}
+
+// fetch requests the current weather at c
+func (p *Provider) fetch(ctx context.Context, c model.Coordinates) (model.Weather, error) {
+	u := p.baseURL + "/data/2.5/weather?" + url.Values{
+		"lat":   {strconv.FormatFloat(c.Lat, 'f', 4, 64)},
+		"lon":   {strconv.FormatFloat(c.Lon, 'f', 4, 64)},
+		"units": {"metric"},
+		"appid": {p.apiKey},
+	}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return model.Weather{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		// The URL holds the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return model.Weather{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return model.Weather{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return model.Weather{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	var r openWeatherResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return model.Weather{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	w := model.Weather{
+		Temperature:   r.Main.Temp,
+		WindSpeed:     r.Wind.Speed,
+		WindGust:      r.Wind.Gust,
+		WindDirection: r.Wind.Deg,
+	}
+	if len(r.Weather) > 0 {
+		w.Description = r.Weather[0].Description
+	}
+	return w, nil
+}
@@ -0,0 +1,84 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOpenWeatherServer serves a stormy current weather and counts requests
+func newOpenWeatherServer(t *testing.T, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		assert.Equal(t, "/data/2.5/weather", r.URL.Path)
+		assert.Equal(t, "secret", r.URL.Query().Get("appid"))
+		assert.Equal(t, "metric", r.URL.Query().Get("units"))
+		w.Write([]byte(`{"weather":[{"description":"moderate rain"}],"main":{"temp":9.4},"wind":{"speed":17.5,"deg":240,"gust":26.1}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProvider_Enrich(t *testing.T) {
+	var requests int32
+	server := newOpenWeatherServer(t, &requests)
+
+	p, err := New(Options{Provider: ProviderOpenWeather, URL: server.URL, APIKey: "secret", CacheTTL: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "weather", p.Name())
+
+	msg := model.Message{Message: "P 2 Stormschade Dak Utrecht", Coordinates: &model.Coordinates{Lat: 52.0907, Lon: 5.1214}}
+	require.NoError(t, p.Enrich(context.Background(), &msg))
+	assert.Equal(t, &model.Weather{Temperature: 9.4, WindSpeed: 17.5, WindGust: 26.1, WindDirection: 240, Description: "moderate rain"}, msg.Weather)
+
+	// A nearby incident shares the cached weather
+	msg = model.Message{Message: "P 1 NATUURBRAND Heide", Coordinates: &model.Coordinates{Lat: 52.1, Lon: 5.1}}
+	require.NoError(t, p.Enrich(context.Background(), &msg))
+	assert.NotNil(t, msg.Weather)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestProvider_Skips(t *testing.T) {
+	var requests int32
+	server := newOpenWeatherServer(t, &requests)
+
+	p, err := New(Options{Provider: ProviderOpenWeather, URL: server.URL, APIKey: "secret", Keywords: []string{"Wateroverlast"}})
+	require.NoError(t, err)
+
+	msg := model.Message{Message: "P 2 Stormschade Dak Utrecht", Coordinates: &model.Coordinates{Lat: 52.09, Lon: 5.12}}
+	require.NoError(t, p.Enrich(context.Background(), &msg))
+	assert.Nil(t, msg.Weather, "calls without a keyword")
+
+	msg = model.Message{Message: "P 2 Wateroverlast Kelder"}
+	require.NoError(t, p.Enrich(context.Background(), &msg))
+	assert.Nil(t, msg.Weather, "calls without coordinates")
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
+
+func TestProvider_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p, err := New(Options{Provider: ProviderOpenWeather, URL: server.URL, APIKey: "expired"})
+	require.NoError(t, err)
+
+	msg := model.Message{Message: "P 1 Bosbrand", Coordinates: &model.Coordinates{Lat: 52.09, Lon: 5.12}}
+	assert.EqualError(t, p.Enrich(context.Background(), &msg), "unexpected status code: 401")
+	assert.Nil(t, msg.Weather)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Options{Provider: "knmi", APIKey: "secret"})
+	assert.EqualError(t, err, `unknown weather provider "knmi"`)
+	_, err = New(Options{Provider: ProviderOpenWeather})
+	assert.EqualError(t, err, "weather provider openweather requires an api key")
+}
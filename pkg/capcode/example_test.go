@@ -0,0 +1,22 @@
+package capcode_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+)
+
+func ExampleLookup_Find() {
+	csv := "0101001;Brandweer;Utrecht;Utrecht Centrum;TS 4231\n"
+	lookup, err := capcode.NewLookupFromReader(strings.NewReader(csv))
+	if err != nil {
+		panic(err)
+	}
+
+	// Leading zeros are optional
+	if info, ok := lookup.Find("101001"); ok {
+		fmt.Println(info.Agency, info.Function)
+	}
+	// Output: Brandweer TS 4231
+}
@@ -0,0 +1,167 @@
+package capcode
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Loader parses capcode records from a capcode database
+type Loader interface {
+	// Name returns a human readable name of the format
+	Name() string
+	// Load reads all capcode records from r
+	Load(r io.Reader) ([]CapcodeInfo, error)
+}
+
+// Writer is implemented by loaders that can serialize records back into
+// their own format
+type Writer interface {
+	Write(w io.Writer, records []CapcodeInfo) error
+}
+
+// fileLoader is implemented by loaders that need random access to a file
+// on disk instead of a stream (e.g. SQLite)
+type fileLoader interface {
+	LoadFile(path string) ([]CapcodeInfo, error)
+	SaveFile(path string, records []CapcodeInfo) error
+}
+
+// LoaderFor returns the loader matching the file extension of path.
+// URLs are matched on their path component; unknown extensions fall back to CSV.
+func LoaderFor(p string) Loader {
+	if IsRemote(p) {
+		if u, err := url.Parse(p); err == nil {
+			p = u.Path
+		}
+	}
+
+	switch strings.ToLower(path.Ext(p)) {
+	case ".json":
+		return JSONLoader{}
+	case ".db", ".sqlite", ".sqlite3":
+		return SQLiteLoader{}
+	default:
+		return CSVLoader{}
+	}
+}
+
+// CSVLoader loads semicolon separated capcode lists:
+// capcode;agency;region;station;function
+type CSVLoader struct{}
+
+// Name returns the format name
+func (CSVLoader) Name() string {
+	return "CSV"
+}
+
+// Load reads semicolon separated capcode records from r
+func (CSVLoader) Load(r io.Reader) ([]CapcodeInfo, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+
+	// Read all records
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	// Skip header row if it exists
+	startIdx := 0
+	if len(records) > 0 {
+		// Check if first row is a header by looking for "capcode" or "agency"
+		firstRow := strings.ToLower(records[0][0])
+		if strings.Contains(firstRow, "capcode") || strings.Contains(firstRow, "cap") {
+			startIdx = 1
+		}
+	}
+
+	result := make([]CapcodeInfo, 0, len(records))
+	for i := startIdx; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 5 {
+			continue // Skip incomplete records
+		}
+
+		result = append(result, CapcodeInfo{
+			Capcode:  strings.Trim(record[0], `"`),
+			Agency:   strings.Trim(record[1], `"`),
+			Region:   strings.Trim(record[2], `"`),
+			Station:  strings.Trim(record[3], `"`),
+			Function: strings.Trim(record[4], `"`),
+		})
+	}
+
+	return result, nil
+}
+
+// Write serializes records as semicolon separated CSV with a header row
+func (CSVLoader) Write(w io.Writer, records []CapcodeInfo) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+
+	if err := writer.Write([]string{"capcode", "agency", "region", "station", "function"}); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, info := range records {
+		if err := writer.Write([]string{info.Capcode, info.Agency, info.Region, info.Station, info.Function}); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// JSONLoader loads a JSON array of capcode objects:
+// [{"capcode": "0101001", "agency": "Brandweer", ...}]
+type JSONLoader struct{}
+
+// Name returns the format name
+func (JSONLoader) Name() string {
+	return "JSON"
+}
+
+// Load reads a JSON array of capcode records from r
+func (JSONLoader) Load(r io.Reader) ([]CapcodeInfo, error) {
+	var records []CapcodeInfo
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	result := records[:0]
+	for _, info := range records {
+		if info.Capcode == "" {
+			continue // Skip records without capcode
+		}
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+// Write serializes records as an indented JSON array
+func (JSONLoader) Write(w io.Writer, records []CapcodeInfo) error {
+	if records == nil {
+		records = []CapcodeInfo{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
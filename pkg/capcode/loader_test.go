@@ -0,0 +1,95 @@
+package capcode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderFor(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"capcodelijst.csv", "CSV"},
+		{"capcodes.JSON", "JSON"},
+		{"capcodes.db", "SQLite database"},
+		{"capcodes.sqlite", "SQLite database"},
+		{"capcodes.sqlite3", "SQLite database"},
+		{"capcodes", "CSV"},
+		{"https://example.com/capcodes.json?token=abc", "JSON"},
+		{"https://example.com/capcodes.csv", "CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, LoaderFor(tt.path).Name())
+		})
+	}
+}
+
+func TestJSONLoader_Load(t *testing.T) {
+	data := `[
+		{"capcode": "0101001", "agency": "Brandweer", "region": "Utrecht", "station": "Centrum", "function": "Kazernealarm"},
+		{"capcode": "", "agency": "Invalid"},
+		{"capcode": "0101002", "agency": "Ambulance"}
+	]`
+
+	records, err := JSONLoader{}.Load(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "0101001", records[0].Capcode)
+	assert.Equal(t, "Kazernealarm", records[0].Function)
+	assert.Equal(t, "Ambulance", records[1].Agency)
+	assert.Empty(t, records[1].Region)
+}
+
+func TestJSONLoader_Empty(t *testing.T) {
+	records, err := JSONLoader{}.Load(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestJSONLoader_Invalid(t *testing.T) {
+	_, err := JSONLoader{}.Load(strings.NewReader(`{"capcode": "0101001"}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read JSON")
+}
+
+func TestNewLookup_JSONFile(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "capcodes.json")
+	err := os.WriteFile(jsonPath, []byte(`[{"capcode": "0101001", "agency": "Brandweer"}]`), 0644)
+	require.NoError(t, err)
+
+	lookup, err := NewLookup(jsonPath)
+	require.NoError(t, err)
+
+	info := lookup.Get("101001")
+	require.NotNil(t, info)
+	assert.Equal(t, "Brandweer", info.Agency)
+}
+
+func TestNewLookup_JSONFileNotFound(t *testing.T) {
+	_, err := NewLookup("/nonexistent/capcodes.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open capcode JSON")
+}
+
+func TestNewLookup_SQLiteFileNotFound(t *testing.T) {
+	_, err := NewLookup("/nonexistent/capcodes.db")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open capcode SQLite database")
+}
+
+func TestLookup_Replace(t *testing.T) {
+	lookup := NewLookupFromRecords([]CapcodeInfo{{Capcode: "0101001", Agency: "Brandweer"}})
+	require.NotNil(t, lookup.Get("0101001"))
+
+	lookup.Replace([]CapcodeInfo{{Capcode: "0101002", Agency: "Ambulance"}})
+	assert.Nil(t, lookup.Get("0101001"))
+	assert.Equal(t, "Ambulance", lookup.Get("101002").Agency)
+}
@@ -0,0 +1,210 @@
+// Package capcode looks up the agency, region, station and function of P2000
+// capcodes in a capcode database, loaded from CSV, JSON or SQLite files or
+// a remote URL.
+package capcode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CapcodeInfo contains information about a capcode from the capcode database
+type CapcodeInfo struct {
+	Capcode  string `json:"capcode"`
+	Agency   string `json:"agency"`
+	Region   string `json:"region"`
+	Station  string `json:"station"`
+	Function string `json:"function"`
+}
+
+// Lookup provides capcode information lookup functionality
+type Lookup struct {
+	mu   sync.RWMutex
+	data map[string]CapcodeInfo
+}
+
+// NewLookup creates a new capcode lookup from a capcode database file.
+// The format (CSV, JSON or SQLite) is detected from the file extension.
+func NewLookup(path string) (*Lookup, error) {
+	loader := LoaderFor(path)
+
+	var records []CapcodeInfo
+	if fl, ok := loader.(fileLoader); ok {
+		r, err := fl.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		records = r
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open capcode %s: %w", loader.Name(), err)
+		}
+		defer file.Close()
+
+		r, err := loader.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		records = r
+	}
+
+	return NewLookupFromRecords(records), nil
+}
+
+// NewLookupFromReader creates a new capcode lookup from semicolon separated CSV data
+func NewLookupFromReader(r io.Reader) (*Lookup, error) {
+	records, err := CSVLoader{}.Load(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLookupFromRecords(records), nil
+}
+
+// NewLookupFromRecords creates a new capcode lookup from already parsed records
+func NewLookupFromRecords(records []CapcodeInfo) *Lookup {
+	return &Lookup{data: index(records)}
+}
+
+// Replace atomically swaps the lookup data with the given records
+func (l *Lookup) Replace(records []CapcodeInfo) {
+	data := index(records)
+
+	l.mu.Lock()
+	l.data = data
+	l.mu.Unlock()
+}
+
+// Normalize returns capcode without surrounding space and leading zeros,
+// which feeds and configurations do not use consistently: "0101001" and
+// "101001" are the same capcode. A capcode of only zeros is "0".
+func Normalize(capcode string) string {
+	capcode = strings.TrimSpace(capcode)
+	if capcode == "" {
+		return ""
+	}
+	if normalized := strings.TrimLeft(capcode, "0"); normalized != "" {
+		return normalized
+	}
+	return "0"
+}
+
+// index builds the lookup map, keyed by both the original and the
+// normalized (no leading zeros) capcode
+func index(records []CapcodeInfo) map[string]CapcodeInfo {
+	data := make(map[string]CapcodeInfo, len(records)*2)
+
+	for _, info := range records {
+		// Store with normalized capcode (without leading zeros) as key
+		data[Normalize(info.Capcode)] = info
+
+		// Also store with original capcode for exact matches
+		data[info.Capcode] = info
+	}
+
+	return data
+}
+
+// Get retrieves capcode information, returns nil if not found
+// Handles both formats with and without leading zeros
+func (l *Lookup) Get(capcode string) *CapcodeInfo {
+	if info, ok := l.Find(capcode); ok {
+		return &info
+	}
+	return nil
+}
+
+// Find retrieves capcode information like Get, but returns it by value so
+// lookups on the message path do not allocate
+func (l *Lookup) Find(capcode string) (CapcodeInfo, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Try exact match first
+	if info, ok := l.data[capcode]; ok {
+		return info, true
+	}
+
+	// Try normalized version (without leading zeros)
+	info, ok := l.data[Normalize(capcode)]
+	return info, ok
+}
+
+// GetMultiple retrieves information for multiple capcodes
+func (l *Lookup) GetMultiple(capcodes []string) []CapcodeInfo {
+	result := make([]CapcodeInfo, 0, len(capcodes))
+
+	for _, capcode := range capcodes {
+		if info, ok := l.Find(capcode); ok {
+			result = append(result, info)
+		}
+	}
+
+	return result
+}
+
+// Set adds or replaces the information for a capcode
+func (l *Lookup) Set(info CapcodeInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.deleteLocked(info.Capcode)
+	for key, value := range index([]CapcodeInfo{info}) {
+		l.data[key] = value
+	}
+}
+
+// Delete removes a capcode, reports whether it existed
+func (l *Lookup) Delete(capcode string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.deleteLocked(capcode)
+}
+
+// deleteLocked removes all keys pointing to the record of capcode.
+// The caller must hold the write lock.
+func (l *Lookup) deleteLocked(capcode string) bool {
+	normalized := Normalize(capcode)
+
+	info, ok := l.data[capcode]
+	if !ok {
+		info, ok = l.data[normalized]
+	}
+	if !ok {
+		return false
+	}
+
+	for key, value := range l.data {
+		if value.Capcode == info.Capcode {
+			delete(l.data, key)
+		}
+	}
+	return true
+}
+
+// All returns every capcode record once, sorted by capcode
+func (l *Lookup) All() []CapcodeInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]struct{}, len(l.data)/2)
+	result := make([]CapcodeInfo, 0, len(l.data)/2)
+	for _, info := range l.data {
+		if _, ok := seen[info.Capcode]; ok {
+			continue
+		}
+		seen[info.Capcode] = struct{}{}
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Capcode < result[j].Capcode
+	})
+	return result
+}
@@ -35,6 +35,14 @@ func TestNewLookup_ValidCSV(t *testing.T) {
 	assert.Equal(t, "Kazernealarm", info.Function)
 }
 
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "101001", Normalize("0101001"))
+	assert.Equal(t, "101001", Normalize("101001"))
+	assert.Equal(t, "1420059", Normalize(" 001420059 "))
+	assert.Equal(t, "0", Normalize("0000"))
+	assert.Equal(t, "", Normalize(" "))
+}
+
 func TestNewLookup_WithoutHeader(t *testing.T) {
 	tmpDir := t.TempDir()
 	csvPath := filepath.Join(tmpDir, "capcodes.csv")
@@ -329,15 +337,15 @@ func TestGetMultiple(t *testing.T) {
 	require.NoError(t, err)
 
 	tests := []struct {
-		name         string
-		capcodes     []string
-		expectedLen  int
+		name          string
+		capcodes      []string
+		expectedLen   int
 		expectedFirst string
 	}{
 		{
-			name:         "Multiple valid capcodes",
-			capcodes:     []string{"0101001", "0101002"},
-			expectedLen:  2,
+			name:          "Multiple valid capcodes",
+			capcodes:      []string{"0101001", "0101002"},
+			expectedLen:   2,
 			expectedFirst: "0101001",
 		},
 		{
@@ -346,9 +354,9 @@ func TestGetMultiple(t *testing.T) {
 			expectedLen: 0,
 		},
 		{
-			name:         "Mix of valid and invalid",
-			capcodes:     []string{"0101001", "9999999", "0234567"},
-			expectedLen:  2,
+			name:          "Mix of valid and invalid",
+			capcodes:      []string{"0101001", "9999999", "0234567"},
+			expectedLen:   2,
 			expectedFirst: "0101001",
 		},
 		{
@@ -357,15 +365,15 @@ func TestGetMultiple(t *testing.T) {
 			expectedLen: 0,
 		},
 		{
-			name:         "With normalized capcodes",
-			capcodes:     []string{"101001", "234567"},
-			expectedLen:  2,
+			name:          "With normalized capcodes",
+			capcodes:      []string{"101001", "234567"},
+			expectedLen:   2,
 			expectedFirst: "0101001",
 		},
 		{
-			name:         "Duplicate capcodes",
-			capcodes:     []string{"0101001", "0101001", "0101002"},
-			expectedLen:  3, // Duplicates are not filtered
+			name:          "Duplicate capcodes",
+			capcodes:      []string{"0101001", "0101001", "0101002"},
+			expectedLen:   3, // Duplicates are not filtered
 			expectedFirst: "0101001",
 		},
 	}
@@ -532,6 +540,20 @@ func BenchmarkGet_Normalized(b *testing.B) {
 	}
 }
 
+func BenchmarkFind(b *testing.B) {
+	records := make([]CapcodeInfo, 1000)
+	for i := range records {
+		records[i] = CapcodeInfo{Capcode: padCapcode(i), Agency: "Agency", Region: "Region", Station: "Station"}
+	}
+	lookup := NewLookupFromRecords(records)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lookup.Find("00000500")
+	}
+}
+
 func BenchmarkGetMultiple(b *testing.B) {
 	tmpDir := b.TempDir()
 	csvPath := filepath.Join(tmpDir, "bench.csv")
@@ -0,0 +1,128 @@
+package capcode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	remoteRequestTimeout = 30 * time.Second
+)
+
+// IsRemote reports whether the capcode source is an HTTP(S) URL
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// RemoteLookup downloads the capcode database over HTTP and keeps it up to
+// date using ETag based conditional requests
+type RemoteLookup struct {
+	*Lookup
+
+	url        string
+	loader     Loader
+	interval   time.Duration
+	httpClient *http.Client
+	logger     zerolog.Logger
+
+	mu   sync.Mutex
+	etag string
+}
+
+// NewRemoteLookup downloads the capcode CSV from url and returns a lookup
+// that can be refreshed periodically with Run
+func NewRemoteLookup(ctx context.Context, url string, interval time.Duration, logger zerolog.Logger) (*RemoteLookup, error) {
+	r := &RemoteLookup{
+		Lookup:   &Lookup{data: make(map[string]CapcodeInfo)},
+		url:      url,
+		loader:   LoaderFor(url),
+		interval: interval,
+		httpClient: &http.Client{
+			Timeout: remoteRequestTimeout,
+		},
+		logger: logger,
+	}
+
+	if _, err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Refresh fetches the capcode CSV if it changed since the last download.
+// It reports whether the lookup data was replaced.
+func (r *RemoteLookup) Refresh(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	r.mu.Lock()
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	r.mu.Unlock()
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to download capcode CSV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return false, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	records, err := r.loader.Load(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	r.Replace(records)
+
+	r.mu.Lock()
+	r.etag = resp.Header.Get("ETag")
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Run refreshes the capcode CSV every interval until ctx is cancelled
+func (r *RemoteLookup) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			updated, err := r.Refresh(ctx)
+			if err != nil {
+				r.logger.Warn().
+					Err(err).
+					Str("url", r.url).
+					Msg("failed to refresh capcode CSV, keeping previous data")
+				continue
+			}
+			if updated {
+				r.logger.Info().
+					Str("url", r.url).
+					Msg("capcode CSV refreshed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package capcode
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() zerolog.Logger {
+	var buf bytes.Buffer
+	return zerolog.New(&buf).With().Timestamp().Logger()
+}
+
+func TestIsRemote(t *testing.T) {
+	assert.True(t, IsRemote("https://example.com/capcodes.csv"))
+	assert.True(t, IsRemote("http://example.com/capcodes.csv"))
+	assert.False(t, IsRemote("capcodelijst.csv"))
+	assert.False(t, IsRemote("/data/capcodes.csv"))
+}
+
+func TestNewRemoteLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"))
+	}))
+	defer server.Close()
+
+	lookup, err := NewRemoteLookup(context.Background(), server.URL, time.Hour, getTestLogger())
+	require.NoError(t, err)
+
+	info := lookup.Get("0101001")
+	require.NotNil(t, info)
+	assert.Equal(t, "Brandweer", info.Agency)
+}
+
+func TestNewRemoteLookup_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	lookup, err := NewRemoteLookup(context.Background(), server.URL, time.Hour, getTestLogger())
+	assert.Error(t, err)
+	assert.Nil(t, lookup)
+	assert.Contains(t, err.Error(), "unexpected status code: 500")
+}
+
+func TestRemoteLookup_RefreshWithETag(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n > 1 {
+			assert.Equal(t, `"v1"`, r.Header.Get("If-None-Match"))
+		}
+		if n == 2 {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if n == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"))
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		w.Write([]byte("0101002;Ambulance;Utrecht;Oost;A1 Dienst\n"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	lookup, err := NewRemoteLookup(ctx, server.URL, time.Hour, getTestLogger())
+	require.NoError(t, err)
+
+	// Second request returns 304 and keeps existing data
+	updated, err := lookup.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.NotNil(t, lookup.Get("0101001"))
+
+	// Third request returns new data
+	updated, err = lookup.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Nil(t, lookup.Get("0101001"))
+	assert.NotNil(t, lookup.Get("0101002"))
+}
+
+func TestRemoteLookup_RefreshFailureKeepsData(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	lookup, err := NewRemoteLookup(ctx, server.URL, time.Hour, getTestLogger())
+	require.NoError(t, err)
+
+	fail.Store(true)
+	updated, err := lookup.Refresh(ctx)
+	assert.Error(t, err)
+	assert.False(t, updated)
+	assert.NotNil(t, lookup.Get("0101001"))
+}
+
+func TestRemoteLookup_Run(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lookup, err := NewRemoteLookup(ctx, server.URL, 10*time.Millisecond, getTestLogger())
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		lookup.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
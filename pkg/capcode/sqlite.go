@@ -0,0 +1,118 @@
+package capcode
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SQLiteDriver is the database/sql driver name used to open SQLite capcode
+// databases. The driver itself must be registered by the binary (for example
+// by importing modernc.org/sqlite), keeping this package free of cgo.
+var SQLiteDriver = "sqlite"
+
+// sqliteQuery selects capcode records from the conventional capcodes table
+const sqliteQuery = `SELECT capcode, agency, region, station, function FROM capcodes`
+
+// SQLiteLoader loads capcode records from a SQLite database with a
+// capcodes(capcode, agency, region, station, function) table
+type SQLiteLoader struct{}
+
+// Name returns the format name
+func (SQLiteLoader) Name() string {
+	return "SQLite database"
+}
+
+// Load copies the SQLite database from r to a temporary file and reads it
+func (l SQLiteLoader) Load(r io.Reader) ([]CapcodeInfo, error) {
+	tmp, err := os.CreateTemp("", "capcodes-*.sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary database: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temporary database: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary database: %w", err)
+	}
+
+	return l.LoadFile(tmp.Name())
+}
+
+// LoadFile reads capcode records from the SQLite database at path
+func (SQLiteLoader) LoadFile(path string) ([]CapcodeInfo, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open capcode SQLite database: %w", err)
+	}
+
+	db, err := sql.Open(SQLiteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capcode SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(sqliteQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capcodes: %w", err)
+	}
+	defer rows.Close()
+
+	var result []CapcodeInfo
+	for rows.Next() {
+		var info CapcodeInfo
+		var agency, region, station, function sql.NullString
+		if err := rows.Scan(&info.Capcode, &agency, &region, &station, &function); err != nil {
+			return nil, fmt.Errorf("failed to read capcode row: %w", err)
+		}
+		info.Agency = agency.String
+		info.Region = region.String
+		info.Station = station.String
+		info.Function = function.String
+		result = append(result, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capcodes: %w", err)
+	}
+
+	return result, nil
+}
+
+// SaveFile replaces the contents of the capcodes table with records
+func (SQLiteLoader) SaveFile(path string, records []CapcodeInfo) error {
+	db, err := sql.Open(SQLiteDriver, path)
+	if err != nil {
+		return fmt.Errorf("failed to open capcode SQLite database: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM capcodes`); err != nil {
+		return fmt.Errorf("failed to clear capcodes: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO capcodes (capcode, agency, region, station, function) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, info := range records {
+		if _, err := stmt.Exec(info.Capcode, info.Agency, info.Region, info.Station, info.Function); err != nil {
+			return fmt.Errorf("failed to insert capcode %s: %w", info.Capcode, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit capcodes: %w", err)
+	}
+	return nil
+}
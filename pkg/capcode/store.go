@@ -0,0 +1,131 @@
+package capcode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Store applies runtime changes to a lookup and persists them back to the
+// capcode database file it was loaded from
+type Store struct {
+	lookup *Lookup
+	path   string
+	loader Loader
+	mu     sync.Mutex
+}
+
+// NewStore creates a store for lookup. Changes are written to path using the
+// format detected from its extension; an empty or remote path keeps changes
+// in memory only.
+func NewStore(lookup *Lookup, path string) *Store {
+	if IsRemote(path) {
+		path = ""
+	}
+
+	return &Store{
+		lookup: lookup,
+		path:   path,
+		loader: LoaderFor(path),
+	}
+}
+
+// Persistent reports whether changes are written back to disk
+func (s *Store) Persistent() bool {
+	return s.path != ""
+}
+
+// Lookup returns the underlying lookup
+func (s *Store) Lookup() *Lookup {
+	return s.lookup
+}
+
+// Put adds or updates a capcode and persists the change. The lookup only
+// changes once the change is persisted.
+func (s *Store) Put(info CapcodeInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.without(info.Capcode)
+	records = append(records, info)
+	slices.SortFunc(records, func(a, b CapcodeInfo) int { return strings.Compare(a.Capcode, b.Capcode) })
+	if err := s.save(records); err != nil {
+		return err
+	}
+	s.lookup.Set(info)
+	return nil
+}
+
+// Delete removes a capcode and persists the change, reports whether the
+// capcode existed. The lookup only changes once the change is persisted.
+func (s *Store) Delete(capcode string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.lookup.Find(capcode); !ok {
+		return false, nil
+	}
+	if err := s.save(s.without(capcode)); err != nil {
+		return true, err
+	}
+	s.lookup.Delete(capcode)
+	return true, nil
+}
+
+// without returns all records except the one Find returns for capcode
+func (s *Store) without(capcode string) []CapcodeInfo {
+	records := s.lookup.All()
+	if existing, ok := s.lookup.Find(capcode); ok {
+		records = slices.DeleteFunc(records, func(info CapcodeInfo) bool { return info.Capcode == existing.Capcode })
+	}
+	return records
+}
+
+// save writes records to the backing file. Stream formats are written to a
+// temporary file first and renamed so readers never see a partial file; the
+// file keeps its permissions.
+func (s *Store) save(records []CapcodeInfo) error {
+	if s.path == "" {
+		return nil
+	}
+
+	if fl, ok := s.loader.(fileLoader); ok {
+		return fl.SaveFile(s.path, records)
+	}
+
+	writer, ok := s.loader.(Writer)
+	if !ok {
+		return fmt.Errorf("capcode %s cannot be written", s.loader.Name())
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".capcodes-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(s.path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+
+	if err := writer.Write(tmp, records); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write capcode %s: %w", s.loader.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace capcode %s: %w", s.loader.Name(), err)
+	}
+	return nil
+}
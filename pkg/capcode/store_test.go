@@ -0,0 +1,105 @@
+package capcode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PutPersistsCSV(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "capcodes.csv")
+	err := os.WriteFile(csvPath, []byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"), 0644)
+	require.NoError(t, err)
+
+	lookup, err := NewLookup(csvPath)
+	require.NoError(t, err)
+
+	store := NewStore(lookup, csvPath)
+	assert.True(t, store.Persistent())
+
+	err = store.Put(CapcodeInfo{Capcode: "0101002", Agency: "Ambulance", Region: "Utrecht"})
+	require.NoError(t, err)
+	err = store.Put(CapcodeInfo{Capcode: "101001", Agency: "Brandweer", Station: "Noord"})
+	require.NoError(t, err)
+
+	// Reload from disk to verify persistence
+	reloaded, err := NewLookup(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Ambulance", reloaded.Get("0101002").Agency)
+	assert.Equal(t, "Noord", reloaded.Get("0101001").Station)
+	assert.Len(t, reloaded.All(), 2)
+}
+
+func TestStore_DeletePersistsJSON(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "capcodes.json")
+	err := os.WriteFile(jsonPath, []byte(`[{"capcode": "0101001", "agency": "Brandweer"}, {"capcode": "0101002", "agency": "Ambulance"}]`), 0644)
+	require.NoError(t, err)
+
+	lookup, err := NewLookup(jsonPath)
+	require.NoError(t, err)
+
+	store := NewStore(lookup, jsonPath)
+
+	existed, err := store.Delete("101001")
+	require.NoError(t, err)
+	assert.True(t, existed)
+
+	existed, err = store.Delete("0999999")
+	require.NoError(t, err)
+	assert.False(t, existed)
+
+	reloaded, err := NewLookup(jsonPath)
+	require.NoError(t, err)
+	assert.Nil(t, reloaded.Get("0101001"))
+	assert.NotNil(t, reloaded.Get("0101002"))
+}
+
+func TestStore_KeepsFileMode(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "capcodes.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("0101001;Brandweer;Utrecht;Centrum;Kazernealarm\n"), 0o640))
+	require.NoError(t, os.Chmod(csvPath, 0o640))
+	lookup, err := NewLookup(csvPath)
+	require.NoError(t, err)
+
+	require.NoError(t, NewStore(lookup, csvPath).Put(CapcodeInfo{Capcode: "0101002", Agency: "Ambulance"}))
+	st, err := os.Stat(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), st.Mode().Perm())
+}
+
+func TestStore_FailedSaveKeepsLookup(t *testing.T) {
+	lookup := NewLookupFromRecords([]CapcodeInfo{{Capcode: "0101001", Agency: "Brandweer"}})
+	store := NewStore(lookup, filepath.Join(t.TempDir(), "missing", "capcodes.csv"))
+
+	assert.Error(t, store.Put(CapcodeInfo{Capcode: "101001", Agency: "Ambulance"}))
+	assert.Error(t, store.Put(CapcodeInfo{Capcode: "0101002", Agency: "Ambulance"}))
+	existed, err := store.Delete("0101001")
+	assert.Error(t, err)
+	assert.True(t, existed)
+
+	assert.Equal(t, []CapcodeInfo{{Capcode: "0101001", Agency: "Brandweer"}}, lookup.All())
+}
+
+func TestStore_RemoteIsInMemory(t *testing.T) {
+	lookup := NewLookupFromRecords(nil)
+	store := NewStore(lookup, "https://example.com/capcodes.csv")
+	assert.False(t, store.Persistent())
+
+	require.NoError(t, store.Put(CapcodeInfo{Capcode: "0101001", Agency: "Brandweer"}))
+	assert.NotNil(t, lookup.Get("0101001"))
+}
+
+func TestLookup_All(t *testing.T) {
+	lookup := NewLookupFromRecords([]CapcodeInfo{
+		{Capcode: "0101002"},
+		{Capcode: "0101001"},
+	})
+
+	all := lookup.All()
+	require.Len(t, all, 2)
+	assert.Equal(t, "0101001", all[0].Capcode)
+	assert.Equal(t, "0101002", all[1].Capcode)
+}
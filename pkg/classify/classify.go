@@ -0,0 +1,95 @@
+// Package classify derives a normalized incident severity from the Dutch
+// phrasing of P2000 dispatch messages, so routing, tags and metrics need not
+// know every urgency code and fire size of each discipline.
+package classify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Severity is the normalized urgency of an incident
+type Severity string
+
+const (
+	SeverityUnknown  Severity = ""         // No urgency recognized
+	SeverityLow      Severity = "low"      // Planned or non-urgent, such as B and P 3
+	SeverityMedium   Severity = "medium"   // Urgent, such as A2 and P 2
+	SeverityHigh     Severity = "high"     // Life threatening or immediate deployment, such as A1, P 1 and middelbrand
+	SeverityCritical Severity = "critical" // Large incidents, such as grote brand and GRIP
+)
+
+// severityLevels orders the severities, unknown being 0
+var severityLevels = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// Level returns the rank of s from 0 (unknown) to 4 (critical)
+func (s Severity) Level() int {
+	return severityLevels[s]
+}
+
+// Severities lists the known severities from low to critical
+func Severities() []Severity {
+	return []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+}
+
+// Parse returns the severity named s, case-insensitively
+func Parse(s string) (Severity, bool) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := severityLevels[sev]
+	return sev, ok
+}
+
+// Rule assigns a severity to messages matching a pattern
+type Rule struct {
+	Pattern  *regexp.Regexp
+	Severity Severity
+}
+
+// DefaultRules is the curated rule set for Dutch dispatch phrasing. Urgency
+// codes count at the start of the message only, as elsewhere in the text
+// they are usually part of an address or unit number.
+var DefaultRules = []Rule{
+	{regexp.MustCompile(`(?i)\bzeer\s+grote\s+brand\b`), SeverityCritical},
+	{regexp.MustCompile(`(?i)\bgrote\s+brand\b`), SeverityCritical},
+	{regexp.MustCompile(`(?i)\bGRIP\s?:?\s?[1-5]\b`), SeverityCritical},
+	{regexp.MustCompile(`(?i)\bmiddel\s?brand\b`), SeverityHigh},
+	{regexp.MustCompile(`(?i)\bdirecte\s+inzet\b`), SeverityHigh},
+	{regexp.MustCompile(`^\s*A[01]\b`), SeverityHigh},
+	{regexp.MustCompile(`(?i)^\s*(P|PRIO)\s?1\b`), SeverityHigh},
+	{regexp.MustCompile(`^\s*A2\b`), SeverityMedium},
+	{regexp.MustCompile(`(?i)^\s*(P|PRIO)\s?2\b`), SeverityMedium},
+	{regexp.MustCompile(`^\s*B[12]?\b`), SeverityLow},
+	{regexp.MustCompile(`(?i)^\s*(P|PRIO)\s?[3-5]\b`), SeverityLow},
+}
+
+// Classifier assigns severities with a rule set
+type Classifier struct {
+	rules []Rule
+}
+
+// New creates a classifier for rules, DefaultRules when nil
+func New(rules []Rule) *Classifier {
+	if rules == nil {
+		rules = DefaultRules
+	}
+	return &Classifier{rules: rules}
+}
+
+// Default classifies with DefaultRules
+var Default = New(nil)
+
+// Classify returns the highest severity of the rules matching text
+func (c *Classifier) Classify(text string) Severity {
+	severity := SeverityUnknown
+	for _, r := range c.rules {
+		if r.Severity.Level() > severity.Level() && r.Pattern.MatchString(text) {
+			severity = r.Severity
+		}
+	}
+	return severity
+}
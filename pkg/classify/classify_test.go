@@ -0,0 +1,56 @@
+package classify
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Severity
+	}{
+		{"A1 Ambulance Reanimatie Dorpsstraat Utrecht", SeverityHigh},
+		{"A0 Lifeliner inzet Amersfoort", SeverityHigh},
+		{"A2 Ambulance Oudegracht Utrecht", SeverityMedium},
+		{"B1 Besteld vervoer Utrecht", SeverityLow},
+		{"B Ambulance Houten", SeverityLow},
+		{"P 1 BDH-01 Buitenbrand Lange Viestraat Utrecht", SeverityHigh},
+		{"PRIO 1 Brand woning Amsterdam", SeverityHigh},
+		{"Prio 2 Assistentie ambulance", SeverityMedium},
+		{"P 3 Dienstverlening Utrecht", SeverityLow},
+		{"P 2 Middelbrand industrie Nieuwegein", SeverityHigh},
+		{"P 1 Grote brand Rotterdam", SeverityCritical},
+		{"P 1 ZEER GROTE BRAND loods Eindhoven", SeverityCritical},
+		{"P 1 GRIP 2 Brand chemie Moerdijk", SeverityCritical},
+		{"P 3 Directe inzet Brandweer Zeist", SeverityHigh},
+		{"Proefalarm brandweer", SeverityUnknown},
+		{"Bezoek A1 straat", SeverityUnknown},
+		{"Ongeval Rijksweg A2 Utrecht", SeverityUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Default.Classify(tt.text))
+		})
+	}
+}
+
+func TestClassifier_CustomRules(t *testing.T) {
+	c := New([]Rule{{Pattern: regexp.MustCompile(`(?i)\bongeval\b`), Severity: SeverityMedium}})
+	assert.Equal(t, SeverityMedium, c.Classify("Ongeval Rijksweg A2"))
+	assert.Equal(t, SeverityUnknown, c.Classify("A1 Reanimatie"))
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 0, SeverityUnknown.Level())
+	assert.Equal(t, 4, SeverityCritical.Level())
+	assert.Less(t, SeverityMedium.Level(), SeverityHigh.Level())
+
+	s, ok := Parse(" High")
+	assert.True(t, ok)
+	assert.Equal(t, SeverityHigh, s)
+	_, ok = Parse("urgent")
+	assert.False(t, ok)
+}
@@ -0,0 +1,36 @@
+package model_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+func ExampleMessage_Enrich() {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht", Station: "Utrecht Centrum", Function: "TS 4231"},
+	})
+
+	var msg model.Message
+	frame := `{"type": "FLEX", "timestamp": 1760522400, "capcodes": ["0101001"], "message": "P 1 GRIP 1 Brand woning Damstraat Utrecht"}`
+	if err := json.Unmarshal([]byte(frame), &msg); err != nil {
+		panic(err)
+	}
+	msg.Enrich(lookup)
+
+	fmt.Println(msg.Priority, msg.GRIP, msg.Severity)
+	fmt.Println(msg.CapcodeInfo[0].Station)
+	// Output:
+	// P 1 1 critical
+	// Utrecht Centrum
+}
+
+func ExampleParsePriority() {
+	fmt.Println(model.ParsePriority("A1 Ambulance Dorpsstraat Utrecht"))
+	fmt.Println(model.ParsePriority("Proefalarm brandweer") == "")
+	// Output:
+	// A1
+	// true
+}
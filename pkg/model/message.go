@@ -0,0 +1,166 @@
+// Package model contains the domain types shared by message sources,
+// filters and outputs, independent of how messages are received.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+)
+
+var (
+	// priorityPattern matches the urgency code P2000 messages start with,
+	// e.g. "A1", "B2" or "P 1"
+	priorityPattern = regexp.MustCompile(`^\s*(A[0-2]|B[1-2]?|P\s?[1-3])\b`)
+
+	// gripPattern matches a GRIP (coordinated incident response) level
+	gripPattern = regexp.MustCompile(`(?i)\bGRIP\s?:?\s?([1-5])\b`)
+)
+
+// Message is a P2000 message as received from the feed. The enrichment
+// fields are filled in after receiving by Enrich or by sources that provide
+// them directly.
+type Message struct {
+	Type         string   `json:"type"`
+	Timestamp    int64    `json:"timestamp"`
+	Signal       Signal   `json:"signal"`
+	FrequencyErr float64  `json:"frequency_error"`
+	Capcodes     []string `json:"capcodes"`
+	Message      string   `json:"message"`
+	Agency       string   `json:"agency"`
+
+	ID           string                `json:"id,omitempty"`           // Stable ID assigned by the forwarder, see MessageID
+	Priority     string                `json:"priority,omitempty"`     // Urgency code parsed from the text (A1, P 1, ...)
+	GRIP         int                   `json:"grip,omitempty"`         // GRIP level, 0 when not mentioned
+	Severity     classify.Severity     `json:"severity,omitempty"`     // Normalized urgency classified from the text
+	Test         bool                  `json:"test,omitempty"`         // Test page, such as a proefalarm or the monthly siren test
+	Reanimation  bool                  `json:"reanimation,omitempty"`  // Resuscitation call for the ambulance
+	Location     string                `json:"location,omitempty"`     // Incident location from the source or reverse geocoding
+	Municipality string                `json:"municipality,omitempty"` // Municipality of the address verified against the BAG
+	Coordinates  *Coordinates          `json:"coordinates,omitempty"`  // Incident position when geocoded
+	CapcodeInfo  []capcode.CapcodeInfo `json:"capcode_info,omitempty"` // Capcode database entries of known capcodes
+	Translation  string                `json:"translation,omitempty"`  // Machine translation of the text
+	Weather      *Weather              `json:"weather,omitempty"`      // Current weather at the incident, for storm and nature fire calls
+}
+
+// MessageID returns the stable ID of a message: a hash of its type,
+// timestamp, capcodes and text, so every copy of a page received, by any
+// instance or after a reconnect, has the same ID
+func MessageID(m Message) string {
+	capcodes := slices.Clone(m.Capcodes)
+	slices.Sort(capcodes)
+
+	h := sha256.New()
+	h.Write([]byte(m.Type + "\n" + strconv.FormatInt(m.Timestamp, 10) + "\n" + strings.Join(capcodes, ",") + "\n" + m.Message))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DefaultTestText is the text of fabricated test messages
+const DefaultTestText = "P 2 Testmelding p2000-forwarder"
+
+// NewTestMessage fabricates a FLEX message sent at t, for verifying delivery
+// without waiting for a real page. DefaultTestText is used when text is
+// empty.
+func NewTestMessage(text string, capcodes []string, t time.Time) Message {
+	if text == "" {
+		text = DefaultTestText
+	}
+	return Message{
+		Type:      "FLEX",
+		Timestamp: t.Unix(),
+		Capcodes:  capcodes,
+		Message:   text,
+	}
+}
+
+// Coordinates is a WGS84 position
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000
+
+// Distance returns the great-circle distance to o in meters
+func (c Coordinates) Distance(o Coordinates) float64 {
+	lat1, lat2 := c.Lat*math.Pi/180, o.Lat*math.Pi/180
+	dLat, dLon := lat2-lat1, (o.Lon-c.Lon)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// Weather is the current weather at a position
+type Weather struct {
+	Temperature   float64 `json:"temperature"`           // °C
+	WindSpeed     float64 `json:"wind_speed"`            // m/s
+	WindGust      float64 `json:"wind_gust,omitempty"`   // m/s, 0 when not reported
+	WindDirection int     `json:"wind_direction"`        // Degrees the wind blows from
+	Description   string  `json:"description,omitempty"` // e.g. "light rain"
+}
+
+// beaufortLimits are the upper wind speeds in m/s of Beaufort 0 to 11
+var beaufortLimits = [...]float64{0.2, 1.5, 3.3, 5.4, 7.9, 10.7, 13.8, 17.1, 20.7, 24.4, 28.4, 32.6}
+
+// Beaufort returns the wind force on the Beaufort scale
+func (w Weather) Beaufort() int {
+	for force, limit := range beaufortLimits {
+		if w.WindSpeed <= limit {
+			return force
+		}
+	}
+	return 12
+}
+
+// Signal represents the signal information
+type Signal struct {
+	Baudrate int    `json:"baudrate"`
+	Frame    int    `json:"frame"`
+	Subtype  string `json:"subtype"`
+	Function string `json:"function"`
+}
+
+// Enrich parses the priority and GRIP level from the message text,
+// classifies its severity and resolves the capcodes with lookup, which may
+// be nil
+func (m *Message) Enrich(lookup *capcode.Lookup) {
+	m.Priority = ParsePriority(m.Message)
+	m.GRIP = ParseGRIP(m.Message)
+	m.Severity = classify.Default.Classify(m.Message)
+
+	m.CapcodeInfo = nil
+	if lookup == nil {
+		return
+	}
+	for _, code := range m.Capcodes {
+		if info, ok := lookup.Find(code); ok {
+			m.CapcodeInfo = append(m.CapcodeInfo, info)
+		}
+	}
+}
+
+// ParsePriority returns the urgency code a message starts with, or an empty
+// string when it has none
+func ParsePriority(text string) string {
+	if m := priorityPattern.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ParseGRIP returns the GRIP level mentioned in the text, or 0
+func ParseGRIP(text string) int {
+	if m := gripPattern.FindStringSubmatch(text); m != nil {
+		level, _ := strconv.Atoi(m[1])
+		return level
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kaije/p2000-nfty/pkg/capcode"
+	"github.com/kaije/p2000-nfty/pkg/classify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := map[string]string{
+		"A1 Rit 12345":       "A1",
+		"A2 Ambu":            "A2",
+		"B2 Besteld vervoer": "B2",
+		"P 1 BR woning":      "P 1",
+		"P2 Assistentie":     "P2",
+		"Proefalarm":         "",
+		"":                   "",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, ParsePriority(text), text)
+	}
+}
+
+func TestParseGRIP(t *testing.T) {
+	tests := map[string]int{
+		"P 1 GRIP 1 Brand industrie":  1,
+		"P 1 Grip2 Ongeval":           2,
+		"P 1 GRIP: 3 Rotterdam":       3,
+		"P 1 Brand woning":            0,
+		"P 1 Gripvoorraad 4 Leverans": 0,
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, ParseGRIP(text), text)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	lookup := capcode.NewLookupFromRecords([]capcode.CapcodeInfo{
+		{Capcode: "0101001", Agency: "Brandweer", Region: "Utrecht"},
+	})
+
+	msg := Message{
+		Message:  "P 1 GRIP 1 Brand industrie Utrecht",
+		Capcodes: []string{"0101001", "0999999"},
+	}
+	msg.Enrich(lookup)
+
+	assert.Equal(t, "P 1", msg.Priority)
+	assert.Equal(t, 1, msg.GRIP)
+	assert.Equal(t, classify.SeverityCritical, msg.Severity)
+	require.Len(t, msg.CapcodeInfo, 1)
+	assert.Equal(t, "Brandweer", msg.CapcodeInfo[0].Agency)
+
+	msg.Enrich(nil)
+	assert.Empty(t, msg.CapcodeInfo)
+}
+
+func TestMessageID(t *testing.T) {
+	msg := Message{Type: "FLEX", Timestamp: 1760522400, Capcodes: []string{"001180000", "000120901"}, Message: "P 1 Brand woning Utrecht"}
+	id := MessageID(msg)
+	assert.Len(t, id, 64)
+
+	other := msg
+	other.Capcodes = []string{"000120901", "001180000"}
+	other.Priority = "P 1"
+	assert.Equal(t, id, MessageID(other), "enrichment and capcode order do not change the ID")
+	assert.Equal(t, []string{"000120901", "001180000"}, other.Capcodes, "capcodes are not sorted in place")
+
+	repeat := msg
+	repeat.Timestamp++
+	assert.NotEqual(t, id, MessageID(repeat))
+}
+
+func TestNewTestMessage(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+
+	msg := NewTestMessage("", nil, at)
+	assert.Equal(t, "FLEX", msg.Type)
+	assert.Equal(t, DefaultTestText, msg.Message)
+	assert.Equal(t, int64(1700000000), msg.Timestamp)
+
+	msg = NewTestMessage("A1 Proef", []string{"0101001"}, at)
+	assert.Equal(t, "A1 Proef", msg.Message)
+	assert.Equal(t, []string{"0101001"}, msg.Capcodes)
+}
+
+func TestMessage_DecodeFeed(t *testing.T) {
+	data := `{"type":"FLEX","timestamp":1700000000,"signal":{"baudrate":1600,"frame":1,"subtype":"ALN","function":"3"},"frequency_error":0.5,"capcodes":["0101001"],"message":"P 1 Test","agency":"Brandweer"}`
+
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(data), &msg))
+	assert.Equal(t, "FLEX", msg.Type)
+	assert.Equal(t, 1600, msg.Signal.Baudrate)
+	assert.Equal(t, []string{"0101001"}, msg.Capcodes)
+	assert.Empty(t, msg.Priority, "enrichment fields are not part of the feed")
+}
+
+func TestWeather_Beaufort(t *testing.T) {
+	tests := map[float64]int{
+		0:    0,
+		1.5:  1,
+		5.5:  4,
+		10.7: 5,
+		24.5: 10,
+		33:   12,
+	}
+
+	for speed, expected := range tests {
+		assert.Equal(t, expected, Weather{WindSpeed: speed}.Beaufort(), speed)
+	}
+}
+
+func TestCoordinates_Distance(t *testing.T) {
+	dom := Coordinates{Lat: 52.0907, Lon: 5.1214}
+	dam := Coordinates{Lat: 52.3731, Lon: 4.8926}
+
+	assert.InDelta(t, 35055, dom.Distance(dam), 1)
+	assert.InDelta(t, dom.Distance(dam), dam.Distance(dom), 0.001)
+	assert.Zero(t, dom.Distance(dom))
+}
@@ -0,0 +1,22 @@
+package notify_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+)
+
+func ExampleSenderFunc() {
+	var sender notify.Sender = notify.SenderFunc("stdout", func(ctx context.Context, msg model.Message) error {
+		_, err := fmt.Println(msg.Capcodes, msg.Message)
+		return err
+	})
+
+	msg := model.Message{Capcodes: []string{"0101001"}, Message: "P 1 Brand woning Utrecht"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		fmt.Println(sender.Name(), "failed:", err)
+	}
+	// Output: [0101001] P 1 Brand woning Utrecht
+}
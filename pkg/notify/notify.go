@@ -0,0 +1,44 @@
+// Package notify defines the interface notification destinations implement,
+// so programs embedding P2000 ingestion can deliver messages to their own
+// outputs and the forwarder's destinations alike.
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+)
+
+// ErrSkipped is returned by Send when the destination deliberately did not
+// deliver the message, e.g. a call destination below its priority. Callers
+// treat the message as not delivered, but not as a failed delivery.
+var ErrSkipped = errors.New("notification skipped")
+
+// Sender delivers P2000 messages to a single destination
+type Sender interface {
+	// Name uniquely identifies the destination
+	Name() string
+	Send(ctx context.Context, msg model.Message) error
+}
+
+// SenderFunc adapts a function to a Sender named name
+func SenderFunc(name string, send func(ctx context.Context, msg model.Message) error) Sender {
+	return senderFunc{name: name, send: send}
+}
+
+// senderFunc is the Sender returned by SenderFunc
+type senderFunc struct {
+	name string
+	send func(ctx context.Context, msg model.Message) error
+}
+
+// Name returns the name given to SenderFunc
+func (s senderFunc) Name() string {
+	return s.name
+}
+
+// Send calls the function given to SenderFunc
+func (s senderFunc) Send(ctx context.Context, msg model.Message) error {
+	return s.send(ctx, msg)
+}
@@ -0,0 +1,4 @@
+// Package p2000v1 is the generated client and server code of the gRPC API of
+// the forwarder, defined in proto/p2000/v1/p2000.proto. Every call needs the
+// admin API token as bearer token in the "authorization" metadata.
+package p2000v1
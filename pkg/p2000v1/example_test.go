@@ -0,0 +1,39 @@
+package p2000v1_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kaije/p2000-nfty/pkg/p2000v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+func ExampleP2000ServiceClient_Subscribe() {
+	conn, err := grpc.NewClient("localhost:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+os.Getenv("P2000_API_TOKEN"))
+	stream, err := p2000v1.NewP2000ServiceClient(conn).Subscribe(ctx, &p2000v1.SubscribeRequest{
+		Capcodes: []string{"0101001"},
+		When:     `grip >= 1 or regions contains "Utrecht"`,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		fmt.Println(resp.Message.Priority, resp.Message.Text)
+	}
+}
@@ -0,0 +1,858 @@
+// gRPC API of the P2000 forwarder, for integrators who want typed contracts
+// over the JSON admin API. Regenerate the Go code in pkg/p2000v1 with
+// `make proto` after changing this file.
+//
+// Every call needs the admin API token as bearer token in the
+// "authorization" metadata: "Bearer <api.token>".
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: p2000/v1/p2000.proto
+
+package p2000v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only messages for any of these capcodes, all messages when empty.
+	// Leading zeros are ignored.
+	Capcodes []string `protobuf:"bytes,1,rep,name=capcodes,proto3" json:"capcodes,omitempty"`
+	// Only messages matching this routing rule condition, e.g.
+	// `grip >= 1 or regions contains "Utrecht"`
+	When string `protobuf:"bytes,2,opt,name=when,proto3" json:"when,omitempty"`
+	// Also messages the filters did not forward
+	IncludeFiltered bool `protobuf:"varint,3,opt,name=include_filtered,json=includeFiltered,proto3" json:"include_filtered,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubscribeRequest) GetCapcodes() []string {
+	if x != nil {
+		return x.Capcodes
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetWhen() string {
+	if x != nil {
+		return x.When
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetIncludeFiltered() bool {
+	if x != nil {
+		return x.IncludeFiltered
+	}
+	return false
+}
+
+type SubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeResponse) Reset() {
+	*x = SubscribeResponse{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeResponse) ProtoMessage() {}
+
+func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubscribeResponse) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type ListMessagesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of messages, 100 when 0
+	Limit         int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesRequest) Reset() {
+	*x = ListMessagesRequest{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesRequest) ProtoMessage() {}
+
+func (x *ListMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesRequest.ProtoReflect.Descriptor instead.
+func (*ListMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListMessagesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesResponse) Reset() {
+	*x = ListMessagesResponse{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesResponse) ProtoMessage() {}
+
+func (x *ListMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesResponse.ProtoReflect.Descriptor instead.
+func (*ListMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListMessagesResponse) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type GetMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMessageRequest) Reset() {
+	*x = GetMessageRequest{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMessageRequest) ProtoMessage() {}
+
+func (x *GetMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMessageRequest.ProtoReflect.Descriptor instead.
+func (*GetMessageRequest) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetMessageRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMessageResponse) Reset() {
+	*x = GetMessageResponse{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMessageResponse) ProtoMessage() {}
+
+func (x *GetMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMessageResponse.ProtoReflect.Descriptor instead.
+func (*GetMessageResponse) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetMessageResponse) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type LookupCapcodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capcode       string                 `protobuf:"bytes,1,opt,name=capcode,proto3" json:"capcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupCapcodeRequest) Reset() {
+	*x = LookupCapcodeRequest{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupCapcodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupCapcodeRequest) ProtoMessage() {}
+
+func (x *LookupCapcodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupCapcodeRequest.ProtoReflect.Descriptor instead.
+func (*LookupCapcodeRequest) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LookupCapcodeRequest) GetCapcode() string {
+	if x != nil {
+		return x.Capcode
+	}
+	return ""
+}
+
+type LookupCapcodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capcode       *Capcode               `protobuf:"bytes,1,opt,name=capcode,proto3" json:"capcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupCapcodeResponse) Reset() {
+	*x = LookupCapcodeResponse{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupCapcodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupCapcodeResponse) ProtoMessage() {}
+
+func (x *LookupCapcodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupCapcodeResponse.ProtoReflect.Descriptor instead.
+func (*LookupCapcodeResponse) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LookupCapcodeResponse) GetCapcode() *Capcode {
+	if x != nil {
+		return x.Capcode
+	}
+	return nil
+}
+
+// Message is an enriched P2000 message as stored in the history
+type Message struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Stable ID shared by every copy of the page
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ReceivedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	// Send time from the feed, unset when unknown
+	SentAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	// FLEX or POCSAG
+	Type     string   `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Capcodes []string `protobuf:"bytes,5,rep,name=capcodes,proto3" json:"capcodes,omitempty"`
+	Text     string   `protobuf:"bytes,6,opt,name=text,proto3" json:"text,omitempty"`
+	Agency   string   `protobuf:"bytes,7,opt,name=agency,proto3" json:"agency,omitempty"`
+	// Urgency code parsed from the text, e.g. A1 or P 1
+	Priority string `protobuf:"bytes,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	// GRIP level, 0 when not mentioned
+	Grip int32 `protobuf:"varint,9,opt,name=grip,proto3" json:"grip,omitempty"`
+	// Normalized urgency: low, medium, high or critical, empty when unknown
+	Severity     string `protobuf:"bytes,10,opt,name=severity,proto3" json:"severity,omitempty"`
+	Test         bool   `protobuf:"varint,11,opt,name=test,proto3" json:"test,omitempty"`
+	Reanimation  bool   `protobuf:"varint,12,opt,name=reanimation,proto3" json:"reanimation,omitempty"`
+	Location     string `protobuf:"bytes,13,opt,name=location,proto3" json:"location,omitempty"`
+	Municipality string `protobuf:"bytes,14,opt,name=municipality,proto3" json:"municipality,omitempty"`
+	// Set when the incident was geocoded
+	Coordinates *Coordinates `protobuf:"bytes,15,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
+	// Capcode database entries of the known capcodes
+	CapcodeInfo []*Capcode `protobuf:"bytes,16,rep,name=capcode_info,json=capcodeInfo,proto3" json:"capcode_info,omitempty"`
+	// Whether the filters and rules forwarded the message
+	Forwarded bool `protobuf:"varint,17,opt,name=forwarded,proto3" json:"forwarded,omitempty"`
+	// Incident thread shared by follow-up pages
+	Thread        string `protobuf:"bytes,18,opt,name=thread,proto3" json:"thread,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+func (x *Message) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetCapcodes() []string {
+	if x != nil {
+		return x.Capcodes
+	}
+	return nil
+}
+
+func (x *Message) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Message) GetAgency() string {
+	if x != nil {
+		return x.Agency
+	}
+	return ""
+}
+
+func (x *Message) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *Message) GetGrip() int32 {
+	if x != nil {
+		return x.Grip
+	}
+	return 0
+}
+
+func (x *Message) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Message) GetTest() bool {
+	if x != nil {
+		return x.Test
+	}
+	return false
+}
+
+func (x *Message) GetReanimation() bool {
+	if x != nil {
+		return x.Reanimation
+	}
+	return false
+}
+
+func (x *Message) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Message) GetMunicipality() string {
+	if x != nil {
+		return x.Municipality
+	}
+	return ""
+}
+
+func (x *Message) GetCoordinates() *Coordinates {
+	if x != nil {
+		return x.Coordinates
+	}
+	return nil
+}
+
+func (x *Message) GetCapcodeInfo() []*Capcode {
+	if x != nil {
+		return x.CapcodeInfo
+	}
+	return nil
+}
+
+func (x *Message) GetForwarded() bool {
+	if x != nil {
+		return x.Forwarded
+	}
+	return false
+}
+
+func (x *Message) GetThread() string {
+	if x != nil {
+		return x.Thread
+	}
+	return ""
+}
+
+type Coordinates struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lat           float64                `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon           float64                `protobuf:"fixed64,2,opt,name=lon,proto3" json:"lon,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Coordinates) Reset() {
+	*x = Coordinates{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Coordinates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Coordinates) ProtoMessage() {}
+
+func (x *Coordinates) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Coordinates.ProtoReflect.Descriptor instead.
+func (*Coordinates) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Coordinates) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *Coordinates) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+type Capcode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capcode       string                 `protobuf:"bytes,1,opt,name=capcode,proto3" json:"capcode,omitempty"`
+	Agency        string                 `protobuf:"bytes,2,opt,name=agency,proto3" json:"agency,omitempty"`
+	Region        string                 `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty"`
+	Station       string                 `protobuf:"bytes,4,opt,name=station,proto3" json:"station,omitempty"`
+	Function      string                 `protobuf:"bytes,5,opt,name=function,proto3" json:"function,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Capcode) Reset() {
+	*x = Capcode{}
+	mi := &file_p2000_v1_p2000_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Capcode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capcode) ProtoMessage() {}
+
+func (x *Capcode) ProtoReflect() protoreflect.Message {
+	mi := &file_p2000_v1_p2000_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capcode.ProtoReflect.Descriptor instead.
+func (*Capcode) Descriptor() ([]byte, []int) {
+	return file_p2000_v1_p2000_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Capcode) GetCapcode() string {
+	if x != nil {
+		return x.Capcode
+	}
+	return ""
+}
+
+func (x *Capcode) GetAgency() string {
+	if x != nil {
+		return x.Agency
+	}
+	return ""
+}
+
+func (x *Capcode) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Capcode) GetStation() string {
+	if x != nil {
+		return x.Station
+	}
+	return ""
+}
+
+func (x *Capcode) GetFunction() string {
+	if x != nil {
+		return x.Function
+	}
+	return ""
+}
+
+var File_p2000_v1_p2000_proto protoreflect.FileDescriptor
+
+const file_p2000_v1_p2000_proto_rawDesc = "" +
+	"\n" +
+	"\x14p2000/v1/p2000.proto\x12\bp2000.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"m\n" +
+	"\x10SubscribeRequest\x12\x1a\n" +
+	"\bcapcodes\x18\x01 \x03(\tR\bcapcodes\x12\x12\n" +
+	"\x04when\x18\x02 \x01(\tR\x04when\x12)\n" +
+	"\x10include_filtered\x18\x03 \x01(\bR\x0fincludeFiltered\"@\n" +
+	"\x11SubscribeResponse\x12+\n" +
+	"\amessage\x18\x01 \x01(\v2\x11.p2000.v1.MessageR\amessage\"+\n" +
+	"\x13ListMessagesRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"E\n" +
+	"\x14ListMessagesResponse\x12-\n" +
+	"\bmessages\x18\x01 \x03(\v2\x11.p2000.v1.MessageR\bmessages\"#\n" +
+	"\x11GetMessageRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"A\n" +
+	"\x12GetMessageResponse\x12+\n" +
+	"\amessage\x18\x01 \x01(\v2\x11.p2000.v1.MessageR\amessage\"0\n" +
+	"\x14LookupCapcodeRequest\x12\x18\n" +
+	"\acapcode\x18\x01 \x01(\tR\acapcode\"D\n" +
+	"\x15LookupCapcodeResponse\x12+\n" +
+	"\acapcode\x18\x01 \x01(\v2\x11.p2000.v1.CapcodeR\acapcode\"\xce\x04\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12;\n" +
+	"\vreceived_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\x123\n" +
+	"\asent_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x1a\n" +
+	"\bcapcodes\x18\x05 \x03(\tR\bcapcodes\x12\x12\n" +
+	"\x04text\x18\x06 \x01(\tR\x04text\x12\x16\n" +
+	"\x06agency\x18\a \x01(\tR\x06agency\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\tR\bpriority\x12\x12\n" +
+	"\x04grip\x18\t \x01(\x05R\x04grip\x12\x1a\n" +
+	"\bseverity\x18\n" +
+	" \x01(\tR\bseverity\x12\x12\n" +
+	"\x04test\x18\v \x01(\bR\x04test\x12 \n" +
+	"\vreanimation\x18\f \x01(\bR\vreanimation\x12\x1a\n" +
+	"\blocation\x18\r \x01(\tR\blocation\x12\"\n" +
+	"\fmunicipality\x18\x0e \x01(\tR\fmunicipality\x127\n" +
+	"\vcoordinates\x18\x0f \x01(\v2\x15.p2000.v1.CoordinatesR\vcoordinates\x124\n" +
+	"\fcapcode_info\x18\x10 \x03(\v2\x11.p2000.v1.CapcodeR\vcapcodeInfo\x12\x1c\n" +
+	"\tforwarded\x18\x11 \x01(\bR\tforwarded\x12\x16\n" +
+	"\x06thread\x18\x12 \x01(\tR\x06thread\"1\n" +
+	"\vCoordinates\x12\x10\n" +
+	"\x03lat\x18\x01 \x01(\x01R\x03lat\x12\x10\n" +
+	"\x03lon\x18\x02 \x01(\x01R\x03lon\"\x89\x01\n" +
+	"\aCapcode\x12\x18\n" +
+	"\acapcode\x18\x01 \x01(\tR\acapcode\x12\x16\n" +
+	"\x06agency\x18\x02 \x01(\tR\x06agency\x12\x16\n" +
+	"\x06region\x18\x03 \x01(\tR\x06region\x12\x18\n" +
+	"\astation\x18\x04 \x01(\tR\astation\x12\x1a\n" +
+	"\bfunction\x18\x05 \x01(\tR\bfunction2\xc0\x02\n" +
+	"\fP2000Service\x12F\n" +
+	"\tSubscribe\x12\x1a.p2000.v1.SubscribeRequest\x1a\x1b.p2000.v1.SubscribeResponse0\x01\x12M\n" +
+	"\fListMessages\x12\x1d.p2000.v1.ListMessagesRequest\x1a\x1e.p2000.v1.ListMessagesResponse\x12G\n" +
+	"\n" +
+	"GetMessage\x12\x1b.p2000.v1.GetMessageRequest\x1a\x1c.p2000.v1.GetMessageResponse\x12P\n" +
+	"\rLookupCapcode\x12\x1e.p2000.v1.LookupCapcodeRequest\x1a\x1f.p2000.v1.LookupCapcodeResponseB1Z/github.com/kaije/p2000-nfty/pkg/p2000v1;p2000v1b\x06proto3"
+
+var (
+	file_p2000_v1_p2000_proto_rawDescOnce sync.Once
+	file_p2000_v1_p2000_proto_rawDescData []byte
+)
+
+func file_p2000_v1_p2000_proto_rawDescGZIP() []byte {
+	file_p2000_v1_p2000_proto_rawDescOnce.Do(func() {
+		file_p2000_v1_p2000_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_p2000_v1_p2000_proto_rawDesc), len(file_p2000_v1_p2000_proto_rawDesc)))
+	})
+	return file_p2000_v1_p2000_proto_rawDescData
+}
+
+var file_p2000_v1_p2000_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_p2000_v1_p2000_proto_goTypes = []any{
+	(*SubscribeRequest)(nil),      // 0: p2000.v1.SubscribeRequest
+	(*SubscribeResponse)(nil),     // 1: p2000.v1.SubscribeResponse
+	(*ListMessagesRequest)(nil),   // 2: p2000.v1.ListMessagesRequest
+	(*ListMessagesResponse)(nil),  // 3: p2000.v1.ListMessagesResponse
+	(*GetMessageRequest)(nil),     // 4: p2000.v1.GetMessageRequest
+	(*GetMessageResponse)(nil),    // 5: p2000.v1.GetMessageResponse
+	(*LookupCapcodeRequest)(nil),  // 6: p2000.v1.LookupCapcodeRequest
+	(*LookupCapcodeResponse)(nil), // 7: p2000.v1.LookupCapcodeResponse
+	(*Message)(nil),               // 8: p2000.v1.Message
+	(*Coordinates)(nil),           // 9: p2000.v1.Coordinates
+	(*Capcode)(nil),               // 10: p2000.v1.Capcode
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_p2000_v1_p2000_proto_depIdxs = []int32{
+	8,  // 0: p2000.v1.SubscribeResponse.message:type_name -> p2000.v1.Message
+	8,  // 1: p2000.v1.ListMessagesResponse.messages:type_name -> p2000.v1.Message
+	8,  // 2: p2000.v1.GetMessageResponse.message:type_name -> p2000.v1.Message
+	10, // 3: p2000.v1.LookupCapcodeResponse.capcode:type_name -> p2000.v1.Capcode
+	11, // 4: p2000.v1.Message.received_at:type_name -> google.protobuf.Timestamp
+	11, // 5: p2000.v1.Message.sent_at:type_name -> google.protobuf.Timestamp
+	9,  // 6: p2000.v1.Message.coordinates:type_name -> p2000.v1.Coordinates
+	10, // 7: p2000.v1.Message.capcode_info:type_name -> p2000.v1.Capcode
+	0,  // 8: p2000.v1.P2000Service.Subscribe:input_type -> p2000.v1.SubscribeRequest
+	2,  // 9: p2000.v1.P2000Service.ListMessages:input_type -> p2000.v1.ListMessagesRequest
+	4,  // 10: p2000.v1.P2000Service.GetMessage:input_type -> p2000.v1.GetMessageRequest
+	6,  // 11: p2000.v1.P2000Service.LookupCapcode:input_type -> p2000.v1.LookupCapcodeRequest
+	1,  // 12: p2000.v1.P2000Service.Subscribe:output_type -> p2000.v1.SubscribeResponse
+	3,  // 13: p2000.v1.P2000Service.ListMessages:output_type -> p2000.v1.ListMessagesResponse
+	5,  // 14: p2000.v1.P2000Service.GetMessage:output_type -> p2000.v1.GetMessageResponse
+	7,  // 15: p2000.v1.P2000Service.LookupCapcode:output_type -> p2000.v1.LookupCapcodeResponse
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_p2000_v1_p2000_proto_init() }
+func file_p2000_v1_p2000_proto_init() {
+	if File_p2000_v1_p2000_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_p2000_v1_p2000_proto_rawDesc), len(file_p2000_v1_p2000_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_p2000_v1_p2000_proto_goTypes,
+		DependencyIndexes: file_p2000_v1_p2000_proto_depIdxs,
+		MessageInfos:      file_p2000_v1_p2000_proto_msgTypes,
+	}.Build()
+	File_p2000_v1_p2000_proto = out.File
+	file_p2000_v1_p2000_proto_goTypes = nil
+	file_p2000_v1_p2000_proto_depIdxs = nil
+}
@@ -0,0 +1,264 @@
+// gRPC API of the P2000 forwarder, for integrators who want typed contracts
+// over the JSON admin API. Regenerate the Go code in pkg/p2000v1 with
+// `make proto` after changing this file.
+//
+// Every call needs the admin API token as bearer token in the
+// "authorization" metadata: "Bearer <api.token>".
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: p2000/v1/p2000.proto
+
+package p2000v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	P2000Service_Subscribe_FullMethodName     = "/p2000.v1.P2000Service/Subscribe"
+	P2000Service_ListMessages_FullMethodName  = "/p2000.v1.P2000Service/ListMessages"
+	P2000Service_GetMessage_FullMethodName    = "/p2000.v1.P2000Service/GetMessage"
+	P2000Service_LookupCapcode_FullMethodName = "/p2000.v1.P2000Service/LookupCapcode"
+)
+
+// P2000ServiceClient is the client API for P2000Service service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// P2000Service streams processed messages and serves the message history
+// and the capcode database
+type P2000ServiceClient interface {
+	// Subscribe streams the messages matching the request as they are
+	// processed, until the client cancels. A client that cannot keep up loses
+	// messages rather than delaying the others.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeResponse], error)
+	// ListMessages returns the latest messages of the history, newest first
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	// GetMessage returns a message of the history by ID
+	GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*GetMessageResponse, error)
+	// LookupCapcode returns the capcode database entry of a capcode
+	LookupCapcode(ctx context.Context, in *LookupCapcodeRequest, opts ...grpc.CallOption) (*LookupCapcodeResponse, error)
+}
+
+type p2000ServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewP2000ServiceClient(cc grpc.ClientConnInterface) P2000ServiceClient {
+	return &p2000ServiceClient{cc}
+}
+
+func (c *p2000ServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &P2000Service_ServiceDesc.Streams[0], P2000Service_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, SubscribeResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2000Service_SubscribeClient = grpc.ServerStreamingClient[SubscribeResponse]
+
+func (c *p2000ServiceClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, P2000Service_ListMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2000ServiceClient) GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*GetMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMessageResponse)
+	err := c.cc.Invoke(ctx, P2000Service_GetMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2000ServiceClient) LookupCapcode(ctx context.Context, in *LookupCapcodeRequest, opts ...grpc.CallOption) (*LookupCapcodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupCapcodeResponse)
+	err := c.cc.Invoke(ctx, P2000Service_LookupCapcode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// P2000ServiceServer is the server API for P2000Service service.
+// All implementations must embed UnimplementedP2000ServiceServer
+// for forward compatibility.
+//
+// P2000Service streams processed messages and serves the message history
+// and the capcode database
+type P2000ServiceServer interface {
+	// Subscribe streams the messages matching the request as they are
+	// processed, until the client cancels. A client that cannot keep up loses
+	// messages rather than delaying the others.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[SubscribeResponse]) error
+	// ListMessages returns the latest messages of the history, newest first
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	// GetMessage returns a message of the history by ID
+	GetMessage(context.Context, *GetMessageRequest) (*GetMessageResponse, error)
+	// LookupCapcode returns the capcode database entry of a capcode
+	LookupCapcode(context.Context, *LookupCapcodeRequest) (*LookupCapcodeResponse, error)
+	mustEmbedUnimplementedP2000ServiceServer()
+}
+
+// UnimplementedP2000ServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedP2000ServiceServer struct{}
+
+func (UnimplementedP2000ServiceServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[SubscribeResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedP2000ServiceServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedP2000ServiceServer) GetMessage(context.Context, *GetMessageRequest) (*GetMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessage not implemented")
+}
+func (UnimplementedP2000ServiceServer) LookupCapcode(context.Context, *LookupCapcodeRequest) (*LookupCapcodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupCapcode not implemented")
+}
+func (UnimplementedP2000ServiceServer) mustEmbedUnimplementedP2000ServiceServer() {}
+func (UnimplementedP2000ServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeP2000ServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to P2000ServiceServer will
+// result in compilation errors.
+type UnsafeP2000ServiceServer interface {
+	mustEmbedUnimplementedP2000ServiceServer()
+}
+
+func RegisterP2000ServiceServer(s grpc.ServiceRegistrar, srv P2000ServiceServer) {
+	// If the following call pancis, it indicates UnimplementedP2000ServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&P2000Service_ServiceDesc, srv)
+}
+
+func _P2000Service_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(P2000ServiceServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, SubscribeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2000Service_SubscribeServer = grpc.ServerStreamingServer[SubscribeResponse]
+
+func _P2000Service_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2000ServiceServer).ListMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2000Service_ListMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2000ServiceServer).ListMessages(ctx, req.(*ListMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2000Service_GetMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2000ServiceServer).GetMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2000Service_GetMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2000ServiceServer).GetMessage(ctx, req.(*GetMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2000Service_LookupCapcode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupCapcodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2000ServiceServer).LookupCapcode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2000Service_LookupCapcode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2000ServiceServer).LookupCapcode(ctx, req.(*LookupCapcodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// P2000Service_ServiceDesc is the grpc.ServiceDesc for P2000Service service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var P2000Service_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "p2000.v1.P2000Service",
+	HandlerType: (*P2000ServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListMessages",
+			Handler:    _P2000Service_ListMessages_Handler,
+		},
+		{
+			MethodName: "GetMessage",
+			Handler:    _P2000Service_GetMessage_Handler,
+		},
+		{
+			MethodName: "LookupCapcode",
+			Handler:    _P2000Service_LookupCapcode_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _P2000Service_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "p2000/v1/p2000.proto",
+}
@@ -0,0 +1,35 @@
+// Package plugin defines what custom filters and notifiers export to be
+// loaded by the forwarder. A plugin is a main package built with
+// go build -buildmode=plugin against the same version of this module as the
+// forwarder, exporting NewFilter or NewNotifier:
+//
+//	func NewFilter(config map[string]string) (plugin.Filter, error)
+//	func NewNotifier(config map[string]string) (notify.Sender, error)
+//
+// config holds the settings of the plugin from the forwarder configuration.
+package plugin
+
+import (
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/notify"
+)
+
+// Names of the functions plugins export
+const (
+	FilterSymbol   = "NewFilter"
+	NotifierSymbol = "NewNotifier"
+)
+
+// Filter decides whether a message is notified. It is called for every
+// message passing the built-in filters and may be called concurrently, e.g.
+// by the explain endpoint.
+type Filter interface {
+	Allow(msg model.Message) bool
+}
+
+// NewFilterFunc is the signature of NewFilter
+type NewFilterFunc = func(config map[string]string) (Filter, error)
+
+// NewNotifierFunc is the signature of NewNotifier. The sender may be called
+// concurrently by the notification workers.
+type NewNotifierFunc = func(config map[string]string) (notify.Sender, error)
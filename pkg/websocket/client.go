@@ -0,0 +1,358 @@
+// Package websocket receives P2000 messages from the public websocket feed,
+// reconnecting with backoff when the connection drops. It can be used on
+// its own to embed P2000 ingestion in other programs.
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	wsURL             = "wss://p2000.riekeltbrands.nl/websocket"
+	initialBackoff    = 1 * time.Second
+	maxBackoff        = 30 * time.Second
+	backoffMultiplier = 2
+	pingInterval      = 30 * time.Second
+	pongTimeout       = 10 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+// ErrMaxReconnects is returned by Connect when the maximum number of
+// reconnect attempts in a row failed
+var ErrMaxReconnects = errors.New("maximum reconnect attempts reached")
+
+// Metrics receives the connection statistics of a client
+type Metrics interface {
+	RecordWebsocketDial(failed bool)
+	RecordWebsocketReadError()
+	RecordWebsocketParseFailure()
+	RecordWebsocketIdleTimeout()
+	SetWebsocketBackoff(seconds float64)
+}
+
+// Client handles WebSocket connection with automatic reconnection
+type Client struct {
+	conn       *connection // The current connection, nil between connections
+	connMu     sync.Mutex
+	dialer     *websocket.Dialer
+	header     http.Header // Extra headers sent with the dial
+	logger     zerolog.Logger
+	msgHandler func(model.Message)
+	onFrame    func([]byte)
+	onInvalid  func([]byte, error)
+	metrics    Metrics
+	statusChan chan bool // true = connected, false = disconnected
+	done       chan struct{}
+	backoff    time.Duration
+	jitter     float64       // Random fraction of the backoff added or removed
+	maxRetries int           // Failed attempts in a row before giving up, 0 is unlimited
+	failures   int           // Failed attempts since the last connection
+	idle       time.Duration // Reconnect after this long without data, 0 disables
+}
+
+// NewClient creates a new WebSocket client
+func NewClient(logger zerolog.Logger, msgHandler func(model.Message)) *Client {
+	dialer := *websocket.DefaultDialer
+	return &Client{
+		dialer:     &dialer,
+		logger:     logger,
+		msgHandler: msgHandler,
+		statusChan: make(chan bool, 1),
+		done:       make(chan struct{}),
+		backoff:    initialBackoff,
+	}
+}
+
+// Connect establishes WebSocket connection with retry logic
+func (c *Client) Connect(ctx context.Context) error {
+	c.logger.Info().Msg("starting websocket client")
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info().Msg("websocket client shutting down")
+			return ctx.Err()
+		default:
+			if err := c.connectAndListen(ctx); err != nil {
+				c.notifyStatus(false)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				c.failures++
+				if c.maxRetries > 0 && c.failures >= c.maxRetries {
+					c.logger.Error().Err(err).
+						Int("attempts", c.failures).
+						Msg("connection failed, giving up")
+					return fmt.Errorf("%w: %w", ErrMaxReconnects, err)
+				}
+
+				wait := c.jittered(c.backoff)
+				c.logger.Error().Err(err).
+					Dur("backoff", wait).
+					Int("attempts", c.failures).
+					Msg("connection failed, retrying")
+				if c.metrics != nil {
+					c.metrics.SetWebsocketBackoff(wait.Seconds())
+				}
+
+				select {
+				case <-time.After(wait):
+					c.increaseBackoff()
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+// connectAndListen establishes connection and processes messages
+func (c *Client) connectAndListen(ctx context.Context) error {
+	c.logger.Info().Str("url", wsURL).Msg("connecting to websocket")
+
+	ws, _, err := c.dialer.DialContext(ctx, wsURL, c.header)
+	if c.metrics != nil {
+		c.metrics.RecordWebsocketDial(err != nil)
+	}
+	if err != nil {
+		return fmt.Errorf("dial failed: %w", err)
+	}
+
+	conn := newConnection(ws, c.idle)
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+	defer c.release(conn)
+
+	c.resetBackoff()
+	c.failures = 0
+	if c.metrics != nil {
+		c.metrics.SetWebsocketBackoff(0)
+	}
+	c.notifyStatus(true)
+	c.logger.Info().Msg("websocket connection established")
+
+	// Set initial read deadline
+	readDeadline := pingInterval + pongTimeout
+	ws.SetReadDeadline(time.Now().Add(readDeadline))
+
+	// Setup ping/pong handlers
+	ws.SetPongHandler(func(string) error {
+		ws.SetReadDeadline(time.Now().Add(readDeadline))
+		return nil
+	})
+
+	conn.keepalive(pingInterval, func(err error) {
+		c.logger.Error().Err(err).Msg("failed to send ping")
+	})
+
+	// Unblock the read below on shutdown
+	stop := context.AfterFunc(ctx, func() { conn.close(true) })
+	defer stop()
+
+	// Read messages into one buffer reused for every frame
+	var frame bytes.Buffer
+	for {
+		_, r, err := ws.NextReader()
+		if err == nil {
+			frame.Reset()
+			_, err = frame.ReadFrom(r)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if conn.timedOut() {
+				if c.metrics != nil {
+					c.metrics.RecordWebsocketIdleTimeout()
+				}
+				return fmt.Errorf("no data received for %s", c.idle)
+			}
+			if c.metrics != nil {
+				c.metrics.RecordWebsocketReadError()
+			}
+			return fmt.Errorf("read failed: %w", err)
+		}
+
+		// Extend read deadline after successful read
+		ws.SetReadDeadline(time.Now().Add(readDeadline))
+		conn.touch()
+		c.handleMessage(frame.Bytes())
+	}
+}
+
+// release closes conn, waits for its keepalive to exit and forgets it
+func (c *Client) release(conn *connection) {
+	conn.close(true)
+	conn.wait()
+
+	c.connMu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.connMu.Unlock()
+}
+
+// OnFrame registers a hook that receives every raw frame before it is
+// parsed, including frames that are not valid messages. The frame is only
+// valid during the call, hooks must copy what they keep. It must be set
+// before Connect is called.
+func (c *Client) OnFrame(hook func(data []byte)) {
+	c.onFrame = hook
+}
+
+// OnInvalidFrame registers a hook that receives the frames that are not
+// valid messages with the parse error, e.g. to archive them or forward them
+// to a debug topic. Such frames are then logged at debug instead of error
+// level. As with OnFrame, data is only valid during the call. It must be set
+// before Connect is called.
+func (c *Client) OnInvalidFrame(hook func(data []byte, err error)) {
+	c.onInvalid = hook
+}
+
+// SetMetrics records connection attempts, failures, read errors, parse
+// failures and the current backoff in m. It must be set before Connect is
+// called.
+func (c *Client) SetMetrics(m Metrics) {
+	c.metrics = m
+}
+
+// SetJitter randomizes each reconnect backoff by up to fraction (0-1) of
+// its length, so many clients do not reconnect in lockstep. It must be set
+// before Connect is called.
+func (c *Client) SetJitter(fraction float64) {
+	c.jitter = fraction
+}
+
+// SetMaxReconnectAttempts makes Connect return ErrMaxReconnects after n
+// failed connection attempts in a row; 0 retries forever. It must be set
+// before Connect is called.
+func (c *Client) SetMaxReconnectAttempts(n int) {
+	c.maxRetries = n
+}
+
+// SetIdleTimeout makes the client reconnect when no data arrived for d,
+// even though the feed still answers pings; 0 disables the watchdog. It
+// must be set before Connect is called.
+func (c *Client) SetIdleTimeout(d time.Duration) {
+	c.idle = d
+}
+
+// handleMessage processes incoming WebSocket messages
+func (c *Client) handleMessage(data []byte) {
+	if c.onFrame != nil {
+		c.onFrame(data)
+	}
+
+	var msg model.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		if c.metrics != nil {
+			c.metrics.RecordWebsocketParseFailure()
+		}
+		event := c.logger.Error()
+		if c.onInvalid != nil {
+			event = c.logger.Debug()
+		}
+		event.Err(err).
+			Str("raw_message", string(data)).
+			Msg("failed to parse message")
+		if c.onInvalid != nil {
+			c.onInvalid(data, err)
+		}
+		return
+	}
+
+	c.logger.Debug().
+		Str("type", msg.Type).
+		Str("agency", msg.Agency).
+		Strs("capcodes", msg.Capcodes).
+		Str("message", msg.Message).
+		Msg("received P2000 message")
+
+	if c.msgHandler != nil {
+		c.msgHandler(msg)
+	}
+}
+
+// closeConnection gracefully closes the current connection, if any
+func (c *Client) closeConnection() {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+
+	if conn != nil {
+		conn.close(true)
+	}
+}
+
+// Dialer returns the dialer used for upstream connections so callers can
+// customize it before Connect is called
+func (c *Client) Dialer() *websocket.Dialer {
+	return c.dialer
+}
+
+// Reconnect drops the current connection; Connect dials a new one after
+// the usual backoff
+func (c *Client) Reconnect() {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+
+	if conn != nil {
+		conn.close(false)
+	}
+}
+
+// StatusChan returns a channel that receives connection status updates
+func (c *Client) StatusChan() <-chan bool {
+	return c.statusChan
+}
+
+// notifyStatus sends connection status update
+func (c *Client) notifyStatus(connected bool) {
+	select {
+	case c.statusChan <- connected:
+	default:
+		// Channel full, skip
+	}
+}
+
+// increaseBackoff increases reconnection backoff time
+func (c *Client) increaseBackoff() {
+	c.backoff *= backoffMultiplier
+	if c.backoff > maxBackoff {
+		c.backoff = maxBackoff
+	}
+}
+
+// jittered returns d randomly moved by up to the jitter fraction of d
+func (c *Client) jittered(d time.Duration) time.Duration {
+	if c.jitter <= 0 {
+		return d
+	}
+	return d + time.Duration(c.jitter*(2*rand.Float64()-1)*float64(d))
+}
+
+// resetBackoff resets reconnection backoff to initial value
+func (c *Client) resetBackoff() {
+	c.backoff = initialBackoff
+}
+
+// Close gracefully shuts down the client
+func (c *Client) Close() {
+	close(c.done)
+	c.closeConnection()
+	c.logger.Info().Msg("websocket client closed")
+}
@@ -3,7 +3,11 @@ package websocket
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,6 +15,10 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/kaije/p2000-nfty/internal/filter"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,9 +31,9 @@ func getTestLogger() zerolog.Logger {
 
 func TestNewClient(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
@@ -39,15 +47,15 @@ func TestNewClient(t *testing.T) {
 
 func TestHandleMessage_ValidJSON(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
 	client := NewClient(logger, handler)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:      "FLEX",
 		Timestamp: 1234567890,
 		Capcodes:  []string{"0101001", "0101002"},
@@ -70,9 +78,9 @@ func TestHandleMessage_ValidJSON(t *testing.T) {
 
 func TestHandleMessage_InvalidJSON(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
@@ -85,11 +93,48 @@ func TestHandleMessage_InvalidJSON(t *testing.T) {
 	assert.Nil(t, receivedMsg)
 }
 
+func TestHandleMessage_OnFrame(t *testing.T) {
+	var frames []string
+	var handled int
+	client := NewClient(getTestLogger(), func(msg model.Message) {
+		handled++
+	})
+	client.OnFrame(func(data []byte) {
+		frames = append(frames, string(data))
+	})
+
+	client.handleMessage([]byte(`{"message":"A1 Test"}`))
+	client.handleMessage([]byte("invalid json {"))
+
+	// Frames that fail to parse are passed to the hook as well
+	assert.Equal(t, []string{`{"message":"A1 Test"}`, "invalid json {"}, frames)
+	assert.Equal(t, 1, handled)
+}
+
+func TestHandleMessage_OnInvalidFrame(t *testing.T) {
+	var invalid []string
+	var handled int
+	client := NewClient(getTestLogger(), func(msg model.Message) {
+		handled++
+	})
+	client.OnInvalidFrame(func(data []byte, err error) {
+		assert.Error(t, err)
+		invalid = append(invalid, string(data))
+	})
+
+	client.handleMessage([]byte(`{"message":"A1 Test"}`))
+	client.handleMessage([]byte("invalid json {"))
+	client.handleMessage([]byte(`{"type":"heartbeat","timestamp":"now"}`))
+
+	assert.Equal(t, []string{"invalid json {", `{"type":"heartbeat","timestamp":"now"}`}, invalid)
+	assert.Equal(t, 1, handled)
+}
+
 func TestHandleMessage_EmptyMessage(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
@@ -106,7 +151,7 @@ func TestHandleMessage_NoHandler(t *testing.T) {
 	logger := getTestLogger()
 	client := NewClient(logger, nil)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:    "FLEX",
 		Message: "Test",
 	}
@@ -122,19 +167,19 @@ func TestHandleMessage_NoHandler(t *testing.T) {
 
 func TestHandleMessage_ComplexSignal(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
 	client := NewClient(logger, handler)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:         "FLEX",
 		Timestamp:    1234567890,
 		FrequencyErr: 0.123,
-		Signal: Signal{
+		Signal: model.Signal{
 			Baudrate: 1200,
 			Frame:    1,
 			Subtype:  "A",
@@ -246,9 +291,9 @@ func TestClose(t *testing.T) {
 // TestConnectAndListen_WithMockServer tests the full connection flow
 func TestConnectAndListen_WithMockServer(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMessages []P2000Message
+	var receivedMessages []model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMessages = append(receivedMessages, msg)
 	}
 
@@ -265,7 +310,7 @@ func TestConnectAndListen_WithMockServer(t *testing.T) {
 		defer conn.Close()
 
 		// Send a test message
-		testMsg := P2000Message{
+		testMsg := model.Message{
 			Type:     "FLEX",
 			Message:  "Test message",
 			Capcodes: []string{"0101001"},
@@ -288,10 +333,10 @@ func TestConnectAndListen_WithMockServer(t *testing.T) {
 }
 
 func TestP2000Message_JSONMarshaling(t *testing.T) {
-	msg := P2000Message{
+	msg := model.Message{
 		Type:      "FLEX",
 		Timestamp: 1234567890,
-		Signal: Signal{
+		Signal: model.Signal{
 			Baudrate: 1200,
 			Frame:    1,
 			Subtype:  "A",
@@ -309,7 +354,7 @@ func TestP2000Message_JSONMarshaling(t *testing.T) {
 	assert.NotEmpty(t, jsonData)
 
 	// Unmarshal
-	var decoded P2000Message
+	var decoded model.Message
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
 
@@ -323,12 +368,12 @@ func TestP2000Message_JSONMarshaling(t *testing.T) {
 }
 
 func TestP2000Message_EmptyFields(t *testing.T) {
-	msg := P2000Message{}
+	msg := model.Message{}
 
 	jsonData, err := json.Marshal(msg)
 	require.NoError(t, err)
 
-	var decoded P2000Message
+	var decoded model.Message
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
 
@@ -342,7 +387,7 @@ func TestP2000Message_PartialData(t *testing.T) {
 	// JSON with only some fields
 	jsonStr := `{"type":"FLEX","message":"Test","capcodes":["0101001"]}`
 
-	var msg P2000Message
+	var msg model.Message
 	err := json.Unmarshal([]byte(jsonStr), &msg)
 	require.NoError(t, err)
 
@@ -353,7 +398,7 @@ func TestP2000Message_PartialData(t *testing.T) {
 }
 
 func TestSignal_JSONMarshaling(t *testing.T) {
-	signal := Signal{
+	signal := model.Signal{
 		Baudrate: 1200,
 		Frame:    1,
 		Subtype:  "A",
@@ -363,7 +408,7 @@ func TestSignal_JSONMarshaling(t *testing.T) {
 	jsonData, err := json.Marshal(signal)
 	require.NoError(t, err)
 
-	var decoded Signal
+	var decoded model.Signal
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
 
@@ -375,15 +420,15 @@ func TestSignal_JSONMarshaling(t *testing.T) {
 
 func TestHandleMessage_MultipleCapcodes(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
 	client := NewClient(logger, handler)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:     "FLEX",
 		Capcodes: []string{"0101001", "0101002", "0101003", "0234567"},
 		Message:  "Multiple units",
@@ -404,15 +449,15 @@ func TestHandleMessage_MultipleCapcodes(t *testing.T) {
 
 func TestHandleMessage_UnicodeMessage(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
 	client := NewClient(logger, handler)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:    "FLEX",
 		Message: "Brand 🔥 woning 🏠 met personen 👨‍👩‍👧",
 		Agency:  "Brandweer München",
@@ -431,9 +476,9 @@ func TestHandleMessage_UnicodeMessage(t *testing.T) {
 
 func TestHandleMessage_LongMessage(t *testing.T) {
 	logger := getTestLogger()
-	var receivedMsg *P2000Message
+	var receivedMsg *model.Message
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		receivedMsg = &msg
 	}
 
@@ -441,7 +486,7 @@ func TestHandleMessage_LongMessage(t *testing.T) {
 
 	longMessage := strings.Repeat("A very long emergency message. ", 100)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:    "FLEX",
 		Message: longMessage,
 	}
@@ -505,13 +550,13 @@ func TestConnect_ContextTimeout(t *testing.T) {
 func BenchmarkHandleMessage(b *testing.B) {
 	logger := getTestLogger()
 
-	handler := func(msg P2000Message) {
+	handler := func(msg model.Message) {
 		_ = msg
 	}
 
 	client := NewClient(logger, handler)
 
-	testMsg := P2000Message{
+	testMsg := model.Message{
 		Type:      "FLEX",
 		Timestamp: 1234567890,
 		Capcodes:  []string{"0101001", "0101002"},
@@ -528,10 +573,10 @@ func BenchmarkHandleMessage(b *testing.B) {
 }
 
 func BenchmarkJSONMarshal(b *testing.B) {
-	msg := P2000Message{
+	msg := model.Message{
 		Type:      "FLEX",
 		Timestamp: 1234567890,
-		Signal: Signal{
+		Signal: model.Signal{
 			Baudrate: 1200,
 			Frame:    1,
 			Subtype:  "A",
@@ -555,7 +600,117 @@ func BenchmarkJSONUnmarshal(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		var msg P2000Message
+		var msg model.Message
 		json.Unmarshal(data, &msg)
 	}
 }
+
+// BenchmarkHandleMessage_Filter measures the path of a frame from the read loop to
+// the capcode filter with thousands of configured capcodes
+func BenchmarkHandleMessage_Filter(b *testing.B) {
+	capcodes := make([]string, 5000)
+	for i := range capcodes {
+		capcodes[i] = fmt.Sprintf("%07d", 1420000+i)
+	}
+	f := filter.NewCapcodeFilter(false, capcodes, false, zerolog.Nop())
+	client := NewClient(zerolog.Nop(), func(msg model.Message) {
+		f.ShouldForward(msg.Capcodes)
+	})
+	data := []byte(`{"type":"FLEX","timestamp":1760522400,"signal":{"baudrate":1600,"frame":7,"subtype":"ALN","function":"3"},"frequency_error":-1.25,"capcodes":["0120901","1424999","1999999"],"message":"A1 Dorpsstraat 12 Utrecht 123456","agency":"Brandweer"}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.handleMessage(data)
+	}
+}
+
+func TestReconnect_NoConnection(t *testing.T) {
+	client := NewClient(getTestLogger(), nil)
+
+	assert.NotPanics(t, func() {
+		client.Reconnect()
+	})
+	assert.NotNil(t, client.Dialer())
+	assert.NotSame(t, websocket.DefaultDialer, client.Dialer())
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.NewMetrics()
+	client := NewClient(getTestLogger(), nil)
+	client.SetMetrics(m)
+	client.Dialer().NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, client.Connect(ctx))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketDialAttempts))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketDialFailures))
+	assert.Equal(t, initialBackoff.Seconds(), testutil.ToFloat64(m.WebsocketBackoff))
+
+	client.handleMessage([]byte("invalid json {"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketParseFailures))
+}
+
+func TestMetrics_ReadError(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		// Drop the connection without a close frame
+		conn.UnderlyingConn().Close()
+	}))
+	defer server.Close()
+
+	m := metrics.NewMetrics()
+	client := NewClient(getTestLogger(), nil)
+	client.SetMetrics(m)
+	client.Dialer().TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	client.Dialer().NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, client.connectAndListen(ctx))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketDialAttempts))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.WebsocketDialFailures))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketReadErrors))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.WebsocketBackoff))
+}
+
+func TestConnect_MaxReconnectAttempts(t *testing.T) {
+	dials := 0
+	client := NewClient(getTestLogger(), nil)
+	client.SetMaxReconnectAttempts(3)
+	client.backoff = time.Millisecond
+	client.Dialer().NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials++
+		return nil, errors.New("connection refused")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Connect(ctx)
+	assert.ErrorIs(t, err, ErrMaxReconnects)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, 3, dials)
+}
+
+func TestJittered(t *testing.T) {
+	client := NewClient(getTestLogger(), nil)
+	assert.Equal(t, 10*time.Second, client.jittered(10*time.Second))
+
+	client.SetJitter(0.2)
+	for i := 0; i < 100; i++ {
+		d := client.jittered(10 * time.Second)
+		assert.GreaterOrEqual(t, d, 8*time.Second)
+		assert.LessOrEqual(t, d, 12*time.Second)
+	}
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connection is a single upstream WebSocket connection. Writes are
+// serialized, and the keepalive goroutine lives exactly as long as the
+// connection.
+type connection struct {
+	ws        *websocket.Conn
+	writeMu   sync.Mutex // gorilla/websocket allows one concurrent writer
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	idleTimeout time.Duration
+	watchdog    *time.Timer // Closes the connection after idleTimeout without data
+	idle        atomic.Bool // Set when the watchdog closed the connection
+}
+
+// newConnection wraps ws. With idleTimeout set, the connection is closed
+// when touch is not called within that time.
+func newConnection(ws *websocket.Conn, idleTimeout time.Duration) *connection {
+	c := &connection{
+		ws:          ws,
+		closed:      make(chan struct{}),
+		idleTimeout: idleTimeout,
+	}
+	if idleTimeout > 0 {
+		c.watchdog = time.AfterFunc(idleTimeout, func() {
+			c.idle.Store(true)
+			c.close(false)
+		})
+	}
+	return c
+}
+
+// touch records that data arrived, restarting the watchdog
+func (c *connection) touch() {
+	if c.watchdog != nil {
+		c.watchdog.Reset(c.idleTimeout)
+	}
+}
+
+// timedOut reports whether the watchdog closed the connection
+func (c *connection) timedOut() bool {
+	return c.idle.Load()
+}
+
+// write sends a message with the write timeout
+func (c *connection) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.ws.WriteMessage(messageType, data)
+}
+
+// keepalive starts sending pings at interval until the connection is
+// closed. A failed ping closes the connection, failing the reader.
+func (c *connection) keepalive(interval time.Duration, onError func(error)) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.write(websocket.PingMessage, nil); err != nil {
+					onError(err)
+					c.close(false)
+					return
+				}
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+}
+
+// close closes the connection once, first sending a close frame when
+// graceful is set. Blocked reads and writes return with an error.
+func (c *connection) close(graceful bool) {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		if graceful {
+			c.write(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			)
+		}
+		c.ws.Close()
+	})
+}
+
+// wait closes the connection, stops the watchdog and blocks until its
+// goroutines have exited. It must be called by the goroutine reading the
+// connection.
+func (c *connection) wait() {
+	c.close(false)
+	if c.watchdog != nil {
+		c.watchdog.Stop()
+	}
+	c.wg.Wait()
+}
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kaije/p2000-nfty/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdleServer starts a feed that accepts connections and keeps them open
+// without sending messages. The returned client dials it.
+func newIdleServer(t *testing.T) *Client {
+	return newFeedServer(t, 0)
+}
+
+// newFeedServer starts a feed that sends an empty message every interval,
+// or none when interval is 0. The returned client dials it.
+func newFeedServer(t *testing.T, interval time.Duration) *Client {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if interval > 0 {
+			go func() {
+				for range time.Tick(interval) {
+					if err := conn.WriteMessage(websocket.TextMessage, []byte("{}")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(getTestLogger(), nil)
+	client.Dialer().TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	client.Dialer().NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return client
+}
+
+// listen runs connectAndListen until the connection is established
+func listen(t *testing.T, ctx context.Context, client *Client) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- client.connectAndListen(ctx) }()
+	select {
+	case connected := <-client.StatusChan():
+		require.True(t, connected)
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not connect")
+	}
+	return result
+}
+
+func TestConnectAndListen_Shutdown(t *testing.T) {
+	client := newIdleServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := listen(t, ctx, client)
+
+	cancel()
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("connectAndListen did not return after cancel")
+	}
+	assert.Nil(t, client.conn)
+}
+
+func TestConnectAndListen_Reconnect(t *testing.T) {
+	client := newIdleServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each dropped connection is released before the next one is made
+	for i := 0; i < 3; i++ {
+		result := listen(t, ctx, client)
+		first := client.conn
+		client.Reconnect()
+		select {
+		case err := <-result:
+			assert.ErrorContains(t, err, "read failed")
+		case <-time.After(2 * time.Second):
+			t.Fatal("connectAndListen did not return after reconnect")
+		}
+		assert.Nil(t, client.conn)
+		select {
+		case <-first.closed:
+		default:
+			t.Fatal("connection not closed")
+		}
+	}
+}
+
+func TestConnectAndListen_IdleTimeout(t *testing.T) {
+	m := &metrics.Metrics{
+		WebsocketIdleTimeouts: prometheus.NewCounter(prometheus.CounterOpts{Name: "idle"}),
+		WebsocketBackoff:      prometheus.NewGauge(prometheus.GaugeOpts{Name: "backoff"}),
+		WebsocketDialAttempts: prometheus.NewCounter(prometheus.CounterOpts{Name: "dials"}),
+		WebsocketDialFailures: prometheus.NewCounter(prometheus.CounterOpts{Name: "dial_failures"}),
+		WebsocketReadErrors:   prometheus.NewCounter(prometheus.CounterOpts{Name: "read_errors"}),
+	}
+	client := newIdleServer(t)
+	client.SetMetrics(m)
+	client.SetIdleTimeout(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := client.connectAndListen(ctx)
+	assert.EqualError(t, err, "no data received for 100ms")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.WebsocketIdleTimeouts))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.WebsocketReadErrors))
+}
+
+func TestConnectAndListen_IdleTimeoutReset(t *testing.T) {
+	client := newFeedServer(t, 20*time.Millisecond)
+	client.SetIdleTimeout(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.connectAndListen(ctx), context.DeadlineExceeded)
+}
+
+func TestConnection_ConcurrentWrites(t *testing.T) {
+	client := newIdleServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := listen(t, ctx, client)
+
+	client.connMu.Lock()
+	conn := client.conn
+	client.connMu.Unlock()
+	conn.keepalive(time.Millisecond, func(error) {})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				conn.write(websocket.PingMessage, nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cancel()
+	assert.ErrorIs(t, <-result, context.Canceled)
+}
@@ -0,0 +1,28 @@
+package websocket_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/kaije/p2000-nfty/pkg/model"
+	"github.com/kaije/p2000-nfty/pkg/websocket"
+	"github.com/rs/zerolog"
+)
+
+func ExampleClient() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := websocket.NewClient(zerolog.Nop(), func(msg model.Message) {
+		msg.Enrich(nil)
+		fmt.Println(msg.Priority, msg.Message)
+	})
+	defer client.Close()
+
+	// Connect reconnects with backoff until ctx is cancelled
+	if err := client.Connect(ctx); err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
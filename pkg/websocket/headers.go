@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reservedHeaders are part of the WebSocket handshake and cannot be
+// configured as dial headers
+var reservedHeaders = map[string]bool{
+	"Upgrade":                  true,
+	"Connection":               true,
+	"Sec-Websocket-Key":        true,
+	"Sec-Websocket-Version":    true,
+	"Sec-Websocket-Extensions": true,
+}
+
+// ValidateHeaders checks extra headers sent with the upstream dial, such as
+// an Origin, User-Agent or API key
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s must be a single line", name)
+		}
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("header %s is set by the websocket handshake", name)
+		}
+	}
+	return nil
+}
+
+// SetHeaders adds extra headers to every upstream dial. It must be set
+// before Connect is called.
+func (c *Client) SetHeaders(headers map[string]string) {
+	c.header = make(http.Header, len(headers))
+	for name, value := range headers {
+		c.header.Set(name, value)
+	}
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		errorMsg string
+	}{
+		{name: "Valid", headers: map[string]string{"Origin": "https://example.com", "User-Agent": "p2000-nfty", "X-Api-Key": "secret"}},
+		{name: "None"},
+		{name: "Reserved", headers: map[string]string{"sec-websocket-key": "a"}, errorMsg: "header sec-websocket-key is set by the websocket handshake"},
+		{name: "Invalid name", headers: map[string]string{"Api Key": "a"}, errorMsg: `invalid header name "Api Key"`},
+		{name: "Multiple lines", headers: map[string]string{"X-Api-Key": "a\nCookie: b"}, errorMsg: "header X-Api-Key must be a single line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHeaders(tt.headers)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestConnect_Headers(t *testing.T) {
+	received := make(chan http.Header, 1)
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient(getTestLogger(), nil)
+	client.SetHeaders(map[string]string{"origin": "https://example.com", "X-Api-Key": "secret"})
+	client.Dialer().EnableCompression = true
+	client.Dialer().TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	client.Dialer().NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client.connectAndListen(ctx)
+
+	require.Len(t, received, 1)
+	header := <-received
+	assert.Equal(t, "https://example.com", header.Get("Origin"))
+	assert.Equal(t, "secret", header.Get("X-Api-Key"))
+	assert.Contains(t, header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+}